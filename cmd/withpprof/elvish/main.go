@@ -11,12 +11,13 @@ import (
 	"src.elv.sh/pkg/pprof"
 	"src.elv.sh/pkg/prog"
 	"src.elv.sh/pkg/shell"
+	"src.elv.sh/pkg/web"
 )
 
 func main() {
 	os.Exit(prog.Run(
 		[3]*os.File{os.Stdin, os.Stdout, os.Stderr}, os.Args,
 		prog.Composite(
-			&pprof.Program{}, &buildinfo.Program{}, &daemon.Program{}, &lsp.Program{},
+			&pprof.Program{}, &buildinfo.Program{}, &daemon.Program{}, &lsp.Program{}, &web.Program{},
 			&shell.Program{ActivateDaemon: daemon.Activate})))
 }
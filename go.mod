@@ -8,7 +8,6 @@ require (
 	go.etcd.io/bbolt v1.3.9
 	golang.org/x/sync v0.6.0
 	golang.org/x/sys v0.17.0
-	pkg.nimblebun.works/go-lsp v1.1.0
 )
 
 go 1.21
@@ -58,6 +58,14 @@ type App interface {
 	RedrawFull()
 	// Notify adds a note and requests a redraw.
 	Notify(note ui.Text)
+	// Styles returns the styles of the named parts of widgets, to be used by
+	// widgets built for the App.
+	Styles() tk.Styles
+	// Suspend moves the cursor below the UI, restores the terminal to the
+	// state before ReadCode was called, and calls f. Afterwards, it sets up
+	// the terminal again and requests a full redraw. It must only be called
+	// from an event handler while ReadCode is running.
+	Suspend(f func()) error
 }
 
 type app struct {
@@ -67,19 +75,48 @@ type app struct {
 	TTY               TTY
 	MaxHeight         func() int
 	RPromptPersistent func() bool
+	Mouse             func() bool
 	BeforeReadline    []func()
 	AfterReadline     []func(string)
 	Highlighter       Highlighter
 	Prompt            Prompt
 	RPrompt           Prompt
 	GlobalBindings    tk.Bindings
+	styles            tk.Styles
 
 	StateMutex sync.RWMutex
 	State      State
 
 	codeArea tk.CodeArea
+
+	restoreTTY func()
+
+	// The fields below are only accessed from the goroutine running the loop.
+
+	// The last size of the terminal that could be measured.
+	lastHeight, lastWidth int
+	mouseTracking         bool
+	// Layout of the UI as last rendered, used to find the widget a mouse event
+	// is targeted at.
+	layout layout
+	// Mouse events waiting for a cursor position report, which is needed to
+	// translate their positions.
+	pendingMouse []term.MouseEvent
 }
 
+// Layout of the widgets in the main buffer.
+type layout struct {
+	widgets []tk.Widget
+	// The first line and the number of lines of each widget.
+	tops, heights []int
+	// The line of the buffer the cursor is on.
+	dotLine int
+}
+
+// Maximum number of mouse events that can wait for a cursor position report.
+// Further events are dropped.
+const maxPendingMouse = 16
+
 // State represents mutable state of an App.
 type State struct {
 	// Notes that have been added since the last redraw.
@@ -94,15 +131,19 @@ func NewApp(spec AppSpec) App {
 	lp := newLoop()
 	a := app{
 		loop:              lp,
+		lastHeight:        24,
+		lastWidth:         80,
 		TTY:               spec.TTY,
 		MaxHeight:         spec.MaxHeight,
 		RPromptPersistent: spec.RPromptPersistent,
+		Mouse:             spec.Mouse,
 		BeforeReadline:    spec.BeforeReadline,
 		AfterReadline:     spec.AfterReadline,
 		Highlighter:       spec.Highlighter,
 		Prompt:            spec.Prompt,
 		RPrompt:           spec.RPrompt,
 		GlobalBindings:    spec.GlobalBindings,
+		styles:            spec.Styles,
 		State:             spec.State,
 	}
 	if a.TTY == nil {
@@ -114,6 +155,9 @@ func NewApp(spec AppSpec) App {
 	if a.RPromptPersistent == nil {
 		a.RPromptPersistent = func() bool { return false }
 	}
+	if a.Mouse == nil {
+		a.Mouse = func() bool { return false }
+	}
 	if a.Highlighter == nil {
 		a.Highlighter = dummyHighlighter{}
 	}
@@ -130,13 +174,16 @@ func NewApp(spec AppSpec) App {
 	lp.RedrawCb(a.redraw)
 
 	a.codeArea = tk.NewCodeArea(tk.CodeAreaSpec{
-		Bindings:    spec.CodeAreaBindings,
-		Highlighter: a.Highlighter.Get,
-		Prompt:      a.Prompt.Get,
-		RPrompt:     a.RPrompt.Get,
-		QuotePaste:  spec.QuotePaste,
-		OnSubmit:    a.CommitCode,
-		State:       spec.CodeAreaState,
+		Bindings:          spec.CodeAreaBindings,
+		Highlighter:       a.Highlighter.Get,
+		Prompt:            a.Prompt.Get,
+		RPrompt:           a.RPrompt.Get,
+		QuotePaste:        spec.QuotePaste,
+		HighlightBrackets: spec.HighlightBrackets,
+		AutoPair:          spec.AutoPair,
+		OnSubmit:          a.CommitCode,
+		Styles:            spec.Styles,
+		State:             spec.CodeAreaState,
 
 		SimpleAbbreviations:    spec.SimpleAbbreviations,
 		CommandAbbreviations:   spec.CommandAbbreviations,
@@ -222,32 +269,85 @@ func (a *app) handle(e event) {
 		case sys.SIGWINCH:
 			a.RedrawFull()
 		}
-	case term.Event:
-		target := a.ActiveWidget()
-		handled := target.Handle(e)
-		if !handled {
-			handled = a.GlobalBindings.Handle(target, e)
-		}
-		if !handled {
-			if k, ok := e.(term.KeyEvent); ok {
-				a.Notify(ui.T("Unbound key: " + ui.Key(k).String()))
+	case term.MouseEvent:
+		if a.mouseTracking && len(a.pendingMouse) < maxPendingMouse {
+			if len(a.pendingMouse) == 0 {
+				a.TTY.RequestCursorPosition()
 			}
+			a.pendingMouse = append(a.pendingMouse, e)
 		}
+		if !a.loop.HasReturned() {
+			a.reqRead <- struct{}{}
+		}
+	case term.CursorPosition:
+		if len(a.pendingMouse) == 0 {
+			a.handleTermEvent(e)
+			return
+		}
+		for _, me := range a.pendingMouse {
+			a.dispatchMouse(me, e)
+		}
+		a.pendingMouse = nil
 		if !a.loop.HasReturned() {
 			a.triggerPrompts(false)
 			a.reqRead <- struct{}{}
 		}
+	case term.Event:
+		a.handleTermEvent(e)
 	}
 }
 
+func (a *app) handleTermEvent(e term.Event) {
+	target := a.ActiveWidget()
+	handled := target.Handle(e)
+	if !handled {
+		handled = a.GlobalBindings.Handle(target, e)
+	}
+	if !handled {
+		if k, ok := e.(term.KeyEvent); ok {
+			a.Notify(ui.T("Unbound key: " + ui.Key(k).String()))
+		}
+	}
+	if !a.loop.HasReturned() {
+		a.triggerPrompts(false)
+		a.reqRead <- struct{}{}
+	}
+}
+
+// Translates the position of a mouse event using the cursor position reported
+// after it, and sends it to the active addon if it falls within the addon.
+//
+// The cursor is always on the dot of the main buffer when the report is
+// requested, so the terminal line of the first line of the buffer can be
+// derived from the report.
+func (a *app) dispatchMouse(e term.MouseEvent, cursor term.CursorPosition) {
+	l := a.layout
+	i := len(l.widgets) - 1
+	if i < 1 || l.widgets[i] != a.ActiveWidget() {
+		return
+	}
+	line := e.Line - cursor.Line + l.dotLine - l.tops[i]
+	if line < 0 || line >= l.heights[i] {
+		return
+	}
+	e.Pos = term.Pos{Line: line, Col: e.Col - 1}
+	l.widgets[i].Handle(e)
+}
+
 func (a *app) triggerPrompts(force bool) {
 	a.Prompt.Trigger(force)
 	a.RPrompt.Trigger(force)
 }
 
 func (a *app) redraw(flag redrawFlag) {
-	// Get the dimensions available.
+	// Get the dimensions available. Measuring the terminal can fail, for
+	// example while it is being resized; use the last size in that case.
 	height, width := a.TTY.Size()
+	if height > 0 && width > 0 {
+		a.lastHeight, a.lastWidth = height, width
+	} else {
+		height, width = a.lastHeight, a.lastWidth
+	}
 	if maxHeight := a.MaxHeight(); maxHeight > 0 && maxHeight < height {
 		height = maxHeight
 	}
@@ -268,7 +368,8 @@ func (a *app) redraw(flag redrawFlag) {
 			s.HideTips = true
 			s.HideRPrompt = hideRPrompt
 		})
-		bufMain := renderApp([]tk.Widget{a.codeArea /* no addon */}, width, height)
+		a.setMouseTracking(false)
+		bufMain, _ := renderApp([]tk.Widget{a.codeArea /* no addon */}, width, height)
 		a.codeArea.MutateState(func(s *tk.CodeAreaState) {
 			s.HideTips = false
 			s.HideRPrompt = false
@@ -279,11 +380,20 @@ func (a *app) redraw(flag redrawFlag) {
 		a.TTY.UpdateBuffer(bufNotes, bufMain, flag&fullRedraw != 0)
 		a.TTY.ResetBuffer()
 	} else {
-		bufMain := renderApp(append([]tk.Widget{a.codeArea}, addons...), width, height)
+		a.setMouseTracking(a.Mouse() && len(addons) > 0)
+		bufMain, l := renderApp(append([]tk.Widget{a.codeArea}, addons...), width, height)
+		a.layout = l
 		a.TTY.UpdateBuffer(bufNotes, bufMain, flag&fullRedraw != 0)
 	}
 }
 
+func (a *app) setMouseTracking(on bool) {
+	if on != a.mouseTracking {
+		a.TTY.SetMouseTracking(on)
+		a.mouseTracking = on
+	}
+}
+
 // Renders notes. This does not respect height so that overflow notes end up in
 // the scrollback buffer.
 func renderNotes(notes []ui.Text, width int) *term.Buffer {
@@ -301,21 +411,31 @@ func renderNotes(notes []ui.Text, width int) *term.Buffer {
 }
 
 // Renders the codearea, and uses the rest of the height for the listing.
-func renderApp(widgets []tk.Widget, width, height int) *term.Buffer {
+// Also returns where each widget ended up in the buffer.
+func renderApp(widgets []tk.Widget, width, height int) (*term.Buffer, layout) {
 	heights, focus := distributeHeight(widgets, width, height)
+	l := layout{widgets: widgets,
+		tops: make([]int, len(widgets)), heights: make([]int, len(widgets))}
 	var buf *term.Buffer
 	for i, w := range widgets {
+		if buf != nil {
+			l.tops[i] = len(buf.Lines)
+		}
 		if heights[i] == 0 {
 			continue
 		}
 		buf2 := w.Render(width, heights[i])
+		l.heights[i] = len(buf2.Lines)
 		if buf == nil {
 			buf = buf2
 		} else {
 			buf.Extend(buf2, i == focus)
 		}
 	}
-	return buf
+	if buf != nil {
+		l.dotLine = buf.Dot.Line
+	}
+	return buf, l
 }
 
 // Distributes the height among all the widgets. Returns the height for each
@@ -409,13 +529,15 @@ func (a *app) ReadCode() (string, error) {
 			f(content)
 		}
 		a.resetAllStates()
+		a.pendingMouse = nil
 	}()
 
 	restore, err := a.TTY.Setup()
 	if err != nil {
 		return "", err
 	}
-	defer restore()
+	a.restoreTTY = restore
+	defer func() { a.restoreTTY() }()
 
 	var wg sync.WaitGroup
 	defer wg.Wait()
@@ -502,7 +624,23 @@ func (a *app) CommitCode() {
 	a.loop.Return(code, nil)
 }
 
+func (a *app) Suspend(f func()) error {
+	a.redraw(finalRedraw)
+	a.restoreTTY()
+	f()
+	restore, err := a.TTY.Setup()
+	if err != nil {
+		a.restoreTTY = func() {}
+		return err
+	}
+	a.restoreTTY = restore
+	a.RedrawFull()
+	return nil
+}
+
 func (a *app) Notify(note ui.Text) {
 	a.MutateState(func(s *State) { s.Notes = append(s.Notes, note) })
 	a.Redraw()
 }
+
+func (a *app) Styles() tk.Styles { return a.styles }
@@ -10,6 +10,7 @@ type AppSpec struct {
 	TTY               TTY
 	MaxHeight         func() int
 	RPromptPersistent func() bool
+	Mouse             func() bool
 	BeforeReadline    []func()
 	AfterReadline     []func(string)
 
@@ -21,6 +22,13 @@ type AppSpec struct {
 	CodeAreaBindings tk.Bindings
 	QuotePaste       func() bool
 
+	HighlightBrackets func() bool
+	AutoPair          func() bool
+
+	// Styles for the named parts of widgets. If nil, the default stylings
+	// are used.
+	Styles tk.Styles
+
 	SimpleAbbreviations    func(f func(abbr, full string))
 	CommandAbbreviations   func(f func(abbr, full string))
 	SmallWordAbbreviations func(f func(abbr, full string))
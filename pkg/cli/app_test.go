@@ -126,6 +126,52 @@ func TestReadCode_FinalRedraw(t *testing.T) {
 	f.TTY.TestBuffer(t, wantFinalBuf)
 }
 
+func TestSuspend(t *testing.T) {
+	restores, restoresInF := 0, 0
+	var errSuspend error
+	suspended := make(chan struct{}, 1)
+	var f *Fixture
+	f = Setup(
+		WithTTY(func(tty TTYCtrl) {
+			tty.SetSetup(func() { restores++ }, nil)
+		}),
+		WithSpec(func(spec *AppSpec) {
+			spec.CodeAreaState.Buffer = tk.CodeBuffer{Content: "code", Dot: 4}
+			spec.GlobalBindings = tk.MapBindings{
+				term.K('X', ui.Ctrl): func(tk.Widget) {
+					errSuspend = f.App.Suspend(func() {
+						restoresInF = restores
+					})
+					suspended <- struct{}{}
+				},
+			}
+		}))
+	defer f.Stop()
+	f.TTY.TestBuffer(t, bb().Write("code").SetDotHere().Buffer())
+
+	f.TTY.Inject(term.K('X', ui.Ctrl))
+	select {
+	case <-suspended:
+	case <-time.After(testutil.Scaled(time.Second)):
+		t.Fatal("Suspend not called")
+	}
+	if errSuspend != nil {
+		t.Errorf("Suspend returns error %v", errSuspend)
+	}
+	if restoresInF != 1 {
+		t.Errorf("TTY restored %d times before calling f, want 1", restoresInF)
+	}
+	// The cursor is moved below the UI before f is called, and the UI is
+	// redrawn after f returns.
+	f.TTY.TestBuffer(t, bb().Write("code").Newline().SetDotHere().Buffer())
+	f.TTY.TestBuffer(t, bb().Write("code").SetDotHere().Buffer())
+
+	f.Stop()
+	if restores != 2 {
+		t.Errorf("TTY restored %d times in total, want 2", restores)
+	}
+}
+
 // Signals.
 
 func TestReadCode_ReturnsEOFOnSIGHUP(t *testing.T) {
@@ -174,6 +220,40 @@ func TestReadCode_RedrawsOnSIGWINCH(t *testing.T) {
 		Write("1234567890").SetDotHere().Buffer())
 }
 
+func TestReadCode_RewrapsAddonsOnSIGWINCH(t *testing.T) {
+	f := Setup(WithSpec(func(spec *AppSpec) {
+		spec.State.Addons = []tk.Widget{tk.NewListBox(tk.ListBoxSpec{
+			Horizontal: true,
+			State:      tk.ListBoxState{Items: tk.TestItems{NItems: 2}, Selected: -1},
+		})}
+	}))
+	defer f.Stop()
+
+	// Both items fit on one line.
+	f.TTY.TestBuffer(t, bb().Newline().SetDotHere().
+		Write("item 0  item 1").Buffer())
+
+	f.TTY.SetSize(24, 10)
+	f.TTY.InjectSignal(sys.SIGWINCH)
+
+	// The items are now in one column.
+	f.TTY.TestBuffer(t, term.NewBufferBuilder(10).Newline().SetDotHere().
+		Write("item 0").Newline().Write("item 1").Buffer())
+}
+
+func TestReadCode_UsesLastSizeWhenTerminalCannotBeMeasured(t *testing.T) {
+	f := Setup(WithTTY(func(tty TTYCtrl) { tty.SetSize(24, 4) }))
+	defer f.Stop()
+
+	f.TTY.TestBuffer(t, term.NewBufferBuilder(4).SetDotHere().Buffer())
+
+	f.TTY.SetSize(-1, -1)
+	feedInput(f.TTY, "12345")
+
+	f.TTY.TestBuffer(t, term.NewBufferBuilder(4).
+		Write("12345").SetDotHere().Buffer())
+}
+
 // Code area.
 
 func TestReadCode_LetsCodeAreaHandleEvents(t *testing.T) {
@@ -286,6 +366,24 @@ func TestReadCode_RedrawsOnLateUpdateFromPrompt(t *testing.T) {
 	f.TTY.TestBuffer(t, bb().Write("new").SetDotHere().Buffer())
 }
 
+func TestReadCode_LateUpdateFromPromptUsesNewSize(t *testing.T) {
+	promptContent := "old"
+	prompt := testPrompt{
+		get:         func() ui.Text { return ui.T(promptContent) },
+		lateUpdates: make(chan struct{}),
+	}
+	f := Setup(WithSpec(func(spec *AppSpec) { spec.Prompt = prompt }))
+	defer f.Stop()
+
+	f.TTY.TestBuffer(t, bb().Write("old").SetDotHere().Buffer())
+
+	// The terminal has been resized, but SIGWINCH hasn't arrived yet.
+	f.TTY.SetSize(24, 2)
+	promptContent = "new"
+	prompt.lateUpdates <- struct{}{}
+	f.TTY.TestBuffer(t, term.NewBufferBuilder(2).Write("new").SetDotHere().Buffer())
+}
+
 func TestReadCode_ShowsRPrompt(t *testing.T) {
 	f := Setup(WithSpec(func(spec *AppSpec) {
 		spec.RPrompt = NewConstPrompt(ui.T("R"))
@@ -487,6 +585,61 @@ func TestReadCode_NotifiesAboutUnboundKey(t *testing.T) {
 	f.TestTTYNotes(t, "Unbound key: F1")
 }
 
+func TestReadCode_SendsMouseEventsToAddon(t *testing.T) {
+	selectedCh := make(chan int, 1)
+	f := Setup(WithSpec(func(spec *AppSpec) {
+		spec.Mouse = func() bool { return true }
+		spec.State.Addons = []tk.Widget{tk.NewListBox(tk.ListBoxSpec{
+			OnSelect: func(_ tk.Items, i int) { selectedCh <- i },
+			State:    tk.ListBoxState{Items: tk.TestItems{NItems: 3}, Selected: -1},
+		})}
+	}))
+	defer f.Stop()
+
+	// The main code area is on line 0 and the items are on lines 1 to 3, with
+	// the dot on line 1.
+	f.TestTTY(t, "\n", term.DotHere, "item 0\nitem 1\nitem 2")
+	if !f.TTY.MouseTracking() {
+		t.Errorf("mouse tracking not turned on with an addon")
+	}
+
+	// The terminal reports positions from 1. With the dot on terminal line 10,
+	// terminal line 12 is the line of item 2.
+	f.TTY.Inject(
+		term.MouseEvent{Pos: term.Pos{Line: 12, Col: 1}, Down: true, Button: 0},
+		term.CursorPosition{Line: 10, Col: 1})
+	select {
+	case i := <-selectedCh:
+		if i != 2 {
+			t.Errorf("got selected %d, want 2", i)
+		}
+	case <-time.After(testutil.Scaled(100 * time.Millisecond)):
+		t.Fatal("mouse event not sent to the addon")
+	}
+	if n := f.TTY.CursorPositionRequests(); n != 1 {
+		t.Errorf("got %d cursor position requests, want 1", n)
+	}
+
+	f.App.PopAddon()
+	f.App.Redraw()
+	f.TestTTY(t /* nothing */)
+	if f.TTY.MouseTracking() {
+		t.Errorf("mouse tracking not turned off without an addon")
+	}
+}
+
+func TestReadCode_DoesNotTrackMouseWhenDisabled(t *testing.T) {
+	f := Setup(WithSpec(func(spec *AppSpec) {
+		spec.State.Addons = []tk.Widget{tk.Label{Content: ui.T("addon")}}
+	}))
+	defer f.Stop()
+
+	f.TestTTY(t, "\n", term.DotHere, "addon")
+	if f.TTY.MouseTracking() {
+		t.Errorf("mouse tracking turned on when disabled")
+	}
+}
+
 // Misc features.
 
 func TestReadCode_TrimsBufferToMaxHeight(t *testing.T) {
@@ -43,6 +43,17 @@ type fakeTTY struct {
 	// Number of times the TTY screen has been cleared, incremented in
 	// ClearScreen.
 	cleared int
+	// Messages of desktop notifications, appended in NotifyDesktop.
+	desktopNotes []string
+	// Titles and working directories, appended in SetTitle and ReportCwd.
+	titles, cwds []string
+	// Semantic prompt marks, appended in MarkSemanticPrompt.
+	marks []string
+	// Whether mouse tracking is on, set in SetMouseTracking.
+	mouseTracking bool
+	// Number of times the cursor position has been requested, incremented in
+	// RequestCursorPosition.
+	cursorPositionRequests int
 
 	sizeMutex sync.RWMutex
 	// Predefined sizes.
@@ -136,6 +147,42 @@ func (t *fakeTTY) ClearScreen() {
 	t.cleared++
 }
 
+func (t *fakeTTY) NotifyDesktop(msg string) {
+	t.bufMutex.Lock()
+	defer t.bufMutex.Unlock()
+	t.desktopNotes = append(t.desktopNotes, msg)
+}
+
+func (t *fakeTTY) SetTitle(title string) {
+	t.bufMutex.Lock()
+	defer t.bufMutex.Unlock()
+	t.titles = append(t.titles, title)
+}
+
+func (t *fakeTTY) ReportCwd(dir string) {
+	t.bufMutex.Lock()
+	defer t.bufMutex.Unlock()
+	t.cwds = append(t.cwds, dir)
+}
+
+func (t *fakeTTY) MarkSemanticPrompt(mark string) {
+	t.bufMutex.Lock()
+	defer t.bufMutex.Unlock()
+	t.marks = append(t.marks, mark)
+}
+
+func (t *fakeTTY) SetMouseTracking(on bool) {
+	t.bufMutex.Lock()
+	defer t.bufMutex.Unlock()
+	t.mouseTracking = on
+}
+
+func (t *fakeTTY) RequestCursorPosition() {
+	t.bufMutex.Lock()
+	defer t.bufMutex.Unlock()
+	t.cursorPositionRequests++
+}
+
 func (t *fakeTTY) NotifySignals() <-chan os.Signal { return t.sigCh }
 
 func (t *fakeTTY) StopSignals() { close(t.sigCh) }
@@ -213,6 +260,52 @@ func (t TTYCtrl) ScreenCleared() int {
 	return t.cleared
 }
 
+// DesktopNotes returns the messages of desktop notifications that have been
+// sent so far.
+func (t TTYCtrl) DesktopNotes() []string {
+	t.bufMutex.RLock()
+	defer t.bufMutex.RUnlock()
+	return append([]string(nil), t.desktopNotes...)
+}
+
+// Titles returns the titles that have been set so far.
+func (t TTYCtrl) Titles() []string {
+	t.bufMutex.RLock()
+	defer t.bufMutex.RUnlock()
+	return append([]string(nil), t.titles...)
+}
+
+// ReportedCwds returns the working directories that have been reported so far.
+func (t TTYCtrl) ReportedCwds() []string {
+	t.bufMutex.RLock()
+	defer t.bufMutex.RUnlock()
+	return append([]string(nil), t.cwds...)
+}
+
+// SemanticPromptMarks returns the semantic prompt marks that have been written
+// so far.
+func (t TTYCtrl) SemanticPromptMarks() []string {
+	t.bufMutex.RLock()
+	defer t.bufMutex.RUnlock()
+	return append([]string(nil), t.marks...)
+}
+
+// MouseTracking returns whether mouse tracking is on.
+func (t TTYCtrl) MouseTracking() bool {
+	t.bufMutex.RLock()
+	defer t.bufMutex.RUnlock()
+	return t.mouseTracking
+}
+
+// CursorPositionRequests returns the number of times the cursor position has
+// been requested. The requests are not answered automatically; use Inject to
+// send CursorPosition events.
+func (t TTYCtrl) CursorPositionRequests() int {
+	t.bufMutex.RLock()
+	defer t.bufMutex.RUnlock()
+	return t.cursorPositionRequests
+}
+
 // TestBuffer verifies that a buffer will appear within 100ms, and aborts the
 // test if it doesn't.
 func (t TTYCtrl) TestBuffer(tt *testing.T, b *term.Buffer) {
@@ -31,6 +31,9 @@ type CompletionItem struct {
 	ToShow ui.Text
 	// Used when inserting a candidate.
 	ToInsert string
+	// If not empty, shown on the right of the filter when the candidate is
+	// selected. Not used for filtering.
+	Description string
 }
 
 type completion struct {
@@ -49,10 +52,16 @@ func NewCompletion(app cli.App, cfg CompletionSpec) (Completion, error) {
 	if len(cfg.Items) == 0 {
 		return nil, errNoCandidates
 	}
-	w := tk.NewComboBox(tk.ComboBoxSpec{
+	styles := app.Styles()
+	var w tk.ComboBox
+	w = tk.NewComboBox(tk.ComboBoxSpec{
 		CodeArea: tk.CodeAreaSpec{
-			Prompt:      modePrompt(" COMPLETING "+cfg.Name+" ", true),
+			Prompt: modePrompt(styles, " COMPLETING "+cfg.Name+" ", true),
+			RPrompt: func() ui.Text {
+				return selectedDescription(styles, w.ListBox().CopyState())
+			},
 			Highlighter: cfg.Filter.Highlighter,
+			Styles:      styles,
 		},
 		ListBox: tk.ListBoxSpec{
 			Horizontal: true,
@@ -69,6 +78,7 @@ func NewCompletion(app cli.App, cfg CompletionSpec) (Completion, error) {
 				app.PopAddon()
 			},
 			ExtendStyle: true,
+			Styles:      styles,
 		},
 		OnFilter: func(w tk.ComboBox, p string) {
 			w.ListBox().Reset(filterCompletionItems(cfg.Items, cfg.Filter.makePredicate(p)), 0)
@@ -77,6 +87,17 @@ func NewCompletion(app cli.App, cfg CompletionSpec) (Completion, error) {
 	return completion{w, codeArea}, nil
 }
 
+func selectedDescription(styles tk.Styles, state tk.ListBoxState) ui.Text {
+	items, ok := state.Items.(completionItems)
+	if !ok || state.Selected < 0 || state.Selected >= len(items) {
+		return nil
+	}
+	if d := items[state.Selected].Description; d != "" {
+		return ui.T(d, styles.For("completion-description"))
+	}
+	return nil
+}
+
 func (w completion) Dismiss() {
 	w.attached.MutateState(func(s *tk.CodeAreaState) { s.Pending = tk.PendingCode{} })
 }
@@ -6,6 +6,7 @@ import (
 	"src.elv.sh/pkg/cli"
 	. "src.elv.sh/pkg/cli/clitest"
 	"src.elv.sh/pkg/cli/term"
+	"src.elv.sh/pkg/cli/tk"
 	"src.elv.sh/pkg/diag"
 	"src.elv.sh/pkg/ui"
 )
@@ -42,6 +43,43 @@ func TestCompletion_Dismiss(t *testing.T) {
 	f.TestTTY(t /* nothing */)
 }
 
+func TestCompletion_Description(t *testing.T) {
+	f := Setup()
+	defer f.Stop()
+
+	w, _ := NewCompletion(f.App, CompletionSpec{
+		Name: "WORD",
+		Items: []CompletionItem{
+			{ToShow: ui.T("foo"), ToInsert: "foo", Description: "does foo"},
+			{ToShow: ui.T("bar"), ToInsert: "bar"},
+		},
+	})
+	f.App.PushAddon(w)
+	f.App.Redraw()
+	f.TestTTY(t,
+		"foo\n", Styles,
+		"___",
+		" COMPLETING WORD  ", Styles,
+		"***************** ", term.DotHere,
+		"                        does foo", Styles,
+		"                        dddddddd",
+		"foo  bar", Styles,
+		"+++",
+	)
+
+	// The description is only shown for the selected candidate.
+	w.ListBox().Select(tk.Next)
+	f.App.Redraw()
+	f.TestTTY(t,
+		"bar\n", Styles,
+		"___",
+		" COMPLETING WORD  ", Styles,
+		"***************** ", term.DotHere, "\n",
+		"foo  bar", Styles,
+		"     +++",
+	)
+}
+
 func TestNewCompletion_NoItems(t *testing.T) {
 	f := Setup()
 	defer f.Stop()
@@ -53,6 +53,7 @@ func NewHistlist(app cli.App, spec HistlistSpec) (Histlist, error) {
 	}
 	cmdItems := histlistItems{cmds, last}
 
+	styles := app.Styles()
 	w := tk.NewComboBox(tk.ComboBoxSpec{
 		CodeArea: tk.CodeAreaSpec{
 			Prompt: func() ui.Text {
@@ -60,13 +61,15 @@ func NewHistlist(app cli.App, spec HistlistSpec) (Histlist, error) {
 				if spec.Dedup() {
 					content += "(dedup on) "
 				}
-				return modeLine(content, true)
+				return modeLine(styles, content, true)
 			},
 			RPrompt:     spec.CodeAreaRPrompt,
 			Highlighter: spec.Filter.Highlighter,
+			Styles:      styles,
 		},
 		ListBox: tk.ListBoxSpec{
 			Bindings: spec.Bindings,
+			Styles:   styles,
 			OnAccept: func(it tk.Items, i int) {
 				text := it.(histlistItems).entries[i].Text
 				codeArea.MutateState(func(s *tk.CodeAreaState) {
@@ -50,7 +50,7 @@ func (w *histwalk) MaxHeight(width, height int) int {
 
 func (w *histwalk) render(width int) *term.Buffer {
 	cmd, _ := w.cursor.Get()
-	content := modeLine(fmt.Sprintf(" HISTORY #%d ", cmd.Seq), false)
+	content := modeLine(w.app.Styles(), fmt.Sprintf(" HISTORY #%d ", cmd.Seq), false)
 	return term.NewBufferBuilder(width).WriteStyled(content).Buffer()
 }
 
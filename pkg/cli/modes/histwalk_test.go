@@ -126,7 +126,7 @@ func TestHistWalk_FallbackHandler(t *testing.T) {
 func startHistwalk(app cli.App, cfg HistwalkSpec) {
 	w, err := NewHistwalk(app, cfg)
 	if err != nil {
-		app.Notify(ErrorText(err))
+		app.Notify(ErrorText(app.Styles(), err))
 		return
 	}
 	app.PushAddon(w)
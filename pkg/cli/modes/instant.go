@@ -6,7 +6,6 @@ import (
 	"src.elv.sh/pkg/cli"
 	"src.elv.sh/pkg/cli/term"
 	"src.elv.sh/pkg/cli/tk"
-	"src.elv.sh/pkg/ui"
 )
 
 // Instant is a mode that executes code whenever it changes and shows the
@@ -26,6 +25,7 @@ type InstantSpec struct {
 type instant struct {
 	InstantSpec
 	attachedTo tk.CodeArea
+	styles     tk.Styles
 	textView   tk.TextView
 	lastCode   string
 	lastErr    error
@@ -43,9 +43,9 @@ func (w *instant) MaxHeight(width, height int) int {
 
 func (w *instant) render(width, height int) *term.Buffer {
 	bb := term.NewBufferBuilder(width).
-		WriteStyled(modeLine(" INSTANT ", false)).SetDotHere()
+		WriteStyled(modeLine(w.styles, " INSTANT ", false)).SetDotHere()
 	if w.lastErr != nil {
-		bb.Newline().Write(w.lastErr.Error(), ui.FgRed)
+		bb.Newline().Write(w.lastErr.Error(), w.styles.For("error"))
 	}
 	buf := bb.Buffer()
 	if len(buf.Lines) < height {
@@ -98,7 +98,9 @@ func NewInstant(app cli.App, cfg InstantSpec) (Instant, error) {
 	w := instant{
 		InstantSpec: cfg,
 		attachedTo:  codeArea,
-		textView:    tk.NewTextView(tk.TextViewSpec{Scrollable: true}),
+		styles:      app.Styles(),
+		textView: tk.NewTextView(tk.TextViewSpec{
+			Scrollable: true, Styles: app.Styles()}),
 	}
 	w.update(true)
 	return &w, nil
@@ -67,10 +67,15 @@ func NewLastcmd(app cli.App, cfg LastcmdSpec) (Lastcmd, error) {
 		})
 		app.PopAddon()
 	}
+	styles := app.Styles()
 	w := tk.NewComboBox(tk.ComboBoxSpec{
-		CodeArea: tk.CodeAreaSpec{Prompt: modePrompt(" LASTCMD ", true)},
+		CodeArea: tk.CodeAreaSpec{
+			Prompt: modePrompt(styles, " LASTCMD ", true),
+			Styles: styles,
+		},
 		ListBox: tk.ListBoxSpec{
 			Bindings: cfg.Bindings,
+			Styles:   styles,
 			OnAccept: func(it tk.Items, i int) {
 				accept(it.(lastcmdItems).entries[i].content)
 			},
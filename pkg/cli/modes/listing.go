@@ -57,12 +57,15 @@ func NewListing(app cli.App, spec ListingSpec) (Listing, error) {
 		app.PopAddon()
 		spec.Accept(s)
 	}
+	styles := app.Styles()
 	w := tk.NewComboBox(tk.ComboBoxSpec{
 		CodeArea: tk.CodeAreaSpec{
-			Prompt: modePrompt(spec.Caption, true),
+			Prompt: modePrompt(styles, spec.Caption, true),
+			Styles: styles,
 		},
 		ListBox: tk.ListBoxSpec{
 			Bindings: spec.Bindings,
+			Styles:   styles,
 			OnAccept: func(it tk.Items, i int) {
 				accept(it.(listingItems)[i].ToAccept)
 			},
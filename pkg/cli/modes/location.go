@@ -91,13 +91,16 @@ func NewLocation(app cli.App, cfg LocationSpec) (Location, error) {
 
 	l := locationList{dirs}
 
+	styles := app.Styles()
 	w := tk.NewComboBox(tk.ComboBoxSpec{
 		CodeArea: tk.CodeAreaSpec{
-			Prompt:      modePrompt(" LOCATION ", true),
+			Prompt:      modePrompt(styles, " LOCATION ", true),
 			Highlighter: cfg.Filter.Highlighter,
+			Styles:      styles,
 		},
 		ListBox: tk.ListBoxSpec{
 			Bindings: cfg.Bindings,
+			Styles:   styles,
 			OnAccept: func(it tk.Items, i int) {
 				path := it.(locationList).dirs[i].Path
 				if strings.HasPrefix(path, wsKind) {
@@ -105,7 +108,7 @@ func NewLocation(app cli.App, cfg LocationSpec) (Location, error) {
 				}
 				err := cfg.Store.Chdir(path)
 				if err != nil {
-					app.Notify(ErrorText(err))
+					app.Notify(ErrorText(styles, err))
 				}
 				app.PopAddon()
 			},
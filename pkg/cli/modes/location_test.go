@@ -232,7 +232,7 @@ func TestLocation_Workspace(t *testing.T) {
 func locationBuf(filter string, lines ...string) *term.Buffer {
 	b := term.NewBufferBuilder(50).
 		Newline(). // empty code area
-		WriteStyled(modeLine(" LOCATION ", true)).
+		WriteStyled(modeLine(nil, " LOCATION ", true)).
 		Write(filter).SetDotHere()
 	for i, line := range lines {
 		b.Newline()
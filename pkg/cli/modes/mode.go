@@ -24,16 +24,16 @@ func FocusedCodeArea(a cli.App) (tk.CodeArea, error) {
 }
 
 // Returns text styled as a modeline.
-func modeLine(content string, space bool) ui.Text {
-	t := ui.T(content, ui.Bold, ui.FgWhite, ui.BgMagenta)
+func modeLine(styles tk.Styles, content string, space bool) ui.Text {
+	t := ui.T(content, styles.For("mode-line"))
 	if space {
 		t = ui.Concat(t, ui.T(" "))
 	}
 	return t
 }
 
-func modePrompt(content string, space bool) func() ui.Text {
-	p := modeLine(content, space)
+func modePrompt(styles tk.Styles, content string, space bool) func() ui.Text {
+	p := modeLine(styles, content, space)
 	return func() ui.Text { return p }
 }
 
@@ -41,7 +41,8 @@ func modePrompt(content string, space bool) func() ui.Text {
 // mode widget.
 var Prompt = modePrompt
 
-// ErrorText returns a red "error:" followed by unstyled space and err.Error().
-func ErrorText(err error) ui.Text {
-	return ui.Concat(ui.T("error:", ui.FgRed), ui.T(" "), ui.T(err.Error()))
+// ErrorText returns "error:" with the "error" styling, followed by unstyled
+// space and err.Error().
+func ErrorText(styles tk.Styles, err error) ui.Text {
+	return ui.Concat(ui.T("error:", styles.For("error")), ui.T(" "), ui.T(err.Error()))
 }
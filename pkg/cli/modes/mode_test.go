@@ -14,11 +14,11 @@ import (
 var Args = tt.Args
 
 func TestModeLine(t *testing.T) {
-	testModeLine(t, modeLine)
+	testModeLine(t, func(s string, b bool) ui.Text { return modeLine(nil, s, b) })
 }
 
 func TestModePrompt(t *testing.T) {
-	prompt := func(s string, b bool) ui.Text { return modePrompt(s, b)() }
+	prompt := func(s string, b bool) ui.Text { return modePrompt(nil, s, b)() }
 	testModeLine(t, tt.Fn(prompt).Named("prompt"))
 
 }
@@ -48,7 +48,7 @@ func startMode(app cli.App, w tk.Widget, err error) {
 		app.Redraw()
 	}
 	if err != nil {
-		app.Notify(ErrorText(err))
+		app.Notify(ErrorText(app.Styles(), err))
 	}
 }
 
@@ -63,6 +63,8 @@ type navigation struct {
 	lastFilter string
 	stateMutex sync.RWMutex
 	state      navigationState
+	// Height of the codearea as last rendered.
+	codeAreaHeight int
 }
 
 func (w *navigation) MutateState(f func(*navigationState)) {
@@ -78,6 +80,13 @@ func (w *navigation) CopyState() navigationState {
 }
 
 func (w *navigation) Handle(event term.Event) bool {
+	if e, ok := event.(term.MouseEvent); ok {
+		if e.Line < w.codeAreaHeight {
+			return false
+		}
+		e.Line -= w.codeAreaHeight
+		return w.colView.Handle(e)
+	}
 	if w.colView.Handle(event) {
 		return true
 	}
@@ -97,6 +106,7 @@ func (w *navigation) Handle(event term.Event) bool {
 
 func (w *navigation) Render(width, height int) *term.Buffer {
 	buf := w.codeArea.Render(width, height)
+	w.codeAreaHeight = len(buf.Lines)
 	bufColView := w.colView.Render(width, height-len(buf.Lines))
 	buf.Extend(bufColView, false)
 	return buf
@@ -120,7 +130,7 @@ func (w *navigation) ascend() {
 
 	err = w.Cursor.Ascend()
 	if err != nil {
-		w.app.Notify(ErrorText(err))
+		w.app.Notify(ErrorText(w.app.Styles(), err))
 	} else {
 		w.codeArea.MutateState(func(s *tk.CodeAreaState) {
 			s.Buffer = tk.CodeBuffer{}
@@ -145,7 +155,7 @@ func (w *navigation) descend() {
 	}
 	err := w.Cursor.Descend(selected.Name())
 	if err != nil {
-		w.app.Notify(ErrorText(err))
+		w.app.Notify(ErrorText(w.app.Styles(), err))
 	} else {
 		w.codeArea.MutateState(func(s *tk.CodeAreaState) {
 			s.Buffer = tk.CodeBuffer{}
@@ -176,12 +186,13 @@ func NewNavigation(app cli.App, spec NavigationSpec) (Navigation, error) {
 		codeArea: tk.NewCodeArea(tk.CodeAreaSpec{
 			Prompt: func() ui.Text {
 				if w.CopyState().ShowHidden {
-					return modeLine(" NAVIGATING (show hidden) ", true)
+					return modeLine(app.Styles(), " NAVIGATING (show hidden) ", true)
 				}
-				return modeLine(" NAVIGATING ", true)
+				return modeLine(app.Styles(), " NAVIGATING ", true)
 			},
 			RPrompt:     spec.CodeAreaRPrompt,
 			Highlighter: spec.Filter.Highlighter,
+			Styles:      app.Styles(),
 		}),
 		colView: tk.NewColView(tk.ColViewSpec{
 			Bindings: spec.Bindings,
@@ -214,6 +225,7 @@ func updateState(w *navigation, selectName string) {
 	cursor := w.Cursor
 	filter := w.lastFilter
 	showHidden := w.CopyState().ShowHidden
+	styles := w.app.Styles()
 
 	var parentCol, currentCol tk.Widget
 
@@ -227,19 +239,20 @@ func updateState(w *navigation, selectName string) {
 
 	parent, err := cursor.Parent()
 	if err == nil {
-		parentCol = makeCol(parent, showHidden)
+		parentCol = makeCol(styles, parent, showHidden)
 	} else {
-		parentCol = makeErrCol(err)
+		parentCol = makeErrCol(styles, err)
 	}
 
 	current, err := cursor.Current()
 	if err == nil {
 		currentCol = makeColInner(
+			styles,
 			current,
 			w.Filter.makePredicate(filter),
 			showHidden,
 			func(it tk.Items, i int) {
-				previewCol := makeCol(it.(fileItems)[i], showHidden)
+				previewCol := makeCol(styles, it.(fileItems)[i], showHidden)
 				colView.MutateState(func(s *tk.ColViewState) {
 					s.Columns[2] = previewCol
 				})
@@ -249,7 +262,7 @@ func updateState(w *navigation, selectName string) {
 			tryToSelectName(currentCol, selectName)
 		}
 	} else {
-		currentCol = makeErrCol(err)
+		currentCol = makeErrCol(styles, err)
 		tryToSelectNothing(parentCol)
 	}
 
@@ -290,14 +303,14 @@ func tryToSelectName(w tk.Widget, name string) {
 	})
 }
 
-func makeCol(f NavigationFile, showHidden bool) tk.Widget {
-	return makeColInner(f, func(string) bool { return true }, showHidden, nil)
+func makeCol(styles tk.Styles, f NavigationFile, showHidden bool) tk.Widget {
+	return makeColInner(styles, f, func(string) bool { return true }, showHidden, nil)
 }
 
-func makeColInner(f NavigationFile, filter func(string) bool, showHidden bool, onSelect func(tk.Items, int)) tk.Widget {
+func makeColInner(styles tk.Styles, f NavigationFile, filter func(string) bool, showHidden bool, onSelect func(tk.Items, int)) tk.Widget {
 	files, content, err := f.Read()
 	if err != nil {
-		return makeErrCol(err)
+		return makeErrCol(styles, err)
 	}
 
 	if files != nil {
@@ -314,7 +327,7 @@ func makeColInner(f NavigationFile, filter func(string) bool, showHidden bool, o
 			return files[i].Name() < files[j].Name()
 		})
 		return tk.NewListBox(tk.ListBoxSpec{
-			Padding: 1, ExtendStyle: true, OnSelect: onSelect,
+			Padding: 1, ExtendStyle: true, OnSelect: onSelect, Styles: styles,
 			State: tk.ListBoxState{Items: fileItems(files)},
 		})
 	}
@@ -323,11 +336,12 @@ func makeColInner(f NavigationFile, filter func(string) bool, showHidden bool, o
 	return tk.NewTextView(tk.TextViewSpec{
 		State:      tk.TextViewState{Lines: lines},
 		Scrollable: true,
+		Styles:     styles,
 	})
 }
 
-func makeErrCol(err error) tk.Widget {
-	return tk.Label{Content: ui.T(err.Error(), ui.FgRed)}
+func makeErrCol(styles tk.Styles, err error) tk.Widget {
+	return tk.Label{Content: ui.T(err.Error(), styles.For("error"))}
 }
 
 type fileItems []NavigationFile
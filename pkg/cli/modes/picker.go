@@ -0,0 +1,182 @@
+package modes
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"src.elv.sh/pkg/cli"
+	"src.elv.sh/pkg/cli/term"
+	"src.elv.sh/pkg/cli/tk"
+	"src.elv.sh/pkg/ui"
+)
+
+// Picker is a mode for picking one or more items from a list, which can be
+// narrowed down by fuzzy matching. It is based on the ComboBox widget.
+type Picker interface {
+	tk.ComboBox
+}
+
+// PickerSpec specifies the configuration for the picker mode.
+type PickerSpec struct {
+	// Key bindings.
+	Bindings tk.Bindings
+	// Caption of the picker. If empty, defaults to " PICK ".
+	Caption string
+	// Items to pick from.
+	Items []string
+	// Whether multiple items can be picked. If true, Tab toggles whether the
+	// selected item is marked, and accepting picks all the marked items, or
+	// the selected item if no item is marked.
+	Multi bool
+	// A function to call with the indices of the picked items in Items, in
+	// ascending order. If unspecified, defaults to a function that does
+	// nothing.
+	Accept func([]int)
+}
+
+// NewPicker creates a new picker mode.
+//
+// The query is split into words by whitespace, and an item is shown if each
+// word is a subsequence of the item. The match is case-insensitive, unless the
+// word contains an uppercase letter. Items are shown in their original order,
+// with the matched runes highlighted.
+func NewPicker(app cli.App, spec PickerSpec) Picker {
+	if spec.Accept == nil {
+		spec.Accept = func([]int) {}
+	}
+	if spec.Caption == "" {
+		spec.Caption = " PICK "
+	}
+	if spec.Bindings == nil {
+		spec.Bindings = tk.DummyBindings{}
+	}
+	// Only accessed from the event loop of the app.
+	marked := make([]bool, len(spec.Items))
+	styles := app.Styles()
+	matchStyling := styles.For("picker-match")
+
+	var w tk.ComboBox
+	toggleMark := func() {
+		state := w.ListBox().CopyState()
+		items, ok := state.Items.(pickerItems)
+		if !ok || state.Selected < 0 || state.Selected >= items.Len() {
+			return
+		}
+		i := items.indices[state.Selected]
+		marked[i] = !marked[i]
+		query := w.CodeArea().CopyState().Buffer.Content
+		w.ListBox().Reset(filterPickerItems(spec, marked, query, matchStyling),
+			min(state.Selected+1, items.Len()-1))
+	}
+	w = tk.NewComboBox(tk.ComboBoxSpec{
+		CodeArea: tk.CodeAreaSpec{
+			Prompt: modePrompt(styles, spec.Caption, true),
+			Styles: styles,
+		},
+		ListBox: tk.ListBoxSpec{
+			Styles: styles,
+			Bindings: tk.FuncBindings(func(w tk.Widget, event term.Event) bool {
+				if spec.Multi && event == term.K(ui.Tab) {
+					toggleMark()
+					return true
+				}
+				return spec.Bindings.Handle(w, event)
+			}),
+			OnAccept: func(it tk.Items, i int) {
+				var picked []int
+				for j, m := range marked {
+					if m {
+						picked = append(picked, j)
+					}
+				}
+				if len(picked) == 0 {
+					picked = []int{it.(pickerItems).indices[i]}
+				}
+				spec.Accept(picked)
+			},
+		},
+		OnFilter: func(w tk.ComboBox, q string) {
+			w.ListBox().Reset(filterPickerItems(spec, marked, q, matchStyling), 0)
+		},
+	})
+	return w
+}
+
+type pickerItems struct {
+	texts []ui.Text
+	// Indices of the items in PickerSpec.Items.
+	indices []int
+}
+
+func (it pickerItems) Len() int           { return len(it.texts) }
+func (it pickerItems) Show(i int) ui.Text { return it.texts[i] }
+
+func filterPickerItems(spec PickerSpec, marked []bool, query string, matchStyling ui.Styling) pickerItems {
+	var items pickerItems
+	words := strings.Fields(query)
+	for i, item := range spec.Items {
+		matched, ok := fuzzyMatch(words, item)
+		if !ok {
+			continue
+		}
+		var t ui.Text
+		if spec.Multi {
+			if marked[i] {
+				t = ui.T("* ")
+			} else {
+				t = ui.T("  ")
+			}
+		}
+		items.texts = append(items.texts, ui.Concat(t, highlightMatched(item, matched, matchStyling)))
+		items.indices = append(items.indices, i)
+	}
+	return items
+}
+
+// Matches each word against s as a subsequence, and returns the byte
+// positions of all the matched runes in s.
+func fuzzyMatch(words []string, s string) (map[int]bool, bool) {
+	matched := make(map[int]bool)
+	for _, word := range words {
+		foldCase := strings.ToLower(word) == word
+		j := 0
+		for _, r := range word {
+			for {
+				if j == len(s) {
+					return nil, false
+				}
+				r2, size := utf8.DecodeRuneInString(s[j:])
+				if r2 == r || (foldCase && unicode.ToLower(r2) == r) {
+					matched[j] = true
+					j += size
+					break
+				}
+				j += size
+			}
+		}
+	}
+	return matched, true
+}
+
+func highlightMatched(s string, matched map[int]bool, matchStyling ui.Styling) ui.Text {
+	var t ui.Text
+	start := 0
+	for i := range s {
+		if i > start && matched[i] != matched[start] {
+			t = append(t, pickerSegment(s[start:i], matched[start], matchStyling)...)
+			start = i
+		}
+	}
+	if start < len(s) {
+		t = append(t, pickerSegment(s[start:], matched[start], matchStyling)...)
+	}
+	return t
+}
+
+func pickerSegment(s string, matched bool, matchStyling ui.Styling) ui.Text {
+	if matched {
+		return ui.T(s, matchStyling)
+	}
+	return ui.T(s)
+}
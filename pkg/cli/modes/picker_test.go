@@ -0,0 +1,162 @@
+package modes
+
+import (
+	"testing"
+
+	"src.elv.sh/pkg/cli"
+	. "src.elv.sh/pkg/cli/clitest"
+	"src.elv.sh/pkg/cli/term"
+	"src.elv.sh/pkg/ui"
+)
+
+func TestPicker_BasicUI(t *testing.T) {
+	f := Setup()
+	defer f.Stop()
+
+	startPicker(f.App, PickerSpec{
+		Caption: " TEST ",
+		Items:   []string{"foo", "bar"},
+	})
+	f.TestTTY(t,
+		"\n",
+		" TEST  ", Styles,
+		"****** ", term.DotHere, "\n",
+		"foo                                               \n", Styles,
+		"++++++++++++++++++++++++++++++++++++++++++++++++++",
+		"bar",
+	)
+}
+
+func TestPicker_FuzzyFiltering(t *testing.T) {
+	f := Setup()
+	defer f.Stop()
+
+	startPicker(f.App, PickerSpec{
+		Items: []string{"foo.go", "bar.elv", "Foo.elv"},
+	})
+	// Lowercase words match case-insensitively, and each word matches
+	// independently.
+	f.TTY.Inject(term.K('f'), term.K('e'), term.K(' '), term.K('v'))
+	f.TestTTY(t,
+		"\n",
+		" PICK  fe v", Styles,
+		"******     ", term.DotHere, "\n",
+		"Foo.elv                                           ", pickerStyles,
+		"U+++U+U+++++++++++++++++++++++++++++++++++++++++++",
+	)
+	// Words with an uppercase letter match case-sensitively.
+	f.TTY.Inject(term.K(ui.Backspace), term.K(ui.Backspace), term.K(ui.Backspace),
+		term.K(ui.Backspace), term.K('F'))
+	f.TestTTY(t,
+		"\n",
+		" PICK  F", Styles,
+		"****** ", term.DotHere, "\n",
+		"Foo.elv                                           ", pickerStyles,
+		"U+++++++++++++++++++++++++++++++++++++++++++++++++",
+	)
+}
+
+func TestPicker_MatchStylingIsConfigurable(t *testing.T) {
+	f := Setup(WithSpec(func(spec *cli.AppSpec) {
+		spec.Styles = func(name string) ui.Styling {
+			if name == "picker-match" {
+				return ui.FgRed
+			}
+			return nil
+		}
+	}))
+	defer f.Stop()
+
+	startPicker(f.App, PickerSpec{Items: []string{"foo"}})
+	f.TTY.Inject(term.K('o'))
+	f.TestTTY(t,
+		"\n",
+		" PICK  o", Styles,
+		"****** ", term.DotHere, "\n",
+		"foo                                               ", ui.RuneStylesheet{
+			'+': ui.Inverse, 'R': ui.Stylings(ui.FgRed, ui.Inverse)},
+		"+R++++++++++++++++++++++++++++++++++++++++++++++++",
+	)
+}
+
+func TestPicker_Accept(t *testing.T) {
+	f := Setup()
+	defer f.Stop()
+
+	var picked []int
+	startPicker(f.App, PickerSpec{
+		Items: []string{"foo", "bar", "baz"},
+		Accept: func(i []int) {
+			picked = i
+			f.App.PopAddon()
+		},
+	})
+	f.TTY.Inject(term.K('b'), term.K(ui.Down))
+	f.TestTTY(t,
+		"\n",
+		" PICK  b", Styles,
+		"****** ", term.DotHere, "\n",
+		"bar\n", pickerStyles,
+		"_",
+		"baz                                               ", pickerStyles,
+		"U+++++++++++++++++++++++++++++++++++++++++++++++++",
+	)
+	f.TTY.Inject(term.K(ui.Enter))
+	f.TestTTY(t /* nothing */)
+	if want := []int{2}; !equalInts(picked, want) {
+		t.Errorf("picked %v, want %v", picked, want)
+	}
+}
+
+func TestPicker_Multi(t *testing.T) {
+	f := Setup()
+	defer f.Stop()
+
+	var picked []int
+	startPicker(f.App, PickerSpec{
+		Items: []string{"foo", "bar", "baz"},
+		Multi: true,
+		Accept: func(i []int) {
+			picked = i
+			f.App.PopAddon()
+		},
+	})
+	// Mark foo and baz.
+	f.TTY.Inject(term.K(ui.Tab), term.K(ui.Down), term.K(ui.Tab))
+	f.TestTTY(t,
+		"\n",
+		" PICK  ", Styles,
+		"****** ", term.DotHere, "\n",
+		"* foo\n",
+		"  bar\n",
+		"* baz                                             ", Styles,
+		"++++++++++++++++++++++++++++++++++++++++++++++++++",
+	)
+	f.TTY.Inject(term.K(ui.Enter))
+	f.TestTTY(t /* nothing */)
+	if want := []int{0, 2}; !equalInts(picked, want) {
+		t.Errorf("picked %v, want %v", picked, want)
+	}
+}
+
+var pickerStyles = ui.RuneStylesheet{
+	'_': ui.Underlined,
+	'+': ui.Inverse,
+	'U': ui.Stylings(ui.Underlined, ui.Inverse),
+}
+
+func startPicker(app cli.App, spec PickerSpec) {
+	startMode(app, NewPicker(app, spec), nil)
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,6 +1,7 @@
 package modes
 
 import (
+	"src.elv.sh/pkg/cli"
 	"src.elv.sh/pkg/cli/term"
 	"src.elv.sh/pkg/cli/tk"
 )
@@ -21,6 +22,7 @@ type StubSpec struct {
 
 type stub struct {
 	StubSpec
+	styles tk.Styles
 }
 
 func (w stub) Render(width, height int) *term.Buffer {
@@ -35,7 +37,7 @@ func (w stub) MaxHeight(width, height int) int {
 
 func (w stub) render(width int) *term.Buffer {
 	return term.NewBufferBuilder(width).
-		WriteStyled(modeLine(w.Name, false)).SetDotHere().Buffer()
+		WriteStyled(modeLine(w.styles, w.Name, false)).SetDotHere().Buffer()
 }
 
 func (w stub) Handle(event term.Event) bool {
@@ -47,9 +49,9 @@ func (w stub) Focus() bool {
 }
 
 // NewStub creates a new Stub mode.
-func NewStub(cfg StubSpec) Stub {
+func NewStub(app cli.App, cfg StubSpec) Stub {
 	if cfg.Bindings == nil {
 		cfg.Bindings = tk.DummyBindings{}
 	}
-	return stub{cfg}
+	return stub{cfg, app.Styles()}
 }
@@ -42,7 +42,7 @@ func TestStub_Handling(t *testing.T) {
 }
 
 func startStub(app cli.App, spec StubSpec) {
-	w := NewStub(spec)
+	w := NewStub(app, spec)
 	app.PushAddon(w)
 	app.Redraw()
 }
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"src.elv.sh/pkg/cli/tk"
 	"src.elv.sh/pkg/ui"
 )
 
@@ -29,17 +30,16 @@ type Prompt struct {
 type Config struct {
 	// The function that computes the prompt.
 	Compute func() ui.Text
-	// Function to transform stale prompts.
+	// Function to transform stale prompts. Defaults to applying the
+	// "stale-prompt" styling of Styles.
 	StaleTransform func(ui.Text) ui.Text
 	// Threshold for a prompt to be considered as stale.
 	StaleThreshold func() time.Duration
 	// How eager the prompt should be updated. When >= 5, updated when directory
 	// is changed. When >= 10, always update. Default is 5.
 	Eagerness func() int
-}
-
-func defaultStaleTransform(t ui.Text) ui.Text {
-	return ui.StyleText(t, ui.Inverse)
+	// Styles used by the default StaleTransform.
+	Styles tk.Styles
 }
 
 const defaultStaleThreshold = 200 * time.Millisecond
@@ -54,7 +54,10 @@ func New(cfg Config) *Prompt {
 		cfg.Compute = func() ui.Text { return unknownContent }
 	}
 	if cfg.StaleTransform == nil {
-		cfg.StaleTransform = defaultStaleTransform
+		styles := cfg.Styles
+		cfg.StaleTransform = func(t ui.Text) ui.Text {
+			return ui.StyleText(t, styles.For("stale-prompt"))
+		}
 	}
 	if cfg.StaleThreshold == nil {
 		cfg.StaleThreshold = func() time.Duration { return defaultStaleThreshold }
@@ -18,14 +18,25 @@ func K(r rune, mods ...ui.Mod) KeyEvent {
 }
 
 // MouseEvent represents a mouse event (either pressing or releasing).
+//
+// When read from the terminal, the position is 1-based and relative to the
+// top-left corner of the terminal. When handled by widgets, it is 0-based and
+// relative to the top-left corner of the widget as last rendered.
 type MouseEvent struct {
 	Pos
 	Down bool
-	// Number of the Button, 0-based. -1 for unknown.
+	// Number of the Button, 0-based. -1 for unknown. Scrolling the wheel up and
+	// down are reported as presses of buttons ScrollUp and ScrollDown.
 	Button int
 	Mod    ui.Mod
 }
 
+// Buttons numbers in MouseEvent for scrolling the wheel.
+const (
+	ScrollUp   = 3
+	ScrollDown = 4
+)
+
 // CursorPosition represents a report of the current cursor position from the
 // terminal driver, usually as a response from a cursor position request.
 type CursorPosition Pos
@@ -36,11 +36,18 @@ type bReader struct {
 	wStop *os.File
 	// A mutex that is held when Read is in process.
 	mutex sync.Mutex
+	// Bytes pushed back by Unread, to be returned before reading the file.
+	unread []byte
 }
 
 func (r *bReader) ReadByteWithTimeout(timeout time.Duration) (byte, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
+	if len(r.unread) > 0 {
+		b := r.unread[0]
+		r.unread = r.unread[1:]
+		return b, nil
+	}
 	for {
 		ready, err := eunix.WaitForRead(timeout, r.file, r.rStop)
 		if err != nil {
@@ -69,6 +76,12 @@ func (r *bReader) ReadByteWithTimeout(timeout time.Duration) (byte, error) {
 	}
 }
 
+func (r *bReader) Unread(bs []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.unread = append(bs[:len(bs):len(bs)], r.unread...)
+}
+
 func (r *bReader) Stop() error {
 	_, err := r.wStop.Write([]byte{'q'})
 	r.mutex.Lock()
@@ -4,12 +4,16 @@ package term
 
 import (
 	"time"
+	"unicode/utf8"
 )
 
 type byteReaderWithTimeout interface {
 	// ReadByteWithTimeout reads a single byte with a timeout. A negative
 	// timeout means no timeout.
 	ReadByteWithTimeout(timeout time.Duration) (byte, error)
+	// Unread pushes back bytes, so that they are returned by the next calls to
+	// ReadByteWithTimeout.
+	Unread(bs []byte)
 }
 
 const badRune = '\ufffd'
@@ -18,6 +22,9 @@ var utf8SeqTimeout = 10 * time.Millisecond
 
 // Reads a rune from the reader. The timeout applies to the first byte; a
 // negative value means no timeout.
+//
+// An invalid UTF-8 sequence results in an error. If the sequence is cut short
+// by a byte that can't continue it, that byte is pushed back to be read again.
 func readRune(rd byteReaderWithTimeout, timeout time.Duration) (rune, error) {
 	leader, err := rd.ReadByteWithTimeout(timeout)
 	if err != nil {
@@ -27,7 +34,7 @@ func readRune(rd byteReaderWithTimeout, timeout time.Duration) (rune, error) {
 	pending := 0
 	switch {
 	case leader>>7 == 0:
-		r = rune(leader)
+		return rune(leader), nil
 	case leader>>5 == 0x6:
 		r = rune(leader & 0x1f)
 		pending = 1
@@ -37,13 +44,26 @@ func readRune(rd byteReaderWithTimeout, timeout time.Duration) (rune, error) {
 	case leader>>3 == 0x1e:
 		r = rune(leader & 0x7)
 		pending = 3
+	default:
+		// A continuation byte, or a byte that never appears in UTF-8.
+		return badRune, seqError{"invalid UTF-8", string([]byte{leader})}
 	}
+	seq := []byte{leader}
 	for i := 0; i < pending; i++ {
 		b, err := rd.ReadByteWithTimeout(utf8SeqTimeout)
 		if err != nil {
 			return badRune, err
 		}
+		if b>>6 != 0x2 {
+			rd.Unread([]byte{b})
+			return badRune, seqError{"incomplete UTF-8", string(seq)}
+		}
+		seq = append(seq, b)
 		r = r<<6 + rune(b&0x3f)
 	}
+	if !utf8.Valid(seq) {
+		// Overlong encodings, surrogates and values beyond the Unicode range.
+		return badRune, seqError{"invalid UTF-8", string(seq)}
+	}
 	return r, nil
 }
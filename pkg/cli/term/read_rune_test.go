@@ -60,3 +60,49 @@ func TestReadRune_ErrorAtNonFirstByte(t *testing.T) {
 		t.Errorf("got err %v, want non-nil", err)
 	}
 }
+
+var invalidUTF8 = []string{
+	// Continuation byte without a leader
+	"\x80",
+	// Byte that never appears in UTF-8
+	"\xff",
+	// Overlong encoding of '/'
+	"\xc0\xaf",
+	// Surrogate
+	"\xed\xa0\x80",
+}
+
+func TestReadRune_InvalidUTF8(t *testing.T) {
+	for _, content := range invalidUTF8 {
+		t.Run(content, func(t *testing.T) {
+			rd, w, cleanup := setupFileReader()
+			defer cleanup()
+
+			w.Write([]byte(content))
+			r, err := readRune(rd, 0)
+			if r != '\ufffd' {
+				t.Errorf("got rune %q, want %q", r, '\ufffd')
+			}
+			if err == nil {
+				t.Errorf("got err %v, want non-nil", err)
+			}
+		})
+	}
+}
+
+func TestReadRune_IncompleteSequence(t *testing.T) {
+	rd, w, cleanup := setupFileReader()
+	defer cleanup()
+
+	w.Write([]byte("\xe4\xbdx"))
+
+	r, err := readRune(rd, 0)
+	if r != '\ufffd' || err == nil {
+		t.Errorf("got (%q, %v), want (%q, non-nil)", r, err, '\ufffd')
+	}
+	// The byte that cut the sequence short can be read again.
+	r, err = readRune(rd, 0)
+	if r != 'x' || err != nil {
+		t.Errorf("got (%q, %v), want (%q, nil)", r, err, 'x')
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sync/atomic"
+	"time"
 )
 
 // Reader reads events from the terminal.
@@ -27,6 +29,25 @@ var ErrStopped = errors.New("stopped")
 
 var errTimeout = errors.New("timed out")
 
+// Timeout for the bytes following an Escape in escape sequences. Modern
+// terminal emulators send escape sequences very fast, so 10ms is more than
+// sufficient. SSH connections on a slow link might be problematic though.
+var keySeqTimeout atomic.Int64
+
+const defaultKeySeqTimeout = 10 * time.Millisecond
+
+func init() { keySeqTimeout.Store(int64(defaultKeySeqTimeout)) }
+
+// KeySeqTimeout returns how long the Reader waits for the rest of an escape
+// sequence after reading an Escape. If nothing arrives in time, the Escape is
+// taken as a key on its own; otherwise it is taken as part of an escape
+// sequence or an Alt-modified key.
+func KeySeqTimeout() time.Duration { return time.Duration(keySeqTimeout.Load()) }
+
+// SetKeySeqTimeout sets the value returned by KeySeqTimeout. It has no effect
+// on Windows, where key events are not encoded as escape sequences.
+func SetKeySeqTimeout(d time.Duration) { keySeqTimeout.Store(int64(d)) }
+
 type seqError struct {
 	msg string
 	seq string
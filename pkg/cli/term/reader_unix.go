@@ -4,7 +4,7 @@ package term
 
 import (
 	"os"
-	"time"
+	"unicode"
 
 	"src.elv.sh/pkg/ui"
 )
@@ -40,11 +40,6 @@ func (rd *reader) Close() {
 // Used by readRune in readOne to signal end of current sequence.
 const runeEndOfSeq rune = -1
 
-// Timeout for bytes in escape sequences. Modern terminal emulators send escape
-// sequences very fast, so 10ms is more than sufficient. SSH connections on a
-// slow link might be problematic though.
-var keySeqTimeout = 10 * time.Millisecond
-
 func readEvent(rd byteReaderWithTimeout) (event Event, err error) {
 	var r rune
 	r, err = readRune(rd, -1)
@@ -53,12 +48,13 @@ func readEvent(rd byteReaderWithTimeout) (event Event, err error) {
 	}
 
 	currentSeq := string(r)
-	// Attempts to read a rune within a timeout of keySeqTimeout. It returns
+	timeout := KeySeqTimeout()
+	// Attempts to read a rune within a timeout of KeySeqTimeout. It returns
 	// runeEndOfSeq if there is any error; the caller should terminate the
 	// current sequence when it sees that value.
 	readRune :=
 		func() rune {
-			r, e := readRune(rd, keySeqTimeout)
+			r, e := readRune(rd, timeout)
 			if e != nil {
 				return runeEndOfSeq
 			}
@@ -85,8 +81,21 @@ func readEvent(rd byteReaderWithTimeout) (event Event, err error) {
 		}
 		if r2 == runeEndOfSeq {
 			// TODO(xiaq): Error is swallowed.
-			// Nothing follows. Taken as a lone Escape.
-			event = KeyEvent{'[', ui.Ctrl}
+			// Nothing follows. Taken as a lone Escape, or Alt-Escape if there
+			// were two.
+			if hasTwoLeadingESC {
+				event = KeyEvent{'[', ui.Ctrl | ui.Alt}
+			} else {
+				event = KeyEvent{'[', ui.Ctrl}
+			}
+			break
+		}
+		if hasTwoLeadingESC && r2 != '[' && r2 != 'O' {
+			// The second Escape doesn't start a CSI-style or G3-style
+			// sequence. Take the two Escapes as Alt-Escape, and leave what
+			// follows for the next event.
+			rd.Unread([]byte(string(r2)))
+			event = KeyEvent{'[', ui.Ctrl | ui.Alt}
 			break
 		}
 		switch r2 {
@@ -99,6 +108,10 @@ func readEvent(rd byteReaderWithTimeout) (event Event, err error) {
 			}
 
 			nums := make([]int, 0, 2)
+			// Sub-parameters, separated by colons, of the first parameter.
+			// They are used in the CSI u encoding for alternate keys.
+			var subNums []int
+			inSub := false
 			var starter rune
 
 			// Read an optional starter.
@@ -124,10 +137,9 @@ func readEvent(rd byteReaderWithTimeout) (event Event, err error) {
 					return
 				}
 				down := true
-				button := int(cb & 3)
-				if button == 3 {
+				button := mouseButton(int(cb))
+				if button == -1 {
 					down = false
-					button = -1
 				}
 				mod := mouseModify(int(cb))
 				event = MouseEvent{
@@ -139,10 +151,28 @@ func readEvent(rd byteReaderWithTimeout) (event Event, err error) {
 				switch {
 				case r == ';':
 					nums = append(nums, 0)
+					inSub = false
+				case r == ':':
+					if len(nums) == 0 {
+						nums = append(nums, 0)
+					}
+					// Only keep the sub-parameters of the first parameter;
+					// those of the other parameters are ignored.
+					inSub = true
+					if len(nums) == 1 {
+						subNums = append(subNums, 0)
+					}
 				case '0' <= r && r <= '9':
 					if len(nums) == 0 {
 						nums = append(nums, 0)
 					}
+					if inSub {
+						if len(nums) == 1 {
+							cur := len(subNums) - 1
+							subNums[cur] = subNums[cur]*10 + int(r-'0')
+						}
+						break
+					}
 					cur := len(nums) - 1
 					nums[cur] = nums[cur]*10 + int(r-'0')
 				case r == runeEndOfSeq:
@@ -170,13 +200,16 @@ func readEvent(rd byteReaderWithTimeout) (event Event, err error) {
 				}
 				down := r == 'M'
 				button := nums[0] & 3
+				if nums[0]&64 != 0 {
+					button = mouseButton(nums[0])
+				}
 				mod := mouseModify(nums[0])
 				event = MouseEvent{Pos{nums[2], nums[1]}, down, button, mod}
 			} else if r == '~' && len(nums) == 1 && (nums[0] == 200 || nums[0] == 201) {
 				b := nums[0] == 200
 				event = PasteSetting(b)
 			} else {
-				k := parseCSI(nums, r, currentSeq)
+				k := parseCSI(nums, subNums, r, currentSeq)
 				if k == (ui.Key{}) {
 					badSeq("bad CSI")
 				} else {
@@ -338,9 +371,93 @@ var csiSeqTilde27 = map[int]rune{
 	58: ':', 59: ';', 60: '<', 61: '=', 62: '>', 63: ';',
 }
 
-// parseCSI parses a CSI-style key sequence. See comments above for all the 3
-// variants this function handles.
-func parseCSI(nums []int, last rune, seq string) ui.Key {
+// CSI-style key sequences ending with 'u', introduced by fixterms and extended
+// by the kitty keyboard protocol. The first argument is the Unicode codepoint of
+// the key, and the optional second argument identifies the modifier, using the
+// same encoding as xterm. For instance, \e[97;5u is Ctrl-A. The first argument
+// may have sub-parameters for the shifted and base layout forms of the key, such
+// as \e[97:65;2u for Shift-A; see csiUKey for how they are used.
+//
+// Sent by foot, kitty, WezTerm, iTerm2 and xterm (with formatOtherKeys set to
+// 1), usually only when configured to do so.
+//
+// The same encoding is used by xterm's modifyOtherKeys feature, in the form of
+// \e[27;mod;codepoint~; see csiSeqTilde27.
+
+// Modifier bits of the CSI u encoding beyond the first 4 of xterm. Super and
+// Hyper are not supported and are ignored along with the lock keys; Meta is
+// conflated with Alt, like in xtermModify.
+const (
+	csiUSuper    = 0x8
+	csiUHyper    = 0x10
+	csiUMeta     = 0x20
+	csiUCapsLock = 0x40
+	csiUNumLock  = 0x80
+)
+
+// Converts the codepoint and modifier in a CSI u sequence to a ui.Key.
+// Alternate forms of the codepoint are given in alts, in the order of shifted
+// and base layout forms. It returns the zero value if the sequence is invalid.
+func csiUKey(code int, alts []int, mod int) ui.Key {
+	if code <= 0 || code > unicode.MaxRune || mod < 0 || mod > 256 {
+		return ui.Key{}
+	}
+	var k ui.Key
+	if mod > 0 {
+		modFlags := mod - 1
+		if modFlags&0x1 != 0 {
+			k.Mod |= ui.Shift
+		}
+		if modFlags&(0x2|csiUMeta) != 0 {
+			k.Mod |= ui.Alt
+		}
+		if modFlags&0x4 != 0 {
+			k.Mod |= ui.Ctrl
+		}
+	}
+	r := rune(code)
+	if k.Mod&ui.Shift != 0 && len(alts) > 0 && alts[0] > 0 && alts[0] <= unicode.MaxRune {
+		// Use the shifted form reported by the terminal, which depends on the
+		// keyboard layout, so that a Shift-modified key is reported in the same
+		// way as when it is input as text, like '!' instead of Shift-1.
+		r = rune(alts[0])
+		k.Mod &^= ui.Shift
+	}
+	switch r {
+	case '\r':
+		r = ui.Enter
+	case 0x1b:
+		// Normalize Escape to Ctrl-[, like a lone Escape in readEvent.
+		r = '['
+		k.Mod |= ui.Ctrl
+	}
+	if k.Mod&ui.Ctrl != 0 {
+		// Normalize the same way as ctrlModify: Ctrl-modified letters are upper
+		// case, and the ambiguous Ctrl keys use their non-Ctrl form.
+		switch r {
+		case 'i', 'I':
+			r = ui.Tab
+			k.Mod &^= ui.Ctrl
+		case 'j', 'J':
+			r = ui.Enter
+			k.Mod &^= ui.Ctrl
+		case '?':
+			r = ui.Backspace
+			k.Mod &^= ui.Ctrl
+		default:
+			if 'a' <= r && r <= 'z' {
+				r = unicode.ToUpper(r)
+			}
+		}
+	}
+	k.Rune = r
+	return k
+}
+
+// parseCSI parses a CSI-style key sequence. See comments above for all the
+// variants this function handles. The sub-parameters of the first argument are
+// in subNums.
+func parseCSI(nums, subNums []int, last rune, seq string) ui.Key {
 	if k, ok := csiSeqByLast[last]; ok {
 		if len(nums) == 0 {
 			// Unmodified: \e[A (Up)
@@ -370,6 +487,19 @@ func parseCSI(nums []int, last rune, seq string) ui.Key {
 				k := ui.K(r)
 				return xtermModify(k, nums[1], seq)
 			}
+			// Generated by xterm's modifyOtherKeys feature.
+			return csiUKey(nums[2], nil, nums[1])
+		}
+	case 'u':
+		if 1 <= len(nums) && len(nums) <= 3 {
+			// Unmodified: \e[97u (a); modified: \e[97;5u (Ctrl-A). The
+			// optional third argument is the text generated by the key, which
+			// is not needed.
+			mod := 0
+			if len(nums) >= 2 {
+				mod = nums[1]
+			}
+			return csiUKey(nums[0], subNums, mod)
 		}
 	case '$', '^', '@':
 		// Modified by urxvt; see comment above csiSeqTilde.
@@ -417,6 +547,22 @@ func xtermModify(k ui.Key, mod int, seq string) ui.Key {
 	return k
 }
 
+// Returns the button encoded in the first byte of a mouse event. The lowest 2
+// bits identify the button, or a release of an unknown button if they are 3;
+// the bit 64 means that the wheel was scrolled instead.
+func mouseButton(n int) int {
+	if n&64 != 0 {
+		if n&3 == 0 {
+			return ScrollUp
+		}
+		return ScrollDown
+	}
+	if n&3 == 3 {
+		return -1
+	}
+	return n & 3
+}
+
 func mouseModify(n int) ui.Mod {
 	var mod ui.Mod
 	if n&4 != 0 {
@@ -6,8 +6,10 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/testutil"
 	"src.elv.sh/pkg/ui"
 )
 
@@ -34,9 +36,17 @@ var readEventTests = []struct {
 	{"\t", K('\t')},
 	{"\x7f", K('\x7f')}, // backspace
 
+	// Multi-byte UTF-8 key.
+	{"é", K('é')},
+	{"你", K('你')},
+
 	// Alt plus simple graphical key.
 	{"\033a", K('a', ui.Alt)},
 	{"\033[", K('[', ui.Alt)},
+	{"\033é", K('é', ui.Alt)},
+
+	// Two Escapes with nothing following, taken as Alt-Escape.
+	{"\033\033", K('[', ui.Ctrl, ui.Alt)},
 
 	// G3-style key.
 	{"\033OA", K(ui.Up)},
@@ -90,6 +100,42 @@ var readEventTests = []struct {
 	// identifies the key.
 	{"\033[27;4;63~", K(';', ui.Shift, ui.Alt)},
 
+	// CSI-sequence key with three arguments and ending in '~', generated by
+	// xterm's modifyOtherKeys and not in csiSeqTilde27.
+	{"\033[27;5;97~", K('A', ui.Ctrl)},
+	{"\033[27;3;233~", K('é', ui.Alt)},
+
+	// CSI u key.
+	{"\033[97u", K('a')},
+	{"\033[233u", K('é')},
+	{"\033[97;3u", K('a', ui.Alt)},
+	{"\033[233;3u", K('é', ui.Alt)},
+	// Ctrl-modified letters are normalized to upper case.
+	{"\033[97;5u", K('A', ui.Ctrl)},
+	{"\033[97;6u", K('A', ui.Shift, ui.Ctrl)},
+	// Ambiguous Ctrl keys are normalized like in the legacy encoding.
+	{"\033[105;5u", K(ui.Tab)},
+	// Keys with special codepoints.
+	{"\033[13u", K(ui.Enter)},
+	{"\033[13;5u", K(ui.Enter, ui.Ctrl)},
+	{"\033[9;2u", K(ui.Tab, ui.Shift)},
+	{"\033[27u", K('[', ui.Ctrl)},
+	{"\033[27;3u", K('[', ui.Ctrl, ui.Alt)},
+	// The shifted form of the key is used if reported.
+	{"\033[97:65;2u", K('A')},
+	{"\033[49:33;2u", K('!')},
+	{"\033[49:33;6u", K('!', ui.Ctrl)},
+	// Base layout forms are ignored.
+	{"\033[1089::99;5u", K('с', ui.Ctrl)},
+	// Meta is conflated with Alt; lock keys are ignored.
+	{"\033[97;33u", K('a', ui.Alt)},
+	{"\033[97;69u", K('A', ui.Ctrl)},
+	// Sub-parameters of the modifier and the text argument are ignored.
+	{"\033[97;5:1u", K('A', ui.Ctrl)},
+	{"\033[97;1;97u", K('a')},
+	// With a leading Escape.
+	{"\033\033[97u", K('a', ui.Alt)},
+
 	// Cursor Position Report.
 	{"\033[3;4R", CursorPosition{3, 4}},
 
@@ -109,6 +155,10 @@ var readEventTests = []struct {
 	{"\033[M\x10\x23\x24", MouseEvent{Pos{4, 3}, true, 0, ui.Ctrl}},
 	{"\033[M\x14\x23\x24", MouseEvent{Pos{4, 3}, true, 0, ui.Shift | ui.Ctrl}},
 
+	// Scrolling.
+	{"\033[M\x60\x23\x24", MouseEvent{Pos{4, 3}, true, ScrollUp, 0}},
+	{"\033[M\x61\x23\x24", MouseEvent{Pos{4, 3}, true, ScrollDown, 0}},
+
 	// SGR-style mouse event.
 	{"\033[<0;3;4M", MouseEvent{Pos{4, 3}, true, 0, 0}},
 	// Other buttons.
@@ -118,6 +168,10 @@ var readEventTests = []struct {
 	// Modified.
 	{"\033[<4;3;4M", MouseEvent{Pos{4, 3}, true, 0, ui.Shift}},
 	{"\033[<16;3;4M", MouseEvent{Pos{4, 3}, true, 0, ui.Ctrl}},
+	// Scrolling.
+	{"\033[<64;3;4M", MouseEvent{Pos{4, 3}, true, ScrollUp, 0}},
+	{"\033[<65;3;4M", MouseEvent{Pos{4, 3}, true, ScrollDown, 0}},
+	{"\033[<68;3;4M", MouseEvent{Pos{4, 3}, true, ScrollUp, ui.Shift}},
 }
 
 func TestReader_ReadEvent(t *testing.T) {
@@ -169,6 +223,14 @@ var readEventBadSeqTests = []struct {
 
 	// G3 allows a small list of allowed bytes after \033O
 	{"\033Ox", "bad G3"},
+
+	// CSI u needs a valid codepoint, and at most 3 arguments
+	{"\033[0u", "bad CSI"},
+	{"\033[1114112u", "bad CSI"},
+	{"\033[97;1;97;1u", "bad CSI"},
+
+	// Invalid UTF-8
+	{"\xff", "invalid UTF-8"},
 }
 
 func TestReader_ReadEvent_BadSeq(t *testing.T) {
@@ -190,6 +252,47 @@ func TestReader_ReadEvent_BadSeq(t *testing.T) {
 	}
 }
 
+func TestReader_ReadEvent_AltEscapeFollowedByKey(t *testing.T) {
+	r, w := setupReader(t)
+
+	w.WriteString("\033\033x")
+	testReadEvents(t, r, K('[', ui.Ctrl, ui.Alt), K('x'))
+}
+
+func TestReader_ReadEvent_IncompleteUTF8FollowedByKey(t *testing.T) {
+	r, w := setupReader(t)
+
+	w.WriteString("\xc3x")
+	if _, err := r.ReadEvent(); err == nil {
+		t.Errorf("got nil err, want non-nil")
+	}
+	// The byte that cut the UTF-8 sequence short is not lost.
+	testReadEvents(t, r, K('x'))
+}
+
+func TestReader_ReadEvent_KeySeqTimeout(t *testing.T) {
+	r, w := setupReader(t)
+	SetKeySeqTimeout(testutil.Scaled(time.Second))
+	t.Cleanup(func() { SetKeySeqTimeout(defaultKeySeqTimeout) })
+
+	// With a long enough timeout, a slowly arriving key following an Escape
+	// is still taken as Alt-modified.
+	w.WriteString("\033")
+	time.Sleep(testutil.Scaled(20 * time.Millisecond))
+	w.WriteString("x")
+	testReadEvents(t, r, K('x', ui.Alt))
+}
+
+func testReadEvents(t *testing.T, r Reader, wantEvents ...Event) {
+	t.Helper()
+	for _, want := range wantEvents {
+		ev, err := r.ReadEvent()
+		if ev != want || err != nil {
+			t.Errorf("got (%v, %v), want (%v, nil)", ev, err, want)
+		}
+	}
+}
+
 func TestReader_ReadRawEvent(t *testing.T) {
 	rd, w := setupReader(t)
 
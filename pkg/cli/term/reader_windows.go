@@ -167,6 +167,14 @@ func convertEvent(event ewindows.InputEvent) Event {
 			}
 		}
 		mod := convertMod(filteredMod)
+		if r == 0 && mod&^ui.Shift == 0 && isCharKey(event.WVirtualKeyCode) {
+			// A key that normally inputs a character didn't input one, and
+			// no modifier key other than Shift explains that. This is a dead
+			// key, which inputs nothing on its own and modifies the character
+			// input by the next key (e.g. ^ followed by e inputs ê). The
+			// composed character arrives as a separate event.
+			return nil
+		}
 		if mod == 0 && event.WVirtualKeyCode == 0x1b {
 			// Special case: Normalize 0x1b to Ctrl-[.
 			//
@@ -207,6 +215,14 @@ func convertRune(keyCode uint16, mod ui.Mod) rune {
 	return 0
 }
 
+// Reports whether the virtual key code is for a key that inputs a character,
+// as opposed to a function key or a modifier key.
+func isCharKey(keyCode uint16) bool {
+	return '0' <= keyCode && keyCode <= '9' || 'A' <= keyCode && keyCode <= 'Z' ||
+		0xba <= keyCode && keyCode <= 0xc0 || 0xdb <= keyCode && keyCode <= 0xdf ||
+		keyCode == 0xe2
+}
+
 func convertMod(state uint32) ui.Mod {
 	mod := ui.Mod(0)
 	if state&(leftAlt|rightAlt) != 0 {
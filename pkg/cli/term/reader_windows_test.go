@@ -38,6 +38,10 @@ func TestConvertEvent(t *testing.T) {
 		Args(funcKeyEvent('A', leftCtrl)).Rets(K('A', ui.Ctrl)),
 		Args(funcKeyEvent('A', leftAlt)).Rets(K('a', ui.Alt)),
 
+		// Dead keys
+		Args(funcKeyEvent(0xde, 0)).Rets(nil),
+		Args(funcKeyEvent(0xc0, shift)).Rets(nil),
+
 		// Unrecognized functional key
 		Args(funcKeyEvent(0, 0)).Rets(nil),
 	)
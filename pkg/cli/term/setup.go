@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"src.elv.sh/pkg/env"
 	"src.elv.sh/pkg/sys"
 	"src.elv.sh/pkg/wcwidth"
 )
@@ -15,6 +16,12 @@ func Setup(in, out *os.File) (func() error, error) {
 	return setup(in, out)
 }
 
+// IsDumb returns whether the terminal is declared to be a dumb terminal, which
+// doesn't support any escape sequences, by setting $E:TERM to "dumb".
+func IsDumb() bool {
+	return os.Getenv(env.TERM) == "dumb"
+}
+
 // SetupForEval sets up the terminal for evaluating Elvish code. It returns a
 // function to call after the evaluation finishes.
 func SetupForEval(in, out *os.File) func() {
@@ -22,9 +29,8 @@ func SetupForEval(in, out *os.File) func() {
 }
 
 const (
-	lackEOLRune    = '\u23ce'
-	lackEOL        = "\033[7m" + string(lackEOLRune) + "\033[m"
-	enableSGRMouse = false
+	lackEOLRune = '\u23ce'
+	lackEOL     = "\033[7m" + string(lackEOLRune) + "\033[m"
 )
 
 // setupVT performs setup for VT-like terminals.
@@ -66,11 +72,6 @@ func setupVT(out *os.File) error {
 	*/
 	s += "\033[?7l"
 
-	// Turn on SGR-style mouse tracking.
-	if enableSGRMouse {
-		s += "\033[?1000;1006h"
-	}
-
 	// Enable bracketed paste.
 	s += "\033[?2004h"
 
@@ -83,10 +84,9 @@ func restoreVT(out *os.File) error {
 	s := ""
 	// Turn on autowrap.
 	s += "\033[?7h"
-	// Turn off mouse tracking.
-	if enableSGRMouse {
-		s += "\033[?1000;1006l"
-	}
+	// Turn off mouse tracking, in case it was turned on with
+	// Writer.SetMouseTracking.
+	s += disableMouseTracking
 	// Disable bracketed paste.
 	s += "\033[?2004l"
 	// Move the cursor to the first row, even if we haven't written anything
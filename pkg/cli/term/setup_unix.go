@@ -52,11 +52,42 @@ func setup(in, out *os.File) (func() error, error) {
 }
 
 func setupForEval(in, out *os.File) func() {
-	// There is nothing to set up on Unix, but we try to sanitize the terminal
-	// when evaluation finishes.
-	return func() { sanitize(in, out) }
+	// There is nothing to set up on Unix, but we save the terminal attributes
+	// and try to sanitize the terminal when evaluation finishes.
+	fd := int(in.Fd())
+	savedTermios, err := eunix.TermiosForFd(fd)
+	if err != nil {
+		return func() { sanitize(in, out) }
+	}
+	return func() {
+		sanitize(in, out)
+		restoreIfBroken(fd, out, savedTermios)
+	}
 }
 
+// Restores the saved terminal attributes if the evaluation has left the
+// terminal in non-canonical mode or with echo turned off. This typically
+// happens when a full-screen program was killed before it could restore the
+// terminal itself; in that case the screen state is likely also broken, so
+// also reset it.
+//
+// Other changes to the terminal attributes, like those made with stty, are
+// kept.
+func restoreIfBroken(fd int, out *os.File, saved *eunix.Termios) {
+	term, err := eunix.TermiosForFd(fd)
+	if err != nil || (term.ICanon() == saved.ICanon() && term.Echo() == saved.Echo()) {
+		return
+	}
+	saved.ApplyToFd(fd)
+	if !IsDumb() {
+		out.WriteString(resetScreen)
+	}
+}
+
+// Leaves the alternate screen, shows the cursor and resets the cursor keys and
+// keypad to normal mode.
+const resetScreen = "\033[?1049l\033[?25h\033[?1l\033>"
+
 func sanitize(in, out *os.File) {
 	// Some programs use non-blocking IO but do not correctly clear the
 	// non-blocking flags after exiting, so we always clear the flag. See #822
@@ -64,3 +95,6 @@ func sanitize(in, out *os.File) {
 	unix.SetNonblock(int(in.Fd()), false)
 	unix.SetNonblock(int(out.Fd()), false)
 }
+
+// Mouse events are reported as escape sequences, which the Reader decodes.
+const vtMouse = true
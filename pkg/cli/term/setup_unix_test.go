@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/creack/pty"
+	"src.elv.sh/pkg/sys/eunix"
 )
 
 func TestSetupTerminal(t *testing.T) {
@@ -24,3 +25,31 @@ func TestSetupTerminal(t *testing.T) {
 	// set.
 	// termios, err := sys.TermiosForFd(int(tty.Fd()))
 }
+
+func TestSetupForEval_RestoresBrokenTerminal(t *testing.T) {
+	pty, tty, err := pty.Open()
+	if err != nil {
+		t.Skip("cannot open pty for testing setupForEval")
+	}
+	defer pty.Close()
+	defer tty.Close()
+	fd := int(tty.Fd())
+
+	restore := setupForEval(tty, tty)
+	// Simulate a full-screen program that was killed without restoring the
+	// terminal.
+	broken, _ := eunix.TermiosForFd(fd)
+	broken.SetICanon(false)
+	broken.SetEcho(false)
+	broken.ApplyToFd(fd)
+	restore()
+
+	term, err := eunix.TermiosForFd(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !term.ICanon() || !term.Echo() {
+		t.Errorf("terminal not restored: icanon = %v, echo = %v",
+			term.ICanon(), term.Echo())
+	}
+}
@@ -52,3 +52,7 @@ func setupForEval(_, out *os.File) func() {
 	}
 	return func() {}
 }
+
+// Mouse events are reported as console input events instead of escape
+// sequences, and are not supported by the Reader yet.
+const vtMouse = false
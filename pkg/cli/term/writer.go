@@ -4,6 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 var logWriterDetail = false
@@ -23,6 +27,26 @@ type Writer interface {
 	ShowCursor()
 	// HideCursor hides the cursor.
 	HideCursor()
+	// NotifyDesktop asks the terminal to show a desktop notification with the
+	// OSC 9 sequence. Terminals that don't support it ignore the sequence.
+	NotifyDesktop(msg string)
+	// SetTitle sets the title of the terminal window or tab with the OSC 2
+	// sequence.
+	SetTitle(title string)
+	// ReportCwd tells the terminal the working directory with the OSC 7
+	// sequence, which some terminals use to open new windows or tabs in the
+	// same directory.
+	ReportCwd(dir string)
+	// MarkSemanticPrompt writes an OSC 133 sequence with the given mark, like
+	// "A" or "D;0", which terminals use to find prompts and command outputs.
+	MarkSemanticPrompt(mark string)
+	// SetMouseTracking turns SGR-style mouse tracking on or off. When it is on,
+	// the terminal reports mouse events to the Reader instead of using them
+	// for selecting text. It does nothing on Windows.
+	SetMouseTracking(on bool)
+	// RequestCursorPosition asks the terminal to report the position of the
+	// cursor, which the Reader reads as a CursorPosition event.
+	RequestCursorPosition()
 }
 
 // writer renders the editor UI.
@@ -64,25 +88,28 @@ func deltaPos(from, to Pos) []byte {
 }
 
 const (
-	hideCursor = "\033[?25l"
-	showCursor = "\033[?25h"
+	hideCursor       = "\033[?25l"
+	showCursor       = "\033[?25h"
+	saveCursorPos    = "\0337"
+	restoreCursorPos = "\0338"
 )
 
 // UpdateBuffer updates the terminal display to reflect current buffer.
 func (w *writer) UpdateBuffer(bufNoti, buf *Buffer, fullRefresh bool) error {
-	if buf.Width != w.curBuf.Width && w.curBuf.Lines != nil {
-		// Width change, force full refresh
-		w.curBuf.Lines = nil
-		fullRefresh = true
-	}
-
 	bytesBuf := new(bytes.Buffer)
 
 	bytesBuf.WriteString(hideCursor)
 
 	// Rewind cursor
-	if pLine := w.curBuf.Dot.Line; pLine > 0 {
-		fmt.Fprintf(bytesBuf, "\033[%dA", pLine)
+	if buf.Width != w.curBuf.Width && w.curBuf.Lines != nil {
+		// Width change, force full refresh. The terminal may have reflowed the
+		// old buffer, so the number of lines above the dot is unknown; go back
+		// to the position saved when the old buffer was written instead.
+		bytesBuf.WriteString(restoreCursorPos)
+		w.curBuf.Lines = nil
+		fullRefresh = true
+	} else if w.curBuf.Dot.Line > 0 {
+		fmt.Fprintf(bytesBuf, "\033[%dA", w.curBuf.Dot.Line)
 	}
 	bytesBuf.WriteString("\r")
 
@@ -169,8 +196,13 @@ func (w *writer) UpdateBuffer(bufNoti, buf *Buffer, fullRefresh bool) error {
 		bytesBuf.WriteString("\n\033[J\033[A")
 	}
 	switchStyle("")
-	cursor := buf.Cursor()
-	bytesBuf.Write(deltaPos(cursor, buf.Dot))
+	// Save the position of the top left corner of the buffer, to be used when
+	// the width changes. Terminals that reflow their content when resized move
+	// the saved position along with the content, and terminals that truncate
+	// lines leave it in place, so it is correct in both cases.
+	bytesBuf.Write(deltaPos(buf.Cursor(), Pos{}))
+	bytesBuf.WriteString(saveCursorPos)
+	bytesBuf.Write(deltaPos(Pos{}, buf.Dot))
 
 	// Show cursor.
 	bytesBuf.WriteString(showCursor)
@@ -196,6 +228,62 @@ func (w *writer) ShowCursor() {
 	fmt.Fprint(w.file, showCursor)
 }
 
+func (w *writer) NotifyDesktop(msg string) {
+	fmt.Fprintf(w.file, "\033]9;%s\007", sanitizeOSC(msg))
+}
+
+func (w *writer) SetTitle(title string) {
+	fmt.Fprintf(w.file, "\033]2;%s\007", sanitizeOSC(title))
+}
+
+func (w *writer) ReportCwd(dir string) {
+	host, _ := os.Hostname()
+	path := filepath.ToSlash(dir)
+	if !strings.HasPrefix(path, "/") {
+		// Windows paths like C:/foo.
+		path = "/" + path
+	}
+	u := url.URL{Scheme: "file", Host: host, Path: path}
+	fmt.Fprintf(w.file, "\033]7;%s\007", u.String())
+}
+
+func (w *writer) MarkSemanticPrompt(mark string) {
+	fmt.Fprintf(w.file, "\033]133;%s\007", sanitizeOSC(mark))
+}
+
+const (
+	enableMouseTracking  = "\033[?1000;1006h"
+	disableMouseTracking = "\033[?1000;1006l"
+)
+
+func (w *writer) SetMouseTracking(on bool) {
+	if !vtMouse {
+		return
+	}
+	if on {
+		fmt.Fprint(w.file, enableMouseTracking)
+	} else {
+		fmt.Fprint(w.file, disableMouseTracking)
+	}
+}
+
+func (w *writer) RequestCursorPosition() {
+	fmt.Fprint(w.file, "\033[6n")
+}
+
+// Replaces control characters, which would terminate an OSC sequence early,
+// with spaces. This includes the C1 control characters, since some terminals
+// treat U+009C as the string terminator. Invalid UTF-8 bytes are replaced with
+// U+FFFD by strings.Map.
+func sanitizeOSC(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || (0x7f <= r && r <= 0x9f) {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
 func (w *writer) ClearScreen() {
 	fmt.Fprint(w.file,
 		"\033[H",  // move cursor to the top left corner
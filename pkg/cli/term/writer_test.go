@@ -1,6 +1,7 @@
 package term
 
 import (
+	"os"
 	"strings"
 	"testing"
 )
@@ -20,5 +21,47 @@ func TestWriter(t *testing.T) {
 		NewBufferBuilder(10).Write("note 1").Buffer(),
 		NewBufferBuilder(10).Write("line 1").SetDotHere().Buffer(),
 		false)
-	testOutput(hideCursor + "\rnote 1\033[K\n" + "line 1\r\033[6C" + showCursor)
+	testOutput(hideCursor + "\rnote 1\033[K\n" + "line 1" +
+		"\r" + saveCursorPos + "\r\033[6C" + showCursor)
+
+	w.NotifyDesktop("done\a\nok")
+	testOutput("\033]9;done  ok\007")
+
+	w.SetTitle("vim\tfoo")
+	testOutput("\033]2;vim foo\007")
+
+	w.SetTitle("a\u009cb\u0080c\x9cd")
+	testOutput("\033]2;a b c\ufffdd\007")
+
+	host, _ := os.Hostname()
+	w.ReportCwd("/tmp/a b")
+	testOutput("\033]7;file://" + host + "/tmp/a%20b\007")
+
+	w.MarkSemanticPrompt("D;1")
+	testOutput("\033]133;D;1\007")
+}
+
+func TestWriter_RewindsToSavedPositionWhenWidthChanges(t *testing.T) {
+	sb := &strings.Builder{}
+	w := NewWriter(sb)
+	w.UpdateBuffer(nil,
+		NewBufferBuilder(10).Write("0123456789").Newline().
+			Write("ab").SetDotHere().Buffer(),
+		false)
+	// The position of the top left corner is saved after writing the buffer.
+	want := hideCursor + "\r0123456789\nab" +
+		"\033[1A\r" + saveCursorPos + "\033[1B\r\033[2C" + showCursor
+	if sb.String() != want {
+		t.Errorf("got %q, want %q", sb.String(), want)
+	}
+	sb.Reset()
+
+	// The first line may or may not take up two lines of the narrowed
+	// terminal, so the saved position is used instead of rewinding.
+	w.UpdateBuffer(nil, NewBufferBuilder(5).Write("ab").SetDotHere().Buffer(), false)
+	want = hideCursor + restoreCursorPos + "\r \033[J\r" + "ab" +
+		"\r" + saveCursorPos + "\r\033[2C" + showCursor
+	if sb.String() != want {
+		t.Errorf("got %q, want %q", sb.String(), want)
+	}
 }
@@ -46,8 +46,21 @@ type CodeAreaSpec struct {
 	// should be quoted. If this function is not given, the Widget defaults to
 	// not quoting pasted texts.
 	QuotePaste func() bool
+	// A function that returns whether the bracket at or just before the dot,
+	// and the bracket matching it, should be highlighted with the
+	// "matching-bracket" styling. If this function is not given, the Widget
+	// defaults to not highlighting matching brackets.
+	HighlightBrackets func() bool
+	// A function that returns whether closing brackets and quotes should be
+	// inserted automatically along with opening ones, and skipped over when
+	// typed. If this function is not given, the Widget defaults to not
+	// auto-pairing.
+	AutoPair func() bool
 	// A function that is called on the submit event.
 	OnSubmit func()
+	// Styles for the "pending" and "matching-bracket" parts. If nil, the
+	// default stylings are used.
+	Styles Styles
 
 	// State. When used in New, this field specifies the initial state.
 	State CodeAreaState
@@ -138,6 +151,12 @@ func NewCodeArea(spec CodeAreaSpec) CodeArea {
 	if spec.QuotePaste == nil {
 		spec.QuotePaste = func() bool { return false }
 	}
+	if spec.HighlightBrackets == nil {
+		spec.HighlightBrackets = func() bool { return false }
+	}
+	if spec.AutoPair == nil {
+		spec.AutoPair = func() bool { return false }
+	}
 	if spec.OnSubmit == nil {
 		spec.OnSubmit = func() {}
 	}
@@ -357,7 +376,11 @@ func (w *codeArea) handleKeyEvent(key ui.Key) bool {
 		return true
 	case ui.K(ui.Backspace), ui.K('H', ui.Ctrl):
 		w.resetInserts()
+		autoPair := w.AutoPair()
 		w.MutateState(func(s *CodeAreaState) {
+			if autoPair && w.autoUnpair() {
+				return
+			}
 			c := &s.Buffer
 			// Remove the last rune.
 			_, chop := utf8.DecodeLastRuneInString(c.Content[:c.Dot])
@@ -380,6 +403,11 @@ func (w *codeArea) handleKeyEvent(key ui.Key) bool {
 			w.resetInserts()
 		}
 		s := string(key.Rune)
+		if w.AutoPair() && w.autoPair(key.Rune) {
+			// Auto-pairing interrupts abbreviations.
+			w.resetInserts()
+			return true
+		}
 		w.State.Buffer.InsertAtDot(s)
 		w.inserts += s
 		w.lastCodeBuffer = w.State.Buffer
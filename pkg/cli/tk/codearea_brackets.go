@@ -0,0 +1,162 @@
+package tk
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"src.elv.sh/pkg/parse"
+)
+
+// Closing counterparts of brackets and quotes that are inserted automatically
+// when auto-pairing is enabled.
+var autoPairs = map[rune]rune{
+	'(': ')', '[': ']', '{': '}', '\'': '\'', '"': '"',
+}
+
+func parseForBrackets(code string) parse.Tree {
+	// Parse errors are ignored, since the parser still returns a tree for
+	// incomplete code, which is exactly what we are dealing with most of the
+	// time.
+	tree, _ := parse.Parse(parse.Source{Name: "[codearea]", Code: code}, parse.Config{Recover: true})
+	return tree
+}
+
+// Returns the position of the bracket at or just before dot, and the position
+// of the bracket matching it. Brackets are paired using the parse tree, so
+// brackets in string literals or comments are never matched.
+func matchingBracket(code string, dot int) (int, int, bool) {
+	var pairs [][2]int
+	collectBracketPairs(parseForBrackets(code).Root, &pairs)
+	// Prefer the bracket at the dot over the one before it.
+	for _, pos := range []int{dot, dot - 1} {
+		for _, pair := range pairs {
+			switch pos {
+			case pair[0]:
+				return pair[0], pair[1], true
+			case pair[1]:
+				return pair[1], pair[0], true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// Collects the positions of pairs of brackets in n and its descendants. The
+// opening and closing brackets of a pair are always separators that are
+// children of the same node.
+func collectBracketPairs(n parse.Node, pairs *[][2]int) {
+	var openers []int
+	for _, ch := range parse.Children(n) {
+		if sep, ok := ch.(*parse.Sep); ok {
+			switch text := parse.SourceText(sep); text {
+			case "(", "[", "{", "?(":
+				// The opening bracket is the last byte of the separator.
+				openers = append(openers, sep.Range().To-1)
+			case ")", "]", "}":
+				if len(openers) > 0 {
+					opener := openers[len(openers)-1]
+					openers = openers[:len(openers)-1]
+					*pairs = append(*pairs, [2]int{opener, sep.Range().From})
+				}
+			}
+		}
+		collectBracketPairs(ch, pairs)
+	}
+}
+
+// Returns the string literal or comment that contains pos, or nil if there is
+// none. A position is contained in a string literal if it is after the opening
+// quote and before the closing quote, and in a comment if it is after the "#".
+func quotedOrCommentAt(code string, pos int) parse.Node {
+	var found parse.Node
+	var walk func(n parse.Node)
+	walk = func(n parse.Node) {
+		r := n.Range()
+		if pos <= r.From || pos > r.To {
+			return
+		}
+		switch n := n.(type) {
+		case *parse.Primary:
+			if (n.Type == parse.SingleQuoted || n.Type == parse.DoubleQuoted) &&
+				(pos < r.To || !closedQuote(parse.SourceText(n))) {
+				found = n
+				return
+			}
+		case *parse.Sep:
+			if strings.HasPrefix(parse.SourceText(n), "#") {
+				found = n
+				return
+			}
+		}
+		for _, ch := range parse.Children(n) {
+			walk(ch)
+		}
+	}
+	walk(parseForBrackets(code).Root)
+	return found
+}
+
+// Reports whether the source text of a quoted string has its closing quote.
+func closedQuote(text string) bool {
+	return len(text) >= 2 && text[len(text)-1] == text[0] &&
+		!strings.HasSuffix(text[:len(text)-1], `\`)
+}
+
+// Handles the insertion of r when auto-pairing is enabled, and returns whether
+// it has been handled. This function assumes the state mutex is held.
+func (w *codeArea) autoPair(r rune) bool {
+	buf := &w.State.Buffer
+	next, _ := utf8.DecodeRuneInString(buf.Content[buf.Dot:])
+
+	// Skip over a closing bracket or quote that matches what is being typed,
+	// instead of inserting another one.
+	if next == r {
+		switch r {
+		case ')', ']', '}':
+			if from, _, ok := matchingBracket(buf.Content, buf.Dot); ok && from == buf.Dot {
+				buf.Dot++
+				return true
+			}
+		case '\'', '"':
+			if n := quotedOrCommentAt(buf.Content, buf.Dot); n != nil && n.Range().To == buf.Dot+1 {
+				buf.Dot++
+				return true
+			}
+		}
+	}
+
+	closer, ok := autoPairs[r]
+	if !ok || !beforeClosingContext(next) || quotedOrCommentAt(buf.Content, buf.Dot) != nil {
+		return false
+	}
+	*buf = CodeBuffer{
+		Content: buf.Content[:buf.Dot] + string(r) + string(closer) + buf.Content[buf.Dot:],
+		Dot:     buf.Dot + len(string(r)),
+	}
+	return true
+}
+
+// Reports whether a closing bracket or quote can be inserted before next,
+// which is the rune after the dot, or utf8.RuneError at the end of the buffer.
+func beforeClosingContext(next rune) bool {
+	return next == utf8.RuneError || parse.IsWhitespace(next) ||
+		strings.ContainsRune(")]};|", next)
+}
+
+// Handles the deletion of the rune before the dot when auto-pairing is enabled,
+// and returns whether it has been handled. An opening bracket or quote
+// immediately followed by its automatically inserted counterpart is deleted
+// together with it. This function assumes the state mutex is held.
+func (w *codeArea) autoUnpair() bool {
+	buf := &w.State.Buffer
+	prev, prevLen := utf8.DecodeLastRuneInString(buf.Content[:buf.Dot])
+	next, nextLen := utf8.DecodeRuneInString(buf.Content[buf.Dot:])
+	if closer, ok := autoPairs[prev]; !ok || next != closer {
+		return false
+	}
+	*buf = CodeBuffer{
+		Content: buf.Content[:buf.Dot-prevLen] + buf.Content[buf.Dot+nextLen:],
+		Dot:     buf.Dot - prevLen,
+	}
+	return true
+}
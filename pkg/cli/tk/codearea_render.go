@@ -15,8 +15,6 @@ type view struct {
 	tips    []ui.Text
 }
 
-var stylingForPending = ui.Underlined
-
 func getView(w *codeArea) *view {
 	s := w.CopyState()
 	code, pFrom, pTo := patchPending(s.Buffer, s.Pending)
@@ -25,11 +23,17 @@ func getView(w *codeArea) *view {
 		errors = nil
 	}
 	if pFrom < pTo {
-		// Apply stylingForPending to [pFrom, pTo)
+		// Apply the pending styling to [pFrom, pTo)
 		parts := styledCode.Partition(pFrom, pTo)
-		pending := ui.StyleText(parts[1], stylingForPending)
+		pending := ui.StyleText(parts[1], w.Styles.For("pending"))
 		styledCode = ui.Concat(parts[0], pending, parts[2])
 	}
+	if w.HighlightBrackets() {
+		if from, to, ok := matchingBracket(code.Content, code.Dot); ok {
+			styledCode = styleByte(styledCode, from, w.Styles.For("matching-bracket"))
+			styledCode = styleByte(styledCode, to, w.Styles.For("matching-bracket"))
+		}
+	}
 
 	var rprompt ui.Text
 	if !s.HideRPrompt {
@@ -39,6 +43,12 @@ func getView(w *codeArea) *view {
 	return &view{w.Prompt(), rprompt, styledCode, code.Dot, errors}
 }
 
+// Applies styling to the byte at pos, which must be a single-byte rune.
+func styleByte(t ui.Text, pos int, styling ui.Styling) ui.Text {
+	parts := t.Partition(pos, pos+1)
+	return ui.Concat(parts[0], ui.StyleText(parts[1], styling), parts[2])
+}
+
 func patchPending(c CodeBuffer, p PendingCode) (CodeBuffer, int, int) {
 	if p.From > p.To || p.From < 0 || p.To > len(c.Content) {
 		// Invalid Pending.
@@ -193,6 +193,42 @@ var codeAreaRenderTests = []renderTest{
 		Want: bb(10).Write("a").Newline().Write("b").SetDotHere().
 			Newline().Write("c"),
 	},
+	{
+		Name: "matching brackets are highlighted when the dot is at a bracket",
+		Given: NewCodeArea(CodeAreaSpec{
+			HighlightBrackets: func() bool { return true },
+			State: CodeAreaState{
+				Buffer: CodeBuffer{Content: "x (a [b])", Dot: 5}}}),
+		Width: 10, Height: 24,
+		Want: bb(10).Write("x (a ").SetDotHere().WriteStringSGR("[", "1;4").
+			Write("b").WriteStringSGR("]", "1;4").Write(")"),
+	},
+	{
+		Name: "matching brackets are highlighted when the dot is after a bracket",
+		Given: NewCodeArea(CodeAreaSpec{
+			HighlightBrackets: func() bool { return true },
+			State: CodeAreaState{
+				Buffer: CodeBuffer{Content: "x (a [b])", Dot: 9}}}),
+		Width: 10, Height: 24,
+		Want: bb(10).Write("x ").WriteStringSGR("(", "1;4").Write("a [b]").
+			WriteStringSGR(")", "1;4").SetDotHere(),
+	},
+	{
+		Name: "brackets in string literals are not highlighted",
+		Given: NewCodeArea(CodeAreaSpec{
+			HighlightBrackets: func() bool { return true },
+			State: CodeAreaState{
+				Buffer: CodeBuffer{Content: "x '(' ')'", Dot: 3}}}),
+		Width: 10, Height: 24,
+		Want: bb(10).Write("x '").SetDotHere().Write("(' ')'"),
+	},
+	{
+		Name: "matching brackets are not highlighted by default",
+		Given: NewCodeArea(CodeAreaSpec{State: CodeAreaState{
+			Buffer: CodeBuffer{Content: "(a)", Dot: 0}}}),
+		Width: 10, Height: 24,
+		Want: bb(10).SetDotHere().Write("(a)"),
+	},
 }
 
 func TestCodeArea_Render(t *testing.T) {
@@ -416,6 +452,44 @@ var codeAreaHandleTests = []handleTest{
 		Events:       []term.Event{term.K('x'), term.K(' '), term.K('e'), term.K('h'), term.K(' ')},
 		WantNewState: CodeAreaState{Buffer: CodeBuffer{Content: "x eh ", Dot: 5}},
 	},
+	{
+		Name:         "auto-pairing brackets and quotes",
+		Given:        NewCodeArea(CodeAreaSpec{AutoPair: func() bool { return true }}),
+		Events:       []term.Event{term.K('('), term.K('['), term.K('\'')},
+		WantNewState: CodeAreaState{Buffer: CodeBuffer{Content: "([''])", Dot: 3}},
+	},
+	{
+		Name:  "auto-pairing skips over closing brackets and quotes",
+		Given: NewCodeArea(CodeAreaSpec{AutoPair: func() bool { return true }}),
+		Events: []term.Event{
+			term.K('{'), term.K(' '), term.K('"'), term.K('x'), term.K('"'),
+			term.K(' '), term.K('}')},
+		WantNewState: CodeAreaState{Buffer: CodeBuffer{Content: `{ "x" }`, Dot: 7}},
+	},
+	{
+		Name: "auto-pairing does not apply in string literals",
+		Given: NewCodeArea(CodeAreaSpec{
+			AutoPair: func() bool { return true },
+			State: CodeAreaState{
+				Buffer: CodeBuffer{Content: "echo 'a", Dot: 7}}}),
+		Events:       []term.Event{term.K('(')},
+		WantNewState: CodeAreaState{Buffer: CodeBuffer{Content: "echo 'a(", Dot: 8}},
+	},
+	{
+		Name: "auto-pairing does not apply before a word",
+		Given: NewCodeArea(CodeAreaSpec{
+			AutoPair: func() bool { return true },
+			State: CodeAreaState{
+				Buffer: CodeBuffer{Content: "echo a", Dot: 5}}}),
+		Events:       []term.Event{term.K('(')},
+		WantNewState: CodeAreaState{Buffer: CodeBuffer{Content: "echo (a", Dot: 6}},
+	},
+	{
+		Name:         "backspace deletes auto-paired brackets together",
+		Given:        NewCodeArea(CodeAreaSpec{AutoPair: func() bool { return true }}),
+		Events:       []term.Event{term.K('('), term.K(ui.Backspace)},
+		WantNewState: CodeAreaState{},
+	},
 	{
 		Name: "key bindings",
 		Given: NewCodeArea(CodeAreaSpec{Bindings: MapBindings{
@@ -49,6 +49,10 @@ type colView struct {
 	// Mutex for synchronizing access to State.
 	StateMutex sync.RWMutex
 	ColViewSpec
+
+	// The x offsets and widths of the columns as last rendered, protected by
+	// StateMutex.
+	xs, widths []int
 }
 
 // NewColView creates a new ColView from the given spec.
@@ -96,6 +100,12 @@ const colViewColGap = 1
 // column.
 func (w *colView) Render(width, height int) *term.Buffer {
 	cols, widths := w.prepareRender(width)
+	xs := make([]int, len(cols))
+	defer func() {
+		w.StateMutex.Lock()
+		defer w.StateMutex.Unlock()
+		w.xs, w.widths = xs, widths
+	}()
 	if len(cols) == 0 {
 		return &term.Buffer{Width: width}
 	}
@@ -104,6 +114,7 @@ func (w *colView) Render(width, height int) *term.Buffer {
 		if i > 0 {
 			buf.Width += colViewColGap
 		}
+		xs[i] = buf.Width
 		bufCol := col.Render(widths[i], height)
 		buf.ExtendRight(bufCol)
 	}
@@ -140,10 +151,16 @@ func (w *colView) prepareRender(width int) ([]Widget, []int) {
 
 // Handle handles the event first by consulting the overlay handler, and then
 // delegating the event to the currently focused column.
+//
+// Clicking a column to the left or right of the focused column calls Left or
+// Right. Other mouse events are delegated to the focused column.
 func (w *colView) Handle(event term.Event) bool {
 	if w.Bindings.Handle(w, event) {
 		return true
 	}
+	if e, ok := event.(term.MouseEvent); ok {
+		return w.handleMouse(e)
+	}
 	state := w.CopyState()
 	if 0 <= state.FocusColumn && state.FocusColumn < len(state.Columns) {
 		if state.Columns[state.FocusColumn].Handle(event) {
@@ -163,6 +180,32 @@ func (w *colView) Handle(event term.Event) bool {
 	}
 }
 
+func (w *colView) handleMouse(e term.MouseEvent) bool {
+	state := w.CopyState()
+	if state.FocusColumn < 0 || state.FocusColumn >= len(state.Columns) {
+		return false
+	}
+	w.StateMutex.RLock()
+	xs, widths := w.xs, w.widths
+	w.StateMutex.RUnlock()
+	if len(xs) != len(state.Columns) {
+		// Not rendered since the columns changed.
+		return false
+	}
+	focusX := xs[state.FocusColumn]
+	if e.Button == 0 && e.Down {
+		if e.Col < focusX {
+			w.Left()
+			return true
+		} else if e.Col >= focusX+widths[state.FocusColumn] {
+			w.Right()
+			return true
+		}
+	}
+	e.Col -= focusX
+	return state.Columns[state.FocusColumn].Handle(e)
+}
+
 func (w *colView) Left() {
 	w.OnLeft(w)
 }
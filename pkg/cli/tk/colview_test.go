@@ -1,6 +1,7 @@
 package tk
 
 import (
+	"reflect"
 	"testing"
 
 	"src.elv.sh/pkg/cli/term"
@@ -122,6 +123,46 @@ func TestColView_Handle(t *testing.T) {
 	expectUnhandled(term.K('b'))
 }
 
+func TestColView_Handle_Mouse(t *testing.T) {
+	var moved []string
+	w := NewColView(ColViewSpec{
+		State: ColViewState{
+			Columns: []Widget{
+				makeListbox("x", 3, 0),
+				makeListbox("y", 3, 0),
+				makeListbox("z", 3, 0),
+			},
+			FocusColumn: 1,
+		},
+		OnLeft:  func(ColView) { moved = append(moved, "left") },
+		OnRight: func(ColView) { moved = append(moved, "right") },
+	})
+	// The columns are 10 wide with a gap of 1, so they start at 0, 11 and 22.
+	w.Render(32, 3)
+
+	click := func(line, col int) {
+		t.Helper()
+		if !w.Handle(term.MouseEvent{Pos: term.Pos{Line: line, Col: col}, Down: true}) {
+			t.Errorf("click at (%d, %d) not handled", line, col)
+		}
+	}
+
+	click(2, 13)
+	focused := w.CopyState().Columns[1].(ListBox)
+	if selected := focused.CopyState().Selected; selected != 2 {
+		t.Errorf("got selected %d in the focused column, want 2", selected)
+	}
+	w.Handle(term.MouseEvent{Down: true, Button: term.ScrollUp})
+	if selected := focused.CopyState().Selected; selected != 1 {
+		t.Errorf("got selected %d after scrolling up, want 1", selected)
+	}
+	click(0, 5)
+	click(0, 25)
+	if want := []string{"left", "right"}; !reflect.DeepEqual(moved, want) {
+		t.Errorf("got moves %v, want %v", moved, want)
+	}
+}
+
 func TestDistribute(t *testing.T) {
 	tt.Test(t, distribute,
 		// Nice integer distributions.
@@ -29,6 +29,8 @@ type comboBox struct {
 
 	// Last filter value.
 	lastFilter string
+	// Height of the codearea as last rendered.
+	codeAreaHeight int
 }
 
 // NewComboBox creates a new ComboBox from the given spec.
@@ -48,6 +50,7 @@ func NewComboBox(spec ComboBoxSpec) ComboBox {
 // Render renders the codearea and the listbox below it.
 func (w *comboBox) Render(width, height int) *term.Buffer {
 	buf := w.codeArea.Render(width, height)
+	w.codeAreaHeight = len(buf.Lines)
 	bufListBox := w.listBox.Render(width, height-len(buf.Lines))
 	buf.Extend(bufListBox, false)
 	return buf
@@ -60,7 +63,17 @@ func (w *comboBox) MaxHeight(width, height int) int {
 // Handle first lets the listbox handle the event, and if it is unhandled, lets
 // the codearea handle it. If the codearea has handled the event and the code
 // content has changed, it calls OnFilter with the new content.
+//
+// Mouse events are only sent to the listbox, and only if they are below the
+// codearea.
 func (w *comboBox) Handle(event term.Event) bool {
+	if e, ok := event.(term.MouseEvent); ok {
+		if e.Line < w.codeAreaHeight {
+			return false
+		}
+		e.Line -= w.codeAreaHeight
+		return w.listBox.Handle(e)
+	}
 	if w.listBox.Handle(event) {
 		return true
 	}
@@ -90,6 +90,27 @@ func TestComboBox_Handle(t *testing.T) {
 	}
 }
 
+func TestComboBox_Handle_Mouse(t *testing.T) {
+	w := NewComboBox(ComboBoxSpec{
+		ListBox: ListBoxSpec{
+			State: ListBoxState{Items: TestItems{NItems: 3}}}})
+	// The codearea takes up the first line.
+	w.Render(10, 4)
+
+	handled := w.Handle(term.MouseEvent{Pos: term.Pos{Line: 2}, Down: true})
+	if !handled {
+		t.Errorf("click on the listbox not handled")
+	}
+	if selected := w.ListBox().CopyState().Selected; selected != 1 {
+		t.Errorf("got selected %d, want 1", selected)
+	}
+
+	handled = w.Handle(term.MouseEvent{Pos: term.Pos{Line: 0}, Down: true})
+	if handled {
+		t.Errorf("click on the codearea handled")
+	}
+}
+
 func TestRefilter(t *testing.T) {
 	onFilter := make(chan string, 100)
 	w := NewComboBox(ComboBoxSpec{
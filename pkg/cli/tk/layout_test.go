@@ -8,6 +8,13 @@ import (
 	"src.elv.sh/pkg/ui"
 )
 
+var (
+	vscrollbarThumb  = ui.T(" ", ui.FgMagenta, ui.Inverse)
+	vscrollbarTrough = ui.T("│", ui.FgMagenta)
+	hscrollbarThumb  = ui.T(" ", ui.FgMagenta, ui.Inverse)
+	hscrollbarTrough = ui.T("━", ui.FgMagenta)
+)
+
 var layoutRenderTests = []struct {
 	name     string
 	renderer Renderer
@@ -35,57 +42,57 @@ var layoutRenderTests = []struct {
 	},
 	{
 		"VScrollbar showing full thumb",
-		VScrollbar{4, 0, 3},
+		VScrollbar{Total: 4, Low: 0, High: 3},
 		10, 2,
 		bb(1).WriteStyled(vscrollbarThumb).WriteStyled(vscrollbarThumb),
 	},
 	{
 		"VScrollbar showing thumb in first half",
-		VScrollbar{4, 0, 1},
+		VScrollbar{Total: 4, Low: 0, High: 1},
 		10, 2,
 		bb(1).WriteStyled(vscrollbarThumb).WriteStyled(vscrollbarTrough),
 	},
 	{
 		"VScrollbar showing a minimal 1-size thumb at beginning",
-		VScrollbar{4, 0, 0},
+		VScrollbar{Total: 4, Low: 0, High: 0},
 		10, 2,
 		bb(1).WriteStyled(vscrollbarThumb).WriteStyled(vscrollbarTrough),
 	},
 	{
 		"VScrollbar showing a minimal 1-size thumb at end",
-		VScrollbar{4, 3, 3},
+		VScrollbar{Total: 4, Low: 3, High: 3},
 		10, 2,
 		bb(1).WriteStyled(vscrollbarTrough).WriteStyled(vscrollbarThumb),
 	},
 	{
 		"VScrollbarContainer",
 		VScrollbarContainer{Label{ui.T("abcd1234")},
-			VScrollbar{4, 0, 1}},
+			VScrollbar{Total: 4, Low: 0, High: 1}},
 		5, 2,
 		bb(5).Write("abcd").WriteStyled(vscrollbarThumb).
 			Newline().Write("1234").WriteStyled(vscrollbarTrough),
 	},
 	{
 		"HScrollbar showing full thumb",
-		HScrollbar{4, 0, 3},
+		HScrollbar{Total: 4, Low: 0, High: 3},
 		2, 10,
 		bb(2).WriteStyled(hscrollbarThumb).WriteStyled(hscrollbarThumb),
 	},
 	{
 		"HScrollbar showing thumb in first half",
-		HScrollbar{4, 0, 1},
+		HScrollbar{Total: 4, Low: 0, High: 1},
 		2, 10,
 		bb(2).WriteStyled(hscrollbarThumb).WriteStyled(hscrollbarTrough),
 	},
 	{
 		"HScrollbar showing a minimal 1-size thumb at beginning",
-		HScrollbar{4, 0, 0},
+		HScrollbar{Total: 4, Low: 0, High: 0},
 		2, 10,
 		bb(2).WriteStyled(hscrollbarThumb).WriteStyled(hscrollbarTrough),
 	},
 	{
 		"HScrollbar showing a minimal 1-size thumb at end",
-		HScrollbar{4, 3, 3},
+		HScrollbar{Total: 4, Low: 3, High: 3},
 		2, 10,
 		bb(2).WriteStyled(hscrollbarTrough).WriteStyled(hscrollbarThumb),
 	},
@@ -45,6 +45,9 @@ type ListBoxSpec struct {
 	// first segment of the item, and the right spacing and padding will be
 	// styled the same as the last segment of the item.
 	ExtendStyle bool
+	// Styles for the "selected" and "scrollbar" parts. If nil, the default
+	// stylings are used.
+	Styles Styles
 
 	// State. When used in [NewListBox], this field specifies the initial state.
 	State ListBoxState
@@ -55,6 +58,20 @@ type listBox struct {
 	StateMutex sync.RWMutex
 	// Configuration and state.
 	ListBoxSpec
+
+	// Where the items were last rendered, protected by StateMutex. Only one of
+	// lineItems and cols is used, depending on the layout.
+	//
+	// The index of the item on each line.
+	lineItems []int
+	// The columns, and the height of each column.
+	cols      []listBoxCol
+	colHeight int
+}
+
+// A column of the horizontal layout, as last rendered.
+type listBoxCol struct {
+	x, width, first int
 }
 
 // NewListBox creates a new ListBox from the given spec.
@@ -76,8 +93,6 @@ func NewListBox(spec ListBoxSpec) ListBox {
 	return &listBox{ListBoxSpec: spec}
 }
 
-var stylingForSelected = ui.Inverse
-
 func (w *listBox) Render(width, height int) *term.Buffer {
 	if w.Horizontal {
 		return w.renderHorizontal(width, height)
@@ -123,6 +138,7 @@ func (w *listBox) renderHorizontal(width, height int) *term.Buffer {
 	})
 
 	if state.Items == nil || state.Items.Len() == 0 {
+		w.setLayout(nil, nil, 0)
 		return Label{Content: w.Placeholder}.Render(width, height)
 	}
 
@@ -133,6 +149,7 @@ func (w *listBox) renderHorizontal(width, height int) *term.Buffer {
 	remainedWidth := width
 	hasCropped := false
 	last := first
+	var cols []listBoxCol
 	for i := first; i < n; i += colHeight {
 		selectedRow := -1
 		// Render the column starting from i.
@@ -155,7 +172,8 @@ func (w *listBox) renderHorizontal(width, height int) *term.Buffer {
 		colBuf := croppedLines{
 			lines: col, padding: w.Padding,
 			selectFrom: selectedRow, selectTo: selectedRow + 1,
-			extendStyle: w.ExtendStyle}.Render(colWidth, colHeight)
+			extendStyle: w.ExtendStyle, styles: w.Styles}.Render(colWidth, colHeight)
+		cols = append(cols, listBoxCol{buf.Width, colWidth, i})
 		buf.ExtendRight(colBuf)
 
 		remainedWidth -= colWidth
@@ -165,10 +183,11 @@ func (w *listBox) renderHorizontal(width, height int) *term.Buffer {
 		remainedWidth -= listBoxColGap
 		buf.Width += listBoxColGap
 	}
+	w.setLayout(nil, cols, colHeight)
 	// We may not have used all the width required; force buffer width.
 	buf.Width = width
 	if colHeight < height && (first != 0 || last != n-1 || hasCropped) {
-		scrollbar := HScrollbar{Total: n, Low: first, High: last + 1}
+		scrollbar := HScrollbar{Total: n, Low: first, High: last + 1, Styles: w.Styles}
 		buf.Extend(scrollbar.Render(width, 1), false)
 	}
 	return buf
@@ -188,12 +207,14 @@ func (w *listBox) renderVertical(width, height int) *term.Buffer {
 	})
 
 	if state.Items == nil || state.Items.Len() == 0 {
+		w.setLayout(nil, nil, 0)
 		return Label{Content: w.Placeholder}.Render(width, height)
 	}
 
 	items, selected, first := state.Items, state.Selected, state.First
 	n := items.Len()
 	allLines := []ui.Text{}
+	lineItems := []int{}
 	hasCropped := firstCrop > 0
 
 	var i, selectFrom, selectTo int
@@ -214,26 +235,63 @@ func (w *listBox) renderVertical(width, height int) *term.Buffer {
 			hasCropped = true
 		}
 		allLines = append(allLines, lines...)
+		for range lines {
+			lineItems = append(lineItems, i)
+		}
 	}
+	w.setLayout(lineItems, nil, 0)
 
 	var rd Renderer = croppedLines{
 		lines: allLines, padding: w.Padding,
-		selectFrom: selectFrom, selectTo: selectTo, extendStyle: w.ExtendStyle}
+		selectFrom: selectFrom, selectTo: selectTo, extendStyle: w.ExtendStyle,
+		styles: w.Styles}
 	if first > 0 || i < n || hasCropped {
 		rd = VScrollbarContainer{
 			Content:   rd,
-			Scrollbar: VScrollbar{Total: n, Low: first, High: i},
+			Scrollbar: VScrollbar{Total: n, Low: first, High: i, Styles: w.Styles},
 		}
 	}
 	return rd.Render(width, height)
 }
 
+func (w *listBox) setLayout(lineItems []int, cols []listBoxCol, colHeight int) {
+	w.StateMutex.Lock()
+	defer w.StateMutex.Unlock()
+	w.lineItems, w.cols, w.colHeight = lineItems, cols, colHeight
+}
+
+// Returns the index of the item rendered at the given position, or -1 if there
+// is none.
+func (w *listBox) itemAt(pos term.Pos) int {
+	w.StateMutex.RLock()
+	defer w.StateMutex.RUnlock()
+	if w.Horizontal {
+		if pos.Line < 0 || pos.Line >= w.colHeight {
+			return -1
+		}
+		for _, col := range w.cols {
+			if col.x <= pos.Col && pos.Col < col.x+col.width {
+				if i := col.first + pos.Line; i < w.State.Items.Len() {
+					return i
+				}
+				return -1
+			}
+		}
+		return -1
+	}
+	if pos.Line < 0 || pos.Line >= len(w.lineItems) {
+		return -1
+	}
+	return w.lineItems[pos.Line]
+}
+
 type croppedLines struct {
 	lines       []ui.Text
 	padding     int
 	selectFrom  int
 	selectTo    int
 	extendStyle bool
+	styles      Styles
 }
 
 func (c croppedLines) Render(width, height int) *term.Buffer {
@@ -261,7 +319,7 @@ func (c croppedLines) Render(width, height int) *term.Buffer {
 			acc = ui.Concat(acc, right).TrimWcwidth(width)
 		}
 		if selected {
-			acc = ui.StyleText(acc, stylingForSelected)
+			acc = ui.StyleText(acc, c.styles.For("selected"))
 		}
 
 		bb.WriteStyled(acc)
@@ -274,6 +332,9 @@ func (w *listBox) Handle(event term.Event) bool {
 		return true
 	}
 
+	if e, ok := event.(term.MouseEvent); ok {
+		return w.handleMouse(e)
+	}
 	switch event {
 	case term.K(ui.Up):
 		w.Select(Prev)
@@ -288,6 +349,34 @@ func (w *listBox) Handle(event term.Event) bool {
 	return false
 }
 
+// Clicking an item selects it, and clicking the selected item accepts it.
+// Scrolling the wheel moves the selection.
+func (w *listBox) handleMouse(e term.MouseEvent) bool {
+	if !e.Down {
+		return false
+	}
+	switch e.Button {
+	case 0:
+		i := w.itemAt(e.Pos)
+		if i < 0 {
+			return false
+		}
+		if i == w.CopyState().Selected {
+			w.Accept()
+		} else {
+			w.Select(func(ListBoxState) int { return i })
+		}
+		return true
+	case term.ScrollUp:
+		w.Select(Prev)
+		return true
+	case term.ScrollDown:
+		w.Select(Next)
+		return true
+	}
+	return false
+}
+
 func (w *listBox) CopyState() ListBoxState {
 	w.StateMutex.RLock()
 	defer w.StateMutex.RUnlock()
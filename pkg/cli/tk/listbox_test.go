@@ -313,6 +313,27 @@ var listBoxHandleTests = []handleTest{
 
 		WantNewState: ListBoxState{Items: TestItems{NItems: 10}, Selected: 5},
 	},
+	{
+		Name:  "scrolling wheel up moving selection up",
+		Given: NewListBox(ListBoxSpec{State: ListBoxState{Items: TestItems{NItems: 10}, Selected: 1}}),
+		Event: term.MouseEvent{Down: true, Button: term.ScrollUp},
+
+		WantNewState: ListBoxState{Items: TestItems{NItems: 10}, Selected: 0},
+	},
+	{
+		Name:  "scrolling wheel down moving selection down",
+		Given: NewListBox(ListBoxSpec{State: ListBoxState{Items: TestItems{NItems: 10}, Selected: 1}}),
+		Event: term.MouseEvent{Down: true, Button: term.ScrollDown},
+
+		WantNewState: ListBoxState{Items: TestItems{NItems: 10}, Selected: 2},
+	},
+	{
+		Name:  "mouse releases not handled",
+		Given: NewListBox(ListBoxSpec{State: ListBoxState{Items: TestItems{NItems: 10}, Selected: 1}}),
+		Event: term.MouseEvent{Down: false, Button: 0},
+
+		WantUnhandled: true,
+	},
 	{
 		Name:  "other keys not handled",
 		Given: NewListBox(ListBoxSpec{State: ListBoxState{Items: TestItems{NItems: 10}, Selected: 5}}),
@@ -357,6 +378,60 @@ func TestListBox_Handle_EnterEmitsAccept(t *testing.T) {
 	}
 }
 
+func TestListBox_Handle_ClickSelectsAndAccepts(t *testing.T) {
+	accepted := -1
+	w := NewListBox(ListBoxSpec{
+		OnAccept: func(it Items, i int) { accepted = i },
+		State: ListBoxState{
+			Items: TestItems{Prefix: "item\n", NItems: 10}, Selected: 0}})
+	w.Render(10, 6)
+
+	click := func(line, col int) bool {
+		return w.Handle(term.MouseEvent{
+			Pos: term.Pos{Line: line, Col: col}, Down: true, Button: 0})
+	}
+	// Lines 2 and 3 are both item 1.
+	if !click(3, 0) {
+		t.Errorf("click on an item not handled")
+	}
+	if selected := w.CopyState().Selected; selected != 1 {
+		t.Errorf("got selected %d, want 1", selected)
+	}
+	if accepted != -1 {
+		t.Errorf("click on an unselected item accepted it")
+	}
+	click(2, 5)
+	if accepted != 1 {
+		t.Errorf("got accepted %d, want 1", accepted)
+	}
+	if click(6, 0) {
+		t.Errorf("click below the items handled")
+	}
+}
+
+func TestListBox_Handle_ClickInHorizontalLayout(t *testing.T) {
+	w := NewListBox(ListBoxSpec{
+		Horizontal: true,
+		State:      ListBoxState{Items: TestItems{NItems: 5}, Selected: 0}})
+	// Columns are 6 wide with a gap of 2, so they start at 0, 8 and 16.
+	w.Render(30, 2)
+
+	click := func(line, col int) bool {
+		return w.Handle(term.MouseEvent{
+			Pos: term.Pos{Line: line, Col: col}, Down: true, Button: 0})
+	}
+	click(1, 9)
+	if selected := w.CopyState().Selected; selected != 3 {
+		t.Errorf("got selected %d, want 3", selected)
+	}
+	if click(0, 6) {
+		t.Errorf("click between columns handled")
+	}
+	if click(1, 17) {
+		t.Errorf("click below the last item handled")
+	}
+}
+
 func TestListBox_Select_ChangeState(t *testing.T) {
 	// number of items = 10, height = 3
 	var tests = []struct {
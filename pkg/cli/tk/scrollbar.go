@@ -23,24 +23,22 @@ type VScrollbar struct {
 	Total int
 	Low   int
 	High  int
+	// Styles for the "scrollbar" part.
+	Styles Styles
 }
 
-var (
-	vscrollbarThumb  = ui.T(" ", ui.FgMagenta, ui.Inverse)
-	vscrollbarTrough = ui.T("│", ui.FgMagenta)
-)
-
 func (v VScrollbar) Render(width, height int) *term.Buffer {
 	posLow, posHigh := findScrollInterval(v.Total, v.Low, v.High, height)
 	bb := term.NewBufferBuilder(1)
+	styling := v.Styles.For("scrollbar")
 	for i := 0; i < height; i++ {
 		if i > 0 {
 			bb.Newline()
 		}
 		if posLow <= i && i < posHigh {
-			bb.WriteStyled(vscrollbarThumb)
+			bb.WriteStyled(ui.T(" ", styling, ui.Inverse))
 		} else {
-			bb.WriteStyled(vscrollbarTrough)
+			bb.WriteStyled(ui.T("│", styling))
 		}
 	}
 	return bb.Buffer()
@@ -51,21 +49,19 @@ type HScrollbar struct {
 	Total int
 	Low   int
 	High  int
+	// Styles for the "scrollbar" part.
+	Styles Styles
 }
 
-var (
-	hscrollbarThumb  = ui.T(" ", ui.FgMagenta, ui.Inverse)
-	hscrollbarTrough = ui.T("━", ui.FgMagenta)
-)
-
 func (h HScrollbar) Render(width, height int) *term.Buffer {
 	posLow, posHigh := findScrollInterval(h.Total, h.Low, h.High, width)
 	bb := term.NewBufferBuilder(width)
+	styling := h.Styles.For("scrollbar")
 	for i := 0; i < width; i++ {
 		if posLow <= i && i < posHigh {
-			bb.WriteStyled(hscrollbarThumb)
+			bb.WriteStyled(ui.T(" ", styling, ui.Inverse))
 		} else {
-			bb.WriteStyled(hscrollbarTrough)
+			bb.WriteStyled(ui.T("━", styling))
 		}
 	}
 	return bb.Buffer()
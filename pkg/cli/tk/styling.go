@@ -0,0 +1,54 @@
+package tk
+
+import (
+	"src.elv.sh/pkg/ui"
+)
+
+// Default stylings for the named parts of widgets:
+//
+//   - "selected": The selected item of a ListBox.
+//
+//   - "pending": The pending text of a CodeArea, like the candidate being
+//     previewed during completion.
+//
+//   - "matching-bracket": The bracket at or just before the cursor of a
+//     CodeArea and the bracket matching it.
+//
+//   - "scrollbar": Scrollbars; the thumb is additionally shown in inverse.
+//
+//   - "mode-line": The mode line of modes implemented in the modes package.
+//
+//   - "error": Error messages shown by modes implemented in the modes package.
+//
+//   - "completion-description": The description of the selected completion
+//     candidate.
+//
+//   - "picker-match": The matched runes of items in the picker mode.
+//
+//   - "stale-prompt": Prompts that are stale, used by the prompt package.
+var defaultStylings = map[string]ui.Styling{
+	"selected":               ui.Inverse,
+	"pending":                ui.Underlined,
+	"matching-bracket":       ui.Stylings(ui.Bold, ui.Underlined),
+	"scrollbar":              ui.FgMagenta,
+	"mode-line":              ui.Stylings(ui.Bold, ui.FgWhite, ui.BgMagenta),
+	"error":                  ui.FgRed,
+	"completion-description": ui.Dim,
+	"picker-match":           ui.Underlined,
+	"stale-prompt":           ui.Inverse,
+}
+
+// Styles looks up the stylings of the named parts of widgets, in order to
+// customize their appearance. A nil Styles, or one that returns nil for a name,
+// uses the default stylings.
+type Styles func(name string) ui.Styling
+
+// For returns the styling of the named part of widgets.
+func (s Styles) For(name string) ui.Styling {
+	if s != nil {
+		if styling := s(name); styling != nil {
+			return styling
+		}
+	}
+	return defaultStylings[name]
+}
@@ -0,0 +1,58 @@
+package tk
+
+import (
+	"reflect"
+	"testing"
+
+	"src.elv.sh/pkg/cli/term"
+	"src.elv.sh/pkg/ui"
+)
+
+func TestStyles(t *testing.T) {
+	styles := Styles(func(name string) ui.Styling {
+		if name == "scrollbar" {
+			return ui.FgBlue
+		}
+		return nil
+	})
+
+	buf := VScrollbar{Total: 2, Low: 0, High: 1, Styles: styles}.Render(1, 2)
+	wantBuf := bb(1).WriteStyled(ui.T(" ", ui.FgBlue, ui.Inverse)).
+		Newline().WriteStyled(ui.T("│", ui.FgBlue)).Buffer()
+	if !reflect.DeepEqual(buf, wantBuf) {
+		t.Errorf("got buffer %v, want %v", buf, wantBuf)
+	}
+}
+
+func TestStyles_NilUsesDefaults(t *testing.T) {
+	buf := HScrollbar{Total: 1, Low: 0, High: 1}.Render(1, 1)
+	wantBuf := term.NewBufferBuilder(1).WriteStyled(hscrollbarThumb).Buffer()
+	if !reflect.DeepEqual(buf, wantBuf) {
+		t.Errorf("got buffer %v, want %v", buf, wantBuf)
+	}
+}
+
+func TestStyles_NilResultUsesDefault(t *testing.T) {
+	styles := Styles(func(string) ui.Styling { return nil })
+	if got := styles.For("selected"); got != ui.Inverse {
+		t.Errorf("got %v, want %v", got, ui.Inverse)
+	}
+}
+
+func TestListBox_UsesStyles(t *testing.T) {
+	w := NewListBox(ListBoxSpec{
+		Styles: func(name string) ui.Styling {
+			if name == "selected" {
+				return ui.FgRed
+			}
+			return nil
+		},
+		State: ListBoxState{Items: TestItems{NItems: 2}, Selected: 0},
+	})
+	buf := w.Render(6, 2)
+	wantBuf := bb(6).WriteStyled(ui.T("item 0", ui.FgRed)).
+		Newline().Write("item 1").Buffer()
+	if !reflect.DeepEqual(buf, wantBuf) {
+		t.Errorf("got buffer %v, want %v", buf, wantBuf)
+	}
+}
@@ -31,6 +31,8 @@ type TextViewSpec struct {
 	// If true, a vertical scrollbar will be shown when there are more lines
 	// that can be displayed, and the widget responds to Up and Down keys.
 	Scrollable bool
+	// Styles for the "scrollbar" part. If nil, the default stylings are used.
+	Styles Styles
 	// State. Specifies the initial state if used in New.
 	State TextViewState
 }
@@ -74,7 +76,8 @@ func (w *textView) Render(width, height int) *term.Buffer {
 
 	if needScrollbar {
 		scrollbar := VScrollbar{
-			Total: len(lines), Low: first, High: first + height}
+			Total: len(lines), Low: first, High: first + height,
+			Styles: w.Styles}
 		buf.ExtendRight(scrollbar.Render(1, height))
 	}
 	return buf
@@ -100,6 +103,16 @@ func (w *textView) Handle(event term.Event) bool {
 	}
 
 	if w.Scrollable {
+		if e, ok := event.(term.MouseEvent); ok && e.Down {
+			switch e.Button {
+			case term.ScrollUp:
+				w.ScrollBy(-1)
+				return true
+			case term.ScrollDown:
+				w.ScrollBy(1)
+				return true
+			}
+		}
 		switch event {
 		case term.K(ui.Up):
 			w.ScrollBy(-1)
@@ -111,6 +111,32 @@ var textViewHandleTests = []handleTest{
 
 		WantNewState: TextViewState{Lines: []string{"1", "2", "3", "4"}, First: 3},
 	},
+	{
+		Name: "scrolling wheel up moving window up when scrollable",
+		Given: NewTextView(TextViewSpec{
+			Scrollable: true,
+			State:      TextViewState{Lines: []string{"1", "2", "3", "4"}, First: 1}}),
+		Event: term.MouseEvent{Down: true, Button: term.ScrollUp},
+
+		WantNewState: TextViewState{Lines: []string{"1", "2", "3", "4"}, First: 0},
+	},
+	{
+		Name: "scrolling wheel down moving window down when scrollable",
+		Given: NewTextView(TextViewSpec{
+			Scrollable: true,
+			State:      TextViewState{Lines: []string{"1", "2", "3", "4"}, First: 1}}),
+		Event: term.MouseEvent{Down: true, Button: term.ScrollDown},
+
+		WantNewState: TextViewState{Lines: []string{"1", "2", "3", "4"}, First: 2},
+	},
+	{
+		Name: "scrolling wheel doing nothing when not scrollable",
+		Given: NewTextView(TextViewSpec{
+			State: TextViewState{Lines: []string{"1", "2", "3", "4"}, First: 1}}),
+		Event: term.MouseEvent{Down: true, Button: term.ScrollDown},
+
+		WantUnhandled: true,
+	},
 	{
 		Name: "bindings",
 		Given: NewTextView(TextViewSpec{
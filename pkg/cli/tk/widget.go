@@ -33,7 +33,8 @@ type MaxHeighter interface {
 // Handler wraps the Handle method.
 type Handler interface {
 	// Try to handle a terminal event and returns whether the event has been
-	// handled.
+	// handled. The position of a [term.MouseEvent] is relative to where the
+	// widget was last rendered.
 	Handle(event term.Event) bool
 }
 
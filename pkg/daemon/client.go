@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net"
 	"sync"
+	"time"
 
 	"src.elv.sh/pkg/daemon/daemondefs"
 	"src.elv.sh/pkg/daemon/internal/api"
@@ -21,7 +22,9 @@ var (
 
 // Implementation of the Client interface.
 type client struct {
-	sockPath  string
+	sockPath string
+	// Protects rpcClient, since requests may be made from multiple goroutines.
+	mutex     sync.Mutex
 	rpcClient *rpc.Client
 	waits     sync.WaitGroup
 }
@@ -29,7 +32,7 @@ type client struct {
 // NewClient creates a new Client instance that talks to the socket. Connection
 // creation is deferred to the first request.
 func NewClient(sockPath string) daemondefs.Client {
-	return &client{sockPath, nil, sync.WaitGroup{}}
+	return &client{sockPath: sockPath}
 }
 
 // SockPath returns the socket path that the Client talks to. If the client is
@@ -41,6 +44,8 @@ func (c *client) SockPath() string {
 // ResetConn resets the current connection. A new connection will be established
 // the next time a request is made. If the client is nil, it does nothing.
 func (c *client) ResetConn() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	if c.rpcClient == nil {
 		return nil
 	}
@@ -61,18 +66,20 @@ func (c *client) call(f string, req, res any) error {
 	defer c.waits.Done()
 
 	for attempt := 0; attempt < retriesOnShutdown; attempt++ {
-		if c.rpcClient == nil {
-			conn, err := net.Dial("unix", c.sockPath)
-			if err != nil {
-				return err
-			}
-			c.rpcClient = rpc.NewClient(conn)
+		rc, err := c.connect()
+		if err != nil {
+			return err
 		}
 
-		err := c.rpcClient.Call(api.ServiceName+"."+f, req, res)
+		err = rc.Call(api.ServiceName+"."+f, req, res)
 		if err == rpc.ErrShutdown {
-			// Clear rpcClient so as to reconnect next time
-			c.rpcClient = nil
+			// Clear rpcClient so as to reconnect next time, unless another
+			// request has done that already
+			c.mutex.Lock()
+			if c.rpcClient == rc {
+				c.rpcClient = nil
+			}
+			c.mutex.Unlock()
 			continue
 		} else {
 			return err
@@ -81,6 +88,20 @@ func (c *client) call(f string, req, res any) error {
 	return ErrDaemonUnreachable
 }
 
+// Returns the current RPC client, connecting to the daemon if there is none.
+func (c *client) connect() (*rpc.Client, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.rpcClient == nil {
+		conn, err := net.Dial("unix", c.sockPath)
+		if err != nil {
+			return nil, err
+		}
+		c.rpcClient = rpc.NewClient(conn)
+	}
+	return c.rpcClient, nil
+}
+
 // Convenience methods for RPC methods. These are quite repetitive; when the
 // number of RPC calls grow above some threshold, a code generator should be
 // written to generate them.
@@ -120,6 +141,41 @@ func (c *client) DelCmd(seq int) error {
 	return err
 }
 
+func (c *client) SetCmdPinned(seq int, pinned bool) error {
+	req := &api.SetCmdPinnedRequest{Seq: seq, Pinned: pinned}
+	res := &api.SetCmdPinnedResponse{}
+	err := c.call("SetCmdPinned", req, res)
+	return err
+}
+
+func (c *client) PinnedCmds() ([]int, error) {
+	req := &api.PinnedCmdsRequest{}
+	res := &api.PinnedCmdsResponse{}
+	err := c.call("PinnedCmds", req, res)
+	return res.Seqs, err
+}
+
+func (c *client) CompactCmds(maxEntries int, maxAge time.Duration) (int, error) {
+	req := &api.CompactCmdsRequest{MaxEntries: maxEntries, MaxAge: maxAge}
+	res := &api.CompactCmdsResponse{}
+	err := c.call("CompactCmds", req, res)
+	return res.Deleted, err
+}
+
+func (c *client) SetCmdResult(seq int, duration time.Duration, failed bool) error {
+	req := &api.SetCmdResultRequest{Seq: seq, Duration: duration, Failed: failed}
+	res := &api.SetCmdResultResponse{}
+	err := c.call("SetCmdResult", req, res)
+	return err
+}
+
+func (c *client) CmdInfos(from, upto int) ([]storedefs.CmdInfo, error) {
+	req := &api.CmdInfosRequest{From: from, Upto: upto}
+	res := &api.CmdInfosResponse{}
+	err := c.call("CmdInfos", req, res)
+	return res.Infos, err
+}
+
 func (c *client) Cmd(seq int) (string, error) {
 	req := &api.CmdRequest{Seq: seq}
 	res := &api.CmdResponse{}
@@ -168,3 +224,73 @@ func (c *client) Dirs(blacklist map[string]struct{}) ([]storedefs.Dir, error) {
 	err := c.call("Dirs", req, res)
 	return res.Dirs, err
 }
+
+func (c *client) Snapshot() (storedefs.Snapshot, error) {
+	req := &api.SnapshotRequest{}
+	res := &api.SnapshotResponse{}
+	err := c.call("Snapshot", req, res)
+	return res.Snapshot, err
+}
+
+func (c *client) MergeSnapshot(snapshot storedefs.Snapshot) error {
+	req := &api.MergeSnapshotRequest{Snapshot: snapshot}
+	res := &api.MergeSnapshotResponse{}
+	err := c.call("MergeSnapshot", req, res)
+	return err
+}
+
+func (c *client) Data(ns, key string) (string, error) {
+	req := &api.DataRequest{NS: ns, Key: key}
+	res := &api.DataResponse{}
+	err := c.call("Data", req, res)
+	return res.Value, err
+}
+
+func (c *client) DataKeys(ns string) ([]string, error) {
+	req := &api.DataKeysRequest{NS: ns}
+	res := &api.DataKeysResponse{}
+	err := c.call("DataKeys", req, res)
+	return res.Keys, err
+}
+
+func (c *client) SetData(ns, key, value string) error {
+	req := &api.SetDataRequest{NS: ns, Key: key, Value: value}
+	res := &api.SetDataResponse{}
+	err := c.call("SetData", req, res)
+	return err
+}
+
+func (c *client) DelData(ns, key string) error {
+	req := &api.DelDataRequest{NS: ns, Key: key}
+	res := &api.DelDataResponse{}
+	err := c.call("DelData", req, res)
+	return err
+}
+
+func (c *client) CompareAndSetData(ns, key, old, new string) (bool, error) {
+	req := &api.CompareAndSetDataRequest{NS: ns, Key: key, Old: old, New: new}
+	res := &api.CompareAndSetDataResponse{}
+	err := c.call("CompareAndSetData", req, res)
+	return res.Swapped, err
+}
+
+func (c *client) ChangeData(expected, changes []storedefs.DataEntry) (bool, error) {
+	req := &api.ChangeDataRequest{Expected: expected, Changes: changes}
+	res := &api.ChangeDataResponse{}
+	err := c.call("ChangeData", req, res)
+	return res.Changed, err
+}
+
+func (c *client) SetTrustedHash(path, hash string) error {
+	req := &api.SetTrustedHashRequest{Path: path, Hash: hash}
+	res := &api.SetTrustedHashResponse{}
+	err := c.call("SetTrustedHash", req, res)
+	return err
+}
+
+func (c *client) TrustedHash(path string) (string, error) {
+	req := &api.TrustedHashRequest{Path: path}
+	res := &api.TrustedHashResponse{}
+	err := c.call("TrustedHash", req, res)
+	return res.Hash, err
+}
@@ -3,11 +3,13 @@
 package api
 
 import (
+	"time"
+
 	"src.elv.sh/pkg/store/storedefs"
 )
 
 // Version is the API version. It should be bumped any time the API changes.
-const Version = -93
+const Version = -99
 
 // ServiceName is the name of the RPC service exposed by the daemon.
 const ServiceName = "Daemon"
@@ -49,6 +51,48 @@ type DelCmdRequest struct {
 type DelCmdResponse struct {
 }
 
+type SetCmdPinnedRequest struct {
+	Seq    int
+	Pinned bool
+}
+
+type SetCmdPinnedResponse struct {
+}
+
+type PinnedCmdsRequest struct {
+}
+
+type PinnedCmdsResponse struct {
+	Seqs []int
+}
+
+type CompactCmdsRequest struct {
+	MaxEntries int
+	MaxAge     time.Duration
+}
+
+type CompactCmdsResponse struct {
+	Deleted int
+}
+
+type SetCmdResultRequest struct {
+	Seq      int
+	Duration time.Duration
+	Failed   bool
+}
+
+type SetCmdResultResponse struct {
+}
+
+type CmdInfosRequest struct {
+	From int
+	Upto int
+}
+
+type CmdInfosResponse struct {
+	Infos []storedefs.CmdInfo
+}
+
 type CmdRequest struct {
 	Seq int
 }
@@ -117,3 +161,87 @@ type DirsRequest struct {
 type DirsResponse struct {
 	Dirs []storedefs.Dir
 }
+
+type SnapshotRequest struct {
+}
+
+type SnapshotResponse struct {
+	Snapshot storedefs.Snapshot
+}
+
+type MergeSnapshotRequest struct {
+	Snapshot storedefs.Snapshot
+}
+
+type MergeSnapshotResponse struct {
+}
+
+type DataRequest struct {
+	NS  string
+	Key string
+}
+
+type DataResponse struct {
+	Value string
+}
+
+type DataKeysRequest struct {
+	NS string
+}
+
+type DataKeysResponse struct {
+	Keys []string
+}
+
+type SetDataRequest struct {
+	NS    string
+	Key   string
+	Value string
+}
+
+type SetDataResponse struct {
+}
+
+type DelDataRequest struct {
+	NS  string
+	Key string
+}
+
+type DelDataResponse struct {
+}
+
+type CompareAndSetDataRequest struct {
+	NS  string
+	Key string
+	Old string
+	New string
+}
+
+type CompareAndSetDataResponse struct {
+	Swapped bool
+}
+
+type ChangeDataRequest struct {
+	Expected []storedefs.DataEntry
+	Changes  []storedefs.DataEntry
+}
+
+type ChangeDataResponse struct {
+	Changed bool
+}
+
+type SetTrustedHashRequest struct {
+	Path string
+	Hash string
+}
+
+type SetTrustedHashResponse struct {
+}
+
+type TrustedHashRequest struct {
+	Path string
+}
+
+type TrustedHashResponse struct {
+	Hash string
+}
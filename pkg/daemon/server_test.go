@@ -44,7 +44,12 @@ func TestProgram_ServesClientRequests(t *testing.T) {
 
 	// Test store requests.
 	storetest.TestCmd(t, client)
+	storetest.TestCmdCompact(t, client)
+	storetest.TestCmdResult(t, client)
 	storetest.TestDir(t, client)
+	storetest.TestSnapshot(t, client)
+	storetest.TestTrust(t, client)
+	storetest.TestData(t, client)
 }
 
 func TestProgram_StillServesIfCannotOpenDB(t *testing.T) {
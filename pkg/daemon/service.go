@@ -54,6 +54,47 @@ func (s *service) DelCmd(req *api.DelCmdRequest, res *api.DelCmdResponse) error
 	return err
 }
 
+func (s *service) SetCmdPinned(req *api.SetCmdPinnedRequest, res *api.SetCmdPinnedResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.store.SetCmdPinned(req.Seq, req.Pinned)
+}
+
+func (s *service) PinnedCmds(req *api.PinnedCmdsRequest, res *api.PinnedCmdsResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	seqs, err := s.store.PinnedCmds()
+	res.Seqs = seqs
+	return err
+}
+
+func (s *service) CompactCmds(req *api.CompactCmdsRequest, res *api.CompactCmdsResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	deleted, err := s.store.CompactCmds(req.MaxEntries, req.MaxAge)
+	res.Deleted = deleted
+	return err
+}
+
+func (s *service) SetCmdResult(req *api.SetCmdResultRequest, res *api.SetCmdResultResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.store.SetCmdResult(req.Seq, req.Duration, req.Failed)
+}
+
+func (s *service) CmdInfos(req *api.CmdInfosRequest, res *api.CmdInfosResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	infos, err := s.store.CmdInfos(req.From, req.Upto)
+	res.Infos = infos
+	return err
+}
+
 func (s *service) Cmd(req *api.CmdRequest, res *api.CmdResponse) error {
 	if s.err != nil {
 		return s.err
@@ -112,3 +153,85 @@ func (s *service) Dirs(req *api.DirsRequest, res *api.DirsResponse) error {
 	res.Dirs = dirs
 	return err
 }
+
+func (s *service) Snapshot(req *api.SnapshotRequest, res *api.SnapshotResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	snapshot, err := s.store.Snapshot()
+	res.Snapshot = snapshot
+	return err
+}
+
+func (s *service) MergeSnapshot(req *api.MergeSnapshotRequest, res *api.MergeSnapshotResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.store.MergeSnapshot(req.Snapshot)
+}
+
+func (s *service) Data(req *api.DataRequest, res *api.DataResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	value, err := s.store.Data(req.NS, req.Key)
+	res.Value = value
+	return err
+}
+
+func (s *service) DataKeys(req *api.DataKeysRequest, res *api.DataKeysResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	keys, err := s.store.DataKeys(req.NS)
+	res.Keys = keys
+	return err
+}
+
+func (s *service) SetData(req *api.SetDataRequest, res *api.SetDataResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.store.SetData(req.NS, req.Key, req.Value)
+}
+
+func (s *service) DelData(req *api.DelDataRequest, res *api.DelDataResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.store.DelData(req.NS, req.Key)
+}
+
+func (s *service) CompareAndSetData(req *api.CompareAndSetDataRequest, res *api.CompareAndSetDataResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	swapped, err := s.store.CompareAndSetData(req.NS, req.Key, req.Old, req.New)
+	res.Swapped = swapped
+	return err
+}
+
+func (s *service) ChangeData(req *api.ChangeDataRequest, res *api.ChangeDataResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	changed, err := s.store.ChangeData(req.Expected, req.Changes)
+	res.Changed = changed
+	return err
+}
+
+func (s *service) SetTrustedHash(req *api.SetTrustedHashRequest, res *api.SetTrustedHashResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.store.SetTrustedHash(req.Path, req.Hash)
+}
+
+func (s *service) TrustedHash(req *api.TrustedHashRequest, res *api.TrustedHashResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	hash, err := s.store.TrustedHash(req.Path)
+	res.Hash = hash
+	return err
+}
@@ -2,11 +2,16 @@ package edit
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
 
 	"src.elv.sh/pkg/cli"
 	"src.elv.sh/pkg/cli/modes"
 	"src.elv.sh/pkg/cli/term"
 	"src.elv.sh/pkg/cli/tk"
+	"src.elv.sh/pkg/env"
 	"src.elv.sh/pkg/eval"
 	"src.elv.sh/pkg/eval/errs"
 	"src.elv.sh/pkg/eval/vals"
@@ -48,7 +53,7 @@ func insertRaw(app cli.App, tty cli.TTY) {
 		return
 	}
 	tty.SetRawInput(1)
-	w := modes.NewStub(modes.StubSpec{
+	w := modes.NewStub(app, modes.StubSpec{
 		Bindings: tk.FuncBindings(func(w tk.Widget, event term.Event) bool {
 			switch event := event.(type) {
 			case term.KeyEvent:
@@ -141,10 +146,70 @@ func wordify(fm *eval.Frame, code string) error {
 	return nil
 }
 
+// Writes the content of the focused code area to a temporary file, opens it
+// with the external editor, and replaces the content with the file after the
+// editor exits.
+func editInEditor(app cli.App) error {
+	codeArea, ok := focusedCodeArea(app)
+	if !ok {
+		return nil
+	}
+	file, err := os.CreateTemp("", "elvish-*.elv")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(codeArea.CopyState().Buffer.Content)
+	if err2 := file.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return err
+	}
+
+	args := append(strings.Fields(externalEditor()), file.Name())
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	var errRun error
+	err = app.Suspend(func() { errRun = cmd.Run() })
+	if err != nil {
+		return err
+	}
+	if errRun != nil {
+		return fmt.Errorf("editor %s: %w", args[0], errRun)
+	}
+
+	content, err := os.ReadFile(file.Name())
+	if err != nil {
+		return err
+	}
+	// Editors usually add a newline to the end of the file.
+	code := strings.TrimSuffix(string(content), "\n")
+	codeArea.MutateState(func(s *tk.CodeAreaState) {
+		s.Buffer = tk.CodeBuffer{Content: code, Dot: len(code)}
+	})
+	return nil
+}
+
+// Returns the command of the external editor, taken from $E:VISUAL or
+// $E:EDITOR, or vi if neither is set.
+func externalEditor() string {
+	for _, name := range []string{env.VISUAL, env.EDITOR} {
+		if editor := os.Getenv(name); strings.TrimSpace(editor) != "" {
+			return editor
+		}
+	}
+	return "vi"
+}
+
 func initTTYBuiltins(app cli.App, tty cli.TTY, nb eval.NsBuilder) {
 	nb.AddGoFns(map[string]any{
-		"insert-raw": func() { insertRaw(app, tty) },
-		"clear":      func() { clear(app, tty) },
+		"insert-raw":     func() { insertRaw(app, tty) },
+		"clear":          func() { clear(app, tty) },
+		"edit-in-editor": func() error { return editInEditor(app) },
+		"pick": func(fm *eval.Frame, opts pickOpts, inputs eval.Inputs) error {
+			return pick(fm, tty, opts, inputs)
+		},
 	})
 }
 
@@ -167,7 +232,7 @@ func initMiscBuiltins(ed *Editor, nb eval.NsBuilder) {
 func focusedCodeArea(app cli.App) (tk.CodeArea, bool) {
 	codeArea, err := modes.FocusedCodeArea(app)
 	if err != nil {
-		app.Notify(modes.ErrorText(err))
+		app.Notify(modes.ErrorText(app.Styles(), err))
 		return nil, false
 	}
 	return codeArea, true
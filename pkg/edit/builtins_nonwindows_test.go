@@ -0,0 +1,79 @@
+//go:build !windows
+
+package edit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"src.elv.sh/pkg/cli/term"
+	"src.elv.sh/pkg/edit/complete"
+	"src.elv.sh/pkg/env"
+	"src.elv.sh/pkg/testutil"
+	"src.elv.sh/pkg/ui"
+)
+
+func TestEditInEditor(t *testing.T) {
+	editor := filepath.Join(testutil.TempDir(t), "editor")
+	testutil.Setenv(t, env.EDITOR, editor)
+	testutil.Unsetenv(t, env.VISUAL)
+	f := setup(t)
+
+	writeScript(t, editor, `read -r line < "$1"; printf 'echo %s\n' "$line" > "$1"`)
+	feedInput(f.TTYCtrl, "foo")
+	f.TTYCtrl.Inject(term.K('e', ui.Alt))
+	f.TestTTY(t, "~> echo foo", Styles,
+		"   vvvv", term.DotHere)
+
+	// Ctrl-X Ctrl-E is also bound to edit:edit-in-editor.
+	f.TTYCtrl.Inject(term.K('X', ui.Ctrl), term.K('E', ui.Ctrl))
+	f.TestTTY(t, "~> echo echo foo", Styles,
+		"   vvvv", term.DotHere)
+
+	writeScript(t, editor, `exit 1`)
+	f.TTYCtrl.Inject(term.K('e', ui.Alt))
+	f.TestTTYNotes(t, "[binding error] editor "+editor+": exit status 1")
+	f.TestTTY(t, "~> echo echo foo", Styles,
+		"   vvvv", term.DotHere)
+}
+
+func TestCompleteHelpFlags(t *testing.T) {
+	dir := testutil.TempDir(t)
+	testutil.Setenv(t, env.PATH, dir)
+	counter := filepath.Join(dir, "counter")
+	writeScript(t, filepath.Join(dir, "cmd"),
+		`echo x >> `+counter+`; echo '  -a, --all   show all'`)
+
+	wantItems := []complete.RawItem{
+		complete.ComplexItem{Stem: "-a", Display: ui.T("-a (show all)")},
+		complete.ComplexItem{Stem: "--all", Display: ui.T("--all (show all)")},
+	}
+	for i := 0; i < 2; i++ {
+		items, err := completeHelpFlags([]string{"cmd", "--a"})
+		if !reflect.DeepEqual(items, wantItems) || err != nil {
+			t.Errorf("got (%v, %v), want (%v, nil)", items, err, wantItems)
+		}
+	}
+	// The output is cached.
+	if content, _ := os.ReadFile(counter); string(content) != "x\n" {
+		t.Errorf("command run %d times, want 1", strings.Count(string(content), "x"))
+	}
+
+	testutil.Set(t, &helpFlagsTimeout, 10*time.Millisecond)
+	writeScript(t, filepath.Join(dir, "slow"), `while :; do :; done`)
+	items, err := completeHelpFlags([]string{"slow", "-"})
+	if len(items) != 0 || err != nil {
+		t.Errorf("got (%v, %v), want (nil, nil)", items, err)
+	}
+}
+
+func writeScript(t *testing.T, name, content string) {
+	err := os.WriteFile(name, []byte("#!/bin/sh\n"+content+"\n"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -298,6 +298,7 @@ func TestBufferBuiltins(t *testing.T) {
 // includes some builtins defined in files other than builtins.go.
 var focusedWidgetNotCodeAreaTests = []string{
 	"edit:insert-raw",
+	"edit:edit-in-editor",
 	"edit:smart-enter",
 	"edit:move-dot-right", // other buffer builtins not tested
 	"edit:completion:start",
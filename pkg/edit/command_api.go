@@ -15,7 +15,7 @@ func initCommandAPI(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
 			AddVar("binding", bindingVar).
 			AddGoFns(map[string]any{
 				"start": func() {
-					w := modes.NewStub(modes.StubSpec{
+					w := modes.NewStub(ed.app, modes.StubSpec{
 						Bindings: bindings,
 						Name:     " COMMAND ",
 					})
@@ -64,7 +64,7 @@ func Complete(code CodeBuffer, ev *eval.Evaler, cfg Config) (*Result, error) {
 	}
 
 	// Ignore the error; the function always returns a valid *ChunkNode.
-	tree, _ := parse.Parse(parse.Source{Name: "[interactive]", Code: code.Content}, parse.Config{})
+	tree, _ := parse.Parse(parse.Source{Name: "[interactive]", Code: code.Content}, parse.Config{Recover: true})
 	path := np.FindLeft(tree.Root, code.Dot)
 	if len(path) == 0 {
 		// This can happen when there is a parse error.
@@ -60,6 +60,10 @@ func TestComplete(t *testing.T) {
 			AddGoFn("builtin-fn1", func() {}).
 			AddGoFn("builtin-fn2", func() {}).
 			Ns())
+	ev.AddModule("a/mod1", eval.BuildNs().
+		AddVar("mod-var", vars.NewReadOnly(nil)).
+		AddGoFn("mod-fn", func() {}).
+		Ns())
 
 	var cfg Config
 	cfg = Config{
@@ -260,6 +264,13 @@ func TestComplete(t *testing.T) {
 				Name: "command", Replace: r(15, 19),
 				Items: []modes.CompletionItem{ci("new-fn")}},
 			nil),
+		// Functions in namespaces imported with "use" in the code.
+		//       0123456789012345
+		Args(cb("use a/mod1; mod1:"), ev, cfg).Rets(
+			&Result{
+				Name: "command", Replace: r(12, 17),
+				Items: []modes.CompletionItem{ci("mod1:mod-fn")}},
+			nil),
 
 		// TODO(xiaq): Add tests for completing indices.
 
@@ -346,6 +357,31 @@ func TestComplete(t *testing.T) {
 				Name: "variable", Replace: r(13, 13),
 				Items: []modes.CompletionItem{ci("lorem")}},
 			nil),
+		// Namespaces imported with "use" in the code.
+		//       0123456789012345678
+		Args(cb("use a/mod1; p $mod"), ev, cfg).Rets(
+			&Result{
+				Name: "variable", Replace: r(15, 18),
+				Items: []modes.CompletionItem{ci("mod1:")}},
+			nil),
+		//       0123456789012345678901
+		Args(cb("use a/mod1; p $mod1:"), ev, cfg).Rets(
+			&Result{
+				Name: "variable", Replace: r(20, 20),
+				Items: []modes.CompletionItem{ci("mod-fn~"), ci("mod-var")}},
+			nil),
+		//       012345678901234567890
+		Args(cb("use a/mod1 m; p $m:"), ev, cfg).Rets(
+			&Result{
+				Name: "variable", Replace: r(19, 19),
+				Items: []modes.CompletionItem{ci("mod-fn~"), ci("mod-var")}},
+			nil),
+		// Namespaces imported in a scope not visible from the point of
+		// completion are not included.
+		//       01234567890123456789012
+		Args(cb("{ use a/mod1 }; p $mod1:"), ev, cfg).Rets(
+			&Result{Name: "variable", Replace: r(24, 24)},
+			nil),
 		// Variables in the special e: namespace.
 		//       012345
 		Args(cb("p $e:"), ev, cfg).Rets(
@@ -443,6 +479,100 @@ func TestComplete(t *testing.T) {
 	}
 }
 
+func TestComplete_FnDescription(t *testing.T) {
+	testutil.Set(t, &eachExternal, func(func(string)) {})
+	ev := eval.NewEvaler()
+	err := ev.Eval(parse.SourceForTest(strings.Join([]string{
+		"# Does foo.",
+		"#",
+		"# More details.",
+		"fn doc-foo { }",
+		"fn doc-bar { }",
+		"var mod: = (ns [&foo~=(",
+		"  # Does mod:foo.",
+		"  fn foo { }",
+		"  put $foo~)])",
+	}, "\n")), eval.EvalCfg{})
+	if err != nil {
+		t.Fatalf("evaler setup: %v", err)
+	}
+	cfg := Config{Filterer: FilterPrefix}
+
+	tt.Test(t, Complete,
+		Args(cb("doc-"), ev, cfg).Rets(
+			&Result{
+				Name: "command", Replace: r(0, 4),
+				Items: []modes.CompletionItem{
+					ci("doc-bar"),
+					{ToShow: ui.T("doc-foo"), ToInsert: "doc-foo", Description: "Does foo."},
+				}},
+			nil),
+		Args(cb("mod:"), ev, cfg).Rets(
+			&Result{
+				Name: "command", Replace: r(0, 4),
+				Items: []modes.CompletionItem{
+					{ToShow: ui.T("mod:foo"), ToInsert: "mod:foo", Description: "Does mod:foo."},
+				}},
+			nil),
+	)
+}
+
+func TestComplete_Alias(t *testing.T) {
+	testutil.Set(t, &eachExternal, func(func(string)) {})
+	ev := eval.NewEvaler()
+	for _, code := range []string{
+		"alias ll 'ls -l'", "alias lla 'll -a'", "alias lg 'git log | less'",
+		"alias ls 'ls --color'", "alias dyn 'echo (put x)'",
+	} {
+		err := ev.Eval(parse.SourceForTest(code), eval.EvalCfg{})
+		if err != nil {
+			t.Fatalf("evaler setup: %v", err)
+		}
+	}
+	argGeneratorDebugCfg := Config{
+		Filterer: func(ctxName, seed string, items []RawItem) []RawItem {
+			return items
+		},
+		ArgGenerator: func(args []string) ([]RawItem, error) {
+			item := noQuoteItem(fmt.Sprintf("%#v", args))
+			return []RawItem{item}, nil
+		},
+	}
+	argResult := func(code string, args ...string) *Result {
+		return &Result{
+			Name: "argument", Replace: r(len(code), len(code)),
+			Items: []modes.CompletionItem{
+				ci(fmt.Sprintf("%#v", args)),
+			}}
+	}
+
+	tt.Test(t, Complete,
+		// Arguments of aliases are completed like those of the command they
+		// expand to.
+		Args(cb("ll a "), ev, argGeneratorDebugCfg).Rets(
+			argResult("ll a ", "ls", "--color", "-l", "a", ""), nil),
+		// Aliases of aliases are expanded too, but each alias is only
+		// expanded once.
+		Args(cb("lla "), ev, argGeneratorDebugCfg).Rets(
+			argResult("lla ", "ls", "--color", "-l", "-a", ""), nil),
+		// Aliases to pipelines expand to the last command.
+		Args(cb("lg "), ev, argGeneratorDebugCfg).Rets(
+			argResult("lg ", "less", ""), nil),
+		// Aliases whose expansions are not literal are not expanded.
+		Args(cb("dyn "), ev, argGeneratorDebugCfg).Rets(
+			argResult("dyn ", "dyn", ""), nil),
+
+		// The description of an alias is its code.
+		Args(cb("lg"), ev, Config{Filterer: FilterPrefix}).Rets(
+			&Result{
+				Name: "command", Replace: r(0, 2),
+				Items: []modes.CompletionItem{
+					{ToShow: ui.T("lg"), ToInsert: "lg", Description: "git log | less"},
+				}},
+			nil),
+	)
+}
+
 func cb(s string) CodeBuffer { return CodeBuffer{s, len(s)} }
 
 func ci(s string) modes.CompletionItem { return modes.CompletionItem{ToShow: ui.T(s), ToInsert: s} }
@@ -41,6 +41,7 @@ func GenerateForSudo(args []string, ev *eval.Evaler, cfg Config) ([]RawItem, err
 // Internal generators, used from completers.
 
 func generateArgs(args []string, ev *eval.Evaler, p np.Path, cfg Config) ([]RawItem, error) {
+	args = expandAliases(ev, args)
 	switch args[0] {
 	case "set", "tmp":
 		for i := 1; i < len(args); i++ {
@@ -78,6 +79,27 @@ func generateArgs(args []string, ev *eval.Evaler, p np.Path, cfg Config) ([]RawI
 	return cfg.ArgGenerator(args)
 }
 
+// Replaces the head of args with the command that it expands to if it is an
+// alias, so that the arguments of an alias are completed like those of the
+// command. Aliases of aliases are expanded too.
+func expandAliases(ev *eval.Evaler, args []string) []string {
+	expanded := make(map[string]bool)
+	for !expanded[args[0]] {
+		expanded[args[0]] = true
+		v := ev.Global().IndexString(args[0] + eval.FnSuffix)
+		if v == nil {
+			break
+		}
+		alias, ok := v.Get().(*eval.Alias)
+		if !ok || alias.Words == nil {
+			break
+		}
+		words := alias.Words[:len(alias.Words):len(alias.Words)]
+		args = append(words, args[1:]...)
+	}
+	return args
+}
+
 func generateExternalCommands(seed string) ([]RawItem, error) {
 	if fsutil.DontSearch(seed) {
 		// Completing a local external command name.
@@ -97,12 +119,14 @@ func generateCommands(seed string, ev *eval.Evaler, p np.Path) ([]RawItem, error
 	var cands []RawItem
 	addPlainItem := func(s string) { cands = append(cands, PlainItem(s)) }
 
-	if strings.HasPrefix(seed, "e:") {
-		// Generate all external commands with the e: prefix, and be done.
-		eachExternal(func(command string) {
-			addPlainItem("e:" + command)
-		})
-		return cands, nil
+	for _, prefix := range []string{"e:", "external:"} {
+		if strings.HasPrefix(seed, prefix) {
+			// Generate all external commands with the prefix, and be done.
+			eachExternal(func(command string) {
+				addPlainItem(prefix + command)
+			})
+			return cands, nil
+		}
 	}
 
 	// Generate all special forms.
@@ -119,8 +143,12 @@ func generateCommands(seed string, ev *eval.Evaler, p np.Path) ([]RawItem, error
 		eachVariableInNs(ev, p, ns, func(varname string) {
 			switch {
 			case strings.HasSuffix(varname, eval.FnSuffix):
-				addPlainItem(
-					ns + varname[:len(varname)-len(eval.FnSuffix)])
+				name := ns + varname[:len(varname)-len(eval.FnSuffix)]
+				if summary := fnSummary(ev, p, ns, varname); summary != "" {
+					cands = append(cands, ComplexItem{Stem: name, Description: summary})
+				} else {
+					addPlainItem(name)
+				}
 			case strings.HasSuffix(varname, eval.NsSuffix):
 				addPlainItem(ns + varname)
 			}
@@ -20,7 +20,7 @@ func eachVariableInNs(ev *eval.Evaler, p np.Path, ns string, f func(s string)) {
 		ev.Global().IterateKeysString(f)
 		ev.Builtin().IterateKeysString(f)
 		eachDefinedVariable(p[len(p)-1], p[0].Range().From, f)
-	case "e:":
+	case "e:", "external:":
 		eachExternal(func(cmd string) {
 			f(cmd + eval.FnSuffix)
 		})
@@ -31,37 +31,115 @@ func eachVariableInNs(ev *eval.Evaler, p np.Path, ns string, f func(s string)) {
 			}
 		}
 	default:
-		// TODO: Support namespaces defined in the code too.
-		segs := eval.SplitQNameSegs(ns)
-		mod := ev.Global().IndexString(segs[0])
-		if mod == nil {
-			mod = ev.Builtin().IndexString(segs[0])
+		if mod := findNs(ev, p, ns); mod != nil {
+			mod.IterateKeysString(f)
 		}
-		for _, seg := range segs[1:] {
-			if mod == nil {
-				return
-			}
-			mod = mod.Get().(*eval.Ns).IndexString(seg)
+	}
+}
+
+// Finds the namespace ns (including the trailing ":") that can be found at the
+// point of np. Returns nil if it can't be found.
+func findNs(ev *eval.Evaler, p np.Path, ns string) *eval.Ns {
+	segs := eval.SplitQNameSegs(ns)
+	var mod *eval.Ns
+	if spec, ok := findUseSpec(p[len(p)-1], p[0].Range().From, segs[0]); ok {
+		// Namespaces imported with "use" in the code take precedence, since
+		// they shadow the global and builtin ones.
+		mod = ev.Module(spec)
+	} else if v := ev.Global().IndexString(segs[0]); v != nil {
+		mod, _ = v.Get().(*eval.Ns)
+	} else if v := ev.Builtin().IndexString(segs[0]); v != nil {
+		mod, _ = v.Get().(*eval.Ns)
+	} else if segs[0] == "builtin:" {
+		mod = ev.Builtin()
+	}
+	for _, seg := range segs[1:] {
+		if mod == nil {
+			return nil
 		}
-		if mod != nil {
-			mod.Get().(*eval.Ns).IterateKeysString(f)
+		v := mod.IndexString(seg)
+		if v == nil {
+			return nil
 		}
+		mod, _ = v.Get().(*eval.Ns)
 	}
+	return mod
+}
+
+// Returns the first line of the documentation of the function variable
+// varname in namespace ns, or "" if it is not a user-defined function with
+// documentation.
+func fnSummary(ev *eval.Evaler, p np.Path, ns, varname string) string {
+	var mod *eval.Ns
+	switch ns {
+	case "", ":":
+		mod = ev.Global()
+	case "e:", "external:", "E:":
+		return ""
+	default:
+		mod = findNs(ev, p, ns)
+	}
+	if mod == nil {
+		return ""
+	}
+	v := mod.IndexString(varname)
+	if v == nil {
+		return ""
+	}
+	var summary string
+	switch fn := v.Get().(type) {
+	case *eval.Closure:
+		summary, _, _ = strings.Cut(fn.Doc, "\n")
+	case *eval.Alias:
+		summary, _, _ = strings.Cut(fn.Code, "\n")
+	}
+	return summary
+}
+
+// Returns the module spec of the last "use" form in n visible at pos that
+// defines the namespace with the given name (including the trailing ":").
+func findUseSpec(n parse.Node, pos int, name string) (string, bool) {
+	spec, found := "", false
+	eachDefinedNs(n, pos, func(nsName, s string) {
+		if nsName == name {
+			spec, found = s, true
+		}
+	})
+	return spec, found
 }
 
 // Calls f for each variables defined in n that are visible at pos.
 func eachDefinedVariable(n parse.Node, pos int, f func(string)) {
-	if fn, ok := n.(*parse.Form); ok {
-		eachDefinedVariableInForm(fn, f)
-	}
-	if pn, ok := n.(*parse.Primary); ok && pn.Type == parse.Lambda {
-		for _, param := range pn.Elements {
-			if varRef, ok := cmpd.StringLiteral(param); ok {
-				_, name := eval.SplitSigil(varRef)
-				f(name)
+	eachVisibleNode(n, pos, func(n parse.Node) {
+		if fn, ok := n.(*parse.Form); ok {
+			eachDefinedVariableInForm(fn, f)
+		}
+		if pn, ok := n.(*parse.Primary); ok && pn.Type == parse.Lambda {
+			for _, param := range pn.Elements {
+				if varRef, ok := cmpd.StringLiteral(param); ok {
+					_, name := eval.SplitSigil(varRef)
+					f(name)
+				}
 			}
 		}
-	}
+	})
+}
+
+// Calls f with the name (including the trailing ":") and module spec of each
+// namespace imported with "use" in n that is visible at pos.
+func eachDefinedNs(n parse.Node, pos int, f func(name, spec string)) {
+	eachVisibleNode(n, pos, func(n parse.Node) {
+		if fn, ok := n.(*parse.Form); ok {
+			if name, spec, ok := useForm(fn); ok {
+				f(name, spec)
+			}
+		}
+	})
+}
+
+// Calls f for n and each descendant of n whose definitions are visible at pos.
+func eachVisibleNode(n parse.Node, pos int, f func(parse.Node)) {
+	f(n)
 	for _, ch := range parse.Children(n) {
 		if ch.Range().From > pos {
 			break
@@ -71,7 +149,7 @@ func eachDefinedVariable(n parse.Node, pos int, f func(string)) {
 				continue
 			}
 		}
-		eachDefinedVariable(ch, pos, f)
+		eachVisibleNode(ch, pos, f)
 	}
 }
 
@@ -99,5 +177,32 @@ func eachDefinedVariableInForm(fn *parse.Form, f func(string)) {
 				f(name + eval.FnSuffix)
 			}
 		}
+	case "use":
+		if name, _, ok := useForm(fn); ok {
+			f(name)
+		}
+	}
+}
+
+// Returns the name (including the trailing ":") and module spec of the
+// namespace imported by a "use" form. The name is derived from the spec in
+// the same way as the compiler.
+func useForm(fn *parse.Form) (name, spec string, ok bool) {
+	if fn.Head == nil || len(fn.Args) == 0 {
+		return "", "", false
+	}
+	if head, _ := cmpd.StringLiteral(fn.Head); head != "use" {
+		return "", "", false
+	}
+	spec, ok = cmpd.StringLiteral(fn.Args[0])
+	if !ok {
+		return "", "", false
+	}
+	name = spec[strings.LastIndexByte(spec, '/')+1:]
+	if len(fn.Args) >= 2 {
+		if name, ok = cmpd.StringLiteral(fn.Args[1]); !ok {
+			return "", "", false
+		}
 	}
+	return name + eval.NsSuffix, spec, true
 }
@@ -33,6 +33,9 @@ type ComplexItem struct {
 	Stem       string  // Used in the code and the menu.
 	CodeSuffix string  // Appended to the code.
 	Display    ui.Text // How the item is displayed. If empty, defaults to ui.T(Stem).
+	// Shown when the item is selected, like the first line of the
+	// documentation of a function.
+	Description string
 }
 
 func (c ComplexItem) String() string { return c.Stem }
@@ -44,7 +47,8 @@ func (c ComplexItem) Cook(q parse.PrimaryType) modes.CompletionItem {
 		display = ui.T(c.Stem)
 	}
 	return modes.CompletionItem{
-		ToInsert: quoted + c.CodeSuffix,
-		ToShow:   display,
+		ToInsert:    quoted + c.CodeSuffix,
+		ToShow:      display,
+		Description: c.Description,
 	}
 }
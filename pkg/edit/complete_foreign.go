@@ -0,0 +1,154 @@
+package edit
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"src.elv.sh/pkg/edit/complete"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/ui"
+)
+
+// Can be changed in tests.
+var foreignCompletionTimeout = 2 * time.Second
+
+// Loads the completion for a command from bash-completion and runs it. The
+// arguments are: a file to source first (may be empty), the command name,
+// and the words of the command line, the last of which is being completed.
+const bashCompletionScript = `
+exec 2>/dev/null
+extra=$1 cmd=$2
+shift
+for f in /usr/share/bash-completion/bash_completion /etc/bash_completion \
+	/usr/local/share/bash-completion/bash_completion \
+	/opt/homebrew/share/bash-completion/bash_completion; do
+	if [ -r "$f" ]; then
+		. "$f"
+		break
+	fi
+done
+if [ -n "$extra" ]; then
+	. "$extra"
+fi
+if ! complete -p "$cmd" >/dev/null; then
+	if declare -F __load_completion >/dev/null; then
+		__load_completion "$cmd"
+	elif declare -F _completion_loader >/dev/null; then
+		_completion_loader "$cmd"
+	else
+		for dir in /usr/share/bash-completion/completions \
+			/usr/local/share/bash-completion/completions; do
+			if [ -r "$dir/$cmd" ]; then
+				. "$dir/$cmd"
+				break
+			fi
+		done
+	fi
+fi
+spec=$(complete -p "$cmd") || exit 0
+COMP_WORDS=("$@")
+COMP_CWORD=$(( $# - 1 ))
+COMP_LINE="$*"
+COMP_POINT=${#COMP_LINE}
+cur=${COMP_WORDS[COMP_CWORD]}
+prev=${COMP_WORDS[COMP_CWORD-1]}
+if [[ $spec =~ \ -F\ ([^ ]+) ]]; then
+	"${BASH_REMATCH[1]}" "$cmd" "$cur" "$prev"
+else
+	# Other kinds of completion specs, like "-W words" or "-A file", can be
+	# passed to compgen directly.
+	spec=${spec#complete}
+	spec=${spec% *}
+	eval "COMPREPLY=(\$(compgen $spec -- \"\$cur\"))"
+fi
+printf '%s\n' "${COMPREPLY[@]}"
+`
+
+type completeBashOpts struct{ Source string }
+
+func (*completeBashOpts) SetDefaultOptions() {}
+
+// Generates candidates for the last argument using the completion of bash,
+// typically from the bash-completion project.
+func completeBash(fm *eval.Frame, opts completeBashOpts, args ...string) error {
+	if err := fm.Evaler.CheckRestricted("running external command bash"); err != nil {
+		return err
+	}
+	items, err := generateBash(opts.Source, args)
+	if err != nil {
+		return err
+	}
+	return putRawItems(fm, items)
+}
+
+func generateBash(source string, args []string) ([]complete.RawItem, error) {
+	if len(args) < 2 {
+		return nil, nil
+	}
+	bashArgs := append([]string{"-c", bashCompletionScript, "bash", source}, args...)
+	out, err := runForCompletion(foreignCompletionTimeout, "bash", bashArgs...)
+	if err != nil {
+		return nil, err
+	}
+	var items []complete.RawItem
+	for _, line := range strings.Split(out, "\n") {
+		// Candidates are often padded with a space to tell bash to insert a
+		// space after them.
+		if line = strings.TrimRight(line, " "); line != "" {
+			items = append(items, complete.PlainItem(line))
+		}
+	}
+	return items, nil
+}
+
+// Generates candidates for the last argument using the completion of fish.
+func completeFish(fm *eval.Frame, args ...string) error {
+	if err := fm.Evaler.CheckRestricted("running external command fish"); err != nil {
+		return err
+	}
+	items, err := generateFish(args)
+	if err != nil {
+		return err
+	}
+	return putRawItems(fm, items)
+}
+
+func generateFish(args []string) ([]complete.RawItem, error) {
+	if len(args) < 2 {
+		return nil, nil
+	}
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteForFish(arg)
+	}
+	out, err := runForCompletion(foreignCompletionTimeout, "fish", "--no-config",
+		"-c", "complete -C $argv[1] 2>/dev/null", strings.Join(quoted, " "))
+	if err != nil {
+		return nil, err
+	}
+	var items []complete.RawItem
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		stem, desc, hasDesc := strings.Cut(line, "\t")
+		item := complete.ComplexItem{Stem: stem}
+		if hasDesc {
+			item.Display = ui.T(stem + " (" + desc + ")")
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+var fishSafeWord = regexp.MustCompile(`^[[:alnum:]_./=:@%+,-]*$`)
+
+// Quotes a word for fish, leaving words that only contain safe characters
+// as is so that fish can complete partial words.
+func quoteForFish(s string) string {
+	if fishSafeWord.MatchString(s) {
+		return s
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
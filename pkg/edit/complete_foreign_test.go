@@ -0,0 +1,59 @@
+package edit
+
+import (
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"src.elv.sh/pkg/edit/complete"
+	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/testutil"
+)
+
+func TestGenerateBash(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	source := filepath.Join(testutil.TempDir(t), "completions.bash")
+	must.WriteFile(source, `
+_mycmd() { COMPREPLY=($(compgen -W "foo bar baz" -- "$2")); }
+complete -F _mycmd mycmd
+complete -W "lorem ipsum" other
+`)
+
+	tests := []struct {
+		args []string
+		want []complete.RawItem
+	}{
+		{[]string{"mycmd", "b"}, []complete.RawItem{complete.PlainItem("bar"), complete.PlainItem("baz")}},
+		{[]string{"mycmd", "x", ""}, []complete.RawItem{complete.PlainItem("foo"), complete.PlainItem("bar"), complete.PlainItem("baz")}},
+		{[]string{"other", "l"}, []complete.RawItem{complete.PlainItem("lorem")}},
+		{[]string{"elvish-no-such-command", ""}, nil},
+	}
+	for _, test := range tests {
+		got, err := generateBash(source, test.args)
+		if !reflect.DeepEqual(got, test.want) || err != nil {
+			t.Errorf("generateBash(%q) -> (%v, %v), want (%v, nil)", test.args, got, err, test.want)
+		}
+	}
+}
+
+var quoteForFishTests = []struct {
+	s    string
+	want string
+}{
+	{"", ""},
+	{"--color=auto", "--color=auto"},
+	{"a b", "'a b'"},
+	{`it's`, `'it\'s'`},
+	{`a\b`, `'a\\b'`},
+}
+
+func TestQuoteForFish(t *testing.T) {
+	for _, test := range quoteForFishTests {
+		if got := quoteForFish(test.s); got != test.want {
+			t.Errorf("quoteForFish(%q) -> %q, want %q", test.s, got, test.want)
+		}
+	}
+}
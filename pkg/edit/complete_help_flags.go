@@ -0,0 +1,187 @@
+package edit
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"src.elv.sh/pkg/edit/complete"
+	"src.elv.sh/pkg/ui"
+)
+
+// Can be changed in tests.
+var (
+	helpFlagsTimeout = time.Second
+	// Maximum number of bytes read from the output of a --help command.
+	helpFlagsMaxOutput = 1 << 20
+)
+
+// A flag parsed from the output of a --help command.
+type helpFlag struct {
+	name string
+	// The argument of the flag as shown in the output, like "=WHEN".
+	arg  string
+	desc string
+}
+
+type helpFlagsCacheEntry struct {
+	modTime time.Time
+	flags   []helpFlag
+}
+
+var (
+	helpFlagsCacheMutex sync.Mutex
+	// Cached flags, indexed by the paths to commands.
+	helpFlagsCache = map[string]helpFlagsCacheEntry{}
+)
+
+// Generates candidates for the last argument. If it starts with "-", the
+// candidates are flags parsed from the output of "$args[0] --help";
+// otherwise they are filenames.
+func completeHelpFlags(args []string) ([]complete.RawItem, error) {
+	if len(args) < 2 || !strings.HasPrefix(args[len(args)-1], "-") {
+		return complete.GenerateFileNames(args)
+	}
+	var items []complete.RawItem
+	for _, flag := range helpFlags(args[0]) {
+		display := flag.name + flag.arg
+		if flag.desc != "" {
+			display += " (" + flag.desc + ")"
+		}
+		items = append(items, complete.ComplexItem{Stem: flag.name, Display: ui.T(display)})
+	}
+	return items, nil
+}
+
+// Returns the flags of an external command, running it with --help if they
+// are not cached yet. Errors running the command are ignored.
+func helpFlags(cmd string) []helpFlag {
+	path, err := exec.LookPath(cmd)
+	if err != nil {
+		return nil
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	helpFlagsCacheMutex.Lock()
+	entry, ok := helpFlagsCache[path]
+	helpFlagsCacheMutex.Unlock()
+	if ok && entry.modTime.Equal(stat.ModTime()) {
+		return entry.flags
+	}
+
+	// Some commands exit with a non-zero status after writing the help text,
+	// so ignore the error.
+	out, _ := runSandboxedForCompletion(helpFlagsTimeout, path, "--help")
+	flags := parseHelpFlags(out)
+
+	helpFlagsCacheMutex.Lock()
+	helpFlagsCache[path] = helpFlagsCacheEntry{stat.ModTime(), flags}
+	helpFlagsCacheMutex.Unlock()
+	return flags
+}
+
+// Runs a command for generating completion candidates, and returns its output,
+// including both stdout and stderr. The command gets no stdin, and all its
+// output goes to a pipe, so it can't interact with the terminal. It is killed
+// after the timeout, and any output beyond helpFlagsMaxOutput is discarded.
+func runForCompletion(timeout time.Duration, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return runCmdForCompletion(exec.CommandContext(ctx, name, args...), timeout)
+}
+
+// Like runForCompletion, but runs the command with an empty environment in a
+// new empty directory, which is removed afterwards. This is used for running
+// arbitrary commands, so that they are less likely to act on the user's files.
+func runSandboxedForCompletion(timeout time.Duration, name string, args ...string) (string, error) {
+	dir, err := os.MkdirTemp("", "elvish-completion-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c := exec.CommandContext(ctx, name, args...)
+	c.Dir = dir
+	c.Env = []string{}
+	return runCmdForCompletion(c, timeout)
+}
+
+func runCmdForCompletion(c *exec.Cmd, timeout time.Duration) (string, error) {
+	var out limitedBuffer
+	out.limit = helpFlagsMaxOutput
+	c.Stdout, c.Stderr = &out, &out
+	// Don't wait for the output of any process the command has started.
+	c.WaitDelay = timeout
+	err := c.Run()
+	return out.String(), err
+}
+
+// A bytes.Buffer that silently discards everything written beyond the limit.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room < len(p) {
+		b.Buffer.Write(p[:max(room, 0)])
+	} else {
+		b.Buffer.Write(p)
+	}
+	return len(p), nil
+}
+
+var (
+	// Matches a flag and its argument in the flag part of a line.
+	helpFlagPattern = regexp.MustCompile(`^(--?[[:alnum:]][[:alnum:]_-]*)(\[?[= ]\S+)?`)
+	// Separates the flag part and the description in a line.
+	helpDescSeparator = regexp.MustCompile(`\s{2,}|\t`)
+)
+
+// Parses flags from the output of a --help command. Lines listing flags look
+// like the following:
+//
+//	-a, --all                  do not ignore entries starting with .
+//	    --color[=WHEN]         color the output
+//	-w, --width=COLS           set output width to COLS
+//
+// The description may also start on the next line.
+func parseHelpFlags(text string) []helpFlag {
+	var flags []helpFlag
+	seen := map[string]bool{}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "-") {
+			continue
+		}
+		flagPart, desc := trimmed, ""
+		if loc := helpDescSeparator.FindStringIndex(trimmed); loc != nil {
+			flagPart, desc = trimmed[:loc[0]], trimmed[loc[1]:]
+		} else if i+1 < len(lines) && indent(lines[i+1]) > indent(line) &&
+			!strings.HasPrefix(strings.TrimSpace(lines[i+1]), "-") {
+			desc = strings.TrimSpace(lines[i+1])
+		}
+		for _, field := range strings.Split(flagPart, ",") {
+			m := helpFlagPattern.FindStringSubmatch(strings.TrimSpace(field))
+			if m == nil || seen[m[1]] {
+				continue
+			}
+			seen[m[1]] = true
+			flags = append(flags, helpFlag{m[1], m[2], desc})
+		}
+	}
+	return flags
+}
+
+func indent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
@@ -0,0 +1,101 @@
+package edit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/testutil"
+)
+
+var parseHelpFlagsTests = []struct {
+	name string
+	text string
+	want []helpFlag
+}{
+	{
+		name: "GNU style",
+		text: `Usage: ls [OPTION]... [FILE]...
+List information about the FILEs.
+
+  -a, --all                  do not ignore entries starting with .
+      --color[=WHEN]         color the output WHEN
+  -w, --width=COLS           set output width to COLS
+`,
+		want: []helpFlag{
+			{"-a", "", "do not ignore entries starting with ."},
+			{"--all", "", "do not ignore entries starting with ."},
+			{"--color", "[=WHEN]", "color the output WHEN"},
+			{"-w", "", "set output width to COLS"},
+			{"--width", "=COLS", "set output width to COLS"},
+		},
+	},
+	{
+		name: "description on next line",
+		text: `  -v, --verbose
+        Print more output.
+  -q
+  --quiet
+`,
+		want: []helpFlag{
+			{"-v", "", "Print more output."},
+			{"--verbose", "", "Print more output."},
+			{"-q", "", ""},
+			{"--quiet", "", ""},
+		},
+	},
+	{
+		name: "Go flag style",
+		text: "Usage of prog:\n  -n int\n    \tnumber of items\n  -v\tverbose\n",
+		want: []helpFlag{
+			{"-n", " int", "number of items"},
+			{"-v", "", "verbose"},
+		},
+	},
+	{
+		name: "argument in angle brackets",
+		text: "  -o <file>   write output to <file>\n",
+		want: []helpFlag{{"-o", " <file>", "write output to <file>"}},
+	},
+	{
+		name: "lines without flags and duplicate flags",
+		text: "Some text\n - not a flag\n -a  first\n -a  second\n",
+		want: []helpFlag{{"-a", "", "first"}},
+	},
+}
+
+func TestParseHelpFlags(t *testing.T) {
+	for _, test := range parseHelpFlagsTests {
+		t.Run(test.name, func(t *testing.T) {
+			got := parseHelpFlags(test.text)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHelpFlags_Sandboxed(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a shell script")
+	}
+	dir := testutil.InTempDir(t)
+	testutil.Setenv(t, "FOO", "bar")
+	must.WriteFile("marker", "")
+	// The script only prints the flag if it has no stdin, doesn't see $E:FOO,
+	// and is not run in the current directory.
+	must.WriteFile("cmd", "#!/bin/sh\n"+
+		"if ! read line && [ -z \"$FOO\" ] && [ ! -e marker ]; then\n"+
+		"  echo '  --sandboxed  ok'\n"+
+		"fi\n")
+	must.OK(os.Chmod("cmd", 0o755))
+
+	got := helpFlags(filepath.Join(dir, "cmd"))
+	want := []helpFlag{{name: "--sandboxed", desc: "ok"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
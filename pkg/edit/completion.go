@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 	"unicode/utf8"
 
 	"src.elv.sh/pkg/cli/modes"
@@ -61,7 +63,7 @@ func completionStart(ed *Editor, bindings tk.Bindings, ev *eval.Evaler, cfg comp
 	result, err := complete.Complete(
 		complete.CodeBuffer{Content: buf.Content, Dot: buf.Dot}, ev, cfg)
 	if err != nil {
-		ed.app.Notify(modes.ErrorText(err))
+		ed.app.Notify(modes.ErrorText(ed.app.Styles(), err))
 		return
 	}
 	if smart {
@@ -101,7 +103,7 @@ func completionStart(ed *Editor, bindings tk.Bindings, ev *eval.Evaler, cfg comp
 		ed.app.PushAddon(w)
 	}
 	if err != nil {
-		ed.app.Notify(modes.ErrorText(err))
+		ed.app.Notify(modes.ErrorText(ed.app.Styles(), err))
 	}
 }
 
@@ -110,25 +112,31 @@ func initCompletion(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
 	bindings := newMapBindings(ed, ev, bindingVar)
 	matcherMapVar := newMapVar(vals.EmptyMap)
 	argGeneratorMapVar := newMapVar(vals.EmptyMap)
+	cacheTTLVar := newFloatVar(0)
+	cache := newArgGeneratorCache()
 	cfg := func() complete.Config {
+		ttl := time.Duration(cacheTTLVar.Get().(float64) * float64(time.Second))
 		return complete.Config{
 			Filterer: adaptMatcherMap(
 				ed, ev, matcherMapVar.Get().(vals.Map)),
 			ArgGenerator: adaptArgGeneratorMap(
-				ev, argGeneratorMapVar.Get().(vals.Map)),
+				ev, argGeneratorMapVar.Get().(vals.Map), cache, ttl),
 		}
 	}
 	generateForSudo := func(args []string) ([]complete.RawItem, error) {
 		return complete.GenerateForSudo(args, ev, cfg())
 	}
 	nb.AddGoFns(map[string]any{
-		"complete-filename": wrapArgGenerator(complete.GenerateFileNames),
-		"complete-getopt":   completeGetopt,
-		"complete-sudo":     wrapArgGenerator(generateForSudo),
-		"complex-candidate": complexCandidate,
-		"match-prefix":      wrapMatcher(strings.HasPrefix),
-		"match-subseq":      wrapMatcher(strutil.HasSubseq),
-		"match-substr":      wrapMatcher(strings.Contains),
+		"complete-bash":       completeBash,
+		"complete-filename":   wrapArgGenerator(complete.GenerateFileNames),
+		"complete-fish":       completeFish,
+		"complete-getopt":     completeGetopt,
+		"complete-help-flags": restrictedArgGenerator("running commands", completeHelpFlags),
+		"complete-sudo":       wrapArgGenerator(generateForSudo),
+		"complex-candidate":   complexCandidate,
+		"match-prefix":        wrapMatcher(strings.HasPrefix),
+		"match-subseq":        wrapMatcher(strutil.HasSubseq),
+		"match-substr":        wrapMatcher(strings.Contains),
 	})
 	app := ed.app
 	nb.AddNs("completion",
@@ -136,10 +144,12 @@ func initCompletion(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
 			AddVars(map[string]vars.Var{
 				"arg-completer": argGeneratorMapVar,
 				"binding":       bindingVar,
+				"cache-ttl":     cacheTTLVar,
 				"matcher":       matcherMapVar,
 			}).
 			AddGoFns(map[string]any{
 				"accept":      func() { listingAccept(app) },
+				"clear-cache": cache.clear,
 				"smart-start": func() { completionStart(ed, bindings, ev, cfg(), true) },
 				"start":       func() { completionStart(ed, bindings, ev, cfg(), false) },
 				"up":          func() { listingUp(app) },
@@ -202,24 +212,39 @@ func wrapArgGenerator(gen complete.ArgGenerator) wrappedArgGenerator {
 		if err != nil {
 			return err
 		}
-		out := fm.ValueOutput()
-		for _, rawItem := range rawItems {
-			var v any
-			switch rawItem := rawItem.(type) {
-			case complete.ComplexItem:
-				v = complexItem(rawItem)
-			case complete.PlainItem:
-				v = string(rawItem)
-			default:
-				v = rawItem
-			}
-			err := out.Put(v)
-			if err != nil {
-				return err
-			}
+		return putRawItems(fm, rawItems)
+	}
+}
+
+// Like wrapArgGenerator, but throws a SecurityError in restricted mode.
+func restrictedArgGenerator(op string, gen complete.ArgGenerator) wrappedArgGenerator {
+	wrapped := wrapArgGenerator(gen)
+	return func(fm *eval.Frame, args ...string) error {
+		if err := fm.Evaler.CheckRestricted(op); err != nil {
+			return err
+		}
+		return wrapped(fm, args...)
+	}
+}
+
+func putRawItems(fm *eval.Frame, items []complete.RawItem) error {
+	out := fm.ValueOutput()
+	for _, item := range items {
+		var v any
+		switch item := item.(type) {
+		case complete.ComplexItem:
+			v = complexItem(item)
+		case complete.PlainItem:
+			v = string(item)
+		default:
+			v = item
+		}
+		err := out.Put(v)
+		if err != nil {
+			return err
 		}
-		return nil
 	}
+	return nil
 }
 
 func commonPrefix(s1, s2 string) string {
@@ -342,7 +367,10 @@ func adaptMatcherMap(nt notifier, ev *eval.Evaler, m vals.Map) complete.Filterer
 	}
 }
 
-func adaptArgGeneratorMap(ev *eval.Evaler, m vals.Map) complete.ArgGenerator {
+// Converts a map of argument completers to a complete.ArgGenerator. If ttl is
+// positive, the outputs of the argument completers are cached in cache for that
+// long.
+func adaptArgGeneratorMap(ev *eval.Evaler, m vals.Map, cache *argGeneratorCache, ttl time.Duration) complete.ArgGenerator {
 	return func(args []string) ([]complete.RawItem, error) {
 		gen, ok := lookupFn(m, args[0])
 		if !ok {
@@ -351,54 +379,117 @@ func adaptArgGeneratorMap(ev *eval.Evaler, m vals.Map) complete.ArgGenerator {
 		if gen == nil {
 			return complete.GenerateFileNames(args)
 		}
-		argValues := make([]any, len(args))
-		for i, arg := range args {
-			argValues[i] = arg
+		if ttl <= 0 {
+			return callArgGenerator(ev, gen, args)
 		}
-		var output []complete.RawItem
-		var outputMutex sync.Mutex
-		collect := func(item complete.RawItem) {
-			outputMutex.Lock()
-			defer outputMutex.Unlock()
-			output = append(output, item)
+		// The cached output is shared by all the words being completed after
+		// the same preceding words, so the completer is called with an empty
+		// word, and the candidates are then narrowed by the matcher.
+		preceding := args[:len(args)-1]
+		return cache.get(preceding, ttl, func() ([]complete.RawItem, error) {
+			return callArgGenerator(ev, gen, append(slices.Clip(preceding), ""))
+		})
+	}
+}
+
+// Caches the outputs of argument completers, indexed by the working directory,
+// the command and the arguments before the one being completed.
+type argGeneratorCache struct {
+	mutex   sync.Mutex
+	entries map[string]argGeneratorCacheEntry
+}
+
+type argGeneratorCacheEntry struct {
+	items   []complete.RawItem
+	expires time.Time
+}
+
+func newArgGeneratorCache() *argGeneratorCache {
+	return &argGeneratorCache{entries: make(map[string]argGeneratorCacheEntry)}
+}
+
+// Returns the cached output for the command and preceding arguments if it
+// hasn't expired, or calls gen and caches its output for ttl if it doesn't
+// return an error.
+func (c *argGeneratorCache) get(preceding []string, ttl time.Duration, gen func() ([]complete.RawItem, error)) ([]complete.RawItem, error) {
+	wd, _ := os.Getwd()
+	key := wd + "\x00" + strings.Join(preceding, "\x00")
+	now := time.Now()
+	c.mutex.Lock()
+	entry, ok := c.entries[key]
+	c.mutex.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.items, nil
+	}
+	items, err := gen()
+	if err != nil {
+		return items, err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for k, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, k)
 		}
-		valueCb := func(ch <-chan any) {
-			for v := range ch {
-				switch v := v.(type) {
-				case string:
-					collect(complete.PlainItem(v))
-				case complexItem:
-					collect(complete.ComplexItem(v))
-				default:
-					collect(complete.PlainItem(vals.ToString(v)))
-				}
+	}
+	c.entries[key] = argGeneratorCacheEntry{items, now.Add(ttl)}
+	return items, nil
+}
+
+func (c *argGeneratorCache) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = make(map[string]argGeneratorCacheEntry)
+}
+
+func callArgGenerator(ev *eval.Evaler, gen eval.Callable, args []string) ([]complete.RawItem, error) {
+	argValues := make([]any, len(args))
+	for i, arg := range args {
+		argValues[i] = arg
+	}
+	var output []complete.RawItem
+	var outputMutex sync.Mutex
+	collect := func(item complete.RawItem) {
+		outputMutex.Lock()
+		defer outputMutex.Unlock()
+		output = append(output, item)
+	}
+	valueCb := func(ch <-chan any) {
+		for v := range ch {
+			switch v := v.(type) {
+			case string:
+				collect(complete.PlainItem(v))
+			case complexItem:
+				collect(complete.ComplexItem(v))
+			default:
+				collect(complete.PlainItem(vals.ToString(v)))
 			}
 		}
-		bytesCb := func(r *os.File) {
-			buffered := bufio.NewReader(r)
-			for {
-				line, err := buffered.ReadString('\n')
-				if line != "" {
-					collect(complete.PlainItem(strutil.ChopLineEnding(line)))
-				}
-				if err != nil {
-					break
-				}
+	}
+	bytesCb := func(r *os.File) {
+		buffered := bufio.NewReader(r)
+		for {
+			line, err := buffered.ReadString('\n')
+			if line != "" {
+				collect(complete.PlainItem(strutil.ChopLineEnding(line)))
+			}
+			if err != nil {
+				break
 			}
 		}
-		port1, done, err := eval.PipePort(valueCb, bytesCb)
-		if err != nil {
-			panic(err)
-		}
-		err = ev.Call(gen,
-			eval.CallCfg{Args: argValues, From: "[editor arg generator]"},
-			eval.EvalCfg{Ports: []*eval.Port{
-				// TODO: Supply the Chan component of port 2.
-				nil, port1, {File: os.Stderr}}})
-		done()
-
-		return output, err
 	}
+	port1, done, err := eval.PipePort(valueCb, bytesCb)
+	if err != nil {
+		panic(err)
+	}
+	err = ev.Call(gen,
+		eval.CallCfg{Args: argValues, From: "[editor arg generator]"},
+		eval.EvalCfg{Ports: []*eval.Port{
+			// TODO: Supply the Chan component of port 2.
+			nil, port1, {File: os.Stderr}}})
+	done()
+
+	return output, err
 }
 
 func lookupFn(m vals.Map, ctxName string) (eval.Callable, bool) {
@@ -155,6 +155,62 @@ func TestCompleteSudo(t *testing.T) {
 	testGlobal(t, f.Evaler, "cands", vals.MakeList("val1", "val2"))
 }
 
+func TestCompletionCache(t *testing.T) {
+	f := setup(t)
+
+	evals(f.Evaler,
+		`var n = 0`,
+		`set edit:completion:arg-completer[foo] = {|@args|
+		   set n = (+ $n 1)
+		   put val$n
+		 }`,
+		`var @cands1 = (edit:complete-sudo sudo foo '')`,
+		`var @cands2 = (edit:complete-sudo sudo foo '')`)
+	// Without a TTL, the completer is called every time.
+	testGlobal(t, f.Evaler, "cands2", vals.MakeList("val2"))
+
+	evals(f.Evaler,
+		`set edit:completion:cache-ttl = 60`,
+		`var @cands3 = (edit:complete-sudo sudo foo '')`,
+		`var @cands4 = (edit:complete-sudo sudo foo '')`,
+		`var @cands5 = (edit:complete-sudo sudo foo x)`,
+		`var @cands6 = (edit:complete-sudo sudo foo a '')`)
+	testGlobal(t, f.Evaler, "cands4", vals.MakeList("val3"))
+	// The word being completed is not part of the key.
+	testGlobal(t, f.Evaler, "cands5", vals.MakeList("val3"))
+	// Different preceding arguments are cached separately.
+	testGlobal(t, f.Evaler, "cands6", vals.MakeList("val4"))
+
+	evals(f.Evaler,
+		`edit:completion:clear-cache`,
+		`var @cands7 = (edit:complete-sudo sudo foo '')`)
+	testGlobal(t, f.Evaler, "cands7", vals.MakeList("val5"))
+
+	evals(f.Evaler,
+		`set edit:completion:cache-ttl = 0.001`,
+		`edit:completion:clear-cache`,
+		`var @cands8 = (edit:complete-sudo sudo foo y)`,
+		`sleep 0.01`,
+		`var @cands9 = (edit:complete-sudo sudo foo y)`)
+	// Cache entries expire.
+	testGlobal(t, f.Evaler, "cands9", vals.MakeList("val7"))
+}
+
+func TestCompletionCache_CallsCompleterWithEmptyWord(t *testing.T) {
+	f := setup(t)
+
+	evals(f.Evaler,
+		`set edit:completion:cache-ttl = 60`,
+		`var seen = []`,
+		`set edit:completion:arg-completer[foo] = {|@args|
+		   set seen = [$@seen $args]
+		   put val1 val2
+		 }`,
+		`var @cands = (edit:complete-sudo sudo foo a v)`)
+	testGlobal(t, f.Evaler, "seen", vals.MakeList(vals.MakeList("foo", "a", "")))
+	testGlobal(t, f.Evaler, "cands", vals.MakeList("val1", "val2"))
+}
+
 func TestCompletionMatcher(t *testing.T) {
 	f := setup(t)
 
@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"src.elv.sh/pkg/cli"
 	"src.elv.sh/pkg/cli/histutil"
+	"src.elv.sh/pkg/cli/term"
 	"src.elv.sh/pkg/diag"
 	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/errs"
 	"src.elv.sh/pkg/eval/vals"
 	"src.elv.sh/pkg/eval/vars"
 	"src.elv.sh/pkg/store/storedefs"
@@ -20,6 +23,30 @@ func initMaxHeight(appSpec *cli.AppSpec, nb eval.NsBuilder) {
 	nb.AddVar("max-height", maxHeight)
 }
 
+func initMouse(appSpec *cli.AppSpec, nb eval.NsBuilder) {
+	mouse := newBoolVar(true)
+	appSpec.Mouse = func() bool { return mouse.GetRaw().(bool) }
+	nb.AddVar("mouse", mouse)
+}
+
+func initKeySeqTimeout(nb eval.NsBuilder) {
+	nb.AddVar("key-seq-timeout", vars.FromSetGet(
+		func(v any) error {
+			var seconds float64
+			err := vals.ScanToGo(v, &seconds)
+			if err != nil {
+				return err
+			}
+			if seconds < 0 {
+				return errs.BadValue{What: "$edit:key-seq-timeout",
+					Valid: "non-negative number", Actual: vals.ReprPlain(v)}
+			}
+			term.SetKeySeqTimeout(time.Duration(seconds * float64(time.Second)))
+			return nil
+		},
+		func() any { return term.KeySeqTimeout().Seconds() }))
+}
+
 func initReadlineHooks(appSpec *cli.AppSpec, ev *eval.Evaler, nb eval.NsBuilder) {
 	initBeforeReadline(appSpec, ev, nb)
 	initAfterReadline(appSpec, ev, nb)
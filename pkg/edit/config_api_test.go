@@ -2,12 +2,56 @@ package edit
 
 import (
 	"testing"
+	"time"
 
 	"src.elv.sh/pkg/cli/term"
 	"src.elv.sh/pkg/store/storedefs"
 	"src.elv.sh/pkg/ui"
 )
 
+func TestKeySeqTimeout(t *testing.T) {
+	f := setup(t)
+	t.Cleanup(func() { term.SetKeySeqTimeout(10 * time.Millisecond) })
+
+	evals(f.Evaler,
+		`set edit:key-seq-timeout = 0.5`,
+		`var timeout = $edit:key-seq-timeout`,
+		`var set-negative = ?(set edit:key-seq-timeout = -1)`)
+	if got := term.KeySeqTimeout(); got != 500*time.Millisecond {
+		t.Errorf("got key sequence timeout %v, want 500ms", got)
+	}
+	testGlobal(t, f.Evaler, "timeout", 0.5)
+	// Exceptions are booleanly false
+	evals(f.Evaler, `var ok = (bool $set-negative)`)
+	testGlobal(t, f.Evaler, "ok", false)
+}
+
+func TestMouse(t *testing.T) {
+	f := setup(t)
+
+	evals(f.Evaler, `edit:location:start`)
+	f.TestTTY(t,
+		"~> \n",
+		" LOCATION  ", Styles,
+		"********** ", term.DotHere,
+	)
+	if !f.TTYCtrl.MouseTracking() {
+		t.Errorf("mouse tracking not turned on in location mode")
+	}
+
+	evals(f.Evaler, `set edit:mouse = $false`)
+	// Type into the filter to wait for a redraw.
+	feedInput(f.TTYCtrl, "x")
+	f.TestTTY(t,
+		"~> \n",
+		" LOCATION  x", Styles,
+		"**********  ", term.DotHere,
+	)
+	if f.TTYCtrl.MouseTracking() {
+		t.Errorf("mouse tracking not turned off after setting $edit:mouse")
+	}
+}
+
 func TestBeforeReadline(t *testing.T) {
 	f := setup(t, rc(
 		`var called = 0`,
@@ -136,10 +180,10 @@ func TestAddCmdFilters_SkipsRemainingOnFalse(t *testing.T) {
 func TestGlobalBindings(t *testing.T) {
 	f := setup(t, rc(
 		`var called = $false`,
-		`set edit:global-binding[Ctrl-X] = { set called = $true }`,
+		`set edit:global-binding[Ctrl-B] = { set called = $true }`,
 	))
 
-	f.TTYCtrl.Inject(term.K('X', ui.Ctrl))
+	f.TTYCtrl.Inject(term.K('B', ui.Ctrl))
 	f.TTYCtrl.Inject(term.K(ui.Enter))
 	f.Wait()
 
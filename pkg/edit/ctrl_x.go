@@ -0,0 +1,45 @@
+package edit
+
+// Implementation of the Ctrl-X prefix mode, used for Emacs-style key sequences
+// like Ctrl-X Ctrl-E.
+
+import (
+	"src.elv.sh/pkg/cli"
+	"src.elv.sh/pkg/cli/modes"
+	"src.elv.sh/pkg/cli/term"
+	"src.elv.sh/pkg/cli/tk"
+	"src.elv.sh/pkg/eval"
+)
+
+func initCtrlX(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
+	bindingVar := newBindingVar(emptyBindingsMap)
+	bindings := prefixBindings{ed.app, newMapBindings(ed, ev, bindingVar)}
+	nb.AddNs("ctrl-x",
+		eval.BuildNsNamed("edit:ctrl-x").
+			AddVar("binding", bindingVar).
+			AddGoFns(map[string]any{
+				"start": func() {
+					w := modes.NewStub(ed.app, modes.StubSpec{
+						Bindings: bindings,
+						Name:     " CTRL-X ",
+					})
+					ed.app.PushAddon(w)
+				},
+			}))
+}
+
+// Bindings for a mode that only handles the next key: the mode is closed
+// before the binding is called, and keys without bindings are ignored.
+type prefixBindings struct {
+	app cli.App
+	tk.Bindings
+}
+
+func (b prefixBindings) Handle(w tk.Widget, e term.Event) bool {
+	if _, ok := e.(term.KeyEvent); !ok {
+		return false
+	}
+	closeMode(b.app)
+	b.Bindings.Handle(w, e)
+	return true
+}
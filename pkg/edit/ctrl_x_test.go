@@ -0,0 +1,30 @@
+package edit
+
+import (
+	"testing"
+
+	"src.elv.sh/pkg/cli/term"
+	"src.elv.sh/pkg/ui"
+)
+
+func TestCtrlXMode(t *testing.T) {
+	f := setup(t)
+
+	evals(f.Evaler, `set edit:ctrl-x:binding[Ctrl-A] = { edit:insert-at-dot foo }`)
+	f.TTYCtrl.Inject(term.K('X', ui.Ctrl))
+	f.TestTTY(t,
+		"~> ", term.DotHere, "\n",
+		" CTRL-X ", Styles,
+		"********",
+	)
+
+	// The mode is closed before calling the binding.
+	f.TTYCtrl.Inject(term.K('A', ui.Ctrl))
+	f.TestTTY(t, "~> foo", Styles,
+		"   !!!", term.DotHere)
+
+	// Keys without bindings just close the mode.
+	f.TTYCtrl.Inject(term.K('X', ui.Ctrl), term.K('B', ui.Ctrl))
+	f.TestTTY(t, "~> foo", Styles,
+		"   !!!", term.DotHere)
+}
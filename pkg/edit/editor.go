@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"src.elv.sh/pkg/cli"
 	"src.elv.sh/pkg/eval"
@@ -34,9 +35,22 @@ type Editor struct {
 	// set in initHighlighter.
 	applyAutofix func()
 
+	// Name of the source used when checking the code being edited, and the
+	// code compiled by the last check. These fields are used in
+	// initHighlighter.
+	sourceName atomic.Value
+	compiled   atomic.Pointer[eval.Compiled]
+
 	// Maybe move this to another type that represents the REPL cycle as a whole, not just the
 	// read/edit portion represented by the Editor type.
 	AfterCommand []func(src parse.Source, duration float64, err error)
+	// Callbacks run before and after each pipeline at the top level of an
+	// interactive command.
+	BeforePipeline []func(code string, start time.Time)
+	AfterPipeline  []func(code string, start time.Time, duration float64, err error)
+
+	// The value of $edit:pager. This field is set in initRepl.
+	pager vars.PtrVar
 }
 
 // An interface that wraps notifyf and notifyError. It is only implemented by
@@ -64,17 +78,23 @@ func NewEditor(tty cli.TTY, ev *eval.Evaler, st storedefs.Store) *Editor {
 	}
 
 	initMaxHeight(&appSpec, nb)
+	initMouse(&appSpec, nb)
+	initKeySeqTimeout(nb)
 	initReadlineHooks(&appSpec, ev, nb)
 	initAddCmdFilters(&appSpec, ev, nb, hs)
 	initGlobalBindings(&appSpec, ed, ev, nb)
 	initInsertAPI(&appSpec, ed, ev, nb)
-	initHighlighter(&appSpec, ed, ev, nb)
+	appSpec.Styles = initStyles(nb)
+	initHighlighter(&appSpec, ed, ev, appSpec.Styles, nb)
 	initPrompts(&appSpec, ed, ev, nb)
+	initTerminalReports(&appSpec, ed, ev, tty, nb)
+	initLocalRC(&appSpec, ed, ev, st, nb)
 	ed.app = cli.NewApp(appSpec)
 
 	initExceptionsAPI(ed, nb)
 	initVarsAPI(nb)
 	initCommandAPI(ed, ev, nb)
+	initCtrlX(ed, ev, nb)
 	initListings(ed, ev, st, hs, nb)
 	initNavigation(ed, ev, nb)
 	initCompletion(ed, ev, nb)
@@ -82,7 +102,8 @@ func NewEditor(tty cli.TTY, ev *eval.Evaler, st storedefs.Store) *Editor {
 	initInstant(ed, ev, nb)
 	initMinibuf(ed, ev, nb)
 
-	initRepl(ed, ev, nb)
+	initRepl(ed, ev, tty, nb)
+	initPromptSegments(ed, tty, nb)
 	initBufferBuiltins(ed.app, nb)
 	initTTYBuiltins(ed.app, tty, nb)
 	initMiscBuiltins(ed, nb)
@@ -116,6 +137,24 @@ func (ed *Editor) ReadCode() (string, error) {
 	return ed.app.ReadCode()
 }
 
+// SetSourceName sets the name of the source used when checking the code being
+// edited, which should be the name that will be used when evaluating it.
+func (ed *Editor) SetSourceName(name string) {
+	ed.sourceName.Store(name)
+}
+
+// Compiled returns the code compiled when checking the code being edited, if
+// it has the same source as src and has no parse or compilation errors, or nil
+// otherwise. It can be passed to [eval.Evaler.EvalCompiled] to avoid parsing
+// and compiling the code again.
+func (ed *Editor) Compiled(src parse.Source) *eval.Compiled {
+	c := ed.compiled.Load()
+	if c == nil || c.Source() != src {
+		return nil
+	}
+	return c
+}
+
 // Notify adds a note to the notification buffer.
 func (ed *Editor) Notify(note ui.Text) {
 	ed.app.Notify(note)
@@ -128,6 +167,32 @@ func (ed *Editor) RunAfterCommandHooks(src parse.Source, duration float64, err e
 	}
 }
 
+// RunBeforePipelineHooks runs callbacks before a pipeline at the top level of
+// an interactive command is executed.
+func (ed *Editor) RunBeforePipelineHooks(code string, start time.Time) {
+	for _, f := range ed.BeforePipeline {
+		f(code, start)
+	}
+}
+
+// RunAfterPipelineHooks runs callbacks after a pipeline at the top level of an
+// interactive command has been executed.
+func (ed *Editor) RunAfterPipelineHooks(code string, start time.Time, duration float64, err error) {
+	for _, f := range ed.AfterPipeline {
+		f(code, start, duration, err)
+	}
+}
+
+// Pager returns the command of the pager to use when the value outputs of an
+// interactive command don't fit in the terminal, or nil if paging is disabled.
+func (ed *Editor) Pager() []string {
+	var pager []string
+	for it := ed.pager.Get().(vals.List).Iterator(); it.HasElem(); it.Next() {
+		pager = append(pager, vals.ToString(it.Elem()))
+	}
+	return pager
+}
+
 // Ns returns a namespace for manipulating the editor from Elvish code.
 //
 // See https://elv.sh/ref/edit.html for the Elvish API.
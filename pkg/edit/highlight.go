@@ -14,10 +14,15 @@ import (
 	"src.elv.sh/pkg/ui"
 )
 
-func initHighlighter(appSpec *cli.AppSpec, ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
+func initHighlighter(appSpec *cli.AppSpec, ed *Editor, ev *eval.Evaler, stylingFor func(string) ui.Styling, nb eval.NsBuilder) {
 	hl := highlight.NewHighlighter(highlight.Config{
-		Check: func(t parse.Tree) (string, []diag.RangeError) {
-			autofixes, err := ev.CheckTree(t, nil)
+		Check: func(t parse.Tree, parseErr error) (string, []diag.RangeError) {
+			compiled, autofixes, err := ev.CompileTree(t, nil)
+			if parseErr != nil {
+				// The tree is only partial.
+				compiled = nil
+			}
+			ed.compiled.Store(compiled)
 			autofix := strings.Join(autofixes, "; ")
 			ed.autofix.Store(autofix)
 
@@ -35,6 +40,13 @@ func initHighlighter(appSpec *cli.AppSpec, ed *Editor, ev *eval.Evaler, nb eval.
 				bindingTip("autofix: "+autofix, "apply-autofix"),
 				bindingTip("autofix first", "smart-enter", "completion:smart-start"))
 		},
+		Styling: stylingFor,
+		SourceName: func() string {
+			if name, ok := ed.sourceName.Load().(string); ok {
+				return name
+			}
+			return "[interactive]"
+		},
 	})
 	appSpec.Highlighter = hl
 	ed.applyAutofix = func() {
@@ -73,8 +85,10 @@ func hasCommand(ev *eval.Evaler, cmd string) bool {
 		if hasFn(ev.Builtin(), first) || hasFn(ev.Global(), first) {
 			return true
 		}
-	case first == "e:":
+	case first == "e:" || first == "external:":
 		return hasExternalCommand(rest)
+	case first == "builtin:":
+		return hasFn(ev.Builtin(), rest)
 	default:
 		// Qualified name. Find the top-level module first.
 		if hasQualifiedFn(ev, first, rest) {
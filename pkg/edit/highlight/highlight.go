@@ -11,9 +11,25 @@ import (
 
 // Config keeps configuration for highlighting code.
 type Config struct {
-	Check      func(n parse.Tree) (string, []diag.RangeError)
+	// Checks the parsed code, returning an autofix and errors. The error from
+	// parsing the code is also passed; if it is not nil, the tree is only
+	// partial.
+	Check      func(t parse.Tree, parseErr error) (string, []diag.RangeError)
 	HasCommand func(name string) bool
 	AutofixTip func(autofix string) ui.Text
+	// Returns the styling for a style category, like "comment" or
+	// "bad-command". If nil, default stylings are used.
+	Styling func(category string) ui.Styling
+	// Returns the name of the source used when parsing the code. If nil,
+	// "[interactive]" is used.
+	SourceName func() string
+}
+
+func (cfg Config) sourceName() string {
+	if cfg.SourceName == nil {
+		return "[interactive]"
+	}
+	return cfg.SourceName()
 }
 
 // Information collected about a command region, used for asynchronous
@@ -40,13 +56,13 @@ func highlight(code string, cfg Config, lateCb func(ui.Text)) (ui.Text, []ui.Tex
 		}
 	}
 
-	tree, errParse := parse.Parse(parse.Source{Name: "[interactive]", Code: code}, parse.Config{})
+	tree, errParse := parse.Parse(parse.Source{Name: cfg.sourceName(), Code: code}, parse.Config{Recover: true})
 	for _, err := range parse.UnpackErrors(errParse) {
 		addDiagError(err)
 	}
 
 	if cfg.Check != nil {
-		autofix, diagErrors := cfg.Check(tree)
+		autofix, diagErrors := cfg.Check(tree, errParse)
 		for _, err := range diagErrors {
 			addDiagError(err)
 		}
@@ -77,10 +93,10 @@ func highlight(code string, cfg Config, lateCb func(ui.Text)) (ui.Text, []ui.Tex
 				cmdRegions = append(cmdRegions, cmdRegion{len(text), regionCode})
 			} else {
 				// Treat all commands as good commands.
-				styling = stylingForGoodCommand
+				styling = cfg.stylingFor("command")
 			}
 		} else {
-			styling = stylingFor[r.Type]
+			styling = cfg.stylingFor(categoryOf(r.Type))
 		}
 		seg := &ui.Segment{Text: regionCode}
 		if styling != nil {
@@ -103,9 +119,9 @@ func highlight(code string, cfg Config, lateCb func(ui.Text)) (ui.Text, []ui.Tex
 			for _, cmdRegion := range cmdRegions {
 				var styling ui.Styling
 				if cfg.HasCommand(cmdRegion.cmd) {
-					styling = stylingForGoodCommand
+					styling = cfg.stylingFor("command")
 				} else {
-					styling = stylingForBadCommand
+					styling = cfg.stylingFor("bad-command")
 				}
 				seg := &newText[cmdRegion.seg]
 				*seg = ui.StyleSegment(*seg, styling)
@@ -59,6 +59,36 @@ func TestHighlighter_HighlightRegions(t *testing.T) {
 	)
 }
 
+func TestHighlighter_Styling(t *testing.T) {
+	testutil.Set(t, &maxBlockForLate, testutil.Scaled(100*time.Millisecond))
+	hl := NewHighlighter(Config{
+		HasCommand: func(name string) bool { return name == "ls" },
+		Styling: func(category string) ui.Styling {
+			switch category {
+			case "command":
+				return ui.FgBlue
+			case "bad-command":
+				return ui.Underlined
+			case "punctuation":
+				return ui.FgCyan
+			case "variable":
+				return ui.Bold
+			}
+			return nil
+		},
+	})
+
+	tt.Test(t, tt.Fn(hl.Get).Named("hl.Get"),
+		Args("ls [$x]").Rets(
+			ui.MarkLines(
+				"ls [$x]", ui.RuneStylesheet{
+					'/': ui.FgBlue, 'c': ui.FgCyan, 'b': ui.Bold},
+				"// cbbc"),
+			noTips),
+		Args("bad").Rets(ui.T("bad", ui.Underlined), noTips),
+	)
+}
+
 func TestHighlighter_ParseErrors(t *testing.T) {
 	hl := NewHighlighter(Config{})
 	tt.Test(t, tt.Fn(hl.Get).Named("hl.Get"),
@@ -74,6 +104,14 @@ func TestHighlighter_ParseErrors(t *testing.T) {
 				"ls $? ]", styles,
 				"vv $? ?"),
 			matchTexts("1:5", "1:7")),
+		// Code after an unexpected rune is still highlighted
+		Args("ls ] x\nls $x").Rets(
+			ui.MarkLines(
+				"ls ] x\n", styles,
+				"vv ?   ",
+				"ls $x", styles,
+				"vv $$"),
+			matchTexts("1:4")),
 		// Errors at the end are ignored
 		Args("ls $").Rets(any, noTips),
 		Args("ls [").Rets(any, noTips),
@@ -84,7 +122,7 @@ func TestHighlighter_AutofixesAndCheckErrors(t *testing.T) {
 	ev := eval.NewEvaler()
 	ev.AddModule("mod1", &eval.Ns{})
 	hl := NewHighlighter(Config{
-		Check: func(t parse.Tree) (string, []diag.RangeError) {
+		Check: func(t parse.Tree, _ error) (string, []diag.RangeError) {
 			autofixes, err := ev.CheckTree(t, nil)
 			compErrors := eval.UnpackCompilationErrors(err)
 			rangeErrors := make([]diag.RangeError, len(compErrors))
@@ -4,36 +4,40 @@ import (
 	"src.elv.sh/pkg/ui"
 )
 
-var stylingFor = map[string]ui.Styling{
-	barewordRegion:     nil,
-	singleQuotedRegion: ui.FgYellow,
-	doubleQuotedRegion: ui.FgYellow,
-	variableRegion:     ui.FgMagenta,
-	wildcardRegion:     nil,
-	tildeRegion:        nil,
-
-	commentRegion: ui.FgCyan,
-
-	">":  ui.FgGreen,
-	">>": ui.FgGreen,
-	"<":  ui.FgGreen,
-	"?>": ui.FgGreen,
-	"|":  ui.FgGreen,
-	"?(": ui.Bold,
-	"(":  ui.Bold,
-	")":  ui.Bold,
-	"[":  ui.Bold,
-	"]":  ui.Bold,
-	"{":  ui.Bold,
-	"}":  ui.Bold,
-	"&":  ui.Bold,
+// Default stylings for the style categories used by the highlighter.
+var defaultStylings = map[string]ui.Styling{
+	"bareword":      nil,
+	"single-quoted": ui.FgYellow,
+	"double-quoted": ui.FgYellow,
+	"variable":      ui.FgMagenta,
+	"wildcard":      nil,
+	"tilde":         nil,
+	"comment":       ui.FgCyan,
+	"operator":      ui.FgGreen,
+	"punctuation":   ui.Bold,
+	"command":       ui.FgGreen,
+	"bad-command":   ui.FgRed,
+	"keyword":       ui.FgYellow,
+	"syntax-error":  ui.Stylings(ui.FgBrightWhite, ui.BgRed),
+}
 
-	commandRegion: ui.FgGreen,
-	keywordRegion: ui.FgYellow,
-	errorRegion:   ui.Stylings(ui.FgBrightWhite, ui.BgRed),
+// Returns the style category of a region type. Most region types are also
+// style categories; the exceptions are operators, punctuation and errors.
+func categoryOf(regionType string) string {
+	switch regionType {
+	case ">", ">>", "<", "?>", "|":
+		return "operator"
+	case "?(", "(", ")", "[", "]", "{", "}", "&":
+		return "punctuation"
+	case errorRegion:
+		return "syntax-error"
+	}
+	return regionType
 }
 
-var (
-	stylingForGoodCommand = ui.FgGreen
-	stylingForBadCommand  = ui.FgRed
-)
+func (cfg Config) stylingFor(category string) ui.Styling {
+	if cfg.Styling != nil {
+		return cfg.Styling(category)
+	}
+	return defaultStylings[category]
+}
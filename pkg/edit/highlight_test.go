@@ -9,6 +9,7 @@ import (
 	"src.elv.sh/pkg/cli/term"
 	"src.elv.sh/pkg/env"
 	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/parse"
 	"src.elv.sh/pkg/testutil"
 	"src.elv.sh/pkg/tt"
 	"src.elv.sh/pkg/ui"
@@ -35,6 +36,33 @@ func TestHighlighter(t *testing.T) {
 	)
 }
 
+func TestHighlighter_Compiled(t *testing.T) {
+	f := setup(t)
+	f.Editor.SetSourceName("[tty 1]")
+
+	feedInput(f.TTYCtrl, "put $true")
+	f.TestTTY(t,
+		"~> put $true", Styles,
+		"   vvv $$$$$", term.DotHere,
+	)
+	if f.Editor.Compiled(parse.Source{Name: "[tty 1]", Code: "put $true"}) == nil {
+		t.Errorf("Compiled returns nil for the code being edited")
+	}
+	if f.Editor.Compiled(parse.Source{Name: "[tty 2]", Code: "put $true"}) != nil {
+		t.Errorf("Compiled returns non-nil for a different source name")
+	}
+
+	feedInput(f.TTYCtrl, "x")
+	f.TestTTY(t,
+		"~> put $truex", Styles,
+		"   vvv ??????", term.DotHere, "\n",
+		"compilation error: [tty 1]:1:5-10: variable $truex not found",
+	)
+	if f.Editor.Compiled(parse.Source{Name: "[tty 1]", Code: "put $truex"}) != nil {
+		t.Errorf("Compiled returns non-nil for code with compilation errors")
+	}
+}
+
 func TestHighlighter_Autofix(t *testing.T) {
 	f := setup(t)
 	f.Evaler.AddModule("mod1", &eval.Ns{})
@@ -70,6 +98,7 @@ func TestMakeHasCommand(t *testing.T) {
 			eval.BuildNs().
 				AddFn("good", goodFn).
 				AddNs("b", eval.BuildNs().AddFn("good", goodFn))))
+	ev.Eval(parse.Source{Name: "[test]", Code: "alias good-alias 'nop'"}, eval.EvalCfg{})
 
 	// Set up environment.
 	testDir := testutil.InTempDir(t)
@@ -96,9 +125,13 @@ func TestMakeHasCommand(t *testing.T) {
 
 		// Builtin function
 		Args(ev, "put").Rets(true),
+		Args(ev, "builtin:put").Rets(true),
+		Args(ev, "builtin:bad").Rets(false),
 
 		// User-defined function
 		Args(ev, "good").Rets(true),
+		// Alias
+		Args(ev, "good-alias").Rets(true),
 
 		// Function in modules
 		Args(ev, "a:good").Rets(true),
@@ -120,6 +153,7 @@ func TestMakeHasCommand(t *testing.T) {
 		// With explicit e:
 		Args(ev, "e:external").Rets(true),
 		Args(ev, "e:bad-external").Rets(false),
+		Args(ev, "external:external").Rets(true),
 
 		// Non-existent
 		Args(ev, "bad").Rets(false),
@@ -0,0 +1,106 @@
+package edit
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/store/storedefs"
+)
+
+type histStatsOpts struct {
+	Top  int
+	Days int
+}
+
+func (o *histStatsOpts) SetDefaultOptions() { o.Top = 10 }
+
+func histStats(opts histStatsOpts, db storedefs.Store) (vals.Map, error) {
+	if db == nil {
+		return nil, errStoreOffline
+	}
+	infos, err := db.CmdInfos(0, -1)
+	if err != nil {
+		return nil, err
+	}
+	var since time.Time
+	if opts.Days > 0 {
+		since = time.Now().AddDate(0, 0, -opts.Days)
+	}
+	return cmdStats(infos, opts.Top, since), nil
+}
+
+// Computes the statistics of the commands, only considering those added no
+// earlier than since if it's not the zero value.
+func cmdStats(infos []storedefs.CmdInfo, top int, since time.Time) vals.Map {
+	var (
+		total      int
+		countOf    = map[string]int{}
+		countOnDay = map[string]int{}
+		nResults   int
+		nFailed    int
+		duration   time.Duration
+	)
+	for _, info := range infos {
+		if !since.IsZero() && (info.Time.IsZero() || info.Time.Before(since)) {
+			continue
+		}
+		total++
+		if fields := strings.Fields(info.Text); len(fields) > 0 {
+			countOf[fields[0]]++
+		}
+		if !info.Time.IsZero() {
+			countOnDay[info.Time.Local().Format("2006-01-02")]++
+		}
+		if info.HasResult {
+			nResults++
+			duration += info.Duration
+			if info.Failed {
+				nFailed++
+			}
+		}
+	}
+
+	names := make([]string, 0, len(countOf))
+	for name := range countOf {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if countOf[names[i]] != countOf[names[j]] {
+			return countOf[names[i]] > countOf[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if top >= 0 && len(names) > top {
+		names = names[:top]
+	}
+	topCommands := vals.EmptyList
+	for _, name := range names {
+		topCommands = topCommands.Conj(
+			vals.MakeMap("command", name, "count", countOf[name]))
+	}
+
+	days := make([]string, 0, len(countOnDay))
+	for day := range countOnDay {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	perDay := vals.EmptyList
+	for _, day := range days {
+		perDay = perDay.Conj(vals.MakeMap("date", day, "count", countOnDay[day]))
+	}
+
+	var averageDuration, failureRate any
+	if nResults > 0 {
+		averageDuration = duration.Seconds() / float64(nResults)
+		failureRate = float64(nFailed) / float64(nResults)
+	}
+	return vals.MakeMap(
+		"total", total,
+		"top-commands", topCommands,
+		"per-day", perDay,
+		"with-result", nResults,
+		"average-duration", averageDuration,
+		"failure-rate", failureRate)
+}
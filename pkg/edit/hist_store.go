@@ -2,6 +2,7 @@ package edit
 
 import (
 	"sync"
+	"time"
 
 	"src.elv.sh/pkg/cli/histutil"
 	"src.elv.sh/pkg/store/storedefs"
@@ -13,6 +14,9 @@ type histStore struct {
 	m  sync.Mutex
 	db storedefs.Store
 	hs histutil.Store
+	// Sequence number of the last command added to the database whose result
+	// has not been recorded yet, or 0.
+	lastSeq int
 }
 
 func newHistStore(db storedefs.Store) (*histStore, error) {
@@ -23,7 +27,24 @@ func newHistStore(db storedefs.Store) (*histStore, error) {
 func (s *histStore) AddCmd(cmd storedefs.Cmd) (int, error) {
 	s.m.Lock()
 	defer s.m.Unlock()
-	return s.hs.AddCmd(cmd)
+	seq, err := s.hs.AddCmd(cmd)
+	if s.db != nil && err == nil {
+		s.lastSeq = seq
+	}
+	return seq, err
+}
+
+// SetLastCmdResult records the result of the last command added with AddCmd,
+// if it was added to the database and its result has not been recorded yet.
+func (s *histStore) SetLastCmdResult(duration time.Duration, failed bool) error {
+	s.m.Lock()
+	seq := s.lastSeq
+	s.lastSeq = 0
+	s.m.Unlock()
+	if seq == 0 {
+		return nil
+	}
+	return s.db.SetCmdResult(seq, duration, failed)
 }
 
 // AllCmds returns a slice of all interactive commands in oldest to newest order.
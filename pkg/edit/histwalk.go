@@ -2,21 +2,74 @@ package edit
 
 import (
 	"errors"
+	"math"
+	"sync/atomic"
+	"time"
 
 	"src.elv.sh/pkg/cli"
 	"src.elv.sh/pkg/cli/histutil"
 	"src.elv.sh/pkg/cli/modes"
 	"src.elv.sh/pkg/cli/tk"
 	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/store/storedefs"
 )
 
 func initHistWalk(ed *Editor, ev *eval.Evaler, hs *histStore, nb eval.NsBuilder) {
 	bindingVar := newBindingVar(emptyBindingsMap)
 	bindings := newMapBindings(ed, ev, bindingVar)
 	app := ed.app
+
+	maxEntriesVar := newIntVar(0)
+	maxAgeVar := newFloatVar(math.Inf(1))
+	compact := func() error {
+		if hs.db == nil {
+			return errStoreOffline
+		}
+		_, err := hs.db.CompactCmds(
+			maxEntriesVar.GetRaw().(int), secondsToMaxAge(maxAgeVar.GetRaw().(float64)))
+		return err
+	}
+	// Compact the history after the first command - which is the rc file - and
+	// every compactInterval commands after that. This is done in the
+	// background so that it doesn't delay the prompt, and skipped if the last
+	// compaction is still running.
+	var nCommands int
+	var compacting atomic.Bool
+	ed.AfterCommand = append(ed.AfterCommand,
+		func(parse.Source, float64, error) {
+			maxEntries := maxEntriesVar.GetRaw().(int)
+			maxAge := secondsToMaxAge(maxAgeVar.GetRaw().(float64))
+			hasLimit := maxEntries > 0 || maxAge > 0
+			if nCommands%compactInterval == 0 && hasLimit && hs.db != nil &&
+				compacting.CompareAndSwap(false, true) {
+				go func() {
+					defer compacting.Store(false)
+					_, err := hs.db.CompactCmds(maxEntries, maxAge)
+					notifyError(app, err)
+				}()
+			}
+			nCommands++
+		})
+	// Record the duration and status of interactive commands.
+	ed.AfterCommand = append(ed.AfterCommand,
+		func(_ parse.Source, duration float64, err error) {
+			errSet := hs.SetLastCmdResult(
+				time.Duration(duration*float64(time.Second)), err != nil)
+			// The entry may have been deleted while the command was running.
+			// Errors from the daemon are compared by their messages since they
+			// lose their identities.
+			if errSet != nil && errSet.Error() != storedefs.ErrNoMatchingCmd.Error() {
+				notifyError(app, errSet)
+			}
+		})
+
 	nb.AddNs("history",
 		eval.BuildNsNamed("edit:history").
 			AddVar("binding", bindingVar).
+			AddVar("max-entries", maxEntriesVar).
+			AddVar("max-age", maxAgeVar).
 			AddGoFns(map[string]any{
 				"start": func() { notifyError(app, histwalkStart(app, hs, bindings)) },
 				"up":    func() { notifyError(app, histwalkDo(app, modes.Histwalk.Prev)) },
@@ -31,9 +84,24 @@ func initHistWalk(ed *Editor, ev *eval.Evaler, hs *histStore, nb eval.NsBuilder)
 				},
 				"accept":       func() { notifyError(app, histwalkDo(app, modes.Histwalk.Accept)) },
 				"fast-forward": hs.FastForward,
+				"compact":      compact,
+				"stats": func(opts histStatsOpts) (vals.Map, error) {
+					return histStats(opts, hs.db)
+				},
 			}))
 }
 
+const compactInterval = 100
+
+// Converts the value of $edit:history:max-age to the argument to
+// CompactCmds, where 0 means no limit.
+func secondsToMaxAge(seconds float64) time.Duration {
+	if seconds <= 0 || seconds >= float64(math.MaxInt64/time.Second) {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
 func histwalkStart(app cli.App, hs *histStore, bindings tk.Bindings) error {
 	codeArea, ok := focusedCodeArea(app)
 	if !ok {
@@ -61,6 +129,6 @@ func histwalkDo(app cli.App, f func(modes.Histwalk) error) error {
 
 func notifyError(app cli.App, err error) {
 	if err != nil {
-		app.Notify(modes.ErrorText(err))
+		app.Notify(modes.ErrorText(app.Styles(), err))
 	}
 }
@@ -1,10 +1,16 @@
 package edit
 
 import (
+	"errors"
+	"reflect"
 	"testing"
+	"time"
 
 	"src.elv.sh/pkg/cli/term"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/parse"
 	"src.elv.sh/pkg/store/storedefs"
+	"src.elv.sh/pkg/testutil"
 	"src.elv.sh/pkg/ui"
 )
 
@@ -78,6 +84,154 @@ func TestHistory_FastForward(t *testing.T) {
 	)
 }
 
+func TestHistoryCompact(t *testing.T) {
+	f := setup(t, storeOp(func(s storedefs.Store) {
+		s.AddCmd("echo a")
+		s.AddCmd("echo b")
+		s.AddCmd("echo c")
+		s.AddCmd("echo d")
+		s.SetCmdPinned(1, true)
+	}))
+
+	evals(f.Evaler, `edit:history:compact`)
+	testCmds(t, f.Store, "echo a", "echo b", "echo c", "echo d")
+
+	evals(f.Evaler, `set edit:history:max-entries = 2`, `edit:history:compact`)
+	testCmds(t, f.Store, "echo a", "echo c", "echo d")
+}
+
+func TestHistoryCompact_Automatic(t *testing.T) {
+	f := setup(t, storeOp(func(s storedefs.Store) {
+		s.AddCmd("echo a")
+		s.AddCmd("echo b")
+	}))
+	evals(f.Evaler, `set edit:history:max-entries = 1`)
+
+	// The history is compacted in the background after the first command.
+	f.Editor.RunAfterCommandHooks(parse.Source{Code: "echo c"}, 0, nil)
+	waitCmds(t, f.Store, "echo b")
+
+	// But not after the next few ones.
+	f.Store.AddCmd("echo c")
+	f.Editor.RunAfterCommandHooks(parse.Source{Code: "echo c"}, 0, nil)
+	testCmds(t, f.Store, "echo b", "echo c")
+}
+
+func TestHistory_RecordsCmdResult(t *testing.T) {
+	f := setup(t)
+
+	feedInput(f.TTYCtrl, "echo\n")
+	f.Wait()
+	f.Editor.RunAfterCommandHooks(parse.Source{Code: "echo"}, 1.5, errors.New("bad"))
+	// There is no command left whose result is not recorded.
+	f.Editor.RunAfterCommandHooks(parse.Source{Code: "echo"}, 2, nil)
+
+	infos, err := f.Store.CmdInfos(0, -1)
+	if err != nil || len(infos) != 1 {
+		t.Fatalf("got infos %v and error %v, want 1 info and no error", infos, err)
+	}
+	info := infos[0]
+	if !info.HasResult || info.Duration != 1500*time.Millisecond || !info.Failed {
+		t.Errorf("got info %v, want result with duration 1.5s and failed", info)
+	}
+}
+
+func TestHistoryStats(t *testing.T) {
+	f := setup(t, storeOp(func(s storedefs.Store) {
+		s.AddCmd("echo a")
+		s.AddCmd("ls")
+		s.AddCmd("echo b")
+		s.SetCmdResult(1, time.Second, false)
+		s.SetCmdResult(2, 2*time.Second, true)
+	}))
+
+	evals(f.Evaler, `var s = (edit:history:stats &top=1)`)
+	s := getGlobal(f.Evaler, "s")
+	for key, want := range map[string]any{
+		"total":            3,
+		"top-commands":     vals.MakeList(vals.MakeMap("command", "echo", "count", 2)),
+		"with-result":      2,
+		"average-duration": 1.5,
+		"failure-rate":     0.5,
+	} {
+		if got, _ := vals.Index(s, key); !vals.Equal(got, want) {
+			t.Errorf("$s[%s] = %s, want %s", key, vals.ReprPlain(got), vals.ReprPlain(want))
+		}
+	}
+}
+
+func TestCmdStats(t *testing.T) {
+	day1 := time.Date(2026, 10, 14, 12, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	infos := []storedefs.CmdInfo{
+		{Text: "make test", Seq: 1},
+		{Text: "ls", Seq: 2, Time: day1},
+		{Text: "make", Seq: 3, Time: day1, HasResult: true, Duration: time.Second, Failed: true},
+		{Text: "  git status", Seq: 4, Time: day2, HasResult: true, Duration: 3 * time.Second},
+		{Text: "ls -l", Seq: 5, Time: day2},
+	}
+
+	got := cmdStats(infos, 2, time.Time{})
+	want := vals.MakeMap(
+		"total", 5,
+		"top-commands", vals.MakeList(
+			vals.MakeMap("command", "ls", "count", 2),
+			vals.MakeMap("command", "make", "count", 2)),
+		"per-day", vals.MakeList(
+			vals.MakeMap("date", "2026-10-14", "count", 2),
+			vals.MakeMap("date", "2026-10-15", "count", 2)),
+		"with-result", 2,
+		"average-duration", 2.0,
+		"failure-rate", 0.5)
+	if !vals.Equal(got, want) {
+		t.Errorf("got %s, want %s", vals.ReprPlain(got), vals.ReprPlain(want))
+	}
+
+	got = cmdStats(infos, 10, day2)
+	want = vals.MakeMap(
+		"total", 2,
+		"top-commands", vals.MakeList(
+			vals.MakeMap("command", "git", "count", 1),
+			vals.MakeMap("command", "ls", "count", 1)),
+		"per-day", vals.MakeList(
+			vals.MakeMap("date", "2026-10-15", "count", 2)),
+		"with-result", 1,
+		"average-duration", 3.0,
+		"failure-rate", 0.0)
+	if !vals.Equal(got, want) {
+		t.Errorf("got %s, want %s", vals.ReprPlain(got), vals.ReprPlain(want))
+	}
+}
+
+func testCmds(t *testing.T, s storedefs.Store, wantTexts ...string) {
+	t.Helper()
+	cmds, err := s.CmdsWithSeq(0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		texts[i] = cmd.Text
+	}
+	if !reflect.DeepEqual(texts, wantTexts) {
+		t.Errorf("got commands %q, want %q", texts, wantTexts)
+	}
+}
+
+// Like testCmds, but waits for the commands to match for up to a second.
+func waitCmds(t *testing.T, s storedefs.Store, wantTexts ...string) {
+	t.Helper()
+	deadline := time.Now().Add(testutil.Scaled(time.Second))
+	for time.Now().Before(deadline) {
+		cmds, err := s.CmdsWithSeq(0, -1)
+		if err == nil && len(cmds) == len(wantTexts) {
+			break
+		}
+		time.Sleep(testutil.Scaled(time.Millisecond))
+	}
+	testCmds(t, s, wantTexts...)
+}
+
 func startHistwalkTest(t *testing.T) *fixture {
 	// The part of the test shared by all tests.
 	f := setup(t, storeOp(func(s storedefs.Store) {
@@ -30,13 +30,21 @@ func initInsertAPI(appSpec *cli.AppSpec, nt notifier, ev *eval.Evaler, nb eval.N
 		quotePaste.Set(!quotePaste.Get().(bool))
 	}
 
+	highlightBrackets := newBoolVar(true)
+	appSpec.HighlightBrackets = func() bool { return highlightBrackets.GetRaw().(bool) }
+
+	autoPair := newBoolVar(false)
+	appSpec.AutoPair = func() bool { return autoPair.GetRaw().(bool) }
+
 	nb.AddVar("abbr", simpleAbbrVar)
 	nb.AddVar("command-abbr", commandAbbrVar)
 	nb.AddVar("small-word-abbr", smallWordAbbrVar)
 	nb.AddGoFn("toggle-quote-paste", toggleQuotePaste)
 	nb.AddNs("insert", eval.BuildNs().
 		AddVar("binding", bindingVar).
-		AddVar("quote-paste", quotePaste))
+		AddVar("quote-paste", quotePaste).
+		AddVar("highlight-brackets", highlightBrackets).
+		AddVar("auto-pair", autoPair))
 }
 
 func makeMapIterator(mv vars.PtrVar) func(func(a, b string)) {
@@ -39,6 +39,6 @@ func instantStart(app cli.App, ev *eval.Evaler, bindings tk.Bindings) {
 		app.Redraw()
 	}
 	if err != nil {
-		app.Notify(modes.ErrorText(err))
+		app.Notify(modes.ErrorText(app.Styles(), err))
 	}
 }
@@ -228,7 +228,7 @@ func startMode(app cli.App, w tk.Widget, err error) {
 		app.Redraw()
 	}
 	if err != nil {
-		app.Notify(modes.ErrorText(err))
+		app.Notify(modes.ErrorText(app.Styles(), err))
 	}
 }
 
@@ -0,0 +1,203 @@
+package edit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"src.elv.sh/pkg/cli"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vars"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/store/storedefs"
+)
+
+// Name of the per-directory configuration file.
+const localRCName = ".elvish-local.elv"
+
+var errNoLocalRC = errors.New("no " + localRCName + " in the current directory or its parents")
+
+// A local rc file is loaded when the working directory is the directory
+// containing it or one of its descendants, and only when it has been trusted
+// with its current content. At most one local rc file is loaded at a time: the
+// one in the nearest ancestor of the working directory.
+//
+// A local rc file is evaluated like a module, and its variables are added to
+// the global namespace; when it gets unloaded, they are removed and the
+// variables they shadowed are restored.
+type localRC struct {
+	nt      notifier
+	ev      *eval.Evaler
+	st      storedefs.Store
+	enabled vars.PtrVar
+
+	mutex sync.Mutex
+	// Path and hash of the local rc file last considered for loading.
+	path, hash string
+	// Variables defined by the loaded local rc file, and the variables they
+	// shadowed, which are nil for names that were not defined before.
+	defined  map[string]vars.Var
+	shadowed map[string]vars.Var
+}
+
+func initLocalRC(appSpec *cli.AppSpec, nt notifier, ev *eval.Evaler, st storedefs.Store, nb eval.NsBuilder) {
+	l := &localRC{nt: nt, ev: ev, st: st, enabled: newBoolVar(false)}
+	// Local rc files are only loaded before reading a command, so that they
+	// don't take effect in the middle of a command that changes the working
+	// directory.
+	appSpec.BeforeReadline = append(appSpec.BeforeReadline, l.update)
+	nb.AddNs("local-rc",
+		eval.BuildNsNamed("edit:local-rc").
+			AddVar("enabled", l.enabled).
+			AddGoFns(map[string]any{
+				"trust":   l.trust,
+				"untrust": l.untrust,
+			}))
+}
+
+// Loads or unloads local rc files according to the current working directory.
+func (l *localRC) update() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var path, hash string
+	var content []byte
+	if l.enabled.Get().(bool) {
+		if p, err := findLocalRC(); err == nil {
+			content, err = os.ReadFile(p)
+			if err != nil {
+				l.nt.notifyError("local rc", err)
+			} else {
+				path, hash = p, storedefs.HashContent(content)
+			}
+		}
+	}
+	if path == l.path && hash == l.hash {
+		return
+	}
+	l.unload()
+	l.path, l.hash = path, hash
+	if path == "" {
+		return
+	}
+
+	if l.st == nil {
+		l.nt.notifyError("local rc", errStoreOffline)
+		return
+	}
+	trustedHash, err := l.st.TrustedHash(path)
+	if err != nil {
+		l.nt.notifyError("local rc", err)
+		return
+	}
+	if trustedHash != hash {
+		l.nt.notifyf("%s is not trusted; run edit:local-rc:trust to load it", path)
+		return
+	}
+	l.load(path, content)
+}
+
+func (l *localRC) load(path string, content []byte) {
+	src := parse.Source{Name: path, Code: string(content), IsFile: true}
+	var ns *eval.Ns
+	loadFn := eval.NewGoFn("[local rc]", func(fm *eval.Frame) error {
+		var err error
+		ns, err = fm.Eval(src, nil, new(eval.Ns))
+		return err
+	})
+	notifyPort, cleanup := makeNotifyPort(l.nt)
+	err := l.ev.Call(loadFn, eval.CallCfg{From: "[local rc]"},
+		eval.EvalCfg{Ports: []*eval.Port{nil, notifyPort, notifyPort}})
+	cleanup()
+	if err != nil {
+		l.nt.notifyError("local rc", err)
+		return
+	}
+
+	global := l.ev.Global()
+	nb := eval.BuildNs()
+	l.defined = make(map[string]vars.Var)
+	l.shadowed = make(map[string]vars.Var)
+	ns.IterateKeysString(func(name string) {
+		v := ns.IndexString(name)
+		nb.AddVar(name, v)
+		l.defined[name] = v
+		l.shadowed[name] = global.IndexString(name)
+	})
+	l.ev.ExtendGlobal(nb)
+}
+
+func (l *localRC) unload() {
+	if l.defined == nil {
+		return
+	}
+	names := make(map[string]struct{}, len(l.defined))
+	for name := range l.defined {
+		names[name] = struct{}{}
+	}
+	l.ev.DeleteFromGlobal(names)
+	nb := eval.BuildNs()
+	for name, v := range l.shadowed {
+		if v != nil {
+			nb.AddVar(name, v)
+		}
+	}
+	l.ev.ExtendGlobal(nb)
+	l.defined, l.shadowed = nil, nil
+}
+
+// Trusts the local rc file for the current working directory with its current
+// content. It is loaded before reading the next command.
+func (l *localRC) trust() error {
+	return l.setTrustedHash(func(content []byte) string { return storedefs.HashContent(content) })
+}
+
+// Stops trusting the local rc file for the current working directory. It is
+// unloaded before reading the next command.
+func (l *localRC) untrust() error {
+	return l.setTrustedHash(func([]byte) string { return "" })
+}
+
+func (l *localRC) setTrustedHash(f func(content []byte) string) error {
+	if l.st == nil {
+		return errStoreOffline
+	}
+	path, err := findLocalRC()
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	err = l.st.SetTrustedHash(path, f(content))
+	if err != nil {
+		return err
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	// Make the next update reconsider the file.
+	l.path, l.hash = "", ""
+	return nil
+}
+
+// Returns the path of the local rc file in the nearest ancestor of the working
+// directory, including the working directory itself.
+func findLocalRC() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, localRCName)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errNoLocalRC
+		}
+		dir = parent
+	}
+}
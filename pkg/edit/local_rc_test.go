@@ -0,0 +1,123 @@
+package edit
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/store"
+	"src.elv.sh/pkg/testutil"
+)
+
+type recordingNotifier struct{ notes []string }
+
+func (n *recordingNotifier) notifyf(format string, args ...any) {
+	n.notes = append(n.notes, fmt.Sprintf(format, args...))
+}
+
+func (n *recordingNotifier) notifyError(ctx string, e error) {
+	n.notifyf("[%v error] %v", ctx, e)
+}
+
+func (n *recordingNotifier) take() []string {
+	notes := n.notes
+	n.notes = nil
+	return notes
+}
+
+func TestLocalRC(t *testing.T) {
+	dir := testutil.InTempDir(t)
+	testutil.ApplyDir(testutil.Dir{
+		"project": testutil.Dir{
+			localRCName: "var x = project; fn greet { echo hello }",
+			"sub":       testutil.Dir{},
+		},
+		"other": testutil.Dir{},
+	})
+	rcPath := filepath.Join(dir, "project", localRCName)
+
+	nt := &recordingNotifier{}
+	ev := eval.NewEvaler()
+	evals(ev, "var x = global")
+	l := &localRC{nt: nt, ev: ev, st: store.MustTempStore(t), enabled: newBoolVar(false)}
+	update := func(wd string) {
+		t.Helper()
+		must.Chdir(filepath.Join(dir, wd))
+		l.update()
+	}
+	testNotes := func(want ...string) {
+		t.Helper()
+		if diff := cmp.Diff(want, nt.take()); diff != "" {
+			t.Errorf("notes (-want +got):\n%s", diff)
+		}
+	}
+
+	// Nothing happens when not enabled.
+	update("project")
+	testNotes()
+	testGlobal(t, ev, "x", "global")
+
+	// Untrusted files are not loaded, and only reported once.
+	l.enabled.Set(true)
+	update("project")
+	testNotes(rcPath + " is not trusted; run edit:local-rc:trust to load it")
+	update("project/sub")
+	testNotes()
+	testGlobal(t, ev, "x", "global")
+
+	// Trusted files are loaded in descendant directories too.
+	if err := l.trust(); err != nil {
+		t.Fatalf("trust: %v", err)
+	}
+	update("project/sub")
+	testNotes()
+	testGlobal(t, ev, "x", "project")
+	if !ev.Global().HasKeyString("greet~") {
+		t.Errorf("greet~ not defined")
+	}
+
+	// Definitions are removed when leaving the directory, restoring the
+	// variables they shadowed.
+	update("other")
+	testNotes()
+	testGlobal(t, ev, "x", "global")
+	if ev.Global().HasKeyString("greet~") {
+		t.Errorf("greet~ still defined")
+	}
+
+	// Changing the content of the file revokes the trust.
+	update("project")
+	testGlobal(t, ev, "x", "project")
+	must.WriteFile(rcPath, "var x = changed")
+	update("project")
+	testNotes(rcPath + " is not trusted; run edit:local-rc:trust to load it")
+	testGlobal(t, ev, "x", "global")
+
+	// Untrusting a file unloads it.
+	l.trust()
+	update("project")
+	testGlobal(t, ev, "x", "changed")
+	l.untrust()
+	update("project")
+	testNotes(rcPath + " is not trusted; run edit:local-rc:trust to load it")
+	testGlobal(t, ev, "x", "global")
+
+	// Errors are reported.
+	must.WriteFile(rcPath, "fail bad")
+	l.trust()
+	update("project")
+	if notes := nt.take(); len(notes) != 1 {
+		t.Errorf("got notes %q, want one note", notes)
+	}
+	testGlobal(t, ev, "x", "global")
+
+	// Trusting fails without a local rc file.
+	must.Chdir(filepath.Join(dir, "other"))
+	if err := l.trust(); err != errNoLocalRC {
+		t.Errorf("trust got error %v, want %v", err, errNoLocalRC)
+	}
+}
@@ -20,9 +20,10 @@ func initMinibuf(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
 
 func minibufStart(ed *Editor, ev *eval.Evaler, bindings tk.Bindings) {
 	w := tk.NewCodeArea(tk.CodeAreaSpec{
-		Prompt:   modes.Prompt(" MINIBUF ", true),
+		Prompt:   modes.Prompt(ed.app.Styles(), " MINIBUF ", true),
 		Bindings: bindings,
 		OnSubmit: func() { minibufSubmit(ed, ev) },
+		Styles:   ed.app.Styles(),
 		// TODO: Add Highlighter. Right now the async highlighter is not
 		// directly usable.
 	})
@@ -44,6 +45,6 @@ func minibufSubmit(ed *Editor, ev *eval.Evaler) {
 	ports := []*eval.Port{eval.DummyInputPort, notifyPort, notifyPort}
 	err := ev.Eval(src, eval.EvalCfg{Ports: ports})
 	if err != nil {
-		app.Notify(modes.ErrorText(err))
+		app.Notify(modes.ErrorText(app.Styles(), err))
 	}
 }
@@ -106,7 +106,7 @@ func initNavigation(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
 					},
 				})
 				if err != nil {
-					app.Notify(modes.ErrorText(err))
+					app.Notify(modes.ErrorText(app.Styles(), err))
 				} else {
 					startMode(app, w, nil)
 				}
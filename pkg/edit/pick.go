@@ -0,0 +1,83 @@
+package edit
+
+import (
+	"io"
+
+	"src.elv.sh/pkg/cli"
+	"src.elv.sh/pkg/cli/modes"
+	"src.elv.sh/pkg/cli/term"
+	"src.elv.sh/pkg/cli/tk"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/ui"
+)
+
+type pickOpts struct {
+	Multi   bool
+	Caption string
+}
+
+func (*pickOpts) SetDefaultOptions() {}
+
+func pick(fm *eval.Frame, tty cli.TTY, opts pickOpts, inputs eval.Inputs) error {
+	var values []any
+	inputs(func(v any) { values = append(values, v) })
+	picked, err := pickValues(tty, opts, values)
+	if err != nil {
+		return err
+	}
+	out := fm.ValueOutput()
+	for _, v := range picked {
+		err := out.Put(v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shows a picker for values on the terminal, and returns the picked values. It
+// returns no values if the picker is cancelled.
+func pickValues(tty cli.TTY, opts pickOpts, values []any) ([]any, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	items := make([]string, len(values))
+	for i, v := range values {
+		if s, ok := v.(string); ok {
+			items[i] = s
+		} else {
+			items[i] = vals.ReprPlain(v)
+		}
+	}
+
+	app := cli.NewApp(cli.AppSpec{TTY: tty})
+	var picked []any
+	w := modes.NewPicker(app, modes.PickerSpec{
+		Bindings: tk.FuncBindings(func(w tk.Widget, event term.Event) bool {
+			switch event {
+			case term.K('[', ui.Ctrl), term.K('C', ui.Ctrl), term.K('G', ui.Ctrl):
+				app.PopAddon()
+				app.CommitEOF()
+				return true
+			}
+			return false
+		}),
+		Caption: opts.Caption,
+		Items:   items,
+		Multi:   opts.Multi,
+		Accept: func(indices []int) {
+			for _, i := range indices {
+				picked = append(picked, values[i])
+			}
+			app.PopAddon()
+			app.CommitCode()
+		},
+	})
+	app.PushAddon(w)
+	_, err := app.ReadCode()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return picked, nil
+}
@@ -0,0 +1,72 @@
+package edit
+
+import (
+	"testing"
+
+	"src.elv.sh/pkg/cli/clitest"
+	"src.elv.sh/pkg/cli/term"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/ui"
+)
+
+var pickValuesTests = []struct {
+	name   string
+	opts   pickOpts
+	values []any
+	keys   []term.Event
+	want   []any
+}{
+	{
+		name:   "no values",
+		values: nil,
+		want:   nil,
+	},
+	{
+		name:   "accept selected",
+		values: []any{"foo", "bar", "baz"},
+		keys:   []term.Event{term.K(ui.Down), term.K(ui.Enter)},
+		want:   []any{"bar"},
+	},
+	{
+		name:   "filter",
+		values: []any{"foo", "bar", "baz"},
+		keys:   []term.Event{term.K('z'), term.K(ui.Enter)},
+		want:   []any{"baz"},
+	},
+	{
+		name:   "non-string values are matched by their repr",
+		values: []any{"foo", vals.MakeList("a", "b")},
+		keys:   []term.Event{term.K('['), term.K(ui.Enter)},
+		want:   []any{vals.MakeList("a", "b")},
+	},
+	{
+		name:   "multi",
+		opts:   pickOpts{Multi: true},
+		values: []any{"foo", "bar", "baz"},
+		keys: []term.Event{
+			term.K(ui.Tab), term.K(ui.Down), term.K(ui.Tab), term.K(ui.Enter)},
+		want: []any{"foo", "baz"},
+	},
+	{
+		name:   "cancel",
+		values: []any{"foo", "bar", "baz"},
+		keys:   []term.Event{term.K('G', ui.Ctrl)},
+		want:   nil,
+	},
+}
+
+func TestPickValues(t *testing.T) {
+	for _, test := range pickValuesTests {
+		t.Run(test.name, func(t *testing.T) {
+			tty, ttyCtrl := clitest.NewFakeTTY()
+			ttyCtrl.Inject(test.keys...)
+			picked, err := pickValues(tty, test.opts, test.values)
+			if err != nil {
+				t.Errorf("got error %v", err)
+			}
+			if got, want := vals.MakeList(picked...), vals.MakeList(test.want...); !vals.Equal(got, want) {
+				t.Errorf("got %s, want %s", vals.ReprPlain(got), vals.ReprPlain(want))
+			}
+		})
+	}
+}
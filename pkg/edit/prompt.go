@@ -18,23 +18,26 @@ import (
 
 func initPrompts(appSpec *cli.AppSpec, nt notifier, ev *eval.Evaler, nb eval.NsBuilder) {
 	promptVal, rpromptVal := getDefaultPromptVals()
-	initPrompt(&appSpec.Prompt, "prompt", promptVal, nt, ev, nb)
-	initPrompt(&appSpec.RPrompt, "rprompt", rpromptVal, nt, ev, nb)
+	staleTransform := eval.NewGoFn("<default stale transform>",
+		func(original ui.Text) ui.Text {
+			return ui.StyleText(original, appSpec.Styles.For("stale-prompt"))
+		})
+	initPrompt(&appSpec.Prompt, "prompt", promptVal, staleTransform, nt, ev, nb)
+	initPrompt(&appSpec.RPrompt, "rprompt", rpromptVal, staleTransform, nt, ev, nb)
 
 	rpromptPersistentVar := newBoolVar(false)
 	appSpec.RPromptPersistent = func() bool { return rpromptPersistentVar.Get().(bool) }
 	nb.AddVar("rprompt-persistent", rpromptPersistentVar)
 }
 
-func initPrompt(p *cli.Prompt, name string, val eval.Callable, nt notifier, ev *eval.Evaler, nb eval.NsBuilder) {
+func initPrompt(p *cli.Prompt, name string, val, staleTransform eval.Callable, nt notifier, ev *eval.Evaler, nb eval.NsBuilder) {
 	computeVar := vars.FromPtr(&val)
 	nb.AddVar(name, computeVar)
 	eagernessVar := newIntVar(5)
 	nb.AddVar("-"+name+"-eagerness", eagernessVar)
 	staleThresholdVar := newFloatVar(0.2)
 	nb.AddVar(name+"-stale-threshold", staleThresholdVar)
-	staleTransformVar := newFnVar(
-		eval.NewGoFn("<default stale transform>", defaultStaleTransform))
+	staleTransformVar := newFnVar(staleTransform)
 	nb.AddVar(name+"-stale-transform", staleTransformVar)
 
 	*p = prompt.New(prompt.Config{
@@ -85,10 +88,6 @@ func getDefaultRPrompt(username, hostname string) eval.Callable {
 	})
 }
 
-func defaultStaleTransform(original ui.Text) ui.Text {
-	return ui.StyleText(original, ui.Inverse)
-}
-
 // Calls a function with the given arguments and closed input, and concatenates
 // its outputs to a styled text. Used to call prompts and stale transformers.
 func callForStyledText(nt notifier, ev *eval.Evaler, ctx string, fn eval.Callable, args ...any) ui.Text {
@@ -0,0 +1,333 @@
+package edit
+
+// This file implements a framework for building prompts out of segments.
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"src.elv.sh/pkg/cli"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/errs"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/fsutil"
+	"src.elv.sh/pkg/mods/git"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/ui"
+	"src.elv.sh/pkg/wcwidth"
+)
+
+func initPromptSegments(ed *Editor, tty cli.TTY, nb eval.NsBuilder) {
+	var (
+		lastMutex    sync.Mutex
+		lastDuration float64
+		lastErr      error
+	)
+	ed.AfterCommand = append(ed.AfterCommand,
+		func(src parse.Source, duration float64, err error) {
+			lastMutex.Lock()
+			defer lastMutex.Unlock()
+			lastDuration, lastErr = duration, err
+		})
+
+	r := &segmentRenderer{tty: tty, cache: make(map[string]cachedSegment)}
+	nb.AddGoFn("render-segments", r.render)
+	nb.AddNs("segment", eval.BuildNs().AddGoFns(map[string]any{
+		"cwd":  func() string { return fsutil.Getwd() },
+		"git":  gitSegment,
+		"venv": venvSegment,
+		"duration": func(opts durationSegmentOpts) string {
+			lastMutex.Lock()
+			defer lastMutex.Unlock()
+			return durationSegment(lastDuration, opts.Min)
+		},
+		"exit-status": func() string {
+			lastMutex.Lock()
+			defer lastMutex.Unlock()
+			return exitStatusSegment(lastErr)
+		},
+	}))
+}
+
+type segmentRenderer struct {
+	tty   cli.TTY
+	mutex sync.Mutex
+	// Cached outputs of segments, keyed by their names, or their indices in
+	// the list of segments for segments without names.
+	cache map[string]cachedSegment
+}
+
+type cachedSegment struct {
+	text   ui.Text
+	pwd    string
+	expiry time.Time
+}
+
+// A segment, as specified by a map passed to edit:render-segments.
+type segmentSpec struct {
+	Fn       eval.Callable
+	Style    string
+	Priority int
+	Cache    float64
+	Name     string
+}
+
+type renderSegmentsOpts struct {
+	Separator string
+	MaxWidth  int
+}
+
+func (opts *renderSegmentsOpts) SetDefaultOptions() { opts.Separator = " " }
+
+func (r *segmentRenderer) render(fm *eval.Frame, opts renderSegmentsOpts, segments vals.List) (ui.Text, error) {
+	var specs []segmentSpec
+	var errIterate error
+	err := vals.Iterate(segments, func(v any) bool {
+		spec, err := parseSegmentSpec(v)
+		if err != nil {
+			errIterate = err
+			return false
+		}
+		specs = append(specs, spec)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if errIterate != nil {
+		return nil, errIterate
+	}
+
+	type rendered struct {
+		text     ui.Text
+		width    int
+		priority int
+	}
+	pwd, _ := os.Getwd()
+	r.dropStale(pwd, time.Now())
+	var rs []rendered
+	for i, spec := range specs {
+		key := spec.Name
+		if key == "" {
+			key = "#" + strconv.Itoa(i)
+		}
+		text, err := r.renderOne(fm, spec, key, pwd)
+		if err != nil {
+			return nil, err
+		}
+		if w := textWidth(text); w > 0 {
+			rs = append(rs, rendered{text, w, spec.Priority})
+		}
+	}
+
+	maxWidth := opts.MaxWidth
+	if maxWidth <= 0 {
+		_, maxWidth = r.tty.Size()
+	}
+	sepWidth := wcwidth.Of(opts.Separator)
+	width := func() int {
+		w := sepWidth * (len(rs) - 1)
+		for _, r := range rs {
+			w += r.width
+		}
+		return w
+	}
+	// Drop segments with the lowest priority, and among them the last one,
+	// until the segments fit or only one segment is left.
+	for len(rs) > 1 && width() > maxWidth {
+		drop := 0
+		for i, r := range rs {
+			if r.priority <= rs[drop].priority {
+				drop = i
+			}
+		}
+		rs = append(rs[:drop], rs[drop+1:]...)
+	}
+
+	var result ui.Text
+	for i, r := range rs {
+		if i > 0 {
+			result = ui.Concat(result, ui.T(opts.Separator))
+		}
+		result = ui.Concat(result, r.text)
+	}
+	if len(rs) == 1 && width() > maxWidth {
+		result = trimTextLeft(result, maxWidth)
+	}
+	return result, nil
+}
+
+func parseSegmentSpec(v any) (segmentSpec, error) {
+	switch v := v.(type) {
+	case eval.Callable:
+		return segmentSpec{Fn: v}, nil
+	case vals.Map:
+		var spec segmentSpec
+		if err := vals.ScanMapToGo(v, &spec); err != nil {
+			return segmentSpec{}, err
+		}
+		if spec.Fn == nil {
+			return segmentSpec{}, errs.BadValue{What: "segment",
+				Valid: "map with a fn key", Actual: vals.ReprPlain(v)}
+		}
+		if spec.Style != "" && ui.ParseStyling(spec.Style) == nil {
+			return segmentSpec{}, errs.BadValue{What: "segment style",
+				Valid: "valid style transformer", Actual: parse.Quote(spec.Style)}
+		}
+		return spec, nil
+	default:
+		return segmentSpec{}, errs.BadValue{What: "segment",
+			Valid: "callable or map", Actual: vals.Kind(v)}
+	}
+}
+
+// Removes cached outputs that have expired or were obtained in another
+// directory, so that the cache doesn't grow without bound.
+func (r *segmentRenderer) dropStale(pwd string, now time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for key, cached := range r.cache {
+		if cached.pwd != pwd || !now.Before(cached.expiry) {
+			delete(r.cache, key)
+		}
+	}
+}
+
+// Calls the function of a segment and concatenates its outputs, using the
+// cached result under key if there is one that is recent enough.
+func (r *segmentRenderer) renderOne(fm *eval.Frame, spec segmentSpec, key, pwd string) (ui.Text, error) {
+	if spec.Cache > 0 {
+		r.mutex.Lock()
+		cached, ok := r.cache[key]
+		r.mutex.Unlock()
+		if ok && cached.pwd == pwd && time.Now().Before(cached.expiry) {
+			return cached.text, nil
+		}
+	}
+
+	outputs, err := fm.CaptureOutput(func(fm *eval.Frame) error {
+		return spec.Fn.Call(fm, nil, eval.NoOpts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var text ui.Text
+	for _, output := range outputs {
+		newText, err := text.Concat(output)
+		if err != nil {
+			return nil, errs.BadValue{What: "segment output",
+				Valid: "string, number or styled text", Actual: vals.Kind(output)}
+		}
+		text = newText.(ui.Text)
+	}
+	if spec.Style != "" {
+		text = ui.StyleText(text, ui.ParseStyling(spec.Style))
+	}
+
+	if spec.Cache > 0 {
+		r.mutex.Lock()
+		expiry := time.Now().Add(time.Duration(spec.Cache * float64(time.Second)))
+		r.cache[key] = cachedSegment{text, pwd, expiry}
+		r.mutex.Unlock()
+	}
+	return text, nil
+}
+
+func textWidth(t ui.Text) int {
+	w := 0
+	for _, seg := range t {
+		w += wcwidth.Of(seg.Text)
+	}
+	return w
+}
+
+// Returns the largest suffix of t that fits in the given width together with
+// an ellipsis, prefixed with the ellipsis.
+func trimTextLeft(t ui.Text, wmax int) ui.Text {
+	wmax -= wcwidth.OfRune('…')
+	// Segments of the suffix, from the last to the first.
+	var segs []*ui.Segment
+	for i := len(t) - 1; i >= 0 && wmax > 0; i-- {
+		seg := t[i]
+		rs := []rune(seg.Text)
+		j := len(rs)
+		for j > 0 && wcwidth.OfRune(rs[j-1]) <= wmax {
+			j--
+			wmax -= wcwidth.OfRune(rs[j])
+		}
+		segs = append(segs, &ui.Segment{Style: seg.Style, Text: string(rs[j:])})
+		if j > 0 {
+			break
+		}
+	}
+	result := ui.T("…")
+	for i := len(segs) - 1; i >= 0; i-- {
+		result = append(result, segs[i])
+	}
+	return result
+}
+
+func gitSegment(fm *eval.Frame) (string, error) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	status, err := git.Status(fm.Context(), fm.ErrorFile(), pwd, 5, false)
+	if err != nil || status == nil {
+		return "", err
+	}
+	m := status.(vals.Map)
+	index := func(k string) any { v, _ := m.Index(k); return v }
+
+	var s string
+	if branch, ok := index("branch").(string); ok {
+		s = branch
+	} else if commit, ok := index("commit").(string); ok && len(commit) >= 7 {
+		s = commit[:7]
+	}
+	for _, k := range []string{"staged", "unstaged", "untracked", "conflicts"} {
+		if index(k).(int) > 0 {
+			s += "*"
+			break
+		}
+	}
+	if n := index("ahead").(int); n > 0 {
+		s += "↑" + strconv.Itoa(n)
+	}
+	if n := index("behind").(int); n > 0 {
+		s += "↓" + strconv.Itoa(n)
+	}
+	return s, nil
+}
+
+func venvSegment() string {
+	if venv := os.Getenv("VIRTUAL_ENV"); venv != "" {
+		return filepath.Base(venv)
+	}
+	return ""
+}
+
+type durationSegmentOpts struct{ Min float64 }
+
+func (opts *durationSegmentOpts) SetDefaultOptions() { opts.Min = 2 }
+
+func durationSegment(duration, min float64) string {
+	if duration < min {
+		return ""
+	}
+	return time.Duration(duration * float64(time.Second)).
+		Round(100 * time.Millisecond).String()
+}
+
+func exitStatusSegment(err error) string {
+	if err == nil {
+		return ""
+	}
+	if status, ok := externalExitStatus(err); ok {
+		return "✗ " + strconv.Itoa(status)
+	}
+	return "✗"
+}
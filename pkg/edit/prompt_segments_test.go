@@ -0,0 +1,179 @@
+package edit
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/testutil"
+	"src.elv.sh/pkg/ui"
+)
+
+func TestRenderSegments(t *testing.T) {
+	f := setup(t)
+
+	evals(f.Evaler,
+		`var p = (edit:render-segments &separator=' | ' [
+			{ put foo }
+			{ }
+			[&fn={ put bar; num 1 } &style='red bold']
+			{ put '' }
+		])`)
+	testGlobal(t, f.Evaler, "p", ui.Concat(
+		ui.T("foo | "), ui.T("bar1", ui.FgRed, ui.Bold)))
+}
+
+func TestRenderSegments_DropsSegmentsToFit(t *testing.T) {
+	f := setup(t)
+
+	evals(f.Evaler,
+		`var segs = [
+			[&fn={ put aaaa } &priority=1]
+			{ put bbbb }
+			{ put cccc }
+		]`,
+		`var p1 = (edit:render-segments &max-width=14 $segs)`,
+		`var p2 = (edit:render-segments &max-width=13 $segs)`,
+		`var p3 = (edit:render-segments &max-width=8 $segs)`,
+		`var p4 = (edit:render-segments &max-width=4 $segs)`)
+	testGlobals(t, f.Evaler, map[string]any{
+		"p1": ui.T("aaaa bbbb cccc"),
+		"p2": ui.T("aaaa bbbb"),
+		"p3": ui.T("aaaa"),
+		"p4": ui.T("aaaa"),
+	})
+}
+
+func TestRenderSegments_TrimsLastSegmentFromLeft(t *testing.T) {
+	f := setup(t)
+
+	evals(f.Evaler,
+		`var p = (edit:render-segments &max-width=6 [
+			[&fn={ put /usr/local/bin } &style=blue]
+		])`)
+	testGlobal(t, f.Evaler, "p", ui.Concat(ui.T("…"), ui.T("l/bin", ui.FgBlue)))
+}
+
+func TestRenderSegments_DefaultMaxWidthIsTerminalWidth(t *testing.T) {
+	f := setup(t)
+
+	evals(f.Evaler,
+		`var p = (edit:render-segments [{ repeat 100 x } { put y }])`)
+	var got strings.Builder
+	for _, seg := range getGlobal(f.Evaler, "p").(ui.Text) {
+		got.WriteString(seg.Text)
+	}
+	if want := "…" + strings.Repeat("x", f.width-1); got.String() != want {
+		t.Errorf("got %q, want %q", got.String(), want)
+	}
+}
+
+func TestRenderSegments_Cache(t *testing.T) {
+	f := setup(t)
+
+	evals(f.Evaler,
+		`var n = 0`,
+		`var seg = { set n = (+ $n 1); put $n }`,
+		`var p1 = (edit:render-segments [[&fn=$seg &cache=100]])`,
+		`var p2 = (edit:render-segments [[&fn=$seg &cache=100]])`,
+		`var p3 = (edit:render-segments [$seg])`)
+	testGlobals(t, f.Evaler, map[string]any{
+		"p1": ui.T("1"),
+		"p2": ui.T("1"),
+		"p3": ui.T("2"),
+	})
+}
+
+func TestRenderSegments_CacheIsKeyedOnIndexOrName(t *testing.T) {
+	f := setup(t)
+
+	// The segment functions are new closures every time, like when the list
+	// of segments is built inside the prompt function.
+	evals(f.Evaler,
+		`var n = 0`,
+		`fn segs {|name| put [[&fn={ set n = (+ $n 1); put $n } &cache=100 &name=$name]] }`,
+		`var p1 = (edit:render-segments (segs ''))`,
+		`var p2 = (edit:render-segments (segs ''))`,
+		`var p3 = (edit:render-segments (segs foo))`,
+		`var p4 = (edit:render-segments (segs foo))`)
+	testGlobals(t, f.Evaler, map[string]any{
+		"p1": ui.T("1"),
+		"p2": ui.T("1"),
+		"p3": ui.T("2"),
+		"p4": ui.T("2"),
+	})
+}
+
+func TestSegmentRenderer_DropStale(t *testing.T) {
+	now := time.Now()
+	r := &segmentRenderer{cache: map[string]cachedSegment{
+		"fresh":         {ui.T("a"), "/dir", now.Add(time.Second)},
+		"expired":       {ui.T("b"), "/dir", now},
+		"other-dir":     {ui.T("c"), "/other", now.Add(time.Second)},
+		"also-expired":  {ui.T("d"), "/dir", now.Add(-time.Second)},
+		"another-fresh": {ui.T("e"), "/dir", now.Add(time.Hour)},
+	}}
+	r.dropStale("/dir", now)
+	var keys []string
+	for key := range r.cache {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if want := []string{"another-fresh", "fresh"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("got keys %v, want %v", keys, want)
+	}
+}
+
+func TestRenderSegments_Errors(t *testing.T) {
+	f := setup(t)
+
+	for _, code := range []string{
+		`edit:render-segments [foo]`,
+		`edit:render-segments [[&style=red]]`,
+		`edit:render-segments [[&fn={ put x } &style=bad-style]]`,
+		`edit:render-segments [{ put [list] }]`,
+		`edit:render-segments [{ fail bad }]`,
+	} {
+		evals(f.Evaler, "var ret = (bool ?("+code+"))")
+		testGlobal(t, f.Evaler, "ret", false)
+	}
+}
+
+func TestSegmentCwd(t *testing.T) {
+	f := setup(t)
+
+	evals(f.Evaler, `var p = (edit:segment:cwd)`)
+	testGlobal(t, f.Evaler, "p", "~")
+}
+
+func TestSegmentVenv(t *testing.T) {
+	f := setup(t)
+
+	testutil.Setenv(t, "VIRTUAL_ENV", "")
+	evals(f.Evaler, `var p1 = (edit:segment:venv)`)
+	testutil.Setenv(t, "VIRTUAL_ENV", "/home/user/project/.venv")
+	evals(f.Evaler, `var p2 = (edit:segment:venv)`)
+	testGlobals(t, f.Evaler, map[string]any{"p1": "", "p2": ".venv"})
+}
+
+func TestSegmentDurationAndExitStatus(t *testing.T) {
+	f := setup(t)
+
+	f.Editor.RunAfterCommandHooks(parse.Source{Code: "echo"}, 0.5, nil)
+	evals(f.Evaler,
+		`var d1 = (edit:segment:duration)`,
+		`var d2 = (edit:segment:duration &min=0)`,
+		`var e1 = (edit:segment:exit-status)`)
+	f.Editor.RunAfterCommandHooks(parse.Source{Code: "fail"}, 75.44, errors.New("failed"))
+	evals(f.Evaler,
+		`var d3 = (edit:segment:duration)`,
+		`var e2 = (edit:segment:exit-status)`)
+	testGlobals(t, f.Evaler, map[string]any{
+		"d1": "", "d2": "500ms", "d3": "1m15.4s",
+		"e1": "", "e2": "✗",
+	})
+}
@@ -4,13 +4,19 @@ package edit
 // information about the most recently executed interactive command.
 
 import (
+	"math"
+	"os"
+	"os/exec"
+	"time"
+
+	"src.elv.sh/pkg/cli"
 	"src.elv.sh/pkg/eval"
 	"src.elv.sh/pkg/eval/vals"
 	"src.elv.sh/pkg/eval/vars"
 	"src.elv.sh/pkg/parse"
 )
 
-func initRepl(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
+func initRepl(ed *Editor, ev *eval.Evaler, tty cli.TTY, nb eval.NsBuilder) {
 	var commandDuration float64
 	// TODO: Ensure that this variable can only be written from the Elvish code
 	// in elv_init.go.
@@ -23,4 +29,77 @@ func initRepl(ed *Editor, ev *eval.Evaler, nb eval.NsBuilder) {
 			m := vals.MakeMap("src", src, "duration", duration, "error", err)
 			eval.CallHook(ev, nil, "$<edit>:after-command", afterCommandHook.Get().(vals.List), m)
 		})
+
+	beforePipelineHook := newListVar(vals.EmptyList)
+	nb.AddVar("before-pipeline", beforePipelineHook)
+	ed.BeforePipeline = append(ed.BeforePipeline,
+		func(code string, start time.Time) {
+			m := vals.MakeMap("code", code, "start-time", unixSeconds(start))
+			eval.CallHook(ev, nil, "$<edit>:before-pipeline", beforePipelineHook.Get().(vals.List), m)
+		})
+	afterPipelineHook := newListVar(vals.EmptyList)
+	nb.AddVar("after-pipeline", afterPipelineHook)
+	ed.AfterPipeline = append(ed.AfterPipeline,
+		func(code string, start time.Time, duration float64, err error) {
+			m := vals.MakeMap("code", code, "start-time", unixSeconds(start),
+				"duration", duration, "error", err)
+			eval.CallHook(ev, nil, "$<edit>:after-pipeline", afterPipelineHook.Get().(vals.List), m)
+		})
+
+	longCommandThreshold := math.Inf(1)
+	nb.AddVar("long-command-threshold", vars.FromPtr(&longCommandThreshold))
+	ed.AfterCommand = append(ed.AfterCommand,
+		func(src parse.Source, duration float64, err error) {
+			if duration >= longCommandThreshold {
+				notifyDesktop(tty, longCommandMessage(src, duration, err))
+			}
+		})
+	nb.AddGoFn("notify-desktop", func(msg string) { notifyDesktop(tty, msg) })
+
+	ed.pager = newListVar(vals.MakeList("less", "-R"))
+	nb.AddVar("pager", ed.pager)
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+func longCommandMessage(src parse.Source, duration float64, err error) string {
+	code := firstLine(src.Code)
+	status := "finished"
+	if err != nil {
+		status = "failed"
+	}
+	d := time.Duration(duration * float64(time.Second)).Round(100 * time.Millisecond)
+	return code + " " + status + " in " + d.String()
+}
+
+// Shows a desktop notification with notify-send if it is available in a
+// graphical session, and with the OSC 9 sequence otherwise.
+//
+// The notification is shown even if the terminal is focused. Elvish can't tell
+// whether it is: the terminal can be asked to report focus changes, but the
+// reports sent while a command is running would become input to the command.
+func notifyDesktop(tty cli.TTY, msg string) {
+	if !notifySend(msg) {
+		tty.NotifyDesktop(msg)
+	}
+}
+
+// Shows a desktop notification with notify-send, and reports whether it could
+// be run. A variable for testing.
+var notifySend = func(msg string) bool {
+	if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		return false
+	}
+	path, err := exec.LookPath("notify-send")
+	if err != nil {
+		return false
+	}
+	cmd := exec.Command(path, "Elvish", msg)
+	if cmd.Start() != nil {
+		return false
+	}
+	go cmd.Wait()
+	return true
 }
@@ -0,0 +1,98 @@
+package edit
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/testutil"
+)
+
+func TestPipelineHooks(t *testing.T) {
+	f := setup(t, rc(
+		`var before after`,
+		`set edit:before-pipeline = [{|m| set before = $m }]`,
+		`set edit:after-pipeline = [{|m| set after = $m }]`))
+
+	start := time.Unix(100, 500_000_000)
+	f.Editor.RunBeforePipelineHooks("sleep 2", start)
+	testGlobal(t, f.Evaler, "before",
+		vals.MakeMap("code", "sleep 2", "start-time", 100.5))
+
+	err := errors.New("failed")
+	f.Editor.RunAfterPipelineHooks("sleep 2", start, 2.0, err)
+	testGlobal(t, f.Evaler, "after",
+		vals.MakeMap("code", "sleep 2", "start-time", 100.5, "duration", 2.0, "error", err))
+}
+
+func TestLongCommandThreshold(t *testing.T) {
+	f := setup(t, rc(`set edit:long-command-threshold = 10`))
+
+	f.Editor.RunAfterCommandHooks(parse.Source{Code: "sleep 20"}, 20.01, nil)
+	f.Editor.RunAfterCommandHooks(parse.Source{Code: "echo"}, 0.1, nil)
+	f.Editor.RunAfterCommandHooks(
+		parse.Source{Code: "make\nmake test"}, 75.44, errors.New("failed"))
+
+	wantNotes := []string{"sleep 20 finished in 20s", "make … failed in 1m15.4s"}
+	if notes := f.TTYCtrl.DesktopNotes(); !reflect.DeepEqual(notes, wantNotes) {
+		t.Errorf("got desktop notes %q, want %q", notes, wantNotes)
+	}
+}
+
+func TestLongCommandThreshold_DisabledByDefault(t *testing.T) {
+	f := setup(t)
+
+	f.Editor.RunAfterCommandHooks(parse.Source{Code: "sleep 1000"}, 1000, nil)
+
+	if notes := f.TTYCtrl.DesktopNotes(); len(notes) > 0 {
+		t.Errorf("got desktop notes %q, want none", notes)
+	}
+}
+
+func TestNotifyDesktop(t *testing.T) {
+	f := setup(t)
+
+	evals(f.Evaler, `edit:notify-desktop 'build done'`)
+
+	wantNotes := []string{"build done"}
+	if notes := f.TTYCtrl.DesktopNotes(); !reflect.DeepEqual(notes, wantNotes) {
+		t.Errorf("got desktop notes %q, want %q", notes, wantNotes)
+	}
+}
+
+func TestNotifyDesktop_NotifySend(t *testing.T) {
+	f := setup(t)
+	var sent []string
+	testutil.Set(t, &notifySend, func(msg string) bool {
+		sent = append(sent, msg)
+		return true
+	})
+
+	evals(f.Evaler, `edit:notify-desktop 'build done'`)
+
+	if want := []string{"build done"}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("got notify-send messages %q, want %q", sent, want)
+	}
+	if notes := f.TTYCtrl.DesktopNotes(); len(notes) > 0 {
+		t.Errorf("got desktop notes %q, want none", notes)
+	}
+}
+
+func TestPager(t *testing.T) {
+	f := setup(t)
+
+	if pager := f.Editor.Pager(); !reflect.DeepEqual(pager, []string{"less", "-R"}) {
+		t.Errorf("got default pager %q, want [less -R]", pager)
+	}
+	evals(f.Evaler, `set edit:pager = [more]`)
+	if pager := f.Editor.Pager(); !reflect.DeepEqual(pager, []string{"more"}) {
+		t.Errorf("got pager %q, want [more]", pager)
+	}
+	evals(f.Evaler, `set edit:pager = []`)
+	if pager := f.Editor.Pager(); pager != nil {
+		t.Errorf("got pager %q, want nil", pager)
+	}
+}
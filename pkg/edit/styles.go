@@ -0,0 +1,145 @@
+package edit
+
+import (
+	"sync"
+
+	"src.elv.sh/pkg/cli/tk"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/eval/vars"
+	"src.elv.sh/pkg/ui"
+)
+
+var defaultTheme = vals.MakeMap(
+	// Syntax highlighting.
+	"bareword", "",
+	"single-quoted", "yellow",
+	"double-quoted", "yellow",
+	"variable", "magenta",
+	"wildcard", "",
+	"tilde", "",
+	"comment", "cyan",
+	"operator", "green",
+	"punctuation", "bold",
+	"command", "green",
+	"bad-command", "red",
+	"keyword", "yellow",
+	"syntax-error", "bright-white bg-red",
+	// Widgets.
+	"selected", "inverse",
+	"pending", "underlined",
+	"matching-bracket", "bold underlined",
+	"scrollbar", "magenta",
+	"mode-line", "bold white bg-magenta",
+	"error", "red",
+	"completion-description", "dim",
+	"picker-match", "underlined",
+	"stale-prompt", "inverse",
+)
+
+var monochromeTheme = vals.MakeMap(
+	"bareword", "",
+	"single-quoted", "",
+	"double-quoted", "",
+	"variable", "italic",
+	"wildcard", "",
+	"tilde", "",
+	"comment", "dim",
+	"operator", "",
+	"punctuation", "bold",
+	"command", "bold",
+	"bad-command", "underlined",
+	"keyword", "bold",
+	"syntax-error", "inverse",
+	"selected", "inverse",
+	"pending", "underlined",
+	"matching-bracket", "bold underlined",
+	"scrollbar", "",
+	"mode-line", "bold inverse",
+	"error", "bold",
+	"completion-description", "dim",
+	"picker-match", "underlined",
+	"stale-prompt", "inverse",
+)
+
+// Initializes $edit:styles and $edit:themes, and returns the styles for looking
+// up the styling of a style category.
+func initStyles(nb eval.NsBuilder) tk.Styles {
+	styles := newStylesVar(defaultTheme)
+	nb.AddVar("styles", styles)
+	nb.AddVar("themes", vars.NewReadOnly(vals.MakeMap(
+		"default", defaultTheme,
+		"monochrome", monochromeTheme)))
+	return styles.stylingFor
+}
+
+// The variable $edit:styles. Styles are parsed when the variable is set, so
+// that looking them up doesn't need to parse them again.
+type stylesVar struct {
+	mutex  sync.RWMutex
+	m      vals.Map
+	parsed map[string]ui.Styling
+}
+
+// The default theme, parsed.
+var defaultStylings = parseStyles(defaultTheme)
+
+func newStylesVar(m vals.Map) *stylesVar {
+	return &stylesVar{m: m, parsed: parseStyles(m)}
+}
+
+func (v *stylesVar) Get() any {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	return v.m
+}
+
+func (v *stylesVar) Set(val any) error {
+	var m vals.Map
+	if err := vals.ScanToGo(val, &m); err != nil {
+		return err
+	}
+	parsed := parseStyles(m)
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.m, v.parsed = m, parsed
+	return nil
+}
+
+func (v *stylesVar) stylingFor(name string) ui.Styling {
+	v.mutex.RLock()
+	styling, ok := v.parsed[name]
+	v.mutex.RUnlock()
+	if ok {
+		return styling
+	}
+	// Fall back to the default theme when the style is missing or invalid.
+	return defaultStylings[name]
+}
+
+// Parses all the valid styles in a map. Entries that are not strings or are
+// not valid styles are left out.
+func parseStyles(m vals.Map) map[string]ui.Styling {
+	parsed := make(map[string]ui.Styling)
+	for it := m.Iterator(); it.HasElem(); it.Next() {
+		k, v := it.Elem()
+		name, ok1 := k.(string)
+		s, ok2 := v.(string)
+		if !ok1 || !ok2 {
+			continue
+		}
+		if styling := parseStyle(s); styling != nil {
+			parsed[name] = styling
+		}
+	}
+	return parsed
+}
+
+// Like ui.ParseStyling, but also accepts an empty string, which is parsed to a
+// styling that does nothing.
+func parseStyle(s string) ui.Styling {
+	if s == "" {
+		return ui.Stylings()
+	}
+	return ui.ParseStyling(s)
+}
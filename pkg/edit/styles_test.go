@@ -0,0 +1,76 @@
+package edit
+
+import (
+	"reflect"
+	"testing"
+
+	"src.elv.sh/pkg/cli/term"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/ui"
+)
+
+func TestStyles(t *testing.T) {
+	f := setup(t, rc(
+		`set edit:styles[command] = blue`,
+		`set edit:styles[variable] = 'red'`))
+
+	feedInput(f.TTYCtrl, "put $true")
+	f.TestTTY(t,
+		"~> put $true", Styles,
+		"   /// !!!!!", term.DotHere,
+	)
+}
+
+func TestStyles_InvalidStyleFallsBackToDefault(t *testing.T) {
+	f := setup(t, rc(
+		`set edit:styles[command] = bad-style`,
+		`set edit:styles[variable] = [red]`))
+
+	feedInput(f.TTYCtrl, "put $true")
+	f.TestTTY(t,
+		"~> put $true", Styles,
+		"   vvv $$$$$", term.DotHere,
+	)
+}
+
+func TestStyles_Widgets(t *testing.T) {
+	f := setup(t, rc(`set edit:styles[mode-line] = 'bold red'`))
+
+	evals(f.Evaler, `edit:location:start`)
+	f.TestTTY(t,
+		"~> \n",
+		" LOCATION  ", ui.RuneStylesheet{'r': ui.Stylings(ui.Bold, ui.FgRed)},
+		"rrrrrrrrrr ", term.DotHere,
+	)
+}
+
+func TestThemes(t *testing.T) {
+	f := setup(t, rc(`set edit:styles = $edit:themes[monochrome]`))
+
+	feedInput(f.TTYCtrl, "put $true # x")
+	f.TestTTY(t,
+		"~> put $true # x", ui.RuneStylesheet{
+			'b': ui.Bold, 'i': ui.Italic, 'd': ui.Dim},
+		"   bbb iiiiidddd", term.DotHere,
+	)
+}
+
+func TestStylesVar(t *testing.T) {
+	v := newStylesVar(vals.MakeMap("selected", "red", "pending", "bad-style"))
+	if got := v.stylingFor("selected"); !reflect.DeepEqual(got, ui.FgRed) {
+		t.Errorf("got %v for selected, want %v", got, ui.FgRed)
+	}
+	if got := v.stylingFor("pending"); !reflect.DeepEqual(got, ui.Underlined) {
+		t.Errorf("got %v for invalid pending, want default %v", got, ui.Underlined)
+	}
+
+	if err := v.Set("not a map"); err == nil {
+		t.Errorf("Set with a string returns no error")
+	}
+	if err := v.Set(vals.MakeMap("selected", "blue")); err != nil {
+		t.Errorf("Set returns error %v", err)
+	}
+	if got := v.stylingFor("selected"); !reflect.DeepEqual(got, ui.FgBlue) {
+		t.Errorf("got %v for selected after Set, want %v", got, ui.FgBlue)
+	}
+}
@@ -0,0 +1,104 @@
+package edit
+
+// This file implements setting the terminal title, reporting the working
+// directory and marking prompts and command outputs for the terminal.
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"src.elv.sh/pkg/cli"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/fsutil"
+	"src.elv.sh/pkg/parse"
+)
+
+func initTerminalReports(appSpec *cli.AppSpec, ed *Editor, ev *eval.Evaler, tty cli.TTY, nb eval.NsBuilder) {
+	titleVar := newFnVar(eval.NewGoFn("<default terminal title>", defaultTerminalTitle))
+	nb.AddVar("terminal-title", titleVar)
+	reportCwdVar := newBoolVar(true)
+	nb.AddVar("report-cwd", reportCwdVar)
+	shellIntegrationVar := newBoolVar(true)
+	nb.AddVar("shell-integration", shellIntegrationVar)
+
+	setTitle := func(code string) {
+		title := callForStyledText(ed, ev, "terminal-title", titleVar.Get().(eval.Callable), code)
+		var sb strings.Builder
+		for _, seg := range title {
+			sb.WriteString(seg.Text)
+		}
+		if sb.Len() > 0 {
+			tty.SetTitle(sb.String())
+		}
+	}
+	// Whether the start of a command output has been marked, and its end hasn't.
+	var inCommand bool
+	appSpec.BeforeReadline = append(appSpec.BeforeReadline, func() {
+		if shellIntegrationVar.Get().(bool) {
+			tty.MarkSemanticPrompt("A")
+		}
+		setTitle("")
+		if reportCwdVar.Get().(bool) {
+			if dir, err := os.Getwd(); err == nil {
+				tty.ReportCwd(dir)
+			}
+		}
+	})
+	appSpec.AfterReadline = append(appSpec.AfterReadline, func(code string) {
+		if strings.TrimSpace(code) != "" {
+			setTitle(code)
+		}
+		if shellIntegrationVar.Get().(bool) {
+			tty.MarkSemanticPrompt("C")
+			inCommand = true
+		}
+	})
+	ed.AfterCommand = append(ed.AfterCommand,
+		func(_ parse.Source, _ float64, err error) {
+			if inCommand {
+				tty.MarkSemanticPrompt("D;" + strconv.Itoa(exitCode(err)))
+				inCommand = false
+			}
+		})
+}
+
+// Returns the exit code that corresponds to the error of a command, as used by
+// the OSC 133 sequence.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if status, ok := externalExitStatus(err); ok {
+		return status
+	}
+	return 1
+}
+
+// Returns the exit status of an external command if err is caused by it
+// exiting with a non-zero status.
+func externalExitStatus(err error) (int, bool) {
+	if exc, ok := err.(eval.Exception); ok {
+		if exit, ok := exc.Reason().(eval.ExternalCmdExit); ok && exit.Exited() {
+			return exit.ExitStatus(), true
+		}
+	}
+	return 0, false
+}
+
+func defaultTerminalTitle(code string) string {
+	if code == "" {
+		return fsutil.Getwd()
+	}
+	return firstLine(code)
+}
+
+// Returns the first non-empty line of code, followed by " …" if there are more
+// lines.
+func firstLine(code string) string {
+	code = strings.TrimSpace(code)
+	if i := strings.IndexByte(code, '\n'); i != -1 {
+		return code[:i] + " …"
+	}
+	return code
+}
@@ -0,0 +1,95 @@
+package edit
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"src.elv.sh/pkg/cli/term"
+	"src.elv.sh/pkg/parse"
+)
+
+func TestTerminalReports(t *testing.T) {
+	f := setup(t)
+
+	feedInput(f.TTYCtrl, "echo foo\n")
+	f.Wait()
+
+	wantTitles := []string{"~", "echo foo"}
+	if titles := f.TTYCtrl.Titles(); !reflect.DeepEqual(titles, wantTitles) {
+		t.Errorf("got titles %q, want %q", titles, wantTitles)
+	}
+	wantCwds := []string{f.Home}
+	if cwds := f.TTYCtrl.ReportedCwds(); !reflect.DeepEqual(cwds, wantCwds) {
+		t.Errorf("got reported cwds %q, want %q", cwds, wantCwds)
+	}
+}
+
+func TestTerminalTitle_Custom(t *testing.T) {
+	f := setup(t, rc(
+		`set edit:terminal-title = {|code| if (eq $code '') { put idle } else { put 'running ' $code } }`))
+
+	feedInput(f.TTYCtrl, "echo foo\n")
+	f.Wait()
+
+	wantTitles := []string{"idle", "running echo foo"}
+	if titles := f.TTYCtrl.Titles(); !reflect.DeepEqual(titles, wantTitles) {
+		t.Errorf("got titles %q, want %q", titles, wantTitles)
+	}
+}
+
+func TestTerminalTitle_NoOutput(t *testing.T) {
+	f := setup(t, rc(`set edit:terminal-title = {|_| }`, `set edit:report-cwd = $false`))
+
+	f.TTYCtrl.Inject(term.K('\n'))
+	f.Wait()
+
+	if titles := f.TTYCtrl.Titles(); len(titles) > 0 {
+		t.Errorf("got titles %q, want none", titles)
+	}
+	if cwds := f.TTYCtrl.ReportedCwds(); len(cwds) > 0 {
+		t.Errorf("got reported cwds %q, want none", cwds)
+	}
+}
+
+func TestShellIntegration(t *testing.T) {
+	f := setup(t)
+
+	// A command that isn't read by the editor, like rc.elv, is not marked.
+	f.Editor.RunAfterCommandHooks(parse.Source{Code: "rc"}, 0, nil)
+	feedInput(f.TTYCtrl, "fail foo\n")
+	f.Wait()
+	f.Editor.RunAfterCommandHooks(parse.Source{Code: "fail foo"}, 0, errors.New("foo"))
+
+	wantMarks := []string{"A", "C", "D;1"}
+	if marks := f.TTYCtrl.SemanticPromptMarks(); !reflect.DeepEqual(marks, wantMarks) {
+		t.Errorf("got marks %q, want %q", marks, wantMarks)
+	}
+}
+
+func TestShellIntegration_Disabled(t *testing.T) {
+	f := setup(t, rc(`set edit:shell-integration = $false`))
+
+	f.TTYCtrl.Inject(term.K('\n'))
+	f.Wait()
+	f.Editor.RunAfterCommandHooks(parse.Source{Code: ""}, 0, nil)
+
+	if marks := f.TTYCtrl.SemanticPromptMarks(); len(marks) > 0 {
+		t.Errorf("got marks %q, want none", marks)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if code := exitCode(nil); code != 0 {
+		t.Errorf("exitCode(nil) -> %v, want 0", code)
+	}
+	if code := exitCode(errors.New("foo")); code != 1 {
+		t.Errorf("exitCode(errors.New(...)) -> %v, want 1", code)
+	}
+}
+
+func TestDefaultTerminalTitle(t *testing.T) {
+	if got := defaultTerminalTitle("  make\nmake test\n"); got != "make …" {
+		t.Errorf("got %q, want %q", got, "make …")
+	}
+}
@@ -60,6 +60,7 @@ func setup(c testutil.Cleanuper, fns ...func(*fixture)) *fixture {
 	st := store.MustTempStore(c)
 	home := testutil.InTempHome(c)
 	testutil.Setenv(c, "PATH", "")
+	testutil.Set(c, &notifySend, func(string) bool { return false })
 
 	tty, ttyCtrl := clitest.NewFakeTTY()
 	ev := eval.NewEvaler()
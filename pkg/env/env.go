@@ -4,13 +4,17 @@ package env
 
 // Environment variables with special significance to Elvish.
 const (
+	CDPATH    = "CDPATH"
+	EDITOR    = "EDITOR"
 	HOME      = "HOME"
 	LS_COLORS = "LS_COLORS"
 	NO_COLOR  = "NO_COLOR"
 	PATH      = "PATH"
 	PWD       = "PWD"
 	SHLVL     = "SHLVL"
+	TERM      = "TERM"
 	USERNAME  = "USERNAME"
+	VISUAL    = "VISUAL"
 
 	// Only used on Unix
 	XDG_CONFIG_HOME = "XDG_CONFIG_HOME"
@@ -0,0 +1,76 @@
+package eval
+
+import (
+	"unsafe"
+
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/persistent/hash"
+)
+
+// Alias is a function created by the alias builtin. It runs a piece of code,
+// with the arguments it is called with appended to the last command in the
+// code.
+type Alias struct {
+	Name string
+	// The code that the alias expands to.
+	Code string
+	// The head and arguments of the last command in Code, if they are all
+	// literal strings; nil otherwise. Used to complete the arguments of the
+	// alias as if they were passed to the command.
+	Words []string
+	fn    *Closure
+}
+
+var _ Callable = &Alias{}
+
+// Kind returns "fn".
+func (*Alias) Kind() string { return "fn" }
+
+// Equal compares by address.
+func (a *Alias) Equal(rhs any) bool { return a == rhs }
+
+// Hash returns the hash of the address of the alias.
+func (a *Alias) Hash() uint32 { return hash.Pointer(unsafe.Pointer(a)) }
+
+// Repr returns a representation containing the name of the alias and the code
+// it expands to.
+func (a *Alias) Repr(int) string {
+	return "<alias " + parse.Quote(a.Name) + " " + parse.Quote(a.Code) + ">"
+}
+
+// Call runs the code of the alias, passing args to its last command.
+func (a *Alias) Call(fm *Frame, args []any, opts map[string]any) error {
+	return a.fn.Call(fm, args, opts)
+}
+
+// Returns the last form in the code of an alias, and the words of the form if
+// they are all literal strings.
+func aliasLastForm(tree parse.Tree) (*parse.Form, []string) {
+	pipelines := tree.Root.Pipelines
+	if len(pipelines) == 0 {
+		return nil, nil
+	}
+	forms := pipelines[len(pipelines)-1].Forms
+	form := forms[len(forms)-1]
+	var words []string
+	for _, cn := range append([]*parse.Compound{form.Head}, form.Args...) {
+		s, ok := literalString(cn)
+		if !ok {
+			return form, nil
+		}
+		words = append(words, s)
+	}
+	return form, words
+}
+
+// Returns the value of a compound node that is a single literal string.
+func literalString(cn *parse.Compound) (string, bool) {
+	if cn == nil || len(cn.Indexings) != 1 || len(cn.Indexings[0].Indices) > 0 {
+		return "", false
+	}
+	switch pn := cn.Indexings[0].Head; pn.Type {
+	case parse.Bareword, parse.SingleQuoted, parse.DoubleQuoted:
+		return pn.Value, true
+	}
+	return "", false
+}
@@ -16,6 +16,8 @@ var benchmarks = []struct {
 	{"put-x", "put x"},
 	{"for-100", "for x [(range 100)] { }"},
 	{"range-100", "range 100 | each {|_| }"},
+	{"capture-100", "for x [(range 100)] { nop (put $x) }"},
+	{"while-100", "var x = (num 0); while (< $x 100) { set x = (+ $x 1) }"},
 	{"read-local", "var x = val; nop $x"},
 	{"read-upval", "var x = val; { nop $x }"},
 }
@@ -0,0 +1,170 @@
+package eval
+
+import (
+	"sort"
+	"sync"
+)
+
+// Maximum number of finished background jobs whose results are kept for wait.
+// When more jobs finish without being waited for, the oldest ones are
+// forgotten.
+const maxFinishedBgJobs = 64
+
+// A bgJob is a pipeline run in the background with &.
+type bgJob struct {
+	id     int
+	source string
+	// Closed when the job has finished.
+	done chan struct{}
+	// The result of the job; only valid after done is closed.
+	err error
+
+	mu sync.Mutex
+	// Pids of the external commands of the job that are running.
+	pids map[int]struct{}
+}
+
+func (j *bgJob) finished() bool {
+	select {
+	case <-j.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (j *bgJob) addPid(pid int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.pids[pid] = struct{}{}
+}
+
+func (j *bgJob) removePid(pid int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.pids, pid)
+}
+
+// Returns the pids of the running external commands of the job, sorted.
+func (j *bgJob) runningPids() []int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	pids := make([]int, 0, len(j.pids))
+	for pid := range j.pids {
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+	return pids
+}
+
+// Records a background job that has started.
+func (ev *Evaler) addBgJob(source string) *bgJob {
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	ev.nextBgJobID++
+	j := &bgJob{id: ev.nextBgJobID, source: source,
+		done: make(chan struct{}), pids: map[int]struct{}{}}
+	ev.bgJobs[j.id] = j
+	return j
+}
+
+// Records that a background job has finished with err. The job is kept until
+// it is waited for, or until too many jobs have finished after it.
+func (ev *Evaler) finishBgJob(j *bgJob, err error) {
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	j.err = err
+	close(j.done)
+	var finished []int
+	for id, j := range ev.bgJobs {
+		if j.finished() {
+			finished = append(finished, id)
+		}
+	}
+	if len(finished) > maxFinishedBgJobs {
+		sort.Ints(finished)
+		for _, id := range finished[:len(finished)-maxFinishedBgJobs] {
+			delete(ev.bgJobs, id)
+		}
+	}
+}
+
+// Returns the background job with the given ID, or nil if there is no such
+// job.
+func (ev *Evaler) getBgJob(id int) *bgJob {
+	ev.mu.RLock()
+	defer ev.mu.RUnlock()
+	return ev.bgJobs[id]
+}
+
+// Forgets a background job that has been waited for or detached.
+func (ev *Evaler) forgetBgJob(id int) {
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	delete(ev.bgJobs, id)
+}
+
+// Returns the background jobs that are running, from the oldest to the newest.
+func (ev *Evaler) runningBgJobs() []*bgJob {
+	ev.mu.RLock()
+	defer ev.mu.RUnlock()
+	var jobs []*bgJob
+	for _, j := range ev.bgJobs {
+		if !j.finished() {
+			jobs = append(jobs, j)
+		}
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].id < jobs[k].id })
+	return jobs
+}
+
+func (ev *Evaler) getNumBgJobs() int {
+	return len(ev.runningBgJobs())
+}
+
+// BgJobs returns the sources of the background jobs that are running, from the
+// oldest to the newest.
+func (ev *Evaler) BgJobs() []string {
+	jobs := ev.runningBgJobs()
+	sources := make([]string, len(jobs))
+	for i, j := range jobs {
+		sources[i] = j.source
+	}
+	return sources
+}
+
+// The exit status of a child process started by spawn-detached.
+type detachedChild struct {
+	// Closed when the process has exited.
+	done chan struct{}
+	// The result of the process; only valid after done is closed.
+	err error
+}
+
+// Records a child process started by spawn-detached, and returns a function to
+// call with its result when it has exited.
+func (ev *Evaler) addDetachedChild(pid int) func(error) {
+	c := &detachedChild{done: make(chan struct{})}
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	ev.detachedChildren[pid] = c
+	return func(err error) {
+		c.err = err
+		close(c.done)
+	}
+}
+
+// Returns the child process started by spawn-detached with the given pid, or
+// nil if there is no such process.
+func (ev *Evaler) getDetachedChild(pid int) *detachedChild {
+	ev.mu.RLock()
+	defer ev.mu.RUnlock()
+	return ev.detachedChildren[pid]
+}
+
+// Forgets a child process started by spawn-detached that has been waited for.
+func (ev *Evaler) forgetDetachedChild(pid int) {
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	delete(ev.detachedChildren, pid)
+}
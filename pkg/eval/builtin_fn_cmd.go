@@ -1,10 +1,22 @@
 package eval
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"src.elv.sh/pkg/env"
+	"src.elv.sh/pkg/errutil"
 	"src.elv.sh/pkg/eval/errs"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/parse"
 )
 
 // Command and process control.
@@ -19,9 +31,18 @@ func init() {
 		"search-external": searchExternal,
 
 		// Process control
-		"fg":   fg,
-		"exec": execFn,
-		"exit": exit,
+		"fg":     fg,
+		"exec":   execFn,
+		"exit":   exit,
+		"kill":   kill,
+		"wait":   waitFn,
+		"jobs":   jobs,
+		"detach": detach,
+
+		"spawn-detached": spawnDetached,
+
+		// Process query
+		"pgrep": pgrep,
 	})
 }
 
@@ -38,6 +59,37 @@ func searchExternal(cmd string) (string, error) {
 	return exec.LookPath(cmd)
 }
 
+// Converts the arguments of exec to strings, defaulting to "elvish" when there
+// are none, and resolves the command to a path.
+func execArgs(args []any) ([]string, error) {
+	var argstrings []string
+	if len(args) == 0 {
+		argstrings = []string{"elvish"}
+	} else {
+		argstrings = make([]string, len(args))
+		for i, a := range args {
+			argstrings[i] = vals.ToString(a)
+		}
+	}
+
+	var err error
+	argstrings[0], err = exec.LookPath(argstrings[0])
+	if err != nil {
+		return nil, err
+	}
+	return argstrings, nil
+}
+
+// Decrements $E:SHLVL. Called from execFn to ensure that $E:SHLVL remains the
+// same in the new command.
+func decSHLVL() {
+	i, err := strconv.Atoi(os.Getenv(env.SHLVL))
+	if err != nil {
+		return
+	}
+	os.Setenv(env.SHLVL, strconv.Itoa(i-1))
+}
+
 // Can be overridden in tests.
 var osExit = os.Exit
 
@@ -55,3 +107,303 @@ func exit(fm *Frame, codes ...int) error {
 	osExit(code)
 	return nil
 }
+
+type spawnDetachedOpts struct{ Output string }
+
+func (o *spawnDetachedOpts) SetDefaultOptions() {}
+
+func spawnDetached(fm *Frame, opts spawnDetachedOpts, name string, args ...any) (int, error) {
+	if err := fm.Evaler.CheckRestricted("running external command " + name); err != nil {
+		return 0, err
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return 0, err
+	}
+	output := opts.Output
+	if output == "" {
+		output = os.DevNull
+	}
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	argstrings := make([]string, len(args))
+	for i, a := range args {
+		argstrings[i] = vals.ToString(a)
+	}
+	cmd := exec.Command(path, argstrings...)
+	cmd.Args[0] = name
+	cmd.Stdout = f
+	cmd.Stderr = f
+	cmd.SysProcAttr = detachedSysProcAttr()
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	// Reap the process when it exits, and keep its exit status for wait.
+	pid := cmd.Process.Pid
+	exited := fm.Evaler.addDetachedChild(pid)
+	go func() {
+		err := cmd.Wait()
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			err = NewExternalCmdExit(name, exitErr.Sys().(syscall.WaitStatus), pid)
+		}
+		exited(err)
+	}()
+	return pid, nil
+}
+
+// Parses the arguments like kill(1): the signal may be given in the first
+// argument as -s SIG, -SIG or -N, and defaults to TERM; the remaining arguments,
+// optionally after a --, are pids or job specs.
+func kill(fm *Frame, args ...any) error {
+	if err := fm.Evaler.CheckRestricted("sending signals to processes"); err != nil {
+		return err
+	}
+	argstrings := make([]string, len(args))
+	for i, a := range args {
+		argstrings[i] = vals.ToString(a)
+	}
+	sigName := "TERM"
+	if len(argstrings) > 0 {
+		switch first := argstrings[0]; {
+		case first == "-s":
+			if len(argstrings) < 2 {
+				return errKillNoSignal
+			}
+			sigName, argstrings = argstrings[1], argstrings[2:]
+		case first != "--" && strings.HasPrefix(first, "-"):
+			sigName, argstrings = first[1:], argstrings[1:]
+		}
+	}
+	if len(argstrings) > 0 && argstrings[0] == "--" {
+		argstrings = argstrings[1:]
+	}
+	if len(argstrings) == 0 {
+		return errs.ArityMismatch{What: "targets", ValidLow: 1, ValidHigh: -1, Actual: 0}
+	}
+	sig, err := parseSignal(sigName)
+	if err != nil {
+		return err
+	}
+	targets := make([]waitTarget, len(argstrings))
+	for i, s := range argstrings {
+		targets[i], err = parseTarget(fm, s)
+		if err != nil {
+			return err
+		}
+	}
+	var errKill error
+	for _, t := range targets {
+		pids := []int{t.pid}
+		if t.job != nil {
+			pids = t.job.runningPids()
+		}
+		for _, pid := range pids {
+			errKill = errutil.Multi(errKill, killProcess(pid, sig))
+		}
+	}
+	return errKill
+}
+
+var errKillNoSignal = errors.New("option -s requires a signal")
+
+// A target of kill or wait: a background job, or a process.
+type waitTarget struct {
+	job *bgJob
+	pid int
+}
+
+// Parses a job spec like %1 or a pid. Like kill(2), a negative pid refers to a
+// process group.
+func parseTarget(fm *Frame, s string) (waitTarget, error) {
+	if idString, ok := strings.CutPrefix(s, "%"); ok {
+		id, err := strconv.Atoi(idString)
+		if err == nil && id > 0 {
+			if j := fm.Evaler.getBgJob(id); j != nil {
+				return waitTarget{job: j}, nil
+			}
+		}
+		return waitTarget{}, fmt.Errorf("no such job: %s", s)
+	}
+	pid, err := strconv.Atoi(s)
+	if err != nil {
+		return waitTarget{}, errs.BadValue{What: "target",
+			Valid: "pid or job spec like %1", Actual: parse.Quote(s)}
+	}
+	return waitTarget{pid: pid}, nil
+}
+
+// How often wait checks whether a process has exited.
+var waitPollInterval = 10 * time.Millisecond
+
+func waitFn(fm *Frame, args ...any) error {
+	done := fm.Context().Done()
+	if len(args) == 0 {
+		for _, j := range fm.Evaler.runningBgJobs() {
+			select {
+			case <-done:
+				return ErrInterrupted
+			case <-j.done:
+			}
+		}
+		return nil
+	}
+	targets := make([]waitTarget, len(args))
+	for i, a := range args {
+		var err error
+		targets[i], err = parseTarget(fm, vals.ToString(a))
+		if err != nil {
+			return err
+		}
+	}
+	out := fm.ValueOutput()
+	for _, t := range targets {
+		var result any
+		if t.job != nil {
+			select {
+			case <-done:
+				return ErrInterrupted
+			case <-t.job.done:
+			}
+			fm.Evaler.forgetBgJob(t.job.id)
+			result = exceptionValue(t.job.err)
+		} else if c := fm.Evaler.getDetachedChild(t.pid); c != nil {
+			select {
+			case <-done:
+				return ErrInterrupted
+			case <-c.done:
+			}
+			fm.Evaler.forgetDetachedChild(t.pid)
+			result = exceptionValue(c.err)
+		} else {
+			// Not a child of Elvish; its exit status is not available.
+			for processExists(t.pid) {
+				select {
+				case <-done:
+					return ErrInterrupted
+				case <-time.After(waitPollInterval):
+				}
+			}
+		}
+		if err := out.Put(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func detach(fm *Frame, args ...any) error {
+	var detached []*bgJob
+	if len(args) == 0 {
+		detached = fm.Evaler.runningBgJobs()
+	} else {
+		detached = make([]*bgJob, len(args))
+		for i, a := range args {
+			s := vals.ToString(a)
+			t, err := parseTarget(fm, s)
+			if err != nil {
+				return err
+			}
+			if t.job == nil {
+				return errs.BadValue{What: "job",
+					Valid: "job spec like %1", Actual: parse.Quote(s)}
+			}
+			detached[i] = t.job
+		}
+	}
+	for _, j := range detached {
+		fm.Evaler.forgetBgJob(j.id)
+	}
+	return nil
+}
+
+func jobs(fm *Frame) error {
+	out := fm.ValueOutput()
+	for _, j := range fm.Evaler.runningBgJobs() {
+		pids := vals.EmptyList
+		for _, pid := range j.runningPids() {
+			pids = pids.Conj(pid)
+		}
+		err := out.Put(vals.MakeMap(
+			"id", "%"+strconv.Itoa(j.id), "source", j.source, "pids", pids))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Converts the result of a job or process to a value: $ok if err is nil, or
+// an exception otherwise.
+func exceptionValue(err error) Exception {
+	if err == nil {
+		return OK
+	}
+	if exc, ok := err.(Exception); ok {
+		return exc
+	}
+	return &exception{err, nil}
+}
+
+// Information about a process, as reported by pgrep.
+type processInfo struct {
+	pid  int
+	ppid int
+	name string
+	// Command line arguments, including the command itself. Empty if not
+	// available.
+	args []string
+}
+
+func (p processInfo) toMap() vals.Map {
+	args := vals.EmptyList
+	for _, arg := range p.args {
+		args = args.Conj(arg)
+	}
+	return vals.MakeMap(
+		"pid", p.pid, "ppid", p.ppid, "name", p.name, "args", args)
+}
+
+type pgrepOpts struct{ Full bool }
+
+func (o *pgrepOpts) SetDefaultOptions() {}
+
+func pgrep(fm *Frame, opts pgrepOpts, patterns ...string) error {
+	var re *regexp.Regexp
+	switch len(patterns) {
+	case 0:
+	case 1:
+		var err error
+		re, err = regexp.Compile(patterns[0])
+		if err != nil {
+			return err
+		}
+	default:
+		return errs.ArityMismatch{What: "arguments",
+			ValidLow: 0, ValidHigh: 1, Actual: len(patterns)}
+	}
+	procs, err := listProcesses()
+	if err != nil {
+		return err
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].pid < procs[j].pid })
+	out := fm.ValueOutput()
+	for _, p := range procs {
+		if re != nil {
+			s := p.name
+			if opts.Full && len(p.args) > 0 {
+				s = strings.Join(p.args, " ")
+			}
+			if !re.MatchString(s) {
+				continue
+			}
+		}
+		if err := out.Put(p.toMap()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
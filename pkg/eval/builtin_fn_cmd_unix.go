@@ -5,13 +5,13 @@ package eval
 import (
 	"errors"
 	"os"
-	"os/exec"
 	"strconv"
+	"strings"
 	"syscall"
 
-	"src.elv.sh/pkg/env"
+	"golang.org/x/sys/unix"
 	"src.elv.sh/pkg/eval/errs"
-	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/parse"
 	"src.elv.sh/pkg/sys/eunix"
 )
 
@@ -23,18 +23,10 @@ var ErrNotInSameProcessGroup = errors.New("not in the same process group")
 var syscallExec = syscall.Exec
 
 func execFn(fm *Frame, args ...any) error {
-	var argstrings []string
-	if len(args) == 0 {
-		argstrings = []string{"elvish"}
-	} else {
-		argstrings = make([]string, len(args))
-		for i, a := range args {
-			argstrings[i] = vals.ToString(a)
-		}
+	if err := fm.Evaler.CheckRestricted("running exec"); err != nil {
+		return err
 	}
-
-	var err error
-	argstrings[0], err = exec.LookPath(argstrings[0])
+	argstrings, err := execArgs(args)
 	if err != nil {
 		return err
 	}
@@ -45,16 +37,6 @@ func execFn(fm *Frame, args ...any) error {
 	return syscallExec(argstrings[0], argstrings, os.Environ())
 }
 
-// Decrements $E:SHLVL. Called from execFn to ensure that $E:SHLVL remains the
-// same in the new command.
-func decSHLVL() {
-	i, err := strconv.Atoi(os.Getenv(env.SHLVL))
-	if err != nil {
-		return
-	}
-	os.Setenv(env.SHLVL, strconv.Itoa(i-1))
-}
-
 func fg(pids ...int) error {
 	if len(pids) == 0 {
 		return errs.ArityMismatch{What: "arguments", ValidLow: 1, ValidHigh: -1, Actual: len(pids)}
@@ -103,3 +85,38 @@ func fg(pids ...int) error {
 
 	return MakePipelineError(errors)
 }
+
+// Parses a signal, which may be a number or a name like "TERM", "SIGTERM" or
+// "term".
+func parseSignal(s string) (syscall.Signal, error) {
+	if i, err := strconv.Atoi(s); err == nil && i >= 0 {
+		return syscall.Signal(i), nil
+	}
+	name := strings.ToUpper(s)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if sig := unix.SignalNum(name); sig != 0 {
+		return sig, nil
+	}
+	return 0, errs.BadValue{What: "signal",
+		Valid: "signal name or non-negative integer", Actual: parse.Quote(s)}
+}
+
+// Sends sig to the process with the given pid. Like kill(2), a negative pid
+// refers to a process group.
+func killProcess(pid int, sig syscall.Signal) error {
+	return syscall.Kill(pid, sig)
+}
+
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	// EPERM means that the process exists but we can't send signals to it.
+	return (err == nil || err == syscall.EPERM) && !isZombie(pid)
+}
+
+// Starts the process in a new session, so that it no longer has a controlling
+// terminal and doesn't get SIGHUP when the terminal is closed.
+func detachedSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
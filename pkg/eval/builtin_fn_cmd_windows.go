@@ -1,13 +1,89 @@
 package eval
 
-import "errors"
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+	"src.elv.sh/pkg/eval/errs"
+	"src.elv.sh/pkg/parse"
+)
 
 var errNotSupportedOnWindows = errors.New("not supported on Windows")
 
-func execFn(...any) error {
-	return errNotSupportedOnWindows
+// Windows has no equivalent of execve, so exec is emulated by running the
+// command as a child process with the standard files of Elvish, waiting for
+// it to finish and then exiting with its exit status.
+func execFn(fm *Frame, args ...any) error {
+	if err := fm.Evaler.CheckRestricted("running exec"); err != nil {
+		return err
+	}
+	argstrings, err := execArgs(args)
+	if err != nil {
+		return err
+	}
+
+	fm.Evaler.PreExit()
+	decSHLVL()
+
+	proc, err := os.StartProcess(argstrings[0], argstrings, &os.ProcAttr{
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr}})
+	if err != nil {
+		return err
+	}
+	state, err := proc.Wait()
+	if err != nil {
+		return err
+	}
+	osExit(state.ExitCode())
+	return nil
 }
 
 func fg(...int) error {
 	return errNotSupportedOnWindows
 }
+
+// Windows doesn't have signals, so the only supported signals are KILL and
+// TERM, both of which terminate the process.
+func parseSignal(s string) (int, error) {
+	switch strings.TrimPrefix(strings.ToUpper(s), "SIG") {
+	case "KILL", "9":
+		return 9, nil
+	case "TERM", "15":
+		return 15, nil
+	}
+	return 0, errs.BadValue{What: "signal",
+		Valid: "KILL, TERM, 9 or 15", Actual: parse.Quote(s)}
+}
+
+func killProcess(pid int, _ int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	defer p.Release()
+	return p.Kill()
+}
+
+// Exit code of processes that are still running.
+const stillActive = 259
+
+func processExists(pid int) bool {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer windows.CloseHandle(h)
+	var code uint32
+	err = windows.GetExitCodeProcess(h, &code)
+	return err == nil && code == stillActive
+}
+
+// Starts the process without a console and in a new process group, so that
+// it is not affected by the console of Elvish being closed.
+func detachedSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		CreationFlags: detachedProcess | windows.CREATE_NEW_PROCESS_GROUP}
+}
@@ -17,9 +17,11 @@ func init() {
 
 		"make-map": makeMap,
 
-		"conj":   conj,
-		"assoc":  assoc,
-		"dissoc": dissoc,
+		"conj":      conj,
+		"assoc":     assoc,
+		"dissoc":    dissoc,
+		"assoc-in":  assocIn,
+		"update-in": updateIn,
 
 		"has-key":   hasKey,
 		"has-value": hasValue,
@@ -86,6 +88,60 @@ func assoc(a, k, v any) (any, error) {
 	return vals.Assoc(a, k, v)
 }
 
+func assocIn(container any, path vals.List, v any) (any, error) {
+	return updatePath(container, listToSlice(path),
+		func(any) (any, error) { return v, nil })
+}
+
+func updateIn(fm *Frame, container any, path vals.List, f Callable) (any, error) {
+	return updatePath(container, listToSlice(path), func(old any) (any, error) {
+		outputs, err := fm.CaptureOutput(func(fm *Frame) error {
+			return f.Call(fm, []any{old}, NoOpts)
+		})
+		if err != nil {
+			return nil, err
+		} else if len(outputs) != 1 {
+			return nil, errs.ArityMismatch{
+				What:     "number of outputs of the callback",
+				ValidLow: 1, ValidHigh: 1, Actual: len(outputs)}
+		}
+		return outputs[0], nil
+	})
+}
+
+// Returns a copy of container with the element at path replaced by the result
+// of f, which is called with the old element. Missing keys of maps are added,
+// with empty maps as intermediate values and $nil as the old element.
+func updatePath(container any, path []any, f func(old any) (any, error)) (any, error) {
+	if len(path) == 0 {
+		return f(container)
+	}
+	k := path[0]
+	child, err := vals.Index(container, k)
+	if err != nil {
+		if _, isMap := container.(vals.Map); !isMap {
+			return nil, err
+		}
+		child = nil
+		if len(path) > 1 {
+			child = vals.EmptyMap
+		}
+	}
+	newChild, err := updatePath(child, path[1:], f)
+	if err != nil {
+		return nil, err
+	}
+	return vals.Assoc(container, k, newChild)
+}
+
+func listToSlice(l vals.List) []any {
+	s := make([]any, 0, l.Len())
+	for it := l.Iterator(); it.HasElem(); it.Next() {
+		s = append(s, it.Elem())
+	}
+	return s
+}
+
 var errCannotDissoc = errors.New("cannot dissoc")
 
 func dissoc(a, k any) (any, error) {
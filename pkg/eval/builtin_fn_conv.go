@@ -0,0 +1,99 @@
+package eval
+
+import (
+	"math"
+	"math/big"
+
+	"src.elv.sh/pkg/eval/vals"
+)
+
+// Strict conversions between types.
+
+func init() {
+	addBuiltinFns(map[string]any{
+		"to-float": toFloat,
+		"to-list":  toList,
+		"explode":  explode,
+	})
+}
+
+// ConversionError is thrown by the strict conversion builtins when a value
+// can't be converted to the requested type.
+type ConversionError struct {
+	// The type converted to, like "float".
+	To    string
+	Value any
+}
+
+var _ vals.PseudoMap = ConversionError{}
+
+func (e ConversionError) Error() string {
+	return "cannot convert " + vals.Kind(e.Value) + " " + vals.ReprPlain(e.Value) +
+		" to " + e.To
+}
+
+func (e ConversionError) Kind() string           { return "conversion-error" }
+func (e ConversionError) Fields() vals.StructMap { return conversionErrorFields{e} }
+
+type conversionErrorFields struct{ e ConversionError }
+
+func (conversionErrorFields) IsStructMap() {}
+
+func (f conversionErrorFields) Type() string { return "conversion" }
+func (f conversionErrorFields) To() string   { return f.e.To }
+func (f conversionErrorFields) Value() any   { return f.e.Value }
+
+func toFloat(v any) (float64, error) {
+	n := v
+	if s, ok := v.(string); ok {
+		n = vals.ParseNum(s)
+	}
+	var f float64
+	switch n := n.(type) {
+	case float64:
+		return n, nil
+	case int:
+		return float64(n), nil
+	case *big.Int:
+		f, _ = new(big.Float).SetInt(n).Float64()
+	case *big.Rat:
+		f, _ = n.Float64()
+	default:
+		return 0, ConversionError{"float", v}
+	}
+	// Exact numbers are always finite, so an infinite result means that the
+	// number is out of the range of floats.
+	if math.IsInf(f, 0) {
+		return 0, ConversionError{"float", v}
+	}
+	return f, nil
+}
+
+func toList(v any) (vals.List, error) {
+	if l, ok := v.(vals.List); ok {
+		return l, nil
+	}
+	if !vals.CanIterate(v) {
+		return nil, ConversionError{"list", v}
+	}
+	l := vals.EmptyList
+	vals.Iterate(v, func(elem any) bool {
+		l = l.Conj(elem)
+		return true
+	})
+	return l, nil
+}
+
+func explode(fm *Frame, v any) error {
+	l, ok := v.(vals.List)
+	if !ok {
+		return ConversionError{"list", v}
+	}
+	out := fm.ValueOutput()
+	for it := l.Iterator(); it.HasElem(); it.Next() {
+		if err := out.Put(it.Elem()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -9,10 +9,11 @@ import (
 
 func init() {
 	addBuiltinFns(map[string]any{
-		"src":    src,
-		"-gc":    _gc,
-		"-stack": _stack,
-		"-log":   _log,
+		"src":           src,
+		"-gc":           _gc,
+		"-stack":        _stack,
+		"-log":          _log,
+		"-intern-stats": _internStats,
 	})
 }
 
@@ -13,8 +13,8 @@ func init() {
 	addBuiltinFns(map[string]any{
 		"has-env":   hasEnv,
 		"get-env":   getEnv,
-		"set-env":   os.Setenv,
-		"unset-env": os.Unsetenv,
+		"set-env":   setEnv,
+		"unset-env": unsetEnv,
 	})
 }
 
@@ -30,3 +30,17 @@ func getEnv(key string) (string, error) {
 	}
 	return value, nil
 }
+
+func setEnv(fm *Frame, key, value string) error {
+	if err := fm.Evaler.CheckRestricted("setting environment variable " + key); err != nil {
+		return err
+	}
+	return os.Setenv(key, value)
+}
+
+func unsetEnv(fm *Frame, key string) error {
+	if err := fm.Evaler.CheckRestricted("unsetting environment variable " + key); err != nil {
+		return err
+	}
+	return os.Unsetenv(key)
+}
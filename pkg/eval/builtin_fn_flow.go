@@ -2,10 +2,14 @@ package eval
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"math/big"
+	"math/rand"
+	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"golang.org/x/sync/semaphore"
 
@@ -22,12 +26,16 @@ func init() {
 	addBuiltinFns(map[string]any{
 		"run-parallel": runParallel,
 		// Exception and control
-		"fail":        fail,
-		"multi-error": multiErrorFn,
-		"return":      returnFn,
-		"break":       breakFn,
-		"continue":    continueFn,
-		"defer":       deferFn,
+		"fail":         fail,
+		"raise":        raise,
+		"rethrow":      rethrow,
+		"is-exception": isException,
+		"multi-error":  multiErrorFn,
+		"return":       returnFn,
+		"break":        breakFn,
+		"continue":     continueFn,
+		"defer":        deferFn,
+		"retry":        retry,
 		// Iterations.
 		"each":  each,
 		"peach": peach,
@@ -52,15 +60,19 @@ func runParallel(fm *Frame, functions ...Callable) error {
 	return MakePipelineError(exceptions)
 }
 
-func each(fm *Frame, f Callable, inputs Inputs) error {
+type eachOpts struct{ Longest bool }
+
+func (*eachOpts) SetDefaultOptions() {}
+
+func each(fm *Frame, opts eachOpts, f Callable, inputs ...any) error {
 	broken := false
 	var err error
-	inputs(func(v any) {
+	call := func(args []any) {
 		if broken {
 			return
 		}
 		newFm := fm.Fork("closure of each")
-		ex := f.Call(newFm, []any{v}, NoOpts)
+		ex := f.Call(newFm, args, NoOpts)
 		newFm.Close()
 
 		if ex != nil {
@@ -74,10 +86,121 @@ func each(fm *Frame, f Callable, inputs Inputs) error {
 				err = ex
 			}
 		}
-	})
+	}
+
+	switch len(inputs) {
+	case 0:
+		fm.IterateInputs(func(v any) {
+			call([]any{v})
+			if broken {
+				fm.stopInputs()
+			}
+		})
+	case 1:
+		if !vals.CanIterate(inputs[0]) {
+			return fmt.Errorf("%s cannot be iterated", vals.Kind(inputs[0]))
+		}
+		vals.Iterate(inputs[0], func(v any) bool {
+			call([]any{v})
+			return !broken
+		})
+	default:
+		zipErr := zipInputs(inputs, opts.Longest, func(args []any) bool {
+			call(args)
+			return !broken
+		})
+		if zipErr != nil {
+			return zipErr
+		}
+	}
 	return err
 }
 
+// Iterates multiple iterable inputs in lockstep, calling f with one element
+// from each of them, until f returns false or all the inputs are exhausted.
+//
+// Since vals.Iterate can't be paused, each input is iterated in its own
+// goroutine, which sends the elements over an unbuffered channel; this avoids
+// reading whole inputs into memory.
+//
+// If longest is false, it is an error for the inputs to have different
+// lengths. This is only found out when the shortest input is exhausted, after
+// f has been called with the elements before that; the remaining elements are
+// then counted to report the lengths. If longest is true, shorter inputs are
+// padded with nil.
+func zipInputs(inputs []any, longest bool, f func([]any) bool) error {
+	for i, input := range inputs {
+		if !vals.CanIterate(input) {
+			return errs.BadValue{What: "input " + strconv.Itoa(i+1),
+				Valid: "iterable", Actual: vals.Kind(input)}
+		}
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+	chs := make([]chan any, len(inputs))
+	for i := range inputs {
+		chs[i] = make(chan any)
+		wg.Add(1)
+		go func(input any, ch chan<- any) {
+			defer wg.Done()
+			defer close(ch)
+			vals.Iterate(input, func(v any) bool {
+				select {
+				case ch <- v:
+					return true
+				case <-stop:
+					return false
+				}
+			})
+		}(inputs[i], chs[i])
+	}
+
+	exhausted := make([]bool, len(chs))
+	for n := 0; ; n++ {
+		args := make([]any, len(chs))
+		nExhausted := 0
+		for i, ch := range chs {
+			v, ok := <-ch
+			args[i], exhausted[i] = v, !ok
+			if !ok {
+				nExhausted++
+			}
+		}
+		if nExhausted == len(chs) {
+			return nil
+		}
+		if nExhausted > 0 && !longest {
+			// Find the first input whose length differs from the first one,
+			// and count the elements of both.
+			length := func(i int) int {
+				if exhausted[i] {
+					return n
+				}
+				count := n + 1
+				for range chs[i] {
+					count++
+				}
+				return count
+			}
+			for i := 1; i < len(chs); i++ {
+				if exhausted[i] != exhausted[0] {
+					want := length(0)
+					return errs.ArityMismatch{What: "input " + strconv.Itoa(i+1),
+						ValidLow: want, ValidHigh: want, Actual: length(i)}
+				}
+			}
+		}
+		if !f(args) {
+			return nil
+		}
+	}
+}
+
 type peachOpt struct{ NumWorkers vals.Num }
 
 func (o *peachOpt) SetDefaultOptions() { o.NumWorkers = math.Inf(1) }
@@ -187,6 +310,204 @@ func fail(v any) error {
 	return FailError{v}
 }
 
+// CustomError is an error returned by the "raise" command. It carries fields
+// defined by the user, which always include a string "type" field.
+type CustomError struct{ Fields vals.Map }
+
+// Error returns the type, followed by the message field if it exists.
+func (e CustomError) Error() string {
+	t, _ := e.Fields.Index("type")
+	if msg, ok := e.Fields.Index("message"); ok {
+		return vals.ToString(t) + ": " + vals.ToString(msg)
+	}
+	return vals.ToString(t)
+}
+
+// Kind returns "custom-error".
+func (CustomError) Kind() string { return "custom-error" }
+
+// Repr returns the representation of the fields, tagged like a pseudo-map.
+func (e CustomError) Repr(indent int) string {
+	return "[^custom-error " + vals.Repr(e.Fields, indent)[1:]
+}
+
+// Index looks up a field.
+func (e CustomError) Index(k any) (any, bool) { return e.Fields.Index(k) }
+
+// HasKey returns whether a field exists.
+func (e CustomError) HasKey(k any) bool { return vals.HasKey(e.Fields, k) }
+
+// IterateKeys iterates the names of the fields.
+func (e CustomError) IterateKeys(f func(any) bool) { vals.IterateKeys(e.Fields, f) }
+
+func raise(fields vals.Map) error {
+	t, ok := fields.Index("type")
+	if !ok {
+		return errs.BadValue{What: "argument of raise",
+			Valid: "map with a type field", Actual: vals.ReprPlain(fields)}
+	}
+	if s, ok := t.(string); !ok || s == "" {
+		return errs.BadValue{What: "type field of argument of raise",
+			Valid: "non-empty string", Actual: vals.ReprPlain(t)}
+	}
+	return CustomError{fields}
+}
+
+var errRethrowNotInCatch = errors.New("rethrow must be called from within a catch block")
+
+func rethrow(fm *Frame) error {
+	if fm.caught == nil {
+		return errRethrowNotInCatch
+	}
+	return fm.caught
+}
+
+type isExceptionOpts struct{ Type any }
+
+func (*isExceptionOpts) SetDefaultOptions() {}
+
+func isException(opts isExceptionOpts, v any) bool {
+	exc, ok := v.(Exception)
+	if !ok || exc.Reason() == nil {
+		return false
+	}
+	if opts.Type == nil {
+		return true
+	}
+	t, err := vals.Index(exc.Reason(), "type")
+	return err == nil && vals.Equal(t, opts.Type)
+}
+
+type retryOpts struct {
+	Times    int
+	Backoff  string
+	Delay    any
+	MaxDelay any
+	Jitter   float64
+	If       Callable
+}
+
+func (o *retryOpts) SetDefaultOptions() {
+	o.Times = 3
+	o.Backoff = "constant"
+	o.Delay = 1
+}
+
+// Reference to [rand.Float64] that can be overridden in tests.
+var randFloat64 = rand.Float64
+
+func retry(fm *Frame, opts retryOpts, f Callable) error {
+	if opts.Times < 1 {
+		return errs.BadValue{What: "retry &times",
+			Valid: "positive integer", Actual: vals.ToString(opts.Times)}
+	}
+	switch opts.Backoff {
+	case "constant", "linear", "exponential":
+	default:
+		return errs.BadValue{What: "retry &backoff",
+			Valid: "constant, linear or exponential", Actual: opts.Backoff}
+	}
+	delay, ok := parseDuration(opts.Delay)
+	if !ok || delay < 0 {
+		return errs.BadValue{What: "retry &delay",
+			Valid:  "non-negative number or duration string",
+			Actual: vals.ReprPlain(opts.Delay)}
+	}
+	maxDelay := time.Duration(math.MaxInt64)
+	if opts.MaxDelay != nil {
+		maxDelay, ok = parseDuration(opts.MaxDelay)
+		if !ok || maxDelay < 0 {
+			return errs.BadValue{What: "retry &max-delay",
+				Valid:  "non-negative number or duration string",
+				Actual: vals.ReprPlain(opts.MaxDelay)}
+		}
+	}
+	if !(0 <= opts.Jitter && opts.Jitter <= 1) {
+		return errs.BadValue{What: "retry &jitter",
+			Valid: "number between 0 and 1", Actual: vals.ToString(opts.Jitter)}
+	}
+
+	for attempt := 1; ; attempt++ {
+		newFm := fm.Fork("retry")
+		err := f.Call(newFm, NoArgs, NoOpts)
+		newFm.Close()
+		if err == nil || attempt == opts.Times {
+			return err
+		}
+		// Flow control exceptions and interrupts are never retried.
+		if _, ok := Reason(err).(Flow); ok || Reason(err) == ErrInterrupted {
+			return err
+		}
+		if opts.If != nil {
+			retryable, errIf := callRetryPredicate(fm, opts.If, err)
+			if errIf != nil {
+				return errIf
+			}
+			if !retryable {
+				return err
+			}
+		}
+
+		// Clamp after applying the jitter, so that the delay never exceeds
+		// maxDelay.
+		d := retryDelay(opts.Backoff, delay, attempt)
+		if opts.Jitter > 0 {
+			jittered := float64(d) * (1 + opts.Jitter*(2*randFloat64()-1))
+			if jittered >= math.MaxInt64 {
+				d = time.Duration(math.MaxInt64)
+			} else {
+				d = time.Duration(jittered)
+			}
+		}
+		if d > maxDelay {
+			d = maxDelay
+		}
+		select {
+		case <-fm.Context().Done():
+			return ErrInterrupted
+		case <-timeAfter(fm, d):
+		}
+	}
+}
+
+// Returns the delay after the given attempt, which is 1-based.
+func retryDelay(backoff string, delay time.Duration, attempt int) time.Duration {
+	var factor float64
+	switch backoff {
+	case "linear":
+		factor = float64(attempt)
+	case "exponential":
+		factor = math.Pow(2, float64(attempt-1))
+	default:
+		factor = 1
+	}
+	d := float64(delay) * factor
+	if d >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(d)
+}
+
+func callRetryPredicate(fm *Frame, pred Callable, err error) (bool, error) {
+	outputs, errCall := fm.CaptureOutput(func(fm *Frame) error {
+		return pred.Call(fm, []any{err}, NoOpts)
+	})
+	if errCall != nil {
+		return false, errCall
+	}
+	if len(outputs) != 1 {
+		return false, errs.ArityMismatch{
+			What:     "number of outputs of the &if callback",
+			ValidLow: 1, ValidHigh: 1, Actual: len(outputs)}
+	}
+	if b, ok := outputs[0].(bool); ok {
+		return b, nil
+	}
+	return false, errs.BadValue{
+		What:  "output of the &if callback",
+		Valid: "boolean", Actual: vals.Kind(outputs[0])}
+}
+
 func multiErrorFn(excs ...Exception) error {
 	return PipelineError{excs}
 }
@@ -212,6 +533,9 @@ func deferFn(fm *Frame, fn Callable) error {
 	deferTraceback := fm.traceback
 	fm.addDefer(func(fm *Frame) Exception {
 		err := fn.Call(fm, NoArgs, NoOpts)
+		if err == nil {
+			return nil
+		}
 		if exc, ok := err.(Exception); ok {
 			return exc
 		}
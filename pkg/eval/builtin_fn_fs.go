@@ -1,6 +1,12 @@
 package eval
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"src.elv.sh/pkg/env"
 	"src.elv.sh/pkg/eval/errs"
 	"src.elv.sh/pkg/fsutil"
 )
@@ -10,13 +16,21 @@ import (
 func init() {
 	addBuiltinFns(map[string]any{
 		// Directory
-		"cd": cd,
+		"cd":    cd,
+		"pushd": pushd,
+		"popd":  popd,
+		"dirs":  dirs,
 
 		// Path
 		"tilde-abbr": tildeAbbr,
 	})
 }
 
+var (
+	errNoPrevDir     = errors.New("no previous directory")
+	errEmptyDirStack = errors.New("directory stack is empty")
+)
+
 func cd(fm *Frame, args ...string) error {
 	var dir string
 	switch len(args) {
@@ -27,12 +41,139 @@ func cd(fm *Frame, args ...string) error {
 			return err
 		}
 	case 1:
-		dir = args[0]
+		if args[0] == "-" {
+			dir = fm.Evaler.getPrevDir()
+			if dir == "" {
+				return errNoPrevDir
+			}
+		} else {
+			dir = searchCdPath(args[0])
+		}
+	default:
+		return errs.ArityMismatch{What: "arguments", ValidLow: 0, ValidHigh: 1, Actual: len(args)}
+	}
+
+	return chdirWithSuggestions(fm.Evaler, dir)
+}
+
+// Like ev.Chdir, but adds suggestions of similar directories to the error if
+// dir doesn't exist.
+func chdirWithSuggestions(ev *Evaler, dir string) error {
+	err := ev.Chdir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return withSuggestions(err, suggestSiblingDirs(dir))
+	}
+	return err
+}
+
+// Returns the first directory in $E:CDPATH that contains dir. Absolute paths
+// and paths starting with . or .. are returned as is, as are paths not found
+// in any directory in $E:CDPATH.
+func searchCdPath(dir string) string {
+	cdPath := os.Getenv(env.CDPATH)
+	if cdPath == "" || filepath.IsAbs(dir) || dir == "." || dir == ".." ||
+		strings.HasPrefix(dir, "./") || strings.HasPrefix(dir, "../") {
+		return dir
+	}
+	for _, root := range filepath.SplitList(cdPath) {
+		if root == "" {
+			root = "."
+		}
+		path := filepath.Join(root, dir)
+		if stat, err := os.Stat(path); err == nil && stat.IsDir() {
+			return path
+		}
+	}
+	return dir
+}
+
+func pushd(fm *Frame, args ...string) error {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	ev := fm.Evaler
+	var dir string
+	switch len(args) {
+	case 0:
+		// Swap the working directory with the top of the stack.
+		ev.mu.RLock()
+		n := len(ev.dirStack)
+		if n > 0 {
+			dir = ev.dirStack[n-1]
+		}
+		ev.mu.RUnlock()
+		if n == 0 {
+			return errEmptyDirStack
+		}
+		err = ev.Chdir(dir)
+		if err != nil {
+			return err
+		}
+		ev.mu.Lock()
+		ev.dirStack = append(ev.dirStack[:n-1:n-1], pwd)
+		ev.mu.Unlock()
+		return nil
+	case 1:
+		dir = searchCdPath(args[0])
 	default:
 		return errs.ArityMismatch{What: "arguments", ValidLow: 0, ValidHigh: 1, Actual: len(args)}
 	}
+	err = chdirWithSuggestions(ev, dir)
+	if err != nil {
+		return err
+	}
+	ev.mu.Lock()
+	ev.dirStack = append(ev.dirStack[:len(ev.dirStack):len(ev.dirStack)], pwd)
+	ev.mu.Unlock()
+	return nil
+}
+
+func popd(fm *Frame) error {
+	ev := fm.Evaler
+	ev.mu.RLock()
+	n := len(ev.dirStack)
+	var dir string
+	if n > 0 {
+		dir = ev.dirStack[n-1]
+	}
+	ev.mu.RUnlock()
+	if n == 0 {
+		return errEmptyDirStack
+	}
+	err := ev.Chdir(dir)
+	if err != nil {
+		return err
+	}
+	ev.mu.Lock()
+	// Limit the capacity, so that a later pushd allocates a new array instead
+	// of overwriting the element that dirs may still be reading.
+	ev.dirStack = ev.dirStack[: n-1 : n-1]
+	ev.mu.Unlock()
+	return nil
+}
+
+func dirs(fm *Frame) error {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	fm.Evaler.mu.RLock()
+	stack := fm.Evaler.dirStack
+	fm.Evaler.mu.RUnlock()
 
-	return fm.Evaler.Chdir(dir)
+	out := fm.ValueOutput()
+	err = out.Put(pwd)
+	if err != nil {
+		return err
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		err := out.Put(stack[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func tildeAbbr(path string) string {
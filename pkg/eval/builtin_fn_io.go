@@ -2,24 +2,40 @@ package eval
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"math/big"
+	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 
 	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/errutil"
 	"src.elv.sh/pkg/eval/errs"
 	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/eval/vars"
 	"src.elv.sh/pkg/parse"
 	"src.elv.sh/pkg/strutil"
+	"src.elv.sh/pkg/sys"
+	"src.elv.sh/pkg/ui"
+	"src.elv.sh/pkg/wcwidth"
 )
 
 // Input and output.
 
 func init() {
 	addBuiltinFns(map[string]any{
+		// Value input
+		"read-value": readValue,
+
 		// Value output
 		"put":    put,
 		"repeat": repeat,
@@ -28,6 +44,8 @@ func init() {
 		"read-bytes": readBytes,
 		"read-upto":  readUpto,
 		"read-line":  readLine,
+		"read-char":  readChar,
+		"ask":        ask,
 
 		// Bytes output
 		"print":  print,
@@ -46,16 +64,21 @@ func init() {
 		"only-bytes":  onlyBytes,
 		"only-values": onlyValues,
 
+		// Both bytes and values
+		"progress": progress,
+
 		// Bytes to value
 		"slurp":           slurp,
 		"from-lines":      fromLines,
 		"from-json":       fromJSON,
+		"from-repr":       fromRepr,
 		"from-terminated": fromTerminated,
 
 		// Value to bytes
 		"to-lines":      toLines,
 		"to-json":       toJSON,
 		"to-terminated": toTerminated,
+		"to-table":      toTable,
 	})
 }
 
@@ -81,31 +104,116 @@ func repeat(fm *Frame, n int, v any) error {
 	return nil
 }
 
-func readBytes(fm *Frame, max int) (string, error) {
+type readOpts struct{ Timeout any }
+
+func (*readOpts) SetDefaultOptions() {}
+
+// Parses the &timeout option of commands that read inputs.
+func parseTimeout(timeout any) (time.Duration, error) {
+	d, ok := parseDuration(timeout)
+	if !ok || d < 0 {
+		return 0, errs.BadValue{What: "&timeout",
+			Valid: "non-negative number or duration string", Actual: vals.ReprPlain(timeout)}
+	}
+	return d, nil
+}
+
+// Returns a function for reading from the byte input. If timeout is not nil,
+// the function fails with a Timeout error when the input doesn't become ready
+// within the duration since inputReader was called.
+func inputReader(fm *Frame, timeout any) (func([]byte) (int, error), error) {
 	in := fm.InputFile()
+	if timeout == nil {
+		return in.Read, nil
+	}
+	d, err := parseTimeout(timeout)
+	if err != nil {
+		return nil, err
+	}
+	deadline := timeNow().Add(d)
+	return func(p []byte) (int, error) {
+		ready, err := waitForInput(in, deadline)
+		if err != nil {
+			return 0, err
+		}
+		if !ready {
+			return 0, Timeout{d}
+		}
+		return in.Read(p)
+	}, nil
+}
+
+func readValue(fm *Frame, opts readOpts) error {
+	var expired <-chan time.Time
+	var d time.Duration
+	if opts.Timeout != nil {
+		var err error
+		d, err = parseTimeout(opts.Timeout)
+		if err != nil {
+			return err
+		}
+		expired = timeAfter(fm, d)
+	}
+	put := func(v any, ok bool) error {
+		if !ok {
+			return errs.ArityMismatch{What: "values", ValidLow: 1, ValidHigh: 1, Actual: 0}
+		}
+		return fm.ValueOutput().Put(v)
+	}
+	// Prefer a value that is already available, so that it is read even if the
+	// timeout is 0.
+	select {
+	case v, ok := <-fm.InputChan():
+		return put(v, ok)
+	default:
+	}
+	select {
+	case v, ok := <-fm.InputChan():
+		return put(v, ok)
+	case <-expired:
+		return Timeout{d}
+	case <-fm.Context().Done():
+		return ErrInterrupted
+	}
+}
+
+func readBytes(fm *Frame, opts readOpts, max int) (string, error) {
+	read, err := inputReader(fm, opts.Timeout)
+	if err != nil {
+		return "", err
+	}
 	buf := make([]byte, max)
-	read := 0
-	for read < max {
-		n, err := in.Read(buf[read:])
-		read += n
+	nRead := 0
+	for nRead < max {
+		n, err := read(buf[nRead:])
+		nRead += n
 		if err == io.EOF {
 			break
 		} else if err != nil {
 			return "", err
 		}
 	}
-	return string(buf[:read]), nil
+	return string(buf[:nRead]), nil
 }
 
-func readUpto(fm *Frame, terminator string) (string, error) {
+func readUpto(fm *Frame, opts readOpts, terminator string) (string, error) {
 	if err := checkTerminator(terminator); err != nil {
 		return "", err
 	}
-	in := fm.InputFile()
+	read, err := inputReader(fm, opts.Timeout)
+	if err != nil {
+		return "", err
+	}
+	return readUptoWith(read, terminator[0])
+}
+
+// Reads one byte at a time with read, so that no input after the terminator
+// is consumed.
+func readUptoWith(read func([]byte) (int, error), terminator byte) (string, error) {
 	var buf []byte
 	for {
 		var b [1]byte
-		_, err := in.Read(b[:])
+		_, err := read(b[:])
 		if err != nil {
 			if err == io.EOF {
 				break
@@ -113,7 +221,7 @@ func readUpto(fm *Frame, terminator string) (string, error) {
 			return "", err
 		}
 		buf = append(buf, b[0])
-		if b[0] == terminator[0] {
+		if b[0] == terminator {
 			break
 		}
 	}
@@ -128,14 +236,102 @@ func checkTerminator(s string) error {
 	return nil
 }
 
-func readLine(fm *Frame) (string, error) {
-	s, err := readUpto(fm, "\n")
+func readLine(fm *Frame, opts readOpts) (string, error) {
+	s, err := readUpto(fm, opts, "\n")
 	if err != nil {
 		return "", err
 	}
 	return strutil.ChopLineEnding(s), nil
 }
 
+func readChar(fm *Frame, opts readOpts) (string, error) {
+	// Without this, a terminal only makes the input available after the user
+	// presses Enter.
+	_, restore, err := setTTYMode(fm.InputFile(), true, false)
+	defer restore()
+	if err != nil {
+		return "", err
+	}
+	read, err := inputReader(fm, opts.Timeout)
+	if err != nil {
+		return "", err
+	}
+	var buf [utf8.UTFMax]byte
+	n := 0
+	for n < charLen(buf[0]) {
+		_, err := read(buf[n : n+1])
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return "", err
+		}
+		n++
+	}
+	return string(buf[:n]), nil
+}
+
+// Returns the length of the UTF-8 encoding of a character from its first byte,
+// treating invalid first bytes as characters of their own. The zero byte (also
+// used before anything is read) has a length of 1.
+func charLen(b byte) int {
+	switch {
+	case b&0xe0 == 0xc0:
+		return 2
+	case b&0xf0 == 0xe0:
+		return 3
+	case b&0xf8 == 0xf0:
+		return 4
+	default:
+		return 1
+	}
+}
+
+type askOpts struct{ Hidden bool }
+
+func (*askOpts) SetDefaultOptions() {}
+
+func ask(fm *Frame, opts askOpts, prompt string) (string, error) {
+	// If the byte input is a terminal, use the controlling terminal for both
+	// the prompt and the input, so that the prompt is shown even when the
+	// outputs are redirected, like in an output capture. Input piped to ask,
+	// like with "yes | ./script.elv", is read from the byte input.
+	in, out := fm.InputFile(), fm.ErrorFile()
+	if sys.IsATTY(in.Fd()) {
+		if tty, err := openTTY(); err == nil {
+			defer tty.Close()
+			in, out = tty, tty
+			// Reading from the terminal is not interrupted by Ctrl-C, so set
+			// a deadline to stop it instead.
+			stop := context.AfterFunc(fm.Context(),
+				func() { tty.SetReadDeadline(time.Now()) })
+			defer stop()
+		}
+	}
+	if _, err := out.WriteString(prompt); err != nil {
+		return "", err
+	}
+	if opts.Hidden {
+		isTTY, restore, err := setTTYMode(in, false, true)
+		defer restore()
+		if err != nil {
+			return "", err
+		}
+		if isTTY {
+			// The newline typed by the user is not echoed either.
+			defer out.WriteString("\n")
+		}
+	}
+	line, err := readUptoWith(in.Read, '\n')
+	if err != nil {
+		if fm.Context().Err() != nil {
+			return "", ErrInterrupted
+		}
+		return "", err
+	}
+	return strutil.ChopLineEnding(line), nil
+}
+
 type printOpts struct{ Sep string }
 
 func (o *printOpts) SetDefaultOptions() { o.Sep = " " }
@@ -320,6 +516,331 @@ func onlyValues(fm *Frame) error {
 	return nil
 }
 
+// Implements the tee special command. Each target is a file name, a file, a
+// callable, or a variable containing a list, a file or a callable. Files named
+// by file names are truncated unless appendFiles is true.
+func tee(fm *Frame, appendFiles bool, targets []any) error {
+	var files []*os.File
+	var fns []Callable
+	var lists []vars.Var
+	for _, target := range targets {
+		switch target := target.(type) {
+		case string:
+			if strings.HasPrefix(target, "-") {
+				// Catch options of tee(1) other than -a, which would otherwise
+				// become file names.
+				return errs.BadValue{What: "tee target",
+					Valid: "file name not starting with -", Actual: parse.Quote(target)}
+			}
+			if err := fm.Evaler.CheckRestricted("writing to file " + target); err != nil {
+				return err
+			}
+			flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+			if appendFiles {
+				flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+			}
+			f, err := os.OpenFile(target, flag, 0o644)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			files = append(files, f)
+		case *os.File:
+			files = append(files, target)
+		case Callable:
+			fns = append(fns, target)
+		case vars.Var:
+			// A variable written on its own is never used as a file name.
+			switch v := target.Get().(type) {
+			case vals.List:
+				lists = append(lists, target)
+			case *os.File:
+				files = append(files, v)
+			case Callable:
+				fns = append(fns, v)
+			default:
+				return errs.BadValue{What: "tee target variable",
+					Valid: "list, file or callable", Actual: vals.Kind(v)}
+			}
+		default:
+			return errs.BadValue{What: "tee target",
+				Valid: "string, file or callable", Actual: vals.Kind(target)}
+		}
+	}
+
+	// The outputs of callables are discarded, so that they don't get mixed with
+	// the inputs passed through.
+	var discardPort *Port
+	if len(fns) > 0 {
+		port, done, err := PipePort(
+			func(ch <-chan any) {
+				for range ch {
+				}
+			},
+			func(r *os.File) { io.Copy(io.Discard, r) })
+		if err != nil {
+			return err
+		}
+		defer done()
+		discardPort = port
+	}
+
+	// Values and bytes are forwarded concurrently, so writes to the targets
+	// are serialized with a mutex. After the first error from any target, the
+	// targets are no longer written to, but the inputs are still forwarded.
+	var mu sync.Mutex
+	var errTarget error
+	toFiles := func(p []byte) {
+		for _, f := range files {
+			if errTarget == nil {
+				_, errTarget = f.Write(p)
+			}
+		}
+	}
+	toFnsAndLists := func(v any) {
+		for _, fn := range fns {
+			if errTarget == nil {
+				newFm := fm.forkWithOutput("tee target", discardPort.fork())
+				newFm.ports[0] = DummyInputPort
+				errTarget = fn.Call(newFm, []any{v}, NoOpts)
+				newFm.Close()
+			}
+		}
+		for _, list := range lists {
+			if errTarget == nil {
+				if l, ok := list.Get().(vals.List); ok {
+					errTarget = list.Set(l.Conj(v))
+				} else {
+					errTarget = errs.BadValue{What: "tee target variable",
+						Valid: "list", Actual: vals.Kind(list.Get())}
+				}
+			}
+		}
+	}
+	// Forward bytes in a goroutine, in chunks. Callables and lists get each
+	// line; only a line that spans multiple chunks is buffered.
+	bytesDone := make(chan error, 1)
+	go func() {
+		out := fm.ByteOutput()
+		in := fm.InputFile()
+		buf := make([]byte, teeChunkSize)
+		var partial []byte
+		for {
+			n, err := in.Read(buf)
+			if n > 0 {
+				chunk := buf[:n]
+				if _, errOut := out.Write(chunk); errOut != nil {
+					bytesDone <- errOut
+					return
+				}
+				mu.Lock()
+				toFiles(chunk)
+				if len(fns) > 0 || len(lists) > 0 {
+					for {
+						i := bytes.IndexByte(chunk, '\n')
+						if i == -1 {
+							partial = append(partial, chunk...)
+							break
+						}
+						line := append(partial, chunk[:i]...)
+						toFnsAndLists(strutil.ChopLineEnding(string(line)))
+						partial, chunk = partial[:0], chunk[i+1:]
+					}
+				}
+				mu.Unlock()
+			}
+			if err != nil {
+				if len(partial) > 0 {
+					mu.Lock()
+					toFnsAndLists(string(partial))
+					mu.Unlock()
+				}
+				if err == io.EOF {
+					err = nil
+				}
+				bytesDone <- err
+				return
+			}
+		}
+	}()
+
+	out := fm.ValueOutput()
+	var errValues error
+	for v := range fm.InputChan() {
+		if errValues = out.Put(v); errValues != nil {
+			break
+		}
+		mu.Lock()
+		toFiles([]byte(vals.ToString(v) + "\n"))
+		toFnsAndLists(v)
+		mu.Unlock()
+	}
+	errBytes := <-bytesDone
+	mu.Lock()
+	defer mu.Unlock()
+	return errutil.Multi(errValues, errBytes, errTarget)
+}
+
+// Size of the chunks in which tee copies byte inputs.
+const teeChunkSize = 32 * 1024
+
+type progressOpts struct {
+	Total    any
+	Interval any
+	Label    string
+}
+
+func (o *progressOpts) SetDefaultOptions() { o.Interval = 0.1 }
+
+func progress(fm *Frame, opts progressOpts) error {
+	total := -1.0
+	if opts.Total != nil {
+		if err := vals.ScanToGo(opts.Total, &total); err != nil || total < 0 {
+			return errs.BadValue{What: "progress &total",
+				Valid: "non-negative number", Actual: vals.ReprPlain(opts.Total)}
+		}
+	}
+	interval, ok := parseDuration(opts.Interval)
+	if !ok || interval <= 0 {
+		return errs.BadValue{What: "progress &interval",
+			Valid:  "positive number or duration string",
+			Actual: vals.ReprPlain(opts.Interval)}
+	}
+
+	var nValues, nBytes atomic.Int64
+	start := timeNow()
+
+	// The progress line is only shown when stderr is a terminal. It is
+	// redrawn periodically, truncated to the width of the terminal so that it
+	// never wraps, and cleared when the inputs are exhausted so that it
+	// doesn't get in the way of the editor.
+	reportDone := make(chan struct{})
+	reportStopped := make(chan struct{})
+	if errFile := fm.ErrorFile(); errFile != nil && sys.IsATTY(errFile.Fd()) {
+		go func() {
+			defer close(reportStopped)
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-reportDone:
+					errFile.WriteString("\r\033[K")
+					return
+				case <-ticker.C:
+					line := progressLine(opts.Label, nValues.Load(), nBytes.Load(),
+						total, timeNow().Sub(start))
+					if _, width := sys.WinSize(errFile); width > 0 {
+						line = wcwidth.Trim(line, width-1)
+					}
+					errFile.WriteString("\r\033[K" + line)
+				}
+			}
+		}()
+	} else {
+		close(reportStopped)
+	}
+
+	bytesDone := make(chan error, 1)
+	go func() {
+		out := fm.ByteOutput()
+		in := fm.InputFile()
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := in.Read(buf)
+			if n > 0 {
+				if _, errOut := out.Write(buf[:n]); errOut != nil {
+					bytesDone <- errOut
+					return
+				}
+				nBytes.Add(int64(n))
+			}
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				bytesDone <- err
+				return
+			}
+		}
+	}()
+
+	out := fm.ValueOutput()
+	var errValues error
+	for v := range fm.InputChan() {
+		if errValues = out.Put(v); errValues != nil {
+			break
+		}
+		nValues.Add(1)
+	}
+	errBytes := <-bytesDone
+	close(reportDone)
+	<-reportStopped
+	return errutil.Multi(errValues, errBytes)
+}
+
+// Returns the line showing the progress. The progress is measured in values,
+// unless no value but some bytes have been seen. A negative total means that it
+// is unknown.
+func progressLine(label string, nValues, nBytes int64, total float64, elapsed time.Duration) string {
+	var parts []string
+	count, format := float64(nValues), func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	unit := " values"
+	if nValues == 0 && nBytes > 0 {
+		count, format, unit = float64(nBytes), formatBytes, ""
+	}
+
+	if total >= 0 {
+		percent := 100.0
+		if total > 0 {
+			percent = min(100, count/total*100)
+		}
+		parts = append(parts, fmt.Sprintf("%s/%s%s (%d%%)",
+			format(count), format(total), unit, int(percent)))
+	} else {
+		parts = append(parts, format(count)+unit)
+	}
+	if nValues > 0 && nBytes > 0 {
+		parts = append(parts, formatBytes(float64(nBytes)))
+	}
+	if elapsed > 0 {
+		rate := count / elapsed.Seconds()
+		if unit == "" {
+			parts = append(parts, formatBytes(rate)+"/s")
+		} else {
+			parts = append(parts, strconv.FormatFloat(rate, 'f', 1, 64)+"/s")
+		}
+		if total > count && rate > 0 {
+			eta := time.Duration((total - count) / rate * float64(time.Second))
+			parts = append(parts, "ETA "+eta.Round(time.Second).String())
+		}
+	}
+
+	line := strings.Join(parts, ", ")
+	if label != "" {
+		line = label + ": " + line
+	}
+	return line
+}
+
+var byteUnits = []string{"KiB", "MiB", "GiB", "TiB"}
+
+// Formats a number of bytes using binary prefixes, like "1.5 MiB".
+func formatBytes(n float64) string {
+	if n < 1024 {
+		return strconv.FormatFloat(n, 'f', 0, 64) + " B"
+	}
+	unit := ""
+	for _, unit = range byteUnits {
+		n /= 1024
+		if n < 1024 {
+			break
+		}
+	}
+	return strconv.FormatFloat(n, 'f', 1, 64) + " " + unit
+}
+
 type blackholeWriter struct{}
 
 func (blackholeWriter) Write(p []byte) (int, error) { return len(p), nil }
@@ -335,7 +856,7 @@ func fromLines(fm *Frame) error {
 	for {
 		line, err := filein.ReadString('\n')
 		if line != "" {
-			err := out.Put(strutil.ChopLineEnding(line))
+			err := out.Put(intern(strutil.ChopLineEnding(line)))
 			if err != nil {
 				return err
 			}
@@ -376,11 +897,18 @@ func fromJSON(fm *Frame) error {
 	}
 }
 
+func fromRepr(fm *Frame) error {
+	out := fm.ValueOutput()
+	return readReprLines(fm.InputFile(), out.Put)
+}
+
 // Converts a interface{} that results from json.Unmarshal to an Elvish value.
 func fromJSONInterface(v any) (any, error) {
 	switch v := v.(type) {
-	case nil, bool, string:
+	case nil, bool:
 		return v, nil
+	case string:
+		return intern(v), nil
 	case json.Number:
 		// The JSON syntax doesn't restrict the precision of numbers. Since
 		// we called json.Decoder.UseNumber, it preserves the full number
@@ -411,7 +939,7 @@ func fromJSONInterface(v any) (any, error) {
 			if err != nil {
 				return nil, err
 			}
-			m = m.Assoc(key, convertedVal)
+			m = m.Assoc(intern(key), convertedVal)
 		}
 		return m, nil
 	default:
@@ -429,7 +957,7 @@ func fromTerminated(fm *Frame, terminator string) error {
 	for {
 		line, err := filein.ReadString(terminator[0])
 		if line != "" {
-			err := out.Put(strutil.ChopTerminator(line, terminator[0]))
+			err := out.Put(intern(strutil.ChopTerminator(line, terminator[0])))
 			if err != nil {
 				return err
 			}
@@ -486,3 +1014,228 @@ func toJSON(fm *Frame, inputs Inputs) error {
 	})
 	return errEncode
 }
+
+type toTableOpts struct {
+	Columns     any
+	SortBy      any
+	Reverse     bool
+	Width       int
+	HeaderStyle string
+}
+
+func (*toTableOpts) SetDefaultOptions() {}
+
+func toTable(fm *Frame, opts toTableOpts, inputs Inputs) error {
+	var headerStyling ui.Styling
+	if opts.HeaderStyle != "" {
+		headerStyling = ui.ParseStyling(opts.HeaderStyle)
+		if headerStyling == nil {
+			return errs.BadValue{What: "option header-style",
+				Valid: "valid styling", Actual: parse.Quote(opts.HeaderStyle)}
+		}
+	}
+
+	var rows []any
+	var errInput error
+	inputs(func(v any) {
+		if errInput != nil {
+			return
+		}
+		if vals.Kind(v) != "map" {
+			errInput = errs.BadValue{What: "input to to-table",
+				Valid: "map", Actual: vals.Kind(v)}
+			return
+		}
+		rows = append(rows, v)
+	})
+	if errInput != nil {
+		return errInput
+	}
+
+	var columns []any
+	if opts.Columns != nil {
+		var err error
+		columns, err = vals.Collect(opts.Columns)
+		if err != nil {
+			return err
+		}
+	} else {
+		columns = tableColumns(rows)
+	}
+	if len(rows) == 0 || len(columns) == 0 {
+		return nil
+	}
+
+	if opts.SortBy != nil {
+		slices.SortStableFunc(rows, func(a, b any) int {
+			va, errA := vals.Index(a, opts.SortBy)
+			vb, errB := vals.Index(b, opts.SortBy)
+			// Rows without the column always come last.
+			switch {
+			case errA != nil && errB != nil:
+				return 0
+			case errA != nil:
+				return 1
+			case errB != nil:
+				return -1
+			}
+			o := compareTotal(va, vb)
+			if opts.Reverse {
+				return -o
+			}
+			return o
+		})
+	} else if opts.Reverse {
+		slices.Reverse(rows)
+	}
+
+	width := opts.Width
+	if width <= 0 && fm.Port(1).File != nil {
+		_, width = sys.WinSize(fm.Port(1).File)
+	}
+
+	table := make([][]ui.Text, len(rows)+1)
+	table[0] = make([]ui.Text, len(columns))
+	// Columns whose values are all numbers are aligned to the right.
+	hasNumber := make([]bool, len(columns))
+	hasNonNumber := make([]bool, len(columns))
+	for j, col := range columns {
+		table[0][j] = ui.T(vals.ToString(col), headerStyling)
+	}
+	for i, row := range rows {
+		table[i+1] = make([]ui.Text, len(columns))
+		for j, col := range columns {
+			v, err := vals.Index(row, col)
+			if err != nil {
+				continue
+			}
+			if vals.Kind(v) == "number" {
+				hasNumber[j] = true
+			} else {
+				hasNonNumber[j] = true
+			}
+			if t, ok := v.(ui.Text); ok {
+				table[i+1][j] = t
+			} else {
+				table[i+1][j] = ui.T(vals.ToString(v))
+			}
+		}
+	}
+
+	widths := tableColumnWidths(table, width)
+	out := fm.ByteOutput()
+	for _, row := range table {
+		// Don't write trailing empty cells.
+		for len(row) > 0 && row[len(row)-1] == nil {
+			row = row[:len(row)-1]
+		}
+		var line ui.Text
+		for j, cell := range row {
+			if j > 0 {
+				line = append(line, ui.T("  ")...)
+			}
+			rightAlign := hasNumber[j] && !hasNonNumber[j]
+			line = append(line, tableCell(cell, widths[j], rightAlign, j == len(row)-1)...)
+		}
+		_, err := out.WriteString(plainOrVTString(line) + "\n")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the union of the keys of all the rows, sorted.
+func tableColumns(rows []any) []any {
+	var columns []any
+	for _, row := range rows {
+		vals.IterateKeys(row, func(k any) bool {
+			if !slices.ContainsFunc(columns, func(c any) bool { return vals.Equal(c, k) }) {
+				columns = append(columns, k)
+			}
+			return true
+		})
+	}
+	slices.SortFunc(columns, compareTotal)
+	return columns
+}
+
+// Like vals.CmpTotal, but returns an int suitable for the slices package.
+func compareTotal(a, b any) int {
+	switch vals.CmpTotal(a, b) {
+	case vals.CmpLess:
+		return -1
+	case vals.CmpMore:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Returns the width of each column. If maxWidth is positive and the table
+// doesn't fit in it, the widest columns are narrowed until it does, or all
+// columns are 1 column wide.
+func tableColumnWidths(table [][]ui.Text, maxWidth int) []int {
+	widths := make([]int, len(table[0]))
+	total := 2 * (len(widths) - 1)
+	for j := range widths {
+		for _, row := range table {
+			widths[j] = max(widths[j], textWidth(row[j]))
+		}
+		total += widths[j]
+	}
+	for maxWidth > 0 && total > maxWidth {
+		widest := 0
+		for j, w := range widths {
+			if w > widths[widest] {
+				widest = j
+			}
+		}
+		if widths[widest] <= 1 {
+			break
+		}
+		widths[widest]--
+		total--
+	}
+	return widths
+}
+
+func tableCell(t ui.Text, width int, rightAlign, last bool) ui.Text {
+	w := textWidth(t)
+	if w > width {
+		if width > 1 {
+			t = ui.Concat(t.TrimWcwidth(width-1), ui.T("…"))
+		} else {
+			t = t.TrimWcwidth(width)
+		}
+		w = textWidth(t)
+	}
+	padding := ui.T(strings.Repeat(" ", width-w))
+	if rightAlign {
+		return ui.Concat(padding, t)
+	} else if last {
+		return t
+	}
+	return ui.Concat(t, padding)
+}
+
+func textWidth(t ui.Text) int {
+	w := 0
+	for _, seg := range t {
+		w += wcwidth.Of(seg.Text)
+	}
+	return w
+}
+
+// Returns the VT string of t if it has any styling, or its plain text
+// otherwise.
+func plainOrVTString(t ui.Text) string {
+	var sb strings.Builder
+	for _, seg := range t {
+		if seg.SGR() != "" {
+			return t.VTString()
+		}
+		sb.WriteString(seg.Text)
+	}
+	return sb.String()
+}
@@ -0,0 +1,32 @@
+package eval
+
+import (
+	"testing"
+	"time"
+
+	"src.elv.sh/pkg/tt"
+)
+
+func TestProgressLine(t *testing.T) {
+	tt.Test(t, progressLine,
+		// Values
+		Args("", int64(0), int64(0), -1.0, time.Duration(0)).Rets("0 values"),
+		Args("", int64(30), int64(0), -1.0, 2*time.Second).
+			Rets("30 values, 15.0/s"),
+		Args("", int64(30), int64(0), 90.0, 2*time.Second).
+			Rets("30/90 values (33%), 15.0/s, ETA 4s"),
+		Args("", int64(30), int64(0), 20.0, 2*time.Second).
+			Rets("30/20 values (100%), 15.0/s"),
+		// Bytes
+		Args("", int64(0), int64(3<<20), -1.0, 2*time.Second).
+			Rets("3.0 MiB, 1.5 MiB/s"),
+		Args("", int64(0), int64(512), 2048.0, time.Second).
+			Rets("512 B/2.0 KiB (25%), 512 B/s, ETA 3s"),
+		// Both values and bytes
+		Args("", int64(10), int64(2048), -1.0, time.Second).
+			Rets("10 values, 2.0 KiB, 10.0/s"),
+		// Label
+		Args("copying", int64(1), int64(0), -1.0, time.Duration(0)).
+			Rets("copying: 1 values"),
+	)
+}
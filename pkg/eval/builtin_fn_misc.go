@@ -5,13 +5,18 @@ package eval
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"net"
+	"reflect"
+	"sort"
+	"strings"
 	"sync"
 
 	"src.elv.sh/pkg/diag"
 	"src.elv.sh/pkg/eval/errs"
 	"src.elv.sh/pkg/eval/vals"
 	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/strutil"
 )
 
 var (
@@ -25,12 +30,17 @@ func init() {
 	addBuiltinFns(map[string]any{
 		"kind-of":    kindOf,
 		"constantly": constantly,
+		"alias":      alias,
 
 		// Introspection
 		"call":    call,
 		"resolve": resolve,
 		"eval":    eval,
 		"use-mod": useMod,
+		"reload":  reload,
+
+		"format-code": formatCode,
+		"parse":       parseCode,
 
 		"deprecate": deprecate,
 
@@ -73,6 +83,50 @@ func constantly(args ...any) Callable {
 	)
 }
 
+func alias(fm *Frame, name, code string) error {
+	if name == "" || strings.ContainsRune(name, ':') {
+		return errs.BadValue{What: "alias name",
+			Valid: "unqualified command name", Actual: parse.Quote(name)}
+	}
+	tree, err := parse.Parse(parse.Source{Name: "[alias " + name + "]", Code: code},
+		parse.Config{WarningWriter: fm.ErrorFile()})
+	if err != nil {
+		return err
+	}
+	form, words := aliasLastForm(tree)
+	if form == nil {
+		return errs.BadValue{What: "alias code",
+			Valid: "non-empty code", Actual: parse.Quote(code)}
+	}
+	// The code is compiled with the alias itself hidden, so that it can refer
+	// to the command it shadows, like in "alias ls 'ls -G'", and redefining an
+	// alias doesn't nest it.
+	global := fm.Evaler.Global().clone()
+	for i := range global.infos {
+		if global.infos[i].name == name+FnSuffix {
+			global.infos[i].deleted = true
+		}
+	}
+	// Compile the code as written first, so that compilation errors point to
+	// the code of the alias rather than the wrapper below.
+	_, _, err = compile(fm.Evaler.Builtin().static(), global.static(), nil, tree, nil)
+	if err != nil {
+		return err
+	}
+	// Forward the arguments to the last command.
+	end := form.Range().To
+	src := parse.Source{Name: "[alias " + name + "]",
+		Code: "var alias~ = {|@args| " + code[:end] + " $@args" + code[end:] + "\n}"}
+	ns, err := fm.Eval(src, nil, global)
+	if err != nil {
+		return err
+	}
+	fn := ns.IndexString("alias" + FnSuffix).Get().(*Closure)
+	fm.Evaler.ExtendGlobal(BuildNs().AddFn(name,
+		&Alias{Name: name, Code: code, Words: words, fn: fn}))
+	return nil
+}
+
 func call(fm *Frame, fn Callable, argsVal vals.List, optsVal vals.Map) error {
 	args := make([]any, 0, argsVal.Len())
 	for it := argsVal.Iterator(); it.HasElem(); it.Next() {
@@ -91,18 +145,44 @@ func call(fm *Frame, fn Callable, argsVal vals.List, optsVal vals.Map) error {
 	return fn.Call(fm.Fork("-call"), args, opts)
 }
 
-func resolve(fm *Frame, head string) string {
+type resolveOpts struct{ All bool }
+
+func (*resolveOpts) SetDefaultOptions() {}
+
+func resolve(fm *Frame, opts resolveOpts, head string) error {
+	out := fm.ValueOutput()
 	special, fnRef := resolveCmdHeadInternally(fm, head, nil)
 	switch {
 	case special != nil:
-		return "special"
+		return out.Put("special")
+	case opts.All && !strings.ContainsAny(head, ":/"):
+		return resolveAll(fm, out, head, fnRef)
 	case fnRef != nil:
-		return "$" + head + FnSuffix
+		return out.Put("$" + head + FnSuffix)
 	default:
-		return "(external " + parse.Quote(head) + ")"
+		return out.Put("(external " + parse.Quote(head) + ")")
 	}
 }
 
+// Outputs all the commands an unqualified command head can refer to, from the
+// one that takes precedence to the ones it shadows.
+func resolveAll(fm *Frame, out ValueOutput, head string, fnRef *varRef) error {
+	if fnRef != nil && fnRef.scope != builtinScope {
+		if err := out.Put("$" + head + FnSuffix); err != nil {
+			return err
+		}
+	}
+	if _, index := fm.searchBuiltin(head+FnSuffix, nil); index != -1 {
+		if err := out.Put("$builtin:" + head + FnSuffix); err != nil {
+			return err
+		}
+	}
+	if hasExternal(head) {
+		return out.Put("(external " + parse.Quote(head) + ")")
+	}
+	return nil
+}
+
 type evalOpts struct {
 	Ns    *Ns
 	OnEnd Callable
@@ -110,6 +190,50 @@ type evalOpts struct {
 
 func (*evalOpts) SetDefaultOptions() {}
 
+func formatCode(code string) (string, error) {
+	src := parse.Source{Name: "[format-code]", Code: code}
+	tree, err := parse.Parse(src, parse.Config{})
+	if err != nil {
+		return "", err
+	}
+	return parse.Format(tree), nil
+}
+
+func parseCode(code string) (vals.Map, error) {
+	src := parse.Source{Name: "[parse]", Code: code}
+	tree, err := parse.Parse(src, parse.Config{})
+	if err != nil {
+		return nil, err
+	}
+	return nodeToMap(tree.Root), nil
+}
+
+// Converts a parse tree node and its descendants to nested maps.
+func nodeToMap(n parse.Node) vals.Map {
+	r := n.Range()
+	m := vals.MakeMap(
+		"type", strutil.CamelToDashed(reflect.TypeOf(n).Elem().Name()),
+		"from", r.From, "to", r.To, "source", parse.SourceText(n))
+	switch n := n.(type) {
+	case *parse.Pipeline:
+		m = m.Assoc("background", n.Background)
+	case *parse.Redir:
+		m = m.Assoc("mode", strutil.CamelToDashed(n.Mode.String()))
+	case *parse.Primary:
+		m = m.Assoc("primary-type", strutil.CamelToDashed(n.Type.String()))
+		switch n.Type {
+		case parse.Bareword, parse.SingleQuoted, parse.DoubleQuoted,
+			parse.Variable, parse.Wildcard:
+			m = m.Assoc("value", n.Value)
+		}
+	}
+	children := vals.EmptyList
+	for _, ch := range parse.Children(n) {
+		children = children.Conj(nodeToMap(ch))
+	}
+	return m.Assoc("children", children)
+}
+
 func eval(fm *Frame, opts evalOpts, code string) error {
 	src := parse.Source{Name: fmt.Sprintf("[eval %d]", nextEvalCount()), Code: code}
 	ns := opts.Ns
@@ -144,7 +268,123 @@ func nextEvalCountImpl() int {
 }
 
 func useMod(fm *Frame, spec string) (*Ns, error) {
-	return use(fm, spec, nil)
+	return use(fm, spec, nil, false)
+}
+
+var errNoRC = errors.New("no rc file to reload")
+
+func reload(fm *Frame, specs ...string) (vals.Map, error) {
+	switch len(specs) {
+	case 0:
+		return reloadRC(fm)
+	case 1:
+		return reloadModule(fm, specs[0])
+	default:
+		return nil, errs.ArityMismatch{What: "arguments",
+			ValidLow: 0, ValidHigh: 1, Actual: len(specs)}
+	}
+}
+
+func reloadRC(fm *Frame) (vals.Map, error) {
+	path := fm.Evaler.EffectiveRcPath
+	if path == "" {
+		return nil, errNoRC
+	}
+	code, err := readFileUTF8(path)
+	if err != nil {
+		return nil, err
+	}
+	old := nsValues(fm.Evaler.Global())
+	err = fm.Evaler.Eval(parse.Source{Name: path, Code: code, IsFile: true},
+		EvalCfg{Interrupts: fm.ctx, Ports: fm.ports})
+	if err != nil {
+		return nil, err
+	}
+	return nsDiff(old, nsValues(fm.Evaler.Global())), nil
+}
+
+func reloadModule(fm *Frame, spec string) (vals.Map, error) {
+	loaded := maps.Clone(fm.Evaler.modules)
+	newNs, err := use(fm, spec, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	// The module may not have been loaded before, in which case everything
+	// in it is reported as added.
+	var oldNs *Ns
+	for key, ns := range fm.Evaler.modules {
+		if ns == newNs {
+			oldNs = loaded[key]
+		}
+	}
+	old := map[string]any{}
+	if oldNs != nil {
+		old = nsValues(oldNs)
+		// Make variables holding the old module, like the ones created by
+		// use, refer to the new one.
+		for _, ns := range []*Ns{fm.local, fm.Evaler.Global()} {
+			for _, v := range ns.slots {
+				if v != nil && v.Get() == oldNs {
+					v.Set(newNs)
+				}
+			}
+		}
+	}
+	return nsDiff(old, nsValues(newNs)), nil
+}
+
+// Returns the values of the variables in a namespace.
+func nsValues(ns *Ns) map[string]any {
+	m := make(map[string]any)
+	ns.IterateKeysString(func(name string) {
+		m[name] = ns.IndexString(name).Get()
+	})
+	return m
+}
+
+// Returns a map with the sorted names of the variables that are added, removed
+// and changed between two namespaces.
+func nsDiff(old, new map[string]any) vals.Map {
+	var added, removed, changed []string
+	for name, v := range new {
+		oldV, ok := old[name]
+		if !ok {
+			added = append(added, name)
+		} else if !sameDefinition(oldV, v) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range old {
+		if _, ok := new[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	return vals.MakeMap(
+		"added", sortedList(added),
+		"removed", sortedList(removed),
+		"changed", sortedList(changed))
+}
+
+// Like vals.Equal, but treats closures defined with the same code as equal,
+// since evaluating the same code again creates different closures.
+func sameDefinition(a, b any) bool {
+	ca, ok1 := a.(*Closure)
+	cb, ok2 := b.(*Closure)
+	if ok1 && ok2 {
+		return ca.SrcMeta.Name == cb.SrcMeta.Name &&
+			ca.SrcMeta.Code[ca.DefRange.From:ca.DefRange.To] ==
+				cb.SrcMeta.Code[cb.DefRange.From:cb.DefRange.To]
+	}
+	return vals.Equal(a, b)
+}
+
+func sortedList(names []string) vals.List {
+	sort.Strings(names)
+	l := vals.EmptyList
+	for _, name := range names {
+		l = l.Conj(name)
+	}
+	return l
 }
 
 func deprecate(fm *Frame, msg string) {
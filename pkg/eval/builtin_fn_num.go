@@ -1,6 +1,7 @@
 package eval
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"math/big"
@@ -260,7 +261,9 @@ func mul(rawNums ...vals.Num) vals.Num {
 
 func slash(fm *Frame, args ...vals.Num) error {
 	if len(args) == 0 {
-		fm.Deprecate("implicit cd is deprecated; use cd or location mode instead", fm.traceback.Head, 21)
+		if !fm.Evaler.getAutoCd() {
+			fm.Deprecate("implicit cd is deprecated; use cd or location mode instead", fm.traceback.Head, 21)
+		}
 		// cd /
 		return fm.Evaler.Chdir("/")
 	}
@@ -341,29 +344,46 @@ func randint(args ...int) (int, error) {
 //lint:ignore SA1019 useful for getting deterministic behavior in Elvish code.
 func randseed(x int) { rand.Seed(int64(x)) }
 
-type rangeOpts struct{ Step vals.Num }
+type rangeOpts struct {
+	Step vals.Num
+	From vals.Num
+}
 
-// TODO: The default value can only be used implicitly; passing "range
+// TODO: The default values can only be used implicitly; passing "range
 // &step=nil" results in an error.
-func (o *rangeOpts) SetDefaultOptions() { o.Step = nil }
+func (o *rangeOpts) SetDefaultOptions() { o.Step, o.From = nil, nil }
+
+var errRangeFromWithArgs = errors.New("&from can't be used with start or end")
 
 func rangeFn(fm *Frame, opts rangeOpts, args ...vals.Num) error {
+	out := cancelableOutput{fm, fm.ValueOutput()}
+
 	var rawNums []vals.Num
 	switch len(args) {
+	case 0:
+		from, step := opts.From, opts.Step
+		if from == nil {
+			from = 0
+		}
+		if step == nil {
+			step = 1
+		}
+		return rangeInfinite(vals.UnifyNums([]vals.Num{from, step}, vals.Int), out)
 	case 1:
 		rawNums = []vals.Num{0, args[0]}
 	case 2:
 		rawNums = []vals.Num{args[0], args[1]}
 	default:
-		return errs.ArityMismatch{What: "arguments", ValidLow: 1, ValidHigh: 2, Actual: len(args)}
+		return errs.ArityMismatch{What: "arguments", ValidLow: 0, ValidHigh: 2, Actual: len(args)}
+	}
+	if opts.From != nil {
+		return errRangeFromWithArgs
 	}
 	if opts.Step != nil {
 		rawNums = append(rawNums, opts.Step)
 	}
 	nums := vals.UnifyNums(rawNums, vals.Int)
 
-	out := fm.ValueOutput()
-
 	switch nums := nums.(type) {
 	case []int:
 		return rangeBuiltinNum(nums, out)
@@ -378,6 +398,88 @@ func rangeFn(fm *Frame, opts rangeOpts, args ...vals.Num) error {
 	}
 }
 
+// A ValueOutput that fails with ErrInterrupted once the frame is canceled, so
+// that long or infinite ranges can be interrupted even if their outputs are
+// consumed.
+type cancelableOutput struct {
+	fm  *Frame
+	out ValueOutput
+}
+
+func (o cancelableOutput) Put(v any) error {
+	if o.fm.Canceled() {
+		return ErrInterrupted
+	}
+	return o.out.Put(v)
+}
+
+// Outputs nums[0], nums[0]+nums[1], nums[0]+2*nums[1], ... until the output
+// fails. Ints are promoted to big ints when they would overflow, and floats
+// stop when the values stop changing, like in a finite range.
+func rangeInfinite(nums any, out ValueOutput) error {
+	switch nums := nums.(type) {
+	case []int:
+		cur, step := nums[0], nums[1]
+		if step == 0 {
+			return badZeroStep(step)
+		}
+		for {
+			err := out.Put(vals.FromGo(cur))
+			if err != nil {
+				return err
+			}
+			next := cur + step
+			if (next > cur) != (step > 0) {
+				// Overflow; continue with big ints.
+				bigCur := big.NewInt(int64(cur))
+				bigCur.Add(bigCur, big.NewInt(int64(step)))
+				return rangeInfiniteBig(bigCur, big.NewInt(int64(step)), out, bigIntDesc)
+			}
+			cur = next
+		}
+	case []*big.Int:
+		return rangeInfiniteBig(nums[0], nums[1], out, bigIntDesc)
+	case []*big.Rat:
+		return rangeInfiniteBig(nums[0], nums[1], out, bigRatDesc)
+	case []float64:
+		cur, step := nums[0], nums[1]
+		if step == 0 {
+			return badZeroStep(step)
+		}
+		for {
+			err := out.Put(vals.FromGo(cur))
+			if err != nil {
+				return err
+			}
+			if cur+step == cur {
+				return nil
+			}
+			cur += step
+		}
+	default:
+		panic("unreachable")
+	}
+}
+
+func rangeInfiniteBig[T bigNum[T]](cur, step T, out ValueOutput, d bigNumDesc[T]) error {
+	if step.Sign() == 0 {
+		return badZeroStep(step)
+	}
+	for {
+		err := out.Put(vals.FromGo(cur))
+		if err != nil {
+			return err
+		}
+		next := d.newZero()
+		next.Add(cur, step)
+		cur = next
+	}
+}
+
+func badZeroStep(step any) error {
+	return errs.BadValue{What: "step", Valid: "non-zero", Actual: vals.ToString(step)}
+}
+
 type builtinNum interface{ int | float64 }
 
 func rangeBuiltinNum[T builtinNum](nums []T, out ValueOutput) error {
@@ -0,0 +1,73 @@
+package eval
+
+import (
+	"src.elv.sh/pkg/eval/vals"
+)
+
+// Sets, represented as maps whose keys are the elements.
+
+func init() {
+	addBuiltinFns(map[string]any{
+		"make-set":         makeSet,
+		"set-union":        setUnion,
+		"set-intersection": setIntersection,
+		"set-difference":   setDifference,
+	})
+}
+
+func makeSet(inputs Inputs) vals.Map {
+	s := vals.EmptyMap
+	inputs(func(v any) { s = s.Assoc(v, true) })
+	return s
+}
+
+func setUnion(sets ...vals.Map) vals.Map {
+	s := vals.EmptyMap
+	for _, set := range sets {
+		for it := set.Iterator(); it.HasElem(); it.Next() {
+			k, _ := it.Elem()
+			s = s.Assoc(k, true)
+		}
+	}
+	return s
+}
+
+func setIntersection(set vals.Map, more ...vals.Map) vals.Map {
+	s := vals.EmptyMap
+	for it := set.Iterator(); it.HasElem(); it.Next() {
+		k, _ := it.Elem()
+		if inAll(k, more) {
+			s = s.Assoc(k, true)
+		}
+	}
+	return s
+}
+
+func setDifference(set vals.Map, more ...vals.Map) vals.Map {
+	s := vals.EmptyMap
+	for it := set.Iterator(); it.HasElem(); it.Next() {
+		k, _ := it.Elem()
+		if !inAny(k, more) {
+			s = s.Assoc(k, true)
+		}
+	}
+	return s
+}
+
+func inAll(k any, sets []vals.Map) bool {
+	for _, set := range sets {
+		if _, ok := set.Index(k); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func inAny(k any, sets []vals.Map) bool {
+	for _, set := range sets {
+		if _, ok := set.Index(k); ok {
+			return true
+		}
+	}
+	return false
+}
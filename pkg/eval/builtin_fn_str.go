@@ -49,9 +49,20 @@ func chainStringComparer(p func(a, b string) bool) func(...string) bool {
 	}
 }
 
-func toString(fm *Frame, args ...any) error {
+type toStringOpts struct{ Strict bool }
+
+func (*toStringOpts) SetDefaultOptions() {}
+
+func toString(fm *Frame, opts toStringOpts, args ...any) error {
 	out := fm.ValueOutput()
 	for _, a := range args {
+		if opts.Strict {
+			switch a.(type) {
+			case string, int, float64, vals.Stringer:
+			default:
+				return ConversionError{"string", a}
+			}
+		}
 		err := out.Put(vals.ToString(a))
 		if err != nil {
 			return err
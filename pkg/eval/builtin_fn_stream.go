@@ -65,6 +65,11 @@ func take(fm *Frame, n int, inputs Inputs) error {
 			errOut = out.Put(v)
 		}
 		i++
+		if i >= n {
+			// The remaining inputs are discarded; don't wait for the
+			// command producing them to produce all of them.
+			fm.stopInputs()
+		}
 	})
 	return errOut
 }
@@ -1,6 +1,7 @@
 package eval
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"math/big"
@@ -15,6 +16,7 @@ import (
 func init() {
 	addBuiltinFns(map[string]any{
 		"sleep":     sleep,
+		"timeout":   timeout,
 		"time":      timeCmd,
 		"benchmark": benchmark,
 	})
@@ -29,24 +31,10 @@ var (
 )
 
 func sleep(fm *Frame, duration any) error {
-	var f float64
-	var d time.Duration
-
-	if err := vals.ScanToGo(duration, &f); err == nil {
-		d = time.Duration(f * float64(time.Second))
-	} else {
-		// See if it is a duration string rather than a simple number.
-		switch duration := duration.(type) {
-		case string:
-			d, err = time.ParseDuration(duration)
-			if err != nil {
-				return ErrInvalidSleepDuration
-			}
-		default:
-			return ErrInvalidSleepDuration
-		}
+	d, ok := parseDuration(duration)
+	if !ok {
+		return ErrInvalidSleepDuration
 	}
-
 	if d < 0 {
 		return ErrNegativeSleepDuration
 	}
@@ -59,6 +47,74 @@ func sleep(fm *Frame, duration any) error {
 	}
 }
 
+// Parses a duration, which may be a number in seconds or a string accepted by
+// [time.ParseDuration].
+func parseDuration(duration any) (time.Duration, bool) {
+	var f float64
+	if err := vals.ScanToGo(duration, &f); err == nil {
+		return time.Duration(f * float64(time.Second)), true
+	}
+	// See if it is a duration string rather than a simple number.
+	if s, ok := duration.(string); ok {
+		d, err := time.ParseDuration(s)
+		return d, err == nil
+	}
+	return 0, false
+}
+
+// Timeout is thrown by the timeout command when the callable doesn't finish
+// within the duration.
+type Timeout struct{ Duration time.Duration }
+
+var _ vals.PseudoMap = Timeout{}
+
+func (t Timeout) Error() string { return "timed out after " + t.Duration.String() }
+
+func (t Timeout) Kind() string           { return "timeout-error" }
+func (t Timeout) Fields() vals.StructMap { return timeoutFields{t} }
+
+type timeoutFields struct{ t Timeout }
+
+func (timeoutFields) IsStructMap() {}
+
+func (f timeoutFields) Type() string      { return "timeout" }
+func (f timeoutFields) Duration() float64 { return f.t.Duration.Seconds() }
+
+func timeout(fm *Frame, duration any, f Callable) error {
+	d, ok := parseDuration(duration)
+	if !ok || d < 0 {
+		return errs.BadValue{What: "duration",
+			Valid: "non-negative number or duration string", Actual: vals.ReprPlain(duration)}
+	}
+
+	ctx, cancel := context.WithTimeout(fm.ctx, d)
+	defer cancel()
+	newFm := fm.Fork("timeout")
+	newFm.ctx = ctx
+	// Put external commands in a job of their own, so that they can be killed
+	// without affecting other commands in the same pipeline.
+	j := &job{}
+	newFm.job = j
+	stopKilling := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				j.kill()
+			}
+		case <-stopKilling:
+		}
+	}()
+
+	err := f.Call(newFm, NoArgs, NoOpts)
+	close(stopKilling)
+	j.done()
+	if ctx.Err() == context.DeadlineExceeded && fm.ctx.Err() == nil {
+		return Timeout{d}
+	}
+	return err
+}
+
 type timeOpt struct{ OnEnd Callable }
 
 func (o *timeOpt) SetDefaultOptions() {}
@@ -17,7 +17,6 @@ var builtinNs = BuildNsNamed("").AddVars(map[string]vars.Var{
 	"false":          vars.NewReadOnly(false),
 	"buildinfo":      vars.NewReadOnly(buildinfo.Value),
 	"version":        vars.NewReadOnly(buildinfo.Value.Version),
-	"paths":          vars.NewEnvListVar("PATH"),
 	"nop" + FnSuffix: vars.NewReadOnly(nopGoFn),
 })
 
@@ -13,17 +13,22 @@ package eval
 // As another example, the "del" special form removes a variable, affecting the
 // compiler.
 //
-// Flow control structures are also implemented as special forms in elvish, with
-// closures functioning as code blocks.
+// Flow control structures are also implemented as special forms in elvish. The
+// bodies of if, while and for are compiled inline as blocks, while other
+// special forms use closures as code blocks.
 
 import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 
+	"src.elv.sh/pkg/buildinfo"
 	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/eval/errs"
 	"src.elv.sh/pkg/eval/vals"
 	"src.elv.sh/pkg/eval/vars"
 	"src.elv.sh/pkg/parse"
@@ -63,6 +68,9 @@ func init() {
 		"while": compileWhile,
 		"for":   compileFor,
 		"try":   compileTry,
+		"match": compileMatch,
+
+		"tee": compileTee,
 
 		"pragma": compilePragma,
 	}
@@ -150,7 +158,8 @@ func compileDel(cp *compiler, fn *parse.Form) effectOp {
 		if len(indices) == 0 {
 			if ref.scope == envScope {
 				f = delEnvVarOp{fn.Range(), ref.subNames[0]}
-			} else if ref.scope == localScope && len(ref.subNames) == 0 {
+			} else if ref.scope == localScope && len(ref.subNames) == 0 &&
+				ref.index >= cp.thisScope().blockStart {
 				f = delLocalVarOp{ref.index}
 				cp.thisScope().infos[ref.index].deleted = true
 			} else {
@@ -168,7 +177,12 @@ func compileDel(cp *compiler, fn *parse.Form) effectOp {
 type delLocalVarOp struct{ index int }
 
 func (op delLocalVarOp) exec(fm *Frame) Exception {
-	fm.local.slots[op.index] = nil
+	// The slots may be shared with namespaces that other goroutines can see,
+	// like the one returned by (*Evaler).Global, so they are never mutated in
+	// place.
+	slots := slices.Clone(fm.local.slots)
+	slots[op.index] = nil
+	fm.local = &Ns{slots, fm.local.infos}
 	return nil
 }
 
@@ -178,6 +192,9 @@ type delEnvVarOp struct {
 }
 
 func (op delEnvVarOp) exec(fm *Frame) Exception {
+	if err := fm.Evaler.CheckRestricted("unsetting environment variable " + op.name); err != nil {
+		return fm.errorp(op, err)
+	}
 	return fm.errorp(op, os.Unsetenv(op.name))
 }
 
@@ -239,13 +256,36 @@ func compileFn(cp *compiler, fn *parse.Form) effectOp {
 	index := cp.thisScope().add(name + FnSuffix)
 	op := cp.lambda(bodyNode)
 
-	return fnOp{fn.Args[0].Range(), index, op}
+	return fnOp{fn.Args[0].Range(), index, op, docComment(cp.srcMeta.Code, fn.Range().From)}
+}
+
+// Returns the content of the comment lines directly before the line at pos,
+// with the leading "#" and up to one space after it removed. Returns "" if
+// pos is not at the start of a line, ignoring leading whitespace.
+func docComment(code string, pos int) string {
+	lineStart := strings.LastIndexByte(code[:pos], '\n') + 1
+	if strings.TrimLeft(code[lineStart:pos], " \t") != "" {
+		return ""
+	}
+	var lines []string
+	for lineStart > 0 {
+		prevStart := strings.LastIndexByte(code[:lineStart-1], '\n') + 1
+		line := strings.TrimLeft(strings.TrimRight(code[prevStart:lineStart-1], "\r"), " \t")
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		lines = append(lines, strings.TrimPrefix(line[1:], " "))
+		lineStart = prevStart
+	}
+	slices.Reverse(lines)
+	return strings.Join(lines, "\n")
 }
 
 type fnOp struct {
 	nameRange diag.Ranging
 	varIndex  int
 	lambdaOp  valuesOp
+	doc       string
 }
 
 func (op fnOp) exec(fm *Frame) Exception {
@@ -259,6 +299,7 @@ func (op fnOp) exec(fm *Frame) Exception {
 	}
 	c := values[0].(*Closure)
 	c.op = fnWrap{c.op}
+	c.Doc = op.doc
 	return fm.errorp(op.nameRange, fm.local.slots[op.varIndex].Set(c))
 }
 
@@ -299,7 +340,7 @@ type useOp struct {
 }
 
 func (op useOp) exec(fm *Frame) Exception {
-	ns, err := use(fm, op.spec, op)
+	ns, err := use(fm, op.spec, op, false)
 	if err != nil {
 		return fm.errorp(op, err)
 	}
@@ -307,9 +348,12 @@ func (op useOp) exec(fm *Frame) Exception {
 	return nil
 }
 
+// Resolves and loads a module. If reload is true, the module is evaluated again
+// even if it has been loaded before.
+//
 // TODO: Add support for module specs relative to a package/workspace.
 // See https://github.com/elves/elvish/issues/1421.
-func use(fm *Frame, spec string, r diag.Ranger) (*Ns, error) {
+func use(fm *Frame, spec string, r diag.Ranger, reload bool) (*Ns, error) {
 	// Handle relative imports. Note that this deliberately does not support Windows backslash as a
 	// path separator because module specs are meant to be platform independent. If necessary, we
 	// translate a module spec to an appropriate path for the platform.
@@ -325,14 +369,18 @@ func use(fm *Frame, spec string, r diag.Ranger) (*Ns, error) {
 			}
 		}
 		path := filepath.Clean(dir + "/" + spec)
-		return useFromFile(fm, spec, path, r)
+		return useFromFile(fm, spec, path, r, reload)
 	}
 
 	// Handle imports of pre-defined modules like `builtin` and `str`.
-	if ns, ok := fm.Evaler.modules[spec]; ok {
+	code, bundled := fm.Evaler.BundledModules[spec]
+	if ns, ok := fm.Evaler.modules[spec]; ok && !(reload && bundled) {
+		if reload {
+			return nil, fmt.Errorf("cannot reload builtin module %s", spec)
+		}
 		return ns, nil
 	}
-	if code, ok := fm.Evaler.BundledModules[spec]; ok {
+	if bundled {
 		return evalModule(fm, spec,
 			parse.Source{Name: "[bundled " + spec + "]", Code: code}, r)
 	}
@@ -342,7 +390,7 @@ func use(fm *Frame, spec string, r diag.Ranger) (*Ns, error) {
 	// TODO: For non-relative imports, use the spec (instead of the full path)
 	// as the module key instead to avoid searching every time.
 	for _, dir := range fm.Evaler.LibDirs {
-		ns, err := useFromFile(fm, spec, filepath.Join(dir, spec), r)
+		ns, err := useFromFile(fm, spec, filepath.Join(dir, spec), r, reload)
 		if _, isNoSuchModule := err.(NoSuchModule); isNoSuchModule {
 			continue
 		}
@@ -354,8 +402,8 @@ func use(fm *Frame, spec string, r diag.Ranger) (*Ns, error) {
 }
 
 // TODO: Make access to fm.Evaler.modules concurrency-safe.
-func useFromFile(fm *Frame, spec, path string, r diag.Ranger) (*Ns, error) {
-	if ns, ok := fm.Evaler.modules[path]; ok {
+func useFromFile(fm *Frame, spec, path string, r diag.Ranger, reload bool) (*Ns, error) {
+	if ns, ok := fm.Evaler.modules[path]; ok && !reload {
 		return ns, nil
 	}
 	_, err := os.Stat(path + ".so")
@@ -363,7 +411,7 @@ func useFromFile(fm *Frame, spec, path string, r diag.Ranger) (*Ns, error) {
 		code, err := readFileUTF8(path + ".elv")
 		if err != nil {
 			if os.IsNotExist(err) {
-				return nil, NoSuchModule{spec}
+				return useFromExtModule(fm, spec, path)
 			}
 			return nil, err
 		}
@@ -371,6 +419,12 @@ func useFromFile(fm *Frame, spec, path string, r diag.Ranger) (*Ns, error) {
 		return evalModule(fm, path, src, r)
 	}
 
+	if err := fm.Evaler.CheckRestricted("loading plugin " + path + ".so"); err != nil {
+		return nil, err
+	}
+	if reload {
+		return nil, fmt.Errorf("cannot reload plugin %s.so", path)
+	}
 	plug, err := pluginOpen(path + ".so")
 	if err != nil {
 		return nil, NoSuchModule{spec}
@@ -387,6 +441,21 @@ func useFromFile(fm *Frame, spec, path string, r diag.Ranger) (*Ns, error) {
 	return *ns, nil
 }
 
+func useFromExtModule(fm *Frame, spec, path string) (*Ns, error) {
+	if _, err := os.Stat(path + extModuleSuffix); err != nil {
+		return nil, NoSuchModule{spec}
+	}
+	if err := fm.Evaler.CheckRestricted("loading external module " + path + extModuleSuffix); err != nil {
+		return nil, err
+	}
+	ns, err := loadExtModule(fm, path+extModuleSuffix)
+	if err != nil {
+		return nil, err
+	}
+	fm.Evaler.modules[path] = ns
+	return ns, nil
+}
+
 func readFileUTF8(fname string) (string, error) {
 	bytes, err := os.ReadFile(fname)
 	if err != nil {
@@ -400,7 +469,7 @@ func readFileUTF8(fname string) (string, error) {
 
 // TODO: Make access to fm.Evaler.modules concurrency-safe.
 func evalModule(fm *Frame, key string, src parse.Source, r diag.Ranger) (*Ns, error) {
-	ns, exec, err := fm.PrepareEval(src, r, new(Ns))
+	ns, exec, exports, err := fm.prepareEval(src, r, new(Ns))
 	if err != nil {
 		return nil, err
 	}
@@ -413,6 +482,10 @@ func evalModule(fm *Frame, key string, src parse.Source, r diag.Ranger) (*Ns, er
 		delete(fm.Evaler.modules, key)
 		return nil, err
 	}
+	if exports != nil {
+		ns = ns.only(exports)
+		fm.Evaler.modules[key] = ns
+	}
 	return ns, nil
 }
 
@@ -488,6 +561,89 @@ func (op *coalesceOp) exec(fm *Frame) Exception {
 	return fm.errorp(op, out.Put(nil))
 }
 
+// Compiles the body of a control structure. Unlike a lambda, a block is
+// compiled into the current scope, so executing it doesn't need to create a
+// closure and a new local namespace. Variables declared in the block are only
+// visible within it, and are created afresh every time the block is executed,
+// so closures created in different executions don't share them.
+func (cp *compiler) block(n *parse.Primary) *blockOp {
+	if n == nil {
+		return nil
+	}
+	sc := cp.thisScope()
+	start := len(sc.infos)
+	// Variables declared in the block can shadow variables outside it; save
+	// which variables are visible so that they can be restored afterwards.
+	deleted := make([]bool, start)
+	for i, info := range sc.infos {
+		deleted[i] = info.deleted
+	}
+	outerBlockStart := sc.blockStart
+	sc.blockStart = start
+	// Pragmas are also scoped to the block.
+	currentPragmaCopy := *cp.currentPragma()
+	cp.pragmas = append(cp.pragmas, &currentPragmaCopy)
+
+	chunkOp := cp.chunkOp(n.Chunk)
+
+	cp.pragmas[len(cp.pragmas)-1] = nil
+	cp.pragmas = cp.pragmas[:len(cp.pragmas)-1]
+	sc.blockStart = outerBlockStart
+	end := len(sc.infos)
+	for i := range deleted {
+		sc.infos[i].deleted = deleted[i]
+	}
+	for i := start; i < end; i++ {
+		sc.infos[i].deleted = true
+	}
+	return &blockOp{n.Range(), chunkOp, start, end}
+}
+
+func (cp *compiler) blocks(ns []*parse.Primary) []*blockOp {
+	ops := make([]*blockOp, len(ns))
+	for i, n := range ns {
+		ops[i] = cp.block(n)
+	}
+	return ops
+}
+
+type blockOp struct {
+	diag.Ranging
+	chunkOp effectOp
+	// Variables declared in the block occupy the local slots in
+	// [varStart, varEnd).
+	varStart, varEnd int
+}
+
+// Executes the block. The Frame must be dedicated to the special form the
+// block belongs to, since its local namespace and defers are replaced.
+func (op *blockOp) exec(fm *Frame) Exception {
+	if op.varStart < op.varEnd {
+		// Closures and background jobs from earlier executions may still be
+		// using the slots, so they are not mutated in place.
+		slots := slices.Clone(fm.local.slots)
+		for i := op.varStart; i < op.varEnd; i++ {
+			slots[i] = MakeVarFromName(fm.local.infos[i].name)
+		}
+		fm.local = &Ns{slots, fm.local.infos}
+	}
+	// Like a closure, a block runs the functions deferred within it when it
+	// finishes.
+	outerDefers := fm.defers
+	fm.defers = new([]func(*Frame) Exception)
+	exc := op.chunkOp.exec(fm)
+	if len(*fm.defers) > 0 {
+		// Calling the deferred functions can modify the Frame, which is still
+		// needed after the block finishes.
+		excDefer := fm.Fork("block defers").runDefers()
+		if excDefer != nil && exc == nil {
+			exc = excDefer
+		}
+	}
+	fm.defers = outerDefers
+	return exc
+}
+
 func compileIf(cp *compiler, fn *parse.Form) effectOp {
 	args := getArgs(cp, fn)
 	var condNodes []*parse.Compound
@@ -510,11 +666,8 @@ func compileIf(cp *compiler, fn *parse.Form) effectOp {
 	}
 
 	condOps := cp.compoundOps(condNodes)
-	bodyOps := cp.primaryOps(bodyNodes)
-	var elseOp valuesOp
-	if elseBody != nil {
-		elseOp = cp.primaryOp(elseBody)
-	}
+	bodyOps := cp.blocks(bodyNodes)
+	elseOp := cp.block(elseBody)
 
 	return &ifOp{fn.Range(), condOps, bodyOps, elseOp}
 }
@@ -522,27 +675,22 @@ func compileIf(cp *compiler, fn *parse.Form) effectOp {
 type ifOp struct {
 	diag.Ranging
 	condOps []valuesOp
-	bodyOps []valuesOp
-	elseOp  valuesOp
+	bodyOps []*blockOp
+	elseOp  *blockOp
 }
 
 func (op *ifOp) exec(fm *Frame) Exception {
-	bodies := make([]Callable, len(op.bodyOps))
-	for i, bodyOp := range op.bodyOps {
-		bodies[i] = execLambdaOp(fm, bodyOp)
-	}
-	elseFn := execLambdaOp(fm, op.elseOp)
 	for i, condOp := range op.condOps {
 		condValues, exc := condOp.exec(fm.Fork("if cond"))
 		if exc != nil {
 			return exc
 		}
 		if allTrue(condValues) {
-			return fm.errorp(op, bodies[i].Call(fm.Fork("if body"), NoArgs, NoOpts))
+			return op.bodyOps[i].exec(fm)
 		}
 	}
 	if op.elseOp != nil {
-		return fm.errorp(op, elseFn.Call(fm.Fork("if else"), NoArgs, NoOpts))
+		return op.elseOp.exec(fm)
 	}
 	return nil
 }
@@ -557,24 +705,19 @@ func compileWhile(cp *compiler, fn *parse.Form) effectOp {
 	}
 
 	condOp := cp.compoundOp(condNode)
-	bodyOp := cp.primaryOp(bodyNode)
-	var elseOp valuesOp
-	if elseNode != nil {
-		elseOp = cp.primaryOp(elseNode)
-	}
+	bodyOp := cp.block(bodyNode)
+	elseOp := cp.block(elseNode)
 
 	return &whileOp{fn.Range(), condOp, bodyOp, elseOp}
 }
 
 type whileOp struct {
 	diag.Ranging
-	condOp, bodyOp, elseOp valuesOp
+	condOp         valuesOp
+	bodyOp, elseOp *blockOp
 }
 
 func (op *whileOp) exec(fm *Frame) Exception {
-	body := execLambdaOp(fm, op.bodyOp)
-	elseBody := execLambdaOp(fm, op.elseOp)
-
 	iterated := false
 	for {
 		condValues, exc := op.condOp.exec(fm.Fork("while cond"))
@@ -585,9 +728,8 @@ func (op *whileOp) exec(fm *Frame) Exception {
 			break
 		}
 		iterated = true
-		err := body.Call(fm.Fork("while"), NoArgs, NoOpts)
-		if err != nil {
-			exc := err.(Exception)
+		exc = op.bodyOp.exec(fm)
+		if exc != nil {
 			if exc.Reason() == Continue {
 				// Do nothing
 			} else if exc.Reason() == Break {
@@ -599,7 +741,7 @@ func (op *whileOp) exec(fm *Frame) Exception {
 	}
 
 	if op.elseOp != nil && !iterated {
-		return fm.errorp(op, elseBody.Call(fm.Fork("while else"), NoArgs, NoOpts))
+		return op.elseOp.exec(fm)
 	}
 	return nil
 }
@@ -617,11 +759,8 @@ func compileFor(cp *compiler, fn *parse.Form) effectOp {
 	lvalue := cp.compileOneLValue(varNode, setLValue|newLValue)
 
 	iterOp := cp.compoundOp(iterNode)
-	bodyOp := cp.primaryOp(bodyNode)
-	var elseOp valuesOp
-	if elseNode != nil {
-		elseOp = cp.primaryOp(elseNode)
-	}
+	bodyOp := cp.block(bodyNode)
+	elseOp := cp.block(elseNode)
 
 	return &forOp{fn.Range(), lvalue, iterOp, bodyOp, elseOp}
 }
@@ -630,8 +769,8 @@ type forOp struct {
 	diag.Ranging
 	lvalue lvalue
 	iterOp valuesOp
-	bodyOp valuesOp
-	elseOp valuesOp
+	bodyOp *blockOp
+	elseOp *blockOp
 }
 
 func (op *forOp) exec(fm *Frame) Exception {
@@ -644,9 +783,6 @@ func (op *forOp) exec(fm *Frame) Exception {
 		return fm.errorp(op, err)
 	}
 
-	body := execLambdaOp(fm, op.bodyOp)
-	elseBody := execLambdaOp(fm, op.elseOp)
-
 	iterated := false
 	var errElement error
 	errIterate := vals.Iterate(iterable, func(v any) bool {
@@ -656,15 +792,14 @@ func (op *forOp) exec(fm *Frame) Exception {
 			errElement = err
 			return false
 		}
-		err = body.Call(fm.Fork("for"), NoArgs, NoOpts)
-		if err != nil {
-			exc := err.(Exception)
+		exc := op.bodyOp.exec(fm)
+		if exc != nil {
 			if exc.Reason() == Continue {
 				// do nothing
 			} else if exc.Reason() == Break {
 				return false
 			} else {
-				errElement = err
+				errElement = exc
 				return false
 			}
 		}
@@ -677,8 +812,8 @@ func (op *forOp) exec(fm *Frame) Exception {
 		return fm.errorp(op, errElement)
 	}
 
-	if !iterated && elseBody != nil {
-		return fm.errorp(op, elseBody.Call(fm.Fork("for else"), NoArgs, NoOpts))
+	if !iterated && op.elseOp != nil {
+		return op.elseOp.exec(fm)
 	}
 	return nil
 }
@@ -764,7 +899,9 @@ func (op *tryOp) exec(fm *Frame) Exception {
 					return fm.errorp(op.catchVar, err)
 				}
 			}
-			err = catch.Call(fm.Fork("try catch"), NoArgs, NoOpts)
+			catchFm := fm.Fork("try catch")
+			catchFm.caught = err.(Exception)
+			err = catch.Call(catchFm, NoArgs, NoOpts)
 		}
 	} else {
 		if elseFn != nil {
@@ -782,7 +919,354 @@ func (op *tryOp) exec(fm *Frame) Exception {
 	return fm.errorp(op, err)
 }
 
-// PragmaForm = 'pragma' 'fallback-resolver' '=' { Compound }
+// MatchForm = 'match' Compound '{' { Pattern Lambda } '}'
+func compileMatch(cp *compiler, fn *parse.Form) effectOp {
+	args := getArgs(cp, fn)
+	valueNode := args.get(0, "value").any()
+	armsNode := args.get(1, "match arms").thunk()
+	if !args.finish() {
+		return nil
+	}
+
+	valueOp := cp.compoundOp(valueNode)
+	var arms []matchArm
+	for _, pn := range armsNode.Chunk.Pipelines {
+		if len(pn.Forms) != 1 || pn.Background {
+			cp.errorpf(pn, "match arm must be a pattern followed by a body")
+			continue
+		}
+		form := pn.Forms[0]
+		if form.Head == nil || len(form.Assignments) > 0 || len(form.Opts) > 0 ||
+			len(form.Redirs) > 0 || len(form.Args) != 1 {
+			cp.errorpf(form, "match arm must be a pattern followed by a body")
+			continue
+		}
+		var vars patternVars
+		pattern := cp.pattern(form.Head, &vars)
+		body := getArgs(cp, form).get(0, "match arm body").thunk()
+		if body == nil {
+			continue
+		}
+		arms = append(arms, matchArm{pattern, cp.matchArmBody(body, vars)})
+	}
+	return &matchOp{fn.Range(), valueOp, arms}
+}
+
+type matchOp struct {
+	diag.Ranging
+	valueOp valuesOp
+	arms    []matchArm
+}
+
+type matchArm struct {
+	pattern pattern
+	bodyOp  *lambdaOp
+}
+
+// Compiles the body of a match arm as a lambda whose arguments are the
+// variables bound by the pattern, so that they are only visible in the body.
+func (cp *compiler) matchArmBody(n *parse.Primary, vars patternVars) *lambdaOp {
+	local, capture := cp.pushScope()
+	for _, name := range vars {
+		local.add(name)
+	}
+	chunkOp := cp.chunkOp(n.Chunk)
+	localInfos := append([]staticVarInfo(nil), local.infos...)
+	cp.popScope()
+	return &lambdaOp{n.Range(), vars, -1, nil, nil, localInfos, capture, chunkOp, cp.srcMeta}
+}
+
+func (op *matchOp) exec(fm *Frame) Exception {
+	value, err := evalForValue(fm, op.valueOp, "value to match")
+	if err != nil {
+		return fm.errorp(op, err)
+	}
+	for _, arm := range op.arms {
+		bindings, ok, exc := arm.pattern.match(fm, value, nil)
+		if exc != nil {
+			return exc
+		}
+		if !ok {
+			continue
+		}
+		// The variables are only assigned once the whole pattern has matched,
+		// as the arguments of the body.
+		args := make([]any, len(arm.bodyOp.argNames))
+		for _, b := range bindings {
+			args[b.index] = b.value
+		}
+		body := execLambdaOp(fm, arm.bodyOp)
+		return fm.errorp(op, body.Call(fm.Fork("match arm"), args, NoOpts))
+	}
+	return nil
+}
+
+// A pattern in a match arm.
+type pattern interface {
+	// Matches v against the pattern, appending the values to assign to the
+	// variables bound by the pattern to bindings.
+	match(fm *Frame, v any, bindings []binding) ([]binding, bool, Exception)
+}
+
+type binding struct {
+	// Index of the variable in the patternVars of the arm.
+	index int
+	value any
+}
+
+// Names of the variables bound by the pattern of a match arm.
+type patternVars []string
+
+// Adds a variable, and returns its index. A name that appears more than once
+// refers to the same variable.
+func (pv *patternVars) add(name string) int {
+	for i, existing := range *pv {
+		if existing == name {
+			return i
+		}
+	}
+	*pv = append(*pv, name)
+	return len(*pv) - 1
+}
+
+// Compiles a pattern:
+//
+//   - _ matches anything.
+//   - =name matches anything and binds it to a new variable $name.
+//   - :kind matches anything whose kind (as output by kind-of) is kind, and
+//     =name:kind also binds it to $name.
+//   - A list whose elements are patterns matches a list whose elements match
+//     them. The list pattern may contain one @name (or @_) element, which
+//     matches any number of elements and binds them as a list.
+//   - Anything else is evaluated when matching and matches values equal to it.
+//
+// Only barewords are treated as the special patterns above, so quoted strings
+// are always matched literally. The variables bound by the pattern are added to
+// vars.
+func (cp *compiler) pattern(n *parse.Compound, vars *patternVars) pattern {
+	primary, ok := cmpd.Primary(n)
+	if !ok {
+		return literalPattern{cp.compoundOp(n)}
+	}
+	switch primary.Type {
+	case parse.Bareword:
+		s := primary.Value
+		switch {
+		case s == "_":
+			return bindPattern{index: -1}
+		case strings.HasPrefix(s, "="), strings.HasPrefix(s, ":"):
+			name, kind, _ := strings.Cut(s, ":")
+			name = strings.TrimPrefix(name, "=")
+			if strings.HasPrefix(s, "=") && !validPatternVarName(name) {
+				cp.errorpf(n, "invalid variable name in pattern: %s", parse.Quote(name))
+				return bindPattern{index: -1}
+			}
+			index := -1
+			if name != "" {
+				index = vars.add(name)
+			}
+			return bindPattern{index, kind}
+		case strings.HasPrefix(s, "@"):
+			cp.errorpf(n, "rest pattern %s can only appear in a list pattern", s)
+			return bindPattern{index: -1}
+		}
+	case parse.List:
+		lp := listPattern{rest: -1, restIndex: -1}
+		for _, elem := range primary.Elements {
+			if s, ok := cmpd.StringLiteral(elem); ok && isBareword(elem) && strings.HasPrefix(s, "@") {
+				if lp.rest != -1 {
+					cp.errorpf(elem, "at most one rest pattern is allowed")
+					continue
+				}
+				name := s[1:]
+				if name != "_" && !validPatternVarName(name) {
+					cp.errorpf(elem, "invalid variable name in pattern: %s", parse.Quote(name))
+					continue
+				}
+				lp.rest = len(lp.elems)
+				if name != "_" {
+					lp.restIndex = vars.add(name)
+				}
+				continue
+			}
+			lp.elems = append(lp.elems, cp.pattern(elem, vars))
+		}
+		return lp
+	}
+	return literalPattern{cp.compoundOp(n)}
+}
+
+func isBareword(n *parse.Compound) bool {
+	primary, ok := cmpd.Primary(n)
+	return ok && primary.Type == parse.Bareword
+}
+
+func validPatternVarName(name string) bool {
+	return name != "" && name != "_" && !strings.ContainsAny(name, ":@$")
+}
+
+// Matches values of a kind, or any value if kind is empty, and binds them to
+// the variable at index if it is not -1.
+type bindPattern struct {
+	index int
+	kind  string
+}
+
+func (p bindPattern) match(fm *Frame, v any, bindings []binding) ([]binding, bool, Exception) {
+	if p.kind != "" && vals.Kind(v) != p.kind {
+		return bindings, false, nil
+	}
+	if p.index != -1 {
+		bindings = append(bindings, binding{p.index, v})
+	}
+	return bindings, true, nil
+}
+
+// Matches values equal to the value of an expression.
+type literalPattern struct{ op valuesOp }
+
+func (p literalPattern) match(fm *Frame, v any, bindings []binding) ([]binding, bool, Exception) {
+	want, err := evalForValue(fm, p.op, "pattern")
+	if err != nil {
+		return bindings, false, fm.errorp(p.op, err)
+	}
+	return bindings, vals.Equal(v, want), nil
+}
+
+// Matches lists element by element, with an optional rest element at index
+// rest, which binds the variable at restIndex if it is not -1.
+type listPattern struct {
+	elems     []pattern
+	rest      int
+	restIndex int
+}
+
+func (p listPattern) match(fm *Frame, v any, bindings []binding) ([]binding, bool, Exception) {
+	list, ok := v.(vals.List)
+	if !ok {
+		return bindings, false, nil
+	}
+	n := list.Len()
+	if (p.rest == -1 && n != len(p.elems)) || (p.rest != -1 && n < len(p.elems)) {
+		return bindings, false, nil
+	}
+	// Number of elements matched by the rest element.
+	nRest := n - len(p.elems)
+	for i, elemPattern := range p.elems {
+		j := i
+		if p.rest != -1 && i >= p.rest {
+			j += nRest
+		}
+		elem, _ := list.Index(j)
+		var ok bool
+		var exc Exception
+		bindings, ok, exc = elemPattern.match(fm, elem, bindings)
+		if exc != nil || !ok {
+			return bindings, false, exc
+		}
+	}
+	if p.restIndex != -1 {
+		restElems := make([]any, nRest)
+		for i := range restElems {
+			restElems[i], _ = list.Index(p.rest + i)
+		}
+		bindings = append(bindings, binding{p.restIndex, vals.MakeList(restElems...)})
+	}
+	return bindings, true, nil
+}
+
+// TeeForm = 'tee' [ '&append' [ '=' Compound ] ] [ '-a' ] { Compound }
+func compileTee(cp *compiler, fn *parse.Form) effectOp {
+	op := &teeOp{Ranging: fn.Range()}
+	for _, opt := range fn.Opts {
+		name := stringLiteralOrError(cp, opt.Key, "option name")
+		if name != "append" {
+			cp.errorpf(opt.Key, "unknown option %s", parse.Quote(name))
+			continue
+		}
+		if opt.Value == nil {
+			op.appendOp = literalValues(opt, true)
+		} else {
+			op.appendOp = cp.compoundOp(opt.Value)
+		}
+	}
+	args := fn.Args
+	// Accept -a like tee(1).
+	if len(args) > 0 {
+		if s, ok := cmpd.StringLiteral(args[0]); ok && s == "-a" {
+			op.appendOp = literalValues(args[0], true)
+			args = args[1:]
+		}
+	}
+	op.targets = make([]teeTarget, len(args))
+	for i, cn := range args {
+		op.targets[i].valuesOp = cp.compoundOp(cn)
+		// A variable written on its own, like $x, is a variable target.
+		if len(cn.Indexings) == 1 && len(cn.Indexings[0].Indices) == 0 &&
+			cn.Indexings[0].Head.Type == parse.Variable {
+			head := cn.Indexings[0].Head
+			if sigil, qname := SplitSigil(head.Value); sigil == "" {
+				op.targets[i].ref = resolveVarRef(cp, qname, head)
+			}
+		}
+	}
+	return op
+}
+
+type teeOp struct {
+	diag.Ranging
+	appendOp valuesOp
+	targets  []teeTarget
+}
+
+type teeTarget struct {
+	valuesOp
+	// Set if the target is written as a variable on its own.
+	ref *varRef
+}
+
+func (op *teeOp) exec(fm *Frame) Exception {
+	appendFiles := false
+	if op.appendOp != nil {
+		v, exc := evalForValue(fm, op.appendOp, "value for &append")
+		if exc != nil {
+			return exc
+		}
+		b, ok := v.(bool)
+		if !ok {
+			return fm.errorp(op.appendOp, errs.BadValue{What: "value for &append",
+				Valid: "bool", Actual: vals.Kind(v)})
+		}
+		appendFiles = b
+	}
+	var targets []any
+	for _, target := range op.targets {
+		if target.ref != nil {
+			v := deref(fm, target.ref)
+			if v == nil {
+				r := target.Range()
+				return fm.errorp(target, NoSuchVariable(fm.srcMeta.Code[r.From:r.To]))
+			}
+			targets = append(targets, v)
+			continue
+		}
+		values, exc := target.exec(fm)
+		if exc != nil {
+			return exc
+		}
+		targets = append(targets, values...)
+	}
+	fm.traceback = fm.addTraceback(op)
+	err := tee(fm, appendFiles, targets)
+	if err == nil {
+		return nil
+	} else if exc, ok := err.(Exception); ok {
+		return exc
+	}
+	return &exception{err, fm.traceback}
+}
+
+// PragmaForm = 'pragma' Name '=' Compound
 func compilePragma(cp *compiler, fn *parse.Form) effectOp {
 	args := getArgs(cp, fn)
 	name := args.get(0, "pragma name").stringLiteral()
@@ -807,12 +1291,91 @@ func compilePragma(cp *compiler, fn *parse.Form) effectOp {
 			cp.errorpf(valueNode,
 				"invalid value for unknown-command: %s", parse.Quote(value))
 		}
+	case "output-capture":
+		value := stringLiteralOrError(cp, valueNode, "value for output-capture")
+		switch value {
+		case "values":
+			cp.currentPragma().spillOutputCapture = false
+		case "spill":
+			cp.currentPragma().spillOutputCapture = true
+		default:
+			cp.errorpf(valueNode,
+				"invalid value for output-capture: %s", parse.Quote(value))
+		}
+	case "min-version":
+		value, err := cmpd.StringLiteralOrError(valueNode, "value for min-version")
+		if err != nil {
+			cp.errorpf(valueNode, "%v", err)
+			break
+		}
+		want, ok := parseVersion(value)
+		if !ok {
+			cp.errorpf(valueNode,
+				"invalid value for min-version: %s", parse.Quote(value))
+		} else if have, _ := parseVersion(buildinfo.VersionBase); versionLess(have, want) {
+			cp.errorpf(valueNode, "requires Elvish %s or later, this is %s",
+				value, buildinfo.VersionBase)
+		}
+	case "export":
+		// Each function body and block has its own pragmas.
+		if len(cp.pragmas) > 1 {
+			cp.errorpf(fn, "pragma export can only be used at the top level")
+		}
+		compileExports(cp, valueNode)
 	default:
 		cp.errorpf(fn.Args[0], "unknown pragma %s", parse.Quote(name))
 	}
 	return nopOp{}
 }
 
+// Compiles the value of the export pragma, a list of variable names such as
+// [foo bar~]. Whether the variables exist is checked after compiling the whole
+// file.
+func compileExports(cp *compiler, n *parse.Compound) {
+	primary, ok := cmpd.Primary(n)
+	if !ok || primary.Type != parse.List {
+		cp.errorpf(n, "value for export must be a list of variable names")
+		return
+	}
+	if cp.exports == nil {
+		cp.exports = []*parse.Compound{}
+	}
+	for _, elem := range primary.Elements {
+		if _, err := cmpd.StringLiteralOrError(elem, "exported variable name"); err != nil {
+			cp.errorpf(elem, "%v", err)
+			continue
+		}
+		cp.exports = append(cp.exports, elem)
+	}
+}
+
+// Parses a version number consisting of up to three numbers separated by dots,
+// like 0.21.0.
+func parseVersion(s string) ([3]int, bool) {
+	var v [3]int
+	fields := strings.Split(s, ".")
+	if len(fields) > len(v) {
+		return v, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v[i] = n
+	}
+	return v, true
+}
+
+func versionLess(a, b [3]int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
 func (cp *compiler) compileOneLValue(n *parse.Compound, f lvalueFlag) lvalue {
 	if len(n.Indexings) != 1 {
 		cp.errorpf(n, "must be valid lvalue")
@@ -25,9 +25,14 @@ type Closure struct {
 	OptDefaults []any
 	SrcMeta     parse.Source
 	DefRange    diag.Ranging
-	op          effectOp
-	newLocal    []staticVarInfo
-	captured    *Ns
+	// Documentation of the function. For functions defined with fn, this is
+	// taken from the comment lines directly before the definition.
+	Doc string
+	op  effectOp
+	// Information about the local variables: the arguments, followed by the
+	// options and variables created in the body. Shared by all calls.
+	localInfos []staticVarInfo
+	captured   *Ns
 }
 
 var (
@@ -95,12 +100,8 @@ func (c *Closure) Call(fm *Frame, args []any, opts map[string]any) error {
 	fm.up = c.captured
 
 	// Populate local scope with arguments, options, and newly created locals.
-	localSize := len(c.ArgNames) + len(c.OptNames) + len(c.newLocal)
-	local := &Ns{make([]vars.Var, localSize), make([]staticVarInfo, localSize)}
+	local := &Ns{make([]vars.Var, len(c.localInfos)), c.localInfos}
 
-	for i, name := range c.ArgNames {
-		local.infos[i] = staticVarInfo{name, false, false}
-	}
 	if c.RestArg == -1 {
 		for i := range c.ArgNames {
 			local.slots[i] = vars.FromInit(args[i])
@@ -123,13 +124,11 @@ func (c *Closure) Call(fm *Frame, args []any, opts map[string]any) error {
 		if !ok {
 			v = c.OptDefaults[i]
 		}
-		local.infos[offset+i] = staticVarInfo{name, false, false}
 		local.slots[offset+i] = vars.FromInit(v)
 	}
 
 	offset += len(c.OptNames)
-	for i, info := range c.newLocal {
-		local.infos[offset+i] = info
+	for i, info := range c.localInfos[offset:] {
 		// TODO: Take info.readOnly into account too when creating variable
 		local.slots[offset+i] = MakeVarFromName(info.name)
 	}
@@ -184,6 +183,7 @@ func (cf closureFields) ArgNames() vals.List { return vals.MakeListSlice(cf.c.Ar
 func (cf closureFields) RestArg() string     { return strconv.Itoa(cf.c.RestArg) }
 func (cf closureFields) OptNames() vals.List { return vals.MakeListSlice(cf.c.OptNames) }
 func (cf closureFields) Src() parse.Source   { return cf.c.SrcMeta }
+func (cf closureFields) Doc() string         { return cf.c.Doc }
 
 func (cf closureFields) OptDefaults() vals.List {
 	return vals.MakeList(cf.c.OptDefaults...)
@@ -6,6 +6,7 @@ import (
 	"os"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"src.elv.sh/pkg/diag"
 	"src.elv.sh/pkg/eval/errs"
@@ -44,6 +45,29 @@ func (op chunkOp) exec(fm *Frame) Exception {
 	return nil
 }
 
+// Like exec, but calls the hooks before and after each pipeline. Either hook
+// may be nil.
+func (op chunkOp) execWithHooks(fm *Frame, before func(string, time.Time), after func(string, time.Time, float64, error)) Exception {
+	for _, subop := range op.subops {
+		code := subop.(*pipelineOp).source
+		start := timeNow()
+		if before != nil {
+			before(code, start)
+		}
+		exc := subop.exec(fm)
+		if after != nil {
+			after(code, start, timeNow().Sub(start).Seconds(), exc)
+		}
+		if exc != nil {
+			return exc
+		}
+	}
+	if fm.Canceled() {
+		return fm.errorp(op, ErrInterrupted)
+	}
+	return nil
+}
+
 func (cp *compiler) pipelineOp(n *parse.Pipeline) effectOp {
 	formOps := cp.formOps(n.Forms)
 
@@ -71,12 +95,26 @@ func (op *pipelineOp) exec(fm *Frame) Exception {
 	if fm.Canceled() {
 		return fm.errorp(op, ErrInterrupted)
 	}
+	if cov := fm.Evaler.Coverage; cov != nil {
+		cov.record(fm.srcMeta, op.From)
+	}
 
+	var start time.Time
 	if op.bg {
+		start = timeNow()
 		fm = fm.Fork("background job" + op.source)
 		fm.ctx = context.Background()
 		fm.background = true
-		fm.Evaler.addNumBgJobs(1)
+		fm.job = nil
+		fm.bgJob = fm.Evaler.addBgJob(op.source)
+	}
+
+	// Start a new job if this is a foreground pipeline not already part of
+	// one.
+	var newJob *job
+	if !op.bg && fm.jobControl && fm.job == nil {
+		newJob = &job{}
+		defer newJob.done()
 	}
 
 	nforms := len(op.subops)
@@ -90,6 +128,9 @@ func (op *pipelineOp) exec(fm *Frame) Exception {
 	// For each form, create a dedicated evalCtx and run asynchronously
 	for i, formOp := range op.subops {
 		newFm := fm.Fork("[form op]")
+		if newJob != nil {
+			newFm.job = newJob
+		}
 		inputIsPipe := i > 0
 		outputIsPipe := i < nforms-1
 		if inputIsPipe {
@@ -101,21 +142,37 @@ func (op *pipelineOp) exec(fm *Frame) Exception {
 			// os.Pipe sets O_CLOEXEC, which is what we want.
 			reader, writer, e := os.Pipe()
 			if e != nil {
-				return fm.errorpf(op, "failed to create pipe: %s", e)
+				// Release the input of this form and wait for the forms
+				// already started, so that no port is left open.
+				if inputIsPipe {
+					newFm.ports[0].stopWriter()
+				}
+				newFm.Close()
+				wg.Add(i - nforms)
+				wg.Wait()
+				err := fm.errorpf(op, "failed to create pipe: %s", e)
+				if op.bg {
+					fm.Evaler.finishBgJob(fm.bgJob, err)
+				}
+				return err
 			}
 			ch := make(chan any, pipelineChanBufferSize)
 			sendStop := make(chan struct{})
 			sendError := new(error)
 			readerGone := new(atomic.Bool)
 			newFm.ports[1] = &Port{
-				File: writer, Chan: ch,
-				closeFile: true, closeChan: true,
+				File: writer, Chan: ch, closer: newCloser(writer, ch, nil),
 				sendStop: sendStop, sendError: sendError, readerGone: readerGone}
 			nextIn = &Port{
-				File: reader, Chan: ch,
-				closeFile: true, closeChan: false,
+				File: reader, Chan: ch, closer: newCloser(reader, nil, nil),
 				// Store in input port for ease of retrieval later
-				sendStop: sendStop, sendError: sendError, readerGone: readerGone}
+				sendStop: sendStop, sendError: sendError, readerGone: readerGone,
+				stopWriter: sync.OnceFunc(func() {
+					*sendError = errs.ReaderGone{}
+					close(sendStop)
+					readerGone.Store(true)
+					reader.Close()
+				})}
 		}
 		f := func(formOp effectOp, pexc *Exception) {
 			exc := formOp.exec(newFm)
@@ -123,10 +180,7 @@ func (op *pipelineOp) exec(fm *Frame) Exception {
 				*pexc = exc
 			}
 			if inputIsPipe {
-				input := newFm.ports[0]
-				*input.sendError = errs.ReaderGone{}
-				close(input.sendStop)
-				input.readerGone.Store(true)
+				newFm.ports[0].stopWriter()
 			}
 			newFm.Close()
 			wg.Done()
@@ -142,12 +196,16 @@ func (op *pipelineOp) exec(fm *Frame) Exception {
 		// Background job, wait for form termination asynchronously.
 		go func() {
 			wg.Wait()
-			fm.Evaler.addNumBgJobs(-1)
+			err := MakePipelineError(excs)
+			fm.Evaler.finishBgJob(fm.bgJob, err)
 			if notify := fm.Evaler.BgJobNotify; notify != nil {
-				msg := "job " + op.source + " finished"
-				err := MakePipelineError(excs)
-				if err != nil {
-					msg += ", errors = " + err.Error()
+				duration := timeNow().Sub(start)
+				var msg string
+				if err == nil {
+					msg = fmt.Sprintf("job %s finished in %v", op.source, duration)
+				} else {
+					msg = fmt.Sprintf("job %s failed in %v, errors = %v",
+						op.source, duration, err)
 				}
 				if fm.Evaler.getNotifyBgJobSuccess() || err != nil {
 					notify(msg)
@@ -242,7 +300,7 @@ type formOp struct {
 	diag.Ranging
 	tempLValues   []lvalue
 	tempAssignOps []effectOp
-	redirOps      []effectOp
+	redirOps      []*redirOp
 	body          formBody
 }
 
@@ -316,10 +374,20 @@ func (op *formOp) exec(fm *Frame) (errRet Exception) {
 
 	// Redirections.
 	for _, redirOp := range op.redirOps {
-		exc := redirOp.exec(fm)
+		finish, exc := redirOp.exec(fm)
 		if exc != nil {
 			return exc
 		}
+		if finish != nil {
+			defer func() {
+				// Only report an error reading values if the command itself
+				// didn't fail.
+				exc := finish()
+				if exc != nil && (errRet == nil || errRet.Reason() == nil) {
+					errRet = exc
+				}
+			}()
+		}
 	}
 
 	if op.body.specialOp != nil {
@@ -343,12 +411,20 @@ func (op *formOp) exec(fm *Frame) (errRet Exception) {
 	}
 
 	var args []any
+	// The number of arguments after evaluating each argument op, used for
+	// finding the op an argument comes from. Stored on the stack for typical
+	// commands.
+	var argEndsBuf [8]int
+	argEnds := argEndsBuf[:0]
 	for _, argOp := range cmd.argOps {
-		moreArgs, exc := argOp.exec(fm)
+		exc := iterateValues(fm, argOp, func(v any) Exception {
+			args = append(args, v)
+			return nil
+		})
 		if exc != nil {
 			return exc
 		}
-		args = append(args, moreArgs...)
+		argEnds = append(argEnds, len(args))
 	}
 
 	// TODO(xiaq): This conversion should be avoided.
@@ -371,6 +447,14 @@ func (op *formOp) exec(fm *Frame) (errRet Exception) {
 	if exc, ok := err.(Exception); ok {
 		return exc
 	}
+	if e, ok := err.(WrongArgType); ok && Callable(e.fn) == headFn {
+		// Point to the offending argument, in addition to the whole form.
+		for i, end := range argEnds {
+			if e.argNum < end {
+				return &exception{err, fm.addTraceback(cmd.argOps[i])}
+			}
+		}
+	}
 	return &exception{err, fm.traceback}
 }
 
@@ -419,7 +503,7 @@ func (cp *compiler) assignmentOps(ns []*parse.Assignment) []effectOp {
 const defaultFileRedirPerm = 0644
 
 // redir compiles a Redir into a op.
-func (cp *compiler) redirOp(n *parse.Redir) effectOp {
+func (cp *compiler) redirOp(n *parse.Redir) *redirOp {
 	var dstOp valuesOp
 	if n.Left != nil {
 		dstOp = cp.compoundOp(n.Left)
@@ -429,11 +513,14 @@ func (cp *compiler) redirOp(n *parse.Redir) effectOp {
 		// TODO: Record and get redirection sign position
 		cp.errorpf(n, "bad redirection sign")
 	}
-	return &redirOp{n.Range(), dstOp, cp.compoundOp(n.Right), n.RightIsFd, n.Mode, flag}
+	if n.IsValues && (n.RightIsFd || n.Mode == parse.ReadWrite) {
+		cp.errorpf(n, "value redirection must use <|, >| or >>| with a file name")
+	}
+	return &redirOp{n.Range(), dstOp, cp.compoundOp(n.Right), n.RightIsFd, n.IsValues, n.Mode, flag}
 }
 
-func (cp *compiler) redirOps(ns []*parse.Redir) []effectOp {
-	ops := make([]effectOp, len(ns))
+func (cp *compiler) redirOps(ns []*parse.Redir) []*redirOp {
+	ops := make([]*redirOp, len(ns))
 	for i, n := range ns {
 		ops[i] = cp.redirOp(n)
 	}
@@ -457,18 +544,22 @@ func makeFlag(m parse.RedirMode) int {
 
 type redirOp struct {
 	diag.Ranging
-	dstOp   valuesOp
-	srcOp   valuesOp
-	srcIsFd bool
-	mode    parse.RedirMode
-	flag    int
+	dstOp    valuesOp
+	srcOp    valuesOp
+	srcIsFd  bool
+	isValues bool
+	mode     parse.RedirMode
+	flag     int
 }
 
 type InvalidFD struct{ FD int }
 
 func (err InvalidFD) Error() string { return fmt.Sprintf("invalid fd: %d", err.FD) }
 
-func (op *redirOp) exec(fm *Frame) Exception {
+// Performs the redirection. For a redirection that reads values, it also
+// returns a function to call after the command has finished, which stops
+// reading and returns any error encountered while reading.
+func (op *redirOp) exec(fm *Frame) (func() Exception, Exception) {
 	var dst int
 	if op.dstOp == nil {
 		// No explicit FD destination specified; use default destinations
@@ -478,51 +569,74 @@ func (op *redirOp) exec(fm *Frame) Exception {
 		case parse.Write, parse.ReadWrite, parse.Append:
 			dst = 1
 		default:
-			return fm.errorpf(op, "bad RedirMode; parser bug")
+			return nil, fm.errorpf(op, "bad RedirMode; parser bug")
 		}
 	} else {
 		// An explicit FD destination specified, evaluate it.
 		var err error
 		dst, err = evalForFd(fm, op.dstOp, false, "redirection destination")
 		if err != nil {
-			return fm.errorp(op, err)
+			return nil, fm.errorp(op, err)
 		}
 	}
 
 	growPorts(&fm.ports, dst+1)
-	fm.ports[dst].close()
+	if op.isValues {
+		return op.execValues(fm, dst)
+	}
+	newPort, exc := op.newPort(fm, dst)
+	if exc != nil {
+		return nil, exc
+	}
+	// Only close the old port after the new one is ready, so that the old port
+	// stays intact and gets closed by the frame if the redirection fails.
+	if newPort != fm.ports[dst] {
+		fm.ports[dst].close()
+		fm.ports[dst] = newPort
+	}
+	return nil, nil
+}
 
+// Returns the port to use as the destination of the redirection.
+func (op *redirOp) newPort(fm *Frame, dst int) (*Port, Exception) {
 	if op.srcIsFd {
 		src, err := evalForFd(fm, op.srcOp, true, "redirection source")
 		if err != nil {
-			return fm.errorp(op, err)
+			return nil, fm.errorp(op, err)
 		}
 		switch {
 		case src == -1:
 			// close
-			fm.ports[dst] = &Port{
+			return &Port{
 				// Ensure that writing to value output throws an exception
-				sendStop: closedSendStop, sendError: &ErrPortDoesNotSupportValueOutput}
+				sendStop: closedSendStop, sendError: &ErrPortDoesNotSupportValueOutput}, nil
 		case src >= len(fm.ports) || fm.ports[src] == nil:
-			return fm.errorp(op, InvalidFD{FD: src})
+			return nil, fm.errorp(op, InvalidFD{FD: src})
+		case src == dst:
+			// Redirecting a port to itself is a no-op.
+			return fm.ports[dst], nil
 		default:
-			fm.ports[dst] = fm.ports[src].fork()
+			return fm.ports[src].fork(), nil
 		}
-		return nil
 	}
 	src, err := evalForValue(fm, op.srcOp, "redirection source")
 	if err != nil {
-		return fm.errorp(op, err)
+		return nil, fm.errorp(op, err)
 	}
 	switch src := src.(type) {
 	case string:
+		if op.mode != parse.Read {
+			if err := fm.Evaler.CheckRestricted("writing to file " + src); err != nil {
+				return nil, fm.errorp(op, err)
+			}
+		}
 		f, err := os.OpenFile(src, op.flag, defaultFileRedirPerm)
 		if err != nil {
-			return fm.errorpf(op, "failed to open file %s: %s", vals.ReprPlain(src), err)
+			return nil, fm.errorpf(op, "failed to open file %s: %s", vals.ReprPlain(src), err)
 		}
-		fm.ports[dst] = fileRedirPort(op.mode, f, true)
+		return fileRedirPort(op.mode, f, true), nil
 	case vals.File:
-		fm.ports[dst] = fileRedirPort(op.mode, src, false)
+		return fileRedirPort(op.mode, src, false), nil
 	case vals.Map, vals.StructMap:
 		var srcFile *os.File
 		switch op.mode {
@@ -530,7 +644,7 @@ func (op *redirOp) exec(fm *Frame) Exception {
 			v, err := vals.Index(src, "r")
 			f, ok := v.(*os.File)
 			if err != nil || !ok {
-				return fm.errorp(op.srcOp, errs.BadValue{
+				return nil, fm.errorp(op.srcOp, errs.BadValue{
 					What:   "map for input redirection",
 					Valid:  "map with file in the 'r' field",
 					Actual: vals.ReprPlain(src)})
@@ -540,37 +654,80 @@ func (op *redirOp) exec(fm *Frame) Exception {
 			v, err := vals.Index(src, "w")
 			f, ok := v.(*os.File)
 			if err != nil || !ok {
-				return fm.errorp(op.srcOp, errs.BadValue{
+				return nil, fm.errorp(op.srcOp, errs.BadValue{
 					What:   "map for output redirection",
 					Valid:  "map with file in the 'w' field",
 					Actual: vals.ReprPlain(src)})
 			}
 			srcFile = f
 		default:
-			return fm.errorpf(op, "can only use < or > with maps")
+			return nil, fm.errorpf(op, "can only use < or > with maps")
 		}
-		fm.ports[dst] = fileRedirPort(op.mode, srcFile, false)
+		return fileRedirPort(op.mode, srcFile, false), nil
 	default:
-		return fm.errorp(op.srcOp, errs.BadValue{
+		return nil, fm.errorp(op.srcOp, errs.BadValue{
 			What:  "redirection source",
 			Valid: "string, file or map", Actual: vals.Kind(src)})
 	}
-	return nil
+}
+
+// Redirects the value component of a port to or from a file, keeping the byte
+// component. Values are read lazily, as the command consumes them.
+func (op *redirOp) execValues(fm *Frame, dst int) (func() Exception, Exception) {
+	src, errEval := evalForValue(fm, op.srcOp, "redirection source")
+	if errEval != nil {
+		return nil, fm.errorp(op, errEval)
+	}
+	name, ok := src.(string)
+	if !ok {
+		return nil, fm.errorp(op.srcOp, errs.BadValue{
+			What:  "value redirection source",
+			Valid: "string", Actual: vals.Kind(src)})
+	}
+	if op.mode != parse.Read {
+		if err := fm.Evaler.CheckRestricted("writing to file " + name); err != nil {
+			return nil, fm.errorp(op, err)
+		}
+	}
+	f, err := os.OpenFile(name, op.flag, defaultFileRedirPerm)
+	if err != nil {
+		return nil, fm.errorpf(op, "failed to open file %s: %s", vals.ReprPlain(name), err)
+	}
+	format := valueFormatFor(name)
+	if op.mode != parse.Read {
+		fm.ports[dst] = valueWriterPort(fm.ports[dst], f, format)
+		return nil, nil
+	}
+	var finishReading func() error
+	fm.ports[dst], finishReading = valueReaderPort(fm.ports[dst], f, format)
+	// The traceback of fm changes when the command is called, so save it now.
+	traceback := fm.addTraceback(op)
+	return func() Exception {
+		if err := finishReading(); err != nil {
+			return &exception{fmt.Errorf("failed to read values from %s: %w",
+				vals.ReprPlain(name), err), traceback}
+		}
+		return nil
+	}, nil
 }
 
 // Creates a port that only have a file component, populating the
 // channel-related fields with suitable values depending on the redirection
 // mode.
 func fileRedirPort(mode parse.RedirMode, f *os.File, closeFile bool) *Port {
+	var closer func()
+	if closeFile {
+		closer = newCloser(f, nil, nil)
+	}
 	if mode == parse.Read {
 		return &Port{
-			File: f, closeFile: closeFile,
+			File: f, closer: closer,
 			// ClosedChan produces no values when reading.
 			Chan: ClosedChan,
 		}
 	}
 	return &Port{
-		File: f, closeFile: closeFile,
+		File: f, closer: closer,
 		// Throws errValueOutputIsClosed when writing.
 		Chan: nil, sendStop: closedSendStop, sendError: &ErrPortDoesNotSupportValueOutput,
 	}
@@ -3,6 +3,8 @@ package eval
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"src.elv.sh/pkg/diag"
@@ -20,6 +22,31 @@ type valuesOp interface {
 	exec(*Frame) ([]any, Exception)
 }
 
+// A valuesOp that can also produce its values one at a time, without building
+// a slice of all of them first. Implemented by ops that may produce a lot of
+// values, like sequences in braced lists.
+type valuesIterator interface {
+	valuesOp
+	iterate(fm *Frame, f func(any) Exception) Exception
+}
+
+// Calls f with each value produced by op, stopping at the first exception.
+func iterateValues(fm *Frame, op valuesOp, f func(any) Exception) Exception {
+	if it, ok := op.(valuesIterator); ok {
+		return it.iterate(fm, f)
+	}
+	values, exc := op.exec(fm)
+	if exc != nil {
+		return exc
+	}
+	for _, v := range values {
+		if exc := f(v); exc != nil {
+			return exc
+		}
+	}
+	return nil
+}
+
 var outputCaptureBufferSize = 16
 
 // Can be mutated for testing.
@@ -109,7 +136,7 @@ func (op compoundOp) exec(fm *Frame) ([]any, Exception) {
 		newvs := make([]any, 0, len(vs))
 		for _, v := range vs {
 			if gp, ok := v.(globPattern); ok {
-				results, err := doGlob(fm.Context(), gp)
+				results, err := doGlob(fm.Context(), gp, fm.Evaler.getGlobNoMatch())
 				if err != nil {
 					return nil, fm.errorp(op, err)
 				}
@@ -123,6 +150,37 @@ func (op compoundOp) exec(fm *Frame) ([]any, Exception) {
 	return vs, nil
 }
 
+func (op compoundOp) iterate(fm *Frame, f func(any) Exception) Exception {
+	if len(op.subops) > 1 || op.tilde {
+		values, exc := op.exec(fm)
+		if exc != nil {
+			return exc
+		}
+		for _, v := range values {
+			if exc := f(v); exc != nil {
+				return exc
+			}
+		}
+		return nil
+	}
+	return iterateValues(fm, op.subops[0], func(v any) Exception {
+		gp, ok := v.(globPattern)
+		if !ok {
+			return f(v)
+		}
+		results, err := doGlob(fm.Context(), gp, fm.Evaler.getGlobNoMatch())
+		if err != nil {
+			return fm.errorp(op, err)
+		}
+		for _, result := range results {
+			if exc := f(result); exc != nil {
+				return exc
+			}
+		}
+		return nil
+	})
+}
+
 func outerProduct(vs []any, us []any, f func(any, any) (any, error)) ([]any, error) {
 	ws := make([]any, len(vs)*len(us))
 	nu := len(us)
@@ -281,7 +339,8 @@ func (cp *compiler) primaryOp(n *parse.Primary) valuesOp {
 	case parse.ExceptionCapture:
 		return exceptionCaptureOp{n.Range(), cp.chunkOp(n.Chunk)}
 	case parse.OutputCapture:
-		return outputCaptureOp{n.Range(), cp.chunkOp(n.Chunk)}
+		return outputCaptureOp{n.Range(), cp.chunkOp(n.Chunk),
+			cp.currentPragma().spillOutputCapture}
 	case parse.List:
 		return listOp{n.Range(), cp.compoundOps(n.Elements)}
 	case parse.Lambda:
@@ -289,7 +348,7 @@ func (cp *compiler) primaryOp(n *parse.Primary) valuesOp {
 	case parse.Map:
 		return mapOp{n.Range(), cp.mapPairs(n.MapPairs)}
 	case parse.Braced:
-		return seqValuesOp{n.Range(), cp.compoundOps(n.Braced)}
+		return seqValuesOp{n.Range(), cp.bracedOps(n.Braced)}
 	default:
 		cp.errorpf(n, "bad PrimaryType; parser bug")
 		return literalValues(n, parse.SourceText(n))
@@ -332,13 +391,13 @@ type listOp struct {
 func (op listOp) exec(fm *Frame) ([]any, Exception) {
 	list := vals.EmptyList
 	for _, subop := range op.subops {
-		moreValues, exc := subop.exec(fm)
+		exc := iterateValues(fm, subop, func(v any) Exception {
+			list = list.Conj(v)
+			return nil
+		})
 		if exc != nil {
 			return nil, exc
 		}
-		for _, moreValue := range moreValues {
-			list = list.Conj(moreValue)
-		}
 	}
 	return []any{list}, nil
 }
@@ -359,9 +418,14 @@ func (op exceptionCaptureOp) exec(fm *Frame) ([]any, Exception) {
 type outputCaptureOp struct {
 	diag.Ranging
 	subop effectOp
+	// Set by the output-capture pragma.
+	spill bool
 }
 
 func (op outputCaptureOp) exec(fm *Frame) ([]any, Exception) {
+	if op.spill {
+		return op.execSpill(fm)
+	}
 	outPort, collect, err := ValueCapturePort()
 	if err != nil {
 		return nil, fm.errorp(op, err)
@@ -370,6 +434,55 @@ func (op outputCaptureOp) exec(fm *Frame) ([]any, Exception) {
 	return collect(), exc
 }
 
+// Captures the output into a spilledCapture, and reads all the values back.
+func (op outputCaptureOp) execSpill(fm *Frame) ([]any, Exception) {
+	var values []any
+	exc := op.iterate(fm, func(v any) Exception {
+		values = append(values, v)
+		return nil
+	})
+	return values, exc
+}
+
+// Like exec, but when the output-capture pragma is "spill", the values are
+// read back from the temporary file one at a time.
+func (op outputCaptureOp) iterate(fm *Frame, f func(any) Exception) Exception {
+	if !op.spill {
+		values, exc := op.exec(fm)
+		if exc != nil {
+			return exc
+		}
+		for _, v := range values {
+			if exc := f(v); exc != nil {
+				return exc
+			}
+		}
+		return nil
+	}
+	c := newSpilledCapture(fm.Evaler, spillThreshold)
+	outPort, done, err := spillCapturePort(c)
+	if err != nil {
+		return fm.errorp(op, err)
+	}
+	exc := op.subop.exec(fm.forkWithOutput("[output capture]", outPort))
+	done()
+	if err := c.finish(); err != nil {
+		return fm.errorp(op, err)
+	}
+	defer c.close()
+	if exc != nil {
+		return exc
+	}
+	err = c.iterate(func(v any) bool {
+		exc = f(v)
+		return exc == nil
+	})
+	if exc != nil {
+		return exc
+	}
+	return fm.errorp(op, err)
+}
+
 func (cp *compiler) lambda(n *parse.Primary) valuesOp {
 	// Parse signature.
 	var (
@@ -430,10 +543,17 @@ func (cp *compiler) lambda(n *parse.Primary) valuesOp {
 	}
 	scopeSizeInit := len(local.infos)
 	chunkOp := cp.chunkOp(n.Chunk)
-	newLocal := local.infos[scopeSizeInit:]
+	localInfos := make([]staticVarInfo, 0, len(local.infos))
+	for _, name := range argNames {
+		localInfos = append(localInfos, staticVarInfo{name, false, false})
+	}
+	for _, name := range optNames {
+		localInfos = append(localInfos, staticVarInfo{name, false, false})
+	}
+	localInfos = append(localInfos, local.infos[scopeSizeInit:]...)
 	cp.popScope()
 
-	return &lambdaOp{n.Range(), argNames, restArg, optNames, optDefaultOps, newLocal, capture, chunkOp, cp.srcMeta}
+	return &lambdaOp{n.Range(), argNames, restArg, optNames, optDefaultOps, localInfos, capture, chunkOp, cp.srcMeta}
 }
 
 type lambdaOp struct {
@@ -442,7 +562,7 @@ type lambdaOp struct {
 	restArg       int
 	optNames      []string
 	optDefaultOps []valuesOp
-	newLocal      []staticVarInfo
+	localInfos    []staticVarInfo
 	capture       *staticUpNs
 	subop         effectOp
 	srcMeta       parse.Source
@@ -469,7 +589,7 @@ func (op *lambdaOp) exec(fm *Frame) ([]any, Exception) {
 		}
 		optDefaults[i] = defaultValue
 	}
-	return []any{&Closure{op.argNames, op.restArg, op.optNames, optDefaults, op.srcMeta, op.Range(), op.subop, op.newLocal, capture}}, nil
+	return []any{&Closure{op.argNames, op.restArg, op.optNames, optDefaults, op.srcMeta, op.Range(), "", op.subop, op.localInfos, capture}}, nil
 }
 
 type mapOp struct {
@@ -568,6 +688,121 @@ func (op seqValuesOp) exec(fm *Frame) ([]any, Exception) {
 	return values, nil
 }
 
+func (op seqValuesOp) iterate(fm *Frame, f func(any) Exception) Exception {
+	for _, subop := range op.subops {
+		if exc := iterateValues(fm, subop, f); exc != nil {
+			return exc
+		}
+	}
+	return nil
+}
+
+// Like compoundOps, but compiles elements like 1..10 to sequenceOp.
+func (cp *compiler) bracedOps(ns []*parse.Compound) []valuesOp {
+	ops := make([]valuesOp, len(ns))
+	for i, n := range ns {
+		if op, ok := cp.sequenceOp(n); ok {
+			ops[i] = op
+		} else {
+			ops[i] = cp.compoundOp(n)
+		}
+	}
+	return ops
+}
+
+var sequencePattern = regexp.MustCompile(`^(-?\d+)\.\.(-?\d+)(?:\.\.(-?\d+))?$`)
+
+// Compiles a bareword like 1..10, 01..10 or 1..10..2 in a braced list to a
+// sequenceOp. Returns false if n is not such a bareword or the numbers are
+// too large.
+func (cp *compiler) sequenceOp(n *parse.Compound) (valuesOp, bool) {
+	if len(n.Indexings) != 1 || len(n.Indexings[0].Indices) > 0 ||
+		n.Indexings[0].Head.Type != parse.Bareword {
+		return nil, false
+	}
+	m := sequencePattern.FindStringSubmatch(n.Indexings[0].Head.Value)
+	if m == nil {
+		return nil, false
+	}
+	from, err1 := strconv.Atoi(m[1])
+	to, err2 := strconv.Atoi(m[2])
+	step, err3 := 1, error(nil)
+	if m[3] != "" {
+		step, err3 = strconv.Atoi(m[3])
+	}
+	if err1 != nil || err2 != nil || err3 != nil {
+		return nil, false
+	}
+	if step == 0 {
+		cp.errorpf(n, "step of sequence must not be 0")
+		return nopValuesOp{n.Range()}, true
+	}
+	if step < 0 {
+		step = -step
+	}
+	width := 0
+	if hasLeadingZero(m[1]) || hasLeadingZero(m[2]) {
+		width = max(len(m[1]), len(m[2]))
+	}
+	return sequenceOp{n.Range(), from, to, step, width}, true
+}
+
+func hasLeadingZero(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	return len(s) > 1 && s[0] == '0'
+}
+
+// Produces the integers from "from" to "to" inclusive, counting up or down by
+// step, as strings zero-padded to width.
+type sequenceOp struct {
+	diag.Ranging
+	from, to, step, width int
+}
+
+func (op sequenceOp) exec(fm *Frame) ([]any, Exception) {
+	var values []any
+	exc := op.iterate(fm, func(v any) Exception {
+		values = append(values, v)
+		return nil
+	})
+	return values, exc
+}
+
+// How many values a sequenceOp produces between checks for interrupts.
+const sequenceCheckInterval = 1024
+
+func (op sequenceOp) iterate(fm *Frame, f func(any) Exception) Exception {
+	n := 0
+	emit := func(i int) Exception {
+		n++
+		if n%sequenceCheckInterval == 0 && fm.Canceled() {
+			return fm.errorp(op, ErrInterrupted)
+		}
+		return f(fmt.Sprintf("%0*d", op.width, i))
+	}
+	if op.from <= op.to {
+		for i := op.from; i <= op.to; i += op.step {
+			if exc := emit(i); exc != nil {
+				return exc
+			}
+			if i > op.to-op.step {
+				// Avoid overflowing i.
+				break
+			}
+		}
+	} else {
+		for i := op.from; i >= op.to; i -= op.step {
+			if exc := emit(i); exc != nil {
+				return exc
+			}
+			if i < op.to+op.step {
+				break
+			}
+		}
+	}
+	return nil
+}
+
 type nopValuesOp struct{ diag.Ranging }
 
 func (nopValuesOp) exec(fm *Frame) ([]any, Exception) { return nil, nil }
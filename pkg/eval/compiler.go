@@ -8,6 +8,7 @@ import (
 	"src.elv.sh/pkg/diag"
 	"src.elv.sh/pkg/eval/vars"
 	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/parse/cmpd"
 	"src.elv.sh/pkg/prog"
 )
 
@@ -35,10 +36,13 @@ type compiler struct {
 	errors []*CompilationError
 	// Suggested code to fix potential issues found during compilation.
 	autofixes []string
+	// Names declared with the export pragma, nil if there is none.
+	exports []*parse.Compound
 }
 
 type scopePragma struct {
 	unknownCommandIsExternal bool
+	spillOutputCapture       bool
 }
 
 func compile(b, g *staticNs, modules []string, tree parse.Tree, w io.Writer) (nsOp, []string, error) {
@@ -47,14 +51,35 @@ func compile(b, g *staticNs, modules []string, tree parse.Tree, w io.Writer) (ns
 		b, []*staticNs{g}, []*staticUpNs{new(staticUpNs)},
 		[]*scopePragma{{unknownCommandIsExternal: true}},
 		modules,
-		w, newDeprecationRegistry(), tree.Source, nil, nil}
+		w, newDeprecationRegistry(), tree.Source, nil, nil, nil}
 	chunkOp := cp.chunkOp(tree.Root)
-	return nsOp{chunkOp, g}, cp.autofixes, diag.PackErrors(cp.errors)
+	exports := cp.checkExports()
+	return nsOp{chunkOp, g, exports}, cp.autofixes, diag.PackErrors(cp.errors)
+}
+
+// Checks that the variables declared with the export pragma exist at the end
+// of the file, and returns their names.
+func (cp *compiler) checkExports() []string {
+	if cp.exports == nil {
+		return nil
+	}
+	names := []string{}
+	for _, n := range cp.exports {
+		name, _ := cmpd.StringLiteral(n)
+		if _, i := cp.thisScope().lookup(name); i == -1 {
+			cp.errorpf(n, "exported variable $%s not found", name)
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
 }
 
 type nsOp struct {
 	inner    effectOp
 	template *staticNs
+	// Names declared with the export pragma, nil if there is none.
+	exports []string
 }
 
 // Prepares the local namespace, and returns the namespace and a function for
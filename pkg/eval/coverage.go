@@ -0,0 +1,120 @@
+package eval
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"src.elv.sh/pkg/parse"
+)
+
+// Coverage records which pipelines in source files have been executed. It is
+// safe for concurrent use, and can be shared by multiple Evalers.
+type Coverage struct {
+	mu sync.Mutex
+	// Code of the source files, indexed by name.
+	code map[string]string
+	// Start positions of the executed pipelines, indexed by name.
+	executed map[string]map[int]bool
+}
+
+// NewCoverage creates a new, empty Coverage.
+func NewCoverage() *Coverage {
+	return &Coverage{
+		code:     make(map[string]string),
+		executed: make(map[string]map[int]bool),
+	}
+}
+
+func (c *Coverage) record(src parse.Source, from int) {
+	if !src.IsFile {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.code[src.Name] != src.Code {
+		// Either a new file, or a file whose content has changed since it
+		// was last evaluated; in the latter case, the old records are
+		// meaningless.
+		c.code[src.Name] = src.Code
+		c.executed[src.Name] = make(map[int]bool)
+	}
+	c.executed[src.Name][from] = true
+}
+
+// LineCoverage is the coverage status of a line.
+type LineCoverage int
+
+// Possible values of LineCoverage.
+const (
+	// The line doesn't start any pipeline.
+	NotExecutable LineCoverage = iota
+	// All the pipelines starting on the line have been executed.
+	Covered
+	// Some of the pipelines starting on the line haven't been executed.
+	Uncovered
+)
+
+// FileCoverage is the coverage of a source file.
+type FileCoverage struct {
+	Name string
+	Code string
+	// Coverage of each line; the first element is for the first line.
+	Lines []LineCoverage
+}
+
+// Counts returns the number of covered lines, and the number of lines that
+// start at least one pipeline.
+func (fc FileCoverage) Counts() (covered, total int) {
+	for _, l := range fc.Lines {
+		switch l {
+		case Covered:
+			covered++
+			total++
+		case Uncovered:
+			total++
+		}
+	}
+	return covered, total
+}
+
+// Files returns the coverage of all the files from which at least one pipeline
+// has been executed, sorted by name. Files for which the predicate returns
+// false are skipped; a nil predicate includes all files.
+func (c *Coverage) Files(pred func(name string) bool) []FileCoverage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var files []FileCoverage
+	for name, code := range c.code {
+		if pred != nil && !pred(name) {
+			continue
+		}
+		files = append(files, FileCoverage{
+			name, code, lineCoverage(name, code, c.executed[name])})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files
+}
+
+func lineCoverage(name, code string, executed map[int]bool) []LineCoverage {
+	lines := make([]LineCoverage, strings.Count(code, "\n")+1)
+	// The file has been evaluated, so it must have parsed successfully.
+	tree, _ := parse.Parse(parse.Source{Name: name, Code: code}, parse.Config{})
+	var walk func(parse.Node)
+	walk = func(n parse.Node) {
+		if pn, ok := n.(*parse.Pipeline); ok {
+			from := pn.Range().From
+			i := strings.Count(code[:from], "\n")
+			if !executed[from] {
+				lines[i] = Uncovered
+			} else if lines[i] == NotExecutable {
+				lines[i] = Covered
+			}
+		}
+		for _, ch := range parse.Children(n) {
+			walk(ch)
+		}
+	}
+	walk(tree.Root)
+	return lines
+}
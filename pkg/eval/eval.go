@@ -6,11 +6,15 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"src.elv.sh/pkg/env"
+	"src.elv.sh/pkg/eval/errs"
 	"src.elv.sh/pkg/eval/vals"
 	"src.elv.sh/pkg/eval/vars"
 	"src.elv.sh/pkg/logutil"
@@ -33,6 +37,8 @@ const (
 const (
 	defaultValuePrefix        = "▶ "
 	defaultNotifyBgJobSuccess = true
+	defaultGlobNoMatch        = "error"
+	defaultAutoCd             = false
 )
 
 // Evaler provides methods for evaluating code, and maintains state that is
@@ -59,6 +65,21 @@ type Evaler struct {
 	// are not used by the Evaler itself right now; they are here so that they
 	// can be exposed to the runtime: module.
 	RcPath, EffectiveRcPath string
+	// Whether the Evaler is in restricted mode, in which running external
+	// commands, writing to the filesystem, mutating environment variables and
+	// accessing the network throw a SecurityError. This makes it possible to
+	// evaluate untrusted code, like completion hooks that come with a
+	// repository.
+	Restricted bool
+	// If not nil, all evaluations are interrupted when this Context is
+	// canceled, in addition to when the Context in their EvalCfg is canceled.
+	// This can be used to put a deadline on all the evaluations of the
+	// Evaler, or to cancel them all at once. Once it is canceled, all further
+	// evaluations fail with ErrInterrupted.
+	Context context.Context
+	// If not nil, the pipelines executed in source files are recorded in it.
+	// This is used by "elvish -test -cover" to report test coverage.
+	Coverage *Coverage
 
 	mu sync.RWMutex
 	// Mutations to fields below must be guarded by mutex.
@@ -84,8 +105,34 @@ type Evaler struct {
 	// Whether to notify the success of background jobs, exposed as
 	// $notify-bg-job-sucess.
 	notifyBgJobSuccess bool
-	// The current number of background jobs, exposed as $num-bg-jobs.
-	numBgJobs int
+	// Background jobs that are running or have finished but not been waited
+	// for, keyed by IDs allocated from nextBgJobID. The number of running ones
+	// is exposed as $num-bg-jobs.
+	bgJobs      map[int]*bgJob
+	nextBgJobID int
+	// Child processes started by spawn-detached that have not been waited for, keyed
+	// by pid.
+	detachedChildren map[int]*detachedChild
+	// Temporary files of spilled output captures that could not be removed
+	// while open.
+	spillFiles map[string]struct{}
+	// External modules that have been started, stopped by PreExit.
+	extModules []*extModule
+	// What to do when a wildcard pattern has no match, exposed as
+	// $glob-nomatch. One of the keys of globNoMatchFlags.
+	globNoMatch string
+	// Whether using a directory as a command changes to it, exposed as
+	// $auto-cd.
+	autoCd bool
+	// The working directory before the last successful Chdir, used by "cd -".
+	prevDir string
+	// The directory stack, maintained by pushd and popd. The top of the
+	// stack is the last element.
+	dirStack []string
+
+	// Functions to call in place of external commands, indexed by command
+	// names.
+	externalMocks map[string]Callable
 }
 
 // NewEvaler creates a new Evaler.
@@ -108,7 +155,11 @@ func NewEvaler() *Evaler {
 
 		valuePrefix:        defaultValuePrefix,
 		notifyBgJobSuccess: defaultNotifyBgJobSuccess,
-		numBgJobs:          0,
+		bgJobs:             map[int]*bgJob{},
+		detachedChildren:   map[int]*detachedChild{},
+		spillFiles:         map[string]struct{}{},
+		globNoMatch:        defaultGlobNoMatch,
+		autoCd:             defaultAutoCd,
 		Args:               vals.EmptyList,
 	}
 
@@ -124,6 +175,7 @@ func NewEvaler() *Evaler {
 
 	ev.ExtendBuiltin(BuildNs().
 		AddVar("pwd", NewPwdVar(ev)).
+		AddVar("paths", restrictedVar{vars.NewEnvListVar("PATH"), ev, "setting $paths"}).
 		AddVar("before-exit", beforeExitHookElvish).
 		AddVar("before-chdir", beforeChdirElvish).
 		AddVar("after-chdir", afterChdirElvish).
@@ -131,6 +183,9 @@ func NewEvaler() *Evaler {
 			vars.FromPtrWithMutex(&ev.valuePrefix, &ev.mu)).
 		AddVar("notify-bg-job-success",
 			vars.FromPtrWithMutex(&ev.notifyBgJobSuccess, &ev.mu)).
+		AddVar("auto-cd", vars.FromPtrWithMutex(&ev.autoCd, &ev.mu)).
+		AddVar("glob-nomatch",
+			vars.FromSetGet(ev.setGlobNoMatch, func() any { return ev.getGlobNoMatch() })).
 		AddVar("num-bg-jobs",
 			vars.FromGet(func() any { return strconv.Itoa(ev.getNumBgJobs()) })).
 		AddVar("args", vars.FromGet(func() any { return ev.Args })))
@@ -141,11 +196,13 @@ func NewEvaler() *Evaler {
 	return ev
 }
 
-// PreExit runs all pre-exit hooks.
+// PreExit runs all pre-exit hooks, and removes leftover temporary files.
 func (ev *Evaler) PreExit() {
 	for _, hook := range ev.PreExitHooks {
 		hook()
 	}
+	ev.removeSpillFiles()
+	ev.closeExtModules()
 }
 
 // Access methods.
@@ -191,6 +248,20 @@ func (ev *Evaler) ExtendBuiltin(ns Nser) {
 	ev.builtin = CombineNs(ev.builtin, ns.Ns())
 }
 
+// AddBuiltin adds a function to the builtin namespace. If impl is a Callable,
+// it is used as is; otherwise it must be a Go function, and is converted to an
+// Elvish function with the same rules as [NewGoFn], which converts arguments
+// and return values of basic Go types automatically.
+//
+// An existing builtin function with the same name is replaced.
+func (ev *Evaler) AddBuiltin(name string, impl any) {
+	fn, ok := impl.(Callable)
+	if !ok {
+		fn = NewGoFn(name, impl)
+	}
+	ev.ExtendBuiltin(BuildNs().AddFn(name, fn))
+}
+
 // ReplaceBuiltin replaces the builtin namespace. It should only be used in
 // tests.
 func (ev *Evaler) ReplaceBuiltin(ns *Ns) {
@@ -205,14 +276,24 @@ func (ev *Evaler) registerDeprecation(d deprecation) bool {
 	return ev.deprecations.register(d)
 }
 
-// AddModule add an internal module so that it can be used with "use $name" from
-// script.
+// AddModule adds an internal module so that it can be used with "use $name"
+// from script. An existing internal module with the same name is replaced.
+//
+// A module namespace is typically built with [BuildNsNamed].
 func (ev *Evaler) AddModule(name string, mod *Ns) {
 	ev.mu.Lock()
 	defer ev.mu.Unlock()
 	ev.modules[name] = mod
 }
 
+// Module returns the internal module with the given use spec, or nil if there
+// is no such module. External modules are not returned.
+func (ev *Evaler) Module(spec string) *Ns {
+	ev.mu.RLock()
+	defer ev.mu.RUnlock()
+	return ev.modules[spec]
+}
+
 // ValuePrefix returns the prefix to prepend to value outputs when writing them
 // to terminal.
 func (ev *Evaler) ValuePrefix() string {
@@ -227,16 +308,93 @@ func (ev *Evaler) getNotifyBgJobSuccess() bool {
 	return ev.notifyBgJobSuccess
 }
 
-func (ev *Evaler) getNumBgJobs() int {
+func (ev *Evaler) getGlobNoMatch() string {
+	ev.mu.RLock()
+	defer ev.mu.RUnlock()
+	return ev.globNoMatch
+}
+
+func (ev *Evaler) setGlobNoMatch(v any) error {
+	s, ok := v.(string)
+	if _, valid := globNoMatchFlags[s]; !ok || !valid {
+		return errs.BadValue{What: "value of $glob-nomatch",
+			Valid: "error, ok or literal", Actual: vals.ReprPlain(v)}
+	}
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	ev.globNoMatch = s
+	return nil
+}
+
+func (ev *Evaler) getAutoCd() bool {
+	ev.mu.RLock()
+	defer ev.mu.RUnlock()
+	return ev.autoCd
+}
+
+func (ev *Evaler) getPrevDir() string {
+	ev.mu.RLock()
+	defer ev.mu.RUnlock()
+	return ev.prevDir
+}
+
+// DirStack returns a copy of the directory stack maintained by pushd and popd,
+// with the top of the stack last.
+func (ev *Evaler) DirStack() []string {
 	ev.mu.RLock()
 	defer ev.mu.RUnlock()
-	return ev.numBgJobs
+	return append([]string(nil), ev.dirStack...)
 }
 
-func (ev *Evaler) addNumBgJobs(delta int) {
+// SetDirStack replaces the directory stack maintained by pushd and popd, with
+// the top of the stack last.
+func (ev *Evaler) SetDirStack(stack []string) {
 	ev.mu.Lock()
 	defer ev.mu.Unlock()
-	ev.numBgJobs += delta
+	ev.dirStack = append([]string(nil), stack...)
+}
+
+// MockExternal makes calls to the external command with the given name call f
+// instead. The function receives the arguments of the call, and uses the ports
+// of the call for its input and output.
+//
+// This is intended for testing Elvish code that runs external commands.
+func (ev *Evaler) MockExternal(name string, f Callable) {
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	if ev.externalMocks == nil {
+		ev.externalMocks = make(map[string]Callable)
+	}
+	ev.externalMocks[name] = f
+}
+
+// UnmockExternal removes the mock for the external command with the given
+// name, if any.
+func (ev *Evaler) UnmockExternal(name string) {
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	delete(ev.externalMocks, name)
+}
+
+// ExternalMocks returns a copy of the mocks of external commands, keyed by the
+// names of the commands.
+func (ev *Evaler) ExternalMocks() map[string]Callable {
+	ev.mu.RLock()
+	defer ev.mu.RUnlock()
+	return maps.Clone(ev.externalMocks)
+}
+
+// SetExternalMocks replaces the mocks of external commands with a copy of m.
+func (ev *Evaler) SetExternalMocks(m map[string]Callable) {
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	ev.externalMocks = maps.Clone(m)
+}
+
+func (ev *Evaler) externalMock(name string) Callable {
+	ev.mu.RLock()
+	defer ev.mu.RUnlock()
+	return ev.externalMocks[name]
 }
 
 // Chdir changes the current directory, and updates $E:PWD on success
@@ -244,15 +402,26 @@ func (ev *Evaler) addNumBgJobs(delta int) {
 // It runs the functions in beforeChdir immediately before changing the
 // directory, and the functions in afterChdir immediately after (if chdir was
 // successful). It returns nil as long as the directory changing part succeeds.
+//
+// In restricted mode, it returns a SecurityError without running any hooks.
 func (ev *Evaler) Chdir(path string) error {
+	if err := ev.CheckRestricted("changing directory to " + path); err != nil {
+		return err
+	}
 	for _, hook := range ev.BeforeChdir {
 		hook(path)
 	}
 
+	oldPwd, errGetwd := os.Getwd()
 	err := os.Chdir(path)
 	if err != nil {
 		return err
 	}
+	if errGetwd == nil {
+		ev.mu.Lock()
+		ev.prevDir = oldPwd
+		ev.mu.Unlock()
+	}
 
 	for _, hook := range ev.AfterChdir {
 		hook(path)
@@ -270,7 +439,9 @@ func (ev *Evaler) Chdir(path string) error {
 
 // EvalCfg keeps configuration for the (*Evaler).Eval method.
 type EvalCfg struct {
-	// Context that can be used to cancel the evaluation.
+	// Context that can be used to cancel the evaluation. When it is canceled,
+	// the evaluation stops before the next pipeline and throws
+	// ErrInterrupted, and external commands that are running are killed.
 	Interrupts context.Context
 	// Ports to use in evaluation. The first 3 elements, if not specified
 	// (either being nil or Ports containing fewer than 3 elements),
@@ -278,10 +449,22 @@ type EvalCfg struct {
 	// DummyOutputPort respectively.
 	Ports []*Port
 	// Whether the Eval method should try to put the Elvish in the foreground
-	// after the code is executed.
+	// after the code is executed. This also enables job control: external
+	// commands in each foreground pipeline are put in their own process group,
+	// which is made the foreground process group of the terminal, so that
+	// signals generated by the terminal (like SIGINT from Ctrl-C) are only
+	// delivered to them and not to Elvish.
 	PutInFg bool
 	// If not nil, used the given global namespace, instead of Evaler's own.
 	Global *Ns
+	// If not nil, called before each pipeline at the top level of the code is
+	// executed, with the source text of the pipeline and the time it starts.
+	BeforePipeline func(code string, start time.Time)
+	// If not nil, called after each pipeline at the top level of the code has
+	// been executed, with the source text of the pipeline, the time it
+	// started, how long it took in seconds, and the exception it threw, if
+	// any.
+	AfterPipeline func(code string, start time.Time, duration float64, err error)
 }
 
 func (cfg *EvalCfg) fillDefaults() {
@@ -309,6 +492,29 @@ func (ev *Evaler) Eval(src parse.Source, cfg EvalCfg) error {
 	if err != nil {
 		return err
 	}
+	return ev.evalTree(tree, nil, cfg)
+}
+
+// EvalCompiled evaluates code compiled with [Evaler.CompileTree], with the
+// given configuration. The returned error may be a compilation error or
+// exception.
+//
+// The compiled code is only reused if cfg.Global is nil and the builtin and
+// global namespaces of the Evaler haven't changed since the code was compiled;
+// otherwise the code is compiled again, without being parsed again. Since
+// evaluating any code changes the global namespace, this is mainly useful for
+// evaluating code that was compiled just before, like the code the editor
+// compiles for highlighting errors.
+func (ev *Evaler) EvalCompiled(c *Compiled, cfg EvalCfg) error {
+	cfg.fillDefaults()
+	return ev.evalTree(c.tree, c, cfg)
+}
+
+// Evaluates a parsed tree, reusing the compiled code in c if it is not nil
+// and still valid.
+func (ev *Evaler) evalTree(tree parse.Tree, c *Compiled, cfg EvalCfg) error {
+	src := tree.Source
+	errFile := cfg.Ports[2].File
 
 	ev.mu.Lock()
 	b := ev.builtin
@@ -323,12 +529,21 @@ func (ev *Evaler) Eval(src parse.Source, cfg EvalCfg) error {
 		ev.mu.Unlock()
 	}
 
-	op, _, err := compile(b.static(), cfg.Global.static(), nil, tree, errFile)
-	if err != nil {
-		if defaultGlobal {
-			ev.mu.Unlock()
+	var op nsOp
+	if c != nil && defaultGlobal && c.builtin == b && c.global == cfg.Global {
+		op = c.op
+		if c.warnings != "" && errFile != nil {
+			io.WriteString(errFile, c.warnings)
+		}
+	} else {
+		var err error
+		op, _, err = compile(b.static(), cfg.Global.static(), nil, tree, errFile)
+		if err != nil {
+			if defaultGlobal {
+				ev.mu.Unlock()
+			}
+			return err
 		}
-		return err
 	}
 
 	fm, cleanup := ev.prepareFrame(src, cfg)
@@ -339,8 +554,25 @@ func (ev *Evaler) Eval(src parse.Source, cfg EvalCfg) error {
 		ev.global = newLocal
 		ev.mu.Unlock()
 	}
+	if cfg.BeforePipeline != nil || cfg.AfterPipeline != nil {
+		if chunk, ok := op.inner.(chunkOp); ok {
+			exec = func() Exception {
+				return chunk.execWithHooks(fm, cfg.BeforePipeline, cfg.AfterPipeline)
+			}
+		}
+	}
 
-	return exec()
+	err := exec()
+	if defaultGlobal {
+		// Deleting variables replaces fm.local; publish the result unless
+		// another evaluation has changed the global namespace in the meantime.
+		ev.mu.Lock()
+		if ev.global == newLocal {
+			ev.global = fm.local
+		}
+		ev.mu.Unlock()
+	}
+	return err
 }
 
 // CallCfg keeps configuration for the (*Evaler).Call method.
@@ -379,11 +611,27 @@ func (ev *Evaler) prepareFrame(src parse.Source, cfg EvalCfg) (*Frame, func()) {
 	if intCtx == nil {
 		intCtx = context.Background()
 	}
+	stopEvalerCtx := func() {}
+	if ev.Context != nil {
+		var cancel context.CancelFunc
+		intCtx, cancel = context.WithCancel(intCtx)
+		stop := context.AfterFunc(ev.Context, cancel)
+		if ev.Context.Err() != nil {
+			// The function passed to AfterFunc is run in a goroutine; make
+			// sure that intCtx is already canceled when the evaluation starts.
+			cancel()
+		}
+		stopEvalerCtx = func() {
+			stop()
+			cancel()
+		}
+	}
 
 	ports := fillDefaultDummyPorts(cfg.Ports)
 
-	fm := &Frame{ev, src, cfg.Global, new(Ns), nil, intCtx, ports, nil, false}
+	fm := &Frame{ev, src, cfg.Global, new(Ns), nil, intCtx, ports, nil, false, cfg.PutInFg, nil, nil, nil}
 	return fm, func() {
+		stopEvalerCtx()
 		if cfg.PutInFg {
 			err := putSelfInFg()
 			if err != nil {
@@ -420,9 +668,38 @@ func (ev *Evaler) Check(src parse.Source, w io.Writer) (error, []string, error)
 // CheckTree checks the given parsed source tree for autofixes and compilation
 // errors. If w is not nil, deprecation messages are written to it.
 func (ev *Evaler) CheckTree(tree parse.Tree, w io.Writer) ([]string, error) {
+	_, autofixes, err := ev.CompileTree(tree, w)
+	return autofixes, err
+}
+
+// Compiled is a parsed source tree compiled by [Evaler.CompileTree], which can
+// be evaluated with [Evaler.EvalCompiled].
+type Compiled struct {
+	tree parse.Tree
+	op   nsOp
+	// Deprecation messages written during compilation, written again when the
+	// code is evaluated.
+	warnings string
+	// The namespaces the code was compiled against.
+	builtin, global *Ns
+}
+
+// Source returns the source of the compiled code.
+func (c *Compiled) Source() parse.Source { return c.tree.Source }
+
+// CompileTree is like [Evaler.CheckTree], but also returns the compiled code,
+// or nil if there are compilation errors.
+func (ev *Evaler) CompileTree(tree parse.Tree, w io.Writer) (*Compiled, []string, error) {
 	ev.mu.RLock()
 	b, g, m := ev.builtin, ev.global, ev.modules
 	ev.mu.RUnlock()
-	_, autofixes, compileErr := compile(b.static(), g.static(), mapKeys(m), tree, w)
-	return autofixes, compileErr
+	var warnings strings.Builder
+	op, autofixes, compileErr := compile(b.static(), g.static(), mapKeys(m), tree, &warnings)
+	if w != nil {
+		io.WriteString(w, warnings.String())
+	}
+	if compileErr != nil {
+		return nil, autofixes, compileErr
+	}
+	return &Compiled{tree, op, warnings.String(), b, g}, autofixes, nil
 }
@@ -1,8 +1,13 @@
 package eval_test
 
 import (
+	"context"
+	"strings"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
 
 	. "src.elv.sh/pkg/eval"
 
@@ -46,6 +51,212 @@ func TestEval_Concurrent(t *testing.T) {
 	}
 }
 
+func TestEval_DelDoesNotMutatePublishedGlobal(t *testing.T) {
+	ev := NewEvaler()
+	ev.Eval(parse.Source{Name: "[test]", Code: "var x = foo"}, EvalCfg{})
+	old := ev.Global()
+
+	// Read the old global namespace while deleting the variable; this is
+	// caught by the race detector if the namespace is mutated in place.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			old.IndexString("x")
+		}
+	}()
+	ev.Eval(parse.Source{Name: "[test]", Code: "del x"}, EvalCfg{})
+	<-done
+
+	if v := old.IndexString("x"); v == nil || v.Get() != "foo" {
+		t.Errorf("$x in old global changed to %v", v)
+	}
+	if ev.Global().HasKeyString("x") {
+		t.Errorf("$x not deleted from new global")
+	}
+}
+
+func TestEval_PipelineHooks(t *testing.T) {
+	ev := NewEvaler()
+	var calls []string
+	cfg := EvalCfg{
+		BeforePipeline: func(code string, _ time.Time) {
+			calls = append(calls, "before "+code)
+		},
+		AfterPipeline: func(code string, _ time.Time, _ float64, err error) {
+			if err != nil {
+				calls = append(calls, "after "+code+": "+err.Error())
+			} else {
+				calls = append(calls, "after "+code)
+			}
+		},
+	}
+	ev.Eval(parse.Source{Name: "[test]", Code: "nop; { nop } | nop\nfail x; nop"}, cfg)
+
+	// Only pipelines at the top level are reported, and execution stops at
+	// the first exception.
+	want := []string{
+		"before nop", "after nop",
+		"before { nop } | nop", "after { nop } | nop",
+		"before fail x", "after fail x: x",
+	}
+	if diff := cmp.Diff(want, calls); diff != "" {
+		t.Errorf("hook calls (-want +got):\n%s", diff)
+	}
+}
+
+func TestEvalCompiled(t *testing.T) {
+	ev := NewEvaler()
+	compileTree := func(code string) *Compiled {
+		t.Helper()
+		tree, err := parse.Parse(parse.Source{Name: "[test]", Code: code}, parse.Config{})
+		if err != nil {
+			t.Fatalf("got parse error %v", err)
+		}
+		compiled, _, err := ev.CompileTree(tree, nil)
+		if err != nil {
+			t.Fatalf("got compile error %v", err)
+		}
+		return compiled
+	}
+
+	compiled := compileTree("var a")
+	if src := compiled.Source(); src.Name != "[test]" || src.Code != "var a" {
+		t.Errorf("got source %v", src)
+	}
+	err := ev.EvalCompiled(compiled, EvalCfg{})
+	if err != nil {
+		t.Errorf("got error %v, want nil", err)
+	}
+	if !ev.Global().HasKeyString("a") {
+		t.Errorf("variable $a not created")
+	}
+
+	// Compiled code that is stale because of another evaluation still works.
+	compiled = compileTree("var b = $a")
+	ev.Eval(parse.Source{Name: "[test]", Code: "var c"}, EvalCfg{})
+	err = ev.EvalCompiled(compiled, EvalCfg{})
+	if err != nil {
+		t.Errorf("got error %v, want nil", err)
+	}
+	g := ev.Global()
+	if !g.HasKeyString("b") || !g.HasKeyString("c") {
+		t.Errorf("variable $b or $c not created")
+	}
+
+	// Stale compiled code is compiled again, which can fail.
+	compiled = compileTree("put $c")
+	ev.DeleteFromGlobal(map[string]struct{}{"c": {}})
+	err = ev.EvalCompiled(compiled, EvalCfg{})
+	if _, ok := err.(*CompilationError); !ok {
+		t.Errorf("got error %v, want compilation error", err)
+	}
+}
+
+func TestCompileTree_CompilationError(t *testing.T) {
+	ev := NewEvaler()
+	tree, _ := parse.Parse(parse.Source{Name: "[test]", Code: "put $x"}, parse.Config{})
+	compiled, _, err := ev.CompileTree(tree, nil)
+	if compiled != nil || err == nil {
+		t.Errorf("got (%v, %v), want (nil, non-nil)", compiled, err)
+	}
+}
+
+func TestEvalerContext(t *testing.T) {
+	ev := NewEvaler()
+	ctx, cancel := context.WithCancel(context.Background())
+	ev.Context = ctx
+
+	err := ev.Eval(parse.Source{Name: "[test]", Code: "var a = foo"}, EvalCfg{})
+	if err != nil {
+		t.Errorf("got error %v before canceling, want nil", err)
+	}
+
+	cancel()
+	err = ev.Eval(parse.Source{Name: "[test]", Code: "var b = foo"}, EvalCfg{})
+	if Reason(err) != ErrInterrupted {
+		t.Errorf("got error %v after canceling, want ErrInterrupted", err)
+	}
+}
+
+func TestEval_LongSequenceIsInterruptible(t *testing.T) {
+	ev := NewEvaler()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := ev.Eval(parse.Source{Name: "[test]", Code: "nop {1..9223372036854775807}"},
+		EvalCfg{Interrupts: ctx})
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("sequence not interrupted, took %v", elapsed)
+	}
+	if Reason(err) != ErrInterrupted {
+		t.Errorf("got error %v, want ErrInterrupted", err)
+	}
+}
+
+func TestEvalerCoverage(t *testing.T) {
+	ev := NewEvaler()
+	cov := NewCoverage()
+	ev.Coverage = cov
+
+	ev.Eval(parse.Source{Name: "[test]", Code: "nop"}, EvalCfg{})
+	ev.Eval(parse.Source{Name: "a.elv", Code: "nop; if $false {\n  nop\n}\nnop\n", IsFile: true},
+		EvalCfg{})
+
+	want := []FileCoverage{{
+		Name:  "a.elv",
+		Code:  "nop; if $false {\n  nop\n}\nnop\n",
+		Lines: []LineCoverage{Covered, Uncovered, NotExecutable, Covered, NotExecutable},
+	}}
+	if diff := cmp.Diff(want, cov.Files(nil)); diff != "" {
+		t.Errorf("Files (-want +got):\n%s", diff)
+	}
+	if got := cov.Files(func(string) bool { return false }); len(got) != 0 {
+		t.Errorf("got %v with predicate rejecting all files, want none", got)
+	}
+}
+
+func TestAddBuiltin(t *testing.T) {
+	ev := NewEvaler()
+	ev.AddBuiltin("add", func(a, b int) int { return a + b })
+	ev.AddBuiltin("hello", NewGoFn("hello", func() string { return "hello" }))
+
+	port, collect, err := ValueCapturePort()
+	if err != nil {
+		panic(err)
+	}
+	err = ev.Eval(parse.Source{Name: "[test]", Code: "add 1 (num 2); hello"},
+		EvalCfg{Ports: []*Port{nil, port}})
+	if err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+	if diff := cmp.Diff([]any{3, "hello"}, collect()); diff != "" {
+		t.Errorf("output (-want +got):\n%s", diff)
+	}
+}
+
+func TestAddModule(t *testing.T) {
+	ev := NewEvaler()
+	ev.AddModule("host", BuildNsNamed("host").
+		AddVar("name", vars.NewReadOnly("app")).
+		AddGoFn("upper", strings.ToUpper).Ns())
+
+	port, collect, err := ValueCapturePort()
+	if err != nil {
+		panic(err)
+	}
+	err = ev.Eval(parse.Source{Name: "[test]", Code: "use host; host:upper $host:name"},
+		EvalCfg{Ports: []*Port{nil, port}})
+	if err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+	if diff := cmp.Diff([]any{"APP"}, collect()); diff != "" {
+		t.Errorf("output (-want +got):\n%s", diff)
+	}
+}
+
 type fooOpts struct{ Opt string }
 
 func (*fooOpts) SetDefaultOptions() {}
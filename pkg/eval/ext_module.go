@@ -0,0 +1,298 @@
+package eval
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/eval/vars"
+)
+
+// External modules are executables that implement Elvish modules. They are
+// found like .elv files, but have the extension extModuleSuffix.
+//
+// When such a module is used, Elvish starts the executable, and communicates
+// with it over its stdin and stdout using messages that are JSON objects, one
+// per line. Values are encoded like to-json and decoded like from-json.
+//
+// The module starts by writing a manifest, listing the functions and variables
+// it provides, and optionally the functions that take value inputs:
+//
+//	{"fns": ["f", "g"], "vars": {"version": "1.0"}, "input-fns": ["g"]}
+//
+// To call a function, Elvish writes a message like:
+//
+//	{"call": "f", "args": ["foo", 1], "opts": {"opt": true}}
+//
+// For functions listed in "input-fns", Elvish then writes a message like
+// {"in": "foo"} for each value input, followed by {"end": true}. These are
+// written while the module is running the call, so that it can process inputs
+// as they arrive; if the call finishes before all inputs are written, the
+// remaining inputs are discarded, but {"end": true} is still written.
+//
+// The module writes any number of messages with either an "out" field (a
+// value output) or a "bytes" field (a string to write to the byte output),
+// followed by a message with a "done" field (the call succeeded) or an
+// "error" field (the call failed with the given message).
+//
+// Calls are sent one at a time. The process is kept running until the Evaler
+// exits, at which point its stdin is closed; if it doesn't exit within
+// extModuleTimeout, it is killed. It is also killed if it doesn't write the
+// manifest within extModuleTimeout, or when a call is interrupted.
+const extModuleSuffix = ".elvmod"
+
+// How long to wait for an external module to write its manifest, and to exit
+// after its stdin is closed. Can be changed in tests.
+var extModuleTimeout = 5 * time.Second
+
+type extModule struct {
+	path   string
+	cmd    *exec.Cmd
+	exited chan struct{}
+
+	mu     sync.Mutex
+	stdin  io.WriteCloser
+	stdout *json.Decoder
+}
+
+type extCallMessage struct {
+	Call string         `json:"call"`
+	Args []any          `json:"args"`
+	Opts map[string]any `json:"opts"`
+}
+
+var errExtModuleExited = errors.New("external module exited")
+
+// Starts the executable at path and builds the namespace from its manifest.
+// The process is stopped when the Evaler exits.
+func loadExtModule(fm *Frame, path string) (*Ns, error) {
+	cmd := exec.Command(path)
+	// Don't use the error port of fm, since the process outlives it.
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	m := &extModule{path: path, cmd: cmd, exited: make(chan struct{}),
+		stdin: stdin, stdout: json.NewDecoder(bufio.NewReader(stdout))}
+	go func() {
+		cmd.Wait()
+		close(m.exited)
+	}()
+	m.stdout.UseNumber()
+
+	type readResult struct {
+		msg vals.Map
+		err error
+	}
+	manifestCh := make(chan readResult, 1)
+	go func() {
+		msg, err := m.read()
+		manifestCh <- readResult{msg, err}
+	}()
+	var manifest vals.Map
+	select {
+	case r := <-manifestCh:
+		manifest, err = r.msg, r.err
+	case <-time.After(extModuleTimeout):
+		err = fmt.Errorf("timed out after %v", extModuleTimeout)
+	case <-fm.Context().Done():
+		err = ErrInterrupted
+	}
+	if err != nil {
+		m.kill()
+		return nil, fmt.Errorf("%s: reading manifest: %w", path, err)
+	}
+	fm.Evaler.addExtModule(m)
+
+	nb := BuildNs()
+	addFns := func(key string, takesInputs bool) {
+		fns, _ := manifest.Index(key)
+		fnList, ok := fns.(vals.List)
+		if !ok {
+			return
+		}
+		for it := fnList.Iterator(); it.HasElem(); it.Next() {
+			name, ok := it.Elem().(string)
+			if !ok {
+				continue
+			}
+			nb.AddGoFn(name, func(fm *Frame, opts RawOptions, args ...any) error {
+				return m.call(fm, name, opts, args, takesInputs)
+			})
+		}
+	}
+	addFns("fns", false)
+	addFns("input-fns", true)
+	vs, _ := manifest.Index("vars")
+	if vs, ok := vs.(vals.Map); ok {
+		for it := vs.Iterator(); it.HasElem(); it.Next() {
+			k, v := it.Elem()
+			if name, ok := k.(string); ok {
+				nb.AddVar(name, vars.NewReadOnly(v))
+			}
+		}
+	}
+	return nb.Ns(), nil
+}
+
+func (m *extModule) call(fm *Frame, name string, opts RawOptions, args []any, takesInputs bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if opts == nil {
+		opts = RawOptions{}
+	}
+	if err := m.write(extCallMessage{name, args, opts}); err != nil {
+		return err
+	}
+	// The module can't be told to abandon a call, so kill it when the call is
+	// interrupted; later calls fail with errExtModuleExited.
+	stopKill := context.AfterFunc(fm.Context(), func() { m.cmd.Process.Kill() })
+	defer stopKill()
+	if takesInputs {
+		stopInputs, inputsDone := m.forwardInputs(fm)
+		defer func() {
+			close(stopInputs)
+			<-inputsDone
+		}()
+	}
+
+	// Always read until the end of the call, even if writing the output fails,
+	// so that the next call doesn't see messages from this call.
+	out := fm.ValueOutput()
+	var errOut error
+	for {
+		reply, err := m.read()
+		if err != nil {
+			if fm.Context().Err() != nil {
+				return ErrInterrupted
+			}
+			return err
+		}
+		if v, ok := reply.Index("out"); ok {
+			if errOut == nil {
+				errOut = out.Put(v)
+			}
+		} else if s, ok := reply.Index("bytes"); ok {
+			if errOut == nil {
+				_, errOut = fm.ByteOutput().WriteString(vals.ToString(s))
+			}
+		} else if msg, ok := reply.Index("error"); ok {
+			return errors.New(vals.ToString(msg))
+		} else if _, ok := reply.Index("done"); ok {
+			return errOut
+		}
+	}
+}
+
+// Writes the value inputs of fm to the module in the background, until they
+// are exhausted or stopInputs is closed, followed by the end message. The done
+// channel is closed after the end message is written.
+func (m *extModule) forwardInputs(fm *Frame) (stopInputs, done chan struct{}) {
+	stopInputs, done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		in := fm.InputChan()
+	loop:
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					break loop
+				}
+				if m.write(map[string]any{"in": v}) != nil {
+					return
+				}
+			case <-stopInputs:
+				break loop
+			}
+		}
+		// Stop the producer if the call finished before reading all inputs.
+		fm.stopInputs()
+		m.write(map[string]any{"end": true})
+	}()
+	return stopInputs, done
+}
+
+// Writes a message.
+func (m *extModule) write(v any) error {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := m.stdin.Write(append(msg, '\n')); err != nil {
+		return errExtModuleExited
+	}
+	return nil
+}
+
+// Reads a message and converts it to an Elvish map.
+func (m *extModule) read() (vals.Map, error) {
+	var v any
+	if err := m.stdout.Decode(&v); err != nil {
+		if err == io.EOF || errors.Is(err, os.ErrClosed) {
+			return nil, errExtModuleExited
+		}
+		return nil, err
+	}
+	converted, err := fromJSONInterface(v)
+	if err != nil {
+		return nil, err
+	}
+	msg, ok := converted.(vals.Map)
+	if !ok {
+		return nil, fmt.Errorf("%s: message is not an object", m.path)
+	}
+	return msg, nil
+}
+
+// Closes the stdin of the module and waits for it to exit, killing it if it
+// doesn't exit within extModuleTimeout.
+func (m *extModule) close() {
+	m.stdin.Close()
+	select {
+	case <-m.exited:
+	case <-time.After(extModuleTimeout):
+		m.kill()
+	}
+}
+
+// Kills the module and waits for it to exit.
+func (m *extModule) kill() {
+	m.stdin.Close()
+	m.cmd.Process.Kill()
+	<-m.exited
+}
+
+func (ev *Evaler) addExtModule(m *extModule) {
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	ev.extModules = append(ev.extModules, m)
+}
+
+// Stops all the external modules. This is done when Elvish is about to exit.
+func (ev *Evaler) closeExtModules() {
+	ev.mu.Lock()
+	modules := ev.extModules
+	ev.extModules = nil
+	ev.mu.Unlock()
+	for _, m := range modules {
+		m.close()
+	}
+}
@@ -0,0 +1,46 @@
+//go:build unix
+
+package eval_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	. "src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/testutil"
+)
+
+func TestExtModule_KilledByPreExit(t *testing.T) {
+	testutil.Set(t, ExtModuleTimeout, 200*time.Millisecond)
+	lib := testutil.TempDir(t)
+	// A module that ignores its stdin being closed.
+	err := os.WriteFile(filepath.Join(lib, "stuck.elvmod"), []byte(
+		"#!/bin/sh\n"+
+			"echo '{\"vars\": {\"pid\": '$$'}}'\n"+
+			"trap '' PIPE\n"+
+			"while :; do sleep 1; done\n"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := NewEvaler()
+	ev.LibDirs = []string{lib}
+	err = ev.Eval(parse.Source{Name: "[test]", Code: "use stuck; var pid = $stuck:pid"}, EvalCfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pid int
+	if err := vals.ScanToGo(ev.Global().IndexString("pid").Get(), &pid); err != nil {
+		t.Fatal(err)
+	}
+
+	ev.PreExit()
+
+	if err := syscall.Kill(pid, 0); err != syscall.ESRCH {
+		t.Errorf("module process still exists after PreExit (kill: %v)", err)
+	}
+}
@@ -1,6 +1,7 @@
 package eval
 
 import (
+	"context"
 	"errors"
 	"os"
 	"os/exec"
@@ -55,23 +56,40 @@ func (e externalCmd) Repr(int) string {
 	return "<external " + parse.Quote(e.Name) + ">"
 }
 
+// Returns whether an external command can be found in the directories in
+// $E:PATH.
+func inPath(name string) bool {
+	_, err := lookPath(name)
+	return err == nil
+}
+
 // Call calls an external command.
 func (e externalCmd) Call(fm *Frame, argVals []any, opts map[string]any) error {
 	if len(opts) > 0 {
 		return ErrExternalCmdOpts
 	}
-	if fsutil.DontSearch(e.Name) {
+	if mock := fm.Evaler.externalMock(e.Name); mock != nil {
+		return mock.Call(fm.Fork("mocked external "+e.Name), argVals, NoOpts)
+	}
+	autoCd := fm.Evaler.getAutoCd()
+	if fsutil.DontSearch(e.Name) || autoCd {
 		stat, err := os.Stat(e.Name)
-		if err == nil && stat.IsDir() {
+		if err == nil && stat.IsDir() && (fsutil.DontSearch(e.Name) || !inPath(e.Name)) {
 			// implicit cd
 			if len(argVals) > 0 {
 				return ErrImplicitCdNoArg
 			}
-			fm.Deprecate("implicit cd is deprecated; use cd or location mode instead", fm.traceback.Head, 21)
+			if !autoCd {
+				fm.Deprecate("implicit cd is deprecated; use cd or location mode instead", fm.traceback.Head, 21)
+			}
 			return fm.Evaler.Chdir(e.Name)
 		}
 	}
 
+	if err := fm.Evaler.CheckRestricted("running external command " + e.Name); err != nil {
+		return err
+	}
+
 	files := make([]*os.File, len(fm.ports))
 	for i, port := range fm.ports {
 		if port != nil {
@@ -86,8 +104,11 @@ func (e externalCmd) Call(fm *Frame, argVals []any, opts map[string]any) error {
 		args[i+1] = vals.ToString(a)
 	}
 
-	path, err := exec.LookPath(e.Name)
+	path, err := lookPath(e.Name)
 	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) && !fsutil.DontSearch(e.Name) {
+			return withSuggestions(err, suggestCommands(fm, e.Name))
+		}
 		return err
 	}
 
@@ -105,13 +126,30 @@ func (e externalCmd) Call(fm *Frame, argVals []any, opts map[string]any) error {
 
 	args[0] = path
 
-	sys := makeSysProcAttr(fm.background)
-	proc, err := os.StartProcess(path, args, &os.ProcAttr{Files: files, Sys: sys})
+	proc, err := startProcess(fm, path, args, files)
 	if err != nil {
 		return err
 	}
+	if fm.bgJob != nil {
+		// Make the process reachable from kill and wait via the job.
+		fm.bgJob.addPid(proc.Pid)
+		defer fm.bgJob.removePid(proc.Pid)
+	}
+	// Like the function returned by context.AfterFunc, returns whether the
+	// process was not killed.
+	stopKilling := func() bool { return true }
+	if fm.job != nil {
+		defer fm.job.forwardInterrupts(fm.ctx)()
+	} else {
+		// Without job control, there is nothing that stops the process when
+		// the evaluation is canceled, so kill it.
+		stopKilling = context.AfterFunc(fm.ctx, func() { proc.Kill() })
+	}
 
 	state, err := proc.Wait()
+	if !stopKilling() {
+		return ErrInterrupted
+	}
 	if err != nil {
 		// This should be a can't happen situation. Nonetheless, treat it as a
 		// soft error rather than panicking since the Go documentation is not
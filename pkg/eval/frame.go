@@ -41,6 +41,15 @@ type Frame struct {
 	traceback *StackTrace
 
 	background bool
+	// Whether foreground pipelines should be run as jobs in their own process
+	// groups.
+	jobControl bool
+	// The foreground job the frame belongs to, if any.
+	job *job
+	// The background job the frame belongs to, if any.
+	bgJob *bgJob
+	// The exception being handled by the innermost catch block, if any.
+	caught Exception
 }
 
 // PrepareEval prepares a piece of code for evaluation in a copy of the current
@@ -53,9 +62,16 @@ type Frame struct {
 // returns the altered local namespace, function that can be called to actuate
 // the evaluation, and a nil error.
 func (fm *Frame) PrepareEval(src parse.Source, r diag.Ranger, ns *Ns) (*Ns, func() Exception, error) {
+	newLocal, exec, _, err := fm.prepareEval(src, r, ns)
+	return newLocal, exec, err
+}
+
+// Like PrepareEval, but also returns the names declared with the export
+// pragma, or nil if there is no such pragma.
+func (fm *Frame) prepareEval(src parse.Source, r diag.Ranger, ns *Ns) (*Ns, func() Exception, []string, error) {
 	tree, err := parse.Parse(src, parse.Config{WarningWriter: fm.ErrorFile()})
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	local := fm.local
 	if ns != nil {
@@ -66,13 +82,14 @@ func (fm *Frame) PrepareEval(src parse.Source, r diag.Ranger, ns *Ns) (*Ns, func
 		traceback = fm.addTraceback(r)
 	}
 	newFm := &Frame{
-		fm.Evaler, src, local, new(Ns), nil, fm.ctx, fm.ports, traceback, fm.background}
+		fm.Evaler, src, local, new(Ns), nil, fm.ctx, fm.ports, traceback,
+		fm.background, fm.jobControl, fm.job, fm.bgJob, fm.caught}
 	op, _, err := compile(fm.Evaler.Builtin().static(), local.static(), nil, tree, fm.ErrorFile())
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	newLocal, exec := op.prepare(newFm)
-	return newLocal, exec, nil
+	return newLocal, exec, op.exports, nil
 }
 
 // Eval evaluates a piece of code in a copy of the current Frame. It returns the
@@ -159,12 +176,23 @@ func (fm *Frame) IterateInputs(f func(any)) {
 	}
 }
 
+// Tells the command writing to the input of the frame to stop, if the input
+// is a pipe from another command in the same pipeline. This allows commands
+// that only need part of their inputs to terminate when their inputs are
+// infinite; the inputs still need to be iterated until the end, which comes
+// soon after.
+func (fm *Frame) stopInputs() {
+	if stop := fm.ports[0].stopWriter; stop != nil {
+		stop()
+	}
+}
+
 func linesToChan(r io.Reader, ch chan<- any) {
 	filein := bufio.NewReader(r)
 	for {
 		line, err := filein.ReadString('\n')
 		if line != "" {
-			ch <- strutil.ChopLineEnding(line)
+			ch <- intern(strutil.ChopLineEnding(line))
 		}
 		if err != nil {
 			if err != io.EOF {
@@ -193,18 +221,54 @@ func (fm *Frame) Canceled() bool {
 // Fork returns a modified copy of fm. The ports are forked, and the name is
 // changed to the given value. Other fields are copied shallowly.
 func (fm *Frame) Fork(name string) *Frame {
-	newPorts := make([]*Port, len(fm.ports))
-	for i, p := range fm.ports {
-		if p != nil {
-			newPorts[i] = p.fork()
+	var newFm *Frame
+	var newPorts []*Port
+	if len(fm.ports) <= len(forkedFrame{}.ports) {
+		// Allocate the Frame and its ports together. This is the common case,
+		// and matters for the performance of code that calls functions in a
+		// tight loop, since each call forks the frame.
+		f := &forkedFrame{}
+		newFm, newPorts = &f.fm, f.portPtrs[:len(fm.ports)]
+		for i, p := range fm.ports {
+			if p != nil {
+				f.ports[i] = p.forkValue()
+				newPorts[i] = &f.ports[i]
+			}
+		}
+	} else {
+		newFm = &Frame{}
+		newPorts = make([]*Port, len(fm.ports))
+		for i, p := range fm.ports {
+			if p != nil {
+				newPorts[i] = p.fork()
+			}
 		}
 	}
-	return &Frame{
+	*newFm = Frame{
 		fm.Evaler, fm.srcMeta,
 		fm.local, fm.up, fm.defers,
 		fm.ctx, newPorts,
-		fm.traceback, fm.background,
+		fm.traceback, fm.background, fm.jobControl, fm.job, fm.bgJob,
+		fm.caught,
 	}
+	return newFm
+}
+
+// ForkWithContext is like Fork, but also replaces the Context of the returned
+// Frame. The Context should be derived from the Context of fm, so that
+// interrupts still work.
+func (fm *Frame) ForkWithContext(name string, ctx context.Context) *Frame {
+	newFm := fm.Fork(name)
+	newFm.ctx = ctx
+	return newFm
+}
+
+// A Frame with storage for the standard 3 ports, so that they can be
+// allocated together.
+type forkedFrame struct {
+	fm       Frame
+	portPtrs [3]*Port
+	ports    [3]Port
 }
 
 // A shorthand for forking a frame and setting the output port.
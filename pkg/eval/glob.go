@@ -5,7 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"src.elv.sh/pkg/eval/vals"
@@ -19,6 +22,27 @@ type globPattern struct {
 	Flags  globFlag
 	Buts   []string
 	TypeCb func(os.FileMode) bool
+	// Patterns from the except modifiers.
+	Excepts []glob.Pattern
+	// Filters from the size and mtime modifiers, all of which must be
+	// satisfied.
+	Filters []func(os.FileInfo) bool
+	// Set by the sort modifier.
+	Sort *globSort
+}
+
+// Specifies the order of the results of a glob pattern.
+type globSort struct {
+	less    func(a, b glob.PathInfo) bool
+	reverse bool
+}
+
+var globSortLessMap = map[string]func(a, b glob.PathInfo) bool{
+	"name": func(a, b glob.PathInfo) bool { return a.Path < b.Path },
+	"mtime": func(a, b glob.PathInfo) bool {
+		return a.Info.ModTime().Before(b.Info.ModTime())
+	},
+	"size": func(a, b glob.PathInfo) bool { return a.Info.Size() < b.Info.Size() },
 }
 
 type globFlag uint
@@ -26,16 +50,28 @@ type globFlag uint
 var typeCbMap = map[string]func(os.FileMode) bool{
 	"dir":     os.FileMode.IsDir,
 	"regular": os.FileMode.IsRegular,
+	"symlink": func(m os.FileMode) bool { return m&os.ModeSymlink != 0 },
 }
 
 const (
 	// noMatchOK indicates that the "nomatch-ok" glob index modifier was
 	// present.
 	noMatchOK globFlag = 1 << iota
+	// noMatchError indicates that the "nomatch-error" glob index modifier was
+	// present.
+	noMatchError
+	// noMatchLiteral indicates that the "nomatch-literal" glob index modifier
+	// was present.
+	noMatchLiteral
+
+	noMatchFlags = noMatchOK | noMatchError | noMatchLiteral
 )
 
-func (f globFlag) Has(g globFlag) bool {
-	return (f & g) == g
+// Maps the possible values of $glob-nomatch to the equivalent flags.
+var globNoMatchFlags = map[string]globFlag{
+	"error":   noMatchError,
+	"ok":      noMatchOK,
+	"literal": noMatchLiteral,
 }
 
 var _ vals.ErrIndexer = globPattern{}
@@ -45,7 +81,10 @@ var (
 	ErrModifierMustBeString  = errors.New("modifier must be string")
 	ErrWildcardNoMatch       = errors.New("wildcard has no match")
 	ErrMultipleTypeModifiers = errors.New("only one type modifier allowed")
+	ErrMultipleNoMatch       = errors.New("only one nomatch modifier allowed")
 	ErrUnknownTypeModifier   = errors.New("unknown type modifier")
+	ErrMultipleSortModifiers = errors.New("only one sort modifier allowed")
+	ErrUnknownSortModifier   = errors.New("unknown sort modifier")
 )
 
 var runeMatchers = map[string]func(rune) bool{
@@ -73,10 +112,18 @@ func (gp globPattern) Index(k any) (any, error) {
 	}
 	modifier := modifierv
 	switch {
-	case modifier == "nomatch-ok":
-		gp.Flags |= noMatchOK
+	case strings.HasPrefix(modifier, "nomatch-") &&
+		globNoMatchFlags[modifier[len("nomatch-"):]] != 0:
+		gp.Flags |= globNoMatchFlags[modifier[len("nomatch-"):]]
+	case modifier == "nocase":
+		gp.IgnoreCase = true
+	case modifier == "match-hidden-all":
+		gp.MatchHidden = true
 	case strings.HasPrefix(modifier, "but:"):
 		gp.Buts = append(gp.Buts, modifier[len("but:"):])
+	case strings.HasPrefix(modifier, "except:"):
+		gp.Excepts = append(gp.Excepts[:len(gp.Excepts):len(gp.Excepts)],
+			glob.Parse(modifier[len("except:"):]))
 	case modifier == "match-hidden":
 		lastSeg, err := gp.lastWildSeg()
 		if err != nil {
@@ -95,6 +142,29 @@ func (gp globPattern) Index(k any) (any, error) {
 			return nil, ErrUnknownTypeModifier
 		}
 		gp.TypeCb = cb
+	case strings.HasPrefix(modifier, "size:"):
+		filter, err := sizeFilter(modifier[len("size:"):])
+		if err != nil {
+			return nil, err
+		}
+		gp.Filters = append(gp.Filters[:len(gp.Filters):len(gp.Filters)], filter)
+	case strings.HasPrefix(modifier, "mtime:"):
+		filter, err := mtimeFilter(modifier[len("mtime:"):], time.Now())
+		if err != nil {
+			return nil, err
+		}
+		gp.Filters = append(gp.Filters[:len(gp.Filters):len(gp.Filters)], filter)
+	case strings.HasPrefix(modifier, "sort:"):
+		if gp.Sort != nil {
+			return nil, ErrMultipleSortModifiers
+		}
+		key := modifier[len("sort:"):]
+		reverse := strings.HasPrefix(key, "-")
+		less, ok := globSortLessMap[strings.TrimPrefix(key, "-")]
+		if !ok {
+			return nil, ErrUnknownSortModifier
+		}
+		gp.Sort = &globSort{less, reverse}
 	default:
 		var matcher func(rune) bool
 		if m, ok := runeMatchers[modifier]; ok {
@@ -139,13 +209,18 @@ func (gp globPattern) Concat(v any) (any, error) {
 		var segs []glob.Segment
 		segs = append(segs, gp.Segments...)
 		segs = append(segs, stringToSegments(rhs)...)
-		return globPattern{Pattern: glob.Pattern{Segments: segs}, Flags: gp.Flags,
-			Buts: gp.Buts, TypeCb: gp.TypeCb}, nil
+		return globPattern{Pattern: glob.Pattern{Segments: segs,
+			IgnoreCase: gp.IgnoreCase, MatchHidden: gp.MatchHidden}, Flags: gp.Flags,
+			Buts: gp.Buts, Excepts: gp.Excepts, TypeCb: gp.TypeCb,
+			Filters: gp.Filters, Sort: gp.Sort}, nil
 	case globPattern:
 		// We know rhs contains exactly one segment.
 		gp.append(rhs.Segments[0])
 		gp.Flags |= rhs.Flags
+		gp.IgnoreCase = gp.IgnoreCase || rhs.IgnoreCase
+		gp.MatchHidden = gp.MatchHidden || rhs.MatchHidden
 		gp.Buts = append(gp.Buts, rhs.Buts...)
+		gp.Excepts = append(gp.Excepts[:len(gp.Excepts):len(gp.Excepts)], rhs.Excepts...)
 		// This handles illegal cases such as `**[type:regular]x*[type:directory]`.
 		if gp.TypeCb != nil && rhs.TypeCb != nil {
 			return nil, ErrMultipleTypeModifiers
@@ -153,6 +228,13 @@ func (gp globPattern) Concat(v any) (any, error) {
 		if rhs.TypeCb != nil {
 			gp.TypeCb = rhs.TypeCb
 		}
+		gp.Filters = append(gp.Filters[:len(gp.Filters):len(gp.Filters)], rhs.Filters...)
+		if gp.Sort != nil && rhs.Sort != nil {
+			return nil, ErrMultipleSortModifiers
+		}
+		if rhs.Sort != nil {
+			gp.Sort = rhs.Sort
+		}
 		return gp, nil
 	}
 
@@ -165,8 +247,10 @@ func (gp globPattern) RConcat(v any) (any, error) {
 		segs := stringToSegments(lhs)
 		// We know gp contains exactly one segment.
 		segs = append(segs, gp.Segments[0])
-		return globPattern{Pattern: glob.Pattern{Segments: segs}, Flags: gp.Flags,
-			Buts: gp.Buts, TypeCb: gp.TypeCb}, nil
+		return globPattern{Pattern: glob.Pattern{Segments: segs,
+			IgnoreCase: gp.IgnoreCase, MatchHidden: gp.MatchHidden}, Flags: gp.Flags,
+			Buts: gp.Buts, Excepts: gp.Excepts, TypeCb: gp.TypeCb,
+			Filters: gp.Filters, Sort: gp.Sort}, nil
 	}
 
 	return nil, vals.ErrConcatNotImplemented
@@ -198,6 +282,58 @@ func (gp *globPattern) append(segs ...glob.Segment) {
 	gp.Segments = append(gp.Segments, segs...)
 }
 
+var sizeUnits = map[byte]int64{'k': 1 << 10, 'M': 1 << 20, 'G': 1 << 30, 'T': 1 << 40}
+
+// Parses the argument of a size modifier, like +10k (larger than 10KiB) or
+// -1M (smaller than 1MiB).
+func sizeFilter(arg string) (func(os.FileInfo) bool, error) {
+	badSize := fmt.Errorf("bad size modifier: %s", parse.Quote(arg))
+	if len(arg) < 2 || (arg[0] != '+' && arg[0] != '-') {
+		return nil, badSize
+	}
+	larger, num := arg[0] == '+', arg[1:]
+	unit := int64(1)
+	if u, ok := sizeUnits[num[len(num)-1]]; ok {
+		unit, num = u, num[:len(num)-1]
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 {
+		return nil, badSize
+	}
+	size := n * unit
+	if larger {
+		return func(info os.FileInfo) bool { return info.Size() > size }, nil
+	}
+	return func(info os.FileInfo) bool { return info.Size() < size }, nil
+}
+
+var durationUnits = map[byte]time.Duration{
+	's': time.Second, 'm': time.Minute, 'h': time.Hour,
+	'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+
+// Parses the argument of an mtime modifier, like -1h (modified less than an
+// hour before now) or +7d (modified more than 7 days before now).
+func mtimeFilter(arg string, now time.Time) (func(os.FileInfo) bool, error) {
+	badMtime := fmt.Errorf("bad mtime modifier: %s", parse.Quote(arg))
+	if len(arg) < 3 || (arg[0] != '+' && arg[0] != '-') {
+		return nil, badMtime
+	}
+	older, num := arg[0] == '+', arg[1:len(arg)-1]
+	unit, ok := durationUnits[arg[len(arg)-1]]
+	if !ok {
+		return nil, badMtime
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 {
+		return nil, badMtime
+	}
+	cutoff := now.Add(-time.Duration(n) * unit)
+	if older {
+		return func(info os.FileInfo) bool { return info.ModTime().Before(cutoff) }, nil
+	}
+	return func(info os.FileInfo) bool { return info.ModTime().After(cutoff) }, nil
+}
+
 func wildcardToSegment(s string) (glob.Segment, error) {
 	switch s {
 	case "*":
@@ -232,13 +368,40 @@ func stringToSegments(s string) []glob.Segment {
 	return segs
 }
 
-func doGlob(ctx context.Context, gp globPattern) ([]any, error) {
+// Returns the pattern as it would be written, without any modifiers.
+func (gp globPattern) literal() string {
+	var sb strings.Builder
+	for _, seg := range gp.Segments {
+		switch seg := seg.(type) {
+		case glob.Literal:
+			sb.WriteString(seg.Data)
+		case glob.Slash:
+			sb.WriteByte('/')
+		case glob.Wild:
+			sb.WriteString([...]string{glob.Question: "?", glob.Star: "*",
+				glob.StarStar: "**"}[seg.Type])
+		}
+	}
+	return sb.String()
+}
+
+// Expands a glob pattern. The noMatch argument determines what to do when
+// there is no match, unless overridden by a nomatch modifier; it must be one
+// of the keys of globNoMatchFlags.
+func doGlob(ctx context.Context, gp globPattern, noMatch string) ([]any, error) {
+	noMatchFlag := gp.Flags & noMatchFlags
+	if noMatchFlag == 0 {
+		noMatchFlag = globNoMatchFlags[noMatch]
+	} else if noMatchFlag&(noMatchFlag-1) != 0 {
+		return nil, ErrMultipleNoMatch
+	}
+
 	but := make(map[string]struct{})
 	for _, s := range gp.Buts {
 		but[s] = struct{}{}
 	}
 
-	vs := make([]any, 0)
+	var infos []glob.PathInfo
 	if !gp.Glob(func(pathInfo glob.PathInfo) bool {
 		select {
 		case <-ctx.Done():
@@ -250,16 +413,44 @@ func doGlob(ctx context.Context, gp globPattern) ([]any, error) {
 		if _, ignore := but[pathInfo.Path]; ignore {
 			return true
 		}
+		for _, except := range gp.Excepts {
+			if except.Match(pathInfo.Path) {
+				return true
+			}
+		}
 
-		if gp.TypeCb == nil || gp.TypeCb(pathInfo.Info.Mode()) {
-			vs = append(vs, pathInfo.Path)
+		if gp.TypeCb != nil && !gp.TypeCb(pathInfo.Info.Mode()) {
+			return true
 		}
+		for _, filter := range gp.Filters {
+			if !filter(pathInfo.Info) {
+				return true
+			}
+		}
+		infos = append(infos, pathInfo)
 		return true
 	}) {
 		return nil, ErrInterrupted
 	}
-	if len(vs) == 0 && !gp.Flags.Has(noMatchOK) {
-		return nil, ErrWildcardNoMatch
+	if s := gp.Sort; s != nil {
+		sort.SliceStable(infos, func(i, j int) bool {
+			if s.reverse {
+				return s.less(infos[j], infos[i])
+			}
+			return s.less(infos[i], infos[j])
+		})
+	}
+	vs := make([]any, len(infos))
+	for i, info := range infos {
+		vs[i] = info.Path
+	}
+	if len(vs) == 0 {
+		switch noMatchFlag {
+		case noMatchError:
+			return nil, ErrWildcardNoMatch
+		case noMatchLiteral:
+			return []any{gp.literal()}, nil
+		}
 	}
 	return vs, nil
 }
@@ -22,6 +22,8 @@ var (
 type WrongArgType struct {
 	argNum    int
 	typeError error
+	// The function that received the argument.
+	fn *goFn
 }
 
 // Error implements the error interface.
@@ -218,7 +220,7 @@ func (b *goFn) Call(f *Frame, args []any, opts map[string]any) error {
 		ptr := reflect.New(typ)
 		err := vals.ScanToGo(arg, ptr.Interface())
 		if err != nil {
-			return WrongArgType{i, err}
+			return WrongArgType{i, err, b}
 		}
 		in = append(in, ptr.Elem())
 	}
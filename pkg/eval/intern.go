@@ -0,0 +1,67 @@
+package eval
+
+import (
+	"hash/maphash"
+	"sync/atomic"
+
+	"src.elv.sh/pkg/eval/vals"
+)
+
+// Strings read from inputs are often repeated many times, like the lines of a
+// log file or the keys of JSON objects. Interning short strings as they are
+// read makes all the copies share the same memory, so that holding a lot of
+// them, for example in a list, doesn't need a separate allocation for each.
+//
+// The intern table is a fixed-size cache indexed by the hash of the strings;
+// a string replaces any other string with the same index. This keeps the
+// memory used by the table bounded, and makes it safe to use concurrently
+// without locking, at the cost of missing some opportunities for sharing.
+
+const (
+	// Only strings up to this length are interned. Longer strings are less
+	// likely to be repeated, and comparing them is more expensive.
+	maxInternLen = 64
+	// Number of slots in the intern table; must be a power of 2.
+	internTableSize = 1 << 12
+)
+
+var (
+	internSeed  = maphash.MakeSeed()
+	internTable [internTableSize]atomic.Pointer[string]
+
+	internHits    atomic.Int64
+	internMisses  atomic.Int64
+	internSkipped atomic.Int64
+)
+
+// Returns a string equal to s, sharing memory with a previous string passed to
+// intern if possible.
+func intern(s string) string {
+	if len(s) > maxInternLen {
+		internSkipped.Add(1)
+		return s
+	}
+	slot := &internTable[maphash.String(internSeed, s)&(internTableSize-1)]
+	if p := slot.Load(); p != nil && *p == s {
+		internHits.Add(1)
+		return *p
+	}
+	internMisses.Add(1)
+	slot.Store(&s)
+	return s
+}
+
+func _internStats() vals.Map {
+	size := 0
+	for i := range internTable {
+		if internTable[i].Load() != nil {
+			size++
+		}
+	}
+	return vals.MakeMap(
+		"hits", int(internHits.Load()),
+		"misses", int(internMisses.Load()),
+		"skipped", int(internSkipped.Load()),
+		"size", size,
+		"capacity", internTableSize)
+}
@@ -0,0 +1,27 @@
+package eval
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestIntern(t *testing.T) {
+	// Build the strings at runtime so that they don't share memory to start
+	// with.
+	s1 := strings.Repeat("x", 10)
+	s2 := strings.Repeat("x", 10)
+	i1, i2 := intern(s1), intern(s2)
+	if i1 != s1 || i2 != s2 {
+		t.Errorf("intern changed the content of strings")
+	}
+	if unsafe.StringData(i1) != unsafe.StringData(i2) {
+		t.Errorf("equal short strings don't share memory after interning")
+	}
+
+	l1 := strings.Repeat("x", maxInternLen+1)
+	l2 := strings.Repeat("x", maxInternLen+1)
+	if unsafe.StringData(intern(l1)) == unsafe.StringData(intern(l2)) {
+		t.Errorf("long strings share memory after interning")
+	}
+}
@@ -0,0 +1,33 @@
+package eval
+
+import (
+	"os"
+	"sync"
+)
+
+// A job is a foreground pipeline run with job control, or the body of a timeout
+// command. All the external commands it starts are put in the same process
+// group.
+type job struct {
+	mu sync.Mutex
+	// The process group of the job; 0 if no process has been started yet.
+	pgid int
+	// Whether the process group has been made the foreground process group of
+	// the terminal.
+	tookTerminal bool
+	// Processes started in the job. Only used on Windows, which doesn't have
+	// process groups.
+	procs []*os.Process
+}
+
+// Called when the pipeline of the job has finished.
+func (j *job) done() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.tookTerminal {
+		err := putSelfInFg()
+		if err != nil {
+			logger.Println("failed to put myself in foreground:", err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+//go:build unix
+
+package eval_test
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	. "src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/parse"
+)
+
+func TestJobControl_ForwardsInterruptsToJob(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not found")
+	}
+	ev := NewEvaler()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := ev.Eval(parse.Source{Name: "[test]", Code: "sleep 10"},
+		EvalCfg{Interrupts: ctx, PutInFg: true})
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("sleep not interrupted, took %v", elapsed)
+	}
+	if err == nil {
+		t.Errorf("got nil error, want non-nil")
+	}
+}
+
+func TestInterrupts_KillsExternalCommandsWithoutJobControl(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not found")
+	}
+	ev := NewEvaler()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := ev.Eval(parse.Source{Name: "[test]", Code: "sleep 10"},
+		EvalCfg{Interrupts: ctx})
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("sleep not killed, took %v", elapsed)
+	}
+	if Reason(err) != ErrInterrupted {
+		t.Errorf("got error %v, want ErrInterrupted", err)
+	}
+}
+
+func TestEvalerContext_KillsExternalCommands(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not found")
+	}
+	ev := NewEvaler()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	ev.Context = ctx
+
+	start := time.Now()
+	err := ev.Eval(parse.Source{Name: "[test]", Code: "sleep 10"}, EvalCfg{})
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("sleep not killed, took %v", elapsed)
+	}
+	if Reason(err) != ErrInterrupted {
+		t.Errorf("got error %v, want ErrInterrupted", err)
+	}
+}
+
+func TestHangUpBgJobs_SkipsDetachedJobs(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not found")
+	}
+	ev := NewEvaler()
+	err := ev.Eval(parse.Source{Name: "[test]", Code: `
+		e:sleep 10 &
+		e:sleep 10 &
+		while (!= 2 (jobs | each {|j| all $j[pids] } | count)) { sleep 0.01 }
+		var hup-pid detached-pid = (jobs | each {|j| put $j[pids][0] })
+		detach %2`}, EvalCfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hupPid, detachedPid int
+	vals.ScanToGo(ev.Global().IndexString("hup-pid").Get(), &hupPid)
+	vals.ScanToGo(ev.Global().IndexString("detached-pid").Get(), &detachedPid)
+	defer syscall.Kill(detachedPid, syscall.SIGKILL)
+
+	ev.HangUpBgJobs()
+
+	if !waitForExit(hupPid, 5*time.Second) {
+		t.Errorf("job not hung up")
+	}
+	if err := syscall.Kill(detachedPid, 0); err != nil {
+		t.Errorf("detached job hung up (kill: %v)", err)
+	}
+}
+
+// Waits until the process with the given pid no longer exists or is a zombie,
+// and reports whether it did so before the timeout.
+func waitForExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if !ProcessExists(pid) {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package eval
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Elvish doesn't launch external commands with posix_spawn: os.StartProcess
+// already uses vfork semantics on Linux (CLONE_VFORK and CLONE_VM), so starting
+// a process is cheap even when Elvish uses a lot of memory, and calling
+// posix_spawn would require cgo.
+//
+// Instead, the remaining significant cost of running a small external command
+// is addressed: searching the executable in $E:PATH needs a stat call for
+// every directory until it is found. Results are cached for a short time, so
+// that loops that run the same external commands repeatedly only need to
+// search once; the cache doesn't need to be invalidated explicitly, since it
+// is unlikely that an executable is added or removed and used within the TTL.
+
+// How long a result of lookPath is cached for. Can be changed in tests.
+var lookPathTTL = time.Second
+
+type lookPathEntry struct {
+	path string
+	time time.Time
+}
+
+var (
+	lookPathMutex sync.Mutex
+	// Value of $E:PATH when the entries were cached.
+	lookPathEnv   string
+	lookPathCache = map[string]lookPathEntry{}
+)
+
+// Like exec.LookPath, but caches the results for names without slashes.
+func lookPath(name string) (string, error) {
+	now := time.Now()
+	pathEnv := os.Getenv("PATH")
+
+	lookPathMutex.Lock()
+	if pathEnv != lookPathEnv {
+		lookPathEnv = pathEnv
+		clear(lookPathCache)
+	}
+	entry, ok := lookPathCache[name]
+	lookPathMutex.Unlock()
+	if ok && now.Sub(entry.time) < lookPathTTL {
+		return entry.path, nil
+	}
+
+	path, err := exec.LookPath(name)
+	// Results for names with slashes and from relative directories in
+	// $E:PATH depend on the working directory, so they are not cached.
+	if err == nil && filepath.IsAbs(path) && filepath.Base(name) == name {
+		lookPathMutex.Lock()
+		if pathEnv == lookPathEnv {
+			lookPathCache[name] = lookPathEntry{path, now}
+		}
+		lookPathMutex.Unlock()
+	}
+	return path, err
+}
@@ -0,0 +1,65 @@
+//go:build unix
+
+package eval
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"src.elv.sh/pkg/testutil"
+)
+
+func TestLookPath_Caches(t *testing.T) {
+	dir := testutil.TempDir(t)
+	testutil.ApplyDirIn(testutil.Dir{
+		"a": testutil.Dir{"foo": testutil.File{Perm: 0755}},
+		"b": testutil.Dir{"foo": testutil.File{Perm: 0755}},
+	}, dir)
+	testutil.Setenv(t, "PATH", filepath.Join(dir, "b"))
+	testutil.Set(t, &lookPathTTL, time.Hour)
+
+	want := filepath.Join(dir, "b", "foo")
+	path, err := lookPath("foo")
+	if path != want || err != nil {
+		t.Fatalf("got (%q, %v), want (%q, nil)", path, err, want)
+	}
+
+	// The cached result is used even after the executable is removed.
+	os.Remove(want)
+	path, err = lookPath("foo")
+	if path != want || err != nil {
+		t.Errorf("got (%q, %v), want cached (%q, nil)", path, err, want)
+	}
+
+	// Changing $E:PATH invalidates the cache.
+	os.Setenv("PATH", filepath.Join(dir, "a"))
+	want = filepath.Join(dir, "a", "foo")
+	path, err = lookPath("foo")
+	if path != want || err != nil {
+		t.Errorf("got (%q, %v), want (%q, nil)", path, err, want)
+	}
+
+	// Cached results expire.
+	lookPathTTL = 0
+	os.Remove(want)
+	if _, err := lookPath("foo"); err == nil {
+		t.Errorf("got nil error after the cached result expired")
+	}
+}
+
+func BenchmarkLookPath(b *testing.B) {
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			exec.LookPath("sh")
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		testutil.Set(b, &lookPathTTL, time.Hour)
+		for i := 0; i < b.N; i++ {
+			lookPath("sh")
+		}
+	})
+}
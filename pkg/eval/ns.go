@@ -70,6 +70,21 @@ func (ns *Ns) clone() *Ns {
 		append([]staticVarInfo(nil), ns.infos...)}
 }
 
+// Returns a copy of ns with only the given names.
+func (ns *Ns) only(names []string) *Ns {
+	keep := make(map[string]bool, len(names))
+	for _, name := range names {
+		keep[name] = true
+	}
+	newNs := ns.clone()
+	for i, info := range newNs.infos {
+		if !keep[info.name] {
+			newNs.infos[i].deleted = true
+		}
+	}
+	return newNs
+}
+
 // Ns returns ns itself.
 func (ns *Ns) Ns() *Ns {
 	return ns
@@ -166,7 +181,7 @@ func (ns *Ns) HasKeyString(k string) bool {
 }
 
 func (ns *Ns) static() *staticNs {
-	return &staticNs{ns.infos}
+	return &staticNs{infos: ns.infos}
 }
 
 // NsBuilder is a helper type used for building an Ns.
@@ -247,10 +262,13 @@ func (nb NsBuilder) Ns() *Ns {
 // staticNs is an empty namespace.
 type staticNs struct {
 	infos []staticVarInfo
+	// Index of the first variable declared in the innermost block being
+	// compiled; see (*compiler).block.
+	blockStart int
 }
 
 func (ns *staticNs) clone() *staticNs {
-	return &staticNs{append([]staticVarInfo(nil), ns.infos...)}
+	return &staticNs{infos: append([]staticVarInfo(nil), ns.infos...)}
 }
 
 func (ns *staticNs) del(k string) {
@@ -8,6 +8,7 @@ import (
 	"os"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"src.elv.sh/pkg/eval/errs"
 	"src.elv.sh/pkg/eval/vals"
@@ -16,10 +17,14 @@ import (
 
 // Port conveys data stream. It always consists of a byte band and a channel band.
 type Port struct {
-	File      *os.File
-	Chan      chan any
-	closeFile bool
-	closeChan bool
+	File *os.File
+	Chan chan any
+	// Releases the resources owned by the Port when it is closed, such as File
+	// and Chan if they were created for the Port. Only populated in the Port
+	// that owns the resources; forked Ports share File and Chan without owning
+	// them. Ownership can be transferred with takeOver. It is safe to call
+	// more than once; see newCloser.
+	closer func()
 
 	// The following two fields are populated as an additional control mechanism
 	// for output ports. When no more value should be send on Chan, sendError is
@@ -33,6 +38,14 @@ type Port struct {
 	// This is used to check if an external command killed by SIGPIPE is caused
 	// by the termination of the reader of the pipe.
 	readerGone *atomic.Bool
+
+	// Only populated in input ports reading from another command in a
+	// pipeline, or reading values from a file. Stops the writing end of the
+	// pipe from writing any more values, and closes the reading end of the
+	// byte pipe; or stops reading values from the file. It is called when
+	// the reader exits, or earlier if the reader doesn't need any more inputs.
+	// It is safe to call more than once.
+	stopWriter func()
 }
 
 // ErrPortDoesNotSupportValueOutput is thrown when writing to a port that does
@@ -45,22 +58,62 @@ var closedSendStop = make(chan struct{})
 
 func init() { close(closedSendStop) }
 
-// Returns a copy of the Port with the Close* flags unset.
+// Returns a copy of the Port that doesn't own any resources.
 func (p *Port) fork() *Port {
-	return &Port{p.File, p.Chan, false, false, p.sendStop, p.sendError, p.readerGone}
+	forked := p.forkValue()
+	return &forked
+}
+
+// Like fork, but returns a value instead of a pointer, so that the caller can
+// decide where to store it.
+func (p *Port) forkValue() Port {
+	return Port{p.File, p.Chan, nil, p.sendStop, p.sendError, p.readerGone, p.stopWriter}
 }
 
-// Closes a Port.
+// Closes a Port, releasing the resources it owns. Closing a Port more than
+// once, or closing a Port whose resources have been taken over by another
+// Port, is a no-op.
 func (p *Port) close() {
-	if p == nil {
-		return
+	if p != nil && p.closer != nil {
+		p.closer()
 	}
-	if p.closeFile {
-		p.File.Close()
+}
+
+// Makes p take over the resources owned by old, so that they are released
+// when p is closed, after the resources p already owns. Closing old afterwards
+// doesn't release them again.
+func (p *Port) takeOver(old *Port) {
+	if old == nil || old.closer == nil {
+		return
 	}
-	if p.closeChan {
-		close(p.Chan)
+	if p.closer == nil {
+		p.closer = old.closer
+	} else {
+		ownCloser, oldCloser := p.closer, old.closer
+		p.closer = sync.OnceFunc(func() {
+			ownCloser()
+			oldCloser()
+		})
 	}
+	old.closer = nil
+}
+
+// Returns a function that closes f and ch if they are not nil, and then calls
+// cleanup if it is not nil. Only the first call has any effect, so that the
+// resources are released exactly once even if the owning Port is closed again
+// while an exception is unwinding.
+func newCloser(f *os.File, ch chan any, cleanup func()) func() {
+	return sync.OnceFunc(func() {
+		if f != nil {
+			f.Close()
+		}
+		if ch != nil {
+			close(ch)
+		}
+		if cleanup != nil {
+			cleanup()
+		}
+	})
 }
 
 var (
@@ -131,7 +184,7 @@ func PipePort(vCb func(<-chan any), bCb func(*os.File)) (*Port, func(), error) {
 		bCb(r)
 	}()
 
-	port := &Port{Chan: ch, closeChan: true, File: w, closeFile: true}
+	port := &Port{Chan: ch, File: w, closer: newCloser(w, ch, nil)}
 	done := func() {
 		port.close()
 		wg.Wait()
@@ -272,6 +325,94 @@ func FilePort(f *os.File, valuePrefix string) (*Port, func()) {
 	}
 }
 
+// ReaderPort returns an input *Port whose byte component reads from r, and
+// whose value component is closed. It also returns a function to clean up the
+// port, which should be called when the *Port is no longer needed.
+//
+// If r is not an *os.File, its content is copied to the port in a separate
+// goroutine, which stops after r reaches EOF or the port is cleaned up. Once
+// the port is cleaned up, the goroutine doesn't start reading from r again. A
+// read that is already in progress can't be interrupted in general, but if r
+// has a SetReadDeadline method, like a net.Conn, it is used to interrupt the
+// read, and the deadline is cleared after the goroutine has stopped.
+func ReaderPort(r io.Reader) (*Port, func(), error) {
+	if f, ok := r.(*os.File); ok {
+		return &Port{File: f, Chan: ClosedChan}, func() {}, nil
+	}
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		defer pw.Close()
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := r.Read(buf)
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if n > 0 {
+				if _, err := pw.Write(buf[:n]); err != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return &Port{File: pr, Chan: ClosedChan}, func() {
+		close(stop)
+		// This makes any pending write to pw fail.
+		pr.Close()
+		if d, ok := r.(interface{ SetReadDeadline(time.Time) error }); ok {
+			if d.SetReadDeadline(time.Now()) == nil {
+				<-stopped
+				d.SetReadDeadline(time.Time{})
+			}
+		}
+	}, nil
+}
+
+// WriterPort returns an output *Port whose byte component writes to w, and
+// whose value component writes each value to w like [FilePort]. It also
+// returns a function to clean up the port and wait for all the output to be
+// written, which should be called when the *Port is no longer needed.
+func WriterPort(w io.Writer, valuePrefix string) (*Port, func(), error) {
+	var mu sync.Mutex
+	write := func(p []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Write(p)
+	}
+	return PipePort(
+		func(ch <-chan any) {
+			for v := range ch {
+				write([]byte(valuePrefix + vals.ReprPlain(v) + "\n"))
+			}
+		},
+		func(r *os.File) {
+			buf := make([]byte, 4096)
+			for {
+				n, err := r.Read(buf)
+				if n > 0 {
+					write(buf[:n])
+				}
+				if err != nil {
+					if err != io.EOF {
+						logger.Println("error on reading:", err)
+					}
+					break
+				}
+			}
+		})
+}
+
 // PortsFromStdFiles is a shorthand for calling PortsFromFiles with os.Stdin,
 // os.Stdout and os.Stderr.
 func PortsFromStdFiles(prefix string) ([]*Port, func()) {
@@ -339,6 +480,15 @@ func (bo byteOutput) WriteString(s string) (int, error) {
 	return n, convertReaderGone(err)
 }
 
+// ReadFrom implements io.ReaderFrom, so that io.Copy uses the ReadFrom method
+// of *os.File. When r is also backed by a file descriptor, this copies the
+// data within the kernel with splice or copy_file_range on Linux, instead of
+// copying it through a buffer in Elvish.
+func (bo byteOutput) ReadFrom(r io.Reader) (int64, error) {
+	n, err := bo.f.ReadFrom(r)
+	return n, convertReaderGone(err)
+}
+
 func convertReaderGone(err error) error {
 	if pathErr, ok := err.(*os.PathError); ok {
 		if pathErr.Err == epipe {
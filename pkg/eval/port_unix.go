@@ -2,6 +2,29 @@
 
 package eval
 
-import "syscall"
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"src.elv.sh/pkg/sys/eunix"
+)
 
 var epipe = syscall.EPIPE
+
+// Waits until f is ready to be read or the deadline has passed, and returns
+// whether f is ready. Files that never block reads, like regular files, are
+// always ready.
+func waitForInput(f *os.File, deadline time.Time) (bool, error) {
+	for {
+		ready, err := eunix.WaitForRead(max(deadline.Sub(timeNow()), 0), f)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		return ready[0], nil
+	}
+}
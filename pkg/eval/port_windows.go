@@ -1,6 +1,10 @@
 package eval
 
-import "syscall"
+import (
+	"os"
+	"syscall"
+	"time"
+)
 
 // Error number 232 is what Windows returns when trying to write on a pipe who
 // reader has gone. The syscall package defines an EPIPE on Windows, but that's
@@ -8,3 +12,8 @@ import "syscall"
 //
 // https://docs.microsoft.com/en-us/windows/win32/debug/system-error-codes--0-499-
 var epipe = syscall.Errno(232)
+
+// Reading with a timeout is not supported on Windows.
+func waitForInput(*os.File, time.Time) (bool, error) {
+	return false, errNotSupportedOnWindows
+}
@@ -0,0 +1,29 @@
+package eval
+
+import "golang.org/x/sys/unix"
+
+// Lists processes with the kern.proc.all sysctl. The command line arguments
+// are not available this way.
+func listProcesses() ([]processInfo, error) {
+	kprocs, err := unix.SysctlKinfoProcSlice("kern.proc.all")
+	if err != nil {
+		return nil, err
+	}
+	procs := make([]processInfo, len(kprocs))
+	for i, kp := range kprocs {
+		procs[i] = processInfo{
+			pid:  int(kp.Proc.P_pid),
+			ppid: int(kp.Eproc.Ppid),
+			name: unix.ByteSliceToString(kp.Proc.P_comm[:]),
+		}
+	}
+	return procs, nil
+}
+
+// Value of P_stat for zombie processes, from sys/proc.h.
+const sZomb = 5
+
+func isZombie(pid int) bool {
+	kp, err := unix.SysctlKinfoProc("kern.proc.pid", pid)
+	return err == nil && kp.Proc.P_stat == sZomb
+}
@@ -0,0 +1,75 @@
+package eval
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Lists processes by reading /proc.
+func listProcesses() ([]processInfo, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	var procs []processInfo
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		stat, err := readProcStat(pid)
+		if err != nil {
+			// The process may have exited.
+			continue
+		}
+		p := processInfo{pid: pid, ppid: stat.ppid, name: stat.comm}
+		if cmdline, err := os.ReadFile("/proc/" + entry.Name() + "/cmdline"); err == nil && len(cmdline) > 0 {
+			p.args = strings.Split(string(bytes.TrimSuffix(cmdline, []byte{0})), "\x00")
+		}
+		procs = append(procs, p)
+	}
+	return procs, nil
+}
+
+func isZombie(pid int) bool {
+	stat, err := readProcStat(pid)
+	return err == nil && stat.state == 'Z'
+}
+
+type procStat struct {
+	comm  string
+	state byte
+	ppid  int
+}
+
+var errBadProcStat = errors.New("bad /proc/[pid]/stat")
+
+// Parses the first fields of /proc/[pid]/stat, which look like:
+//
+//	pid (comm) state ppid ...
+//
+// Since comm may contain spaces and parentheses, it extends to the last ")".
+func readProcStat(pid int) (procStat, error) {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return procStat{}, err
+	}
+	s := string(data)
+	i := strings.IndexByte(s, '(')
+	j := strings.LastIndexByte(s, ')')
+	if i == -1 || j < i {
+		return procStat{}, errBadProcStat
+	}
+	fields := strings.Fields(s[j+1:])
+	if len(fields) < 2 || len(fields[0]) != 1 {
+		return procStat{}, errBadProcStat
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return procStat{}, errBadProcStat
+	}
+	return procStat{s[i+1 : j], fields[0][0], ppid}, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !windows
+
+package eval
+
+import "errors"
+
+var errListProcessesNotSupported = errors.New("listing processes is not supported on this platform")
+
+func listProcesses() ([]processInfo, error) {
+	return nil, errListProcessesNotSupported
+}
+
+func isZombie(pid int) bool { return false }
@@ -0,0 +1,32 @@
+package eval
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Lists processes with a Toolhelp snapshot. The command line arguments are
+// not available this way.
+func listProcesses() ([]processInfo, error) {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(snapshot)
+
+	var procs []processInfo
+	var entry windows.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	for err = windows.Process32First(snapshot, &entry); err == nil; err = windows.Process32Next(snapshot, &entry) {
+		procs = append(procs, processInfo{
+			pid:  int(entry.ProcessID),
+			ppid: int(entry.ParentProcessID),
+			name: windows.UTF16ToString(entry.ExeFile[:]),
+		})
+	}
+	if err != windows.ERROR_NO_MORE_FILES {
+		return nil, err
+	}
+	return procs, nil
+}
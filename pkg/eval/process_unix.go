@@ -3,6 +3,7 @@
 package eval
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"syscall"
@@ -25,6 +26,88 @@ func putSelfInFg() error {
 	return eunix.Tcsetpgrp(0, syscall.Getpgrp())
 }
 
-func makeSysProcAttr(bg bool) *syscall.SysProcAttr {
-	return &syscall.SysProcAttr{Setpgid: bg}
+func startProcess(fm *Frame, path string, args []string, files []*os.File) (*os.Process, error) {
+	j := fm.job
+	if j == nil {
+		return os.StartProcess(path, args, &os.ProcAttr{
+			Files: files, Sys: &syscall.SysProcAttr{Setpgid: fm.background}})
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	// Only take over the terminal when the command reads from the terminal
+	// Elvish is in the foreground of; the child then calls tcsetpgrp before
+	// exec, so there is no window in which it could receive SIGTTIN.
+	fg := files[0] != nil && ownsTerminal(files[0])
+	attr := &syscall.SysProcAttr{Setpgid: true, Pgid: j.pgid, Foreground: fg}
+	proc, err := os.StartProcess(path, args, &os.ProcAttr{Files: files, Sys: attr})
+	if err != nil && j.pgid != 0 {
+		// The process group no longer exists if all the processes in it have
+		// exited; start a new one instead.
+		attr.Pgid = 0
+		proc, err = os.StartProcess(path, args, &os.ProcAttr{Files: files, Sys: attr})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if attr.Pgid == 0 {
+		j.pgid = proc.Pid
+	}
+	if fg {
+		j.tookTerminal = true
+	}
+	return proc, nil
+}
+
+// Reports whether f is a terminal whose foreground process group is that of
+// Elvish.
+func ownsTerminal(f *os.File) bool {
+	if !sys.IsATTY(f.Fd()) {
+		return false
+	}
+	pgid, err := eunix.Tcgetpgrp(int(f.Fd()))
+	return err == nil && pgid == syscall.Getpgrp()
+}
+
+// Kills all the processes in the job.
+func (j *job) kill() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.pgid != 0 {
+		syscall.Kill(-j.pgid, syscall.SIGKILL)
+	}
+}
+
+// Forwards interrupts received by Elvish to the process group of the job,
+// until the returned function is called. This is needed when the job doesn't
+// have the terminal, in which case the terminal delivers signals to Elvish
+// instead.
+func (j *job) forwardInterrupts(ctx context.Context) func() {
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			j.mu.Lock()
+			pgid := j.pgid
+			j.mu.Unlock()
+			if pgid != 0 {
+				syscall.Kill(-pgid, syscall.SIGINT)
+			}
+		case <-stop:
+		}
+	}()
+	return func() { close(stop) }
+}
+
+// HangUpBgJobs sends SIGHUP to the background jobs that are running, like the
+// terminal does to the foreground job when it is closed. Jobs that have been
+// detached are no longer in the job table and don't get the signal.
+func (ev *Evaler) HangUpBgJobs() {
+	for _, j := range ev.runningBgJobs() {
+		for _, pid := range j.runningPids() {
+			// Each external command of a background job is started in its own
+			// process group.
+			syscall.Kill(-pid, syscall.SIGHUP)
+		}
+	}
 }
@@ -1,6 +1,10 @@
 package eval
 
-import "syscall"
+import (
+	"context"
+	"os"
+	"syscall"
+)
 
 // Nop on Windows.
 func putSelfInFg() error { return nil }
@@ -8,10 +12,29 @@ func putSelfInFg() error { return nil }
 // The bitmask for CreationFlags in SysProcAttr to start a process in background.
 const detachedProcess = 0x00000008
 
-func makeSysProcAttr(bg bool) *syscall.SysProcAttr {
+func startProcess(fm *Frame, path string, args []string, files []*os.File) (*os.Process, error) {
 	flags := uint32(0)
-	if bg {
+	if fm.background {
 		flags |= detachedProcess
 	}
-	return &syscall.SysProcAttr{CreationFlags: flags}
+	proc, err := os.StartProcess(path, args, &os.ProcAttr{
+		Files: files, Sys: &syscall.SysProcAttr{CreationFlags: flags}})
+	if err == nil && fm.job != nil {
+		fm.job.mu.Lock()
+		fm.job.procs = append(fm.job.procs, proc)
+		fm.job.mu.Unlock()
+	}
+	return proc, err
 }
+
+// Kills all the processes in the job.
+func (j *job) kill() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, proc := range j.procs {
+		proc.Kill()
+	}
+}
+
+// Nop on Windows, which doesn't have process groups in the Unix sense.
+func (j *job) forwardInterrupts(context.Context) func() { return func() {} }
@@ -0,0 +1,54 @@
+package eval
+
+import (
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/eval/vars"
+)
+
+// SecurityError is thrown when code evaluated by an Evaler in restricted mode
+// attempts an operation that is not allowed.
+type SecurityError struct {
+	// Description of the operation, like "running external command ls".
+	Op string
+}
+
+var _ vals.PseudoMap = SecurityError{}
+
+func (e SecurityError) Error() string {
+	return "not allowed in restricted mode: " + e.Op
+}
+
+func (e SecurityError) Kind() string           { return "security-error" }
+func (e SecurityError) Fields() vals.StructMap { return securityErrorFields{e} }
+
+type securityErrorFields struct{ e SecurityError }
+
+func (securityErrorFields) IsStructMap() {}
+
+func (f securityErrorFields) Type() string { return "security" }
+func (f securityErrorFields) Op() string   { return f.e.Op }
+
+// CheckRestricted returns a SecurityError for the operation if the Evaler is
+// in restricted mode, and nil otherwise. Builtins that run external commands,
+// write to the filesystem, mutate environment variables or access the network
+// must call this before doing so.
+func (ev *Evaler) CheckRestricted(op string) error {
+	if ev.Restricted {
+		return SecurityError{op}
+	}
+	return nil
+}
+
+// A variable whose Set method is checked with CheckRestricted.
+type restrictedVar struct {
+	vars.Var
+	ev *Evaler
+	op string
+}
+
+func (v restrictedVar) Set(val any) error {
+	if err := v.ev.CheckRestricted(v.op); err != nil {
+		return err
+	}
+	return v.Var.Set(val)
+}
@@ -0,0 +1,289 @@
+package eval
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+	"runtime"
+	"sync"
+
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/strutil"
+)
+
+// Holds captured values, keeping them in memory until they exceed a size
+// threshold and writing the rest to a temporary file.
+//
+// Strings, and other values that can be read back from their reprs, are
+// written to the file; values that can't, like functions, are kept in memory.
+// The file is removed as soon as it is created, so that it doesn't outlive
+// Elvish. On systems where open files can't be removed, it is instead removed
+// when the spilledCapture is closed or garbage-collected, or by
+// Evaler.PreExit.
+type spilledCapture struct {
+	ev        *Evaler
+	threshold int
+
+	mu      sync.Mutex
+	size    int
+	mem     []any
+	others  []any
+	file    *os.File
+	w       *bufio.Writer
+	fileLen int64
+	err     error
+}
+
+// Tags of records in the temporary file.
+const (
+	spillString byte = 's'
+	spillRepr   byte = 'r'
+	spillOther  byte = 'o'
+)
+
+// Size accounted for each value that is not a string.
+const spillOtherSize = 16
+
+// Total size of the values an output capture keeps in memory before spilling
+// the rest to a temporary file. A variable for testing.
+var spillThreshold = 16 << 20
+
+func newSpilledCapture(ev *Evaler, threshold int) *spilledCapture {
+	return &spilledCapture{ev: ev, threshold: threshold}
+}
+
+func (c *spilledCapture) add(v any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	s, isString := v.(string)
+	if c.file == nil {
+		if isString {
+			c.size += len(s)
+		} else {
+			c.size += spillOtherSize
+		}
+		if c.size <= c.threshold {
+			c.mem = append(c.mem, v)
+			return
+		}
+		c.err = c.createFile()
+		if c.err != nil {
+			return
+		}
+	}
+	switch {
+	case isString:
+		c.writeRecord(spillString, s)
+	case reprReadable(v):
+		c.writeRecord(spillRepr, vals.ReprPlain(v))
+	default:
+		var buf [binary.MaxVarintLen64 + 1]byte
+		buf[0] = spillOther
+		n := binary.PutUvarint(buf[1:], uint64(len(c.others)))
+		c.write(buf[:n+1])
+		c.others = append(c.others, v)
+	}
+}
+
+func (c *spilledCapture) writeRecord(tag byte, s string) {
+	var buf [binary.MaxVarintLen64 + 1]byte
+	buf[0] = tag
+	n := binary.PutUvarint(buf[1:], uint64(len(s)))
+	c.write(buf[:n+1])
+	c.write([]byte(s))
+}
+
+// Reports whether v can be read back from its repr with ParseRepr.
+func reprReadable(v any) bool {
+	switch v := v.(type) {
+	case nil, bool, string, int, *big.Int, *big.Rat, float64:
+		return true
+	case vals.List:
+		for it := v.Iterator(); it.HasElem(); it.Next() {
+			if !reprReadable(it.Elem()) {
+				return false
+			}
+		}
+		return true
+	case vals.Map:
+		for it := v.Iterator(); it.HasElem(); it.Next() {
+			k, v := it.Elem()
+			if !reprReadable(k) || !reprReadable(v) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (c *spilledCapture) createFile() error {
+	f, err := os.CreateTemp("", "elvish-capture-*")
+	if err != nil {
+		return err
+	}
+	c.file, c.w = f, bufio.NewWriter(f)
+	if os.Remove(f.Name()) != nil {
+		// Windows doesn't allow removing open files.
+		c.ev.addSpillFile(f.Name())
+		runtime.SetFinalizer(c, (*spilledCapture).remove)
+	}
+	return nil
+}
+
+func (c *spilledCapture) write(p []byte) {
+	if c.err == nil {
+		_, c.err = c.w.Write(p)
+	}
+}
+
+// Finishes writing the temporary file, and returns any error during capture.
+func (c *spilledCapture) finish() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.w != nil && c.err == nil {
+		c.err = c.w.Flush()
+		c.fileLen, _ = c.file.Seek(0, io.SeekCurrent)
+	}
+	if c.err != nil && c.file != nil {
+		c.remove()
+	}
+	return c.err
+}
+
+func (c *spilledCapture) remove() {
+	c.file.Close()
+	if c.ev.forgetSpillFile(c.file.Name()) {
+		os.Remove(c.file.Name())
+	}
+}
+
+// Releases the temporary file. The spilledCapture can no longer be iterated
+// after that.
+func (c *spilledCapture) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file != nil && c.err == nil {
+		c.remove()
+		runtime.SetFinalizer(c, nil)
+	}
+}
+
+// Calls f with each captured value until it returns false, reading the values
+// that have been written to the temporary file lazily.
+func (c *spilledCapture) iterate(f func(any) bool) error {
+	for _, v := range c.mem {
+		if !f(v) {
+			return nil
+		}
+	}
+	if c.file == nil {
+		return nil
+	}
+	// Keep c alive, so that the file is not removed during the iteration.
+	defer runtime.KeepAlive(c)
+	r := bufio.NewReader(io.NewSectionReader(c.file, 0, c.fileLen))
+	for {
+		v, err := c.read(r)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !f(v) {
+			return nil
+		}
+	}
+}
+
+var errBadSpillRecord = errors.New("bad record in spill file")
+
+func (c *spilledCapture) read(r *bufio.Reader) (any, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case spillString, spillRepr:
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		if tag == spillRepr {
+			return ParseRepr(string(buf))
+		}
+		return string(buf), nil
+	case spillOther:
+		if n >= uint64(len(c.others)) {
+			return nil, errBadSpillRecord
+		}
+		return c.others[n], nil
+	default:
+		return nil, errBadSpillRecord
+	}
+}
+
+// Records a temporary file that could not be removed while open, so that it
+// is removed by PreExit if it is still around.
+func (ev *Evaler) addSpillFile(name string) {
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	ev.spillFiles[name] = struct{}{}
+}
+
+// Forgets a temporary file recorded with addSpillFile, and reports whether it
+// was still recorded.
+func (ev *Evaler) forgetSpillFile(name string) bool {
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	_, ok := ev.spillFiles[name]
+	delete(ev.spillFiles, name)
+	return ok
+}
+
+// Removes the temporary files recorded with addSpillFile. The files may still
+// be open, so this is only done when Elvish is about to exit.
+func (ev *Evaler) removeSpillFiles() {
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	for name := range ev.spillFiles {
+		os.Remove(name)
+		delete(ev.spillFiles, name)
+	}
+}
+
+// Returns an output port whose value and byte components are saved in a
+// spilledCapture, with bytes saved one string value per line.
+func spillCapturePort(c *spilledCapture) (*Port, func(), error) {
+	return PipePort(
+		func(ch <-chan any) {
+			for v := range ch {
+				c.add(v)
+			}
+		},
+		func(r *os.File) {
+			buffered := bufio.NewReader(r)
+			for {
+				line, err := buffered.ReadString('\n')
+				if line != "" {
+					c.add(strutil.ChopLineEnding(line))
+				}
+				if err != nil {
+					if err != io.EOF {
+						logger.Println("error on reading:", err)
+					}
+					break
+				}
+			}
+		})
+}
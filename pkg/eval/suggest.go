@@ -0,0 +1,126 @@
+package eval
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"src.elv.sh/pkg/fsutil"
+)
+
+// Maximum number of suggestions to include in an error.
+const maxSuggestions = 3
+
+// An error with suggestions of what the user might have meant, like names
+// of commands when a command is not found.
+type suggestionError struct {
+	error
+	suggestions []string
+}
+
+func (e suggestionError) Error() string {
+	return e.error.Error() + " (did you mean " + strings.Join(e.suggestions, ", ") + "?)"
+}
+
+func (e suggestionError) Unwrap() error { return e.error }
+
+// Returns err with the suggestions added, or err itself if there are no
+// suggestions.
+func withSuggestions(err error, suggestions []string) error {
+	if len(suggestions) == 0 {
+		return err
+	}
+	return suggestionError{err, suggestions}
+}
+
+// Returns the names of external commands and functions visible from fm that
+// are close to name.
+func suggestCommands(fm *Frame, name string) []string {
+	return suggest(name, func(f func(string)) {
+		fsutil.EachExternal(f)
+		for _, ns := range []*Ns{fm.local, fm.up, fm.Evaler.Builtin()} {
+			ns.IterateKeysString(func(k string) {
+				if strings.HasSuffix(k, FnSuffix) {
+					f(strings.TrimSuffix(k, FnSuffix))
+				}
+			})
+		}
+	})
+}
+
+// Returns the directories next to dir whose names are close to dir's. This
+// only makes sense if dir doesn't exist.
+func suggestSiblingDirs(dir string) []string {
+	parent, base := filepath.Split(filepath.Clean(dir))
+	entries, err := os.ReadDir(filepath.Join(parent, "."))
+	if err != nil {
+		return nil
+	}
+	suggestions := suggest(base, func(f func(string)) {
+		for _, entry := range entries {
+			if entry.IsDir() {
+				f(entry.Name())
+			}
+		}
+	})
+	for i, name := range suggestions {
+		suggestions[i] = parent + name
+	}
+	return suggestions
+}
+
+// Calls eachCandidate to enumerate candidates, and returns the ones close to
+// name, from the closest to the least close.
+func suggest(name string, eachCandidate func(func(string))) []string {
+	maxDist := max(1, len([]rune(name))/3)
+	dists := make(map[string]int)
+	eachCandidate(func(c string) {
+		if _, seen := dists[c]; seen || c == name {
+			return
+		}
+		if d := editDistance(name, c); d <= maxDist {
+			dists[c] = d
+		}
+	})
+	candidates := make([]string, 0, len(dists))
+	for c := range dists {
+		candidates = append(candidates, c)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		ci, cj := candidates[i], candidates[j]
+		return dists[ci] < dists[cj] || dists[ci] == dists[cj] && ci < cj
+	})
+	if len(candidates) > maxSuggestions {
+		candidates = candidates[:maxSuggestions]
+	}
+	return candidates
+}
+
+// Returns the optimal string alignment distance between a and b, which is
+// the Levenshtein distance extended with transpositions of adjacent runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// d[i][j] is the distance between ra[:i] and rb[:j].
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
@@ -0,0 +1,26 @@
+package eval
+
+import "testing"
+
+var editDistanceTests = []struct {
+	a, b string
+	want int
+}{
+	{"", "", 0},
+	{"abc", "", 3},
+	{"", "abc", 3},
+	{"git", "git", 0},
+	{"gti", "git", 1},
+	{"gt", "git", 1},
+	{"gitt", "git", 1},
+	{"kitten", "sitting", 3},
+	{"你好", "好你", 1},
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, test := range editDistanceTests {
+		if got := editDistance(test.a, test.b); got != test.want {
+			t.Errorf("editDistance(%q, %q) -> %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
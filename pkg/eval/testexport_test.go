@@ -2,13 +2,20 @@ package eval
 
 // Pointers to variables that can be mutated for testing.
 var (
-	GetHome       = &getHome
-	Getwd         = &getwd
-	OSExit        = &osExit
-	TimeAfter     = &timeAfter
-	TimeNow       = &timeNow
-	NextEvalCount = &nextEvalCount
+	GetHome        = &getHome
+	OpenTTY        = &openTTY
+	Getwd          = &getwd
+	OSExit         = &osExit
+	TimeAfter      = &timeAfter
+	TimeNow        = &timeNow
+	NextEvalCount  = &nextEvalCount
+	RandFloat64    = &randFloat64
+	SpillThreshold = &spillThreshold
+
+	ExtModuleTimeout = &extModuleTimeout
 
 	ExceptionCauseStartMarker = &exceptionCauseStartMarker
 	ExceptionCauseEndMarker   = &exceptionCauseEndMarker
 )
+
+var ProcessExists = processExists
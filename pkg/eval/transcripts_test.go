@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -34,6 +36,7 @@ func TestTranscripts(t *testing.T) {
 			ev.ExtendGlobal(eval.BuildNs().
 				AddGoFn("recv-bg-job-notification", func() any { return <-noteCh }))
 		},
+		"restricted", func(ev *eval.Evaler) { ev.Restricted = true },
 		"with-temp-home", func(t *testing.T) { testutil.TempHome(t) },
 		"reseed-afterwards", func(t *testing.T) {
 			t.Cleanup(func() {
@@ -76,6 +79,11 @@ func TestTranscripts(t *testing.T) {
 			ev.ExtendGlobal(eval.BuildNs().
 				AddVar("lib", vars.NewReadOnly(libdir)))
 		},
+		"tmp-rc-file", func(t *testing.T, ev *eval.Evaler) {
+			rc := filepath.Join(testutil.TempDir(t), "rc.elv")
+			ev.EffectiveRcPath = rc
+			ev.ExtendGlobal(eval.BuildNs().AddVar("rc", vars.NewReadOnly(rc)))
+		},
 		"two-tmp-lib-dirs", func(t *testing.T, ev *eval.Evaler) {
 			libdir1 := testutil.TempDir(t)
 			libdir2 := testutil.TempDir(t)
@@ -94,6 +102,12 @@ func TestTranscripts(t *testing.T) {
 			ev.ExtendGlobal(eval.BuildNs().
 				AddVar("test-time-scale", vars.NewReadOnly(testutil.TestTimeScale())))
 		},
+		"fail-if-tty-opened", func(t *testing.T) {
+			testutil.Set(t, eval.OpenTTY, func() (*os.File, error) {
+				t.Error("the terminal is opened")
+				return nil, errors.New("no tty")
+			})
+		},
 		"mock-get-home-error", func(t *testing.T, msg string) {
 			err := errors.New(msg)
 			testutil.Set(t, eval.GetHome,
@@ -113,6 +127,16 @@ func TestTranscripts(t *testing.T) {
 					return time.After(0)
 				})
 		},
+		"ext-module-timeout", func(t *testing.T, arg string) {
+			testutil.Set(t, eval.ExtModuleTimeout, must.OK1(time.ParseDuration(arg)))
+		},
+		"spill-threshold", func(t *testing.T, arg string) {
+			testutil.Set(t, eval.SpillThreshold, must.OK1(strconv.Atoi(arg)))
+		},
+		"mock-rand-float64", func(t *testing.T, arg string) {
+			f := must.OK1(strconv.ParseFloat(arg, 64))
+			testutil.Set(t, eval.RandFloat64, func() float64 { return f })
+		},
 		"mock-benchmark-run-durations", func(t *testing.T, arg string) {
 			// The benchmark command calls time.Now once before a run and once
 			// after a run.
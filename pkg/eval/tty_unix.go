@@ -0,0 +1,55 @@
+//go:build unix
+
+package eval
+
+import (
+	"os"
+
+	"src.elv.sh/pkg/sys/eunix"
+)
+
+// Reference to a function that opens the controlling terminal, which can be
+// overridden in tests.
+var openTTY = func() (*os.File, error) {
+	return os.OpenFile("/dev/tty", os.O_RDWR, 0)
+}
+
+// Changes the attributes of f if it is a terminal, so that input is read a
+// character at a time if charMode is true, and not echoed if noEcho is true.
+// Returns whether f is a terminal, and a function that restores the original
+// attributes.
+//
+// The attributes are changed via SyscallConn instead of calling f.Fd, since
+// the latter puts f in blocking mode, which makes read deadlines stop working.
+func setTTYMode(f *os.File, charMode, noEcho bool) (bool, func(), error) {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return false, func() {}, nil
+	}
+	var saved *eunix.Termios
+	var errApply error
+	conn.Control(func(fd uintptr) {
+		term, err := eunix.TermiosForFd(int(fd))
+		if err != nil {
+			// Not a terminal.
+			return
+		}
+		saved = term.Copy()
+		if charMode {
+			term.SetICanon(false)
+			term.SetVMin(1)
+			term.SetVTime(0)
+		}
+		if noEcho {
+			term.SetEcho(false)
+		}
+		errApply = term.ApplyToFd(int(fd))
+	})
+	if saved == nil {
+		return false, func() {}, nil
+	}
+	restore := func() {
+		conn.Control(func(fd uintptr) { saved.ApplyToFd(int(fd)) })
+	}
+	return true, restore, errApply
+}
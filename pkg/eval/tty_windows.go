@@ -0,0 +1,35 @@
+package eval
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// Reference to a function that opens the console, which can be overridden in
+// tests. The console uses different files for input and output, so it is not
+// supported; commands fall back to using their ports.
+var openTTY = func() (*os.File, error) {
+	return nil, errNotSupportedOnWindows
+}
+
+// Changes the mode of f if it is a console, so that input is read a character
+// at a time if charMode is true, and not echoed if noEcho is true. Returns
+// whether f is a console, and a function that restores the original mode.
+func setTTYMode(f *os.File, charMode, noEcho bool) (bool, func(), error) {
+	h := windows.Handle(f.Fd())
+	var saved uint32
+	if windows.GetConsoleMode(h, &saved) != nil {
+		return false, func() {}, nil
+	}
+	mode := saved
+	if charMode {
+		// Echoing requires line input, so it is turned off too.
+		mode &^= windows.ENABLE_LINE_INPUT | windows.ENABLE_ECHO_INPUT
+	}
+	if noEcho {
+		mode &^= windows.ENABLE_ECHO_INPUT
+	}
+	restore := func() { windows.SetConsoleMode(h, saved) }
+	return true, restore, windows.SetConsoleMode(h, mode)
+}
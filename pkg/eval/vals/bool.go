@@ -7,8 +7,8 @@ type Booler interface {
 }
 
 // Bool converts a value to bool. It is implemented for nil, the builtin bool
-// type, and types implementing the Booler interface. For all other values, it
-// returns true.
+// type, and types implementing the Booler interface. For all other values,
+// including "empty" ones like "", 0 and empty lists, it returns true.
 func Bool(v any) bool {
 	switch v := v.(type) {
 	case nil:
@@ -1,6 +1,7 @@
 package vals
 
 import (
+	"math/big"
 	"testing"
 
 	"src.elv.sh/pkg/tt"
@@ -19,6 +20,14 @@ func TestBool(t *testing.T) {
 		Args(true).Rets(true),
 		Args(false).Rets(false),
 
+		// "Empty" values are true.
+		Args("").Rets(true),
+		Args(0).Rets(true),
+		Args(0.0).Rets(true),
+		Args(big.NewInt(0)).Rets(true),
+		Args(EmptyList).Rets(true),
+		Args(EmptyMap).Rets(true),
+
 		Args(customBooler{true}).Rets(true),
 		Args(customBooler{false}).Rets(false),
 
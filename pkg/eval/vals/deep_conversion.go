@@ -0,0 +1,396 @@
+package vals
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"src.elv.sh/pkg/eval/errs"
+)
+
+// Deep conversion between Go values and Elvish values.
+//
+// Unlike FromGo and ScanToGo, which only convert scalar values and otherwise
+// require the Go type to be an Elvish type already, FromGoDeep and
+// ScanToGoDeep use reflection to convert composite Go values - slices,
+// arrays, maps, structs and pointers - element by element. This makes it
+// possible to expose Go data structures to Elvish without writing
+// marshaling code by hand.
+
+var (
+	bigIntType    = reflect.TypeOf((*big.Int)(nil))
+	bigRatType    = reflect.TypeOf((*big.Rat)(nil))
+	fileType      = reflect.TypeOf(File(nil))
+	listType      = reflect.TypeOf((*List)(nil)).Elem()
+	mapType       = reflect.TypeOf((*Map)(nil)).Elem()
+	structMapType = reflect.TypeOf((*StructMap)(nil)).Elem()
+	pseudoMapType = reflect.TypeOf((*PseudoMap)(nil)).Elem()
+	kinderType    = reflect.TypeOf((*Kinder)(nil)).Elem()
+)
+
+// Returns whether values of the type are already Elvish values, and only need
+// to be converted with FromGo.
+func isElvishType(t reflect.Type) bool {
+	switch t {
+	case reflect.TypeOf(""), reflect.TypeOf(false), reflect.TypeOf(0),
+		reflect.TypeOf(0.0), reflect.TypeOf(' '),
+		bigIntType, bigRatType, fileType:
+		return true
+	}
+	return t.Implements(listType) || t.Implements(mapType) ||
+		t.Implements(structMapType) || t.Implements(pseudoMapType) ||
+		t.Implements(kinderType) ||
+		(CallableType != nil && t.Implements(CallableType))
+}
+
+// FromGoDeep converts a Go value to an Elvish value, converting composite
+// values recursively:
+//
+//   - Values that are already Elvish values are converted with FromGo.
+//
+//   - Integers of all sizes become exact integers, and float32 values become
+//     float64 values. Values of named types whose underlying type is string or
+//     bool become strings or booleans.
+//
+//   - Byte slices become strings; other slices and arrays become lists.
+//
+//   - Maps become maps.
+//
+//   - Structs become maps, with the names of exported fields converted to keys
+//     with CamelToDashed. Unexported fields are omitted.
+//
+//   - Pointers and interfaces are replaced by the values they point to or
+//     contain, with nil becoming $nil.
+//
+// Values of other types (like functions and channels) are returned unchanged.
+//
+// It returns an error if the value contains a cycle, like a struct with a
+// field that points back to the struct itself.
+func FromGoDeep(a any) (any, error) {
+	c := deepConverter{make(map[deepVisit]bool)}
+	return c.fromGo(reflect.ValueOf(a))
+}
+
+var errCyclicValue = errors.New("cannot convert a value that contains a cycle")
+
+// Keeps track of the pointers, maps and slices being converted, in order to
+// detect cycles.
+type deepConverter struct {
+	visiting map[deepVisit]bool
+}
+
+// Identifies a pointer, map or slice. Slices with the same pointer but
+// different lengths are different values.
+type deepVisit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// Marks v as being converted, returning a function that unmarks it, or an
+// error if v is already being converted.
+func (c deepConverter) visit(v reflect.Value) (func(), error) {
+	key := deepVisit{v.Pointer(), v.Type(), 0}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if c.visiting[key] {
+		return nil, errCyclicValue
+	}
+	c.visiting[key] = true
+	return func() { delete(c.visiting, key) }, nil
+}
+
+func (c deepConverter) fromGo(v reflect.Value) (any, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		return c.fromGo(v.Elem())
+	}
+	t := v.Type()
+	if isElvishType(t) {
+		return FromGo(v.Interface()), nil
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil, nil
+		}
+		done, err := c.visit(v)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+		return c.fromGo(v.Elem())
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NormalizeBigInt(big.NewInt(v.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return NormalizeBigInt(new(big.Int).SetUint64(v.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), nil
+		}
+		if v.Len() > 0 {
+			done, err := c.visit(v)
+			if err != nil {
+				return nil, err
+			}
+			defer done()
+		}
+		fallthrough
+	case reflect.Array:
+		l := EmptyList
+		for i := 0; i < v.Len(); i++ {
+			elem, err := c.fromGo(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			l = l.Conj(elem)
+		}
+		return l, nil
+	case reflect.Map:
+		if v.Len() > 0 {
+			done, err := c.visit(v)
+			if err != nil {
+				return nil, err
+			}
+			defer done()
+		}
+		m := EmptyMap
+		for it := v.MapRange(); it.Next(); {
+			key, err := c.fromGo(it.Key())
+			if err != nil {
+				return nil, err
+			}
+			value, err := c.fromGo(it.Value())
+			if err != nil {
+				return nil, err
+			}
+			m = m.Assoc(key, value)
+		}
+		return m, nil
+	case reflect.Struct:
+		m := EmptyMap
+		keys, _ := StructFieldsInfo(t)
+		for i, key := range keys {
+			if key == "" {
+				continue
+			}
+			value, err := c.fromGo(v.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			m = m.Assoc(key, value)
+		}
+		return m, nil
+	default:
+		return v.Interface(), nil
+	}
+}
+
+// ScanToGoDeep converts an Elvish value, and stores it in the destination of
+// ptr, which must be a pointer. It is the reverse of FromGoDeep:
+//
+//   - Integer destinations of all sizes accept numbers and strings that can
+//     be parsed as integers, and report an error if the value is out of range.
+//     Floating-point destinations accept any number.
+//
+//   - Slice destinations accept lists, and byte slices also accept strings.
+//     Array destinations accept lists of the same length.
+//
+//   - Map destinations accept maps.
+//
+//   - Struct destinations accept maps, with the same rules as ScanMapToGo.
+//
+//   - Pointer destinations accept $nil, which becomes a nil pointer, or any
+//     value accepted by the type being pointed to.
+//
+// Destinations of all other types are handled with ScanToGo.
+func ScanToGoDeep(src any, ptr any) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("internal bug: need pointer to scan to, got %T", ptr)
+	}
+	return scanToGoDeep(src, v.Elem())
+}
+
+func scanToGoDeep(src any, dst reflect.Value) error {
+	t := dst.Type()
+	switch t {
+	case reflect.TypeOf(0), reflect.TypeOf(0.0), reflect.TypeOf(' '),
+		reflect.TypeOf((*Num)(nil)).Elem():
+		return ScanToGo(src, dst.Addr().Interface())
+	}
+	if src != nil && TypeOf(src).AssignableTo(t) {
+		dst.Set(ValueOf(src))
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		b, ok := src.(bool)
+		if !ok {
+			return WrongType{"bool", Kind(src)}
+		}
+		dst.SetBool(b)
+	case reflect.String:
+		s, ok := src.(string)
+		if !ok {
+			return WrongType{"string", Kind(src)}
+		}
+		dst.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := elvToBigInt(src)
+		if err != nil {
+			return err
+		}
+		if !i.IsInt64() || dst.OverflowInt(i.Int64()) {
+			return outOfRange(t, i)
+		}
+		dst.SetInt(i.Int64())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, err := elvToBigInt(src)
+		if err != nil {
+			return err
+		}
+		if !i.IsUint64() || dst.OverflowUint(i.Uint64()) {
+			return outOfRange(t, i)
+		}
+		dst.SetUint(i.Uint64())
+	case reflect.Float32, reflect.Float64:
+		n, err := elvToNum(src)
+		if err != nil {
+			return err
+		}
+		dst.SetFloat(ConvertToFloat64(n))
+	case reflect.Pointer:
+		if src == nil {
+			dst.Set(reflect.Zero(t))
+			return nil
+		}
+		p := reflect.New(t.Elem())
+		if err := scanToGoDeep(src, p.Elem()); err != nil {
+			return err
+		}
+		dst.Set(p)
+	case reflect.Slice:
+		if s, ok := src.(string); ok && t.Elem().Kind() == reflect.Uint8 {
+			dst.SetBytes([]byte(s))
+			return nil
+		}
+		l, ok := src.(List)
+		if !ok {
+			return WrongType{"list", Kind(src)}
+		}
+		s := reflect.MakeSlice(t, l.Len(), l.Len())
+		if err := scanListElements(l, s); err != nil {
+			return err
+		}
+		dst.Set(s)
+	case reflect.Array:
+		l, ok := src.(List)
+		if !ok {
+			return WrongType{"list", Kind(src)}
+		}
+		if l.Len() != t.Len() {
+			return errs.ArityMismatch{What: "list elements",
+				ValidLow: t.Len(), ValidHigh: t.Len(), Actual: l.Len()}
+		}
+		a := reflect.New(t).Elem()
+		if err := scanListElements(l, a); err != nil {
+			return err
+		}
+		dst.Set(a)
+	case reflect.Map:
+		m, ok := src.(Map)
+		if !ok {
+			return WrongType{"map", Kind(src)}
+		}
+		gm := reflect.MakeMapWithSize(t, m.Len())
+		for it := m.Iterator(); it.HasElem(); it.Next() {
+			k, v := it.Elem()
+			gk := reflect.New(t.Key()).Elem()
+			if err := scanToGoDeep(k, gk); err != nil {
+				return err
+			}
+			gv := reflect.New(t.Elem()).Elem()
+			if err := scanToGoDeep(v, gv); err != nil {
+				return err
+			}
+			gm.SetMapIndex(gk, gv)
+		}
+		dst.Set(gm)
+	case reflect.Struct:
+		if Kind(src) != "map" {
+			return WrongType{"map", Kind(src)}
+		}
+		keys, _ := StructFieldsInfo(t)
+		for i, key := range keys {
+			if key == "" {
+				continue
+			}
+			v, err := Index(src, key)
+			if err != nil {
+				continue
+			}
+			if err := scanToGoDeep(v, dst.Field(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return ScanToGo(src, dst.Addr().Interface())
+	}
+	return nil
+}
+
+func scanListElements(l List, dst reflect.Value) error {
+	i := 0
+	for it := l.Iterator(); it.HasElem(); it.Next() {
+		if err := scanToGoDeep(it.Elem(), dst.Index(i)); err != nil {
+			return err
+		}
+		i++
+	}
+	return nil
+}
+
+func elvToBigInt(arg any) (*big.Int, error) {
+	n, err := elvToNum(arg)
+	if err != nil {
+		return nil, err
+	}
+	switch n := n.(type) {
+	case int:
+		return big.NewInt(int64(n)), nil
+	case *big.Int:
+		return n, nil
+	default:
+		return nil, errMustBeInteger
+	}
+}
+
+func outOfRange(t reflect.Type, i *big.Int) error {
+	var low, high big.Int
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bits := uint(t.Bits())
+		high.Lsh(big.NewInt(1), bits-1)
+		low.Neg(&high)
+		high.Sub(&high, big.NewInt(1))
+	default:
+		high.Lsh(big.NewInt(1), uint(t.Bits()))
+		high.Sub(&high, big.NewInt(1))
+	}
+	return errs.OutOfRange{What: "integer",
+		ValidLow: low.String(), ValidHigh: high.String(), Actual: i.String()}
+}
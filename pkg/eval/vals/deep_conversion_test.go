@@ -0,0 +1,137 @@
+package vals
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"src.elv.sh/pkg/eval/errs"
+	"src.elv.sh/pkg/tt"
+)
+
+type deepStruct struct {
+	Name    string
+	Age     uint8
+	Tags    []string
+	Scores  map[string]float32
+	Parent  *deepStruct
+	private int
+}
+
+// Equal is required by cmp.Diff, since deepStruct contains unexported fields.
+func (a deepStruct) Equal(b deepStruct) bool { return reflect.DeepEqual(a, b) }
+
+type myString string
+
+func TestFromGoDeep(t *testing.T) {
+	tt.Test(t, FromGoDeep,
+		// Elvish values
+		Args("foo").Rets("foo"),
+		Args(12).Rets(12),
+		Args(1.5).Rets(1.5),
+		Args('x').Rets("x"),
+		Args(big.NewInt(10)).Rets(10),
+		Args(nil).Rets(nil),
+		Args(MakeList("foo")).Rets(eq(MakeList("foo"))),
+
+		// Scalar values
+		Args(int8(-3)).Rets(-3),
+		Args(uint64(1<<63)).Rets(bigInt("9223372036854775808")),
+		Args(float32(0.5)).Rets(0.5),
+		Args(myString("foo")).Rets("foo"),
+
+		// Composite values
+		Args([]byte("foo")).Rets("foo"),
+		Args([]int16{1, 2}).Rets(eq(MakeList(1, 2))),
+		Args([2]string{"a", "b"}).Rets(eq(MakeList("a", "b"))),
+		Args(map[string]uint{"a": 1}).Rets(eq(MakeMap("a", 1))),
+		Args(&deepStruct{
+			Name: "foo", Age: 10, Tags: []string{"x"},
+			Scores: map[string]float32{"a": 0.5}, private: 1,
+		}).Rets(eq(MakeMap(
+			"name", "foo", "age", 10, "tags", MakeList("x"),
+			"scores", MakeMap("a", 0.5), "parent", nil))),
+		Args((*deepStruct)(nil)).Rets(nil),
+		Args([]any{1, nil}).Rets(eq(MakeList(1, nil))),
+	)
+}
+
+func TestFromGoDeep_Cycles(t *testing.T) {
+	cyclicPtr := &deepStruct{Name: "foo"}
+	cyclicPtr.Parent = cyclicPtr
+	cyclicSlice := []any{nil}
+	cyclicSlice[0] = cyclicSlice
+	cyclicMap := map[string]any{}
+	cyclicMap["m"] = cyclicMap
+	shared := &deepStruct{Name: "foo"}
+
+	tt.Test(t, FromGoDeep,
+		Args(cyclicPtr).Rets(nil, errCyclicValue),
+		Args(cyclicSlice).Rets(nil, errCyclicValue),
+		Args(cyclicMap).Rets(nil, errCyclicValue),
+		// Values that appear more than once without forming a cycle are fine.
+		Args([]*deepStruct{shared, shared}).Rets(eq(MakeList(
+			MakeMap("name", "foo", "age", 0, "tags", EmptyList,
+				"scores", EmptyMap, "parent", nil),
+			MakeMap("name", "foo", "age", 0, "tags", EmptyList,
+				"scores", EmptyMap, "parent", nil)))),
+	)
+}
+
+func TestScanToGoDeep(t *testing.T) {
+	// A wrapper around ScanToGoDeep, like the one used in
+	// TestScanToGo_ConcreteTypeDst.
+	scanToGoDeep := func(src any, dstInit any) (any, error) {
+		ptr := reflect.New(TypeOf(dstInit))
+		err := ScanToGoDeep(src, ptr.Interface())
+		return ptr.Elem().Interface(), err
+	}
+
+	tt.Test(t, tt.Fn(scanToGoDeep).Named("scanToGoDeep"),
+		// Scalar values
+		Args("12", 0).Rets(12),
+		Args("12", int8(0)).Rets(int8(12)),
+		Args(bigInt("9223372036854775808"), uint64(0)).Rets(uint64(1<<63)),
+		Args(0.5, float32(0)).Rets(float32(0.5)),
+		Args("foo", myString("")).Rets(myString("foo")),
+		Args("foo", []byte(nil)).Rets([]byte("foo")),
+
+		Args("300", uint8(0)).Rets(uint8(0), errs.OutOfRange{What: "integer",
+			ValidLow: "0", ValidHigh: "255", Actual: "300"}),
+		Args("-129", int8(0)).Rets(int8(0), errs.OutOfRange{What: "integer",
+			ValidLow: "-128", ValidHigh: "127", Actual: "-129"}),
+		Args(0.5, int16(0)).Rets(int16(0), errMustBeInteger),
+		Args(1, myString("")).Rets(myString(""), WrongType{"string", "number"}),
+
+		// Composite values
+		Args(MakeList("1", 2), []uint{}).Rets([]uint{1, 2}),
+		Args(MakeList("a", "b"), [2]string{}).Rets([2]string{"a", "b"}),
+		Args(MakeMap("a", "1"), map[string]int8{}).Rets(map[string]int8{"a": 1}),
+		Args(MakeMap(
+			"name", "foo", "age", "10", "tags", MakeList("x"),
+			"scores", MakeMap("a", 0.5), "parent", MakeMap("name", "bar")),
+			deepStruct{}).
+			Rets(deepStruct{
+				Name: "foo", Age: 10, Tags: []string{"x"},
+				Scores: map[string]float32{"a": 0.5},
+				Parent: &deepStruct{Name: "bar"},
+			}),
+		Args(nil, (*int)(nil)).Rets((*int)(nil)),
+
+		Args("x", []int{}).Rets([]int(nil), WrongType{"list", "string"}),
+		Args(MakeList("a"), [2]string{}).Rets([2]string{},
+			errs.ArityMismatch{What: "list elements",
+				ValidLow: 2, ValidHigh: 2, Actual: 1}),
+		Args(MakeList("a"), map[string]int{}).Rets(map[string]int(nil),
+			WrongType{"map", "list"}),
+		Args(MakeMap("tags", "x"), deepStruct{}).Rets(deepStruct{},
+			WrongType{"list", "string"}),
+	)
+}
+
+func TestScanToGoDeep_ErrorsWithNonPointerDst(t *testing.T) {
+	err := ScanToGoDeep("", 1)
+	if err == nil {
+		t.Errorf("did not return error")
+	}
+}
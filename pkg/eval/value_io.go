@@ -0,0 +1,278 @@
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/strutil"
+)
+
+// This file implements serializing value streams to files and reading them
+// back, used by value redirections and the from-repr builtin.
+
+// A format for serializing values to files, one value per line.
+type valueFormat int
+
+const (
+	// Each value is written with vals.ReprPlain and read back with ParseRepr.
+	reprFormat valueFormat = iota
+	// Each value is written as JSON, like to-json, and read back like
+	// from-json.
+	jsonFormat
+)
+
+// Returns the format to use for a file, determined by its extension.
+func valueFormatFor(name string) valueFormat {
+	if strings.HasSuffix(name, ".jsonl") || strings.HasSuffix(name, ".ndjson") {
+		return jsonFormat
+	}
+	return reprFormat
+}
+
+// Writes a value to w in the given format.
+func writeValue(w io.Writer, format valueFormat, v any) error {
+	if format == jsonFormat {
+		return json.NewEncoder(w).Encode(v)
+	}
+	_, err := io.WriteString(w, vals.ReprPlain(v)+"\n")
+	return err
+}
+
+// Calls f with each value read from r in the given format.
+func readValues(r io.Reader, format valueFormat, f func(any) error) error {
+	if format == jsonFormat {
+		dec := json.NewDecoder(r)
+		dec.UseNumber()
+		for {
+			var v any
+			err := dec.Decode(&v)
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			converted, err := fromJSONInterface(v)
+			if err != nil {
+				return err
+			}
+			if err := f(converted); err != nil {
+				return err
+			}
+		}
+	}
+	return readReprLines(r, f)
+}
+
+// Calls f with the value on each non-empty line of r, which must be a repr of
+// a value.
+func readReprLines(r io.Reader, f func(any) error) error {
+	buffered := bufio.NewReader(r)
+	for lineno := 1; ; lineno++ {
+		line, errRead := buffered.ReadString('\n')
+		line = strutil.ChopLineEnding(line)
+		if strings.TrimSpace(line) != "" {
+			v, err := ParseRepr(line)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", lineno, err)
+			}
+			if err := f(v); err != nil {
+				return err
+			}
+		}
+		if errRead == io.EOF {
+			return nil
+		} else if errRead != nil {
+			return errRead
+		}
+	}
+}
+
+var errNotRepr = errors.New("not a repr of a string, number, boolean, nil, list or map")
+
+// ParseRepr parses the output of vals.ReprPlain back to a value. Only strings,
+// numbers, booleans, nil, and lists and maps of them are supported. The code is
+// never evaluated, so it is safe to call on untrusted input.
+func ParseRepr(code string) (any, error) {
+	tree, err := parse.Parse(parse.Source{Name: "[repr]", Code: code}, parse.Config{})
+	if err != nil {
+		return nil, err
+	}
+	form, ok := singleForm(tree.Root)
+	if !ok || form.Head == nil || len(form.Args) > 0 || len(form.Opts) > 0 ||
+		len(form.Redirs) > 0 {
+		return nil, errNotRepr
+	}
+	return reprValue(form.Head)
+}
+
+// Returns the only form in a chunk.
+func singleForm(n *parse.Chunk) (*parse.Form, bool) {
+	if len(n.Pipelines) != 1 || len(n.Pipelines[0].Forms) != 1 ||
+		n.Pipelines[0].Background {
+		return nil, false
+	}
+	return n.Pipelines[0].Forms[0], true
+}
+
+func reprValue(n *parse.Compound) (any, error) {
+	if len(n.Indexings) != 1 || len(n.Indexings[0].Indices) > 0 {
+		return nil, errNotRepr
+	}
+	p := n.Indexings[0].Head
+	switch p.Type {
+	case parse.Bareword, parse.SingleQuoted, parse.DoubleQuoted:
+		return p.Value, nil
+	case parse.Variable:
+		switch p.Value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "nil":
+			return nil, nil
+		}
+	case parse.List:
+		list := vals.EmptyList
+		for _, elem := range p.Elements {
+			v, err := reprValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			list = list.Conj(v)
+		}
+		return list, nil
+	case parse.Map:
+		m := vals.EmptyMap
+		for _, pair := range p.MapPairs {
+			if pair.Value == nil {
+				return nil, errNotRepr
+			}
+			k, err := reprValue(pair.Key)
+			if err != nil {
+				return nil, err
+			}
+			v, err := reprValue(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			m = m.Assoc(k, v)
+		}
+		return m, nil
+	case parse.OutputCapture:
+		// Numbers are represented like (num 1).
+		form, ok := singleForm(p.Chunk)
+		if !ok || form.Head == nil || len(form.Args) != 1 {
+			break
+		}
+		head, err := reprValue(form.Head)
+		if err != nil || head != "num" {
+			break
+		}
+		arg, err := reprValue(form.Args[0])
+		if s, ok := arg.(string); ok && err == nil {
+			if num := vals.ParseNum(s); num != nil {
+				return num, nil
+			}
+		}
+	}
+	return nil, errNotRepr
+}
+
+// Returns an output port that writes value outputs to f in the given format,
+// and shares the byte component with old, which it takes over. The returned
+// port closes f after all the values have been written when it is closed.
+func valueWriterPort(old *Port, f *os.File, format valueFormat) *Port {
+	ch := make(chan any, filePortChanSize)
+	relayDone := make(chan struct{})
+	go func() {
+		defer close(relayDone)
+		w := bufio.NewWriter(f)
+		var err error
+		for v := range ch {
+			if err == nil {
+				err = writeValue(w, format, v)
+			}
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			logger.Println("error writing values to file:", err)
+		}
+	}()
+	p := &Port{Chan: ch, closer: newCloser(nil, ch, func() {
+		<-relayDone
+		f.Close()
+	})}
+	takeOverFile(p, old)
+	return p
+}
+
+var errStopReading = errors.New("stop reading")
+
+// Returns an input port whose value component reads values from f in the given
+// format as they are consumed, and shares the byte component with old, which it
+// takes over. The returned function stops reading, closes f and returns any
+// error encountered while reading; it is also called when the port is closed.
+func valueReaderPort(old *Port, f *os.File, format valueFormat) (*Port, func() error) {
+	ch := make(chan any)
+	stop := make(chan struct{})
+	relayDone := make(chan struct{})
+	var err error
+	go func() {
+		defer close(relayDone)
+		defer close(ch)
+		err = readValues(f, format, func(v any) error {
+			select {
+			case ch <- v:
+				return nil
+			case <-stop:
+				return errStopReading
+			}
+		})
+		select {
+		case <-stop:
+			// Errors after stopping, including those caused by closing f, are
+			// not interesting.
+			err = nil
+		default:
+		}
+	}()
+	stopReading := sync.OnceFunc(func() { close(stop) })
+	finish := sync.OnceValue(func() error {
+		stopReading()
+		// Closing f also interrupts a read blocked on a pipe.
+		f.Close()
+		<-relayDone
+		return err
+	})
+	p := &Port{Chan: ch, closer: newCloser(nil, nil, func() { finish() })}
+	p.stopWriter = stopReading
+	if old != nil && old.stopWriter != nil {
+		p.stopWriter = func() {
+			stopReading()
+			old.stopWriter()
+		}
+	}
+	takeOverFile(p, old)
+	return p, finish
+}
+
+// Makes p use the byte component of old, and take over the responsibility of
+// closing old when p is closed.
+func takeOverFile(p, old *Port) {
+	if old == nil {
+		p.File = DevNull
+		return
+	}
+	p.File = old.File
+	p.takeOver(old)
+}
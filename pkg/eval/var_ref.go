@@ -88,12 +88,20 @@ func resolveVarRefBuiltin(s scopeSearcher, qname string, r diag.Ranger) *varRef
 	if rest != "" {
 		// Try special namespace first.
 		switch first {
-		case "e:":
+		case "e:", "external:":
 			if strings.HasSuffix(rest, FnSuffix) {
 				return &varRef{scope: externalScope, subNames: []string{rest[:len(rest)-1]}}
 			}
 		case "E:":
 			return &varRef{scope: envScope, subNames: []string{rest}}
+		case "builtin:":
+			// Makes builtins accessible even when shadowed, like in a
+			// function that wraps the builtin with the same name.
+			name, rest := SplitQName(rest)
+			if info, index := s.searchBuiltin(name, r); index != -1 {
+				return &varRef{builtinScope, info, index, SplitQNameSegs(rest)}
+			}
+			return nil
 		}
 	}
 	if info, index := s.searchBuiltin(first, r); index != -1 {
@@ -145,7 +153,12 @@ func derefBase(fm *Frame, ref *varRef) (vars.Var, []string) {
 	case builtinScope:
 		return fm.Evaler.Builtin().slots[ref.index], ref.subNames
 	case envScope:
-		return vars.FromEnv(ref.subNames[0]), nil
+		name := ref.subNames[0]
+		if fm.Evaler.Restricted {
+			return restrictedVar{vars.FromEnv(name), fm.Evaler,
+				"setting environment variable " + name}, nil
+		}
+		return vars.FromEnv(name), nil
 	case externalScope:
 		return vars.NewReadOnly(NewExternalCmd(ref.subNames[0])), nil
 	default:
@@ -45,5 +45,5 @@ func EachExternal(f func(string)) {
 }
 
 func searchPaths() []string {
-	return strings.Split(os.Getenv(env.PATH), string(filepath.ListSeparator))
+	return filepath.SplitList(os.Getenv(env.PATH))
 }
@@ -4,6 +4,8 @@ package glob
 import (
 	"os"
 	"runtime"
+	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -48,7 +50,24 @@ func (p Pattern) Glob(cb func(PathInfo) bool) bool {
 		}
 	}
 
-	return glob(segs, dir, cb)
+	if p.MatchHidden {
+		segs = matchHidden(segs)
+	}
+	return glob(segs, dir, p.IgnoreCase, cb, nil)
+}
+
+// matchHidden returns a copy of segs, with MatchHidden set on all the Wild
+// segments.
+func matchHidden(segs []Segment) []Segment {
+	newSegs := make([]Segment, len(segs))
+	for i, seg := range segs {
+		if wild, ok := seg.(Wild); ok {
+			wild.MatchHidden = true
+			seg = wild
+		}
+		newSegs[i] = seg
+	}
+	return newSegs
 }
 
 // isLetter returns true if the byte is an ASCII letter.
@@ -61,17 +80,34 @@ func isDrive(s string) bool {
 	return len(s) == 2 && s[1] == ':' && isLetter(s[0])
 }
 
+// Reports whether a literal path element can be matched by following the path
+// instead of reading the directory: either the match is case-sensitive, or the
+// element is "." or "..", or the element has no letters whose case can differ.
+func canFollowLiteral(elem string, ignoreCase bool) bool {
+	return !ignoreCase || elem == "." || elem == ".." ||
+		strings.ToLower(elem) == strings.ToUpper(elem)
+}
+
 // glob finds all filenames matching the given Segments in the given dir, and
 // calls the callback on all of them. If the callback returns false, globbing is
 // interrupted, and glob returns false. Otherwise it returns true. Files that
 // can't be lstat'ed and directories that can't be read are ignored silently.
-func glob(segs []Segment, dir string, cb func(PathInfo) bool) bool {
+// If ignoreCase is true, Literal segments are matched case-insensitively
+// against names read from directories.
+//
+// If readAhead is not nil, it delivers the result of reading dir, which must be
+// needed by the Segments (see needsReadDir).
+func glob(segs []Segment, dir string, ignoreCase bool, cb func(PathInfo) bool, readAhead <-chan readDirResult) bool {
 	// Consume non-wildcard path elements simply by following the path. This may
 	// seem like an optimization, but is actually required for "." and ".." to
 	// be used as path elements, as they do not appear in the result of ReadDir.
 	// It is also required for handling directory components that are actually
 	// symbolic links to directories.
-	for len(segs) > 1 && IsLiteral(segs[0]) && IsSlash(segs[1]) {
+	//
+	// When ignoreCase is true, literals whose case matters are instead matched
+	// against the result of ReadDir below.
+	for len(segs) > 1 && IsLiteral(segs[0]) && IsSlash(segs[1]) &&
+		canFollowLiteral(segs[0].(Literal).Data, ignoreCase) {
 		elem := segs[0].(Literal).Data
 		segs = segs[2:]
 		dir += elem + "/"
@@ -89,7 +125,8 @@ func glob(segs []Segment, dir string, cb func(PathInfo) bool) bool {
 			return cb(PathInfo{dir, info})
 		}
 		return true
-	} else if len(segs) == 1 && IsLiteral(segs[0]) {
+	} else if len(segs) == 1 && IsLiteral(segs[0]) &&
+		canFollowLiteral(segs[0].(Literal).Data, ignoreCase) {
 		path := dir + segs[0].(Literal).Data
 		if info, err := os.Lstat(path); err == nil {
 			return cb(PathInfo{path, info})
@@ -97,7 +134,14 @@ func glob(segs []Segment, dir string, cb func(PathInfo) bool) bool {
 		return true
 	}
 
-	infos, err := readDir(dir)
+	var infos []os.DirEntry
+	var err error
+	if readAhead != nil {
+		result := <-readAhead
+		infos, err = result.entries, result.err
+	} else {
+		infos, err = readDir(dir)
+	}
 	if err != nil {
 		// Ignore directories that can't be read.
 		return true
@@ -146,14 +190,16 @@ func glob(segs []Segment, dir string, cb func(PathInfo) bool) bool {
 			first, rest = segs[:i+1], segs[i:]
 		}
 
+		var subdirs []string
 		for _, info := range infos {
 			name := info.Name()
-			if matchElement(first, name) && info.IsDir() {
-				if !glob(rest, dir+name+"/", cb) {
-					return false
-				}
+			if matchElement(first, name, ignoreCase) && info.IsDir() {
+				subdirs = append(subdirs, dir+name+"/")
 			}
 		}
+		if !globSubdirs(rest, subdirs, ignoreCase, cb) {
+			return false
+		}
 
 		if slash {
 			// First slash cannot appear later than a slash in the pattern.
@@ -166,7 +212,7 @@ func glob(segs []Segment, dir string, cb func(PathInfo) bool) bool {
 	// the entire pattern with all files.
 	for _, info := range infos {
 		name := info.Name()
-		if matchElement(segs, name) {
+		if matchElement(segs, name, ignoreCase) {
 			fullname := dir + name
 			info, err := os.Lstat(fullname)
 			if err != nil {
@@ -185,6 +231,67 @@ func glob(segs []Segment, dir string, cb func(PathInfo) bool) bool {
 	return true
 }
 
+// Maximum number of subdirectories of a directory that are read ahead of the
+// traversal.
+const readAheadDirs = 8
+
+// Limits the number of directories that are read concurrently, across all the
+// glob expansions.
+var readDirSem = make(chan struct{}, 4*runtime.GOMAXPROCS(0))
+
+type readDirResult struct {
+	entries []os.DirEntry
+	err     error
+}
+
+// Calls glob on each of the subdirectories in turn. If the Segments need
+// reading the subdirectories, the subdirectories following the one being
+// traversed are read concurrently, so that the traversal doesn't have to wait
+// for reading each of them. The results are still passed to the callback in
+// the same order as a sequential traversal.
+func globSubdirs(segs []Segment, subdirs []string, ignoreCase bool, cb func(PathInfo) bool) bool {
+	if len(subdirs) < 2 || !needsReadDir(segs) {
+		for _, subdir := range subdirs {
+			if !glob(segs, subdir, ignoreCase, cb, nil) {
+				return false
+			}
+		}
+		return true
+	}
+	readAheads := make([]<-chan readDirResult, len(subdirs))
+	for i, subdir := range subdirs {
+		for j := i + 1; j < len(subdirs) && j <= i+readAheadDirs; j++ {
+			if readAheads[j] == nil {
+				readAheads[j] = readDirAsync(subdirs[j])
+			}
+		}
+		if !glob(segs, subdir, ignoreCase, cb, readAheads[i]) {
+			// Directories already being read are simply discarded.
+			return false
+		}
+	}
+	return true
+}
+
+// Reports whether glob will read the directory it is called with, as opposed
+// to following literal path elements or calling lstat.
+func needsReadDir(segs []Segment) bool {
+	return len(segs) > 0 &&
+		!(len(segs) > 1 && IsLiteral(segs[0]) && IsSlash(segs[1])) &&
+		!(len(segs) == 1 && IsLiteral(segs[0]))
+}
+
+func readDirAsync(dir string) <-chan readDirResult {
+	ch := make(chan readDirResult, 1)
+	go func() {
+		readDirSem <- struct{}{}
+		entries, err := readDir(dir)
+		<-readDirSem
+		ch <- readDirResult{entries, err}
+	}()
+	return ch
+}
+
 // readDir is just like os.ReadDir except that it treats an argument of "" as ".".
 func readDir(dir string) ([]os.DirEntry, error) {
 	if dir == "" {
@@ -195,7 +302,7 @@ func readDir(dir string) ([]os.DirEntry, error) {
 
 // matchElement matches a path element against segments, which may not contain
 // any Slash segments. It treats StarStar segments as they are Star segments.
-func matchElement(segs []Segment, name string) bool {
+func matchElement(segs []Segment, name string, ignoreCase bool) bool {
 	if len(segs) == 0 {
 		return name == ""
 	}
@@ -229,7 +336,7 @@ segs:
 
 		// Match at the current position. If this is the last chunk, we need to
 		// make sure name is exhausted by the matching.
-		ok, rest := matchFixedLength(chunk, name)
+		ok, rest := matchFixedLength(chunk, name, ignoreCase)
 		if ok && (rest == "" || len(segs) > 0) {
 			name = rest
 			continue
@@ -245,7 +352,7 @@ segs:
 				if !startingStar.Match(r) {
 					break
 				}
-				ok, rest := matchFixedLength(chunk, name[j:])
+				ok, rest := matchFixedLength(chunk, name[j:], ignoreCase)
 				if ok && (rest == "" || len(segs) > 0) {
 					name = rest
 					continue segs
@@ -261,18 +368,18 @@ segs:
 // matchFixedLength returns whether a run of fixed-length segments (Literal and
 // Question) matches a prefix of name. It returns whether the match is
 // successful and if it is, the remaining part of name.
-func matchFixedLength(segs []Segment, name string) (bool, string) {
+func matchFixedLength(segs []Segment, name string, ignoreCase bool) (bool, string) {
 	for _, seg := range segs {
 		if name == "" {
 			return false, ""
 		}
 		switch seg := seg.(type) {
 		case Literal:
-			n := len(seg.Data)
-			if len(name) < n || name[:n] != seg.Data {
+			rest, ok := matchLiteral(seg.Data, name, ignoreCase)
+			if !ok {
 				return false, ""
 			}
-			name = name[n:]
+			name = rest
 		case Wild:
 			if seg.Type == Question {
 				r, n := utf8.DecodeRuneInString(name)
@@ -289,3 +396,39 @@ func matchFixedLength(segs []Segment, name string) (bool, string) {
 	}
 	return true, name
 }
+
+// matchLiteral returns whether name starts with literal, and if it does, the
+// rest of name.
+func matchLiteral(literal, name string, ignoreCase bool) (string, bool) {
+	if !ignoreCase {
+		if strings.HasPrefix(name, literal) {
+			return name[len(literal):], true
+		}
+		return "", false
+	}
+	// The case-folded forms of a string may have different lengths in UTF-8,
+	// so compare rune by rune.
+	for _, r := range literal {
+		if name == "" {
+			return "", false
+		}
+		r2, n := utf8.DecodeRuneInString(name)
+		if !equalFoldRune(r, r2) {
+			return "", false
+		}
+		name = name[n:]
+	}
+	return name, true
+}
+
+func equalFoldRune(r1, r2 rune) bool {
+	if r1 == r2 {
+		return true
+	}
+	for r := unicode.SimpleFold(r1); r != r1; r = unicode.SimpleFold(r) {
+		if r == r2 {
+			return true
+		}
+	}
+	return false
+}
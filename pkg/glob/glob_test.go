@@ -1,6 +1,7 @@
 package glob
 
 import (
+	"fmt"
 	"os"
 	"reflect"
 	"runtime"
@@ -154,9 +155,105 @@ func TestGlob_InvalidUTF8InFilename(t *testing.T) {
 	}
 }
 
+func TestGlob_Options(t *testing.T) {
+	testutil.InTempDir(t)
+	testutil.ApplyDir(testutil.Dir{
+		"README": "", "readme.md": "", ".ReadMe": "", "other": "",
+		"d": testutil.Dir{"Read": "", ".read": ""},
+	})
+
+	ignoreCase := Parse("*/read*")
+	ignoreCase.IgnoreCase = true
+	if got, want := patternPaths(ignoreCase), []string{"d/Read"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	ignoreCase = Parse("read*")
+	ignoreCase.IgnoreCase = true
+	if got, want := patternPaths(ignoreCase), []string{"README", "readme.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Literal path elements are also matched case-insensitively.
+	for _, pattern := range []string{"*/read", "D/read", "D/*"} {
+		ignoreCase = Parse(pattern)
+		ignoreCase.IgnoreCase = true
+		if got, want := patternPaths(ignoreCase), []string{"d/Read"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", pattern, got, want)
+		}
+	}
+
+	both := Parse("**read*")
+	both.IgnoreCase, both.MatchHidden = true, true
+	wantBoth := []string{".ReadMe", "README", "d/.read", "d/Read", "readme.md"}
+	if got := patternPaths(both); !reflect.DeepEqual(got, wantBoth) {
+		t.Errorf("got %v, want %v", got, wantBoth)
+	}
+}
+
+func TestGlob_OrderWithReadAhead(t *testing.T) {
+	testutil.InTempDir(t)
+	// Enough subdirectories for some of them to be read ahead.
+	var want []string
+	for i := 0; i < 3*readAheadDirs; i++ {
+		name := fmt.Sprintf("d%02d", i)
+		testutil.ApplyDir(testutil.Dir{
+			name: testutil.Dir{"sub": testutil.Dir{"x": ""}, "x": ""}})
+		want = append(want, name+"/sub/x", name+"/x")
+	}
+
+	var got []string
+	Glob("*/**x", func(pathInfo PathInfo) bool {
+		got = append(got, pathInfo.Path)
+		return true
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Stopping early.
+	got = nil
+	Glob("*/**x", func(pathInfo PathInfo) bool {
+		got = append(got, pathInfo.Path)
+		return len(got) < 3
+	})
+	if !reflect.DeepEqual(got, want[:3]) {
+		t.Errorf("got %v, want %v", got, want[:3])
+	}
+}
+
+func TestGlob_PathInfo(t *testing.T) {
+	testutil.InTempDir(t)
+	testutil.ApplyDir(testutil.Dir{"d": testutil.Dir{}, "f": "content"})
+
+	infos := make(map[string]PathInfo)
+	Glob("*", func(pathInfo PathInfo) bool {
+		infos[pathInfo.Path] = pathInfo
+		return true
+	})
+	Glob("d/", func(pathInfo PathInfo) bool {
+		infos[pathInfo.Path] = pathInfo
+		return true
+	})
+
+	if info := infos["d"].Info; info == nil || !info.IsDir() {
+		t.Errorf("Info of d is %v, want dir", info)
+	}
+	if info := infos["d/"].Info; info == nil || !info.IsDir() {
+		t.Errorf("Info of d/ is %v, want dir", info)
+	}
+	if info := infos["f"].Info; info == nil || info.Size() != int64(len("content")) {
+		t.Errorf("Info of f is %v, want size %d", info, len("content"))
+	}
+}
+
 func globPaths(pattern string) []string {
+	return patternPaths(Parse(pattern))
+}
+
+func patternPaths(p Pattern) []string {
 	paths := []string{}
-	Glob(pattern, func(pathInfo PathInfo) bool {
+	p.Glob(func(pathInfo PathInfo) bool {
 		paths = append(paths, pathInfo.Path)
 		return true
 	})
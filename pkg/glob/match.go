@@ -0,0 +1,77 @@
+package glob
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Match returns whether path matches the pattern. Like in Glob, Star and
+// Question don't match "/", StarStar matches any string, and wildcards at the
+// start of a path element don't match a leading "." unless MatchHidden is
+// true.
+//
+// If the pattern doesn't contain any Slash, it is matched against the last
+// element of path instead of the whole path.
+//
+// The IgnoreCase and MatchHidden fields are respected; the DirOverride field
+// is ignored.
+func (p Pattern) Match(path string) bool {
+	segs := p.Segments
+	if p.MatchHidden {
+		segs = matchHidden(segs)
+	}
+	if !hasSlash(segs) {
+		path = path[strings.LastIndexByte(path, '/')+1:]
+	}
+	return matchPath(segs, path, true, p.IgnoreCase)
+}
+
+func hasSlash(segs []Segment) bool {
+	for _, seg := range segs {
+		if IsSlash(seg) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath matches path against segments. The atStart argument indicates
+// whether path starts a new path element.
+func matchPath(segs []Segment, path string, atStart, ignoreCase bool) bool {
+	if len(segs) == 0 {
+		return path == ""
+	}
+	switch seg := segs[0].(type) {
+	case Literal:
+		rest, ok := matchLiteral(seg.Data, path, ignoreCase)
+		return ok && matchPath(segs[1:], rest, false, ignoreCase)
+	case Slash:
+		if !strings.HasPrefix(path, "/") {
+			return false
+		}
+		return matchPath(segs[1:], strings.TrimLeft(path, "/"), true, ignoreCase)
+	case Wild:
+		if atStart && strings.HasPrefix(path, ".") && !seg.MatchHidden {
+			return false
+		}
+		if seg.Type == Question {
+			r, n := utf8.DecodeRuneInString(path)
+			return path != "" && r != '/' && seg.Match(r) &&
+				matchPath(segs[1:], path[n:], false, ignoreCase)
+		}
+		for i := 0; ; {
+			if matchPath(segs[1:], path[i:], atStart && i == 0, ignoreCase) {
+				return true
+			}
+			if i == len(path) {
+				return false
+			}
+			r, n := utf8.DecodeRuneInString(path[i:])
+			if (r == '/' && seg.Type == Star) || !seg.Match(r) {
+				return false
+			}
+			i += n
+		}
+	}
+	return false
+}
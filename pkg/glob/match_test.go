@@ -0,0 +1,61 @@
+package glob
+
+import "testing"
+
+var matchTests = []struct {
+	pattern string
+	path    string
+	want    bool
+}{
+	{"foo", "foo", true},
+	{"foo", "bar", false},
+	{"*.go", "foo.go", true},
+	{"*.go", "foo.c", false},
+	{"*_test.go", "foo_test.go", true},
+	{"*_test.go", "foo.go", false},
+	// Patterns without slashes match the last path element.
+	{"*_test.go", "a/b/foo_test.go", true},
+	{"a", "b/a", true},
+	// Patterns with slashes match the whole path.
+	{"a/*.go", "a/foo.go", true},
+	{"a/*.go", "b/foo.go", false},
+	{"a/*.go", "a/b/foo.go", false},
+	{"a/**.go", "a/b/foo.go", true},
+	{"**/x", "a/b/x", true},
+	{"a//b", "a/b", true},
+	{"a/b", "a//b", true},
+	// Question matches exactly one character, which can't be a slash.
+	{"?.go", "a.go", true},
+	{"?.go", "ab.go", false},
+	{"a?b/c", "a/b/c", false},
+	// Wildcards don't match leading dots.
+	{"*", ".x", false},
+	{"?x", ".x", false},
+	{".*", ".x", true},
+	{"a/*", "a/.x", false},
+}
+
+func TestPattern_Match(t *testing.T) {
+	for _, test := range matchTests {
+		got := Parse(test.pattern).Match(test.path)
+		if got != test.want {
+			t.Errorf("Parse(%q).Match(%q) -> %v, want %v",
+				test.pattern, test.path, got, test.want)
+		}
+	}
+}
+
+func TestPattern_Match_Options(t *testing.T) {
+	ignoreCase := Parse("*.GO")
+	ignoreCase.IgnoreCase = true
+	for _, path := range []string{"a.go", "a.Go", "a.GO"} {
+		if !ignoreCase.Match(path) {
+			t.Errorf("pattern with IgnoreCase doesn't match %q", path)
+		}
+	}
+	matchHidden := Parse("a/*")
+	matchHidden.MatchHidden = true
+	if !matchHidden.Match("a/.x") {
+		t.Errorf("pattern with MatchHidden doesn't match a/.x")
+	}
+}
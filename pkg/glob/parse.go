@@ -59,7 +59,7 @@ rune:
 			add(Literal{literal.String()})
 		}
 	}
-	return Pattern{segments, ""}
+	return Pattern{Segments: segments}
 }
 
 // TODO(xiaq): Contains duplicate code with parse/parser.go.
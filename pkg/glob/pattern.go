@@ -4,6 +4,13 @@ package glob
 type Pattern struct {
 	Segments    []Segment
 	DirOverride string
+	// If true, Literal segments and the path elements they are matched
+	// against are compared case-insensitively, except for path elements that
+	// don't contain any wildcards, which are used as is.
+	IgnoreCase bool
+	// If true, all Wild segments match a leading ".", as if they all had
+	// MatchHidden set.
+	MatchHidden bool
 }
 
 // Segment is the building block of Pattern.
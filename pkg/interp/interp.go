@@ -0,0 +1,152 @@
+// Package interp provides an API for embedding Elvish as a scripting language
+// in Go programs.
+//
+// It is a thin layer on top of [src.elv.sh/pkg/eval] that takes care of
+// setting up an [eval.Evaler] with the standard library, and running code
+// without a terminal. The underlying Evaler is accessible with
+// [(*Interp).Evaler] for functionalities not covered by this package.
+package interp
+
+import (
+	"context"
+	"io"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/mods"
+	"src.elv.sh/pkg/mods/store"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/store/storedefs"
+)
+
+// Options keeps configuration for creating an Interp.
+type Options struct {
+	// Arguments exposed as $args.
+	Args []string
+	// Directories to search libraries.
+	LibDirs []string
+	// If true, the standard library modules (like str: and math:) are not
+	// available.
+	NoStdlib bool
+	// If not nil, the store: module is available and uses this store.
+	Store storedefs.Store
+	// If true, the code is run in restricted mode, in which running external
+	// commands, writing to files and similar operations are not allowed. This
+	// is suitable for running untrusted code. See [eval.Evaler.Restricted].
+	Restricted bool
+}
+
+// Interp is an Elvish interpreter. It keeps state between evaluations of
+// different pieces of code, such as variables defined in the global namespace.
+// It is safe to use concurrently.
+type Interp struct {
+	ev *eval.Evaler
+}
+
+// New creates a new Interp.
+func New(opts Options) *Interp {
+	ev := eval.NewEvaler()
+	ev.Args = vals.MakeListSlice(opts.Args)
+	ev.LibDirs = opts.LibDirs
+	ev.Restricted = opts.Restricted
+	if !opts.NoStdlib {
+		mods.AddTo(ev)
+	}
+	if opts.Store != nil {
+		ev.AddModule("store", store.Ns(opts.Store))
+	}
+	return &Interp{ev}
+}
+
+// Evaler returns the underlying Evaler.
+func (in *Interp) Evaler() *eval.Evaler { return in.ev }
+
+// IO specifies the standard input and outputs of code run by an Interp.
+//
+// A nil Stdin is equivalent to an empty input, and a nil Stdout or Stderr
+// causes the corresponding output to be discarded. Values written to Stdout
+// and Stderr are written on separate lines, like in the terminal.
+type IO struct {
+	Stdin          io.Reader
+	Stdout, Stderr io.Writer
+}
+
+func (stdio IO) ports(valuePrefix string) ([]*eval.Port, func(), error) {
+	ports := make([]*eval.Port, 3)
+	var cleanups []func()
+	cleanup := func() {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+	}
+	if stdio.Stdin != nil {
+		port, done, err := eval.ReaderPort(stdio.Stdin)
+		if err != nil {
+			return nil, nil, err
+		}
+		ports[0] = port
+		cleanups = append(cleanups, done)
+	}
+	for i, w := range []io.Writer{stdio.Stdout, stdio.Stderr} {
+		if w == nil {
+			continue
+		}
+		port, done, err := eval.WriterPort(w, valuePrefix)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		ports[i+1] = port
+		cleanups = append(cleanups, done)
+	}
+	return ports, cleanup, nil
+}
+
+// Eval evaluates a piece of code. The name is used to identify the code in
+// error messages. The evaluation is interrupted when ctx is canceled.
+//
+// The returned error may be a parse error, compilation error or exception.
+func (in *Interp) Eval(ctx context.Context, name, code string, stdio IO) error {
+	ports, cleanup, err := stdio.ports(in.ev.ValuePrefix())
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	return in.ev.Eval(parse.Source{Name: name, Code: code},
+		eval.EvalCfg{Ports: ports, Interrupts: ctx})
+}
+
+// Call calls a function with the given arguments and options. The evaluation
+// is interrupted when ctx is canceled.
+func (in *Interp) Call(ctx context.Context, f eval.Callable, args []any, opts map[string]any, stdio IO) error {
+	ports, cleanup, err := stdio.ports(in.ev.ValuePrefix())
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	return in.ev.Call(f,
+		eval.CallCfg{Args: args, Opts: opts, From: "[interp]"},
+		eval.EvalCfg{Ports: ports, Interrupts: ctx})
+}
+
+// Capture evaluates a piece of code like Eval, and returns its value outputs.
+// Byte outputs are converted to string values, one per line.
+func (in *Interp) Capture(ctx context.Context, name, code string, stdin io.Reader) ([]any, error) {
+	ports := make([]*eval.Port, 3)
+	if stdin != nil {
+		port, done, err := eval.ReaderPort(stdin)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+		ports[0] = port
+	}
+	port, collect, err := eval.ValueCapturePort()
+	if err != nil {
+		return nil, err
+	}
+	ports[1] = port
+	err = in.ev.Eval(parse.Source{Name: name, Code: code},
+		eval.EvalCfg{Ports: ports, Interrupts: ctx})
+	return collect(), err
+}
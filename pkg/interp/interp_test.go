@@ -0,0 +1,144 @@
+package interp_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"src.elv.sh/pkg/eval"
+	. "src.elv.sh/pkg/interp"
+	"src.elv.sh/pkg/store"
+	"src.elv.sh/pkg/testutil"
+)
+
+func TestEval_IO(t *testing.T) {
+	in := New(Options{})
+	var stdout, stderr strings.Builder
+	err := in.Eval(context.Background(), "[test]",
+		"echo (slurp); echo bar >&2",
+		IO{Stdin: strings.NewReader("input"), Stdout: &stdout, Stderr: &stderr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "input\n"; got != want {
+		t.Errorf("got stdout %q, want %q", got, want)
+	}
+	if got, want := stderr.String(), "bar\n"; got != want {
+		t.Errorf("got stderr %q, want %q", got, want)
+	}
+}
+
+func TestEval_StopsReadingStdinAfterReturning(t *testing.T) {
+	in := New(Options{})
+	r, w := net.Pipe()
+	defer w.Close()
+	err := in.Eval(context.Background(), "[test]", "nop", IO{Stdin: r})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Data written after Eval returns can be read from r, rather than being
+	// consumed by a leftover goroutine. The sleeps make sure that such a
+	// goroutine would be reading from r before the data is written, and
+	// before this test starts reading.
+	time.Sleep(testutil.Scaled(10 * time.Millisecond))
+	go w.Write([]byte("later"))
+	time.Sleep(testutil.Scaled(10 * time.Millisecond))
+	r.SetReadDeadline(time.Now().Add(testutil.Scaled(time.Second)))
+	buf := make([]byte, 5)
+	n, err := r.Read(buf)
+	if got := string(buf[:n]); got != "later" || err != nil {
+		t.Errorf("got %q and error %v, want %q and no error", got, err, "later")
+	}
+}
+
+func TestEval_ValueOutput(t *testing.T) {
+	in := New(Options{})
+	var stdout strings.Builder
+	err := in.Eval(context.Background(), "[test]", "put foo [bar]", IO{Stdout: &stdout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "▶ foo\n▶ [bar]\n"; got != want {
+		t.Errorf("got stdout %q, want %q", got, want)
+	}
+}
+
+func TestEval_KeepsGlobalState(t *testing.T) {
+	in := New(Options{})
+	ctx := context.Background()
+	mustEval(t, in, "var x = foo")
+	got, err := in.Capture(ctx, "[test]", "put $x", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]any{"foo"}, got); diff != "" {
+		t.Errorf("output (-want +got):\n%s", diff)
+	}
+}
+
+func TestEval_Context(t *testing.T) {
+	in := New(Options{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := in.Eval(ctx, "[test]", "while $true { }", IO{})
+	if exc, ok := err.(eval.Exception); !ok || exc.Reason() != eval.ErrInterrupted {
+		t.Errorf("got error %v, want ErrInterrupted", err)
+	}
+}
+
+func TestCall(t *testing.T) {
+	in := New(Options{})
+	mustEval(t, in, "fn f {|a &b=x| echo $a $b }")
+	f, _ := in.Evaler().Global().Index("f~")
+	var stdout strings.Builder
+	err := in.Call(context.Background(), f.(eval.Callable),
+		[]any{"foo"}, map[string]any{"b": "bar"}, IO{Stdout: &stdout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "foo bar\n"; got != want {
+		t.Errorf("got stdout %q, want %q", got, want)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	in := New(Options{Args: []string{"a", "b"}})
+	got, _ := in.Capture(context.Background(), "[test]", "put $@args; use str; put (str:to-upper x)", nil)
+	if diff := cmp.Diff([]any{"a", "b", "X"}, got); diff != "" {
+		t.Errorf("output (-want +got):\n%s", diff)
+	}
+
+	in = New(Options{NoStdlib: true})
+	if err := in.Eval(context.Background(), "[test]", "use str", IO{}); err == nil {
+		t.Errorf("got nil error using str with NoStdlib, want non-nil")
+	}
+
+	in = New(Options{Store: store.MustTempStore(t)})
+	got, err := in.Capture(context.Background(), "[test]", "use store; store:add-cmd foo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]any{1}, got); diff != "" {
+		t.Errorf("output (-want +got):\n%s", diff)
+	}
+
+	in = New(Options{Restricted: true})
+	err = in.Eval(context.Background(), "[test]", "echo foo > out", IO{})
+	if exc, ok := err.(eval.Exception); !ok {
+		t.Errorf("got error %v writing to a file with Restricted, want exception", err)
+	} else if _, ok := exc.Reason().(eval.SecurityError); !ok {
+		t.Errorf("got reason %v writing to a file with Restricted, want SecurityError", exc.Reason())
+	}
+}
+
+func mustEval(t *testing.T, in *Interp, code string) {
+	t.Helper()
+	err := in.Eval(context.Background(), "[test]", code, IO{})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,38 +2,113 @@
 package logutil
 
 import (
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 )
 
+// Level is the severity of a log message.
+type Level int
+
+// Possible values of Level, from the least severe to the most severe.
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+var levelNames = [...]string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if 0 <= l && int(l) < len(levelNames) {
+		return levelNames[l]
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// ParseLevel parses the name of a Level, as returned by its String method.
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if s == name {
+			return Level(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q", s)
+}
+
 var (
+	mu  sync.Mutex
 	out = io.Discard
 	// If out is set by SetOutputFile, outFile is set and keeps the same value
 	// as out. Otherwise, outFile is nil.
 	outFile *os.File
-	loggers []*log.Logger
+	// The minimal level of messages that get written. Messages written with
+	// loggers obtained with GetLogger have level Debug.
+	minLevel = Debug
+	// Loggers indexed by subsystem names.
+	loggers = make(map[string][]*log.Logger)
+	// Subsystems that have been disabled.
+	disabled = make(map[string]bool)
 )
 
-// GetLogger gets a logger with a prefix.
+// GetLogger gets a logger with a prefix. The prefix should be the name of the
+// subsystem the logger is for, surrounded by brackets and followed by a space,
+// like "[eval] ".
+//
+// Messages written with the logger have level Debug, and are written only if
+// the minimal level is Debug and the subsystem has not been disabled.
 func GetLogger(prefix string) *log.Logger {
-	logger := log.New(out, prefix, log.LstdFlags)
-	loggers = append(loggers, logger)
+	mu.Lock()
+	defer mu.Unlock()
+	subsystem := subsystemOfPrefix(prefix)
+	logger := log.New(outFor(subsystem, Debug), prefix, log.LstdFlags)
+	loggers[subsystem] = append(loggers[subsystem], logger)
 	return logger
 }
 
+func subsystemOfPrefix(prefix string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(prefix), "["), "]")
+}
+
+// Returns the writer to use for a message with the given subsystem and level.
+// Must be called with mu held.
+func outFor(subsystem string, level Level) io.Writer {
+	if level < minLevel || disabled[subsystem] {
+		return io.Discard
+	}
+	return out
+}
+
+// Updates the output of all loggers. Must be called with mu held.
+func updateLoggers() {
+	for subsystem, ls := range loggers {
+		for _, logger := range ls {
+			logger.SetOutput(outFor(subsystem, Debug))
+		}
+	}
+}
+
 // SetOutput redirects the output of all loggers obtained with GetLogger to the
 // new io.Writer. If the old output was a file opened by SetOutputFile, it is
 // closed.
 func SetOutput(newout io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	setOutput(newout)
+}
+
+func setOutput(newout io.Writer) {
 	if outFile != nil {
 		outFile.Close()
 		outFile = nil
 	}
 	out = newout
-	for _, logger := range loggers {
-		logger.SetOutput(out)
-	}
+	updateLoggers()
 }
 
 // SetOutputFile redirects the output of all loggers obtained with GetLogger to
@@ -41,15 +116,77 @@ func SetOutput(newout io.Writer) {
 // closed. The new file is truncated. SetOutFile("") is equivalent to
 // SetOutput(io.Discard).
 func SetOutputFile(fname string) error {
+	mu.Lock()
+	defer mu.Unlock()
 	if fname == "" {
-		SetOutput(io.Discard)
+		setOutput(io.Discard)
 		return nil
 	}
 	file, err := os.OpenFile(fname, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
 	if err != nil {
 		return err
 	}
-	SetOutput(file)
+	setOutput(file)
 	outFile = file
 	return nil
 }
+
+// GetLevel returns the minimal level of messages that get written.
+func GetLevel() Level {
+	mu.Lock()
+	defer mu.Unlock()
+	return minLevel
+}
+
+// SetLevel sets the minimal level of messages that get written.
+func SetLevel(l Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	minLevel = l
+	updateLoggers()
+}
+
+// Subsystems returns the sorted names of all subsystems that have loggers
+// obtained with GetLogger.
+func Subsystems() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	names := make([]string, 0, len(loggers))
+	for name := range loggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetEnabled enables or disables messages from the named subsystem. All
+// subsystems are enabled by default.
+func SetEnabled(subsystem string, enabled bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	if enabled {
+		delete(disabled, subsystem)
+	} else {
+		disabled[subsystem] = true
+	}
+	updateLoggers()
+}
+
+// Enabled returns whether messages from the named subsystem are enabled.
+func Enabled(subsystem string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return !disabled[subsystem]
+}
+
+// Log writes a message with the given subsystem and level, subject to the same
+// rules as messages written with loggers obtained with GetLogger.
+func Log(subsystem string, level Level, msg string) {
+	mu.Lock()
+	defer mu.Unlock()
+	w := outFor(subsystem, level)
+	if w == io.Discard {
+		return
+	}
+	log.New(w, "["+subsystem+"] ", log.LstdFlags).Printf("%s: %s", level, msg)
+}
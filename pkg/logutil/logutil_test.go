@@ -1,9 +1,11 @@
 package logutil
 
 import (
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"testing"
 
 	"src.elv.sh/pkg/must"
@@ -37,3 +39,47 @@ func TestSetOutput_Error(t *testing.T) {
 		t.Errorf("want non-nil error, got nil")
 	}
 }
+
+func TestLevelAndSubsystems(t *testing.T) {
+	fooLogger := GetLogger("[foo] ")
+	barLogger := GetLogger("[bar] ")
+	r, w := must.Pipe()
+	SetOutput(w)
+	defer SetOutput(io.Discard)
+
+	SetEnabled("bar", false)
+	fooLogger.Println("foo 1")
+	barLogger.Println("bar 1")
+	SetEnabled("bar", true)
+	barLogger.Println("bar 2")
+
+	SetLevel(Warn)
+	fooLogger.Println("foo 2")
+	Log("foo", Info, "foo 3")
+	Log("foo", Error, "foo 4")
+	SetLevel(Debug)
+
+	w.Close()
+	want := must.OK1(regexp.Compile(
+		"^\\[foo\\] .*foo 1\n\\[bar\\] .*bar 2\n\\[foo\\] .*error: foo 4\n$"))
+	if out := must.ReadAllAndClose(r); !want.Match(out) {
+		t.Errorf("got out %q, want one matching %q", out, want)
+	}
+
+	subsystems := Subsystems()
+	if !slices.Contains(subsystems, "foo") || !slices.Contains(subsystems, "bar") {
+		t.Errorf("got subsystems %v, want one containing foo and bar", subsystems)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, level := range []Level{Debug, Info, Warn, Error} {
+		if got, err := ParseLevel(level.String()); got != level || err != nil {
+			t.Errorf("ParseLevel(%q) -> (%v, %v), want (%v, nil)",
+				level.String(), got, err, level)
+		}
+	}
+	if _, err := ParseLevel("bad"); err == nil {
+		t.Errorf("ParseLevel(%q) returns nil error, want non-nil", "bad")
+	}
+}
@@ -5,13 +5,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/sourcegraph/jsonrpc2"
-	lsp "pkg.nimblebun.works/go-lsp"
+	lsp "src.elv.sh/pkg/lsp/protocol"
 	"src.elv.sh/pkg/mods/doc"
 	"src.elv.sh/pkg/must"
 	"src.elv.sh/pkg/prog"
@@ -68,6 +70,22 @@ var diagTests = []struct {
 			Severity: lsp.DSError, Source: "parse", Message: "should be variable name",
 		},
 	}},
+	{"compilation error", "echo $x", []lsp.Diagnostic{
+		{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: 0, Character: 5},
+				End:   lsp.Position{Line: 0, Character: 7}},
+			Severity: lsp.DSError, Source: "compile", Message: "variable $x not found",
+		},
+	}},
+	{"no compilation error with parse error", "echo $x $!", []lsp.Diagnostic{
+		{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: 0, Character: 9},
+				End:   lsp.Position{Line: 0, Character: 10}},
+			Severity: lsp.DSError, Source: "parse", Message: "should be variable name",
+		},
+	}},
 }
 
 func TestDidOpenDiagnostics(t *testing.T) {
@@ -136,6 +154,35 @@ func hoverWith(markdown string) lsp.Hover {
 	return lsp.Hover{Contents: lsp.MarkupContent{Kind: lsp.MKMarkdown, Value: markdown}}
 }
 
+func TestDidChangeDiagnostics_InOrder(t *testing.T) {
+	f := setup(t)
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(""))
+	checkDiag(t, f, diagParam([]lsp.Diagnostic{}))
+
+	// Diagnostics of different versions are computed in different amounts of
+	// time; they should still be published in the order of the versions.
+	const n = 20
+	for i := 1; i <= n; i++ {
+		text := "echo"
+		if i%2 == 0 {
+			text = strings.Repeat("echo $x\n", 100)
+		}
+		params := didChangeParams(text)
+		params.TextDocument.Version = i
+		f.conn.Notify(bgCtx, "textDocument/didChange", params)
+	}
+	for i := 1; i <= n; i++ {
+		select {
+		case got := <-f.diags:
+			if got.Version != i {
+				t.Fatalf("got diagnostics of version %v, want %v", got.Version, i)
+			}
+		case <-time.After(testutil.Scaled(time.Second)):
+			t.Fatalf("timed out waiting for diagnostics of version %v", i)
+		}
+	}
+}
+
 func TestHover(t *testing.T) {
 	f := setup(t)
 
@@ -197,6 +244,201 @@ func TestCompletion(t *testing.T) {
 	}
 }
 
+var definitionTests = []struct {
+	name string
+	text string
+	pos  lsp.Position
+
+	wantLocations []lsp.Location
+}{
+	{
+		name: "function in the same document",
+		//     0123456789
+		text: "fn f { }\nf",
+		pos:  lsp.Position{Line: 1, Character: 0},
+
+		wantLocations: []lsp.Location{{URI: testURI, Range: lspRange(0, 3, 0, 4)}},
+	},
+	{
+		name: "function defined multiple times",
+		text: "fn f { }\nfn f { }\nf",
+		pos:  lsp.Position{Line: 2, Character: 0},
+
+		wantLocations: []lsp.Location{
+			{URI: testURI, Range: lspRange(0, 3, 0, 4)},
+			{URI: testURI, Range: lspRange(1, 3, 1, 4)},
+		},
+	},
+	{
+		name: "function in a lambda",
+		text: "fn g { fn f { } }\nf",
+		pos:  lsp.Position{Line: 1, Character: 0},
+
+		wantLocations: []lsp.Location{{URI: testURI, Range: lspRange(0, 10, 0, 11)}},
+	},
+	{
+		name: "undefined function",
+		text: "f",
+		pos:  lsp.Position{Line: 0, Character: 0},
+
+		wantLocations: []lsp.Location{},
+	},
+	{
+		name: "function at non-command position",
+		text: "fn f { }\necho f",
+		pos:  lsp.Position{Line: 1, Character: 5},
+
+		wantLocations: []lsp.Location{},
+	},
+}
+
+func TestDefinition(t *testing.T) {
+	f := setup(t)
+
+	for _, test := range definitionTests {
+		t.Run(test.name, func(t *testing.T) {
+			f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams(test.text))
+			checkDefinition(t, f, testURI, test.pos, test.wantLocations)
+		})
+	}
+}
+
+func TestDefinition_QualifiedName(t *testing.T) {
+	root := testutil.InTempDir(t)
+	testutil.ApplyDir(testutil.Dir{
+		"mod.elv": "fn g { }\n",
+		"lib": testutil.Dir{
+			"mod.elv": "\nfn g { }\n",
+		},
+		"other.elv": "fn g { }\n",
+	})
+	f := setupWithParams(t, lsp.InitializeParams{RootURI: pathToURI(root)})
+
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams("use ./mod\nmod:g"))
+	checkDefinition(t, f, testURI, lsp.Position{Line: 1, Character: 0},
+		[]lsp.Location{
+			{URI: pathToURI(filepath.Join(root, "lib", "mod.elv")), Range: lspRange(1, 3, 1, 4)},
+			{URI: pathToURI(filepath.Join(root, "mod.elv")), Range: lspRange(0, 3, 0, 4)},
+		})
+}
+
+func TestDefinition_PathWithSpace(t *testing.T) {
+	root := testutil.InTempDir(t)
+	testutil.ApplyDir(testutil.Dir{
+		"a dir": testutil.Dir{"mod.elv": "fn g { }\n"},
+	})
+	uri := pathToURI(root)
+	f := setupWithParams(t, lsp.InitializeParams{RootURI: uri})
+
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams("use ./mod\nmod:g"))
+	checkDefinition(t, f, testURI, lsp.Position{Line: 1, Character: 0},
+		[]lsp.Location{
+			{URI: pathToURI(filepath.Join(root, "a dir", "mod.elv")), Range: lspRange(0, 3, 0, 4)},
+		})
+}
+
+func TestDefinition_CachesWorkspaceFiles(t *testing.T) {
+	root := testutil.InTempDir(t)
+	f := setupWithParams(t, lsp.InitializeParams{
+		RootURI: pathToURI(root), Capabilities: watchFilesCapabilities})
+
+	select {
+	case got := <-f.registrations:
+		want := lsp.RegistrationParams{Registrations: []lsp.Registration{{
+			ID:     "workspace/didChangeWatchedFiles",
+			Method: "workspace/didChangeWatchedFiles",
+			RegisterOptions: map[string]any{
+				"watchers": []any{map[string]any{"globPattern": "**/*.elv"}}},
+		}}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("registration (-want +got):\n%s", diff)
+		}
+	case <-time.After(testutil.Scaled(time.Second)):
+		t.Errorf("timed out waiting for the file watchers to be registered")
+	}
+
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams("use ./mod\nmod:g"))
+	checkDefinition(t, f, testURI, lsp.Position{Line: 1, Character: 0},
+		[]lsp.Location{})
+
+	// New files are not found until the client notifies that files have
+	// changed.
+	must.WriteFile("mod.elv", "fn g { }\n")
+	checkDefinition(t, f, testURI, lsp.Position{Line: 1, Character: 0},
+		[]lsp.Location{})
+	f.conn.Notify(bgCtx, "workspace/didChangeWatchedFiles", struct{}{})
+	checkDefinition(t, f, testURI, lsp.Position{Line: 1, Character: 0},
+		[]lsp.Location{{URI: pathToURI(filepath.Join(root, "mod.elv")), Range: lspRange(0, 3, 0, 4)}})
+}
+
+func TestDefinition_NoCacheWithoutFileWatching(t *testing.T) {
+	root := testutil.InTempDir(t)
+	f := setupWithParams(t, lsp.InitializeParams{RootURI: pathToURI(root)})
+
+	f.conn.Notify(bgCtx, "textDocument/didOpen", didOpenParams("use ./mod\nmod:g"))
+	checkDefinition(t, f, testURI, lsp.Position{Line: 1, Character: 0},
+		[]lsp.Location{})
+
+	// Since the client can't watch files, new files are found immediately.
+	must.WriteFile("mod.elv", "fn g { }\n")
+	checkDefinition(t, f, testURI, lsp.Position{Line: 1, Character: 0},
+		[]lsp.Location{{URI: pathToURI(filepath.Join(root, "mod.elv")), Range: lspRange(0, 3, 0, 4)}})
+	select {
+	case got := <-f.registrations:
+		t.Errorf("got registration %v, want none", got)
+	default:
+	}
+}
+
+var watchFilesCapabilities = lsp.ClientCapabilities{
+	Workspace: &lsp.WorkspaceClientCapabilities{
+		DidChangeWatchedFiles: &lsp.DidChangeWatchedFilesClientCapabilities{
+			DynamicRegistration: true}}}
+
+var uriTests = []struct {
+	path string
+	uri  lsp.DocumentURI
+}{
+	{"/foo/bar.elv", "file:///foo/bar.elv"},
+	{"/a dir/100%.elv", "file:///a%20dir/100%25.elv"},
+}
+
+func TestURIToPathAndPathToURI(t *testing.T) {
+	for _, test := range uriTests {
+		if got := pathToURI(filepath.FromSlash(test.path)); got != test.uri {
+			t.Errorf("pathToURI(%q) -> %q, want %q", test.path, got, test.uri)
+		}
+		if got := uriToPath(test.uri); got != filepath.FromSlash(test.path) {
+			t.Errorf("uriToPath(%q) -> %q, want %q", test.uri, got, test.path)
+		}
+	}
+	if got := uriToPath("untitled:Untitled-1"); got != "" {
+		t.Errorf("uriToPath of non-file URI -> %q, want empty", got)
+	}
+}
+
+func checkDefinition(t *testing.T, f *clientFixture, uri lsp.DocumentURI, pos lsp.Position, want []lsp.Location) {
+	t.Helper()
+	request := lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: uri},
+		Position:     pos,
+	}
+	var response []lsp.Location
+	err := f.conn.Call(bgCtx, "textDocument/definition", request, &response)
+	if err != nil {
+		t.Errorf("got error %v", err)
+	}
+	if diff := cmp.Diff(want, response); diff != "" {
+		t.Errorf("response (-want +got):\n%s", diff)
+	}
+}
+
+func lspRange(line1, char1, line2, char2 int) lsp.Range {
+	return lsp.Range{
+		Start: lsp.Position{Line: line1, Character: char1},
+		End:   lsp.Position{Line: line2, Character: char2}}
+}
+
 var jsonrpcErrorTests = []struct {
 	name    string
 	method  string
@@ -214,6 +456,10 @@ var jsonrpcErrorTests = []struct {
 			TextDocumentPositionParams: lsp.TextDocumentPositionParams{
 				TextDocument: lsp.TextDocumentIdentifier{URI: "file://unknown"}}},
 		unknownDocument("file://unknown")},
+	{"unknown document to definition", "textDocument/definition",
+		lsp.TextDocumentPositionParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: "file://unknown"}},
+		unknownDocument("file://unknown")},
 }
 
 func TestJSONRPCErrors(t *testing.T) {
@@ -278,11 +524,16 @@ func completionParams(line, char int) lsp.CompletionParams {
 }
 
 type clientFixture struct {
-	conn  *jsonrpc2.Conn
-	diags <-chan lsp.PublishDiagnosticsParams
+	conn          *jsonrpc2.Conn
+	diags         <-chan lsp.PublishDiagnosticsParams
+	registrations <-chan lsp.RegistrationParams
 }
 
 func setup(t *testing.T) *clientFixture {
+	return setupWithParams(t, lsp.InitializeParams{})
+}
+
+func setupWithParams(t *testing.T, params lsp.InitializeParams) *clientFixture {
 	r0, w0 := must.Pipe()
 	r1, w1 := must.Pipe()
 
@@ -296,7 +547,8 @@ func setup(t *testing.T) *clientFixture {
 
 	// Run client
 	diags := make(chan lsp.PublishDiagnosticsParams, 100)
-	client := client{diags}
+	registrations := make(chan lsp.RegistrationParams, 100)
+	client := client{diags, registrations}
 	conn := jsonrpc2.NewConn(context.Background(),
 		jsonrpc2.NewBufferedStream(transport{r1, w0}, jsonrpc2.VSCodeObjectCodec{}),
 		client.handler())
@@ -304,7 +556,7 @@ func setup(t *testing.T) *clientFixture {
 
 	// LSP handshake
 	err := conn.Call(context.Background(),
-		"initialize", lsp.InitializeParams{}, &lsp.InitializeResult{})
+		"initialize", params, &lsp.InitializeResult{})
 	if err != nil {
 		t.Errorf("got error %v, want nil", err)
 	}
@@ -313,19 +565,31 @@ func setup(t *testing.T) *clientFixture {
 		t.Errorf("got error %v, want nil", err)
 	}
 
-	return &clientFixture{conn, diags}
+	return &clientFixture{conn, diags, registrations}
 }
 
 type client struct {
-	diags chan<- lsp.PublishDiagnosticsParams
+	diags         chan<- lsp.PublishDiagnosticsParams
+	registrations chan<- lsp.RegistrationParams
 }
 
 func (c *client) handler() jsonrpc2.Handler {
 	return routingHandler(map[string]method{
 		"textDocument/publishDiagnostics": c.publishDiagnostics,
+		"client/registerCapability":       c.registerCapability,
 	})
 }
 
+func (c *client) registerCapability(_ context.Context, rawParams json.RawMessage) (any, error) {
+	var params lsp.RegistrationParams
+	err := json.Unmarshal(rawParams, &params)
+	if err != nil {
+		panic(fmt.Sprintf("parse RegistrationParams: %v", err))
+	}
+	c.registrations <- params
+	return nil, nil
+}
+
 func (c *client) publishDiagnostics(_ context.Context, rawParams json.RawMessage) (any, error) {
 	var params lsp.PublishDiagnosticsParams
 	err := json.Unmarshal(rawParams, &params)
@@ -0,0 +1,240 @@
+// Package protocol contains definitions of the types in the Language Server
+// Protocol used by the language server.
+//
+// Only the types and fields that the language server actually uses are
+// defined; see
+// https://microsoft.github.io/language-server-protocol/specification for the
+// full protocol.
+package protocol
+
+// DocumentURI is the URI of a document, normally with the file scheme.
+type DocumentURI string
+
+// Position is a position in a document. Both fields are zero-based, and
+// Character is measured in UTF-16 code units.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a range in a document. The end position is exclusive.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range in a specific document.
+type Location struct {
+	URI   DocumentURI `json:"uri"`
+	Range Range       `json:"range"`
+}
+
+// DiagnosticSeverity is the severity of a Diagnostic.
+type DiagnosticSeverity int
+
+// Possible values of DiagnosticSeverity.
+const (
+	DSError DiagnosticSeverity = iota + 1
+	DSWarning
+	DSInformation
+	DSHint
+)
+
+// Diagnostic is a problem in a document, like a parse error.
+type Diagnostic struct {
+	Range    Range              `json:"range"`
+	Severity DiagnosticSeverity `json:"severity,omitempty"`
+	Source   string             `json:"source,omitempty"`
+	Message  string             `json:"message"`
+}
+
+// PublishDiagnosticsParams is the parameter of the
+// textDocument/publishDiagnostics notification.
+type PublishDiagnosticsParams struct {
+	URI DocumentURI `json:"uri"`
+	// The version of the document the diagnostics are about.
+	Version     int          `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// TextDocumentIdentifier identifies a document.
+type TextDocumentIdentifier struct {
+	URI DocumentURI `json:"uri"`
+}
+
+// VersionedTextDocumentIdentifier identifies a specific version of a document.
+type VersionedTextDocumentIdentifier struct {
+	TextDocumentIdentifier
+	Version int `json:"version"`
+}
+
+// TextDocumentItem is a document transferred from the client to the server.
+type TextDocumentItem struct {
+	URI        DocumentURI `json:"uri"`
+	LanguageID string      `json:"languageId"`
+	Version    int         `json:"version"`
+	Text       string      `json:"text"`
+}
+
+// TextDocumentPositionParams is the parameter of requests about a position in
+// a document.
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+// DidOpenTextDocumentParams is the parameter of the textDocument/didOpen
+// notification.
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+// DidChangeTextDocumentParams is the parameter of the textDocument/didChange
+// notification.
+type DidChangeTextDocumentParams struct {
+	TextDocument   VersionedTextDocumentIdentifier  `json:"textDocument"`
+	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+// TextDocumentContentChangeEvent is a change to a document. When Range is nil,
+// Text is the full content of the document.
+type TextDocumentContentChangeEvent struct {
+	Range *Range `json:"range,omitempty"`
+	Text  string `json:"text"`
+}
+
+// MarkupKind is the format of a MarkupContent.
+type MarkupKind string
+
+// Possible values of MarkupKind.
+const (
+	MKPlainText MarkupKind = "plaintext"
+	MKMarkdown  MarkupKind = "markdown"
+)
+
+// MarkupContent is a string in some format, like Markdown.
+type MarkupContent struct {
+	Kind  MarkupKind `json:"kind"`
+	Value string     `json:"value"`
+}
+
+// Hover is the result of the textDocument/hover request.
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    *Range        `json:"range,omitempty"`
+}
+
+// CompletionParams is the parameter of the textDocument/completion request.
+type CompletionParams struct {
+	TextDocumentPositionParams
+}
+
+// CompletionItemKind is the kind of a CompletionItem.
+type CompletionItemKind int
+
+// Values of CompletionItemKind used by the language server.
+const (
+	CIKFunction CompletionItemKind = 3
+	CIKVariable CompletionItemKind = 6
+)
+
+// CompletionItem is a completion candidate.
+type CompletionItem struct {
+	Label    string             `json:"label"`
+	Kind     CompletionItemKind `json:"kind,omitempty"`
+	TextEdit *TextEdit          `json:"textEdit,omitempty"`
+}
+
+// TextEdit is a replacement of a range in a document.
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// InitializeParams is the parameter of the initialize request.
+type InitializeParams struct {
+	RootURI      DocumentURI        `json:"rootUri,omitempty"`
+	Capabilities ClientCapabilities `json:"capabilities"`
+}
+
+// ClientCapabilities describes the features supported by the client.
+type ClientCapabilities struct {
+	Workspace *WorkspaceClientCapabilities `json:"workspace,omitempty"`
+}
+
+// WorkspaceClientCapabilities describes the workspace features supported by
+// the client.
+type WorkspaceClientCapabilities struct {
+	DidChangeWatchedFiles *DidChangeWatchedFilesClientCapabilities `json:"didChangeWatchedFiles,omitempty"`
+}
+
+// DidChangeWatchedFilesClientCapabilities describes the support of the client
+// for the workspace/didChangeWatchedFiles notification.
+type DidChangeWatchedFilesClientCapabilities struct {
+	// Whether the client supports registering file watchers with the
+	// client/registerCapability request.
+	DynamicRegistration bool `json:"dynamicRegistration,omitempty"`
+}
+
+// InitializeResult is the result of the initialize request.
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+}
+
+// ServerCapabilities describes the features supported by the server.
+type ServerCapabilities struct {
+	TextDocumentSync   *TextDocumentSyncOptions `json:"textDocumentSync,omitempty"`
+	CompletionProvider *CompletionOptions       `json:"completionProvider,omitempty"`
+	HoverProvider      *HoverOptions            `json:"hoverProvider,omitempty"`
+	DefinitionProvider *DefinitionOptions       `json:"definitionProvider,omitempty"`
+}
+
+// TextDocumentSyncKind is how documents are synchronized to the server.
+type TextDocumentSyncKind int
+
+// Possible values of TextDocumentSyncKind.
+const (
+	TDSyncKindNone TextDocumentSyncKind = iota
+	TDSyncKindFull
+	TDSyncKindIncremental
+)
+
+// TextDocumentSyncOptions describes how documents are synchronized to the
+// server.
+type TextDocumentSyncOptions struct {
+	OpenClose bool                 `json:"openClose,omitempty"`
+	Change    TextDocumentSyncKind `json:"change,omitempty"`
+}
+
+// CompletionOptions describes the completion support of the server.
+type CompletionOptions struct{}
+
+// HoverOptions describes the hover support of the server.
+type HoverOptions struct{}
+
+// DefinitionOptions describes the go-to-definition support of the server.
+type DefinitionOptions struct{}
+
+// RegistrationParams is the parameter of the client/registerCapability
+// request.
+type RegistrationParams struct {
+	Registrations []Registration `json:"registrations"`
+}
+
+// Registration is a request to register a capability with the client.
+type Registration struct {
+	ID              string `json:"id"`
+	Method          string `json:"method"`
+	RegisterOptions any    `json:"registerOptions,omitempty"`
+}
+
+// DidChangeWatchedFilesRegistrationOptions are the options of a registration
+// for the workspace/didChangeWatchedFiles notification.
+type DidChangeWatchedFilesRegistrationOptions struct {
+	Watchers []FileSystemWatcher `json:"watchers"`
+}
+
+// FileSystemWatcher describes the files to watch.
+type FileSystemWatcher struct {
+	GlobPattern string `json:"globPattern"`
+}
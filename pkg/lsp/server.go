@@ -4,12 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
 
 	"github.com/sourcegraph/jsonrpc2"
-	lsp "pkg.nimblebun.works/go-lsp"
 	"src.elv.sh/pkg/diag"
 	"src.elv.sh/pkg/edit/complete"
 	"src.elv.sh/pkg/eval"
+	lsp "src.elv.sh/pkg/lsp/protocol"
 	"src.elv.sh/pkg/mods/doc"
 	"src.elv.sh/pkg/parse"
 	"src.elv.sh/pkg/parse/np"
@@ -25,6 +32,16 @@ var (
 type server struct {
 	evaler    *eval.Evaler
 	documents map[lsp.DocumentURI]document
+	// Root directory of the workspace, or "" if the client didn't specify one.
+	root string
+	// Whether the client supports watching files for the server. If it
+	// doesn't, the workspace index is never cached, since the server would
+	// not know when it becomes outdated.
+	watchFiles bool
+	// Paths of all .elv files in the workspace, indexed by their base names.
+	// Built when first needed, and discarded when the client notifies that
+	// files in the workspace have changed.
+	workspaceFiles map[string][]string
 }
 
 type document struct {
@@ -34,23 +51,23 @@ type document struct {
 }
 
 func newServer() *server {
-	return &server{eval.NewEvaler(), make(map[lsp.DocumentURI]document)}
+	return &server{evaler: eval.NewEvaler(), documents: make(map[lsp.DocumentURI]document)}
 }
 
 func handler(s *server) jsonrpc2.Handler {
 	return routingHandler(map[string]method{
-		"initialize":              s.initialize,
+		"initialize":              convertMethod(s.initialize),
+		"initialized":             s.initialized,
 		"textDocument/didOpen":    convertMethod(s.didOpen),
 		"textDocument/didChange":  convertMethod(s.didChange),
 		"textDocument/hover":      convertMethod(s.hover),
 		"textDocument/completion": convertMethod(s.completion),
+		"textDocument/definition": convertMethod(s.definition),
 
 		"textDocument/didClose": noop,
-		// Required by spec.
-		"initialized": noop,
-		// Called by clients even when server doesn't advertise support:
-		// https://microsoft.github.io/language-server-protocol/specification#workspace_didChangeWatchedFiles
-		"workspace/didChangeWatchedFiles": noop,
+		// Sent by clients for the file watchers registered in the initialized
+		// method.
+		"workspace/didChangeWatchedFiles": s.didChangeWatchedFiles,
 	})
 }
 
@@ -86,7 +103,13 @@ func conn(ctx context.Context) *jsonrpc2.Conn { return ctx.Value(connKey{}).(*js
 
 // Handler implementations. These are all called synchronously.
 
-func (s *server) initialize(_ context.Context, _ json.RawMessage) (any, error) {
+func (s *server) initialize(_ context.Context, params lsp.InitializeParams) (any, error) {
+	if params.RootURI != "" {
+		s.root = uriToPath(params.RootURI)
+	}
+	if ws := params.Capabilities.Workspace; ws != nil && ws.DidChangeWatchedFiles != nil {
+		s.watchFiles = ws.DidChangeWatchedFiles.DynamicRegistration
+	}
 	return &lsp.InitializeResult{
 		Capabilities: lsp.ServerCapabilities{
 			TextDocumentSync: &lsp.TextDocumentSyncOptions{
@@ -95,21 +118,47 @@ func (s *server) initialize(_ context.Context, _ json.RawMessage) (any, error) {
 			},
 			CompletionProvider: &lsp.CompletionOptions{},
 			HoverProvider:      &lsp.HoverOptions{},
+			DefinitionProvider: &lsp.DefinitionOptions{},
 		},
 	}, nil
 }
 
+// Asks the client to watch the .elv files in the workspace, so that the
+// workspace index can be discarded when they change.
+func (s *server) initialized(ctx context.Context, _ json.RawMessage) (any, error) {
+	if !s.watchFiles || s.root == "" {
+		return nil, nil
+	}
+	params := lsp.RegistrationParams{Registrations: []lsp.Registration{{
+		ID:     "workspace/didChangeWatchedFiles",
+		Method: "workspace/didChangeWatchedFiles",
+		RegisterOptions: lsp.DidChangeWatchedFilesRegistrationOptions{
+			Watchers: []lsp.FileSystemWatcher{{GlobPattern: "**/*.elv"}},
+		},
+	}}}
+	// The response can only be read after this handler returns, so the
+	// request is sent in another goroutine.
+	c := conn(ctx)
+	go c.Call(context.Background(), "client/registerCapability", params, nil)
+	return nil, nil
+}
+
 func (s *server) didOpen(ctx context.Context, params lsp.DidOpenTextDocumentParams) (any, error) {
-	uri, content := params.TextDocument.URI, params.TextDocument.Text
-	s.updateDocument(conn(ctx), uri, content)
+	doc := params.TextDocument
+	s.updateDocument(conn(ctx), doc.URI, doc.Version, doc.Text)
 	return nil, nil
 }
 
 func (s *server) didChange(ctx context.Context, params lsp.DidChangeTextDocumentParams) (any, error) {
 	// ContentChanges includes full text since the server is only advertised to
 	// support that; see the initialize method.
-	uri, content := params.TextDocument.URI, params.ContentChanges[0].Text
-	s.updateDocument(conn(ctx), uri, content)
+	doc := params.TextDocument
+	s.updateDocument(conn(ctx), doc.URI, doc.Version, params.ContentChanges[0].Text)
+	return nil, nil
+}
+
+func (s *server) didChangeWatchedFiles(_ context.Context, _ json.RawMessage) (any, error) {
+	s.workspaceFiles = nil
 	return nil, nil
 }
 
@@ -185,24 +234,178 @@ func (s *server) completion(_ context.Context, params lsp.CompletionParams) (any
 	return lspItems, nil
 }
 
-func (s *server) updateDocument(conn *jsonrpc2.Conn, uri lsp.DocumentURI, code string) {
-	tree, err := parse.Parse(parse.Source{Name: string(uri), Code: code}, parse.Config{})
+func (s *server) definition(_ context.Context, params lsp.TextDocumentPositionParams) (any, error) {
+	uri := params.TextDocument.URI
+	document, ok := s.documents[uri]
+	if !ok {
+		return nil, unknownDocument(uri)
+	}
+	pos := lspPositionToIdx(document.code, params.Position)
+
+	p := np.Find(document.parseTree.Root, pos)
+	var expr np.SimpleExprData
+	var form *parse.Form
+	if !p.Match(np.SimpleExpr(&expr, nil), np.Store(&form)) || form.Head != expr.Compound {
+		return []lsp.Location{}, nil
+	}
+	return s.findFnDefinitions(uri, expr.Value), nil
+}
+
+// Finds the definitions of a function called by the given name in the
+// document with the given URI.
+//
+// An unqualified name like f is looked up in the document itself. A qualified
+// name like mod:f is looked up in all files named mod.elv in the workspace,
+// including both open documents and files in the root directory.
+func (s *server) findFnDefinitions(uri lsp.DocumentURI, name string) []lsp.Location {
+	locations := []lsp.Location{}
+	addLocations := func(uri lsp.DocumentURI, code string, tree parse.Tree, name string) {
+		for _, r := range fnDefinitions(tree.Root, name) {
+			locations = append(locations,
+				lsp.Location{URI: uri, Range: lspRangeFromRange(code, r)})
+		}
+	}
+
+	i := strings.LastIndexByte(name, ':')
+	if i == -1 {
+		document := s.documents[uri]
+		addLocations(uri, document.code, document.parseTree, name)
+		return locations
+	}
+	// The name of the module is the last component of the namespace, like
+	// mod in a:mod:f.
+	modName := name[strings.LastIndexByte(name[:i], ':')+1 : i]
+	fnName := name[i+1:]
+
+	fileName := modName + ".elv"
+	openPaths := make(map[string]bool)
+	for docURI, document := range s.documents {
+		path := uriToPath(docURI)
+		openPaths[path] = true
+		if filepath.Base(path) == fileName {
+			addLocations(docURI, document.code, document.parseTree, fnName)
+		}
+	}
+	for _, path := range s.workspaceIndex()[fileName] {
+		if openPaths[path] {
+			continue
+		}
+		code, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		tree, _ := parse.Parse(parse.Source{Name: path, Code: string(code)}, parse.Config{Recover: true})
+		addLocations(pathToURI(path), string(code), tree, fnName)
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		return locations[i].URI < locations[j].URI
+	})
+	return locations
+}
+
+// Returns the paths of .elv files in the workspace, indexed by their base
+// names, walking the root directory if they are not cached.
+func (s *server) workspaceIndex() map[string][]string {
+	if s.workspaceFiles != nil || s.root == "" {
+		return s.workspaceFiles
+	}
+	files := make(map[string][]string)
+	filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != s.root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(d.Name(), ".elv") {
+			files[d.Name()] = append(files[d.Name()], path)
+		}
+		return nil
+	})
+	if s.watchFiles {
+		s.workspaceFiles = files
+	}
+	return files
+}
+
+// Returns the ranges of the names in all "fn" forms in n that define a
+// function with the given name.
+func fnDefinitions(n parse.Node, name string) []diag.Ranging {
+	var ranges []diag.Ranging
+	if form, ok := n.(*parse.Form); ok && form.Head != nil &&
+		parse.SourceText(form.Head) == "fn" &&
+		len(form.Args) > 0 && parse.SourceText(form.Args[0]) == name {
+		ranges = append(ranges, form.Args[0].Range())
+	}
+	for _, ch := range parse.Children(n) {
+		ranges = append(ranges, fnDefinitions(ch, name)...)
+	}
+	return ranges
+}
+
+// Converts a file URI to a path, decoding percent-encoded characters. It
+// returns "" if uri is not a valid file URI.
+func uriToPath(uri lsp.DocumentURI) string {
+	u, err := url.Parse(string(uri))
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	// The path component is already decoded by url.Parse.
+	path := u.Path
+	if runtime.GOOS == "windows" && len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		// Drive letters are preceded by a slash, like in file:///C:/foo.
+		path = path[1:]
+	}
+	return filepath.FromSlash(path)
+}
+
+func pathToURI(path string) lsp.DocumentURI {
+	slashPath := filepath.ToSlash(path)
+	if !strings.HasPrefix(slashPath, "/") {
+		slashPath = "/" + slashPath
+	}
+	u := url.URL{Scheme: "file", Path: slashPath}
+	return lsp.DocumentURI(u.String())
+}
+
+// Updates the document, and publishes its diagnostics.
+//
+// The diagnostics are published before the handler returns rather than in
+// another goroutine, so that diagnostics of an older version of a document
+// never arrive after those of a newer version.
+func (s *server) updateDocument(conn *jsonrpc2.Conn, uri lsp.DocumentURI, version int, code string) {
+	tree, err := parse.Parse(parse.Source{Name: string(uri), Code: code}, parse.Config{Recover: true})
 	s.documents[uri] = document{code, tree, err}
-	go func() {
-		// Convert the parse error to lsp.Diagnostic objects and publish them.
-		entries := parse.UnpackErrors(err)
-		diags := make([]lsp.Diagnostic, len(entries))
-		for i, err := range entries {
-			diags[i] = lsp.Diagnostic{
+	// Convert the parse errors to lsp.Diagnostic objects. If there are no
+	// parse errors, also check the code for compilation errors, like
+	// references to undefined variables. Compilation errors are not reported
+	// for code with parse errors, since they are likely to be caused by the
+	// parse errors.
+	diags := []lsp.Diagnostic{}
+	for _, err := range parse.UnpackErrors(err) {
+		diags = append(diags, lsp.Diagnostic{
+			Range:    lspRangeFromRange(code, err),
+			Severity: lsp.DSError,
+			Source:   "parse",
+			Message:  err.Message,
+		})
+	}
+	if err == nil {
+		_, compileErr := s.evaler.CheckTree(tree, nil)
+		for _, err := range eval.UnpackCompilationErrors(compileErr) {
+			diags = append(diags, lsp.Diagnostic{
 				Range:    lspRangeFromRange(code, err),
 				Severity: lsp.DSError,
-				Source:   "parse",
+				Source:   "compile",
 				Message:  err.Message,
-			}
+			})
 		}
-		conn.Notify(context.Background(), "textDocument/publishDiagnostics",
-			lsp.PublishDiagnosticsParams{URI: uri, Diagnostics: diags})
-	}()
+	}
+	conn.Notify(context.Background(), "textDocument/publishDiagnostics",
+		lsp.PublishDiagnosticsParams{URI: uri, Version: version, Diagnostics: diags})
 }
 
 func unknownDocument(uri lsp.DocumentURI) error {
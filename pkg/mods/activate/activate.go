@@ -0,0 +1,8 @@
+package activate
+
+import _ "embed"
+
+// Code contains the source code of the activate module.
+//
+//go:embed activate.elv
+var Code string
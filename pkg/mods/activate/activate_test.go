@@ -0,0 +1,28 @@
+package activate_test
+
+import (
+	"embed"
+	"path/filepath"
+	"testing"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/evaltest"
+	"src.elv.sh/pkg/mods"
+	storemod "src.elv.sh/pkg/mods/store"
+	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/store"
+)
+
+//go:embed *.elvts
+var transcripts embed.FS
+
+func TestTranscripts(t *testing.T) {
+	evaltest.TestTranscriptsInFS(t, transcripts,
+		"prepare-deps", mods.AddTo,
+		"use-store", func(t *testing.T, ev *eval.Evaler) {
+			s := must.OK1(store.NewStore(filepath.Join(t.TempDir(), "db")))
+			t.Cleanup(func() { s.Close() })
+			ev.AddModule("store", storemod.Ns(s))
+		},
+	)
+}
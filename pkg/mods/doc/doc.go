@@ -10,6 +10,7 @@ import (
 	"src.elv.sh/pkg"
 	"src.elv.sh/pkg/elvdoc"
 	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
 	"src.elv.sh/pkg/md"
 	"src.elv.sh/pkg/parse"
 	"src.elv.sh/pkg/sys"
@@ -19,7 +20,7 @@ var Ns = eval.BuildNsNamed("doc").
 	AddGoFns(map[string]any{
 		"show":     show,
 		"find":     find,
-		"source":   Source,
+		"source":   source,
 		"-symbols": symbols,
 	}).
 	Ns()
@@ -29,19 +30,12 @@ type showOptions struct{ Width int }
 func (opts *showOptions) SetDefaultOptions() {}
 
 func show(fm *eval.Frame, opts showOptions, fqname string) error {
-	doc, err := Source(fqname)
+	doc, err := source(fm, fqname)
 	if err != nil {
 		return err
 	}
-	width := opts.Width
-	if width <= 0 {
-		_, width = sys.WinSize(fm.Port(1).File)
-		if width <= 0 {
-			width = 80
-		}
-	}
 	codec := &md.TTYCodec{
-		Width:              width,
+		Width:              outputWidth(fm, opts.Width),
 		HighlightCodeBlock: elvdoc.HighlightCodeBlock,
 		ConvertRelativeLink: func(dest string) string {
 			// TTYCodec does not show destinations of relative links by default.
@@ -64,6 +58,18 @@ func show(fm *eval.Frame, opts showOptions, fqname string) error {
 	return err
 }
 
+// Returns width if it is positive, or the width of the terminal of the byte
+// output, falling back to 80 if it is not a terminal.
+func outputWidth(fm *eval.Frame, width int) int {
+	if width <= 0 {
+		_, width = sys.WinSize(fm.Port(1).File)
+		if width <= 0 {
+			width = 80
+		}
+	}
+	return width
+}
+
 func find(fm *eval.Frame, qs ...string) {
 	for _, docs := range docsMap() {
 		findIn := func(name, markdown string) {
@@ -84,8 +90,84 @@ func find(fm *eval.Frame, qs ...string) {
 	}
 }
 
+// Returns the doc source for a symbol, falling back to the documentation of
+// user-defined functions in the global namespace.
+func source(fm *eval.Frame, qname string) (string, error) {
+	entry, err := findEntryIn(fm.Evaler, qname)
+	if err != nil {
+		return "", err
+	}
+	return entry.FullContent(), nil
+}
+
+// Like findEntry, but falls back to user-defined functions in the global
+// namespace of ev that have documentation.
+func findEntryIn(ev *eval.Evaler, qname string) (elvdoc.Entry, error) {
+	entry, err := findEntry(qname)
+	if err == nil {
+		return entry, nil
+	}
+	if c := globalClosure(ev, qname); c != nil && c.Doc != "" {
+		return elvdoc.Entry{Name: qname, Content: c.Doc,
+			Fn: &elvdoc.Fn{Usage: closureUsage(qname, c)}}, nil
+	}
+	return elvdoc.Entry{}, err
+}
+
+// Finds a user-defined function by its qualified name in the global namespace
+// of ev. Returns nil if the name doesn't refer to a user-defined function.
+func globalClosure(ev *eval.Evaler, qname string) *eval.Closure {
+	if strings.HasPrefix(qname, "$") {
+		return nil
+	}
+	segs := eval.SplitQNameSegs(qname)
+	ns := ev.Global()
+	for _, seg := range segs[:len(segs)-1] {
+		v := ns.IndexString(seg)
+		if v == nil {
+			return nil
+		}
+		ns, _ = v.Get().(*eval.Ns)
+		if ns == nil {
+			return nil
+		}
+	}
+	v := ns.IndexString(segs[len(segs)-1] + eval.FnSuffix)
+	if v == nil {
+		return nil
+	}
+	c, _ := v.Get().(*eval.Closure)
+	return c
+}
+
+// Returns the usage of a user-defined function in the same format as the usage
+// of builtin functions, like "f $a $b... &opt=default".
+func closureUsage(qname string, c *eval.Closure) string {
+	var sb strings.Builder
+	sb.WriteString(parse.QuoteCommandName(qname))
+	for i, name := range c.ArgNames {
+		sb.WriteString(" $" + name)
+		if i == c.RestArg {
+			sb.WriteString("...")
+		}
+	}
+	for i, name := range c.OptNames {
+		sb.WriteString(" &" + name + "=" + vals.ReprPlain(c.OptDefaults[i]))
+	}
+	return sb.String()
+}
+
 // Source returns the doc source for a symbol.
 func Source(qname string) (string, error) {
+	entry, err := findEntry(qname)
+	if err != nil {
+		return "", err
+	}
+	return entry.FullContent(), nil
+}
+
+// Finds the elvdoc entry for a symbol.
+func findEntry(qname string) (elvdoc.Entry, error) {
 	isVar := strings.HasPrefix(qname, "$")
 	var ns string
 	if strings.ContainsRune(qname, ':') {
@@ -110,7 +192,7 @@ func Source(qname string) (string, error) {
 
 	docs, ok := docsMap()[ns]
 	if !ok {
-		return "", fmt.Errorf("no doc for %s", parse.Quote(qname))
+		return elvdoc.Entry{}, fmt.Errorf("no doc for %s", parse.Quote(qname))
 	}
 	var entries []elvdoc.Entry
 	if isVar {
@@ -120,11 +202,11 @@ func Source(qname string) (string, error) {
 	}
 	for _, entry := range entries {
 		if entry.Name == qname {
-			return entry.FullContent(), nil
+			return entry, nil
 		}
 	}
 
-	return "", fmt.Errorf("no doc for %s", parse.Quote(qname))
+	return elvdoc.Entry{}, fmt.Errorf("no doc for %s", parse.Quote(qname))
 }
 
 func symbols(fm *eval.Frame) error {
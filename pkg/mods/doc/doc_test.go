@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"src.elv.sh/pkg/elvdoc"
+	"src.elv.sh/pkg/eval"
 	"src.elv.sh/pkg/eval/evaltest"
 	"src.elv.sh/pkg/mods/doc"
 	"src.elv.sh/pkg/must"
@@ -32,5 +33,6 @@ func TestTranscripts(t *testing.T) {
 	// The result of reading the FS is cached. As a result, this override can't
 	// be reverted, so we just do it here instead of properly inside a setup
 	// function.
-	evaltest.TestTranscriptsInFS(t, transcripts)
+	evaltest.TestTranscriptsInFS(t, transcripts,
+		"add-help", func(ev *eval.Evaler) { ev.AddBuiltin("help", doc.Help) })
 }
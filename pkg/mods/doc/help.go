@@ -0,0 +1,189 @@
+package doc
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"src.elv.sh/pkg/elvdoc"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/md"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/wcwidth"
+)
+
+// Help is the implementation of the help builtin. It lives in this package
+// since it needs access to the documentation of all the builtin modules, and
+// is added to the builtin namespace by [src.elv.sh/pkg/mods.AddTo].
+var Help = eval.NewGoFn("help", help)
+
+type helpOptions struct {
+	Structured bool
+	Width      int
+}
+
+func (*helpOptions) SetDefaultOptions() {}
+
+func help(fm *eval.Frame, opts helpOptions, names ...string) error {
+	var entries []elvdoc.Entry
+	detailed := len(names) == 1 && !strings.HasSuffix(names[0], ":")
+	if len(names) == 0 {
+		names = []string{"builtin:"}
+	}
+	for _, name := range names {
+		if strings.HasSuffix(name, ":") {
+			ns := name
+			if ns == "builtin:" {
+				ns = ""
+			}
+			docs, ok := docsMap()[ns]
+			if !ok {
+				return fmt.Errorf("no doc for %s", parse.Quote(name))
+			}
+			fns := slices.Clone(docs.Fns)
+			slices.SortFunc(fns, func(a, b elvdoc.Entry) int {
+				return strings.Compare(a.Name, b.Name)
+			})
+			entries = append(entries, fns...)
+			continue
+		}
+		entry, err := findEntryIn(fm.Evaler, name)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+	}
+
+	if opts.Structured {
+		out := fm.ValueOutput()
+		for _, entry := range entries {
+			err := out.Put(helpMap(entry))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	width := outputWidth(fm, opts.Width)
+	var text string
+	if detailed {
+		text = helpDetailed(entries[0], width)
+	} else {
+		text = helpList(entries, width)
+	}
+	_, err := fm.ByteOutput().WriteString(text)
+	return err
+}
+
+func helpMap(entry elvdoc.Entry) vals.Map {
+	summary, examples := summarize(entry.Content)
+	return vals.MakeMap(
+		"name", entry.Name,
+		"signature", signature(entry),
+		"summary", summary,
+		"examples", vals.MakeListSlice(examples),
+		"doc", entry.Content)
+}
+
+func helpDetailed(entry elvdoc.Entry, width int) string {
+	summary, examples := summarize(entry.Content)
+	var sb strings.Builder
+	sb.WriteString("Usage:\n\n  " + signature(entry) + "\n")
+	if summary != "" {
+		sb.WriteString("\n" + wrap(summary, width) + "\n")
+	}
+	if len(examples) > 0 {
+		sb.WriteString("\nExamples:\n")
+		for _, example := range examples {
+			sb.WriteString("\n  " + strings.ReplaceAll(example, "\n", "\n  ") + "\n")
+		}
+	}
+	fmt.Fprintf(&sb, "\nRun doc:show %s for the full documentation.\n",
+		parse.Quote(entry.Name))
+	return sb.String()
+}
+
+func helpList(entries []elvdoc.Entry, width int) string {
+	nameWidth := 0
+	for _, entry := range entries {
+		nameWidth = max(nameWidth, wcwidth.Of(entry.Name))
+	}
+	var sb strings.Builder
+	for _, entry := range entries {
+		summary, _ := summarize(entry.Content)
+		line := wcwidth.Force(entry.Name, nameWidth) + "  " + summary
+		if wcwidth.Of(line) > width {
+			line = wcwidth.Trim(line, width-1) + "…"
+		}
+		sb.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	return sb.String()
+}
+
+// Returns the usage of a function, or the name of a variable.
+func signature(entry elvdoc.Entry) string {
+	if entry.Fn != nil {
+		return entry.Fn.Usage
+	}
+	return entry.Name
+}
+
+// Returns the text of the first paragraph of a Markdown document, and the
+// content of all its elvish-transcript code blocks.
+func summarize(markdown string) (string, []string) {
+	var codec summaryCodec
+	md.Render(markdown, &codec)
+	return codec.summary, codec.examples
+}
+
+var examplesIntro = regexp.MustCompile(`\s*\bExamples?( \([^)]*\))?:$`)
+
+type summaryCodec struct {
+	summary  string
+	examples []string
+}
+
+func (c *summaryCodec) Do(op md.Op) {
+	switch op.Type {
+	case md.OpParagraph:
+		if c.summary == "" {
+			var text md.TextCodec
+			text.Do(op)
+			// The first paragraph often ends with an introduction to the
+			// examples that follow, which is not part of the summary.
+			c.summary = examplesIntro.ReplaceAllString(text.Blocks()[0].Text, "")
+		}
+	case md.OpCodeBlock:
+		if lang, _, _ := strings.Cut(op.Info, " "); lang == "elvish-transcript" {
+			var lines []string
+			for _, line := range op.Lines {
+				// Skip directives for the transcript tests.
+				if !strings.HasPrefix(line, "//") {
+					lines = append(lines, line)
+				}
+			}
+			c.examples = append(c.examples, strings.Join(lines, "\n"))
+		}
+	}
+}
+
+// Wraps text to lines no wider than width, breaking at spaces.
+func wrap(text string, width int) string {
+	var sb strings.Builder
+	lineWidth := 0
+	for _, word := range strings.Fields(text) {
+		w := wcwidth.Of(word)
+		if lineWidth > 0 && lineWidth+1+w > width {
+			sb.WriteByte('\n')
+			lineWidth = 0
+		} else if lineWidth > 0 {
+			sb.WriteByte(' ')
+			lineWidth++
+		}
+		sb.WriteString(word)
+		lineWidth += w
+	}
+	return sb.String()
+}
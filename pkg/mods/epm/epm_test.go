@@ -2,15 +2,70 @@ package epm_test
 
 import (
 	"embed"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
+	"src.elv.sh/pkg/eval"
 	"src.elv.sh/pkg/eval/evaltest"
+	"src.elv.sh/pkg/eval/vars"
 	"src.elv.sh/pkg/mods"
+	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/testutil"
 )
 
 //go:embed *.elvts
 var transcripts embed.FS
 
 func TestTranscripts(t *testing.T) {
-	evaltest.TestTranscriptsInFS(t, transcripts, "prepare-deps", mods.AddTo)
+	evaltest.TestTranscriptsInFS(t, transcripts,
+		"prepare-deps", mods.AddTo,
+		"git-pkg", setupGitPkg,
+	)
+}
+
+// Creates a git repository with two commits, and a managed directory with a
+// domain that installs packages from local git repositories. Defines $lib,
+// $pkg, $rev1 and $rev2.
+func setupGitPkg(t *testing.T, ev *eval.Evaler) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	for _, name := range []string{"AUTHOR", "COMMITTER"} {
+		testutil.Setenv(t, "GIT_"+name+"_NAME", "epm")
+		testutil.Setenv(t, "GIT_"+name+"_EMAIL", "epm@example.com")
+	}
+	dir := testutil.TempDir(t)
+	repo := filepath.Join(dir, "repo", "pkg")
+	must.MkdirAll(repo)
+	git := func(args ...string) string {
+		out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).Output()
+		if err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "first")
+	rev1 := git("rev-parse", "HEAD")
+	git("commit", "-q", "--allow-empty", "-m", "second")
+	rev2 := git("rev-parse", "HEAD")
+
+	lib := filepath.Join(dir, "lib")
+	// The package path is the absolute path of the repository.
+	levels := strings.Count(filepath.ToSlash(repo), "/")
+	testutil.ApplyDirIn(testutil.Dir{
+		"localhost": testutil.Dir{
+			"epm-domain.cfg": `{"method": "git", "protocol": "file", "levels": ` +
+				strconv.Itoa(levels) + `}`,
+		},
+	}, lib)
+
+	ev.ExtendGlobal(eval.BuildNs().
+		AddVar("lib", vars.NewReadOnly(lib)).
+		AddVar("pkg", vars.NewReadOnly("localhost"+filepath.ToSlash(repo))).
+		AddVar("rev1", vars.NewReadOnly(rev1)).
+		AddVar("rev2", vars.NewReadOnly(rev2)))
 }
@@ -19,6 +19,7 @@ var Ns = eval.BuildNsNamed("flag").
 		"call":         call,
 		"parse":        parse,
 		"parse-getopt": parseGetopt,
+		"usage":        usage,
 	}).Ns()
 
 type callOpts struct {
@@ -85,11 +86,48 @@ func parse(argsVal vals.List, specsVal vals.List) (vals.Map, vals.List, error) {
 	if err != nil {
 		return nil, nil, err
 	}
-	var specs []vals.List
-	err = vals.ScanListToGo(specsVal, &specs)
+	fs, err := flagSetFromSpecs(specsVal)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = fs.Parse(args)
 	if err != nil {
 		return nil, nil, err
 	}
+	m := vals.EmptyMap
+	fs.VisitAll(func(f *flag.Flag) {
+		m = m.Assoc(f.Name, f.Value.(flag.Getter).Get())
+	})
+	return m, vals.MakeListSlice(fs.Args()), nil
+}
+
+type usageOpts struct{ Name string }
+
+func (*usageOpts) SetDefaultOptions() {}
+
+func usage(fm *eval.Frame, opts usageOpts, specsVal vals.List) error {
+	fs, err := flagSetFromSpecs(specsVal)
+	if err != nil {
+		return err
+	}
+	out := fm.ByteOutput()
+	if opts.Name != "" {
+		_, err := out.WriteString("Usage of " + opts.Name + ":\n")
+		if err != nil {
+			return err
+		}
+	}
+	fs.SetOutput(out)
+	fs.PrintDefaults()
+	return nil
+}
+
+func flagSetFromSpecs(specsVal vals.List) (*flag.FlagSet, error) {
+	var specs []vals.List
+	err := vals.ScanListToGo(specsVal, &specs)
+	if err != nil {
+		return nil, err
+	}
 
 	fs := newFlagSet("")
 	for _, spec := range specs {
@@ -101,18 +139,10 @@ func parse(argsVal vals.List, specsVal vals.List) (vals.Map, vals.List, error) {
 		vals.ScanListElementsToGo(spec, &name, &value, &description)
 		err := addFlag(fs, name, value, description)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 	}
-	err = fs.Parse(args)
-	if err != nil {
-		return nil, nil, err
-	}
-	m := vals.EmptyMap
-	fs.VisitAll(func(f *flag.Flag) {
-		m = m.Assoc(f.Name, f.Value.(flag.Getter).Get())
-	})
-	return m, vals.MakeListSlice(fs.Args()), nil
+	return fs, nil
 }
 
 func newFlagSet(name string) *flag.FlagSet {
@@ -0,0 +1,316 @@
+// Package git implements the git: module.
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
+)
+
+// Ns is the namespace for the git: module.
+var Ns = eval.BuildNsNamed("git").
+	AddGoFns(map[string]any{
+		"status": status,
+	}).Ns()
+
+// Status of a Git repository, as reported by "git status".
+type repoStatus struct {
+	root      string
+	branch    any // string or nil when HEAD is detached
+	commit    any // string or nil in a new repository
+	upstream  any // string or nil when there is no upstream
+	ahead     int
+	behind    int
+	staged    int
+	unstaged  int
+	untracked int
+	conflicts int
+}
+
+func (s *repoStatus) toMap(stale bool) vals.Map {
+	return vals.MakeMap(
+		"root", s.root,
+		"branch", s.branch,
+		"commit", s.commit,
+		"upstream", s.upstream,
+		"ahead", s.ahead,
+		"behind", s.behind,
+		"staged", s.staged,
+		"unstaged", s.unstaged,
+		"untracked", s.untracked,
+		"conflicts", s.conflicts,
+		"stale", stale)
+}
+
+// A cached status, along with when it was obtained and the modification times
+// of the files that are used to determine whether it is still valid.
+type cacheEntry struct {
+	status *repoStatus
+	time   time.Time
+	mtimes [3]time.Time
+}
+
+// A run of "git status" in the background. The done channel is closed when it
+// finishes, after which err and stderr can be read.
+type refresh struct {
+	done   chan struct{}
+	err    error
+	stderr bytes.Buffer
+}
+
+var (
+	cacheMutex sync.Mutex
+	cache      = make(map[string]cacheEntry)
+	// Refreshes in progress, keyed by the Git directory. There is at most one
+	// for each repository.
+	refreshes = make(map[string]*refresh)
+)
+
+type statusOpts struct {
+	Dir    string
+	MaxAge float64
+	Wait   bool
+}
+
+func (opts *statusOpts) SetDefaultOptions() { opts.MaxAge = 5 }
+
+func status(fm *eval.Frame, opts statusOpts) (any, error) {
+	if err := fm.Evaler.CheckRestricted("running external command git"); err != nil {
+		return nil, err
+	}
+	dir := opts.Dir
+	if dir == "" {
+		var err error
+		dir, err = os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return Status(fm.Context(), fm.ErrorFile(), dir, opts.MaxAge, opts.Wait)
+}
+
+// Status returns the status of the Git repository containing dir as a map, in
+// the same format and with the same caching as git:status. It returns nil if
+// dir is not inside a Git repository.
+//
+// When the cached status is no longer valid, "git status" is run in the
+// background and the stale status is returned, unless wait is true or there is
+// no cached status. When Status waits for "git status", its standard error is
+// written to stderr.
+func Status(ctx context.Context, stderr io.Writer, dir string, maxAge float64, wait bool) (any, error) {
+	root, gitDir, ok := findRepo(dir)
+	if !ok {
+		return nil, nil
+	}
+
+	// Changes to the working tree don't change any of the files checked by
+	// stateMtimes, so cached statuses also expire after &max-age seconds.
+	now := time.Now()
+	mtimes := stateMtimes(gitDir)
+	cacheMutex.Lock()
+	entry, cached := cache[gitDir]
+	if cached && entry.mtimes == mtimes &&
+		now.Sub(entry.time).Seconds() < maxAge {
+		cacheMutex.Unlock()
+		return entry.status.toMap(false), nil
+	}
+	r, ok := refreshes[gitDir]
+	if !ok {
+		r = &refresh{done: make(chan struct{})}
+		refreshes[gitDir] = r
+		go r.run(root, gitDir, now, mtimes)
+	}
+	cacheMutex.Unlock()
+
+	if cached && !wait {
+		return entry.status.toMap(true), nil
+	}
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	stderr.Write(r.stderr.Bytes())
+	if r.err != nil {
+		return nil, r.err
+	}
+	cacheMutex.Lock()
+	entry = cache[gitDir]
+	cacheMutex.Unlock()
+	return entry.status.toMap(false), nil
+}
+
+// Runs "git status" and stores the result in the cache. The refresh is not
+// tied to the context of any caller, since callers that don't wait for it
+// return before it finishes.
+func (r *refresh) run(root, gitDir string, start time.Time, mtimes [3]time.Time) {
+	defer func() {
+		cacheMutex.Lock()
+		delete(refreshes, gitDir)
+		cacheMutex.Unlock()
+		close(r.done)
+	}()
+	cmd := exec.Command("git", "-C", root, "status", "--porcelain=v2", "--branch")
+	// Don't take locks that may interfere with other Git commands the user is
+	// running; this is what other prompt tools do too.
+	cmd.Env = append(os.Environ(), "GIT_OPTIONAL_LOCKS=0")
+	cmd.Stderr = &r.stderr
+	out, err := cmd.Output()
+	if err != nil {
+		r.err = err
+		return
+	}
+	s, err := parseStatus(out)
+	if err != nil {
+		r.err = err
+		return
+	}
+	s.root = root
+
+	cacheMutex.Lock()
+	cache[gitDir] = cacheEntry{s, start, mtimes}
+	cacheMutex.Unlock()
+}
+
+// Finds the Git repository containing dir, returning the root of its working
+// tree and its Git directory.
+func findRepo(dir string) (root, gitDir string, ok bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", false
+	}
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			if info.IsDir() {
+				return dir, dotGit, true
+			}
+			// In linked worktrees and submodules, .git is a file containing
+			// the path of the Git directory.
+			if content, err := os.ReadFile(dotGit); err == nil {
+				if target, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir: "); ok {
+					if !filepath.IsAbs(target) {
+						target = filepath.Join(dir, target)
+					}
+					return dir, target, true
+				}
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", false
+		}
+		dir = parent
+	}
+}
+
+// Returns the modification times of HEAD, the index and the ref HEAD points
+// to. A cached status is considered valid as long as none of them change.
+func stateMtimes(gitDir string) [3]time.Time {
+	var mtimes [3]time.Time
+	mtime := func(path string) time.Time {
+		if info, err := os.Stat(path); err == nil {
+			return info.ModTime()
+		}
+		return time.Time{}
+	}
+	head := filepath.Join(gitDir, "HEAD")
+	mtimes[0] = mtime(head)
+	mtimes[1] = mtime(filepath.Join(gitDir, "index"))
+	if content, err := os.ReadFile(head); err == nil {
+		if ref, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "ref: "); ok {
+			mtimes[2] = mtime(filepath.Join(refsDir(gitDir), filepath.FromSlash(ref)))
+		}
+	}
+	return mtimes
+}
+
+// Returns the directory that contains the refs of the repository. For linked
+// worktrees, this is the Git directory of the main worktree.
+func refsDir(gitDir string) string {
+	if content, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		common := strings.TrimSpace(string(content))
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(gitDir, common)
+		}
+		return common
+	}
+	return gitDir
+}
+
+var errBadStatusOutput = errors.New("bad output from git status")
+
+// Parses the output of "git status --porcelain=v2 --branch".
+func parseStatus(out []byte) (*repoStatus, error) {
+	s := &repoStatus{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if header, ok := strings.CutPrefix(line, "# "); ok {
+			key, value, _ := strings.Cut(header, " ")
+			switch key {
+			case "branch.oid":
+				if value != "(initial)" {
+					s.commit = value
+				}
+			case "branch.head":
+				if value != "(detached)" {
+					s.branch = value
+				}
+			case "branch.upstream":
+				s.upstream = value
+			case "branch.ab":
+				var ok bool
+				aheadStr, behindStr, _ := strings.Cut(value, " ")
+				s.ahead, ok = parseCount(aheadStr, "+")
+				if !ok {
+					return nil, errBadStatusOutput
+				}
+				s.behind, ok = parseCount(behindStr, "-")
+				if !ok {
+					return nil, errBadStatusOutput
+				}
+			}
+			continue
+		}
+		kind, rest, _ := strings.Cut(line, " ")
+		switch kind {
+		case "1", "2":
+			if len(rest) < 2 {
+				return nil, errBadStatusOutput
+			}
+			if rest[0] != '.' {
+				s.staged++
+			}
+			if rest[1] != '.' {
+				s.unstaged++
+			}
+		case "u":
+			s.conflicts++
+		case "?":
+			s.untracked++
+		}
+	}
+	return s, nil
+}
+
+func parseCount(s, prefix string) (int, bool) {
+	s, ok := strings.CutPrefix(s, prefix)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
@@ -0,0 +1,34 @@
+package git_test
+
+import (
+	"embed"
+	"os/exec"
+	"testing"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/evaltest"
+	"src.elv.sh/pkg/mods/git"
+	"src.elv.sh/pkg/testutil"
+)
+
+//go:embed *.elvts
+var transcripts embed.FS
+
+func TestTranscripts(t *testing.T) {
+	evaltest.TestTranscriptsInFS(t, transcripts,
+		"add-git-ns", func(ev *eval.Evaler) { ev.AddModule("git", git.Ns) },
+		"git-or-skip", func(t *testing.T) {
+			if _, err := exec.LookPath("git"); err != nil {
+				t.Skip("git not found")
+			}
+			// Isolate from the user's configuration, and make commits
+			// deterministic.
+			testutil.Setenv(t, "GIT_CONFIG_GLOBAL", "")
+			testutil.Setenv(t, "GIT_CONFIG_NOSYSTEM", "1")
+			for _, name := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+				testutil.Setenv(t, name+"_NAME", "Elvish")
+				testutil.Setenv(t, name+"_EMAIL", "elvish@example.com")
+			}
+		},
+	)
+}
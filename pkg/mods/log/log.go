@@ -0,0 +1,181 @@
+// Package log implements the log: module.
+package log
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/errs"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/eval/vars"
+	"src.elv.sh/pkg/logutil"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/store/storedefs"
+)
+
+// Ns is the namespace for the log: module.
+var Ns = eval.BuildNsNamed("log").
+	AddVars(map[string]vars.Var{
+		"level": vars.FromSetGet(setLevel, getLevel),
+	}).
+	AddGoFns(map[string]any{
+		"set-output": setOutput,
+		"subsystems": subsystems,
+		"enable":     enable,
+		"disable":    disable,
+		"log":        log,
+	}).Ns()
+
+func getLevel() any { return logutil.GetLevel().String() }
+
+func setLevel(v any) error {
+	level, err := parseLevel(v)
+	if err != nil {
+		return err
+	}
+	logutil.SetLevel(level)
+	return nil
+}
+
+func parseLevel(v any) (logutil.Level, error) {
+	s, ok := v.(string)
+	if ok {
+		level, err := logutil.ParseLevel(s)
+		if err == nil {
+			return level, nil
+		}
+	}
+	return 0, errs.BadValue{What: "log level",
+		Valid: "debug, info, warn or error", Actual: vals.ReprPlain(v)}
+}
+
+var (
+	storeMutex sync.Mutex
+	logStore   storedefs.Store
+)
+
+// SetStore sets the store that log messages are written to when the output is
+// set to "store" with log:set-output. A nil store makes the "store" output
+// unavailable, which is also the initial state.
+func SetStore(s storedefs.Store) {
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+	logStore = s
+}
+
+var errNoStore = errors.New("no store is available for logging")
+
+func setOutput(fm *eval.Frame, dest any) error {
+	switch dest := dest.(type) {
+	case string:
+		switch dest {
+		case "-":
+			logutil.SetOutput(os.Stderr)
+			return nil
+		case "store":
+			if err := fm.Evaler.CheckRestricted("writing to the store"); err != nil {
+				return err
+			}
+			storeMutex.Lock()
+			s := logStore
+			storeMutex.Unlock()
+			if s == nil {
+				return errNoStore
+			}
+			logutil.SetOutput(storeWriter{s})
+			return nil
+		}
+		if dest != "" {
+			if err := fm.Evaler.CheckRestricted("writing to file " + dest); err != nil {
+				return err
+			}
+		}
+		return logutil.SetOutputFile(dest)
+	case *os.File:
+		logutil.SetOutput(dest)
+		return nil
+	default:
+		return errs.BadValue{What: "log output",
+			Valid: "string or file", Actual: vals.Kind(dest)}
+	}
+}
+
+// The data namespace in the store that log messages are written to.
+const storeNS = "log"
+
+// Layout of the keys of log messages in the store. It has a fixed width, so
+// sorting the keys also sorts the messages by time.
+const storeKeyLayout = "2006-01-02T15:04:05.000000000Z"
+
+// An io.Writer that writes each log message as an entry in the store. Like
+// store:set-data, the entry is the repr of the message, so it can be read back
+// with store:data.
+type storeWriter struct{ s storedefs.Store }
+
+func (w storeWriter) Write(p []byte) (int, error) {
+	msg := parse.Quote(strings.TrimSuffix(string(p), "\n"))
+	t := time.Now().UTC()
+	for {
+		// Only set the key if it doesn't exist yet, and try the next
+		// nanosecond if it does, so that messages written at the same time
+		// don't overwrite each other.
+		ok, err := w.s.CompareAndSetData(storeNS, t.Format(storeKeyLayout), "", msg)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return len(p), nil
+		}
+		t = t.Add(time.Nanosecond)
+	}
+}
+
+func subsystems(fm *eval.Frame) error {
+	out := fm.ValueOutput()
+	for _, name := range logutil.Subsystems() {
+		err := out.Put(name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func enable(names ...string) {
+	for _, name := range names {
+		logutil.SetEnabled(name, true)
+	}
+}
+
+func disable(names ...string) {
+	for _, name := range names {
+		logutil.SetEnabled(name, false)
+	}
+}
+
+type logOpts struct {
+	Level     any
+	Subsystem string
+}
+
+func (opts *logOpts) SetDefaultOptions() {
+	opts.Level = "info"
+	opts.Subsystem = "script"
+}
+
+func log(opts logOpts, args ...any) error {
+	level, err := parseLevel(opts.Level)
+	if err != nil {
+		return err
+	}
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		strs[i] = vals.ToString(arg)
+	}
+	logutil.Log(opts.Subsystem, level, strings.Join(strs, " "))
+	return nil
+}
@@ -0,0 +1,36 @@
+package log_test
+
+import (
+	"embed"
+	"io"
+	"testing"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/evaltest"
+	"src.elv.sh/pkg/logutil"
+	"src.elv.sh/pkg/mods/log"
+	"src.elv.sh/pkg/mods/store"
+	storepkg "src.elv.sh/pkg/store"
+)
+
+//go:embed *.elvts *.elv
+var transcripts embed.FS
+
+func TestTranscripts(t *testing.T) {
+	evaltest.TestTranscriptsInFS(t, transcripts,
+		"reset-log", func(t *testing.T) {
+			t.Cleanup(func() {
+				logutil.SetOutput(io.Discard)
+				logutil.SetLevel(logutil.Debug)
+				logutil.SetEnabled("script", true)
+			})
+		},
+		"restricted", func(ev *eval.Evaler) { ev.Restricted = true },
+		"add-store", func(t *testing.T, ev *eval.Evaler) {
+			s := storepkg.MustTempStore(t)
+			log.SetStore(s)
+			t.Cleanup(func() { log.SetStore(nil) })
+			ev.ExtendGlobal(eval.BuildNs().AddNs("store", store.Ns(s)))
+		},
+	)
+}
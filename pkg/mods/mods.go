@@ -3,10 +3,13 @@ package mods
 
 import (
 	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/mods/activate"
 	"src.elv.sh/pkg/mods/doc"
 	"src.elv.sh/pkg/mods/epm"
 	"src.elv.sh/pkg/mods/file"
 	"src.elv.sh/pkg/mods/flag"
+	"src.elv.sh/pkg/mods/git"
+	"src.elv.sh/pkg/mods/log"
 	"src.elv.sh/pkg/mods/math"
 	"src.elv.sh/pkg/mods/md"
 	"src.elv.sh/pkg/mods/os"
@@ -15,11 +18,15 @@ import (
 	"src.elv.sh/pkg/mods/re"
 	readline_binding "src.elv.sh/pkg/mods/readline-binding"
 	"src.elv.sh/pkg/mods/runtime"
+	"src.elv.sh/pkg/mods/secret"
+	"src.elv.sh/pkg/mods/sh"
 	"src.elv.sh/pkg/mods/str"
+	"src.elv.sh/pkg/mods/test"
 	"src.elv.sh/pkg/mods/unix"
 )
 
-// AddTo adds all standard library modules to the Evaler.
+// AddTo adds all standard library modules to the Evaler, and the help builtin,
+// which depends on the documentation of all of them.
 //
 // Some modules (the runtime module for now) may rely on properties set on the
 // Evaler, so any mutations afterwards may not be properly reflected.
@@ -32,12 +39,19 @@ func AddTo(ev *eval.Evaler) {
 	ev.AddModule("str", str.Ns)
 	ev.AddModule("file", file.Ns)
 	ev.AddModule("flag", flag.Ns)
+	ev.AddModule("git", git.Ns)
+	ev.AddModule("log", log.Ns)
 	ev.AddModule("doc", doc.Ns)
+	ev.AddBuiltin("help", doc.Help)
 	ev.AddModule("os", os.Ns)
 	ev.AddModule("md", md.Ns)
+	ev.AddModule("secret", secret.Ns)
+	ev.AddModule("sh", sh.Ns)
+	ev.AddModule("test", test.Ns(&test.Results{}))
 	if unix.ExposeUnixNs {
 		ev.AddModule("unix", unix.Ns)
 	}
+	ev.BundledModules["activate"] = activate.Code
 	ev.BundledModules["epm"] = epm.Code
 	ev.BundledModules["readline-binding"] = readline_binding.Code
 }
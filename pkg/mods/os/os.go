@@ -26,10 +26,10 @@ var Ns = eval.BuildNsNamed("os").
 		// File CRUD.
 		"mkdir":      mkdir,
 		"mkdir-all":  mkdirAll,
-		"symlink":    os.Symlink,
+		"symlink":    symlink,
 		"remove":     remove,
 		"remove-all": removeAll,
-		"rename":     os.Rename,
+		"rename":     rename,
 		"chmod":      chmod,
 
 		// File query.
@@ -40,6 +40,9 @@ var Ns = eval.BuildNsNamed("os").
 
 		"eval-symlinks": filepath.EvalSymlinks,
 
+		// File watching.
+		"watch": watch,
+
 		// Temp file/dir.
 		"temp-dir":  TempDir,
 		"temp-file": TempFile,
@@ -59,33 +62,59 @@ type mkdirOpts struct{ Perm int }
 
 func (opts *mkdirOpts) SetDefaultOptions() { opts.Perm = 0755 }
 
-func mkdir(opts mkdirOpts, path string) error {
+func mkdir(fm *eval.Frame, opts mkdirOpts, path string) error {
+	if err := fm.Evaler.CheckRestricted("creating directory " + path); err != nil {
+		return err
+	}
 	return os.Mkdir(path, os.FileMode(opts.Perm))
 }
 
-func mkdirAll(opts mkdirOpts, path string) error {
+func mkdirAll(fm *eval.Frame, opts mkdirOpts, path string) error {
+	if err := fm.Evaler.CheckRestricted("creating directory " + path); err != nil {
+		return err
+	}
 	return os.MkdirAll(path, os.FileMode(opts.Perm))
 }
 
+func symlink(fm *eval.Frame, oldname, newname string) error {
+	if err := fm.Evaler.CheckRestricted("creating symlink " + newname); err != nil {
+		return err
+	}
+	return os.Symlink(oldname, newname)
+}
+
+func rename(fm *eval.Frame, oldpath, newpath string) error {
+	if err := fm.Evaler.CheckRestricted("renaming " + oldpath); err != nil {
+		return err
+	}
+	return os.Rename(oldpath, newpath)
+}
+
 // ErrEmptyPath is thrown by remove and remove-all when given an empty path.
 var ErrEmptyPath = errs.BadValue{
 	What: "path", Valid: "non-empty string", Actual: "empty string"}
 
 // Wraps [os.Remove] to reject empty paths.
-func remove(path string) error {
+func remove(fm *eval.Frame, path string) error {
 	if path == "" {
 		return ErrEmptyPath
 	}
+	if err := fm.Evaler.CheckRestricted("removing " + path); err != nil {
+		return err
+	}
 	return os.Remove(path)
 }
 
 // Wraps [os.RemoveAll] to reject empty paths, and resolve relative paths to
 // absolute paths first. The latter is necessary since the working directory
 // could be changed while [os.RemoveAll] is running.
-func removeAll(path string) error {
+func removeAll(fm *eval.Frame, path string) error {
 	if path == "" {
 		return ErrEmptyPath
 	}
+	if err := fm.Evaler.CheckRestricted("removing " + path); err != nil {
+		return err
+	}
 	if !filepath.IsAbs(path) {
 		absPath, err := filepath.Abs(path)
 		if err != nil {
@@ -102,7 +131,10 @@ type chmodOpts struct {
 
 func (*chmodOpts) SetDefaultOptions() {}
 
-func chmod(opts chmodOpts, perm int, path string) error {
+func chmod(fm *eval.Frame, opts chmodOpts, perm int, path string) error {
+	if err := fm.Evaler.CheckRestricted("changing the mode of " + path); err != nil {
+		return err
+	}
 	if perm < 0 || perm > 0x777 {
 		return errs.OutOfRange{What: "permission bits",
 			ValidLow: "0", ValidHigh: "0o777", Actual: strconv.Itoa(perm)}
@@ -163,21 +195,27 @@ func (o *mktempOpt) SetDefaultOptions() {}
 
 // TempDir is exported so that the implementation may be shared by the path:
 // module.
-func TempDir(opts mktempOpt, args ...string) (string, error) {
+func TempDir(fm *eval.Frame, opts mktempOpt, args ...string) (string, error) {
 	pattern, err := optionalTempPattern(args)
 	if err != nil {
 		return "", err
 	}
+	if err := fm.Evaler.CheckRestricted("creating temporary directory"); err != nil {
+		return "", err
+	}
 	return os.MkdirTemp(opts.Dir, pattern)
 }
 
 // TempFile is exported so that the implementation may be shared by the path:
 // module.
-func TempFile(opts mktempOpt, args ...string) (*os.File, error) {
+func TempFile(fm *eval.Frame, opts mktempOpt, args ...string) (*os.File, error) {
 	pattern, err := optionalTempPattern(args)
 	if err != nil {
 		return nil, err
 	}
+	if err := fm.Evaler.CheckRestricted("creating temporary file"); err != nil {
+		return nil, err
+	}
 	return os.CreateTemp(opts.Dir, pattern)
 }
 
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"testing"
 
+	"src.elv.sh/pkg/eval"
 	"src.elv.sh/pkg/eval/evaltest"
 	"src.elv.sh/pkg/must"
 	"src.elv.sh/pkg/testutil"
@@ -39,5 +40,6 @@ func TestTranscripts(t *testing.T) {
 			must.OK(os.Remove("test-symlink"))
 		},
 		"create-windows-special-files-or-skip", createWindowsSpecialFileOrSkip,
+		"restricted", func(ev *eval.Evaler) { ev.Restricted = true },
 	)
 }
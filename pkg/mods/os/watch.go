@@ -0,0 +1,56 @@
+package os
+
+import (
+	"context"
+	"time"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
+)
+
+// A change to the filesystem reported by watch.
+type watchEvent struct {
+	path string
+	op   string
+	time time.Time
+}
+
+// Possible values of watchEvent.op.
+const (
+	opCreate = "create"
+	opWrite  = "write"
+	opRemove = "remove"
+	opRename = "rename"
+	opChmod  = "chmod"
+)
+
+func (e watchEvent) toMap() vals.Map {
+	return vals.MakeMap(
+		"path", e.path,
+		"op", e.op,
+		"time", float64(e.time.UnixNano())/float64(time.Second))
+}
+
+type watchOpts struct{ Recursive bool }
+
+func (opts *watchOpts) SetDefaultOptions() {}
+
+func watch(fm *eval.Frame, opts watchOpts, paths ...string) error {
+	ctx, cancel := context.WithCancel(fm.Context())
+	defer cancel()
+	out := fm.ValueOutput()
+	var errPut error
+	err := watchPaths(ctx, paths, opts.Recursive, func(e watchEvent) bool {
+		errPut = out.Put(e.toMap())
+		return errPut == nil
+	})
+	if errPut != nil {
+		return errPut
+	}
+	if err != nil {
+		return err
+	}
+	// watchPaths only returns without an error when ctx is done, and ctx can
+	// only be done because of fm.Context().
+	return eval.ErrInterrupted
+}
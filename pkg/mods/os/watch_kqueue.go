@@ -0,0 +1,270 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package os
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const kqueueNotes = unix.NOTE_DELETE | unix.NOTE_WRITE | unix.NOTE_EXTEND |
+	unix.NOTE_ATTRIB | unix.NOTE_RENAME | unix.NOTE_REVOKE
+
+// Watches paths with kqueue, calling emit with each event until ctx is done or
+// emit returns false.
+//
+// Since kqueue watches file descriptors rather than paths, all the files and
+// directories in watched directories are opened. When a watched directory
+// changes, its entries are compared with the ones seen last time to find out
+// which files were created and removed.
+func watchPaths(ctx context.Context, paths []string, recursive bool, emit func(watchEvent) bool) error {
+	kq, err := unix.Kqueue()
+	if err != nil {
+		return err
+	}
+	unix.CloseOnExec(kq)
+	w := &kqueueWatcher{kq, recursive,
+		make(map[int]*kqueueFile), make(map[string]int)}
+	defer w.close()
+
+	// A pipe that becomes readable when ctx is done, to wake up the loop.
+	var p [2]int
+	if err := unix.Pipe(p[:]); err != nil {
+		return err
+	}
+	defer unix.Close(p[0])
+	unix.CloseOnExec(p[0])
+	unix.CloseOnExec(p[1])
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			unix.Write(p[1], []byte{0})
+		case <-stop:
+		}
+		unix.Close(p[1])
+	}()
+	if err := w.register(p[0], unix.EVFILT_READ, 0); err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if err := w.add(path, true, nil); err != nil {
+			return err
+		}
+	}
+
+	// Events are read one at a time, since handling an event may close file
+	// descriptors that later events in the same batch are about.
+	kevents := make([]unix.Kevent_t, 1)
+	for {
+		n, err := unix.Kevent(kq, nil, kevents, nil)
+		if err == unix.EINTR || (err == nil && n == 0) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if int(kevents[0].Ident) == p[0] {
+			return nil
+		}
+		events, err := w.handle(int(kevents[0].Ident), kevents[0].Fflags)
+		for _, e := range events {
+			if !emit(e) {
+				return nil
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+type kqueueWatcher struct {
+	kq        int
+	recursive bool
+	// Watched files, keyed by their file descriptors.
+	files map[int]*kqueueFile
+	// File descriptors of watched paths.
+	fds map[string]int
+}
+
+type kqueueFile struct {
+	path  string
+	isDir bool
+	// The directory this file was found in, or nil for a path passed to
+	// watchPaths.
+	parent *kqueueFile
+	// For directories whose entries are watched, the names of the entries
+	// seen last time; nil otherwise.
+	entries map[string]bool
+}
+
+func (w *kqueueWatcher) register(fd, filter int, fflags uint32) error {
+	var ev unix.Kevent_t
+	unix.SetKevent(&ev, fd, filter, unix.EV_ADD|unix.EV_ENABLE|unix.EV_CLEAR)
+	ev.Fflags = fflags
+	_, err := unix.Kevent(w.kq, []unix.Kevent_t{ev}, nil, nil)
+	return err
+}
+
+// Watches path. If it is a directory and watchEntries is true, its entries are
+// watched too.
+func (w *kqueueWatcher) add(path string, watchEntries bool, parent *kqueueFile) error {
+	if _, ok := w.fds[path]; ok {
+		return nil
+	}
+	// The file is opened non-blocking, since opening a FIFO would otherwise
+	// block.
+	fd, err := unix.Open(path, kqueueOpenFlag|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &fs.PathError{Op: "watch", Path: path, Err: err}
+	}
+	var st unix.Stat_t
+	err = unix.Fstat(fd, &st)
+	if err == nil {
+		err = w.register(fd, unix.EVFILT_VNODE, kqueueNotes)
+	}
+	if err != nil {
+		unix.Close(fd)
+		return &fs.PathError{Op: "watch", Path: path, Err: err}
+	}
+	f := &kqueueFile{path: path, isDir: st.Mode&unix.S_IFMT == unix.S_IFDIR, parent: parent}
+	w.files[fd] = f
+	w.fds[path] = fd
+	if !f.isDir || !watchEntries {
+		return nil
+	}
+	f.entries = make(map[string]bool)
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		f.entries[entry.Name()] = true
+		if err := w.addEntry(f, entry.Name(), entry.Type()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Watches an entry of a directory. Only regular files and directories are
+// opened. Errors other than running out of file descriptors are ignored, since
+// the entry may be gone already or not readable; in the latter case, only its
+// creation and removal are reported.
+func (w *kqueueWatcher) addEntry(dir *kqueueFile, name string, typ fs.FileMode) error {
+	if !typ.IsRegular() && !typ.IsDir() {
+		return nil
+	}
+	err := w.add(filepath.Join(dir.path, name), w.recursive, dir)
+	if errors.Is(err, unix.EMFILE) || errors.Is(err, unix.ENFILE) {
+		return err
+	}
+	return nil
+}
+
+// Stops watching a file, and the entries of it if it is a directory.
+func (w *kqueueWatcher) remove(f *kqueueFile) {
+	fd, ok := w.fds[f.path]
+	if !ok || w.files[fd] != f {
+		return
+	}
+	unix.Close(fd)
+	delete(w.files, fd)
+	delete(w.fds, f.path)
+	if f.parent != nil && f.parent.entries != nil {
+		delete(f.parent.entries, filepath.Base(f.path))
+	}
+	for name := range f.entries {
+		if fd, ok := w.fds[filepath.Join(f.path, name)]; ok {
+			w.remove(w.files[fd])
+		}
+	}
+}
+
+func (w *kqueueWatcher) handle(fd int, fflags uint32) ([]watchEvent, error) {
+	f, ok := w.files[fd]
+	if !ok {
+		return nil, nil
+	}
+	now := time.Now()
+	if fflags&(unix.NOTE_DELETE|unix.NOTE_RENAME|unix.NOTE_REVOKE) != 0 {
+		op := opRemove
+		if fflags&unix.NOTE_RENAME != 0 {
+			op = opRename
+		}
+		w.remove(f)
+		events := []watchEvent{{f.path, op, now}}
+		if f.parent != nil && f.parent.entries != nil {
+			// Another file may have replaced the removed one. This is found
+			// by rescanning the parent directory.
+			moreEvents, err := w.rescan(f.parent, now)
+			return append(events, moreEvents...), err
+		}
+		return events, nil
+	}
+	var events []watchEvent
+	if fflags&unix.NOTE_ATTRIB != 0 {
+		events = append(events, watchEvent{f.path, opChmod, now})
+	}
+	if fflags&(unix.NOTE_WRITE|unix.NOTE_EXTEND) != 0 {
+		if !f.isDir {
+			events = append(events, watchEvent{f.path, opWrite, now})
+		} else if f.entries != nil {
+			moreEvents, err := w.rescan(f, now)
+			return append(events, moreEvents...), err
+		}
+	}
+	return events, nil
+}
+
+// Compares the entries of a directory with the ones seen last time, watching
+// new entries, and returns events for entries that were created or removed.
+func (w *kqueueWatcher) rescan(dir *kqueueFile, now time.Time) ([]watchEvent, error) {
+	entries, err := os.ReadDir(dir.path)
+	if err != nil {
+		// The directory itself may have been removed, which is reported by an
+		// event on it.
+		return nil, nil
+	}
+	var events []watchEvent
+	old := dir.entries
+	dir.entries = make(map[string]bool)
+	for _, entry := range entries {
+		name := entry.Name()
+		dir.entries[name] = true
+		if old[name] {
+			continue
+		}
+		path := filepath.Join(dir.path, name)
+		if err := w.addEntry(dir, name, entry.Type()); err != nil {
+			return events, err
+		}
+		events = append(events, watchEvent{path, opCreate, now})
+	}
+	for name := range old {
+		if dir.entries[name] {
+			continue
+		}
+		path := filepath.Join(dir.path, name)
+		if fd, ok := w.fds[path]; ok {
+			w.remove(w.files[fd])
+		}
+		events = append(events, watchEvent{path, opRemove, now})
+	}
+	return events, nil
+}
+
+func (w *kqueueWatcher) close() {
+	for fd := range w.files {
+		unix.Close(fd)
+	}
+	unix.Close(w.kq)
+}
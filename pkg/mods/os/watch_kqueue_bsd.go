@@ -0,0 +1,7 @@
+//go:build dragonfly || freebsd || netbsd || openbsd
+
+package os
+
+import "golang.org/x/sys/unix"
+
+const kqueueOpenFlag = unix.O_RDONLY
@@ -0,0 +1,7 @@
+package os
+
+import "golang.org/x/sys/unix"
+
+// Opening files with O_EVTONLY doesn't prevent the volumes they are on from
+// being unmounted.
+const kqueueOpenFlag = unix.O_EVTONLY
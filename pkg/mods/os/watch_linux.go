@@ -0,0 +1,148 @@
+package os
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Returned when the kernel has dropped some events because too many changes
+// happened before they could be read.
+var errWatchOverflow = errors.New("too many changes to watch, some have been lost")
+
+const inotifyMask = unix.IN_CREATE | unix.IN_MODIFY | unix.IN_ATTRIB |
+	unix.IN_DELETE | unix.IN_DELETE_SELF |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_MOVE_SELF
+
+// Watches paths with inotify, calling emit with each event until ctx is done
+// or emit returns false.
+func watchPaths(ctx context.Context, paths []string, recursive bool, emit func(watchEvent) bool) error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return err
+	}
+	// Since fd is non-blocking, reads on f go through the runtime poller and
+	// can be interrupted by closing f.
+	f := os.NewFile(uintptr(fd), "inotify")
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		}
+		f.Close()
+	}()
+
+	w := inotifyWatcher{fd, make(map[int]string)}
+	for _, path := range paths {
+		if err := w.add(path, recursive); err != nil {
+			return err
+		}
+	}
+
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		for i := 0; i+unix.SizeofInotifyEvent <= n; {
+			raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[i]))
+			nameBytes := buf[i+unix.SizeofInotifyEvent : i+unix.SizeofInotifyEvent+int(raw.Len)]
+			i += unix.SizeofInotifyEvent + int(raw.Len)
+
+			if raw.Mask&unix.IN_Q_OVERFLOW != 0 {
+				return errWatchOverflow
+			}
+			dir, ok := w.paths[int(raw.Wd)]
+			if !ok {
+				continue
+			}
+			if raw.Mask&unix.IN_IGNORED != 0 {
+				delete(w.paths, int(raw.Wd))
+				continue
+			}
+			path := dir
+			if name := string(trimNUL(nameBytes)); name != "" {
+				path = filepath.Join(dir, name)
+			}
+			op := inotifyOp(raw.Mask)
+			if op == "" {
+				continue
+			}
+			if recursive && op == opCreate && raw.Mask&unix.IN_ISDIR != 0 {
+				// Errors are ignored, since the directory may be gone already.
+				w.add(path, true)
+			}
+			if !emit(watchEvent{path, op, time.Now()}) {
+				return nil
+			}
+		}
+	}
+}
+
+type inotifyWatcher struct {
+	fd int
+	// Paths of watch descriptors.
+	paths map[int]string
+}
+
+func (w inotifyWatcher) add(path string, recursive bool) error {
+	if !recursive {
+		return w.addOne(path)
+	}
+	return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p != path && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if p == path || d.IsDir() {
+			return w.addOne(p)
+		}
+		return nil
+	})
+}
+
+func (w inotifyWatcher) addOne(path string) error {
+	wd, err := unix.InotifyAddWatch(w.fd, path, inotifyMask)
+	if err != nil {
+		return &fs.PathError{Op: "watch", Path: path, Err: err}
+	}
+	w.paths[wd] = path
+	return nil
+}
+
+func inotifyOp(mask uint32) string {
+	switch {
+	case mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0:
+		return opCreate
+	case mask&unix.IN_MODIFY != 0:
+		return opWrite
+	case mask&(unix.IN_DELETE|unix.IN_DELETE_SELF) != 0:
+		return opRemove
+	case mask&(unix.IN_MOVED_FROM|unix.IN_MOVE_SELF) != 0:
+		return opRename
+	case mask&unix.IN_ATTRIB != 0:
+		return opChmod
+	}
+	return ""
+}
+
+func trimNUL(b []byte) []byte {
+	for len(b) > 0 && b[len(b)-1] == 0 {
+		b = b[:len(b)-1]
+	}
+	return b
+}
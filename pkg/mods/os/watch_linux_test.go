@@ -0,0 +1,47 @@
+package os_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/testutil"
+)
+
+func TestWatch_QueueOverflow(t *testing.T) {
+	maxQueued, err := strconv.Atoi(strings.TrimSpace(
+		must.ReadFileString("/proc/sys/fs/inotify/max_queued_events")))
+	if err != nil || maxQueued > 100000 {
+		t.Skip("can't find out or too large max_queued_events")
+	}
+	dir := testutil.TempDir(t)
+	events, done, _ := startWatch(t, "os:watch "+parse.Quote(dir))
+
+	time.Sleep(testutil.Scaled(100 * time.Millisecond))
+	// Don't read the events, so that the output of os:watch blocks and the
+	// kernel queue fills up.
+	for i := 0; i < maxQueued+200; i++ {
+		must.OK(os.WriteFile(filepath.Join(dir, fmt.Sprint(i)), nil, 0o644))
+	}
+	// Drain the events until os:watch stops by itself.
+	timeout := time.After(testutil.Scaled(10 * time.Second))
+	for {
+		select {
+		case <-events:
+			continue
+		case err = <-done:
+		case <-timeout:
+			t.Fatal("os:watch didn't stop after the queue overflowed")
+		}
+		break
+	}
+	if err == nil || !strings.Contains(err.Error(), "too many changes") {
+		t.Errorf("got error %v, want overflow error", err)
+	}
+}
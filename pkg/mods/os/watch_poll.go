@@ -0,0 +1,100 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package os
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// How often the filesystem is polled for changes.
+var pollInterval = 500 * time.Millisecond
+
+type pollState struct {
+	modTime time.Time
+	size    int64
+	mode    fs.FileMode
+}
+
+// Watches paths by polling, calling emit with each event until ctx is done or
+// emit returns false.
+//
+// TODO: Use ReadDirectoryChangesW on Windows.
+func watchPaths(ctx context.Context, paths []string, recursive bool, emit func(watchEvent) bool) error {
+	old, err := pollSnapshot(paths, recursive)
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		// Errors are ignored, since the watched paths may have been removed.
+		new, _ := pollSnapshot(paths, recursive)
+		now := time.Now()
+		for path, st := range new {
+			oldSt, ok := old[path]
+			var op string
+			switch {
+			case !ok:
+				op = opCreate
+			case st.mode != oldSt.mode:
+				op = opChmod
+			case st.modTime != oldSt.modTime || st.size != oldSt.size:
+				op = opWrite
+			default:
+				continue
+			}
+			if !emit(watchEvent{path, op, now}) {
+				return nil
+			}
+		}
+		for path := range old {
+			if _, ok := new[path]; !ok {
+				if !emit(watchEvent{path, opRemove, now}) {
+					return nil
+				}
+			}
+		}
+		old = new
+	}
+}
+
+// Records the state of paths, and if they are directories, their direct
+// children or, if recursive is true, all their descendants.
+func pollSnapshot(paths []string, recursive bool) (map[string]pollState, error) {
+	m := make(map[string]pollState)
+	record := func(path string, info fs.FileInfo) {
+		m[path] = pollState{info.ModTime(), info.Size(), info.Mode()}
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return m, err
+		}
+		record(path, info)
+		if !info.IsDir() {
+			continue
+		}
+		filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || p == path {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				record(p, info)
+			}
+			if d.IsDir() && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+	}
+	return m, nil
+}
@@ -0,0 +1,83 @@
+package os_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
+	osmod "src.elv.sh/pkg/mods/os"
+	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/testutil"
+)
+
+func TestWatch(t *testing.T) {
+	dir := testutil.TempDir(t)
+	events, _, stop := startWatch(t, "os:watch &recursive "+parse.Quote(dir))
+
+	// Give the watcher some time to start.
+	time.Sleep(testutil.Scaled(100 * time.Millisecond))
+	must.WriteFile(filepath.Join(dir, "foo"), "")
+	wantEvent(t, events, filepath.Join(dir, "foo"), "create")
+	must.OK(os.Remove(filepath.Join(dir, "foo")))
+	wantEvent(t, events, filepath.Join(dir, "foo"), "remove")
+
+	must.OK(os.Mkdir(filepath.Join(dir, "d"), 0o755))
+	wantEvent(t, events, filepath.Join(dir, "d"), "create")
+	time.Sleep(testutil.Scaled(100 * time.Millisecond))
+	must.WriteFile(filepath.Join(dir, "d", "bar"), "")
+	wantEvent(t, events, filepath.Join(dir, "d", "bar"), "create")
+
+	err := stop()
+	if eval.Reason(err) != eval.ErrInterrupted {
+		t.Errorf("got error %v, want interrupted", err)
+	}
+}
+
+// Starts evaluating code in the background, returning a channel of the value
+// outputs, a channel that receives the error when the evaluation ends, and a
+// function that cancels the evaluation and returns its error.
+func startWatch(t *testing.T, code string) (<-chan any, <-chan error, func() error) {
+	ev := eval.NewEvaler()
+	ev.ExtendGlobal(eval.BuildNs().AddNs("os", osmod.Ns))
+	ch := make(chan any, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- ev.Eval(parse.Source{Name: "[test]", Code: code},
+			eval.EvalCfg{Interrupts: ctx,
+				Ports: []*eval.Port{nil, {Chan: ch, File: eval.DevNull}}})
+	}()
+	return ch, errCh, func() error {
+		cancel()
+		select {
+		case err := <-errCh:
+			return err
+		case <-time.After(testutil.Scaled(5 * time.Second)):
+			t.Fatal("os:watch didn't stop after being interrupted")
+			return nil
+		}
+	}
+}
+
+// Waits for an event about path with the given op, skipping other events.
+func wantEvent(t *testing.T, events <-chan any, path, op string) {
+	t.Helper()
+	timeout := time.After(testutil.Scaled(5 * time.Second))
+	for {
+		select {
+		case e := <-events:
+			gotPath, _ := vals.Index(e, "path")
+			gotOp, _ := vals.Index(e, "op")
+			if gotPath == path && gotOp == op {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s event on %s", op, path)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+package secret
+
+var (
+	KeyringArgs    = &keyringArgs
+	GPGAgentSocket = &gpgAgentSocket
+)
@@ -0,0 +1,48 @@
+package secret
+
+import (
+	"bufio"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
+)
+
+// Returns the path of the socket of gpg-agent. Can be overridden in tests.
+var gpgAgentSocket = func() (string, error) {
+	out, err := exec.Command("gpgconf", "--list-dirs", "agent-socket").Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+func gpgAgent(fm *eval.Frame) (vals.Map, error) {
+	err := fm.Evaler.CheckRestricted("running external command gpgconf")
+	if err != nil {
+		return nil, err
+	}
+	socket, err := gpgAgentSocket()
+	if err != nil {
+		// GnuPG is not installed.
+		socket = ""
+	}
+	return vals.MakeMap("running", gpgAgentRunning(socket), "socket", socket), nil
+}
+
+// Reports whether gpg-agent is listening on the socket, by checking that it
+// greets new connections with an OK response, as required by the Assuan
+// protocol.
+func gpgAgentRunning(socket string) bool {
+	if socket == "" {
+		return false
+	}
+	conn, err := net.DialTimeout("unix", socket, agentTimeout)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(agentTimeout))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	return err == nil && (line == "OK\n" || strings.HasPrefix(line, "OK "))
+}
@@ -0,0 +1,54 @@
+package secret
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"src.elv.sh/pkg/eval"
+)
+
+var errKeyringNotSupported = errors.New("keyring is not supported on " + runtime.GOOS)
+
+// Returns the command and arguments for looking up a password in the keyring
+// of the OS. Can be overridden in tests.
+var keyringArgs = func(service, account string) []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"security", "find-generic-password",
+			"-s", service, "-a", account, "-w"}
+	case "windows":
+		return nil
+	default:
+		// Works with any implementation of the freedesktop.org Secret Service
+		// API, like GNOME Keyring and KWallet.
+		return []string{"secret-tool", "lookup",
+			"service", service, "account", account}
+	}
+}
+
+func fromKeyring(fm *eval.Frame, service, account string) (*secret, error) {
+	args := keyringArgs(service, account)
+	if args == nil {
+		return nil, errKeyringNotSupported
+	}
+	err := fm.Evaler.CheckRestricted("running external command " + args[0])
+	if err != nil {
+		return nil, err
+	}
+	var stdout bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	err = cmd.Run()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf(
+				"no secret for service %s and account %s in keyring", service, account)
+		}
+		return nil, err
+	}
+	return &secret{strings.TrimSuffix(stdout.String(), "\n")}, nil
+}
@@ -0,0 +1,44 @@
+// Package secret implements the secret: module.
+package secret
+
+import (
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/persistent/hash"
+)
+
+// Ns is the namespace for the secret: module.
+var Ns = eval.BuildNsNamed("secret").
+	AddGoFns(map[string]any{
+		"new":          newSecret,
+		"reveal":       reveal,
+		"from-keyring": fromKeyring,
+		"ssh-agent":    sshAgent,
+		"gpg-agent":    gpgAgent,
+	}).Ns()
+
+// A string that is not shown when converted to a string or printed, so that it
+// doesn't end up in terminal scrollback, logs or tracebacks by accident.
+type secret struct{ value string }
+
+func newSecret(value string) *secret { return &secret{value} }
+
+func reveal(s *secret) string { return s.value }
+
+// Kind returns "secret".
+func (*secret) Kind() string { return "secret" }
+
+// Repr returns an opaque representation that doesn't contain the value.
+func (*secret) Repr(int) string { return "<secret>" }
+
+// String returns the same string as Repr, so that secrets are not revealed
+// when used as strings, for example as arguments to external commands.
+func (*secret) String() string { return "<secret>" }
+
+// Equal compares the values of secrets.
+func (s *secret) Equal(rhs any) bool {
+	r, ok := rhs.(*secret)
+	return ok && s.value == r.value
+}
+
+// Hash returns the hash of the value.
+func (s *secret) Hash() uint32 { return hash.String(s.value) }
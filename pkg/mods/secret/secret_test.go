@@ -0,0 +1,83 @@
+package secret_test
+
+import (
+	"embed"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/evaltest"
+	"src.elv.sh/pkg/mods/secret"
+	"src.elv.sh/pkg/testutil"
+)
+
+//go:embed *.elvts
+var transcripts embed.FS
+
+func TestTranscripts(t *testing.T) {
+	evaltest.TestTranscriptsInFS(t, transcripts,
+		"add-secret-ns", func(ev *eval.Evaler) { ev.AddModule("secret", secret.Ns) },
+		"fake-keyring", func(t *testing.T) {
+			testutil.Set(t, secret.KeyringArgs, func(service, account string) []string {
+				return []string{"sh", "-c",
+					`[ "$0" = svc ] && [ "$1" = me ] && echo hunter2`, service, account}
+			})
+		},
+		"fake-ssh-agent", func(t *testing.T) {
+			socket := listen(t, func(conn net.Conn) {
+				// Read the request, and answer with one key.
+				io.ReadFull(conn, make([]byte, 5))
+				blob := sshString([]byte("ssh-ed25519"), []byte("key"))
+				msg := append([]byte{12}, sshUint32(1)...)
+				msg = append(msg, sshString(blob, []byte("me@host"))...)
+				conn.Write(append(sshUint32(uint32(len(msg))), msg...))
+			})
+			testutil.Setenv(t, "SSH_AUTH_SOCK", socket)
+		},
+		"fake-gpg-agent", func(t *testing.T) {
+			socket := listen(t, func(conn net.Conn) {
+				io.WriteString(conn, "OK Pleased to meet you\n")
+			})
+			testutil.Set(t, secret.GPGAgentSocket,
+				func() (string, error) { return socket, nil })
+		},
+	)
+}
+
+// Listens on a Unix socket in a temporary directory, and serves each
+// connection with f.
+func listen(t *testing.T, f func(net.Conn)) string {
+	socket := filepath.Join(testutil.TempDir(t), "sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			f(conn)
+			conn.Close()
+		}
+	}()
+	return socket
+}
+
+func sshUint32(n uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, n)
+}
+
+func sshString(ss ...[]byte) []byte {
+	var b []byte
+	for _, s := range ss {
+		b = append(b, sshUint32(uint32(len(s)))...)
+		b = append(b, s...)
+	}
+	return b
+}
@@ -0,0 +1,122 @@
+package secret
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"src.elv.sh/pkg/eval/vals"
+)
+
+// Message numbers in the SSH agent protocol; see
+// https://datatracker.ietf.org/doc/html/draft-miller-ssh-agent.
+const (
+	sshAgentFailure           = 5
+	sshAgentRequestIdentities = 11
+	sshAgentIdentitiesAnswer  = 12
+)
+
+// How long to wait for agents to respond.
+const agentTimeout = time.Second
+
+var (
+	errSSHAgentFailure     = errors.New("ssh-agent refused to list keys")
+	errSSHAgentBadResponse = errors.New("bad response from ssh-agent")
+)
+
+func sshAgent() (vals.Map, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	status := vals.MakeMap("running", false, "socket", socket, "keys", vals.EmptyList)
+	if socket == "" {
+		return status, nil
+	}
+	conn, err := net.DialTimeout("unix", socket, agentTimeout)
+	if err != nil {
+		return status, nil
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(agentTimeout))
+	keys, err := sshAgentKeys(conn)
+	if err != nil {
+		return nil, err
+	}
+	return status.Assoc("running", true).Assoc("keys", keys), nil
+}
+
+// Lists the keys held by an SSH agent.
+func sshAgentKeys(rw io.ReadWriter) (vals.List, error) {
+	_, err := rw.Write([]byte{0, 0, 0, 1, sshAgentRequestIdentities})
+	if err != nil {
+		return nil, err
+	}
+	var size uint32
+	err = binary.Read(rw, binary.BigEndian, &size)
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 || size > 1<<20 {
+		return nil, errSSHAgentBadResponse
+	}
+	msg := make([]byte, size)
+	_, err = io.ReadFull(rw, msg)
+	if err != nil {
+		return nil, err
+	}
+	switch msg[0] {
+	case sshAgentIdentitiesAnswer:
+	case sshAgentFailure:
+		return nil, errSSHAgentFailure
+	default:
+		return nil, errSSHAgentBadResponse
+	}
+	r := sshReader{msg[1:]}
+	n, ok := r.uint32()
+	if !ok {
+		return nil, errSSHAgentBadResponse
+	}
+	keys := vals.EmptyList
+	for i := uint32(0); i < n; i++ {
+		blob, ok1 := r.string()
+		comment, ok2 := r.string()
+		if !ok1 || !ok2 {
+			return nil, errSSHAgentBadResponse
+		}
+		keyType, ok := (&sshReader{blob}).string()
+		if !ok {
+			return nil, errSSHAgentBadResponse
+		}
+		sum := sha256.Sum256(blob)
+		keys = keys.Conj(vals.MakeMap(
+			"type", string(keyType),
+			"fingerprint", "SHA256:"+base64.RawStdEncoding.EncodeToString(sum[:]),
+			"comment", string(comment)))
+	}
+	return keys, nil
+}
+
+// Reads data types used in the SSH protocol.
+type sshReader struct{ data []byte }
+
+func (r *sshReader) uint32() (uint32, bool) {
+	if len(r.data) < 4 {
+		return 0, false
+	}
+	n := binary.BigEndian.Uint32(r.data)
+	r.data = r.data[4:]
+	return n, true
+}
+
+func (r *sshReader) string() ([]byte, bool) {
+	n, ok := r.uint32()
+	if !ok || uint32(len(r.data)) < n {
+		return nil, false
+	}
+	s := r.data[:n]
+	r.data = r.data[n:]
+	return s, true
+}
@@ -0,0 +1,282 @@
+// Package session implements checkpoints of interactive sessions, and the
+// session: module for restoring sessions that ended unexpectedly.
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
+)
+
+// State is the state of an interactive session saved in a checkpoint.
+type State struct {
+	PID  int       `json:"pid"`
+	Time time.Time `json:"time"`
+	Pwd  string    `json:"pwd"`
+	// The directory stack maintained by pushd and popd, with the top last.
+	DirStack []string `json:"dir-stack"`
+	// The command line being run, or an empty string if the session was
+	// waiting for input.
+	Running string `json:"running"`
+	// The sources of the background jobs that were running.
+	BgJobs []string `json:"bg-jobs"`
+}
+
+var (
+	timeNow       = time.Now
+	processExists = isAlive
+)
+
+// Checkpointer saves the state of the current interactive session in a file
+// named after its PID.
+type Checkpointer struct {
+	dir string
+	ev  *eval.Evaler
+
+	mu      sync.Mutex
+	running string
+	// Set by Remove; no more checkpoints are saved after that.
+	removed bool
+}
+
+// NewCheckpointer creates a Checkpointer that saves checkpoints in the given
+// directory, which is created when the first checkpoint is saved.
+func NewCheckpointer(dir string, ev *eval.Evaler) *Checkpointer {
+	return &Checkpointer{dir: dir, ev: ev}
+}
+
+func (c *Checkpointer) path() string {
+	return filepath.Join(c.dir, strconv.Itoa(os.Getpid())+".json")
+}
+
+// SetRunning records the command line being run, or an empty string when the
+// command has finished, and saves a checkpoint.
+func (c *Checkpointer) SetRunning(code string) error {
+	c.mu.Lock()
+	c.running = code
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// Save saves a checkpoint. It does nothing after Remove has been called.
+func (c *Checkpointer) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.removed {
+		return nil
+	}
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(State{
+		PID: os.Getpid(), Time: timeNow(), Pwd: pwd,
+		DirStack: c.ev.DirStack(), Running: c.running, BgJobs: c.ev.BgJobs()})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that a crash never leaves a partially
+	// written checkpoint behind.
+	f, err := os.CreateTemp(c.dir, ".checkpoint-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path())
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// Start saves a checkpoint every interval in the background, until the
+// returned function is called; the function waits for any checkpoint being
+// saved. Errors are ignored, since a failed periodic checkpoint is no reason to
+// disrupt the session.
+func (c *Checkpointer) Start(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case <-ticker.C:
+				c.Save()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+		<-exited
+	}
+}
+
+// Remove removes the checkpoint of the current session, and stops further
+// checkpoints from being saved. It should be called when the session ends
+// normally.
+func (c *Checkpointer) Remove() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removed = true
+	err := os.Remove(c.path())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Crashed returns the states saved in the checkpoints in the given directory
+// by sessions whose processes no longer exist, from the newest to the oldest.
+// Files that can't be read as checkpoints are skipped.
+func Crashed(dir string) ([]State, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var states []State
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		pid, err := strconv.Atoi(strings.TrimSuffix(name, ".json"))
+		if err != nil || processExists(pid) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		var state State
+		if json.Unmarshal(data, &state) != nil || state.PID != pid {
+			continue
+		}
+		states = append(states, state)
+	}
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].Time.After(states[j].Time)
+	})
+	return states, nil
+}
+
+// Discard removes the checkpoint of a crashed session.
+func Discard(dir string, pid int) error {
+	return os.Remove(filepath.Join(dir, strconv.Itoa(pid)+".json"))
+}
+
+var errNoCrashedSession = errors.New("no crashed session")
+
+// Ns returns the namespace for the session: module, which works with the
+// checkpoints in the given directory.
+func Ns(dir string) *eval.Ns {
+	return eval.BuildNsNamed("session").
+		AddGoFns(map[string]any{
+			"crashed": crashed(dir),
+			"restore": restore(dir),
+			"discard": discard(dir),
+		}).Ns()
+}
+
+func crashed(dir string) func(*eval.Frame) error {
+	return func(fm *eval.Frame) error {
+		states, err := Crashed(dir)
+		if err != nil {
+			return err
+		}
+		out := fm.ValueOutput()
+		for _, state := range states {
+			err := out.Put(vals.MakeMap(
+				"pid", state.PID,
+				"time", state.Time.Format(time.RFC3339),
+				"pwd", state.Pwd,
+				"dir-stack", vals.MakeListSlice(state.DirStack),
+				"running", state.Running,
+				"bg-jobs", vals.MakeListSlice(state.BgJobs)))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+type pidOpts struct{ PID int }
+
+func (o *pidOpts) SetDefaultOptions() {}
+
+// Finds the crashed session with the given PID, or the newest one if the PID
+// is 0.
+func findCrashed(dir string, pid int) (State, error) {
+	states, err := Crashed(dir)
+	if err != nil {
+		return State{}, err
+	}
+	for _, state := range states {
+		if pid == 0 || state.PID == pid {
+			return state, nil
+		}
+	}
+	return State{}, errNoCrashedSession
+}
+
+func restore(dir string) func(*eval.Frame, pidOpts) error {
+	return func(fm *eval.Frame, opts pidOpts) error {
+		state, err := findCrashed(dir, opts.PID)
+		if err != nil {
+			return err
+		}
+		if err := fm.Evaler.Chdir(state.Pwd); err != nil {
+			return err
+		}
+		fm.Evaler.SetDirStack(state.DirStack)
+		return Discard(dir, state.PID)
+	}
+}
+
+func discard(dir string) func(pidOpts) error {
+	return func(opts pidOpts) error {
+		if opts.PID != 0 {
+			state, err := findCrashed(dir, opts.PID)
+			if err != nil {
+				return err
+			}
+			return Discard(dir, state.PID)
+		}
+		states, err := Crashed(dir)
+		if err != nil {
+			return err
+		}
+		for _, state := range states {
+			if err := Discard(dir, state.PID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,107 @@
+package session_test
+
+import (
+	"embed"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/evaltest"
+	"src.elv.sh/pkg/mods/session"
+	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/testutil"
+)
+
+//go:embed *.elvts
+var transcripts embed.FS
+
+func TestTranscripts(t *testing.T) {
+	evaltest.TestTranscriptsInFS(t, transcripts,
+		"use-session-with-checkpoints", func(t *testing.T, ev *eval.Evaler) {
+			testutil.InTempDir(t)
+			must.MkdirAll("a", "b", "sessions")
+			a, b := must.OK1(filepath.Abs("a")), must.OK1(filepath.Abs("b"))
+			writeCheckpoint(t, session.State{
+				PID: 100, Time: time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC),
+				Pwd: a, DirStack: []string{b}, Running: "make",
+				BgJobs: []string{"sleep 100"}})
+			writeCheckpoint(t, session.State{
+				PID: 200, Time: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC),
+				Pwd: b})
+			// A session that is still running.
+			writeCheckpoint(t, session.State{PID: 300, Pwd: a})
+			// Files that are not checkpoints.
+			must.WriteFile("sessions/400.json", "corrupt")
+			must.WriteFile("sessions/notes.txt", "")
+			testutil.Set(t, session.ProcessExists, func(pid int) bool { return pid == 300 })
+
+			ev.ExtendGlobal(eval.BuildNs().AddNs("session",
+				session.Ns(must.OK1(filepath.Abs("sessions")))))
+		},
+	)
+}
+
+func writeCheckpoint(t *testing.T, state session.State) {
+	t.Helper()
+	must.WriteFile(filepath.Join("sessions", strconv.Itoa(state.PID)+".json"),
+		string(must.OK1(json.Marshal(state))))
+}
+
+func TestCheckpointer(t *testing.T) {
+	testutil.InTempDir(t)
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	testutil.Set(t, session.TimeNow, func() time.Time { return now })
+	testutil.Set(t, session.ProcessExists, func(int) bool { return false })
+	ev := eval.NewEvaler()
+	ev.SetDirStack([]string{"/foo"})
+	c := session.NewCheckpointer("sessions", ev)
+
+	must.OK(c.SetRunning("make"))
+	states := must.OK1(session.Crashed("sessions"))
+	want := []session.State{{
+		PID: os.Getpid(), Time: now, Pwd: must.OK1(os.Getwd()),
+		DirStack: []string{"/foo"}, Running: "make", BgJobs: []string{}}}
+	if !reflect.DeepEqual(states, want) {
+		t.Errorf("got states %v, want %v", states, want)
+	}
+
+	must.OK(c.Remove())
+	if states := must.OK1(session.Crashed("sessions")); len(states) != 0 {
+		t.Errorf("got states %v after Remove, want none", states)
+	}
+	// Removing a checkpoint that doesn't exist is not an error.
+	must.OK(c.Remove())
+
+	// No more checkpoints are saved after Remove.
+	must.OK(c.Save())
+	if states := must.OK1(session.Crashed("sessions")); len(states) != 0 {
+		t.Errorf("got states %v after Save following Remove, want none", states)
+	}
+}
+
+func TestCheckpointer_Start(t *testing.T) {
+	testutil.InTempDir(t)
+	testutil.Set(t, session.ProcessExists, func(int) bool { return false })
+	c := session.NewCheckpointer("sessions", eval.NewEvaler())
+
+	stop := c.Start(time.Millisecond)
+	defer stop()
+	deadline := time.Now().Add(testutil.Scaled(time.Second))
+	for {
+		if states, _ := session.Crashed("sessions"); len(states) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the periodic checkpoint")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	stop()
+	// Calling stop more than once is harmless.
+	stop()
+}
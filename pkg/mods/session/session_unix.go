@@ -0,0 +1,11 @@
+//go:build unix
+
+package session
+
+import "syscall"
+
+func isAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	// EPERM means that the process exists but we can't send signals to it.
+	return err == nil || err == syscall.EPERM
+}
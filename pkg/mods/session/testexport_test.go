@@ -0,0 +1,6 @@
+package session
+
+var (
+	ProcessExists = &processExists
+	TimeNow       = &timeNow
+)
@@ -0,0 +1,160 @@
+// Package sh implements the sh: module.
+package sh
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"src.elv.sh/pkg/env"
+	"src.elv.sh/pkg/eval"
+)
+
+// Ns is the namespace for the sh: module.
+var Ns = eval.BuildNsNamed("sh").
+	AddGoFns(map[string]any{
+		"eval": evalSh,
+	}).Ns()
+
+// Environment variables that are maintained by the shell itself, and not
+// imported back.
+var ignoredEnv = map[string]bool{
+	env.PWD: true, "OLDPWD": true, env.SHLVL: true, "_": true,
+}
+
+// Names of the environment variables the code and the paths of the files to
+// dump the environment to are passed in. They are unset before the
+// environment is dumped, so they are neither seen by the code nor imported.
+const (
+	codeEnv   = "__elvish_sh_code"
+	beforeEnv = "__elvish_sh_before"
+	afterEnv  = "__elvish_sh_after"
+)
+
+// The script run by the shell. The code and the paths are passed in
+// environment variables rather than as arguments so that the code doesn't see
+// them as positional parameters. The code is evaluated with eval so that it
+// doesn't need to be quoted.
+//
+// The environment is dumped before the code runs, and after the code
+// finishes. The latter is done both explicitly and in an EXIT trap, since the
+// code may call exit; it can still be skipped if the code replaces the trap or
+// the shell itself with exec, in which case the dump is missing.
+//
+// The environment is dumped with awk, since "env -0" is not portable. Each
+// variable is written on its own line, with "%" and newlines in the value
+// percent-encoded, and the dump ends with a line containing just ".", so that
+// a missing dump can be told apart from an empty environment.
+const script = `__elvish_awk=$(command -v awk)
+__elvish_code=$` + codeEnv + `
+__elvish_before=$` + beforeEnv + `
+__elvish_after=$` + afterEnv + `
+unset ` + codeEnv + ` ` + beforeEnv + ` ` + afterEnv + `
+__elvish_dump() {
+  "$__elvish_awk" 'BEGIN { for (k in ENVIRON) { v = ENVIRON[k]; gsub(/%/, "%25", v); gsub(/\n/, "%0A", v); print k "=" v }; print "." }'
+}
+__elvish_dump_after() {
+  if [ -z "$__elvish_dumped" ]; then
+    __elvish_dumped=1
+    __elvish_dump > "$__elvish_after"
+  fi
+}
+__elvish_dump > "$__elvish_before"
+trap '__elvish_status=$?; __elvish_dump_after; exit $__elvish_status' EXIT
+eval "$__elvish_code"
+__elvish_status=$?
+__elvish_dump_after
+exit $__elvish_status`
+
+var errNoEnvDump = errors.New("the shell exited without writing its environment, possibly because the code replaced the EXIT trap or used exec")
+
+type evalOpts struct{ Sh string }
+
+func (opts *evalOpts) SetDefaultOptions() { opts.Sh = "/bin/sh" }
+
+func evalSh(fm *eval.Frame, opts evalOpts, code string) error {
+	if err := fm.Evaler.CheckRestricted("running external command " + opts.Sh); err != nil {
+		return err
+	}
+	var envFiles [2]string
+	for i := range envFiles {
+		f, err := os.CreateTemp("", "elvish-sh-env-*")
+		if err != nil {
+			return err
+		}
+		f.Close()
+		defer os.Remove(f.Name())
+		envFiles[i] = f.Name()
+	}
+
+	cmd := exec.CommandContext(fm.Context(), opts.Sh, "-c", script, "sh")
+	cmd.Env = append(os.Environ(), codeEnv+"="+code,
+		beforeEnv+"="+envFiles[0], afterEnv+"="+envFiles[1])
+	cmd.Stdin = fm.InputFile()
+	cmd.Stdout = fm.ByteOutput()
+	cmd.Stderr = fm.ErrorFile()
+	errRun := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if errRun != nil && !errors.As(errRun, &exitErr) {
+		return errRun
+	}
+	// Import the environment even if the code exited with a non-zero status,
+	// since it may have changed the environment before failing.
+	var dumps [2]string
+	for i, name := range envFiles {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		dumps[i] = string(content)
+	}
+	importEnv(dumps[0], dumps[1])
+	if exitErr != nil {
+		return eval.NewExternalCmdExit(
+			opts.Sh, exitErr.Sys().(syscall.WaitStatus), exitErr.Pid())
+	}
+	if dumps[1] == "" {
+		return errNoEnvDump
+	}
+	return nil
+}
+
+// Applies the changes between the environments dumped before and after the
+// code runs. Variables that are the same in both dumps are left alone, so
+// variables that the shell drops from its environment, like those whose names
+// are not valid shell variable names, are not unset.
+func importEnv(before, after string) {
+	if before == "" || after == "" {
+		// The shell exited before it could dump the environment, for example
+		// because of a syntax error, or the code skipped the dump after it.
+		return
+	}
+	oldEnv, newEnv := parseEnv(before), parseEnv(after)
+	for name := range oldEnv {
+		if _, ok := newEnv[name]; !ok {
+			os.Unsetenv(name)
+		}
+	}
+	for name, value := range newEnv {
+		if old, ok := oldEnv[name]; !ok || old != value {
+			os.Setenv(name, value)
+		}
+	}
+}
+
+var envValueDecoder = strings.NewReplacer("%0A", "\n", "%25", "%")
+
+// Parses an environment dumped by the script.
+func parseEnv(dumped string) map[string]string {
+	m := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSuffix(dumped, "\n"), "\n") {
+		name, value, ok := strings.Cut(line, "=")
+		if ok && name != "" && !ignoredEnv[name] {
+			m[name] = envValueDecoder.Replace(value)
+		}
+	}
+	return m
+}
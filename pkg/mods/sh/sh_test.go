@@ -0,0 +1,28 @@
+package sh_test
+
+import (
+	"embed"
+	"os/exec"
+	"testing"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/evaltest"
+	"src.elv.sh/pkg/mods/sh"
+	"src.elv.sh/pkg/testutil"
+)
+
+//go:embed *.elvts
+var transcripts embed.FS
+
+func TestTranscripts(t *testing.T) {
+	evaltest.TestTranscriptsInFS(t, transcripts,
+		"add-sh-ns", func(ev *eval.Evaler) { ev.AddModule("sh", sh.Ns) },
+		"sh-or-skip", func(t *testing.T) {
+			if _, err := exec.LookPath("/bin/sh"); err != nil {
+				t.Skip("/bin/sh not found")
+			}
+			testutil.Unsetenv(t, "X")
+			testutil.Setenv(t, "Y", "old")
+		},
+	)
+}
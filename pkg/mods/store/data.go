@@ -0,0 +1,139 @@
+package store
+
+import (
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/errs"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/store/storedefs"
+)
+
+func data(s storedefs.Store) func(*eval.Frame, string, string) (any, error) {
+	return func(fm *eval.Frame, ns, key string) (any, error) {
+		repr, err := getRepr(fm, s, ns, key)
+		if err != nil {
+			return nil, err
+		} else if repr == "" {
+			return nil, storedefs.ErrNoMatchingData
+		}
+		return eval.ParseRepr(repr)
+	}
+}
+
+func hasData(s storedefs.Store) func(*eval.Frame, string, string) (bool, error) {
+	return func(fm *eval.Frame, ns, key string) (bool, error) {
+		repr, err := getRepr(fm, s, ns, key)
+		return repr != "", err
+	}
+}
+
+func dataKeys(s storedefs.Store) func(*eval.Frame, string) ([]string, error) {
+	return func(fm *eval.Frame, ns string) ([]string, error) {
+		if tx := transactionOf(fm); tx != nil {
+			return tx.keys(ns)
+		}
+		return s.DataKeys(ns)
+	}
+}
+
+func setData(s storedefs.Store) func(*eval.Frame, string, string, any) error {
+	return func(fm *eval.Frame, ns, key string, value any) error {
+		if err := fm.Evaler.CheckRestricted("setting data in the store"); err != nil {
+			return err
+		}
+		repr, err := storableRepr(value)
+		if err != nil {
+			return err
+		}
+		if tx := transactionOf(fm); tx != nil {
+			return tx.put(ns, key, repr)
+		}
+		return s.SetData(ns, key, repr)
+	}
+}
+
+func delData(s storedefs.Store) func(*eval.Frame, string, string) error {
+	return func(fm *eval.Frame, ns, key string) error {
+		if err := fm.Evaler.CheckRestricted("deleting data from the store"); err != nil {
+			return err
+		}
+		if tx := transactionOf(fm); tx != nil {
+			return tx.put(ns, key, "")
+		}
+		return s.DelData(ns, key)
+	}
+}
+
+func updateData(s storedefs.Store) func(*eval.Frame, string, string, eval.Callable) error {
+	return func(fm *eval.Frame, ns, key string, f eval.Callable) error {
+		if err := fm.Evaler.CheckRestricted("updating data in the store"); err != nil {
+			return err
+		}
+		// Retry until the value is not changed by another process while f is
+		// being called. In a transaction, such changes are instead detected
+		// when the transaction is committed.
+		for {
+			oldRepr, err := getRepr(fm, s, ns, key)
+			if err != nil {
+				return err
+			}
+			var old any
+			if oldRepr != "" {
+				if old, err = eval.ParseRepr(oldRepr); err != nil {
+					return err
+				}
+			}
+
+			outputs, err := fm.CaptureOutput(func(fm *eval.Frame) error {
+				return f.Call(fm, []any{old}, eval.NoOpts)
+			})
+			if err != nil {
+				return err
+			} else if len(outputs) != 1 {
+				return errs.ArityMismatch{
+					What:     "number of outputs of the callback",
+					ValidLow: 1, ValidHigh: 1, Actual: len(outputs)}
+			}
+			newRepr, err := storableRepr(outputs[0])
+			if err != nil {
+				return err
+			}
+
+			if tx := transactionOf(fm); tx != nil {
+				return tx.put(ns, key, newRepr)
+			}
+			swapped, err := s.CompareAndSetData(ns, key, oldRepr, newRepr)
+			if err != nil || swapped {
+				return err
+			}
+		}
+	}
+}
+
+// Returns the repr of a key, or "" if it doesn't exist.
+func getRepr(fm *eval.Frame, s storedefs.Store, ns, key string) (string, error) {
+	if tx := transactionOf(fm); tx != nil {
+		return tx.get(ns, key)
+	}
+	repr, err := s.Data(ns, key)
+	if isNoMatchingData(err) {
+		return "", nil
+	}
+	return repr, err
+}
+
+// Returns the repr of a value, or an error if it can't be parsed back.
+func storableRepr(v any) (string, error) {
+	repr := vals.ReprPlain(v)
+	if _, err := eval.ParseRepr(repr); err != nil {
+		return "", errs.BadValue{What: "value to store",
+			Valid:  "string, number, boolean, nil, or list or map of them",
+			Actual: vals.Kind(v)}
+	}
+	return repr, nil
+}
+
+// Errors from the daemon lose their identities, so they are compared by their
+// messages.
+func isNoMatchingData(err error) bool {
+	return err != nil && err.Error() == storedefs.ErrNoMatchingData.Error()
+}
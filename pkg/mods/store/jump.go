@@ -0,0 +1,159 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/store/storedefs"
+)
+
+var errNoMatchingDir = errors.New("no matching directory")
+
+type jumpOpts struct{ List bool }
+
+func (*jumpOpts) SetDefaultOptions() {}
+
+// Multipliers applied to the scores of directories, depending on how they
+// match the fragments.
+const (
+	// All the fragments are found in order as substrings.
+	substringBonus = 2
+	// The last fragment is found in the last path element.
+	lastElemBonus = 2
+)
+
+func jump(s storedefs.Store) func(*eval.Frame, jumpOpts, ...string) error {
+	return func(fm *eval.Frame, opts jumpOpts, fragments ...string) error {
+		blacklist := storedefs.NoBlacklist
+		if pwd, err := os.Getwd(); err == nil {
+			blacklist = map[string]struct{}{pwd: {}}
+		}
+		dirs, err := s.Dirs(blacklist)
+		if err != nil {
+			return err
+		}
+		candidates := matchDirs(dirs, fragments)
+		if opts.List {
+			out := fm.ValueOutput()
+			for _, dir := range candidates {
+				err := out.Put(dir)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if len(candidates) == 0 {
+			return errNoMatchingDir
+		}
+		return fm.Evaler.Chdir(candidates[0])
+	}
+}
+
+// Returns the paths of the existing directories matching all the fragments,
+// from the best match to the worst.
+func matchDirs(dirs []storedefs.Dir, fragments []string) []string {
+	lowerFragments := make([]string, len(fragments))
+	for i, f := range fragments {
+		lowerFragments[i] = strings.ToLower(f)
+	}
+	type candidate struct {
+		path  string
+		score float64
+	}
+	var candidates []candidate
+	for _, dir := range dirs {
+		bonus, ok := matchFragments(strings.ToLower(dir.Path), lowerFragments)
+		if !ok {
+			continue
+		}
+		if stat, err := os.Stat(dir.Path); err != nil || !stat.IsDir() {
+			continue
+		}
+		candidates = append(candidates, candidate{dir.Path, dir.Score * bonus})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	paths := make([]string, len(candidates))
+	for i, c := range candidates {
+		paths[i] = c.path
+	}
+	return paths
+}
+
+// Returns whether all the fragments can be found in path in order, either as
+// substrings or as subsequences of path elements, and the multiplier to apply to the score of
+// path. Both path and fragments must be in lower case.
+func matchFragments(path string, fragments []string) (float64, bool) {
+	if len(fragments) == 0 {
+		return 1, true
+	}
+	bonus := 1.0
+	if findInOrder(path, fragments, substringEnd) {
+		bonus *= substringBonus
+	} else if !findInOrder(path, fragments, subsequenceEnd) {
+		return 0, false
+	}
+	if strings.Contains(filepath.Base(path), fragments[len(fragments)-1]) {
+		bonus *= lastElemBonus
+	}
+	return bonus, true
+}
+
+// Returns whether all the fragments can be found in s in order with the given
+// function, which returns the index right after where a fragment is found, or
+// -1 if it is not found.
+func findInOrder(s string, fragments []string, find func(s, f string) int) bool {
+	for _, f := range fragments {
+		end := find(s, f)
+		if end == -1 {
+			return false
+		}
+		s = s[end:]
+	}
+	return true
+}
+
+func substringEnd(s, f string) int {
+	i := strings.Index(s, f)
+	if i == -1 {
+		return -1
+	}
+	return i + len(f)
+}
+
+// Like substringEnd, but finds f as a subsequence of a path element.
+func subsequenceEnd(s, f string) int {
+	offset := 0
+	for {
+		elem, _, more := strings.Cut(s[offset:], "/")
+		if end := subsequenceEndInElem(elem, f); end != -1 {
+			return offset + end
+		}
+		if !more {
+			return -1
+		}
+		offset += len(elem) + 1
+	}
+}
+
+func subsequenceEndInElem(s, f string) int {
+	j := 0
+	for i := 0; j < len(f); i++ {
+		if i == len(s) {
+			return -1
+		}
+		if s[i] == f[j] {
+			j++
+			if j == len(f) {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
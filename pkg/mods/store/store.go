@@ -9,15 +9,84 @@ func Ns(s storedefs.Store) *eval.Ns {
 	return eval.BuildNsNamed("store").
 		AddGoFns(map[string]any{
 			"next-cmd-seq": s.NextCmdSeq,
-			"add-cmd":      s.AddCmd,
-			"del-cmd":      s.DelCmd,
+			"add-cmd":      addCmd(s),
+			"del-cmd":      delCmd(s),
 			"cmd":          s.Cmd,
 			"cmds":         s.CmdsWithSeq,
 			"next-cmd":     s.NextCmd,
 			"prev-cmd":     s.PrevCmd,
+			"pin-cmd":      setCmdPinned(s, true),
+			"unpin-cmd":    setCmdPinned(s, false),
+			"pinned-cmds":  s.PinnedCmds,
 
-			"add-dir": func(dir string) error { return s.AddDir(dir, 1) },
-			"del-dir": s.DelDir,
+			"add-dir": addDir(s),
+			"del-dir": delDir(s),
 			"dirs":    func() ([]storedefs.Dir, error) { return s.Dirs(storedefs.NoBlacklist) },
+			"jump":    jump(s),
+
+			"data":        data(s),
+			"has-data":    hasData(s),
+			"data-keys":   dataKeys(s),
+			"set-data":    setData(s),
+			"del-data":    delData(s),
+			"update-data": updateData(s),
+			"transact":    transact(s),
+
+			"trust-file":   trustFile(s),
+			"untrust-file": untrustFile(s),
+			"is-trusted":   isTrusted(s),
+
+			"export-snapshot": exportSnapshot(s),
+			"merge-snapshot":  mergeSnapshot(s),
+			"sync":            syncDir(s),
 		}).Ns()
 }
+
+func addCmd(s storedefs.Store) func(*eval.Frame, string) (int, error) {
+	return func(fm *eval.Frame, text string) (int, error) {
+		if err := fm.Evaler.CheckRestricted("adding a command to the store"); err != nil {
+			return 0, err
+		}
+		return s.AddCmd(text)
+	}
+}
+
+func delCmd(s storedefs.Store) func(*eval.Frame, int) error {
+	return func(fm *eval.Frame, seq int) error {
+		if err := fm.Evaler.CheckRestricted("deleting a command from the store"); err != nil {
+			return err
+		}
+		return s.DelCmd(seq)
+	}
+}
+
+func setCmdPinned(s storedefs.Store, pinned bool) func(*eval.Frame, int) error {
+	op := "unpinning a command in the store"
+	if pinned {
+		op = "pinning a command in the store"
+	}
+	return func(fm *eval.Frame, seq int) error {
+		if err := fm.Evaler.CheckRestricted(op); err != nil {
+			return err
+		}
+		return s.SetCmdPinned(seq, pinned)
+	}
+}
+
+func addDir(s storedefs.Store) func(*eval.Frame, string) error {
+	return func(fm *eval.Frame, dir string) error {
+		if err := fm.Evaler.CheckRestricted("adding a directory to the store"); err != nil {
+			return err
+		}
+		return s.AddDir(dir, 1)
+	}
+}
+
+func delDir(s storedefs.Store) func(*eval.Frame, string) error {
+	return func(fm *eval.Frame, dir string) error {
+		if err := fm.Evaler.CheckRestricted("deleting a directory from the store"); err != nil {
+			return err
+		}
+		return s.DelDir(dir)
+	}
+}
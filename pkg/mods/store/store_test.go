@@ -21,5 +21,6 @@ func TestTranscripts(t *testing.T) {
 			s := must.OK1(store.NewStore("db"))
 			ev.ExtendGlobal(eval.BuildNs().AddNs("store", Ns(s)))
 		},
+		"restricted", func(ev *eval.Evaler) { ev.Restricted = true },
 	)
 }
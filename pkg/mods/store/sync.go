@@ -0,0 +1,95 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/store/storedefs"
+)
+
+func exportSnapshot(s storedefs.Store) func(*eval.Frame, string) error {
+	return func(fm *eval.Frame, path string) error {
+		if err := fm.Evaler.CheckRestricted("writing to file " + path); err != nil {
+			return err
+		}
+		snapshot, err := s.Snapshot()
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		// Write to a temporary file first, so that a tool syncing the file never
+		// sees a partially written one.
+		f, err := os.CreateTemp(filepath.Dir(path), ".snapshot-*")
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		if errClose := f.Close(); err == nil {
+			err = errClose
+		}
+		if err == nil {
+			err = os.Rename(f.Name(), path)
+		}
+		if err != nil {
+			os.Remove(f.Name())
+		}
+		return err
+	}
+}
+
+func mergeSnapshot(s storedefs.Store) func(*eval.Frame, string) error {
+	return func(fm *eval.Frame, path string) error {
+		if err := fm.Evaler.CheckRestricted("merging a snapshot into the store"); err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var snapshot storedefs.Snapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return err
+		}
+		return s.MergeSnapshot(snapshot)
+	}
+}
+
+type syncOpts struct{ Name string }
+
+func (o *syncOpts) SetDefaultOptions() {}
+
+func syncDir(s storedefs.Store) func(*eval.Frame, syncOpts, string) error {
+	return func(fm *eval.Frame, opts syncOpts, dir string) error {
+		if err := fm.Evaler.CheckRestricted("syncing the store with " + dir); err != nil {
+			return err
+		}
+		name := opts.Name
+		if name == "" {
+			hostname, err := os.Hostname()
+			if err != nil {
+				return err
+			}
+			name = hostname
+		}
+		own := name + ".json"
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || entry.Name() == own || !strings.HasSuffix(entry.Name(), ".json") {
+				continue
+			}
+			if err := mergeSnapshot(s)(fm, filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return exportSnapshot(s)(fm, filepath.Join(dir, own))
+	}
+}
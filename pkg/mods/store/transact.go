@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/store/storedefs"
+)
+
+var errTransactionConflict = errors.New(
+	"data read in transaction was changed by another process")
+
+// Key of the transaction in the Context of a Frame.
+type transactionKey struct{}
+
+// A transaction of data writes, buffered until the outermost store:transact
+// returns.
+type transaction struct {
+	s storedefs.Store
+	m sync.Mutex
+	// Values of keys in the store when they were first read, which must not
+	// have changed when the transaction is committed.
+	reads map[dataKey]string
+	// Buffered writes, where "" means deleting the key.
+	writes map[dataKey]string
+}
+
+type dataKey struct{ ns, key string }
+
+func transactionOf(fm *eval.Frame) *transaction {
+	tx, _ := fm.Context().Value(transactionKey{}).(*transaction)
+	return tx
+}
+
+// Returns the repr of a key, taking buffered writes into account, or "" if it
+// doesn't exist.
+func (tx *transaction) get(ns, key string) (string, error) {
+	k := dataKey{ns, key}
+	tx.m.Lock()
+	defer tx.m.Unlock()
+	if repr, ok := tx.writes[k]; ok {
+		return repr, nil
+	}
+	if repr, ok := tx.reads[k]; ok {
+		return repr, nil
+	}
+	repr, err := tx.s.Data(ns, key)
+	if isNoMatchingData(err) {
+		repr, err = "", nil
+	} else if err != nil {
+		return "", err
+	}
+	tx.reads[k] = repr
+	return repr, nil
+}
+
+func (tx *transaction) put(ns, key, repr string) error {
+	if ns == "" || key == "" {
+		return storedefs.ErrEmptyDataKey
+	}
+	tx.m.Lock()
+	defer tx.m.Unlock()
+	tx.writes[dataKey{ns, key}] = repr
+	return nil
+}
+
+func (tx *transaction) keys(ns string) ([]string, error) {
+	keys, err := tx.s.DataKeys(ns)
+	if err != nil {
+		return nil, err
+	}
+	tx.m.Lock()
+	defer tx.m.Unlock()
+	exists := make(map[string]bool)
+	for _, key := range keys {
+		exists[key] = true
+	}
+	for k, repr := range tx.writes {
+		if k.ns == ns {
+			exists[k.key] = repr != ""
+		}
+	}
+	keys = keys[:0]
+	for key, ok := range exists {
+		if ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (tx *transaction) commit() error {
+	var expected, changes []storedefs.DataEntry
+	for k, repr := range tx.reads {
+		expected = append(expected, storedefs.DataEntry{NS: k.ns, Key: k.key, Value: repr})
+	}
+	for k, repr := range tx.writes {
+		changes = append(changes, storedefs.DataEntry{NS: k.ns, Key: k.key, Value: repr})
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	changed, err := tx.s.ChangeData(expected, changes)
+	if err == nil && !changed {
+		return errTransactionConflict
+	}
+	return err
+}
+
+func transact(s storedefs.Store) func(*eval.Frame, eval.Callable) error {
+	return func(fm *eval.Frame, f eval.Callable) error {
+		if err := fm.Evaler.CheckRestricted("running a store transaction"); err != nil {
+			return err
+		}
+		if tx := transactionOf(fm); tx != nil {
+			// A nested transaction is part of the outer one, but its writes are
+			// still rolled back if it throws an exception.
+			tx.m.Lock()
+			saved := make(map[dataKey]string, len(tx.writes))
+			for k, repr := range tx.writes {
+				saved[k] = repr
+			}
+			tx.m.Unlock()
+			err := f.Call(fm.Fork("store:transact"), eval.NoArgs, eval.NoOpts)
+			if err != nil {
+				tx.m.Lock()
+				tx.writes = saved
+				tx.m.Unlock()
+			}
+			return err
+		}
+
+		tx := &transaction{s: s,
+			reads: make(map[dataKey]string), writes: make(map[dataKey]string)}
+		ctx := context.WithValue(fm.Context(), transactionKey{}, tx)
+		err := f.Call(fm.ForkWithContext("store:transact", ctx), eval.NoArgs, eval.NoOpts)
+		if err != nil {
+			return err
+		}
+		return tx.commit()
+	}
+}
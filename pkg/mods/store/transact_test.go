@@ -0,0 +1,36 @@
+package store
+
+import (
+	"testing"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/store"
+	"src.elv.sh/pkg/testutil"
+)
+
+func TestTransact_Conflict(t *testing.T) {
+	testutil.InTempDir(t)
+	s := must.OK1(store.NewStore("db"))
+	ev := eval.NewEvaler()
+	ev.ExtendGlobal(eval.BuildNs().
+		AddNs("store", Ns(s)).
+		// Simulates a write from another process.
+		AddGoFn("set-elsewhere", func(value string) error {
+			return s.SetData("ns", "k", value)
+		}))
+
+	code := `store:set-data ns k old
+		store:transact {
+			store:set-data ns k2 (store:data ns k)
+			set-elsewhere new
+		}`
+	err := ev.Eval(parse.Source{Name: "[test]", Code: code}, eval.EvalCfg{})
+	if err == nil || eval.Reason(err) != errTransactionConflict {
+		t.Errorf("got error %v, want %v", err, errTransactionConflict)
+	}
+	if keys, _ := s.DataKeys("ns"); len(keys) != 1 {
+		t.Errorf("got keys %v, want only k", keys)
+	}
+}
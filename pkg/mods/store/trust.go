@@ -0,0 +1,58 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/store/storedefs"
+)
+
+func trustFile(s storedefs.Store) func(*eval.Frame, string) error {
+	return func(fm *eval.Frame, path string) error {
+		if err := fm.Evaler.CheckRestricted("trusting file " + path); err != nil {
+			return err
+		}
+		path, content, err := readFileAbs(path)
+		if err != nil {
+			return err
+		}
+		return s.SetTrustedHash(path, storedefs.HashContent(content))
+	}
+}
+
+func untrustFile(s storedefs.Store) func(*eval.Frame, string) error {
+	return func(fm *eval.Frame, path string) error {
+		if err := fm.Evaler.CheckRestricted("untrusting file " + path); err != nil {
+			return err
+		}
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		return s.SetTrustedHash(path, "")
+	}
+}
+
+func isTrusted(s storedefs.Store) func(string) (bool, error) {
+	return func(path string) (bool, error) {
+		path, content, err := readFileAbs(path)
+		if err != nil {
+			return false, err
+		}
+		hash, err := s.TrustedHash(path)
+		if err != nil {
+			return false, err
+		}
+		return hash == storedefs.HashContent(content), nil
+	}
+}
+
+func readFileAbs(path string) (string, []byte, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", nil, err
+	}
+	content, err := os.ReadFile(path)
+	return path, content, err
+}
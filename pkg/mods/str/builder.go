@@ -0,0 +1,47 @@
+package str
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A mutable string buffer, for building a long string piece by piece without
+// copying it every time.
+type builder struct {
+	mu sync.Mutex
+	sb strings.Builder
+}
+
+// Kind returns "str:builder".
+func (*builder) Kind() string { return "str:builder" }
+
+// Repr returns an opaque representation containing the number of bytes
+// written so far.
+func (b *builder) Repr(int) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return "<str:builder " + strconv.Itoa(b.sb.Len()) + " bytes>"
+}
+
+func newBuilder(strs ...string) *builder {
+	b := &builder{}
+	appendStrs(b, strs...)
+	return b
+}
+
+func appendStrs(b *builder, strs ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, s := range strs {
+		b.sb.WriteString(s)
+	}
+}
+
+// The builder can be appended to after this; strings.Builder makes sure that
+// the returned string is not affected.
+func finish(b *builder) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sb.String()
+}
@@ -17,6 +17,8 @@ import (
 
 var Ns = eval.BuildNsNamed("str").
 	AddGoFns(map[string]any{
+		"append":       appendStrs,
+		"builder":      newBuilder,
 		"compare":      strings.Compare,
 		"contains":     strings.Contains,
 		"contains-any": strings.ContainsAny,
@@ -24,6 +26,7 @@ var Ns = eval.BuildNsNamed("str").
 		"equal-fold":   strings.EqualFold,
 		// TODO: FieldsFunc
 		"fields":          strings.Fields,
+		"finish":          finish,
 		"from-codepoints": fromCodepoints,
 		"from-utf8-bytes": fromUtf8Bytes,
 		"has-prefix":      strings.HasPrefix,
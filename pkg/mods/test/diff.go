@@ -0,0 +1,136 @@
+package test
+
+import (
+	"sort"
+	"strings"
+
+	"src.elv.sh/pkg/eval/vals"
+)
+
+// Returns an element-level diff between two values, with each line prefixed
+// with "- " if it only appears in expected, "+ " if it only appears in actual,
+// and two spaces if it appears in both. Returns "" if the values are not both
+// lists, both maps, or both strings with at least one of them spanning
+// multiple lines.
+func diffValues(expected, actual any) string {
+	switch expected := expected.(type) {
+	case vals.List:
+		if actual, ok := actual.(vals.List); ok {
+			return diffSeqs(reprElems(expected), reprElems(actual), listElems(expected), listElems(actual))
+		}
+	case vals.Map:
+		if actual, ok := actual.(vals.Map); ok {
+			return diffMaps(expected, actual)
+		}
+	case string:
+		if actual, ok := actual.(string); ok &&
+			(strings.Contains(expected, "\n") || strings.Contains(actual, "\n")) {
+			l1 := strings.Split(expected, "\n")
+			l2 := strings.Split(actual, "\n")
+			return diffSeqs(l1, l2, toAnys(l1), toAnys(l2))
+		}
+	}
+	return ""
+}
+
+func listElems(l vals.List) []any {
+	var elems []any
+	for it := l.Iterator(); it.HasElem(); it.Next() {
+		elems = append(elems, it.Elem())
+	}
+	return elems
+}
+
+func reprElems(l vals.List) []string {
+	var reprs []string
+	for it := l.Iterator(); it.HasElem(); it.Next() {
+		reprs = append(reprs, vals.ReprPlain(it.Elem()))
+	}
+	return reprs
+}
+
+func toAnys(ss []string) []any {
+	as := make([]any, len(ss))
+	for i, s := range ss {
+		as[i] = s
+	}
+	return as
+}
+
+// Diffs two sequences using their longest common subsequence. The texts are
+// used for display, and the values are compared with vals.Equal.
+func diffSeqs(texts1, texts2 []string, values1, values2 []any) string {
+	n1, n2 := len(values1), len(values2)
+	// lcs[i][j] is the length of the longest common subsequence of
+	// values1[i:] and values2[j:].
+	lcs := make([][]int, n1+1)
+	for i := range lcs {
+		lcs[i] = make([]int, n2+1)
+	}
+	for i := n1 - 1; i >= 0; i-- {
+		for j := n2 - 1; j >= 0; j-- {
+			if vals.Equal(values1[i], values2[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < n1 || j < n2 {
+		switch {
+		case i < n1 && j < n2 && vals.Equal(values1[i], values2[j]):
+			lines = append(lines, "  "+texts1[i])
+			i++
+			j++
+		case j == n2 || (i < n1 && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "- "+texts1[i])
+			i++
+		default:
+			lines = append(lines, "+ "+texts2[j])
+			j++
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Diffs two maps by their keys, which are sorted.
+func diffMaps(expected, actual vals.Map) string {
+	var keys []any
+	for it := expected.Iterator(); it.HasElem(); it.Next() {
+		k, _ := it.Elem()
+		keys = append(keys, k)
+	}
+	for it := actual.Iterator(); it.HasElem(); it.Next() {
+		k, _ := it.Elem()
+		if _, ok := expected.Index(k); !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return vals.CmpTotal(keys[i], keys[j]) == vals.CmpLess
+	})
+
+	var lines []string
+	pair := func(prefix string, k, v any) {
+		lines = append(lines, prefix+"&"+vals.ReprPlain(k)+"="+vals.ReprPlain(v))
+	}
+	for _, k := range keys {
+		v1, ok1 := expected.Index(k)
+		v2, ok2 := actual.Index(k)
+		switch {
+		case ok1 && ok2 && vals.Equal(v1, v2):
+			pair("  ", k, v1)
+		default:
+			if ok1 {
+				pair("- ", k, v1)
+			}
+			if ok2 {
+				pair("+ ", k, v2)
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
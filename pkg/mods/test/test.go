@@ -0,0 +1,196 @@
+// Package test implements the test: module.
+package test
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/parse"
+)
+
+// Results records the results of test cases run with test:case.
+type Results struct {
+	mu     sync.Mutex
+	groups []string
+	passed int
+	failed []Failure
+}
+
+// Failure describes a failed test case.
+type Failure struct {
+	// Full name of the test case, including the names of enclosing groups.
+	Name string
+	// The error the test case failed with.
+	Err error
+}
+
+// Passed returns the number of test cases that have passed.
+func (r *Results) Passed() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.passed
+}
+
+// Failed returns the test cases that have failed.
+func (r *Results) Failed() []Failure {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Failure(nil), r.failed...)
+}
+
+func (r *Results) fullName(name string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(append(r.groups[:len(r.groups):len(r.groups)], name), " / ")
+}
+
+func (r *Results) pushGroup(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.groups = append(r.groups, name)
+}
+
+func (r *Results) popGroup() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.groups = r.groups[:len(r.groups)-1]
+}
+
+func (r *Results) record(name string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		r.passed++
+	} else {
+		r.failed = append(r.failed, Failure{name, err})
+	}
+}
+
+// Ns returns the namespace for the test: module, which records results of test
+// cases in r.
+func Ns(r *Results) *eval.Ns {
+	return nsBuilder(r).Ns()
+}
+
+// RunnerNs is like Ns, but also has test:mock-external and
+// test:unmock-external. It is used for running test files, and shouldn't be
+// used for normal code since mocks affect the whole Evaler.
+func RunnerNs(r *Results) *eval.Ns {
+	return nsBuilder(r).
+		AddGoFns(map[string]any{
+			"mock-external":   mockExternal,
+			"unmock-external": unmockExternal,
+		}).Ns()
+}
+
+func nsBuilder(r *Results) eval.NsBuilder {
+	return eval.BuildNsNamed("test").
+		AddGoFns(map[string]any{
+			"assert":         assert,
+			"assert-eq":      assertEq,
+			"assert-ne":      assertNe,
+			"assert-matches": assertMatches,
+			"expect-throw":   expectThrow,
+
+			"case": func(fm *eval.Frame, name string, f eval.Callable) error {
+				return testCase(fm, r, name, f)
+			},
+			"group": func(fm *eval.Frame, name string, f eval.Callable) error {
+				r.pushGroup(name)
+				defer r.popGroup()
+				return f.Call(fm.Fork("test:group"), eval.NoArgs, eval.NoOpts)
+			},
+		})
+}
+
+var errNoException = errors.New("expected an exception, got none")
+
+type assertOpts struct{ Message string }
+
+func (*assertOpts) SetDefaultOptions() {}
+
+func assert(opts assertOpts, v any) error {
+	if vals.Bool(v) {
+		return nil
+	}
+	return assertionError(opts.Message, "got "+vals.ReprPlain(v))
+}
+
+func assertEq(opts assertOpts, actual, expected any) error {
+	if vals.Equal(actual, expected) {
+		return nil
+	}
+	detail := fmt.Sprintf("expected %s, got %s",
+		vals.ReprPlain(expected), vals.ReprPlain(actual))
+	if diff := diffValues(expected, actual); diff != "" {
+		detail += "\ndiff (-expected +got):\n" + diff
+	}
+	return assertionError(opts.Message, detail)
+}
+
+func assertNe(opts assertOpts, actual, unexpected any) error {
+	if !vals.Equal(actual, unexpected) {
+		return nil
+	}
+	return assertionError(opts.Message,
+		"expected a value other than "+vals.ReprPlain(unexpected))
+}
+
+func assertMatches(opts assertOpts, actual, pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	if re.MatchString(actual) {
+		return nil
+	}
+	return assertionError(opts.Message, fmt.Sprintf("expected to match %s, got %s",
+		parse.Quote(pattern), parse.Quote(actual)))
+}
+
+func assertionError(message, detail string) error {
+	if message == "" {
+		return fmt.Errorf("assertion failed: %s", detail)
+	}
+	return fmt.Errorf("assertion failed: %s: %s", message, detail)
+}
+
+func expectThrow(fm *eval.Frame, f eval.Callable) (any, error) {
+	err := f.Call(fm.Fork("test:expect-throw"), eval.NoArgs, eval.NoOpts)
+	if err == nil {
+		return nil, errNoException
+	}
+	if exc, ok := err.(eval.Exception); ok {
+		return exc, nil
+	}
+	return eval.NewException(err, nil), nil
+}
+
+func mockExternal(fm *eval.Frame, name string, f eval.Callable) {
+	fm.Evaler.MockExternal(name, f)
+}
+
+func unmockExternal(fm *eval.Frame, name string) {
+	fm.Evaler.UnmockExternal(name)
+}
+
+func testCase(fm *eval.Frame, r *Results, name string, f eval.Callable) error {
+	fullName := r.fullName(name)
+	// Mocks created by the test case don't outlive it.
+	mocks := fm.Evaler.ExternalMocks()
+	err := f.Call(fm.Fork("test:case"), eval.NoArgs, eval.NoOpts)
+	fm.Evaler.SetExternalMocks(mocks)
+	r.record(fullName, err)
+	if err != nil {
+		errFile := fm.ErrorFile()
+		fmt.Fprintf(errFile, "FAIL: %s\n", parse.Quote(fullName))
+		diag.ShowError(errFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package test_test
+
+import (
+	"embed"
+	"testing"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/evaltest"
+	"src.elv.sh/pkg/mods/test"
+)
+
+//go:embed *.elvts *.elv
+var transcripts embed.FS
+
+func TestTranscripts(t *testing.T) {
+	evaltest.TestTranscriptsInFS(t, transcripts,
+		"runner-ns", func(ev *eval.Evaler) {
+			ev.AddModule("test", test.RunnerNs(&test.Results{}))
+		},
+	)
+}
+
+func TestNs_NoMocks(t *testing.T) {
+	ns := test.Ns(&test.Results{})
+	for _, name := range []string{"mock-external~", "unmock-external~"} {
+		if ns.IndexString(name) != nil {
+			t.Errorf("Ns has %s", name)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"math"
 	"math/big"
 	"strconv"
+	"strings"
 	"sync"
 
 	"golang.org/x/sys/unix"
@@ -17,7 +18,7 @@ import (
 )
 
 const (
-	validUmaskMsg = "integer in the range [0..0o777]"
+	validUmaskMsg = "integer in the range [0..0o777] or symbolic mode"
 )
 
 // UmaskVariable is a variable whose value always reflects the current file
@@ -60,21 +61,23 @@ func (UmaskVariable) Get() any {
 
 // Set changes the current file creation umask. It can be called with a string
 // or a number. Strings are treated as octal numbers by default, unless they
-// have an explicit base prefix like 0x or 0b.
+// have an explicit base prefix like 0x or 0b. Strings can also be symbolic
+// modes like "u=rwx,g=rx,o=", which are interpreted relative to the current
+// umask.
 func (UmaskVariable) Set(v any) error {
-	umask, err := parseUmask(v)
+	umaskMutex.Lock()
+	defer umaskMutex.Unlock()
+
+	umask, err := parseUmask(v, umaskVal)
 	if err != nil {
 		return err
 	}
-
-	umaskMutex.Lock()
-	defer umaskMutex.Unlock()
 	unix.Umask(umask)
 	umaskVal = umask
 	return nil
 }
 
-func parseUmask(v any) (int, error) {
+func parseUmask(v any, current int) (int, error) {
 	var umask int
 
 	switch v := v.(type) {
@@ -83,8 +86,12 @@ func parseUmask(v any) (int, error) {
 		if err != nil {
 			i, err = strconv.ParseInt(v, 0, 0)
 			if err != nil {
-				return -1, errs.BadValue{
-					What: "umask", Valid: validUmaskMsg, Actual: vals.ToString(v)}
+				umask, ok := parseSymbolicUmask(v, current)
+				if !ok {
+					return -1, errs.BadValue{
+						What: "umask", Valid: validUmaskMsg, Actual: vals.ToString(v)}
+				}
+				return umask, nil
 			}
 		}
 		umask = int(i)
@@ -116,3 +123,78 @@ func parseUmask(v any) (int, error) {
 	}
 	return umask, nil
 }
+
+// Parses a symbolic mode like the ones accepted by the umask command of POSIX
+// shells. It consists of comma-separated clauses; each clause is zero or more
+// of "ugoa" (defaulting to "a"), followed by one of the operators "=", "+" and
+// "-", followed by zero or more of "rwx". The operators specify the permission
+// bits that are allowed, not the bits in the mask: for example, "go-w" sets the
+// group write and world write bits of the mask.
+func parseSymbolicUmask(s string, current int) (int, bool) {
+	allowed := ^current & 0o777
+	for _, clause := range strings.Split(s, ",") {
+		opIndex := strings.IndexAny(clause, "=+-")
+		if opIndex == -1 {
+			return -1, false
+		}
+		who, ok := parseUmaskWho(clause[:opIndex])
+		if !ok {
+			return -1, false
+		}
+		perm, ok := parseUmaskPerm(clause[opIndex+1:])
+		if !ok {
+			return -1, false
+		}
+		bits := who & perm
+		switch clause[opIndex] {
+		case '=':
+			allowed = allowed&^who | bits
+		case '+':
+			allowed |= bits
+		case '-':
+			allowed &^= bits
+		}
+	}
+	return ^allowed & 0o777, true
+}
+
+// Returns a mask of all the permission bits for the classes in s.
+func parseUmaskWho(s string) (int, bool) {
+	if s == "" {
+		return 0o777, true
+	}
+	who := 0
+	for _, r := range s {
+		switch r {
+		case 'u':
+			who |= 0o700
+		case 'g':
+			who |= 0o070
+		case 'o':
+			who |= 0o007
+		case 'a':
+			who |= 0o777
+		default:
+			return 0, false
+		}
+	}
+	return who, true
+}
+
+// Returns the permissions in s, replicated for all the classes.
+func parseUmaskPerm(s string) (int, bool) {
+	perm := 0
+	for _, r := range s {
+		switch r {
+		case 'r':
+			perm |= 0o444
+		case 'w':
+			perm |= 0o222
+		case 'x':
+			perm |= 0o111
+		default:
+			return 0, false
+		}
+	}
+	return perm, true
+}
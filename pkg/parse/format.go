@@ -0,0 +1,572 @@
+package parse
+
+import (
+	"strings"
+)
+
+const formatIndent = "  "
+
+// Format returns the code of a parse tree in the canonical style:
+//
+//   - Each pipeline is written on its own line, except in lambdas and output
+//     captures that are written on a single line in the source, where
+//     pipelines are separated by "; ".
+//
+//   - Bodies of multi-line lambdas and output captures, and elements of
+//     multi-line lists and maps are indented by two spaces per level, and so
+//     are continuation lines of multi-line pipelines and forms.
+//
+//   - Runs of inline whitespace are replaced by a single space, runs of blank
+//     lines are replaced by a single blank line, and values of map pairs on
+//     consecutive lines are aligned.
+//
+// Comments are preserved. The tree must have been parsed without errors.
+func Format(tree Tree) string {
+	f := &formatter{}
+	f.chunk(tree.Root, true)
+	if f.sb.Len() > 0 {
+		f.sb.WriteString("\n")
+	}
+	return f.sb.String()
+}
+
+type formatter struct {
+	sb     strings.Builder
+	indent int
+	// Whether nothing has been written on the current line, including the
+	// indentation. The indentation is written lazily, so that blank lines
+	// don't have trailing whitespaces.
+	atLineStart bool
+	// Whether the current pipeline or form has continuation lines.
+	continued bool
+}
+
+func (f *formatter) write(s string) {
+	if s == "" {
+		return
+	}
+	if f.atLineStart {
+		f.sb.WriteString(strings.Repeat(formatIndent, f.indent))
+		f.atLineStart = false
+	}
+	f.sb.WriteString(s)
+}
+
+func (f *formatter) newline() {
+	f.sb.WriteString("\n")
+	f.atLineStart = true
+}
+
+// Starts tracking continuation lines of a pipeline or form, and returns a
+// function to stop tracking.
+func (f *formatter) continuation() func() {
+	saved := f.continued
+	f.continued = false
+	return func() {
+		if f.continued {
+			f.indent--
+		}
+		f.continued = saved
+	}
+}
+
+// Starts a continuation line, which is indented by one more level than the
+// first line of the pipeline or form.
+func (f *formatter) continueLine() {
+	if !f.continued {
+		f.indent++
+		f.continued = true
+	}
+	f.newline()
+}
+
+// Writes the pipelines and comments in a chunk. In multi-line mode, each of
+// them is written on its own line; otherwise pipelines are separated by "; ".
+func (f *formatter) chunk(n *Chunk, multiLine bool) {
+	if !multiLine {
+		for i, pn := range n.Pipelines {
+			if i > 0 {
+				f.write("; ")
+			}
+			f.pipeline(pn)
+		}
+		return
+	}
+	// Whether a pipeline or comment has been written, whether the last one
+	// is a pipeline, and the number of newlines seen since then.
+	written, lastIsPipeline, newlines := false, false, 0
+	startLine := func() {
+		if written {
+			if newlines >= 2 {
+				f.newline()
+			}
+			f.newline()
+		}
+		written, newlines = true, 0
+	}
+	for _, ch := range Children(n) {
+		switch ch := ch.(type) {
+		case *Pipeline:
+			startLine()
+			f.pipeline(ch)
+			lastIsPipeline = true
+		case *Sep:
+			text := SourceText(ch)
+			if text == "\n" {
+				newlines++
+				continue
+			}
+			for _, comment := range comments(text) {
+				if written && lastIsPipeline && newlines == 0 {
+					// Keep a comment after a pipeline on the same line.
+					f.write(" " + comment)
+				} else {
+					startLine()
+					f.write(comment)
+				}
+				lastIsPipeline = false
+			}
+		}
+	}
+}
+
+func (f *formatter) pipeline(n *Pipeline) {
+	defer f.continuation()()
+	afterPipe := false
+	for _, ch := range Children(n) {
+		switch ch := ch.(type) {
+		case *Form:
+			if afterPipe {
+				f.write(" ")
+				afterPipe = false
+			}
+			f.form(ch)
+		case *Sep:
+			text := SourceText(ch)
+			switch {
+			case text == "|":
+				f.write(" |")
+				afterPipe = true
+			case text == "&":
+				f.write(" &")
+			default:
+				for _, comment := range comments(text) {
+					f.write(" " + comment)
+				}
+				if afterPipe && strings.ContainsAny(text, "\r\n") {
+					f.continueLine()
+					afterPipe = false
+				}
+			}
+		}
+	}
+}
+
+func (f *formatter) form(n *Form) {
+	defer f.continuation()()
+	first := true
+	for _, ch := range Children(n) {
+		if sep, ok := ch.(*Sep); ok {
+			text := SourceText(sep)
+			for _, comment := range comments(text) {
+				f.write(" " + comment)
+			}
+			if !first && hasLineContinuation(text) {
+				f.write(" ^")
+				f.continueLine()
+				first = true
+			}
+			continue
+		}
+		if !first {
+			f.write(" ")
+		}
+		first = false
+		switch ch := ch.(type) {
+		case *Compound:
+			f.compound(ch)
+		case *MapPair:
+			f.mapPair(ch, 0)
+		case *Redir:
+			f.redir(ch)
+		case *Assignment:
+			f.indexing(ch.Left)
+			f.write("=")
+			f.compound(ch.Right)
+		}
+	}
+}
+
+func (f *formatter) redir(n *Redir) {
+	if hasComment(n) {
+		f.write(SourceText(n))
+		return
+	}
+	for _, ch := range Children(n) {
+		switch ch := ch.(type) {
+		case *Compound:
+			f.compound(ch)
+		case *Sep:
+			if text := SourceText(ch); isWhitespaceSep(text) {
+				f.write(" ")
+			} else {
+				f.write(text)
+			}
+		}
+	}
+}
+
+func (f *formatter) compound(n *Compound) {
+	for _, in := range n.Indexings {
+		f.indexing(in)
+	}
+}
+
+func (f *formatter) indexing(n *Indexing) {
+	f.primary(n.Head)
+	for _, a := range n.Indices {
+		if hasComment(a) || len(a.Semicolons) > 0 {
+			f.write("[" + SourceText(a) + "]")
+			continue
+		}
+		f.write("[")
+		for i, cn := range a.Compounds {
+			if i > 0 {
+				f.write(" ")
+			}
+			f.compound(cn)
+		}
+		f.write("]")
+	}
+}
+
+func (f *formatter) primary(n *Primary) {
+	switch n.Type {
+	case OutputCapture:
+		f.capture(n, "(")
+	case ExceptionCapture:
+		f.capture(n, "?(")
+	case Lambda:
+		f.lambda(n)
+	case List, Map:
+		f.container(n)
+	case Braced:
+		if hasComment(n) {
+			f.write(SourceText(n))
+			return
+		}
+		f.write("{")
+		for i, cn := range n.Braced {
+			if i > 0 {
+				f.write(",")
+			}
+			f.compound(cn)
+		}
+		f.write("}")
+	default:
+		f.write(SourceText(n))
+	}
+}
+
+func (f *formatter) capture(n *Primary, open string) {
+	f.write(open)
+	if isEmptyChunk(n.Chunk) {
+		f.write(")")
+		return
+	}
+	if isMultiLineChunk(n.Chunk) {
+		f.indent++
+		f.newline()
+		f.chunk(n.Chunk, true)
+		f.indent--
+		f.newline()
+	} else {
+		f.chunk(n.Chunk, false)
+	}
+	f.write(")")
+}
+
+func (f *formatter) lambda(n *Primary) {
+	// Collect the signature and comments before the body.
+	var sig []Node
+	hasSig := false
+	var leadingComments []string
+	// Whether there is a newline between the opening brace or the signature
+	// and the body.
+	leadingNewline := false
+	for _, ch := range Children(n) {
+		if ch == Node(n.Chunk) {
+			break
+		}
+		switch ch := ch.(type) {
+		case *Compound, *MapPair:
+			sig = append(sig, ch)
+		case *Sep:
+			text := SourceText(ch)
+			if text == "|" {
+				hasSig = true
+				leadingNewline = false
+			} else if strings.ContainsAny(text, "\r\n") {
+				leadingNewline = true
+			}
+			leadingComments = append(leadingComments, comments(text)...)
+		}
+	}
+
+	f.write("{")
+	if hasSig {
+		f.write("|")
+		for i, item := range sig {
+			if i > 0 {
+				f.write(" ")
+			}
+			switch item := item.(type) {
+			case *Compound:
+				f.compound(item)
+			case *MapPair:
+				f.mapPair(item, 0)
+			}
+		}
+		f.write("|")
+	}
+	if len(leadingComments) == 0 && isEmptyChunk(n.Chunk) {
+		f.write(" }")
+		return
+	}
+	if len(leadingComments) == 0 && !leadingNewline && !isMultiLineChunk(n.Chunk) {
+		f.write(" ")
+		f.chunk(n.Chunk, false)
+		f.write(" }")
+		return
+	}
+	f.indent++
+	for _, comment := range leadingComments {
+		f.newline()
+		f.write(comment)
+	}
+	if !isEmptyChunk(n.Chunk) {
+		f.newline()
+		f.chunk(n.Chunk, true)
+	}
+	f.indent--
+	f.newline()
+	f.write("}")
+}
+
+// A line of elements in a multi-line list or map.
+type containerLine struct {
+	items       []Node
+	comment     string
+	blankBefore bool
+}
+
+// Writes a list or a map.
+func (f *formatter) container(n *Primary) {
+	var items []Node
+	for _, ch := range Children(n) {
+		switch ch.(type) {
+		case *Compound, *MapPair:
+			items = append(items, ch)
+		}
+	}
+	if len(items) == 0 && !hasComment(n) {
+		if n.Type == Map {
+			f.write("[&]")
+		} else {
+			f.write("[]")
+		}
+		return
+	}
+	if !strings.ContainsAny(SourceText(n), "\r\n") {
+		f.write("[")
+		f.items(items, 0)
+		f.write("]")
+		return
+	}
+
+	// Group the elements by the lines they are on in the source.
+	var lines []containerLine
+	newlines := 0
+	newLine := func() *containerLine {
+		lines = append(lines, containerLine{blankBefore: newlines >= 2 && len(lines) > 0})
+		newlines = 0
+		return &lines[len(lines)-1]
+	}
+	for _, ch := range Children(n) {
+		switch ch := ch.(type) {
+		case *Compound, *MapPair:
+			if len(lines) == 0 || newlines > 0 || lines[len(lines)-1].comment != "" {
+				newLine()
+			}
+			line := &lines[len(lines)-1]
+			line.items = append(line.items, ch)
+		case *Sep:
+			text := SourceText(ch)
+			for _, seg := range splitLines(text) {
+				if comment := comments(seg); len(comment) > 0 {
+					if len(lines) > 0 && newlines == 0 && lines[len(lines)-1].comment == "" {
+						lines[len(lines)-1].comment = comment[0]
+					} else {
+						newLine().comment = comment[0]
+					}
+				}
+				newlines++
+			}
+			// splitLines returns one more segment than the number of
+			// newlines.
+			newlines--
+		}
+	}
+
+	f.write("[")
+	f.indent++
+	for i := 0; i < len(lines); {
+		// Find a run of lines that only consist of a single map pair, and
+		// align their values.
+		j := i
+		keyWidth := 0
+		for j < len(lines) && isSinglePairLine(lines[j]) && (j == i || !lines[j].blankBefore) {
+			keyWidth = max(keyWidth, len(f.sub(func(f *formatter) {
+				f.mapPairKey(lines[j].items[0].(*MapPair))
+			})))
+			j++
+		}
+		if j == i {
+			j = i + 1
+			if len(lines[i].items) == 1 {
+				if pair, ok := lines[i].items[0].(*MapPair); ok && pair.Value != nil {
+					// A pair with a multi-line value is aligned on its own.
+					keyWidth = len(f.sub(func(f *formatter) { f.mapPairKey(pair) }))
+				}
+			}
+		}
+		for k := i; k < j; k++ {
+			line := lines[k]
+			if line.blankBefore {
+				f.newline()
+			}
+			f.newline()
+			f.items(line.items, keyWidth)
+			if line.comment != "" {
+				if len(line.items) > 0 {
+					f.write(" ")
+				}
+				f.write(line.comment)
+			}
+		}
+		i = j
+	}
+	f.indent--
+	f.newline()
+	f.write("]")
+}
+
+func isSinglePairLine(line containerLine) bool {
+	if len(line.items) != 1 {
+		return false
+	}
+	// Pairs with multi-line values are not aligned.
+	pair, ok := line.items[0].(*MapPair)
+	return ok && pair.Value != nil &&
+		!strings.ContainsAny(SourceText(pair.Value), "\r\n")
+}
+
+// Writes elements of a list or map, separated by spaces. Values of map pairs
+// are aligned as if their keys had keyWidth bytes.
+func (f *formatter) items(items []Node, keyWidth int) {
+	for i, item := range items {
+		if i > 0 {
+			f.write(" ")
+		}
+		switch item := item.(type) {
+		case *Compound:
+			f.compound(item)
+		case *MapPair:
+			f.mapPair(item, keyWidth)
+		}
+	}
+}
+
+// Writes a map pair. If keyWidth is not 0, the value is aligned as if the key
+// (including the "=") had keyWidth bytes, with one more space after it.
+func (f *formatter) mapPair(n *MapPair, keyWidth int) {
+	key := f.sub(func(f *formatter) { f.mapPairKey(n) })
+	f.write(key)
+	if n.Value != nil {
+		if keyWidth > 0 && len(n.Value.Indexings) > 0 {
+			f.write(strings.Repeat(" ", keyWidth-len(key)+1))
+		}
+		f.compound(n.Value)
+	}
+}
+
+// Writes the part of a map pair up to and including the "=".
+func (f *formatter) mapPairKey(n *MapPair) {
+	f.write("&")
+	f.compound(n.Key)
+	if n.Value != nil {
+		f.write("=")
+	}
+}
+
+// Returns what fn writes as a string, without writing it.
+func (f *formatter) sub(fn func(*formatter)) string {
+	sub := &formatter{indent: f.indent}
+	fn(sub)
+	return sub.sb.String()
+}
+
+// Reports whether the pipelines in a chunk are separated by newlines rather
+// than semicolons, or the chunk starts or ends with a newline.
+func isMultiLineChunk(n *Chunk) bool {
+	for _, ch := range Children(n) {
+		if sep, ok := ch.(*Sep); ok && strings.ContainsAny(SourceText(sep), "\r\n") {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmptyChunk(n *Chunk) bool {
+	return len(n.Pipelines) == 0 && !hasComment(n)
+}
+
+// Reports whether any separator child of n contains a comment.
+func hasComment(n Node) bool {
+	for _, ch := range Children(n) {
+		if sep, ok := ch.(*Sep); ok && len(comments(SourceText(sep))) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the comments in the source text of a separator, each without the
+// trailing newline.
+func comments(text string) []string {
+	var result []string
+	for _, line := range splitLines(text) {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			result = append(result, strings.TrimRight(line[i:], " \t\r"))
+		}
+	}
+	return result
+}
+
+func splitLines(text string) []string {
+	return strings.Split(text, "\n")
+}
+
+func isWhitespaceSep(text string) bool {
+	return strings.Trim(text, " \t\r\n") == ""
+}
+
+// Reports whether the source text of a separator contains a line continuation.
+func hasLineContinuation(text string) bool {
+	for _, line := range splitLines(text) {
+		if strings.HasSuffix(strings.TrimRight(line, "\r"), "^") {
+			return true
+		}
+	}
+	return false
+}
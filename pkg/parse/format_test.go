@@ -0,0 +1,67 @@
+package parse
+
+import (
+	"testing"
+
+	"src.elv.sh/pkg/tt"
+)
+
+func formatCode(code string) string {
+	tree, err := Parse(Source{Name: "[test]", Code: code}, Config{})
+	if err != nil {
+		panic(err)
+	}
+	return Format(tree)
+}
+
+func TestFormat(t *testing.T) {
+	tt.Test(t, tt.Fn(formatCode).Named("Format").ArgsFmt("(%q)"),
+		Args("").Rets(""),
+		Args("\n\n").Rets(""),
+
+		// Whitespaces are normalized.
+		Args("  echo   a\tb  ").Rets("echo a b\n"),
+		Args("echo a  |  wc   -l &").Rets("echo a | wc -l &\n"),
+		Args("echo a >  f  2>&1 <g").Rets("echo a > f 2>&1 <g\n"),
+		Args("echo $a[ 0  1 ] [ a  b ] [ &k=  v  &k2=v2 ] {a, b}").
+			Rets("echo $a[0 1] [a b] [&k=v &k2=v2] {a,b}\n"),
+		Args("echo [] [ ] [&] [ & ]").Rets("echo [] [] [&] [&]\n"),
+		Args("echo 'a  b' \"c  d\"").Rets("echo 'a  b' \"c  d\"\n"),
+
+		// Pipelines are written on their own lines, and blank lines are
+		// collapsed.
+		Args("a; b\n\n\n\nc\n").Rets("a\nb\n\nc\n"),
+
+		// Single-line lambdas and captures stay on a single line.
+		Args("each {|x|   echo $x;put $x} (a ; b) ?( c )").
+			Rets("each {|x| echo $x; put $x } (a; b) ?(c)\n"),
+		Args("f { } {|| } {|a @b &c=d| }").Rets("f { } {|| } {|a @b &c=d| }\n"),
+		Args("fn f {  echo }").Rets("fn f { echo }\n"),
+
+		// Multi-line lambdas and captures are indented.
+		Args("fn f {|x|\necho $x\nif $x {\n    put $x\n}\n}").
+			Rets("fn f {|x|\n  echo $x\n  if $x {\n    put $x\n  }\n}\n"),
+		Args("fn f {\necho }").Rets("fn f {\n  echo\n}\n"),
+		Args("var x = (\na\nb)").Rets("var x = (\n  a\n  b\n)\n"),
+
+		// Multi-line lists and maps keep their elements on the same lines,
+		// and values of map pairs on consecutive lines are aligned.
+		Args("var l = [a b\nc\n\n\n  d]").Rets("var l = [\n  a b\n  c\n\n  d\n]\n"),
+		Args("var m = [\n&a=x\n&long-key=y\n\n&k=[\nz\n]\n]").
+			Rets("var m = [\n  &a=        x\n  &long-key= y\n\n  &k= [\n    z\n  ]\n]\n"),
+
+		// Comments are preserved.
+		Args("# c1\n\n  # c2\necho a   # c3\necho b;  # c4").
+			Rets("# c1\n\n# c2\necho a # c3\necho b # c4\n"),
+		Args("fn f { # c1\n# c2\necho  # c3\n}").
+			Rets("fn f {\n  # c1\n  # c2\n  echo # c3\n}\n"),
+		Args("var l = [ # c1\na # c2\n# c3\nb\n]").
+			Rets("var l = [\n  # c1\n  a # c2\n  # c3\n  b\n]\n"),
+
+		// Multi-line pipelines and forms have their continuation lines
+		// indented.
+		Args("a |\nb |\n    c").Rets("a |\n  b |\n  c\n"),
+		Args("echo a ^\nb ^\n  c").Rets("echo a ^\n  b ^\n  c\n"),
+		Args("a |\nb {\nc\n}").Rets("a |\n  b {\n    c\n  }\n"),
+	)
+}
@@ -17,6 +17,7 @@ import (
 	"math"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"src.elv.sh/pkg/diag"
 )
@@ -31,6 +32,14 @@ type Tree struct {
 type Config struct {
 	// Destination of warnings. If nil, warnings are suppressed.
 	WarningWriter io.Writer
+	// If true, parsing a Chunk doesn't stop at the first rune that can't start
+	// a pipeline. Instead, the rest of the line is skipped and kept in the
+	// parse tree as a Bad node, and parsing resumes on the next line. The
+	// errors are still returned.
+	//
+	// This is useful for tools that need to work on incomplete or invalid code,
+	// like the syntax highlighter of the editor.
+	Recover bool
 }
 
 // Parse parses the given source. The returned error may contain one or more
@@ -47,6 +56,9 @@ func Parse(src Source, cfg Config) (Tree, error) {
 func ParseAs(src Source, n Node, cfg Config) error {
 	ps := &parser{srcName: src.Name, src: src.Code, warn: cfg.WarningWriter}
 	ps.parse(n)
+	if chunk, ok := n.(*Chunk); ok && cfg.Recover {
+		chunk.recover(ps)
+	}
 	ps.done()
 	return diag.PackErrors(ps.errors)
 }
@@ -93,6 +105,32 @@ func (bn *Chunk) parse(ps *parser) {
 	}
 }
 
+// Skips over runes that can't start a pipeline after bn has been parsed, until
+// the end of the source.
+func (bn *Chunk) recover(ps *parser) {
+	for ps.pos < len(ps.src) {
+		r, _ := utf8.DecodeRuneInString(ps.src[ps.pos:])
+		ps.error(fmt.Errorf("unexpected rune %q", r))
+		addChild(bn, ps.parse(&Bad{}).n)
+		bn.parse(ps)
+	}
+	bn.To = ps.pos
+	bn.sourceText = ps.src[bn.From:ps.pos]
+}
+
+// Bad represents text that can't be parsed, skipped when recovering from
+// errors. It can only appear in parse trees built with [Config.Recover] set,
+// as a child of a Chunk.
+type Bad struct {
+	node
+}
+
+func (bn *Bad) parse(ps *parser) {
+	for r := ps.peek(); r != eof && r != '\n'; r = ps.peek() {
+		ps.next()
+	}
+}
+
 func isPipelineSep(r rune) bool {
 	return r == '\r' || r == '\n' || r == ';'
 }
@@ -294,11 +332,12 @@ func validBarewordVariableName(name string, allowSigil bool) bool {
 	return true
 }
 
-// Redir = { Compound } { '<'|'>'|'<>'|'>>' } { Space } ( '&'? Compound )
+// Redir = { Compound } { '<'|'>'|'<>'|'>>' } [ '|' ] { Space } ( '&'? Compound )
 type Redir struct {
 	node
 	Left      *Compound
 	Mode      RedirMode
+	IsValues  bool
 	RightIsFd bool
 	Right     *Compound
 }
@@ -328,6 +367,10 @@ func (rn *Redir) parse(ps *parser) {
 		ps.error(errBadRedirSign)
 	}
 	addSep(rn, ps)
+	// A "|" after the sign makes the redirection apply to the value channel.
+	if parseSep(rn, ps, '|') {
+		rn.IsValues = true
+	}
 	parseSpaces(rn, ps)
 	if parseSep(rn, ps, '&') {
 		rn.RightIsFd = true
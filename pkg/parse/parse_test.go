@@ -3,6 +3,7 @@ package parse
 import (
 	"fmt"
 	"os"
+	"reflect"
 	"testing"
 )
 
@@ -112,6 +113,21 @@ var testCases = []struct {
 				{"Redir", fs{"Left": "6", "Mode": ReadWrite, "Right": "d"}},
 			},
 		}}},
+	{
+		name: "value redirections",
+		code: "a >| b >>|c <| d 2>|e >v f",
+		node: &Form{},
+		want: ast{"Form", fs{
+			"Head": "a",
+			"Args": []string{"f"},
+			"Redirs": []ast{
+				{"Redir", fs{"Mode": Write, "IsValues": true, "Right": "b"}},
+				{"Redir", fs{"Mode": Append, "IsValues": true, "Right": "c"}},
+				{"Redir", fs{"Mode": Read, "IsValues": true, "Right": "d"}},
+				{"Redir", fs{"Left": "2", "Mode": Write, "IsValues": true, "Right": "e"}},
+				{"Redir", fs{"Mode": Write, "Right": "v"}},
+			},
+		}}},
 	{
 		name: "command options",
 		code: "a &a=1 x &b=2",
@@ -693,3 +709,36 @@ func TestParse_ReturnsTreeContainingSourceFromArgument(t *testing.T) {
 		t.Errorf("tree.Source = %v, want %v", tree.Source, src)
 	}
 }
+
+func TestParse_Recover(t *testing.T) {
+	code := "a )b c\nd\n  ]\ne"
+	tree, err := Parse(SourceForTest(code), Config{Recover: true})
+
+	errs := UnpackErrors(err)
+	var errParts []string
+	for _, err := range errs {
+		errParts = append(errParts, code[err.Context.From:err.Context.To])
+	}
+	if want := []string{")", "]"}; !reflect.DeepEqual(errParts, want) {
+		t.Errorf("got errors at %q, want %q", errParts, want)
+	}
+
+	if err := checkParseTree(tree.Root); err != nil {
+		t.Errorf("bad parse tree: %v", err)
+	}
+	err = checkAST(tree.Root, ast{"Chunk", fs{
+		"Pipelines": []string{"a ", "d", "e"}}})
+	if err != nil {
+		t.Errorf("bad AST: %v", err)
+	}
+
+	var bads []string
+	for _, ch := range Children(tree.Root) {
+		if bad, ok := ch.(*Bad); ok {
+			bads = append(bads, SourceText(bad))
+		}
+	}
+	if want := []string{")b c", "]"}; !reflect.DeepEqual(bads, want) {
+		t.Errorf("got Bad nodes %q, want %q", bads, want)
+	}
+}
@@ -24,7 +24,7 @@ var pprintASTTests = []*tt.Case{
   Pipeline/Form
     Compound/Indexing/Primary ExprCtx=CmdExpr Type=Bareword Value="echo"
     Compound/Indexing/Primary ExprCtx=NormalExpr Type=Bareword Value="done"
-    Redir Mode=Write RightIsFd=false
+    Redir Mode=Write IsValues=false RightIsFd=false
       Compound/Indexing/Primary ExprCtx=NormalExpr Type=Bareword Value="/redir-dest"
 `),
 }
@@ -0,0 +1,144 @@
+package shell
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+	"time"
+
+	"src.elv.sh/pkg/env"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/parse"
+)
+
+// The audit log records every command executed by Elvish, for environments
+// where that is required. Unlike the interactive history, it is only ever
+// appended to and never read by Elvish, and records commands regardless of
+// whether they succeed.
+
+// Value of the -audit-log flag that sends the audit log to syslog.
+const auditToSyslog = "syslog"
+
+type auditLog struct {
+	user   string
+	stderr io.Writer
+
+	mu sync.Mutex
+	w  io.WriteCloser
+	// Number of commands recorded so far.
+	n int
+}
+
+// An entry in the audit log, written as one line of JSON. Each command gets a
+// "start" entry before it runs and an "end" entry with its status after it
+// finishes; the two are identified by the same PID and sequence number, and
+// the "end" entry is missing if Elvish is killed while running the command.
+type auditEntry struct {
+	Event string `json:"event"`
+	Time  string `json:"time"`
+	PID   int    `json:"pid"`
+	Seq   int    `json:"seq"`
+	User  string `json:"user"`
+	Cwd   string `json:"cwd"`
+	Src   string `json:"src"`
+	Code  string `json:"code"`
+	// Only set for "end" entries.
+	Status *int `json:"status,omitempty"`
+}
+
+// Opens the audit log at dest, which is either the path of a file or
+// auditToSyslog. Problems writing to the audit log later are reported to
+// stderr.
+func openAuditLog(dest string, stderr io.Writer) (*auditLog, error) {
+	var w io.WriteCloser
+	if dest == auditToSyslog {
+		var err error
+		w, err = openAuditSyslog()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		f, err := os.OpenFile(dest, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+	}
+	return &auditLog{user: auditUser(), stderr: stderr, w: w}, nil
+}
+
+func auditUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv(env.USERNAME)
+}
+
+// Runs f, which executes code from src, and records it in the audit log. The
+// code is recorded separately from src, since src may be a whole script. It is
+// fine to call run on a nil *auditLog, in which case it just runs f.
+func (a *auditLog) run(src parse.Source, code string, f func() error) error {
+	if a == nil {
+		return f()
+	}
+	cwd, _ := os.Getwd()
+	a.mu.Lock()
+	a.n++
+	entry := auditEntry{PID: os.Getpid(), Seq: a.n, User: a.user, Cwd: cwd,
+		Src: src.Name, Code: code}
+	a.mu.Unlock()
+
+	entry.Event = "start"
+	a.write(entry)
+	err := f()
+	status := auditStatus(err)
+	entry.Event, entry.Status = "end", &status
+	a.write(entry)
+	return err
+}
+
+func (a *auditLog) write(entry auditEntry) {
+	entry.Time = time.Now().Format(time.RFC3339)
+	line, _ := json.Marshal(entry)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.w.Write(append(line, '\n')); err != nil {
+		fmt.Fprintln(a.stderr, "Warning: cannot write audit log:", err)
+	}
+}
+
+func (a *auditLog) close() {
+	if a != nil {
+		a.w.Close()
+	}
+}
+
+// Returns the exit status of a command that finished with err, as it would be
+// reported by a POSIX shell.
+func auditStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exc, ok := err.(eval.Exception); ok {
+		if exit, ok := exc.Reason().(eval.ExternalCmdExit); ok && exit.Exited() {
+			return exit.ExitStatus()
+		}
+	}
+	return 1
+}
+
+// Returns the code to record for running a script with arguments.
+func scriptAuditCode(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = parse.Quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
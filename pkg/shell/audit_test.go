@@ -0,0 +1,80 @@
+package shell
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"src.elv.sh/pkg/must"
+	. "src.elv.sh/pkg/prog/progtest"
+	"src.elv.sh/pkg/testutil"
+)
+
+func TestAuditLog(t *testing.T) {
+	setupCleanHomePaths(t)
+	dir := testutil.InTempDir(t)
+	must.WriteFile("hello.elv", "echo hello")
+
+	Test(t, &Program{},
+		ThatElvish("-audit-log", "audit.log", "-c", "echo hello").
+			WritesStdout("hello\n"),
+		ThatElvish("-audit-log", "audit.log", "-c", "fail x").
+			ExitsWith(2).
+			WritesStderrContaining("x"),
+		ThatElvish("-audit-log", "audit.log", "hello.elv", "a b").
+			WritesStdout("hello\n"),
+
+		ThatElvish("-audit-log", "non-existent/audit.log", "-c", "echo hello").
+			ExitsWith(2).
+			WritesStderrContaining("cannot open audit log"),
+	)
+
+	var entries []auditEntry
+	for _, line := range strings.Split(strings.TrimSuffix(must.ReadFileString("audit.log"), "\n"), "\n") {
+		var entry auditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("cannot parse audit log line %q: %v", line, err)
+		}
+		if entry.Time == "" || entry.User != auditUser() {
+			t.Errorf("got time %q and user %q", entry.Time, entry.User)
+		}
+		entries = append(entries, entry)
+	}
+	ok, failed := 0, 1
+	want := []auditEntry{
+		{Event: "start", Seq: 1, Cwd: dir, Src: "code from -c", Code: "echo hello"},
+		{Event: "end", Seq: 1, Cwd: dir, Src: "code from -c", Code: "echo hello",
+			Status: &ok},
+		{Event: "start", Seq: 1, Cwd: dir, Src: "code from -c", Code: "fail x"},
+		{Event: "end", Seq: 1, Cwd: dir, Src: "code from -c", Code: "fail x",
+			Status: &failed},
+		{Event: "start", Seq: 1, Cwd: dir,
+			Src: dir + string(os.PathSeparator) + "hello.elv", Code: "hello.elv 'a b'"},
+		{Event: "end", Seq: 1, Cwd: dir,
+			Src: dir + string(os.PathSeparator) + "hello.elv", Code: "hello.elv 'a b'",
+			Status: &ok},
+	}
+	if diff := cmp.Diff(want, entries,
+		cmpopts.IgnoreFields(auditEntry{}, "Time", "PID", "User")); diff != "" {
+		t.Errorf("audit log entries (-want +got):\n%s", diff)
+	}
+	for i := 0; i+1 < len(entries); i += 2 {
+		if entries[i].PID == 0 || entries[i].PID != entries[i+1].PID {
+			t.Errorf("got PIDs %v and %v for start and end entries",
+				entries[i].PID, entries[i+1].PID)
+		}
+	}
+}
+
+func TestAuditStatus(t *testing.T) {
+	if got := auditStatus(nil); got != 0 {
+		t.Errorf("auditStatus(nil) = %v, want 0", got)
+	}
+	if got := auditStatus(os.ErrNotExist); got != 1 {
+		t.Errorf("auditStatus(os.ErrNotExist) = %v, want 1", got)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build unix
+
+package shell
+
+import (
+	"io"
+	"log/syslog"
+)
+
+func openAuditSyslog() (io.WriteCloser, error) {
+	return syslog.New(syslog.LOG_INFO|syslog.LOG_AUTHPRIV, "elvish")
+}
@@ -0,0 +1,12 @@
+package shell
+
+import (
+	"errors"
+	"io"
+)
+
+var errAuditSyslogNotSupported = errors.New("syslog is not supported on Windows")
+
+func openAuditSyslog() (io.WriteCloser, error) {
+	return nil, errAuditSyslogNotSupported
+}
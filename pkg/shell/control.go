@@ -0,0 +1,179 @@
+package shell
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/parse"
+)
+
+// The control socket allows other programs to evaluate code in a running
+// interactive shell.
+//
+// Clients connect to the socket and write requests that are JSON objects, one
+// per line, like:
+//
+//	{"code": "put foo; echo bar"}
+//
+// For each request, the code is evaluated in the global namespace of the
+// shell, and a response is written on a single line after the evaluation
+// finishes, like:
+//
+//	{"values": ["foo"], "bytes": "bar\n"}
+//
+// The "values" field contains the value outputs, formatted with repr. If the
+// evaluation fails, the response also has an "error" field containing the
+// error message.
+
+type controlRequest struct {
+	Code string `json:"code"`
+}
+
+type controlResponse struct {
+	Values []string `json:"values"`
+	Bytes  string   `json:"bytes"`
+	Error  string   `json:"error,omitempty"`
+}
+
+var (
+	errNotUnixConn     = errors.New("not a Unix socket connection")
+	errControlInUse    = errors.New("another process is listening on the socket")
+	errControlNotSock  = errors.New("file exists and is not a socket")
+	errControlWrongUID = errors.New("connection from another user")
+)
+
+// Starts serving the control socket at path. It returns a function that stops
+// serving and removes the socket file.
+//
+// Only the owner of the shell may evaluate code in it. The socket is created
+// in a private directory and only moved to path after its permissions are
+// restricted, so other users never get a chance to connect to it; connections
+// from other users are also rejected by checking the peer credentials, where
+// supported.
+func serveControl(ev *eval.Evaler, path string, audit *auditLog) (func(), error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".elvish-control-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Chmod(dir, 0700); err != nil {
+		return nil, err
+	}
+	tmpPath := filepath.Join(dir, "sock")
+	l, err := net.Listen("unix", tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(tmpPath, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		l.Close()
+		return nil, err
+	}
+	var n atomic.Int64
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			if err := checkControlPeer(conn); err != nil {
+				logger.Println("rejecting control connection:", err)
+				conn.Close()
+				continue
+			}
+			go serveControlConn(ev, conn, &n, audit)
+		}
+	}()
+	return func() {
+		l.Close()
+		os.Remove(path)
+	}, nil
+}
+
+// Removes a socket file left behind by a shell that didn't exit cleanly. It is
+// an error if path is not a socket, or if a process is still listening on it.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.Mode().Type() != fs.ModeSocket {
+		return errControlNotSock
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return errControlInUse
+	}
+	return os.Remove(path)
+}
+
+func checkControlPeer(conn net.Conn) error {
+	uid, err := peerUID(conn)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if uid != os.Getuid() {
+		return errControlWrongUID
+	}
+	return nil
+}
+
+func serveControlConn(ev *eval.Evaler, conn net.Conn, n *atomic.Int64, audit *auditLog) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(nil, 1<<24)
+	enc := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req controlRequest
+		var resp controlResponse
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp.Error = fmt.Sprintf("bad request: %v", err)
+		} else {
+			src := parse.Source{Name: fmt.Sprintf("[control %d]", n.Add(1)), Code: req.Code}
+			resp = evalForControl(ev, src, audit)
+		}
+		if err := enc.Encode(resp); err != nil {
+			logger.Println("writing control response:", err)
+			return
+		}
+	}
+}
+
+func evalForControl(ev *eval.Evaler, src parse.Source, audit *auditLog) controlResponse {
+	port, collect, err := eval.CapturePort()
+	if err != nil {
+		return controlResponse{Error: err.Error()}
+	}
+	err = audit.run(src, src.Code, func() error {
+		return ev.Eval(src, eval.EvalCfg{Ports: []*eval.Port{nil, port, port}})
+	})
+	values, bytes := collect()
+	resp := controlResponse{Values: make([]string, len(values)), Bytes: string(bytes)}
+	for i, v := range values {
+		resp.Values[i] = vals.ReprPlain(v)
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return resp
+}
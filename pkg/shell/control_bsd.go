@@ -0,0 +1,33 @@
+//go:build darwin || freebsd
+
+package shell
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// Returns the UID of the process on the other end of a Unix socket connection.
+func peerUID(conn net.Conn) (int, error) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return 0, errNotUnixConn
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var cred *unix.Xucred
+	var errCred error
+	err = raw.Control(func(fd uintptr) {
+		cred, errCred = unix.GetsockoptXucred(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if errCred != nil {
+		return 0, errCred
+	}
+	return int(cred.Uid), nil
+}
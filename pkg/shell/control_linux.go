@@ -0,0 +1,31 @@
+package shell
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// Returns the UID of the process on the other end of a Unix socket connection.
+func peerUID(conn net.Conn) (int, error) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return 0, errNotUnixConn
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var cred *unix.Ucred
+	var errCred error
+	err = raw.Control(func(fd uintptr) {
+		cred, errCred = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if errCred != nil {
+		return 0, errCred
+	}
+	return int(cred.Uid), nil
+}
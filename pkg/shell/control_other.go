@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !freebsd
+
+package shell
+
+import (
+	"errors"
+	"net"
+)
+
+// Returns the UID of the process on the other end of a Unix socket connection.
+// Peer credentials are not supported on this platform, so the permissions of
+// the socket file are the only protection.
+func peerUID(conn net.Conn) (int, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,142 @@
+//go:build unix
+
+package shell
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/testutil"
+)
+
+func TestControlSocket(t *testing.T) {
+	testutil.InTempDir(t)
+	ev := eval.NewEvaler()
+	stop, err := serveControl(ev, "sock", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat("sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("got socket permission %o, want 0600", perm)
+	}
+
+	conn, err := net.Dial("unix", "sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	request := func(line string) controlResponse {
+		t.Helper()
+		conn.Write([]byte(line + "\n"))
+		var resp controlResponse
+		respLine, err := r.ReadBytes('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(respLine, &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	tests := []struct {
+		name    string
+		request string
+		want    controlResponse
+	}{
+		{
+			name:    "value and byte outputs",
+			request: `{"code": "put foo [bar]; echo lorem"}`,
+			want:    controlResponse{Values: []string{"foo", "[bar]"}, Bytes: "lorem\n"},
+		},
+		{
+			name:    "global namespace is shared",
+			request: `{"code": "var x = 10"}`,
+			want:    controlResponse{Values: []string{}},
+		},
+		{
+			name:    "global namespace is shared (continued)",
+			request: `{"code": "put $x"}`,
+			want:    controlResponse{Values: []string{"10"}},
+		},
+		{
+			name:    "error",
+			request: `{"code": "put foo; fail bad"}`,
+			want:    controlResponse{Values: []string{"foo"}, Error: "bad"},
+		},
+		{
+			name:    "bad request",
+			request: `not json`,
+			want: controlResponse{
+				Error: "bad request: invalid character 'o' in literal null (expecting 'u')"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if diff := cmp.Diff(test.want, request(test.request)); diff != "" {
+				t.Errorf("response (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if !ev.Global().HasKeyString("x") {
+		t.Errorf("variable $x not in the global namespace")
+	}
+
+	stop()
+	if _, err := os.Stat("sock"); !os.IsNotExist(err) {
+		t.Errorf("socket not removed after stopping")
+	}
+}
+
+func TestControlSocket_RemovesStaleSocket(t *testing.T) {
+	testutil.InTempDir(t)
+	// Leave a socket file behind without anyone listening on it.
+	l, err := net.Listen("unix", "sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+
+	stop, err := serveControl(eval.NewEvaler(), "sock", nil)
+	if err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+	stop()
+}
+
+func TestControlSocket_RefusesSocketInUse(t *testing.T) {
+	testutil.InTempDir(t)
+	stop, err := serveControl(eval.NewEvaler(), "sock", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	_, err = serveControl(eval.NewEvaler(), "sock", nil)
+	if err != errControlInUse {
+		t.Errorf("got error %v, want %v", err, errControlInUse)
+	}
+}
+
+func TestControlSocket_RefusesNonSocket(t *testing.T) {
+	testutil.InTempDir(t)
+	testutil.ApplyDir(testutil.Dir{"sock": "not a socket"})
+
+	_, err := serveControl(eval.NewEvaler(), "sock", nil)
+	if err != errControlNotSock {
+		t.Errorf("got error %v, want %v", err, errControlNotSock)
+	}
+}
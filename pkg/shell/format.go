@@ -0,0 +1,69 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/parse"
+)
+
+// Formats the given files, or stdin if no file is given. The result is written
+// to stdout, or back to the files if write is true. Returns the exit status.
+func runFormat(fds [3]*os.File, files []string, write bool) int {
+	if len(files) == 0 {
+		code, err := io.ReadAll(fds[0])
+		if err != nil {
+			fmt.Fprintln(fds[2], err)
+			return 2
+		}
+		formatted, err := formatCode("[stdin]", string(code))
+		if err != nil {
+			diag.ShowError(fds[2], err)
+			return 1
+		}
+		fmt.Fprint(fds[1], formatted)
+		return 0
+	}
+
+	exit := 0
+	for _, file := range files {
+		if err := formatFile(fds, file, write); err != nil {
+			diag.ShowError(fds[2], err)
+			exit = 1
+		}
+	}
+	return exit
+}
+
+func formatFile(fds [3]*os.File, file string, write bool) error {
+	code, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	formatted, err := formatCode(file, string(code))
+	if err != nil {
+		return err
+	}
+	if !write {
+		_, err := fmt.Fprint(fds[1], formatted)
+		return err
+	}
+	if formatted == string(code) {
+		return nil
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, []byte(formatted), info.Mode().Perm())
+}
+
+func formatCode(name, code string) (string, error) {
+	tree, err := parse.Parse(parse.Source{Name: name, Code: code, IsFile: true}, parse.Config{})
+	if err != nil {
+		return "", err
+	}
+	return parse.Format(tree), nil
+}
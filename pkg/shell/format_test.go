@@ -0,0 +1,40 @@
+package shell
+
+import (
+	"os"
+	"testing"
+
+	. "src.elv.sh/pkg/prog/progtest"
+	"src.elv.sh/pkg/testutil"
+)
+
+func TestFormat(t *testing.T) {
+	setupCleanHomePaths(t)
+	testutil.InTempDir(t)
+	testutil.ApplyDir(testutil.Dir{
+		"a.elv":   "echo  a;echo b\n",
+		"b.elv":   "fn f {\necho b }\n",
+		"bad.elv": "echo (",
+	})
+
+	Test(t, &Program{},
+		ThatElvish("-fmt").WithStdin("echo   a|wc").WritesStdout("echo a | wc\n"),
+		ThatElvish("-fmt", "a.elv", "b.elv").
+			WritesStdout("echo a\necho b\nfn f {\n  echo b\n}\n"),
+		ThatElvish("-fmt", "bad.elv").
+			ExitsWith(1).
+			WritesStderrContaining("bad.elv"),
+		ThatElvish("-fmt", "non-existent").
+			ExitsWith(1).
+			WritesStderrContaining("non-existent"),
+		ThatElvish("-fmt", "-w", "a.elv").DoesNothing(),
+	)
+
+	content, err := os.ReadFile("a.elv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(content), "echo a\necho b\n"; got != want {
+		t.Errorf("a.elv after -fmt -w: got %q, want %q", got, want)
+	}
+}
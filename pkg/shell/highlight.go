@@ -0,0 +1,48 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"src.elv.sh/pkg/edit/highlight"
+)
+
+// With an empty highlight.Config, all commands are highlighted as valid, since
+// checking for them requires an Evaler.
+var highlighter = highlight.NewHighlighter(highlight.Config{})
+
+// Highlights the given files, or stdin if no file is given, and writes the
+// result to stdout as text with VT escape sequences, or as HTML if html is
+// true. Returns the exit status.
+func runHighlight(fds [3]*os.File, files []string, html bool) int {
+	if len(files) == 0 {
+		code, err := io.ReadAll(fds[0])
+		if err != nil {
+			fmt.Fprintln(fds[2], err)
+			return 2
+		}
+		fmt.Fprint(fds[1], highlightCode(string(code), html))
+		return 0
+	}
+
+	exit := 0
+	for _, file := range files {
+		code, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintln(fds[2], err)
+			exit = 1
+			continue
+		}
+		fmt.Fprint(fds[1], highlightCode(string(code), html))
+	}
+	return exit
+}
+
+func highlightCode(code string, html bool) string {
+	text, _ := highlighter.Get(code)
+	if html {
+		return text.HTMLString()
+	}
+	return text.VTString()
+}
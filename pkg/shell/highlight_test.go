@@ -0,0 +1,31 @@
+package shell
+
+import (
+	"testing"
+
+	. "src.elv.sh/pkg/prog/progtest"
+	"src.elv.sh/pkg/testutil"
+)
+
+func TestHighlight(t *testing.T) {
+	setupCleanHomePaths(t)
+	testutil.InTempDir(t)
+	testutil.ApplyDir(testutil.Dir{
+		"a.elv": "echo $x\n",
+		"b.elv": "put 'a<b'\n",
+	})
+
+	Test(t, &Program{},
+		ThatElvish("-highlight").WithStdin("echo $x").
+			WritesStdout("\033[;32mecho\033[m \033[35m$x\033[m"),
+		ThatElvish("-highlight", "a.elv", "b.elv").
+			WritesStdout("\033[;32mecho\033[m \033[35m$x\033[m\n"+
+				"\033[;32mput\033[m \033[33m'a<b'\033[m\n"),
+		ThatElvish("-highlight", "-html", "b.elv").
+			WritesStdout(`<span class="sgr-32">put</span> `+
+				`<span class="sgr-33">&#39;a&lt;b&#39;</span>`+"\n"),
+		ThatElvish("-highlight", "non-existent").
+			ExitsWith(1).
+			WritesStderrContaining("non-existent"),
+	)
+}
@@ -11,11 +11,14 @@ import (
 	"time"
 
 	"src.elv.sh/pkg/cli"
+	"src.elv.sh/pkg/cli/term"
 	"src.elv.sh/pkg/daemon/daemondefs"
 	"src.elv.sh/pkg/diag"
 	"src.elv.sh/pkg/edit"
 	"src.elv.sh/pkg/eval"
 	"src.elv.sh/pkg/mods/daemon"
+	"src.elv.sh/pkg/mods/log"
+	"src.elv.sh/pkg/mods/session"
 	"src.elv.sh/pkg/mods/store"
 	"src.elv.sh/pkg/parse"
 	"src.elv.sh/pkg/strutil"
@@ -33,13 +36,41 @@ type interactCfg struct {
 
 	ActivateDaemon daemondefs.ActivateFunc
 	SpawnConfig    *daemondefs.SpawnConfig
+
+	Audit *auditLog
+
+	// Directory to save checkpoints of the session in. Checkpointing is
+	// disabled if empty.
+	SessionDir string
 }
 
+// How often the session is checkpointed while a command is running, in
+// addition to before and after each command. No periodic checkpoints are taken
+// while Elvish is waiting for input.
+var checkpointInterval = 30 * time.Second
+
 // Interface satisfied by the line editor. Used for swapping out the editor with
 // minEditor when necessary.
 type editor interface {
 	ReadCode() (string, error)
 	RunAfterCommandHooks(src parse.Source, duration float64, err error)
+	Pager() []string
+}
+
+// An editor that compiles the code being edited, like *edit.Editor. The
+// compiled code is evaluated directly instead of parsing and compiling the
+// code again.
+type compilingEditor interface {
+	editor
+	SetSourceName(name string)
+	Compiled(src parse.Source) *eval.Compiled
+}
+
+// An editor that runs hooks around each pipeline at the top level of an
+// interactive command, like *edit.Editor.
+type pipelineHookEditor interface {
+	RunBeforePipelineHooks(code string, start time.Time)
+	RunAfterPipelineHooks(code string, start time.Time, duration float64, err error)
 }
 
 // Runs an interactive shell session.
@@ -65,12 +96,23 @@ func interact(ev *eval.Evaler, fds [3]*os.File, cfg *interactCfg) {
 			ev.PreExitHooks = append(ev.PreExitHooks, func() { cl.Close() })
 			ev.AddModule("store", store.Ns(cl))
 			ev.AddModule("daemon", daemon.Ns(cl))
+			log.SetStore(cl)
 		}
 	}
 
-	// Build Editor.
+	var checkpointer *session.Checkpointer
+	if cfg.SessionDir != "" {
+		ev.AddModule("session", session.Ns(cfg.SessionDir))
+		showCrashedSessions(fds[2], cfg.SessionDir)
+		checkpointer = session.NewCheckpointer(cfg.SessionDir, ev)
+		ev.PreExitHooks = append(ev.PreExitHooks, func() { checkpointer.Remove() })
+	}
+
+	// Build Editor. The full editor requires a terminal that supports escape
+	// sequences; fall back to a basic line editor that doesn't write any escape
+	// sequences otherwise.
 	var ed editor
-	if sys.IsATTY(fds[0].Fd()) {
+	if sys.IsATTY(fds[0].Fd()) && !term.IsDumb() {
 		newed := edit.NewEditor(cli.NewTTY(fds[0], fds[2]), ev, daemonClient)
 		ev.ExtendBuiltin(eval.BuildNs().AddNs("edit", newed))
 		ev.BgJobNotify = func(s string) { newed.Notify(ui.T(s)) }
@@ -92,7 +134,11 @@ func interact(ev *eval.Evaler, fds [3]*os.File, cfg *interactCfg) {
 
 	for {
 		cmdNum++
+		srcName := fmt.Sprintf("[tty %v]", cmdNum)
 
+		if ced, ok := ed.(compilingEditor); ok {
+			ced.SetSourceName(srcName)
+		}
 		line, err := ed.ReadCode()
 		if err == io.EOF {
 			break
@@ -121,14 +167,50 @@ func interact(ev *eval.Evaler, fds [3]*os.File, cfg *interactCfg) {
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
-		err = evalInTTY(fds, ev, ed,
-			parse.Source{Name: fmt.Sprintf("[tty %v]", cmdNum), Code: line})
+		src := parse.Source{Name: srcName, Code: line}
+		stopCheckpoints := func() {}
+		if checkpointer != nil {
+			checkpointer.SetRunning(line)
+			stopCheckpoints = checkpointer.Start(checkpointInterval)
+		}
+		err = cfg.Audit.run(src, line, func() error {
+			return evalInTTY(fds, ev, ed, src, ed.Pager())
+		})
+		stopCheckpoints()
+		if checkpointer != nil {
+			checkpointer.SetRunning("")
+		}
 		if err != nil {
 			diag.ShowError(fds[2], err)
 		}
 	}
 }
 
+// Tells the user about sessions that ended without cleaning up their
+// checkpoints, and how to restore them.
+func showCrashedSessions(w io.Writer, dir string) {
+	states, err := session.Crashed(dir)
+	if err != nil {
+		fmt.Fprintln(w, "Cannot check for crashed sessions:", err)
+		return
+	}
+	if len(states) == 0 {
+		return
+	}
+	last := states[0]
+	if len(states) == 1 {
+		fmt.Fprintf(w, "A previous session (pid %d) ended unexpectedly", last.PID)
+	} else {
+		fmt.Fprintf(w, "%d previous sessions ended unexpectedly; the latest (pid %d) was", len(states), last.PID)
+	}
+	fmt.Fprintf(w, " in %s", parse.Quote(last.Pwd))
+	if last.Running != "" {
+		fmt.Fprintf(w, ", running %s", parse.Quote(last.Running))
+	}
+	fmt.Fprintln(w, ".")
+	fmt.Fprintln(w, "Run \"use session; session:restore\" to restore its directories, or \"use session; session:discard\" to forget about it.")
+}
+
 // Interactive mode panic handler.
 func handlePanic() {
 	r := recover()
@@ -154,7 +236,9 @@ func sourceRC(fds [3]*os.File, ev *eval.Evaler, ed editor, rcPath string) error
 		}
 		return err
 	}
-	return evalInTTY(fds, ev, ed, parse.Source{Name: absPath, Code: code, IsFile: true})
+	// The rc file is not an interactive command, so its value outputs are not
+	// paged.
+	return evalInTTY(fds, ev, ed, parse.Source{Name: absPath, Code: code, IsFile: true}, nil)
 }
 
 type minEditor struct {
@@ -170,6 +254,12 @@ func (ed *minEditor) RunAfterCommandHooks(src parse.Source, duration float64, er
 	// no-op; minEditor doesn't support this hook.
 }
 
+func (ed *minEditor) Pager() []string {
+	// minEditor is used when the terminal doesn't support escape sequences,
+	// which pagers usually require.
+	return nil
+}
+
 func (ed *minEditor) ReadCode() (string, error) {
 	wd, err := os.Getwd()
 	if err != nil {
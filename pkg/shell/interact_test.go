@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"testing"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"src.elv.sh/pkg/daemon/daemondefs"
 	"src.elv.sh/pkg/env"
 	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/parse"
 	. "src.elv.sh/pkg/prog/progtest"
 	"src.elv.sh/pkg/testutil"
 )
@@ -92,6 +94,49 @@ func TestInteract_DBPath_XDG_STATE_HOME(t *testing.T) {
 	)
 }
 
+func TestInteract_CheckpointsSession(t *testing.T) {
+	setupCleanHomePaths(t)
+	xdgStateHome := testutil.Setenv(t, env.XDG_STATE_HOME, t.TempDir())
+	sessionDir := filepath.Join(xdgStateHome, "elvish", "sessions")
+
+	Test(t, &Program{},
+		thatElvishInteract().
+			WithStdin("use str; echo (str:contains (slurp < "+
+				parse.Quote(sessionDir)+"/$pid.json) '\"running\":\"use str')\n").
+			WritesStdout("$true\n"),
+	)
+	// The checkpoint is removed when the session ends normally.
+	entries, _ := os.ReadDir(sessionDir)
+	if len(entries) != 0 {
+		t.Errorf("got checkpoints %v after session ended, want none", entries)
+	}
+}
+
+func TestInteract_ShowsCrashedSessions(t *testing.T) {
+	setupCleanHomePaths(t)
+	xdgStateHome := testutil.Setenv(t, env.XDG_STATE_HOME, t.TempDir())
+	pid := deadPID(t)
+	must.WriteFile(
+		filepath.Join(xdgStateHome, "elvish", "sessions", fmt.Sprint(pid)+".json"),
+		fmt.Sprintf(`{"pid":%d,"pwd":"/crashed","running":"make"}`, pid))
+
+	Test(t, &Program{},
+		thatElvishInteract().
+			WritesStderrContaining(fmt.Sprintf(
+				"A previous session (pid %d) ended unexpectedly in /crashed, running make.", pid)),
+	)
+}
+
+// Returns the PID of a process that has exited.
+func deadPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd.Process.Pid
+}
+
 func thatElvishInteract(args ...string) Case {
 	return ThatElvish(args...).WritesStderrContaining("")
 }
@@ -3,10 +3,15 @@
 package shell
 
 import (
+	"io"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/creack/pty"
 	"src.elv.sh/pkg/env"
+	"src.elv.sh/pkg/eval"
 	"src.elv.sh/pkg/must"
 
 	. "src.elv.sh/pkg/prog/progtest"
@@ -34,3 +39,34 @@ func TestInteract_DBPath_Default(t *testing.T) {
 				filepath.Join(home, ".local", "state", "elvish", "db.bolt")),
 	)
 }
+
+func TestInteract_DumbTerminal(t *testing.T) {
+	setupCleanHomePaths(t)
+	testutil.InTempDir(t)
+	testutil.Setenv(t, env.TERM, "dumb")
+
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		t.Skip("cannot open pty:", err)
+	}
+	defer ptmx.Close()
+	outputCh := make(chan string)
+	go func() {
+		var sb strings.Builder
+		io.Copy(&sb, ptmx)
+		outputCh <- sb.String()
+	}()
+
+	// Write the code, followed by Ctrl-D to signal EOF.
+	ptmx.WriteString("echo hello\n\x04")
+	interact(eval.NewEvaler(), [3]*os.File{tty, tty, tty}, &interactCfg{})
+	tty.Close()
+	output := <-outputCh
+
+	if !strings.Contains(output, "hello\r\n") {
+		t.Errorf("got output %q, want hello", output)
+	}
+	if strings.Contains(output, "\033") {
+		t.Errorf("got output %q, want no escape sequences", output)
+	}
+}
@@ -0,0 +1,165 @@
+package shell
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/sys"
+	"src.elv.sh/pkg/wcwidth"
+)
+
+// Like eval.PortsFromFiles, but the value outputs written to files[1] are
+// piped through the pager once they no longer fit in the terminal. Paging is
+// disabled if the pager command is empty or files[1] is not a terminal.
+func portsWithPager(files [3]*os.File, prefix string, pager []string) ([]*eval.Port, func()) {
+	if len(pager) == 0 || !sys.IsATTY(files[1].Fd()) {
+		return eval.PortsFromFiles(files, prefix)
+	}
+	port1, cleanup1 := pagingPort(files[1], prefix, pager)
+	port2, cleanup2 := eval.FilePort(files[2], prefix)
+	return []*eval.Port{{File: files[0], Chan: eval.ClosedChan}, port1, port2}, func() {
+		cleanup1()
+		cleanup2()
+	}
+}
+
+// Like eval.FilePort, but once the value outputs no longer fit in the
+// terminal, starts the pager, writes to it the value outputs already written
+// to f, and streams the rest of the value outputs to it as they are written.
+// The cleanup function waits for the pager to exit.
+//
+// The pager runs alongside the command, so on Unix it is started in its own
+// process group in the foreground of the terminal, like a job; the external
+// commands of the command are then in the background, and get SIGTTIN if they
+// read from the terminal.
+func pagingPort(f *os.File, prefix string, pager []string) (*eval.Port, func()) {
+	// Use the same buffer size as eval.FilePort.
+	ch := make(chan any, 32)
+	relayDone := make(chan struct{})
+	pw := &pagingWriter{file: f, pager: pager}
+	pw.height, pw.width = sys.WinSize(f)
+	go func() {
+		for v := range ch {
+			pw.write(prefix + vals.ReprPlain(v) + "\n")
+		}
+		close(relayDone)
+	}()
+	return &eval.Port{File: f, Chan: ch}, func() {
+		close(ch)
+		<-relayDone
+		pw.close()
+	}
+}
+
+type pagingWriter struct {
+	file          *os.File
+	pager         []string
+	height, width int
+
+	state pagingState
+	// Output written to the file before the pager is started, and the number
+	// of terminal lines it takes. At most a screenful is kept.
+	shown []string
+	lines int
+	// The pager and the pipe to its stdin, in the pagingStarted state.
+	cmd  *exec.Cmd
+	pipe io.WriteCloser
+}
+
+type pagingState int
+
+const (
+	// The output so far fits in the terminal, and is written to the file.
+	pagingFits pagingState = iota
+	// The pager has been started, and the output is written to it.
+	pagingStarted
+	// The pager couldn't be started, and the output is written to the file.
+	pagingFailed
+	// The pager has exited, and the output is discarded.
+	pagingDone
+)
+
+func (pw *pagingWriter) write(s string) {
+	switch pw.state {
+	case pagingFits:
+		pw.lines += terminalLines(s, pw.width)
+		// Leave one line for the prompt.
+		if pw.lines < pw.height {
+			pw.file.WriteString(s)
+			pw.shown = append(pw.shown, s)
+			return
+		}
+		if !pw.startPager() {
+			pw.state = pagingFailed
+			pw.file.WriteString(s)
+			return
+		}
+		pw.state = pagingStarted
+		for _, shown := range pw.shown {
+			pw.writeToPager(shown)
+		}
+		pw.shown = nil
+		pw.writeToPager(s)
+	case pagingStarted:
+		pw.writeToPager(s)
+	case pagingFailed:
+		pw.file.WriteString(s)
+	}
+}
+
+func (pw *pagingWriter) writeToPager(s string) {
+	if pw.state != pagingStarted {
+		return
+	}
+	_, err := io.WriteString(pw.pipe, s)
+	if err != nil {
+		// The pager has probably exited; discard the rest of the output.
+		pw.state = pagingDone
+	}
+}
+
+// Waits for the pager to exit if it has been started.
+func (pw *pagingWriter) close() {
+	if pw.cmd == nil {
+		return
+	}
+	pw.pipe.Close()
+	pw.cmd.Wait()
+	reclaimTerminal(pw.file)
+}
+
+func (pw *pagingWriter) startPager() bool {
+	cmd := exec.Command(pw.pager[0], pw.pager[1:]...)
+	cmd.Stdout, cmd.Stderr = pw.file, os.Stderr
+	cmd.SysProcAttr = pagerSysProcAttr(pw.file)
+	pipe, err := cmd.StdinPipe()
+	if err != nil {
+		logger.Println("can't create pipe for pager:", err)
+		return false
+	}
+	err = cmd.Start()
+	if err != nil {
+		logger.Println("can't start pager:", err)
+		return false
+	}
+	pw.cmd, pw.pipe = cmd, pipe
+	return true
+}
+
+// Returns the number of terminal lines s takes when written to a terminal
+// with the given width.
+func terminalLines(s string, width int) int {
+	n := 0
+	for _, line := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		if w := wcwidth.Of(line); width > 0 && w > width {
+			n += (w + width - 1) / width
+		} else {
+			n++
+		}
+	}
+	return n
+}
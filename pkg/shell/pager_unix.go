@@ -0,0 +1,36 @@
+//go:build unix
+
+package shell
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"src.elv.sh/pkg/sys"
+	"src.elv.sh/pkg/sys/eunix"
+)
+
+// Starts the pager in its own process group in the foreground of the terminal
+// f, so that it can read from the terminal while the command is running.
+func pagerSysProcAttr(f *os.File) *syscall.SysProcAttr {
+	if !sys.IsATTY(f.Fd()) {
+		return nil
+	}
+	// Ctty is the terminal as a file descriptor of the pager; its stdin is a
+	// pipe, and its stdout is f.
+	return &syscall.SysProcAttr{Setpgid: true, Foreground: true, Ctty: 1}
+}
+
+// Puts Elvish back in the foreground of the terminal f after the pager has
+// exited.
+func reclaimTerminal(f *os.File) {
+	if !sys.IsATTY(f.Fd()) {
+		return
+	}
+	// Elvish is in the background now, so tcsetpgrp would stop it with
+	// SIGTTOU unless it is ignored.
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	eunix.Tcsetpgrp(int(f.Fd()), syscall.Getpgrp())
+}
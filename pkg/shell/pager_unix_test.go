@@ -0,0 +1,117 @@
+//go:build unix
+
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/testutil"
+	"src.elv.sh/pkg/tt"
+)
+
+var prefixLines = []string{
+	"/bin/sh", "-c", `while IFS= read -r l; do printf 'paged %s\n' "$l"; done`}
+
+func TestPagingWriter_WritesDirectlyIfOutputFits(t *testing.T) {
+	pw, name := newTestPagingWriter(t, prefixLines)
+
+	pw.write("a\n")
+	pw.write("b\n")
+	pw.close()
+
+	testOutput(t, name, "a\nb\n")
+}
+
+func TestPagingWriter_PipesAllOutputThroughPagerIfOutputDoesNotFit(t *testing.T) {
+	pw, name := newTestPagingWriter(t, prefixLines)
+
+	pw.write("a\n")
+	pw.write("b\n")
+	pw.write("c\n")
+	pw.write("d\n")
+	pw.close()
+
+	testOutput(t, name, "a\nb\npaged a\npaged b\npaged c\npaged d\n")
+}
+
+func TestPagingWriter_StreamsOutputToPagerBeforeClosed(t *testing.T) {
+	pw, name := newTestPagingWriter(t, prefixLines)
+
+	pw.write("a\n")
+	pw.write("b\n")
+	pw.write("c\n")
+	waitForOutput(t, name, "a\nb\npaged a\npaged b\npaged c\n")
+
+	pw.write("d\n")
+	waitForOutput(t, name, "a\nb\npaged a\npaged b\npaged c\npaged d\n")
+	pw.close()
+}
+
+func TestPagingWriter_WritesDirectlyIfPagerCannotStart(t *testing.T) {
+	pw, name := newTestPagingWriter(t, []string{"/bad/pager"})
+
+	pw.write("a\n")
+	pw.write("b\n")
+	pw.write("c\n")
+	pw.close()
+
+	testOutput(t, name, "a\nb\nc\n")
+}
+
+func TestPagingWriter_DiscardsOutputAfterPagerExits(t *testing.T) {
+	pw, name := newTestPagingWriter(t, []string{"/bin/sh", "-c", "exit 0"})
+
+	for i := 0; i < 100000; i++ {
+		pw.write("a\n")
+	}
+	pw.close()
+
+	testOutput(t, name, "a\na\n")
+}
+
+func TestTerminalLines(t *testing.T) {
+	tt.Test(t, terminalLines,
+		tt.Args("foo\n", 10).Rets(1),
+		tt.Args("foo\nbar\n", 10).Rets(2),
+		tt.Args("0123456789\n", 10).Rets(1),
+		tt.Args("0123456789a\n", 10).Rets(2),
+		tt.Args("你好你好你好\n", 10).Rets(2),
+		tt.Args("\n", 10).Rets(1),
+	)
+}
+
+// Returns a pagingWriter for a terminal with 3 lines, writing to a temporary
+// file, and the name of the file.
+func newTestPagingWriter(t *testing.T, pager []string) (*pagingWriter, string) {
+	name := filepath.Join(testutil.TempDir(t), "out")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return &pagingWriter{file: f, pager: pager, height: 3, width: 10}, name
+}
+
+// Like testOutput, but waits for the output to become want, for up to a
+// second.
+func waitForOutput(t *testing.T, name, want string) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); {
+		if must.ReadFileString(name) == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	testOutput(t, name, want)
+}
+
+func testOutput(t *testing.T, name, want string) {
+	t.Helper()
+	if got := must.ReadFileString(name); got != want {
+		t.Errorf("got output %q, want %q", got, want)
+	}
+}
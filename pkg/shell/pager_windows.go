@@ -0,0 +1,10 @@
+package shell
+
+import (
+	"os"
+	"syscall"
+)
+
+func pagerSysProcAttr(*os.File) *syscall.SysProcAttr { return nil }
+
+func reclaimTerminal(*os.File) {}
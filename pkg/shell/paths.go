@@ -89,3 +89,13 @@ func dbPath() (string, error) {
 		return "", fmt.Errorf("find db: %w", err)
 	}
 }
+
+func sessionDir() (string, error) {
+	if stateHome := os.Getenv(env.XDG_STATE_HOME); stateHome != "" {
+		return filepath.Join(stateHome, "elvish", "sessions"), nil
+	} else if stateHome, err := defaultStateHome(); err == nil {
+		return filepath.Join(stateHome, "elvish", "sessions"), nil
+	} else {
+		return "", fmt.Errorf("find session directory: %w", err)
+	}
+}
@@ -19,6 +19,7 @@ type scriptCfg struct {
 	Cmd         bool
 	CompileOnly bool
 	JSON        bool
+	Audit       *auditLog
 }
 
 // Executes a shell script.
@@ -62,7 +63,13 @@ func script(ev *eval.Evaler, fds [3]*os.File, args []string, cfg *scriptCfg) int
 			return 2
 		}
 	} else {
-		err := evalInTTY(fds, ev, nil, src)
+		auditCode := code
+		if !cfg.Cmd {
+			auditCode = scriptAuditCode(args)
+		}
+		err := cfg.Audit.run(src, auditCode, func() error {
+			return evalInTTY(fds, ev, nil, src, nil)
+		})
 		if err != nil {
 			diag.ShowError(fds[2], err)
 			return 2
@@ -8,6 +8,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"src.elv.sh/pkg/cli/term"
@@ -30,8 +31,17 @@ type Program struct {
 
 	codeInArg   bool
 	compileOnly bool
+	test        bool
+	cover       bool
+	coverReport string
+	format      bool
+	write       bool
+	highlight   bool
+	html        bool
 	noRC        bool
 	rc          string
+	control     string
+	auditLog    string
 	json        *bool
 	daemonPaths *prog.DaemonPaths
 }
@@ -45,10 +55,28 @@ func (p *Program) RegisterFlags(fs *prog.FlagSet) {
 		"Treat the first argument as code to execute")
 	fs.BoolVar(&p.compileOnly, "compileonly", false,
 		"Parse and compile Elvish code without executing it")
+	fs.BoolVar(&p.test, "test", false,
+		"Run test files in the given files and directories")
+	fs.BoolVar(&p.cover, "cover", false,
+		"Report the percentage of lines executed in each file when running -test")
+	fs.StringVar(&p.coverReport, "cover-report", "",
+		"Write annotated source showing lines executed by -test to a file; implies -cover")
+	fs.BoolVar(&p.format, "fmt", false,
+		"Format Elvish code in the given files, or stdin if no file is given")
+	fs.BoolVar(&p.write, "w", false,
+		"Write the result of -fmt to the source files instead of stdout")
+	fs.BoolVar(&p.highlight, "highlight", false,
+		"Highlight Elvish code in the given files, or stdin if no file is given")
+	fs.BoolVar(&p.html, "html", false,
+		"Write the result of -highlight as HTML instead of with escape sequences")
 	fs.BoolVar(&p.noRC, "norc", false,
 		"Don't read the RC file when running interactively")
 	fs.StringVar(&p.rc, "rc", "",
 		"Path to the RC file when running interactively")
+	fs.StringVar(&p.control, "control-socket", "",
+		"Path of a Unix socket to accept code to evaluate when running interactively")
+	fs.StringVar(&p.auditLog, "audit-log", "",
+		"Append a record of every command executed to a file, or to syslog if the value is \"syslog\"")
 
 	p.json = fs.JSON()
 	if p.ActivateDaemon != nil {
@@ -59,19 +87,44 @@ func (p *Program) RegisterFlags(fs *prog.FlagSet) {
 func (p *Program) Run(fds [3]*os.File, args []string) error {
 	cleanup1 := incSHLVL()
 	defer cleanup1()
-	cleanup2 := initSignal(fds)
+	// The Evaler is created later; the signal handler runs its pre-exit hooks
+	// when a signal makes Elvish exit. The signal handler gets nil before that.
+	var evForSignal atomic.Pointer[eval.Evaler]
+	cleanup2 := initSignal(fds, evForSignal.Load)
 	defer cleanup2()
 
 	// https://no-color.org
-	ui.NoColor = os.Getenv(env.NO_COLOR) != ""
+	ui.NoColor = os.Getenv(env.NO_COLOR) != "" || term.IsDumb()
+	if p.test {
+		return prog.Exit(runTests(p, fds, args))
+	}
+	if p.format {
+		return prog.Exit(runFormat(fds, args, p.write))
+	}
+	if p.highlight {
+		return prog.Exit(runHighlight(fds, args, p.html))
+	}
+	var audit *auditLog
+	if p.auditLog != "" {
+		var err error
+		audit, err = openAuditLog(p.auditLog, fds[2])
+		if err != nil {
+			fmt.Fprintln(fds[2], "cannot open audit log:", err)
+			return prog.Exit(2)
+		}
+		defer audit.close()
+	}
+
 	interactive := len(args) == 0
 	ev := p.makeEvaler(fds[2], interactive)
 	defer ev.PreExit()
+	evForSignal.Store(ev)
 
 	if !interactive {
 		exit := script(
 			ev, fds, args, &scriptCfg{
-				Cmd: p.codeInArg, CompileOnly: p.compileOnly, JSON: *p.json})
+				Cmd: p.codeInArg, CompileOnly: p.compileOnly, JSON: *p.json,
+				Audit: audit})
 		return prog.Exit(exit)
 	}
 
@@ -85,9 +138,25 @@ func (p *Program) Run(fds [3]*os.File, args []string) error {
 		}
 	}
 
+	if p.control != "" {
+		stop, err := serveControl(ev, p.control, audit)
+		if err != nil {
+			fmt.Fprintln(fds[2], "Warning: cannot serve control socket:", err)
+		} else {
+			defer stop()
+		}
+	}
+
+	sessionDir, err := sessionDir()
+	if err != nil {
+		fmt.Fprintln(fds[2], "Warning:", err)
+		fmt.Fprintln(fds[2], "Sessions will not be checkpointed.")
+	}
+
 	interact(ev, fds, &interactCfg{
 		RC:             ev.EffectiveRcPath,
-		ActivateDaemon: p.ActivateDaemon, SpawnConfig: spawnCfg})
+		ActivateDaemon: p.ActivateDaemon, SpawnConfig: spawnCfg,
+		Audit: audit, SessionDir: sessionDir})
 	return nil
 }
 
@@ -148,12 +217,12 @@ func incSHLVL() func() {
 	}
 }
 
-func initSignal(fds [3]*os.File) func() {
+func initSignal(fds [3]*os.File, getEvaler func() *eval.Evaler) func() {
 	sigCh := sys.NotifySignals()
 	go func() {
 		for sig := range sigCh {
 			logger.Println("signal", sig)
-			handleSignal(sig, fds[2])
+			handleSignal(sig, fds[2], getEvaler())
 		}
 	}()
 
@@ -163,15 +232,35 @@ func initSignal(fds [3]*os.File) func() {
 	}
 }
 
-func evalInTTY(fds [3]*os.File, ev *eval.Evaler, ed editor, src parse.Source) error {
+// Returns the code compiled by the editor if it is a compilingEditor and the
+// code is the same as src, or nil otherwise.
+func compiledBy(ed editor, src parse.Source) *eval.Compiled {
+	if ced, ok := ed.(compilingEditor); ok {
+		return ced.Compiled(src)
+	}
+	return nil
+}
+
+// Evaluates src with the standard files of Elvish. The value outputs are shown
+// in the pager if it is not empty and they don't fit in the terminal.
+func evalInTTY(fds [3]*os.File, ev *eval.Evaler, ed editor, src parse.Source, pager []string) error {
 	start := time.Now()
-	ports, cleanup := eval.PortsFromFiles(fds, ev.ValuePrefix())
+	ports, cleanup := portsWithPager(fds, ev.ValuePrefix(), pager)
 	defer cleanup()
 	restore := term.SetupForEval(fds[0], fds[1])
 	defer restore()
 	ctx, done := eval.ListenInterrupts()
-	err := ev.Eval(src, eval.EvalCfg{
-		Ports: ports, Interrupts: ctx, PutInFg: true})
+	cfg := eval.EvalCfg{Ports: ports, Interrupts: ctx, PutInFg: true}
+	if hed, ok := ed.(pipelineHookEditor); ok {
+		cfg.BeforePipeline = hed.RunBeforePipelineHooks
+		cfg.AfterPipeline = hed.RunAfterPipelineHooks
+	}
+	var err error
+	if compiled := compiledBy(ed, src); compiled != nil {
+		err = ev.EvalCompiled(compiled, cfg)
+	} else {
+		err = ev.Eval(src, cfg)
+	}
 	done()
 	if ed != nil {
 		ed.RunAfterCommandHooks(src, time.Since(start).Seconds(), err)
@@ -146,5 +146,6 @@ func setOrUnsetenv(t *testing.T, name string, unset bool, set string) {
 func setupCleanHomePaths(t testutil.Cleanuper) string {
 	testutil.Unsetenv(t, env.XDG_CONFIG_HOME)
 	testutil.Unsetenv(t, env.XDG_DATA_HOME)
+	testutil.Unsetenv(t, env.XDG_STATE_HOME)
 	return testutil.TempHome(t)
 }
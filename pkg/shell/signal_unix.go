@@ -8,13 +8,25 @@ import (
 	"os"
 	"syscall"
 
+	"src.elv.sh/pkg/eval"
 	"src.elv.sh/pkg/sys"
 )
 
-func handleSignal(sig os.Signal, stderr io.Writer) {
+// Handles a signal received by Elvish. The Evaler is nil if it has not been
+// created yet.
+func handleSignal(sig os.Signal, stderr io.Writer, ev *eval.Evaler) {
 	switch sig {
 	case syscall.SIGHUP:
 		syscall.Kill(0, syscall.SIGHUP)
+		if ev != nil {
+			// Background jobs run in their own process groups; hang them up
+			// too, except those that have been detached.
+			ev.HangUpBgJobs()
+			// Closing the terminal is a normal way to end a session, so clean
+			// up like exit does, including removing the checkpoint of the
+			// session.
+			ev.PreExit()
+		}
 		os.Exit(0)
 	case syscall.SIGUSR1:
 		fmt.Fprint(stderr, sys.DumpStack())
@@ -0,0 +1,178 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/mods/test"
+	"src.elv.sh/pkg/parse"
+)
+
+const testFileSuffix = "_test.elv"
+
+// Runs all the test files found in paths, and reports the results. Returns the
+// exit status.
+func runTests(p *Program, fds [3]*os.File, paths []string) int {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := findTestFiles(paths)
+	if err != nil {
+		fmt.Fprintln(fds[2], err)
+		return 2
+	}
+
+	var cov *eval.Coverage
+	if p.cover || p.coverReport != "" {
+		cov = eval.NewCoverage()
+	}
+	totalPassed, totalFailed := 0, 0
+	for _, file := range files {
+		passed, failed := runTestFile(p, fds, file, cov)
+		totalPassed += passed
+		totalFailed += failed
+	}
+	fmt.Fprintf(fds[1], "%d passed, %d failed\n", totalPassed, totalFailed)
+	if cov != nil {
+		err := reportCoverage(fds[1], cov, p.coverReport)
+		if err != nil {
+			fmt.Fprintln(fds[2], err)
+			return 2
+		}
+	}
+	if totalFailed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Writes the percentage of lines covered in each non-test file, and writes
+// annotated source to reportPath if it is not empty. In the annotated source,
+// each line is prefixed with "+" if it is covered, "-" if it is not, and a
+// space if it doesn't start any pipeline.
+func reportCoverage(w io.Writer, cov *eval.Coverage, reportPath string) error {
+	files := cov.Files(func(name string) bool {
+		return !strings.HasSuffix(name, testFileSuffix)
+	})
+	var report strings.Builder
+	for _, file := range files {
+		covered, total := file.Counts()
+		percent := 100.0
+		if total > 0 {
+			percent = float64(covered) * 100 / float64(total)
+		}
+		summary := fmt.Sprintf("%.1f%% (%d/%d lines)", percent, covered, total)
+		name := relPath(file.Name)
+		fmt.Fprintf(w, "cover\t%s\t%s\n", name, summary)
+
+		fmt.Fprintf(&report, "# %s: %s\n", name, summary)
+		for i, line := range strings.Split(file.Code, "\n") {
+			if i == len(file.Lines)-1 && line == "" {
+				break
+			}
+			switch file.Lines[i] {
+			case eval.Covered:
+				report.WriteString("+ ")
+			case eval.Uncovered:
+				report.WriteString("- ")
+			default:
+				report.WriteString("  ")
+			}
+			report.WriteString(line)
+			report.WriteByte('\n')
+		}
+	}
+	if reportPath == "" {
+		return nil
+	}
+	return os.WriteFile(reportPath, []byte(report.String()), 0o644)
+}
+
+// Returns path relative to the working directory if it is inside it, or path
+// itself otherwise.
+func relPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
+
+// Finds test files in paths. Directories are searched recursively for files
+// whose names end in _test.elv; other paths are used as is.
+func findTestFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(path, testFileSuffix) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// Runs a test file in a new Evaler, and returns the number of test cases that
+// have passed and failed. An exception thrown outside of test cases counts as a
+// failure. If cov is not nil, the pipelines executed are recorded in it.
+func runTestFile(p *Program, fds [3]*os.File, file string, cov *eval.Coverage) (passed, failed int) {
+	ev := p.makeEvaler(fds[2], false)
+	ev.Coverage = cov
+	results := &test.Results{}
+	ev.AddModule("test", test.RunnerNs(results))
+
+	name, err := filepath.Abs(file)
+	if err != nil {
+		fmt.Fprintf(fds[2], "cannot get full path of test file %q: %v\n", file, err)
+		return 0, 1
+	}
+	code, err := readFileUTF8(name)
+	if err != nil {
+		fmt.Fprintf(fds[2], "cannot read test file %q: %v\n", name, err)
+		return 0, 1
+	}
+
+	ports, cleanup := eval.PortsFromFiles(fds, ev.ValuePrefix())
+	err = ev.Eval(parse.Source{Name: name, Code: code, IsFile: true},
+		eval.EvalCfg{Ports: ports})
+	cleanup()
+	ev.PreExit()
+
+	passed, failed = results.Passed(), len(results.Failed())
+	if err != nil {
+		fmt.Fprintf(fds[2], "FAIL: %s\n", parse.Quote(file))
+		diag.ShowError(fds[2], err)
+		failed++
+	}
+	status := "ok"
+	if failed > 0 {
+		status = "FAIL"
+	}
+	fmt.Fprintf(fds[1], "%s\t%s\t%d passed, %d failed\n", status, file, passed, failed)
+	return passed, failed
+}
@@ -0,0 +1,94 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "src.elv.sh/pkg/prog/progtest"
+	"src.elv.sh/pkg/testutil"
+)
+
+func TestRunTests(t *testing.T) {
+	setupCleanHomePaths(t)
+	testutil.InTempDir(t)
+	testutil.ApplyDir(testutil.Dir{
+		"pass_test.elv": "use test; test:case a { }; test:case b { }",
+		"sub": testutil.Dir{
+			"fail_test.elv": "use test; test:case a { }; test:case b { fail bad }",
+			"helper.elv":    "fail 'should not be run'",
+		},
+		"throw_test.elv": "fail outside",
+	})
+
+	Test(t, &Program{},
+		ThatElvish("-test", "pass_test.elv").
+			WritesStdout("ok\tpass_test.elv\t2 passed, 0 failed\n2 passed, 0 failed\n"),
+		ThatElvish("-test", "sub").
+			ExitsWith(1).
+			WritesStdout("FAIL\t"+filepath.Join("sub", "fail_test.elv")+
+				"\t1 passed, 1 failed\n1 passed, 1 failed\n").
+			WritesStderrContaining("fail_test.elv:1:42-50"),
+		ThatElvish("-test", "throw_test.elv").
+			ExitsWith(1).
+			WritesStdout("FAIL\tthrow_test.elv\t0 passed, 1 failed\n0 passed, 1 failed\n").
+			WritesStderrContaining("throw_test.elv:1:1-12"),
+		ThatElvish("-test").
+			ExitsWith(1).
+			WritesStdoutContaining("3 passed, 2 failed\n").
+			WritesStderrContaining("throw_test.elv:1:1-12"),
+		ThatElvish("-test", "non-existent").
+			ExitsWith(2).
+			WritesStderrContaining("non-existent"),
+	)
+}
+
+func TestRunTests_MockExternal(t *testing.T) {
+	setupCleanHomePaths(t)
+	testutil.InTempDir(t)
+	testutil.ApplyDir(testutil.Dir{
+		"mock_test.elv": "use test\n" +
+			"test:case a { test:mock-external elvish-test-cmd { }; elvish-test-cmd }\n" +
+			"test:case b { var _ = (test:expect-throw { elvish-test-cmd }) }\n",
+	})
+
+	Test(t, &Program{},
+		ThatElvish("-test", "mock_test.elv").
+			WritesStdout("ok\tmock_test.elv\t2 passed, 0 failed\n2 passed, 0 failed\n"),
+	)
+}
+
+func TestRunTests_Cover(t *testing.T) {
+	setupCleanHomePaths(t)
+	testutil.InTempDir(t)
+	testutil.ApplyDir(testutil.Dir{
+		"lib.elv": "fn f {|x|\n  if $x {\n    put yes\n  } else {\n    put no\n  }\n}\n",
+		"lib_test.elv": "use test; use ./lib\n" +
+			"test:case yes { test:assert (eq (lib:f $true) yes) }\n",
+	})
+
+	Test(t, &Program{},
+		ThatElvish("-test", "-cover").
+			WritesStdout("ok\tlib_test.elv\t1 passed, 0 failed\n"+
+				"1 passed, 0 failed\n"+
+				"cover\tlib.elv\t75.0% (3/4 lines)\n"),
+		ThatElvish("-test", "-cover-report", "report").
+			WritesStdoutContaining("cover\tlib.elv\t75.0% (3/4 lines)\n"),
+	)
+
+	wantReport := "# lib.elv: 75.0% (3/4 lines)\n" +
+		"+ fn f {|x|\n" +
+		"+   if $x {\n" +
+		"+     put yes\n" +
+		"    } else {\n" +
+		"-     put no\n" +
+		"    }\n" +
+		"  }\n"
+	report, err := os.ReadFile("report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(report) != wantReport {
+		t.Errorf("got report:\n%s\nwant:\n%s", report, wantReport)
+	}
+}
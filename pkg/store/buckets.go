@@ -2,7 +2,20 @@ package store
 
 const (
 	bucketCmd = "cmd"
-	bucketDir = "dir"
+	// Times when commands were added, the set of pinned commands, and the
+	// durations and statuses of commands. All are keyed by the sequence numbers
+	// of commands.
+	bucketCmdTime   = "cmdtime"
+	bucketCmdPinned = "cmdpinned"
+	bucketCmdResult = "cmdresult"
+	// Commands deleted with DelCmd, keyed by their times and texts, so that
+	// merging a snapshot doesn't bring them back.
+	bucketCmdDeleted = "cmddeleted"
+	bucketDir        = "dir"
+	// Hashes of trusted files, keyed by their paths.
+	bucketTrusted = "trusted"
+	// Data of scripts, with one nested bucket for each namespace.
+	bucketData = "data"
 )
 
 // The following buckets were used before and are thus reserved:
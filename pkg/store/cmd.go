@@ -3,6 +3,7 @@ package store
 import (
 	"bytes"
 	"encoding/binary"
+	"time"
 
 	bolt "go.etcd.io/bbolt"
 	. "src.elv.sh/pkg/store/storedefs"
@@ -13,8 +14,27 @@ func init() {
 		_, err := tx.CreateBucketIfNotExists([]byte(bucketCmd))
 		return err
 	}
+	initDB["initialize command time table"] = func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketCmdTime))
+		return err
+	}
+	initDB["initialize pinned command table"] = func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketCmdPinned))
+		return err
+	}
+	initDB["initialize command result table"] = func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketCmdResult))
+		return err
+	}
+	initDB["initialize deleted command table"] = func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketCmdDeleted))
+		return err
+	}
 }
 
+// Can be changed for tests.
+var timeNow = time.Now
+
 // NextCmdSeq returns the next sequence number of the command history.
 func (s *dbStore) NextCmdSeq() (int, error) {
 	var seq uint64
@@ -38,17 +58,157 @@ func (s *dbStore) AddCmd(cmd string) (int, error) {
 		if err != nil {
 			return err
 		}
-		return b.Put(marshalSeq(seq), []byte(cmd))
+		err = b.Put(marshalSeq(seq), []byte(cmd))
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(bucketCmdTime)).Put(
+			marshalSeq(seq), marshalTime(timeNow()))
 	})
 	return int(seq), err
 }
 
-// DelCmd deletes a command history item with the given sequence number.
+// DelCmd deletes a command history item with the given sequence number. The
+// deletion is recorded, so that it is also applied to stores that merge
+// snapshots of this store.
 func (s *dbStore) DelCmd(seq int) error {
 	return s.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(bucketCmd))
-		return b.Delete(marshalSeq(uint64(seq)))
+		key := marshalSeq(uint64(seq))
+		text := tx.Bucket([]byte(bucketCmd)).Get(key)
+		if text == nil {
+			return nil
+		}
+		var t time.Time
+		if tb := tx.Bucket([]byte(bucketCmdTime)).Get(key); tb != nil {
+			t = unmarshalTime(tb)
+		}
+		err := tx.Bucket([]byte(bucketCmdDeleted)).Put(marshalDeletedCmd(string(text), t), []byte{})
+		if err != nil {
+			return err
+		}
+		return delCmd(tx, key)
+	})
+}
+
+func delCmd(tx *bolt.Tx, key []byte) error {
+	for _, name := range []string{bucketCmd, bucketCmdTime, bucketCmdPinned, bucketCmdResult} {
+		if err := tx.Bucket([]byte(name)).Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetCmdPinned pins or unpins the command history item with the given sequence
+// number. Pinned items are never deleted by CompactCmds.
+func (s *dbStore) SetCmdPinned(seq int, pinned bool) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		key := marshalSeq(uint64(seq))
+		if tx.Bucket([]byte(bucketCmd)).Get(key) == nil {
+			return ErrNoMatchingCmd
+		}
+		b := tx.Bucket([]byte(bucketCmdPinned))
+		if pinned {
+			return b.Put(key, []byte{})
+		}
+		return b.Delete(key)
+	})
+}
+
+// PinnedCmds returns the sequence numbers of all pinned command history items.
+func (s *dbStore) PinnedCmds() ([]int, error) {
+	var seqs []int
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucketCmdPinned)).ForEach(func(k, _ []byte) error {
+			seqs = append(seqs, int(unmarshalSeq(k)))
+			return nil
+		})
+	})
+	return seqs, err
+}
+
+// CompactCmds deletes command history items that are not pinned, so that at
+// most maxEntries such items are left and none of them is older than maxAge. A
+// maxEntries or maxAge of 0 means no limit. Items whose time was not recorded,
+// because they were added by an older version of Elvish, are never considered
+// too old.
+//
+// It returns the number of deleted items.
+func (s *dbStore) CompactCmds(maxEntries int, maxAge time.Duration) (int, error) {
+	var deleted int
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		pinned := tx.Bucket([]byte(bucketCmdPinned))
+		times := tx.Bucket([]byte(bucketCmdTime))
+		// Keys of unpinned items, from the oldest to the newest. The keys are
+		// copied since they are only valid until the bucket is modified.
+		var keys [][]byte
+		c := tx.Bucket([]byte(bucketCmd)).Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			if pinned.Get(k) == nil {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+		}
+
+		var toDelete [][]byte
+		if maxEntries > 0 && len(keys) > maxEntries {
+			toDelete = keys[:len(keys)-maxEntries]
+			keys = keys[len(keys)-maxEntries:]
+		}
+		if maxAge > 0 {
+			now := timeNow()
+			for _, k := range keys {
+				if t := times.Get(k); t != nil && now.Sub(unmarshalTime(t)) > maxAge {
+					toDelete = append(toDelete, k)
+				}
+			}
+		}
+
+		for _, k := range toDelete {
+			if err := delCmd(tx, k); err != nil {
+				return err
+			}
+		}
+		deleted = len(toDelete)
+		return nil
+	})
+	return deleted, err
+}
+
+// SetCmdResult records the duration of running the command history item with
+// the given sequence number, and whether it failed.
+func (s *dbStore) SetCmdResult(seq int, duration time.Duration, failed bool) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		key := marshalSeq(uint64(seq))
+		if tx.Bucket([]byte(bucketCmd)).Get(key) == nil {
+			return ErrNoMatchingCmd
+		}
+		return tx.Bucket([]byte(bucketCmdResult)).Put(
+			key, marshalResult(duration, failed))
+	})
+}
+
+// CmdInfos returns all commands within the specified range, along with their
+// times and results.
+func (s *dbStore) CmdInfos(from, upto int) ([]CmdInfo, error) {
+	var infos []CmdInfo
+	err := s.db.View(func(tx *bolt.Tx) error {
+		times := tx.Bucket([]byte(bucketCmdTime))
+		results := tx.Bucket([]byte(bucketCmdResult))
+		c := tx.Bucket([]byte(bucketCmd)).Cursor()
+		for k, v := c.Seek(marshalSeq(uint64(from))); k != nil && unmarshalSeq(k) < uint64(upto); k, v = c.Next() {
+			info := CmdInfo{Text: string(v), Seq: int(unmarshalSeq(k))}
+			if t := times.Get(k); t != nil {
+				info.Time = unmarshalTime(t)
+			}
+			if r := results.Get(k); r != nil {
+				info.HasResult = true
+				info.Duration, info.Failed = unmarshalResult(r)
+			}
+			infos = append(infos, info)
+		}
+		return nil
 	})
+	return infos, err
 }
 
 // Cmd queries the command history item with the specified sequence number.
@@ -147,3 +307,45 @@ func marshalSeq(seq uint64) []byte {
 func unmarshalSeq(key []byte) uint64 {
 	return binary.BigEndian.Uint64(key)
 }
+
+func marshalTime(t time.Time) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(t.UnixNano()))
+	return b
+}
+
+func unmarshalTime(b []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(b)))
+}
+
+func marshalResult(duration time.Duration, failed bool) []byte {
+	b := make([]byte, 9)
+	binary.BigEndian.PutUint64(b, uint64(duration))
+	if failed {
+		b[8] = 1
+	}
+	return b
+}
+
+func unmarshalResult(b []byte) (time.Duration, bool) {
+	return time.Duration(binary.BigEndian.Uint64(b)), b[8] != 0
+}
+
+// Marshals the key of a deleted command. A zero time is marshaled as 0.
+func marshalDeletedCmd(text string, t time.Time) []byte {
+	var nano int64
+	if !t.IsZero() {
+		nano = t.UnixNano()
+	}
+	b := make([]byte, 8, 8+len(text))
+	binary.BigEndian.PutUint64(b, uint64(nano))
+	return append(b, text...)
+}
+
+func unmarshalDeletedCmd(b []byte) (string, time.Time) {
+	var t time.Time
+	if nano := int64(binary.BigEndian.Uint64(b)); nano != 0 {
+		t = time.Unix(0, nano)
+	}
+	return string(b[8:]), t
+}
@@ -1,12 +1,47 @@
 package store_test
 
 import (
+	"reflect"
 	"testing"
+	"time"
 
 	"src.elv.sh/pkg/store"
+	"src.elv.sh/pkg/store/storedefs"
 	"src.elv.sh/pkg/store/storetest"
+	"src.elv.sh/pkg/testutil"
 )
 
 func TestCmd(t *testing.T) {
 	storetest.TestCmd(t, store.MustTempStore(t))
 }
+
+func TestCmdCompact(t *testing.T) {
+	storetest.TestCmdCompact(t, store.MustTempStore(t))
+}
+
+func TestCmdResult(t *testing.T) {
+	storetest.TestCmdResult(t, store.MustTempStore(t))
+}
+
+func TestCompactCmds_MaxAge(t *testing.T) {
+	s := store.MustTempStore(t)
+	now := time.Unix(1_000_000, 0)
+	testutil.Set(t, store.TimeNow, func() time.Time { return now })
+
+	s.AddCmd("old")
+	s.AddCmd("old pinned")
+	s.SetCmdPinned(2, true)
+	now = now.Add(time.Hour)
+	s.AddCmd("new")
+	now = now.Add(time.Minute)
+
+	deleted, err := s.CompactCmds(0, 30*time.Minute)
+	if deleted != 1 || err != nil {
+		t.Errorf("CompactCmds -> (%v, %v), want (1, nil)", deleted, err)
+	}
+	cmds, _ := s.CmdsWithSeq(0, -1)
+	wantCmds := []storedefs.Cmd{{Text: "old pinned", Seq: 2}, {Text: "new", Seq: 3}}
+	if !reflect.DeepEqual(cmds, wantCmds) {
+		t.Errorf("got commands %v, want %v", cmds, wantCmds)
+	}
+}
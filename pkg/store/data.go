@@ -0,0 +1,158 @@
+package store
+
+import (
+	bolt "go.etcd.io/bbolt"
+	. "src.elv.sh/pkg/store/storedefs"
+)
+
+func init() {
+	initDB["initialize script data table"] = func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketData))
+		return err
+	}
+}
+
+// Data returns the value of the given key in the given data namespace.
+func (s *dbStore) Data(ns, key string) (string, error) {
+	if ns == "" || key == "" {
+		return "", ErrEmptyDataKey
+	}
+	var value string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := getData(tx, ns, key)
+		if v == nil {
+			return ErrNoMatchingData
+		}
+		value = string(v)
+		return nil
+	})
+	return value, err
+}
+
+// DataKeys returns all the keys in the given data namespace, in lexicographical
+// order.
+func (s *dbStore) DataKeys(ns string) ([]string, error) {
+	var keys []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketData)).Bucket([]byte(ns))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, _ []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	})
+	return keys, err
+}
+
+// SetData sets the value of the given key in the given data namespace, creating
+// the namespace if it doesn't exist.
+func (s *dbStore) SetData(ns, key, value string) error {
+	if ns == "" || key == "" {
+		return ErrEmptyDataKey
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putData(tx, ns, key, value)
+	})
+}
+
+// DelData deletes the given key from the given data namespace. It is not an
+// error if the key doesn't exist. The namespace is deleted when its last key
+// is deleted.
+func (s *dbStore) DelData(ns, key string) error {
+	if ns == "" || key == "" {
+		return ErrEmptyDataKey
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return delData(tx, ns, key)
+	})
+}
+
+// CompareAndSetData sets the value of the given key in the given data
+// namespace to new if its current value is old, and reports whether it did so.
+// An empty old value matches a key that doesn't exist, and an empty new value
+// deletes the key.
+func (s *dbStore) CompareAndSetData(ns, key, old, new string) (bool, error) {
+	if ns == "" || key == "" {
+		return false, ErrEmptyDataKey
+	}
+	var swapped bool
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if string(getData(tx, ns, key)) != old {
+			return nil
+		}
+		swapped = true
+		if new == "" {
+			return delData(tx, ns, key)
+		}
+		return putData(tx, ns, key, new)
+	})
+	return swapped, err
+}
+
+// ChangeData applies all the changes if the values of the keys are all as
+// expected, and reports whether it did so. The check and the changes are done
+// atomically.
+func (s *dbStore) ChangeData(expected, changes []DataEntry) (bool, error) {
+	for _, entries := range [][]DataEntry{expected, changes} {
+		for _, e := range entries {
+			if e.NS == "" || e.Key == "" {
+				return false, ErrEmptyDataKey
+			}
+		}
+	}
+	var changed bool
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, e := range expected {
+			if string(getData(tx, e.NS, e.Key)) != e.Value {
+				return nil
+			}
+		}
+		changed = true
+		for _, e := range changes {
+			var err error
+			if e.Value == "" {
+				err = delData(tx, e.NS, e.Key)
+			} else {
+				err = putData(tx, e.NS, e.Key, e.Value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return changed, err
+}
+
+func getData(tx *bolt.Tx, ns, key string) []byte {
+	b := tx.Bucket([]byte(bucketData)).Bucket([]byte(ns))
+	if b == nil {
+		return nil
+	}
+	return b.Get([]byte(key))
+}
+
+func putData(tx *bolt.Tx, ns, key, value string) error {
+	b, err := tx.Bucket([]byte(bucketData)).CreateBucketIfNotExists([]byte(ns))
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(key), []byte(value))
+}
+
+func delData(tx *bolt.Tx, ns, key string) error {
+	data := tx.Bucket([]byte(bucketData))
+	b := data.Bucket([]byte(ns))
+	if b == nil {
+		return nil
+	}
+	if err := b.Delete([]byte(key)); err != nil {
+		return err
+	}
+	if k, _ := b.Cursor().First(); k == nil {
+		return data.DeleteBucket([]byte(ns))
+	}
+	return nil
+}
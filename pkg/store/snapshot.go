@@ -0,0 +1,194 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	. "src.elv.sh/pkg/store/storedefs"
+)
+
+// Snapshot returns a copy of the command and directory history, including the
+// commands that have been deleted.
+func (s *dbStore) Snapshot() (Snapshot, error) {
+	cmds, err := s.CmdInfos(0, -1)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	dirs, err := s.Dirs(NoBlacklist)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var deleted []CmdInfo
+	err = s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucketCmdDeleted)).ForEach(func(k, _ []byte) error {
+			text, t := unmarshalDeletedCmd(k)
+			deleted = append(deleted, CmdInfo{Text: text, Time: t})
+			return nil
+		})
+	})
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return Snapshot{Cmds: cmds, Dirs: dirs, DeletedCmds: deleted}, nil
+}
+
+// A command in the history, along with everything stored about it.
+type cmdEntry struct {
+	CmdInfo
+	pinned bool
+	// Time used to order the command; the same as Time, or the time of the
+	// command before it if Time is zero.
+	orderTime time.Time
+}
+
+// MergeSnapshot merges a snapshot, usually taken from the store of another
+// machine, into the store.
+//
+// Commands deleted in either the store or the snapshot are deleted. Other
+// commands in the snapshot are added unless there is already a command with the
+// same text and time, and are ordered by time among the existing commands; this
+// can change the sequence numbers of existing commands newer than the oldest
+// added command. A directory gets the higher of its scores in the store and the
+// snapshot. This makes merging idempotent and commutative, so stores that merge
+// the snapshots of each other end up with the same commands and directories
+// regardless of the order of merging.
+func (s *dbStore) MergeSnapshot(snapshot Snapshot) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		cmds := tx.Bucket([]byte(bucketCmd))
+		times := tx.Bucket([]byte(bucketCmdTime))
+		pinned := tx.Bucket([]byte(bucketCmdPinned))
+		results := tx.Bucket([]byte(bucketCmdResult))
+		deleted := tx.Bucket([]byte(bucketCmdDeleted))
+
+		for _, cmd := range snapshot.DeletedCmds {
+			if err := deleted.Put(marshalDeletedCmd(cmd.Text, cmd.Time), []byte{}); err != nil {
+				return err
+			}
+		}
+		isDeleted := func(cmd CmdInfo) bool {
+			return deleted.Get(marshalDeletedCmd(cmd.Text, cmd.Time)) != nil
+		}
+
+		type cmdKey struct {
+			text string
+			time int64
+		}
+		keyOf := func(cmd CmdInfo) cmdKey {
+			var t int64
+			if !cmd.Time.IsZero() {
+				t = cmd.Time.UnixNano()
+			}
+			return cmdKey{cmd.Text, t}
+		}
+
+		// Existing commands, from the oldest to the newest.
+		var existing []cmdEntry
+		var toDelete [][]byte
+		seen := make(map[cmdKey]bool)
+		var lastTime time.Time
+		c := cmds.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			e := cmdEntry{CmdInfo: CmdInfo{Text: string(v), Seq: int(unmarshalSeq(k))}}
+			if tb := times.Get(k); tb != nil {
+				e.Time = unmarshalTime(tb)
+				lastTime = e.Time
+			}
+			if isDeleted(e.CmdInfo) {
+				toDelete = append(toDelete, append([]byte(nil), k...))
+				continue
+			}
+			e.orderTime = lastTime
+			e.pinned = pinned.Get(k) != nil
+			if r := results.Get(k); r != nil {
+				e.HasResult = true
+				e.Duration, e.Failed = unmarshalResult(r)
+			}
+			seen[keyOf(e.CmdInfo)] = true
+			existing = append(existing, e)
+		}
+		for _, k := range toDelete {
+			if err := delCmd(tx, k); err != nil {
+				return err
+			}
+		}
+
+		// Commands to add, ordered by time. Commands whose time was not recorded
+		// are added after all the others.
+		var added []cmdEntry
+		for _, cmd := range snapshot.Cmds {
+			key := keyOf(cmd)
+			if seen[key] || isDeleted(cmd) {
+				continue
+			}
+			seen[key] = true
+			cmd.Seq = 0
+			added = append(added, cmdEntry{CmdInfo: cmd, orderTime: cmd.Time})
+		}
+		sort.SliceStable(added, func(i, j int) bool {
+			ti, tj := added[i].orderTime, added[j].orderTime
+			return !ti.IsZero() && (tj.IsZero() || ti.Before(tj))
+		})
+
+		// Existing commands newer than the oldest added command are re-added
+		// together with the added commands, so that they stay ordered by time.
+		if len(added) > 0 && !added[0].orderTime.IsZero() {
+			first := sort.Search(len(existing), func(i int) bool {
+				return existing[i].orderTime.After(added[0].orderTime)
+			})
+			readded := existing[first:]
+			for _, e := range readded {
+				if err := delCmd(tx, marshalSeq(uint64(e.Seq))); err != nil {
+					return err
+				}
+			}
+			merged := make([]cmdEntry, 0, len(readded)+len(added))
+			for len(readded) > 0 && len(added) > 0 {
+				if added[0].orderTime.IsZero() || !added[0].orderTime.Before(readded[0].orderTime) {
+					merged, readded = append(merged, readded[0]), readded[1:]
+				} else {
+					merged, added = append(merged, added[0]), added[1:]
+				}
+			}
+			added = append(append(merged, readded...), added...)
+		}
+
+		for _, e := range added {
+			seq, err := cmds.NextSequence()
+			if err != nil {
+				return err
+			}
+			k := marshalSeq(seq)
+			if err := cmds.Put(k, []byte(e.Text)); err != nil {
+				return err
+			}
+			if !e.Time.IsZero() {
+				if err := times.Put(k, marshalTime(e.Time)); err != nil {
+					return err
+				}
+			}
+			if e.pinned {
+				if err := pinned.Put(k, []byte{}); err != nil {
+					return err
+				}
+			}
+			if e.HasResult {
+				if err := results.Put(k, marshalResult(e.Duration, e.Failed)); err != nil {
+					return err
+				}
+			}
+		}
+
+		dirs := tx.Bucket([]byte(bucketDir))
+		for _, dir := range snapshot.Dirs {
+			k := []byte(dir.Path)
+			if v := dirs.Get(k); v != nil && unmarshalScore(v) >= dir.Score {
+				continue
+			}
+			if err := dirs.Put(k, marshalScore(dir.Score)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
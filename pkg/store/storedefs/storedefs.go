@@ -4,7 +4,12 @@
 // does not need to depend on the concrete implementation.
 package storedefs
 
-import "errors"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+)
 
 // NoBlacklist is an empty blacklist, to be used in GetDirs.
 var NoBlacklist = map[string]struct{}{}
@@ -13,6 +18,14 @@ var NoBlacklist = map[string]struct{}{}
 // completes with no result.
 var ErrNoMatchingCmd = errors.New("no matching command line")
 
+// ErrNoMatchingData is the error returned when querying a key that doesn't
+// exist in a data namespace.
+var ErrNoMatchingData = errors.New("no matching data")
+
+// ErrEmptyDataKey is the error returned when the namespace or key of data is
+// empty.
+var ErrEmptyDataKey = errors.New("namespace and key of data must not be empty")
+
 // Store is an interface satisfied by the storage service.
 type Store interface {
 	NextCmdSeq() (int, error)
@@ -22,10 +35,35 @@ type Store interface {
 	CmdsWithSeq(from, upto int) ([]Cmd, error)
 	NextCmd(from int, prefix string) (Cmd, error)
 	PrevCmd(upto int, prefix string) (Cmd, error)
+	SetCmdPinned(seq int, pinned bool) error
+	PinnedCmds() ([]int, error)
+	CompactCmds(maxEntries int, maxAge time.Duration) (int, error)
+	SetCmdResult(seq int, duration time.Duration, failed bool) error
+	CmdInfos(from, upto int) ([]CmdInfo, error)
 
 	AddDir(dir string, incFactor float64) error
 	DelDir(dir string) error
 	Dirs(blacklist map[string]struct{}) ([]Dir, error)
+
+	Snapshot() (Snapshot, error)
+	MergeSnapshot(snapshot Snapshot) error
+
+	Data(ns, key string) (string, error)
+	DataKeys(ns string) ([]string, error)
+	SetData(ns, key, value string) error
+	DelData(ns, key string) error
+	CompareAndSetData(ns, key, old, new string) (bool, error)
+	ChangeData(expected, changes []DataEntry) (bool, error)
+
+	SetTrustedHash(path, hash string) error
+	TrustedHash(path string) (string, error)
+}
+
+// HashContent returns the hash of the content of a file, in the form used by
+// SetTrustedHash and TrustedHash.
+func HashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
 }
 
 // Dir is an entry in the directory history.
@@ -43,3 +81,33 @@ type Cmd struct {
 }
 
 func (Cmd) IsStructMap() {}
+
+// CmdInfo is an entry in the command history, along with when it was added and
+// the result of running it. Time is the zero value if it was not recorded, and
+// so are Duration and Failed if HasResult is false.
+type CmdInfo struct {
+	Text      string
+	Seq       int
+	Time      time.Time
+	HasResult bool
+	Duration  time.Duration
+	Failed    bool
+}
+
+// Snapshot is a copy of the command and directory history, used to share them
+// across machines. The Seq fields of the commands are not used when merging a
+// snapshot.
+type Snapshot struct {
+	Cmds []CmdInfo
+	Dirs []Dir
+	// Commands that have been deleted, identified by their Text and Time.
+	DeletedCmds []CmdInfo `json:",omitempty"`
+}
+
+// DataEntry is the value of a key in a data namespace. An empty Value means
+// that the key doesn't exist.
+type DataEntry struct {
+	NS    string
+	Key   string
+	Value string
+}
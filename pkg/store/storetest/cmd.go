@@ -3,6 +3,7 @@ package storetest
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"src.elv.sh/pkg/store/storedefs"
 )
@@ -109,3 +110,87 @@ func TestCmd(t *testing.T, store storedefs.Store) {
 func equalCmds(a, b []storedefs.Cmd) bool {
 	return (len(a) == 0 && len(b) == 0) || reflect.DeepEqual(a, b)
 }
+
+// TestCmdCompact tests pinning and compacting the command history of a Store.
+func TestCmdCompact(t *testing.T, store storedefs.Store) {
+	startSeq, _ := store.NextCmdSeq()
+	for _, cmd := range []string{"a", "b", "c", "d", "e", "f"} {
+		store.AddCmd(cmd)
+	}
+	for _, i := range []int{1, 3} {
+		if err := store.SetCmdPinned(startSeq+i, true); err != nil {
+			t.Errorf("store.SetCmdPinned(%v, true) -> %v, want nil", startSeq+i, err)
+		}
+	}
+	store.SetCmdPinned(startSeq+5, true)
+	store.SetCmdPinned(startSeq+5, false)
+	if err := store.SetCmdPinned(startSeq+6, true); !matchErr(err, storedefs.ErrNoMatchingCmd) {
+		t.Errorf("store.SetCmdPinned(%v, true) -> %v, want %v",
+			startSeq+6, err, storedefs.ErrNoMatchingCmd)
+	}
+
+	wantPinned := []int{startSeq + 1, startSeq + 3}
+	if pinned, err := store.PinnedCmds(); !reflect.DeepEqual(pinned, wantPinned) || err != nil {
+		t.Errorf("store.PinnedCmds() -> (%v, %v), want (%v, nil)", pinned, err, wantPinned)
+	}
+
+	before, _ := store.CmdsWithSeq(0, -1)
+	deleted, err := store.CompactCmds(2, 0)
+	after, _ := store.CmdsWithSeq(0, -1)
+	wantAfter := []storedefs.Cmd{
+		{Text: "b", Seq: startSeq + 1}, {Text: "d", Seq: startSeq + 3},
+		{Text: "e", Seq: startSeq + 4}, {Text: "f", Seq: startSeq + 5}}
+	if !equalCmds(after, wantAfter) {
+		t.Errorf("after store.CompactCmds(2, 0), commands are %v, want %v", after, wantAfter)
+	}
+	if wantDeleted := len(before) - len(wantAfter); deleted != wantDeleted || err != nil {
+		t.Errorf("store.CompactCmds(2, 0) -> (%v, %v), want (%v, nil)", deleted, err, wantDeleted)
+	}
+
+	// Deleting a command also unpins it.
+	store.DelCmd(startSeq + 1)
+	wantPinned = []int{startSeq + 3}
+	if pinned, err := store.PinnedCmds(); !reflect.DeepEqual(pinned, wantPinned) || err != nil {
+		t.Errorf("store.PinnedCmds() -> (%v, %v), want (%v, nil)", pinned, err, wantPinned)
+	}
+}
+
+// TestCmdResult tests recording and querying the results of commands in a
+// Store.
+func TestCmdResult(t *testing.T, store storedefs.Store) {
+	startSeq, _ := store.NextCmdSeq()
+	for _, cmd := range []string{"a", "b", "c"} {
+		store.AddCmd(cmd)
+	}
+	if err := store.SetCmdResult(startSeq, time.Second, false); err != nil {
+		t.Errorf("store.SetCmdResult(%v, ...) -> %v, want nil", startSeq, err)
+	}
+	if err := store.SetCmdResult(startSeq+2, time.Minute, true); err != nil {
+		t.Errorf("store.SetCmdResult(%v, ...) -> %v, want nil", startSeq+2, err)
+	}
+	if err := store.SetCmdResult(startSeq+3, 0, false); !matchErr(err, storedefs.ErrNoMatchingCmd) {
+		t.Errorf("store.SetCmdResult(%v, ...) -> %v, want %v",
+			startSeq+3, err, storedefs.ErrNoMatchingCmd)
+	}
+
+	infos, err := store.CmdInfos(startSeq, startSeq+3)
+	if err != nil || len(infos) != 3 {
+		t.Fatalf("store.CmdInfos(...) -> (%v, %v), want 3 infos and nil", infos, err)
+	}
+	for i, info := range infos {
+		// Only check that the times were recorded, since the current time can't
+		// be faked from this package.
+		if info.Time.IsZero() {
+			t.Errorf("infos[%v].Time is zero", i)
+		}
+		infos[i].Time = time.Time{}
+	}
+	wantInfos := []storedefs.CmdInfo{
+		{Text: "a", Seq: startSeq, HasResult: true, Duration: time.Second},
+		{Text: "b", Seq: startSeq + 1},
+		{Text: "c", Seq: startSeq + 2, HasResult: true, Duration: time.Minute, Failed: true},
+	}
+	if !reflect.DeepEqual(infos, wantInfos) {
+		t.Errorf("store.CmdInfos(...) -> %v, want %v", infos, wantInfos)
+	}
+}
@@ -0,0 +1,93 @@
+package storetest
+
+import (
+	"reflect"
+	"testing"
+
+	"src.elv.sh/pkg/store/storedefs"
+)
+
+// TestData tests the script data functionality of a Store.
+func TestData(t *testing.T, store storedefs.Store) {
+	value, err := store.Data("ns", "k")
+	if value != "" || !matchErr(err, storedefs.ErrNoMatchingData) {
+		t.Errorf("store.Data(\"ns\", \"k\") => (%q, %v), want (\"\", %v)",
+			value, err, storedefs.ErrNoMatchingData)
+	}
+
+	store.SetData("ns", "k", "v")
+	store.SetData("ns", "k2", "v2")
+	store.SetData("other-ns", "k", "other")
+	value, err = store.Data("ns", "k")
+	if value != "v" || err != nil {
+		t.Errorf("store.Data(\"ns\", \"k\") => (%q, %v), want (\"v\", nil)", value, err)
+	}
+	keys, err := store.DataKeys("ns")
+	if wantKeys := []string{"k", "k2"}; !reflect.DeepEqual(keys, wantKeys) || err != nil {
+		t.Errorf("store.DataKeys(\"ns\") => (%v, %v), want (%v, nil)", keys, err, wantKeys)
+	}
+
+	// Compare and set.
+	swapCases := []struct {
+		old, new    string
+		wantSwapped bool
+		wantValue   string
+	}{
+		{"wrong", "new", false, "v"},
+		{"v", "new", true, "new"},
+		{"new", "", true, ""},
+		{"", "created", true, "created"},
+	}
+	for _, c := range swapCases {
+		swapped, err := store.CompareAndSetData("ns", "k", c.old, c.new)
+		if swapped != c.wantSwapped || err != nil {
+			t.Errorf("store.CompareAndSetData(\"ns\", \"k\", %q, %q) => (%v, %v), want (%v, nil)",
+				c.old, c.new, swapped, err, c.wantSwapped)
+		}
+		if value, _ := store.Data("ns", "k"); value != c.wantValue {
+			t.Errorf("after store.CompareAndSetData(\"ns\", \"k\", %q, %q), value is %q, want %q",
+				c.old, c.new, value, c.wantValue)
+		}
+	}
+
+	// Deleting.
+	store.DelData("ns", "k")
+	store.DelData("ns", "k2")
+	keys, err = store.DataKeys("ns")
+	if len(keys) != 0 || err != nil {
+		t.Errorf("store.DataKeys(\"ns\") after deleting => (%v, %v), want ([], nil)", keys, err)
+	}
+	if err := store.DelData("ns", "k"); err != nil {
+		t.Errorf("store.DelData(\"ns\", \"k\") on nonexistent key => %v, want nil", err)
+	}
+	if value, _ := store.Data("other-ns", "k"); value != "other" {
+		t.Errorf("store.Data(\"other-ns\", \"k\") => %q, want \"other\"", value)
+	}
+
+	// Changing multiple keys.
+	changes := []storedefs.DataEntry{
+		{NS: "ns", Key: "a", Value: "1"}, {NS: "other-ns", Key: "k", Value: ""}}
+	changed, err := store.ChangeData(
+		[]storedefs.DataEntry{{NS: "other-ns", Key: "k", Value: "wrong"}}, changes)
+	if changed || err != nil {
+		t.Errorf("store.ChangeData with unmet expectation => (%v, %v), want (false, nil)", changed, err)
+	}
+	if value, _ := store.Data("other-ns", "k"); value != "other" {
+		t.Errorf("store.Data(\"other-ns\", \"k\") => %q, want \"other\"", value)
+	}
+	changed, err = store.ChangeData([]storedefs.DataEntry{
+		{NS: "other-ns", Key: "k", Value: "other"}, {NS: "ns", Key: "a", Value: ""}}, changes)
+	if !changed || err != nil {
+		t.Errorf("store.ChangeData with met expectation => (%v, %v), want (true, nil)", changed, err)
+	}
+	if value, _ := store.Data("ns", "a"); value != "1" {
+		t.Errorf("store.Data(\"ns\", \"a\") => %q, want \"1\"", value)
+	}
+	if keys, _ := store.DataKeys("other-ns"); len(keys) != 0 {
+		t.Errorf("store.DataKeys(\"other-ns\") => %v, want []", keys)
+	}
+
+	if err := store.SetData("", "k", "v"); !matchErr(err, storedefs.ErrEmptyDataKey) {
+		t.Errorf("store.SetData(\"\", \"k\", \"v\") => %v, want %v", err, storedefs.ErrEmptyDataKey)
+	}
+}
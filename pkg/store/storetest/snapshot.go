@@ -0,0 +1,100 @@
+package storetest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"src.elv.sh/pkg/must"
+	"src.elv.sh/pkg/store/storedefs"
+)
+
+// TestSnapshot tests taking and merging snapshots of a Store.
+func TestSnapshot(t *testing.T, store storedefs.Store) {
+	startSeq, _ := store.NextCmdSeq()
+	store.AddCmd("local")
+	store.AddDir("/snapshot/a", 1)
+
+	snapshot, err := store.Snapshot()
+	if err != nil {
+		t.Fatalf("store.Snapshot() -> error %v", err)
+	}
+	local := findCmdInfo(snapshot.Cmds, "local")
+	if local == nil || local.Time.IsZero() {
+		t.Fatalf("store.Snapshot() doesn't contain command with time, got %v", snapshot.Cmds)
+	}
+
+	// Merging a snapshot of the store itself doesn't change anything.
+	if err := store.MergeSnapshot(snapshot); err != nil {
+		t.Errorf("store.MergeSnapshot(...) -> %v, want nil", err)
+	}
+	if again, _ := store.Snapshot(); !reflect.DeepEqual(again, snapshot) {
+		t.Errorf("after merging own snapshot, got %v, want %v", again, snapshot)
+	}
+
+	// Older than the local command, but newer than any other command.
+	remoteTime := local.Time.Add(-time.Nanosecond)
+	err = store.MergeSnapshot(storedefs.Snapshot{
+		Cmds: []storedefs.CmdInfo{
+			{Text: "local", Time: local.Time},
+			{Text: "remote", Time: remoteTime, HasResult: true, Duration: time.Second},
+		},
+		Dirs: []storedefs.Dir{{Path: "/snapshot/a", Score: 1}, {Path: "/snapshot/b", Score: 5}},
+	})
+	if err != nil {
+		t.Errorf("store.MergeSnapshot(...) -> %v, want nil", err)
+	}
+	infos, _ := store.CmdInfos(startSeq, -1)
+	for i := range infos {
+		infos[i].Time = infos[i].Time.UTC()
+	}
+	// The remote command is older, so it is ordered before the local one.
+	wantInfos := []storedefs.CmdInfo{
+		{Text: "remote", Seq: startSeq + 1, Time: remoteTime.UTC(),
+			HasResult: true, Duration: time.Second},
+		{Text: "local", Seq: startSeq + 2, Time: local.Time.UTC()},
+	}
+	if !reflect.DeepEqual(infos, wantInfos) {
+		t.Errorf("after merging, got commands %v, want %v", infos, wantInfos)
+	}
+	dirs, _ := store.Dirs(storedefs.NoBlacklist)
+	wantScores := map[string]float64{"/snapshot/a": 10, "/snapshot/b": 5}
+	scores := make(map[string]float64)
+	for _, dir := range dirs {
+		if _, ok := wantScores[dir.Path]; ok {
+			scores[dir.Path] = dir.Score
+		}
+	}
+	if !reflect.DeepEqual(scores, wantScores) {
+		t.Errorf("after merging, got scores %v, want %v", scores, wantScores)
+	}
+
+	// Deleted commands are recorded in snapshots, and are not brought back by
+	// merging snapshots that still have them.
+	store.DelCmd(startSeq + 1)
+	snapshot, _ = store.Snapshot()
+	if deleted := findCmdInfo(snapshot.DeletedCmds, "remote"); deleted == nil || !deleted.Time.Equal(remoteTime) {
+		t.Errorf("after deleting, got deleted commands %v, want remote", snapshot.DeletedCmds)
+	}
+	store.MergeSnapshot(storedefs.Snapshot{
+		Cmds: []storedefs.CmdInfo{{Text: "remote", Time: remoteTime}}})
+	if cmd := findCmdInfo(must.OK1(store.CmdInfos(startSeq, -1)), "remote"); cmd != nil {
+		t.Errorf("deleted command came back after merging: %v", cmd)
+	}
+
+	// Commands deleted in the snapshot are deleted from the store.
+	store.MergeSnapshot(storedefs.Snapshot{
+		DeletedCmds: []storedefs.CmdInfo{{Text: "local", Time: local.Time}}})
+	if cmd := findCmdInfo(must.OK1(store.CmdInfos(startSeq, -1)), "local"); cmd != nil {
+		t.Errorf("command deleted in snapshot still exists after merging: %v", cmd)
+	}
+}
+
+func findCmdInfo(infos []storedefs.CmdInfo, text string) *storedefs.CmdInfo {
+	for i := range infos {
+		if infos[i].Text == text {
+			return &infos[i]
+		}
+	}
+	return nil
+}
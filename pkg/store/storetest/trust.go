@@ -0,0 +1,37 @@
+package storetest
+
+import (
+	"testing"
+
+	"src.elv.sh/pkg/store/storedefs"
+)
+
+// TestTrust tests the trusted file functionality of a Store.
+func TestTrust(t *testing.T, tStore storedefs.Store) {
+	hash, err := tStore.TrustedHash("/a/file")
+	if hash != "" || err != nil {
+		t.Errorf("store.TrustedHash(\"/a/file\") => (%q, %v), want (\"\", nil)", hash, err)
+	}
+
+	err = tStore.SetTrustedHash("/a/file", "abc")
+	if err != nil {
+		t.Errorf("store.SetTrustedHash(\"/a/file\", \"abc\") => %v, want nil", err)
+	}
+	hash, err = tStore.TrustedHash("/a/file")
+	if hash != "abc" || err != nil {
+		t.Errorf("store.TrustedHash(\"/a/file\") => (%q, %v), want (\"abc\", nil)", hash, err)
+	}
+	hash, err = tStore.TrustedHash("/another/file")
+	if hash != "" || err != nil {
+		t.Errorf("store.TrustedHash(\"/another/file\") => (%q, %v), want (\"\", nil)", hash, err)
+	}
+
+	err = tStore.SetTrustedHash("/a/file", "")
+	if err != nil {
+		t.Errorf("store.SetTrustedHash(\"/a/file\", \"\") => %v, want nil", err)
+	}
+	hash, err = tStore.TrustedHash("/a/file")
+	if hash != "" || err != nil {
+		t.Errorf("store.TrustedHash(\"/a/file\") after removing => (%q, %v), want (\"\", nil)", hash, err)
+	}
+}
@@ -0,0 +1,35 @@
+package store
+
+import (
+	bolt "go.etcd.io/bbolt"
+)
+
+func init() {
+	initDB["initialize trusted file table"] = func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketTrusted))
+		return err
+	}
+}
+
+// SetTrustedHash records that the file at path is trusted as long as its
+// content has the given hash. An empty hash removes the record.
+func (s *dbStore) SetTrustedHash(path, hash string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketTrusted))
+		if hash == "" {
+			return b.Delete([]byte(path))
+		}
+		return b.Put([]byte(path), []byte(hash))
+	})
+}
+
+// TrustedHash returns the hash recorded for the file at path with
+// SetTrustedHash, or an empty string if the file is not trusted.
+func (s *dbStore) TrustedHash(path string) (string, error) {
+	var hash string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		hash = string(tx.Bucket([]byte(bucketTrusted)).Get([]byte(path)))
+		return nil
+	})
+	return hash, err
+}
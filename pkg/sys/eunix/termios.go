@@ -63,6 +63,16 @@ func (term *Termios) SetICRNL(v bool) {
 	setFlag(&term.Iflag, unix.ICRNL, v)
 }
 
+// ICanon returns whether the canonical flag is set.
+func (term *Termios) ICanon() bool {
+	return term.Lflag&unix.ICANON != 0
+}
+
+// Echo returns whether the echo flag is set.
+func (term *Termios) Echo() bool {
+	return term.Lflag&unix.ECHO != 0
+}
+
 func setFlag(flag *termiosFlag, mask termiosFlag, v bool) {
 	if v {
 		*flag |= mask
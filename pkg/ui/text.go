@@ -3,6 +3,7 @@ package ui
 import (
 	"bytes"
 	"fmt"
+	"html"
 	"math/big"
 	"strconv"
 	"strings"
@@ -246,6 +247,29 @@ func (t Text) VTString() string {
 	return sb.String()
 }
 
+// HTMLString renders the styled text as HTML. Each styled segment is wrapped in
+// a <span> element with one "sgr-$code" class for each of its SGR values, like
+// "sgr-1 sgr-31" for bold red text; it is up to the stylesheet to define
+// these classes.
+func (t Text) HTMLString() string {
+	var sb strings.Builder
+	for _, seg := range t {
+		var classes []string
+		for _, sgrCode := range seg.Style.SGRValues() {
+			classes = append(classes, "sgr-"+sgrCode)
+		}
+		jointClass := strings.Join(classes, " ")
+		if len(jointClass) > 0 {
+			fmt.Fprintf(&sb, `<span class="%s">`, jointClass)
+		}
+		sb.WriteString(html.EscapeString(seg.Text))
+		if len(jointClass) > 0 {
+			sb.WriteString("</span>")
+		}
+	}
+	return sb.String()
+}
+
 // TextFromSegment returns a [Text] with just seg if seg.Text is non-empty.
 // Otherwise it returns nil.
 func TextFromSegment(seg *Segment) Text {
@@ -156,6 +156,17 @@ func TestTrimWcwidth(t *testing.T) {
 	)
 }
 
+func TestHTMLString(t *testing.T) {
+	tt.Test(t, tt.Fn(Text.HTMLString).Named("Text.HTMLString"),
+		Args(Text{}).Rets(""),
+		Args(T("a<b\n")).Rets("a&lt;b\n"),
+		Args(Text{red("lorem"), &Segment{Text: " "}, blue("ipsum")}).Rets(
+			`<span class="sgr-31">lorem</span> <span class="sgr-34">ipsum</span>`),
+		Args(T("&", Bold, Underlined)).Rets(
+			`<span class="sgr-1 sgr-4">&amp;</span>`),
+	)
+}
+
 type textVTStringTest struct {
 	text         Text
 	wantVTString string
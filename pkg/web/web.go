@@ -0,0 +1,210 @@
+// Package web implements a web-based interface for Elvish.
+//
+// The web interface serves a page with a minimal REPL, which sends code to the
+// server over a WebSocket connection, and receives outputs as they are
+// written.
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"src.elv.sh/pkg/diag"
+	"src.elv.sh/pkg/eval"
+	"src.elv.sh/pkg/eval/vals"
+	"src.elv.sh/pkg/logutil"
+	"src.elv.sh/pkg/mods"
+	"src.elv.sh/pkg/parse"
+	"src.elv.sh/pkg/prog"
+)
+
+var logger = logutil.GetLogger("[web] ")
+
+//go:embed index.html
+var indexHTML []byte
+
+// Program is the web subprogram.
+type Program struct {
+	run  bool
+	port int
+}
+
+func (p *Program) RegisterFlags(fs *prog.FlagSet) {
+	fs.BoolVar(&p.run, "web", false,
+		"Run the web interface")
+	fs.IntVar(&p.port, "port", 3171,
+		"The port of the web interface")
+}
+
+func (p *Program) Run(fds [3]*os.File, args []string) error {
+	if !p.run {
+		return prog.NextProgram()
+	}
+	if len(args) > 0 {
+		return prog.BadUsage("arguments are not allowed with -web")
+	}
+
+	ev := eval.NewEvaler()
+	mods.AddTo(ev)
+	defer ev.PreExit()
+
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	addr := fmt.Sprintf("localhost:%d", p.port)
+	fmt.Fprintf(fds[2], "Serving web interface at http://%s/?token=%s\n", addr, token)
+	return http.ListenAndServe(addr, newHandler(ev, token))
+}
+
+// Generates a random token that clients must present, so that only whoever can
+// see the URL printed on startup can evaluate code.
+func newToken() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+func newHandler(ev *eval.Evaler, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(indexHTML)
+	})
+	s := &server{ev: ev}
+	mux.HandleFunc("/ws", s.serveWS)
+	return checkAccess(mux, token)
+}
+
+// Wraps a handler to reject requests whose Host is not a loopback name, which
+// protects against DNS rebinding, and requests without the right token.
+func checkAccess(h http.Handler, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isLoopbackHost(r.Host) {
+			http.Error(w, "host not allowed", http.StatusForbidden)
+			return
+		}
+		got := r.URL.Query().Get("token")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "bad or missing token", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func isLoopbackHost(host string) bool {
+	hostname, _, err := net.SplitHostPort(host)
+	if err != nil {
+		return false
+	}
+	switch hostname {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return false
+}
+
+type server struct {
+	ev *eval.Evaler
+
+	mu     sync.Mutex
+	cmdNum int
+}
+
+// A message sent to the client. The type is one of "value", "bytes", "error"
+// and "done".
+type message struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+func (s *server) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrade(w, r)
+	if err != nil {
+		logger.Println("upgrade:", err)
+		return
+	}
+	defer conn.Close()
+
+	// Cancel the running code when the client goes away.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	send := func(m message) {
+		data, _ := json.Marshal(m)
+		if err := conn.WriteText(data); err != nil {
+			logger.Println("write:", err)
+			cancel()
+		}
+	}
+
+	for {
+		code, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.cmdNum++
+		name := fmt.Sprintf("[web %d]", s.cmdNum)
+		s.mu.Unlock()
+		err = s.eval(ctx, parse.Source{Name: name, Code: string(code)}, send)
+		if err != nil {
+			var sb strings.Builder
+			diag.ShowError(&sb, err)
+			send(message{"error", sb.String()})
+		}
+		send(message{Type: "done"})
+	}
+}
+
+func (s *server) eval(ctx context.Context, src parse.Source, send func(message)) error {
+	newPort := func() (*eval.Port, func(), error) {
+		return eval.PipePort(
+			func(ch <-chan any) {
+				for v := range ch {
+					send(message{"value", vals.ReprPlain(v)})
+				}
+			},
+			func(r *os.File) {
+				buf := make([]byte, 4096)
+				for {
+					n, err := r.Read(buf)
+					if n > 0 {
+						send(message{"bytes", string(buf[:n])})
+					}
+					if err != nil {
+						break
+					}
+				}
+			})
+	}
+	out, cleanupOut, err := newPort()
+	if err != nil {
+		return err
+	}
+	defer cleanupOut()
+	errPort, cleanupErr, err := newPort()
+	if err != nil {
+		return err
+	}
+	defer cleanupErr()
+	return s.ev.Eval(src, eval.EvalCfg{
+		Ports: []*eval.Port{nil, out, errPort}, Interrupts: ctx})
+}
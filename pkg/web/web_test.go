@@ -0,0 +1,221 @@
+package web
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"src.elv.sh/pkg/eval"
+	. "src.elv.sh/pkg/prog/progtest"
+)
+
+func TestProgram(t *testing.T) {
+	Test(t, &Program{},
+		ThatElvish("-web", "foo").
+			ExitsWith(2).
+			WritesStderrContaining("arguments are not allowed with -web"),
+	)
+}
+
+func TestAcceptKey(t *testing.T) {
+	// Example from RFC 6455.
+	if got, want := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	ts := httptest.NewServer(newHandler(eval.NewEvaler(), testToken))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/?token=" + testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "<title>Elvish</title>") {
+		t.Errorf("index page doesn't contain title")
+	}
+
+	resp, err = http.Get(ts.URL + "/ws?token=" + testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("got status %v for non-websocket request, want 400", resp.StatusCode)
+	}
+}
+
+const testToken = "secret"
+
+func TestAccess(t *testing.T) {
+	ts := httptest.NewServer(newHandler(eval.NewEvaler(), testToken))
+	defer ts.Close()
+
+	for _, path := range []string{"/", "/?token=bad", "/ws", "/ws?token=bad"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("got status %v for %s, want 403", resp.StatusCode, path)
+		}
+	}
+
+	// A Host that is not a loopback name, as sent by a browser after a DNS
+	// rebinding attack.
+	req, _ := http.NewRequest("GET", ts.URL+"/?token="+testToken, nil)
+	req.Host = "evil.example.com:3171"
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("got status %v for non-loopback host, want 403", resp.StatusCode)
+	}
+}
+
+func TestWebSocket_RejectsOtherOrigins(t *testing.T) {
+	ts := httptest.NewServer(newHandler(eval.NewEvaler(), testToken))
+	defer ts.Close()
+
+	_, status := dial(t, ts, "http://evil.example.com")
+	if !strings.Contains(status, "403") {
+		t.Errorf("got status %q, want 403", status)
+	}
+}
+
+func TestWebSocket_Eval(t *testing.T) {
+	ts := httptest.NewServer(newHandler(eval.NewEvaler(), testToken))
+	defer ts.Close()
+
+	c, status := dial(t, ts, ts.URL)
+	if !strings.Contains(status, "101") {
+		t.Fatalf("got status %q, want 101", status)
+	}
+
+	c.send("put foo [bar]")
+	if diff := cmp.Diff([]message{
+		{"value", "foo"}, {"value", "[bar]"}, {Type: "done"},
+	}, c.recvUntilDone()); diff != "" {
+		t.Errorf("messages (-want +got):\n%s", diff)
+	}
+
+	// The global namespace is kept between evaluations.
+	c.send("var x = lorem")
+	c.recvUntilDone()
+	c.send("echo $x")
+	if diff := cmp.Diff([]message{
+		{"bytes", "lorem\n"}, {Type: "done"},
+	}, c.recvUntilDone()); diff != "" {
+		t.Errorf("messages (-want +got):\n%s", diff)
+	}
+
+	// Send a message large enough to use the 16-bit extended length.
+	c.send("put " + strings.Repeat("x", 200))
+	if diff := cmp.Diff([]message{
+		{"value", strings.Repeat("x", 200)}, {Type: "done"},
+	}, c.recvUntilDone()); diff != "" {
+		t.Errorf("messages (-want +got):\n%s", diff)
+	}
+
+	c.send("fail bad")
+	msgs := c.recvUntilDone()
+	if len(msgs) != 2 || msgs[0].Type != "error" || !strings.Contains(msgs[0].Text, "bad") {
+		t.Errorf("got messages %v, want error containing \"bad\"", msgs)
+	}
+}
+
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dial(t *testing.T, ts *httptest.Server, origin string) (*testClient, string) {
+	t.Helper()
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	io.WriteString(conn, "GET /ws?token="+testToken+" HTTP/1.1\r\n"+
+		"Host: "+ts.Listener.Addr().String()+"\r\n"+
+		"Connection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Origin: "+origin+"\r\n"+
+		"Sec-WebSocket-Version: 13\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	r := bufio.NewReader(conn)
+	status, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line == "\r\n" {
+			break
+		}
+	}
+	return &testClient{t, conn, r}, status
+}
+
+func (c *testClient) send(s string) {
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opText}
+	if len(s) <= 125 {
+		frame = append(frame, 0x80|byte(len(s)))
+	} else {
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(s)))
+	}
+	frame = append(frame, mask[:]...)
+	for i := 0; i < len(s); i++ {
+		frame = append(frame, s[i]^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *testClient) recvUntilDone() []message {
+	c.t.Helper()
+	var msgs []message
+	for {
+		var head [2]byte
+		if _, err := io.ReadFull(c.r, head[:]); err != nil {
+			c.t.Fatal(err)
+		}
+		n := int(head[1])
+		if n == 126 {
+			var ext [2]byte
+			io.ReadFull(c.r, ext[:])
+			n = int(binary.BigEndian.Uint16(ext[:]))
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(c.r, payload); err != nil {
+			c.t.Fatal(err)
+		}
+		var msg message
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			c.t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+		if msg.Type == "done" {
+			return msgs
+		}
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"src.elv.sh/pkg/elvdoc"
 	"src.elv.sh/pkg/md"
 	"src.elv.sh/pkg/strutil"
-	"src.elv.sh/pkg/ui"
 )
 
 // A wrapper of [md.HTMLCodec] implementing generic additional features.
@@ -156,8 +155,8 @@ func (c *htmlCodec) Do(op md.Op) {
 				}
 			}
 		} else {
-			c.WriteString(textToHTML(
-				elvdoc.HighlightCodeBlock(op.Info, strutil.JoinLines(op.Lines))))
+			c.WriteString(elvdoc.HighlightCodeBlock(
+				op.Info, strutil.JoinLines(op.Lines)).HTMLString())
 		}
 		c.WriteString("</code></pre>\n")
 	default:
@@ -258,28 +257,3 @@ func renderCodeBlockHeader(header string) string {
 	// setting "display: inline-block" on the element.
 	return "<header>" + md.RenderString(header, &md.HTMLCodec{}) + "</header>"
 }
-
-func textToHTML(t ui.Text) string {
-	var sb strings.Builder
-	for _, seg := range t {
-		var classes []string
-		for _, sgrCode := range seg.Style.SGRValues() {
-			classes = append(classes, "sgr-"+sgrCode)
-		}
-		jointClass := strings.Join(classes, " ")
-		if len(jointClass) > 0 {
-			fmt.Fprintf(&sb, `<span class="%s">`, jointClass)
-		}
-		for _, r := range seg.Text {
-			if r == '\n' {
-				sb.WriteByte('\n')
-			} else {
-				sb.WriteString(html.EscapeString(string(r)))
-			}
-		}
-		if len(jointClass) > 0 {
-			sb.WriteString("</span>")
-		}
-	}
-	return sb.String()
-}